@@ -9,52 +9,38 @@ import (
 	"github.com/*company-data-covered*/services/go/pkg/redisclient"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
-	"google.golang.org/protobuf/proto"
 )
 
 var changedLabResultsLastConsumedName = "changed_lab_results_last_consumed"
 
 type LabResultProcessor struct {
-	ctx          context.Context
 	logger       *zap.SugaredLogger
 	redisClient  *redisclient.Client
 	athenaClient athenapb.AthenaServiceClient
 }
 
-func (p *LabResultProcessor) ProcessMessage(message eventstreaming.ConsumerMessage) error {
+func (p *LabResultProcessor) ProcessTypedMessage(message eventstreaming.ConsumerMessage, results *athenapb.ListChangedLabResultsResult) error {
 	defer func() {
 		if r := recover(); r != nil {
 			p.logger.Error("Recovered from panic: ", r)
 		}
 	}()
 
-	var err error
-	value := message.Value()
-	if value == nil {
-		err = errors.New("message.Value cannot be empty")
-		p.logger.Error(err)
-		return err
-	}
-
-	results, err := p.unmarshalLabResult(value)
+	// The message context continues the trace of the listener that produced it.
+	ctx := message.Context()
+	doc, err := p.getLabResultDocument(ctx, results)
 	if err != nil {
 		p.logger.Error(err)
 		return err
 	}
 
-	doc, err := p.getLabResultDocument(results)
-	if err != nil {
-		p.logger.Error(err)
-		return err
-	}
-
-	err = p.copyToPatientSummaryIfInterfaceDoc(results.GetPatientId(), doc)
+	err = p.copyToPatientSummaryIfInterfaceDoc(ctx, results.GetPatientId(), doc)
 	if err != nil {
 		p.logger.Errorf("Failed to append Lab Result Analytes to Patient Goal Discussion Notes: %s", err)
 		return err
 	}
 
-	err = p.updateLastConsumedTimestamp()
+	err = p.updateLastConsumedTimestamp(ctx)
 	if err != nil {
 		p.logger.Error(err)
 		return err
@@ -63,9 +49,9 @@ func (p *LabResultProcessor) ProcessMessage(message eventstreaming.ConsumerMessa
 	return nil
 }
 
-func (p *LabResultProcessor) updateLastConsumedTimestamp() error {
+func (p *LabResultProcessor) updateLastConsumedTimestamp(ctx context.Context) error {
 	currentTimestampUnix := time.Now().Unix()
-	err := p.redisClient.Set(p.ctx, changedLabResultsLastConsumedName, currentTimestampUnix, 0)
+	err := p.redisClient.Set(ctx, changedLabResultsLastConsumedName, currentTimestampUnix, 0)
 	if err != nil {
 		return errors.Wrap(err, "Failed to update last_processed_changed_lab_results timestamp")
 	}
@@ -73,18 +59,7 @@ func (p *LabResultProcessor) updateLastConsumedTimestamp() error {
 	return nil
 }
 
-func (p *LabResultProcessor) unmarshalLabResult(value []byte) (*athenapb.ListChangedLabResultsResult, error) {
-	changedLabResult := &athenapb.ListChangedLabResultsResult{}
-	err := proto.Unmarshal(value, changedLabResult)
-
-	if err != nil {
-		return nil, errors.Wrap(err, "Failed to unmarshal message.Value")
-	}
-
-	return changedLabResult, nil
-}
-
-func (p *LabResultProcessor) getLabResultDocument(changedLabResult *athenapb.ListChangedLabResultsResult) (*athenapb.LabResultDocument, error) {
+func (p *LabResultProcessor) getLabResultDocument(ctx context.Context, changedLabResult *athenapb.ListChangedLabResultsResult) (*athenapb.LabResultDocument, error) {
 	labResultID := changedLabResult.GetLabResultId()
 	patientID := changedLabResult.GetPatientId()
 
@@ -97,7 +72,7 @@ func (p *LabResultProcessor) getLabResultDocument(changedLabResult *athenapb.Lis
 		return nil, err
 	}
 
-	resp, err := p.athenaClient.GetPatientLabResultDocument(p.ctx, &athenapb.GetPatientLabResultDocumentRequest{PatientId: patientID, LabResultId: labResultID})
+	resp, err := p.athenaClient.GetPatientLabResultDocument(ctx, &athenapb.GetPatientLabResultDocumentRequest{PatientId: patientID, LabResultId: labResultID})
 
 	if err != nil {
 		err = errors.Wrap(err, "Failed to get patient Lab Result Document")
@@ -113,10 +88,10 @@ func (p *LabResultProcessor) getLabResultDocument(changedLabResult *athenapb.Lis
 	return resp.Results[0], nil
 }
 
-func (p *LabResultProcessor) copyToPatientSummaryIfInterfaceDoc(patientID string, labResultDoc *athenapb.LabResultDocument) error {
+func (p *LabResultProcessor) copyToPatientSummaryIfInterfaceDoc(ctx context.Context, patientID string, labResultDoc *athenapb.LabResultDocument) error {
 	if *labResultDoc.DocumentSource == "INTERFACE" {
 		copier := &AnalyteCopier{AthenaClient: p.athenaClient}
-		return copier.Copy(p.ctx, patientID, labResultDoc)
+		return copier.Copy(ctx, patientID, labResultDoc)
 	}
 	return nil
 }
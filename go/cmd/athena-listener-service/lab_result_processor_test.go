@@ -34,6 +34,21 @@ func (cm mockConsumerMessage) Value() []byte {
 func (cm mockConsumerMessage) Timestamp() time.Time {
 	return cm.timestamp
 }
+func (cm mockConsumerMessage) Key() []byte {
+	return nil
+}
+func (cm mockConsumerMessage) Headers() map[string]string {
+	return nil
+}
+func (cm mockConsumerMessage) Partition() int32 {
+	return 0
+}
+func (cm mockConsumerMessage) Offset() int64 {
+	return 0
+}
+func (cm mockConsumerMessage) Context() context.Context {
+	return context.Background()
+}
 
 func TestProcessMessage(t *testing.T) {
 	goodResult := &athenapb.ListChangedLabResultsResult{
@@ -158,6 +173,14 @@ func TestProcessMessage(t *testing.T) {
 				Results: badDoc,
 			},
 		},
+		{
+			Desc: "failure - empty value",
+			Message: mockConsumerMessage{
+				topic:     changedLabResultsTopicName,
+				timestamp: time.Now(),
+			},
+			WantError: true,
+		},
 		{
 			Desc: "failure - missing PatientID in value",
 			Message: mockConsumerMessage{
@@ -233,7 +256,6 @@ func TestProcessMessage(t *testing.T) {
 	for _, tc := range tcs {
 		var auditClient auditpb.AuditServiceClient = &MockAuditServiceClient{}
 		p := &LabResultProcessor{
-			ctx: context.Background(),
 			athenaClient: &AthenaClientMock{
 				UpdatePatientDiscussionNotesHandler: func(ctx context.Context, in *athenapb.UpdatePatientDiscussionNotesRequest, opts ...grpc.CallOption) (*athenapb.UpdatePatientDiscussionNotesResponse, error) {
 					return tc.AthenaDiscussionNoteResponse, tc.AthenaDiscussionNoteError
@@ -253,7 +275,12 @@ func TestProcessMessage(t *testing.T) {
 			logger: zap.NewNop().Sugar(),
 		}
 
-		err := p.ProcessMessage(tc.Message)
+		processor, err := eventstreaming.NewTypedProcessor[*athenapb.ListChangedLabResultsResult](schemaRegistry, changedLabResultsTopicName, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = processor.ProcessMessage(tc.Message)
 
 		var errStr string
 		if err != nil {
@@ -158,7 +158,7 @@ func (l Listener) PollForPatients(ctx context.Context) error {
 		return err
 	}
 
-	numMessagesSent := l.sendPatientsToKafka(results)
+	numMessagesSent := l.sendPatientsToKafka(ctx, results)
 	l.sendNumMessagesMetric(numMessagesSent, monitoring.Tags{
 		"topic":    changedPatientsTopicName,
 		"backfill": "false",
@@ -215,7 +215,7 @@ func (l Listener) PollForLabResults(ctx context.Context) error {
 		return err
 	}
 
-	numMessagesSent := l.sendLabResultsToKafka(results)
+	numMessagesSent := l.sendLabResultsToKafka(ctx, results)
 	l.sendNumMessagesMetric(numMessagesSent, monitoring.Tags{
 		"topic":    changedLabResultsTopicName,
 		"backfill": "false",
@@ -272,7 +272,7 @@ func (l Listener) backfillPatientsIfDirty(ctx context.Context, currentTimestampU
 	}
 	// Since dirty bit is true, we have no guarantee previously-consumed patient results were sent to Kafka.
 	// Set these params to ensure we reprocess previously-consumed patient results in Kafka.
-	numMessagesSent := l.sendPatientsToKafka(results)
+	numMessagesSent := l.sendPatientsToKafka(ctx, results)
 
 	l.sendNumMessagesMetric(numMessagesSent, monitoring.Tags{
 		"topic":    changedLabResultsTopicName,
@@ -322,7 +322,7 @@ func (l Listener) backfillLabResultsIfDirty(ctx context.Context, currentTimestam
 	}
 	// Since dirty bit is true, we have no guarantee previously-consumed results were sent to Kafka.
 	// Set these params to ensure we reprocess previously-consumed results in Kafka.
-	numMessagesSent := l.sendLabResultsToKafka(results)
+	numMessagesSent := l.sendLabResultsToKafka(ctx, results)
 
 	l.sendNumMessagesMetric(numMessagesSent, monitoring.Tags{
 		"topic":    changedLabResultsTopicName,
@@ -392,15 +392,20 @@ func (l Listener) getAllLabResults(ctx context.Context, params *athenapb.ListCha
 }
 
 // Gets all patients and pushes each one into the Kafka topic. Also sends metrics to Datadog.
-func (l Listener) sendPatientsToKafka(patients []*athenapb.ListChangedPatientsResult) int {
+func (l Listener) sendPatientsToKafka(ctx context.Context, patients []*athenapb.ListChangedPatientsResult) int {
+	producer, err := eventstreaming.NewTypedProducer[*athenapb.ListChangedPatientsResult](l.Producer, schemaRegistry, changedPatientsTopicName)
+	if err != nil {
+		l.Logger.Errorf("AthenaListener failed to create patients producer: %v", err)
+		return 0
+	}
+
 	var numMessagesSent int
 	for _, result := range patients {
-		marshaledPatient, err := proto.Marshal(result)
-		if err != nil {
-			l.Logger.Errorf("AthenaListener failed to marshal patient with id %s", result.GetPatientId())
-			continue
-		}
-		err = l.Producer.SendMessage(&eventstreaming.ProducerMessage{Topic: changedPatientsTopicName, Value: marshaledPatient})
+		// Keyed by patient, so that changes of a patient are consumed in order.
+		err = producer.SendMessage(ctx, &eventstreaming.TypedProducerMessage[*athenapb.ListChangedPatientsResult]{
+			Message: result,
+			Key:     []byte(result.GetPatientId()),
+		})
 		if err != nil {
 			l.Logger.Errorf("AthenaListener failed to send message with patient %v: %v", result, err)
 			continue
@@ -411,15 +416,19 @@ func (l Listener) sendPatientsToKafka(patients []*athenapb.ListChangedPatientsRe
 }
 
 // Gets all lab results and pushes each one into the Kafka topic. Also sends metrics to Datadog.
-func (l Listener) sendLabResultsToKafka(labResults []*athenapb.ListChangedLabResultsResult) int {
+func (l Listener) sendLabResultsToKafka(ctx context.Context, labResults []*athenapb.ListChangedLabResultsResult) int {
+	producer, err := eventstreaming.NewTypedProducer[*athenapb.ListChangedLabResultsResult](l.Producer, schemaRegistry, changedLabResultsTopicName)
+	if err != nil {
+		l.Logger.Errorf("AthenaListener failed to create lab results producer: %v", err)
+		return 0
+	}
+
 	var numMessagesSent int
 	for _, result := range labResults {
-		marshaledLabResult, err := proto.Marshal(result)
-		if err != nil {
-			l.Logger.Errorf("AthenaListener failed to marshal lab result with id %s", result.GetLabResultId())
-			continue
-		}
-		err = l.Producer.SendMessage(&eventstreaming.ProducerMessage{Topic: changedLabResultsTopicName, Value: marshaledLabResult})
+		err = producer.SendMessage(ctx, &eventstreaming.TypedProducerMessage[*athenapb.ListChangedLabResultsResult]{
+			Message: result,
+			Key:     []byte(result.GetPatientId()),
+		})
 		if err != nil {
 			l.Logger.Errorf("AthenaListener failed to send message with lab result %v: %v", result, err)
 			continue
@@ -27,7 +27,7 @@ type mockProducer struct {
 	messageErr error
 }
 
-func (m mockProducer) SendMessage(message *eventstreaming.ProducerMessage) error {
+func (m mockProducer) SendMessage(_ context.Context, message *eventstreaming.ProducerMessage) error {
 	if m.messageErr != nil {
 		return m.messageErr
 	}
@@ -682,12 +682,45 @@ func TestSendLabResultsToKafka(t *testing.T) {
 					Client: &mockDatadogClient,
 				},
 			}
-			count := listener.sendLabResultsToKafka(tt.results)
+			count := listener.sendLabResultsToKafka(context.Background(), tt.results)
 			testutils.MustMatch(t, tt.wantCount, count)
 		})
 	}
 }
 
+func TestSendLabResultsToKafka_MemoryBroker(t *testing.T) {
+	broker := eventstreaming.NewMemoryBroker()
+	producer, err := eventstreaming.NewMessageProducer(&eventstreaming.ClientConfig{
+		MemoryBroker: broker,
+		Producer:     &eventstreaming.ProducerConfig{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener := &Listener{
+		Logger:   zap.NewNop().Sugar(),
+		Producer: producer,
+	}
+	results := []*athenapb.ListChangedLabResultsResult{
+		{LabResultId: proto.String("1"), PatientId: proto.String("10")},
+		{LabResultId: proto.String("2"), PatientId: proto.String("20")},
+	}
+
+	count := listener.sendLabResultsToKafka(context.Background(), results)
+	testutils.MustMatch(t, 2, count)
+
+	messages := broker.Messages(changedLabResultsTopicName)
+	testutils.MustMatch(t, len(results), len(messages))
+	for i, message := range messages {
+		var result athenapb.ListChangedLabResultsResult
+		if err := proto.Unmarshal(message.Value, &result); err != nil {
+			t.Fatal(err)
+		}
+		testutils.MustMatch(t, results[i], &result)
+		testutils.MustMatch(t, results[i].GetPatientId(), string(message.Key))
+	}
+}
+
 func TestGetAllPatients(t *testing.T) {
 	tests := []struct {
 		name                        string
@@ -43,12 +43,17 @@ var (
 	auditServiceGRPCAddr       = flag.String("audit-service-grpc-addr", "localhost:8482", "gRPC address for the audit service")
 	redisLockExpiry            = flag.Duration("redis-lock-expiry", 1*time.Minute, "expiry for holding a redis lock on a polling loop")
 	enableRedis                = flag.Bool("enable-redis", true, "enable redis for caching calls")
+	labResultsMaxRetries       = flag.Int("lab-results-max-retries", 3, "number of times a changed lab result message that fails processing is retried")
+	labResultsRetryBackoff     = flag.Duration("lab-results-retry-backoff", 1*time.Second, "initial backoff between changed lab result message retries")
+	labResultsMaxRetryBackoff  = flag.Duration("lab-results-max-retry-backoff", 30*time.Second, "max backoff between changed lab result message retries")
 	envProvider                = providers.NewEnvProvider()
 	changedPatientsTopicName   = featureflags.NewStringFlag("KAFKA_CHANGED_PATIENTS_TOPIC_NAME", "dev.athena.changed-patients").Get(envProvider)
 	changedLabResultsTopicName = featureflags.NewStringFlag("KAFKA_CHANGED_LAB_RESULTS_TOPIC_NAME", "dev.athena.changed-lab-results").Get(envProvider)
+	changedLabResultsDLQName   = featureflags.NewStringFlag("KAFKA_CHANGED_LAB_RESULTS_DLQ_TOPIC_NAME", "dev.athena.changed-lab-results.dlq").Get(envProvider)
 	versionSetting             = featureflags.NewStringFlag("KAFKA_VERSION", "3.2.3")
 	loggingSetting             = featureflags.NewBooleanFlag("KAFKA_LOGGING_VERBOSE", false)
 	brokersSetting             = featureflags.NewStringFlag("KAFKA_BROKERS", "localhost:9092")
+	inMemorySetting            = featureflags.NewBooleanFlag("KAFKA_IN_MEMORY", false)
 	rootCAStrPem               = featureflags.NewStringFlag("KAFKA_BROKER_CA_PEM", "")
 	clientCertStrPem           = featureflags.NewStringFlag("KAFKA_BROKER_CERTIFICATE_PEM", "")
 	clientKeyStrPem            = featureflags.NewStringFlag("KAFKA_BROKER_KEY_PEM", "")
@@ -69,7 +74,7 @@ func getM2MToken(audience string, issuerURL string) (*auth.AutoRefreshToken, err
 }
 
 func listenerProducerConfig() *eventstreaming.ClientConfig {
-	return &eventstreaming.ClientConfig{
+	return withInMemoryKafka(&eventstreaming.ClientConfig{
 		Brokers:        strings.Split(brokersSetting.Get(envProvider), ","),
 		KafkaVersion:   versionSetting.Get(envProvider),
 		VerboseLogging: loggingSetting.Get(envProvider),
@@ -80,7 +85,7 @@ func listenerProducerConfig() *eventstreaming.ClientConfig {
 		},
 		Producer:       &eventstreaming.ProducerConfig{},
 		LoggingOptions: baselogger.LoggerOptions{ServiceName: serviceName},
-	}
+	})
 }
 
 func listenerConsumerConfig() *eventstreaming.ClientConfig {
@@ -88,7 +93,7 @@ func listenerConsumerConfig() *eventstreaming.ClientConfig {
 	assignmentStrategySetting := featureflags.NewStringFlag("KAFKA_ASSIGNMENT_STRATEGY", eventstreaming.RangeAssignment)
 	oldestOffsetSetting := featureflags.NewBooleanFlag("KAFKA_OLDEST_OFFSET", true)
 
-	return &eventstreaming.ClientConfig{
+	return withInMemoryKafka(&eventstreaming.ClientConfig{
 		Brokers:        strings.Split(brokersSetting.Get(envProvider), ","),
 		KafkaVersion:   versionSetting.Get(envProvider),
 		LoggingOptions: baselogger.LoggerOptions{},
@@ -103,7 +108,17 @@ func listenerConsumerConfig() *eventstreaming.ClientConfig {
 			AssignmentStrategy: eventstreaming.AssignmentStrategy(assignmentStrategySetting.Get(envProvider)),
 			ConsumeFromOldest:  oldestOffsetSetting.Get(envProvider),
 		},
+	})
+}
+
+// withInMemoryKafka sets the config to use the in-process memory broker without certificates, if enabled,
+// so that the listener runs locally without Kafka.
+func withInMemoryKafka(config *eventstreaming.ClientConfig) *eventstreaming.ClientConfig {
+	if inMemorySetting.Get(envProvider) {
+		config.MemoryBroker = eventstreaming.DefaultMemoryBroker()
+		config.Certs = nil
 	}
+	return config
 }
 
 func decodePemStrFlag(psf *featureflags.StringFlag) []byte {
@@ -217,14 +232,30 @@ func main() {
 		logger.Panicw("failed to create new eventstreaming message producer for Athena subscriptions", zap.Error(err))
 	}
 
-	consumerConfig := listenerConsumerConfig()
-	consumerConfig.Consumer.Topics = map[string]eventstreaming.MessageProcessor{
-		changedLabResultsTopicName: &LabResultProcessor{
-			ctx:          ctx,
+	labResultProcessor, err := eventstreaming.NewTypedProcessor[*athenapb.ListChangedLabResultsResult](
+		schemaRegistry,
+		changedLabResultsTopicName,
+		&LabResultProcessor{
 			logger:       logger,
 			redisClient:  redisClient,
 			athenaClient: athenapb.NewAthenaServiceClient(athenaServiceConnection),
 		},
+	)
+	if err != nil {
+		logger.Panicw("failed to create lab result processor", zap.Error(err))
+	}
+
+	consumerConfig := listenerConsumerConfig()
+	consumerConfig.Consumer.Topics = map[string]eventstreaming.MessageProcessor{
+		changedLabResultsTopicName: labResultProcessor,
+	}
+	consumerConfig.Consumer.RetryPolicies = map[string]*eventstreaming.RetryPolicy{
+		changedLabResultsTopicName: {
+			MaxRetries:      *labResultsMaxRetries,
+			InitialBackoff:  *labResultsRetryBackoff,
+			MaxBackoff:      *labResultsMaxRetryBackoff,
+			DeadLetterTopic: changedLabResultsDLQName,
+		},
 	}
 
 	consumerGroup, err := eventstreaming.NewConsumerGroup(consumerConfig)
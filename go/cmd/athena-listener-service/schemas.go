@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/*company-data-covered*/services/go/pkg/eventstreaming"
+	athenapb "github.com/*company-data-covered*/services/go/pkg/generated/proto/athena"
+)
+
+// schemaRegistry holds the schemas of the topics produced and consumed by the listener.
+// Messages produced before schema headers were added are consumed as version 1.
+var schemaRegistry = eventstreaming.NewSchemaRegistry(map[string]eventstreaming.TopicSchema{
+	changedPatientsTopicName: {
+		Message:           &athenapb.ListChangedPatientsResult{},
+		Version:           1,
+		AcceptUnversioned: true,
+	},
+	changedLabResultsTopicName: {
+		Message:           &athenapb.ListChangedLabResultsResult{},
+		Version:           1,
+		AcceptUnversioned: true,
+	},
+})
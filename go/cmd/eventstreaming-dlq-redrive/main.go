@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/baseserv"
+	"github.com/*company-data-covered*/services/go/pkg/eventstreaming"
+)
+
+const defaultGroupID = "eventstreaming-dlq-redrive"
+
+var (
+	deadLetterTopic = flag.String("dead-letter-topic", "", "dead-letter topic to re-drive messages from")
+	sourceTopic     = flag.String("source-topic", "", "only re-drive messages from this source topic, if set")
+	groupID         = flag.String("group", "", "consumer group to consume the dead-letter topic with, derived from source-topic if not set")
+	idleTimeout     = flag.Duration("idle-timeout", 30*time.Second, "stop once no message is consumed for this long")
+)
+
+// Re-drives messages from a dead-letter topic back onto their source topics,
+// stopping once the dead-letter topic is drained.
+func main() {
+	flag.Parse()
+	if *deadLetterTopic == "" {
+		log.Fatal("dead-letter-topic is required")
+	}
+
+	producerConfig := baseserv.DefaultEventStreamingProducerConfig()
+	producerConfig.Producer = &eventstreaming.ProducerConfig{}
+	producer, err := eventstreaming.NewMessageProducer(&producerConfig)
+	if err != nil {
+		log.Panic(err)
+	}
+	defer producer.Close()
+
+	redriver := eventstreaming.NewDeadLetterRedriver(producer, *sourceTopic)
+
+	consumerConfig := baseserv.DefaultEventStreamingConsumerConfig()
+	consumerConfig.Consumer.GroupID = redriveGroupID(*groupID, *sourceTopic)
+	// New groups must start from the oldest dead letter, not only the ones arriving after the re-drive started.
+	consumerConfig.Consumer.ConsumeFromOldest = true
+	consumerConfig.Consumer.Topics = map[string]eventstreaming.MessageProcessor{
+		*deadLetterTopic: redriver,
+	}
+	consumerGroup, err := eventstreaming.NewConsumerGroup(&consumerConfig)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("Re-driving messages from %s", *deadLetterTopic)
+	startedAt := time.Now()
+	consumerGroup.Start(ctx)
+
+	waitForIdle(ctx, consumerGroup, redriver, startedAt)
+
+	if err := consumerGroup.Stop(); err != nil {
+		log.Panicf("Error stopping consumer group: %v", err)
+	}
+
+	stats := redriver.Stats()
+	log.Printf("Re-drove %d messages, skipped %d", stats.Redriven, stats.Skipped)
+	for topic, count := range stats.RedrivenByTopic {
+		log.Printf("Re-drove %d messages to %s", count, topic)
+	}
+}
+
+// redriveGroupID returns the consumer group to re-drive with. Without a group, re-drives of a source topic
+// get their own group, as skipped messages of other source topics are marked consumed in it.
+func redriveGroupID(group, sourceTopic string) string {
+	switch {
+	case group != "":
+		return group
+	case sourceTopic != "":
+		return fmt.Sprintf("%s.%s", defaultGroupID, sourceTopic)
+	default:
+		return defaultGroupID
+	}
+}
+
+// waitForIdle returns once no message has been consumed for the idle timeout,
+// or on consumer error or termination.
+func waitForIdle(ctx context.Context, consumerGroup eventstreaming.ConsumerGroup, redriver *eventstreaming.DeadLetterRedriver, startedAt time.Time) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-consumerGroup.Error():
+			log.Printf("consumer error: %v", err)
+			return
+		case <-ctx.Done():
+			log.Println("terminating: via signal")
+			return
+		case now := <-ticker.C:
+			lastActivity := startedAt
+			if lastMessageAt := redriver.Stats().LastMessageAt; lastMessageAt.After(lastActivity) {
+				lastActivity = lastMessageAt
+			}
+			if now.Sub(lastActivity) >= *idleTimeout {
+				log.Println("terminating: dead-letter topic is idle")
+				return
+			}
+		}
+	}
+}
@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/*company-data-covered*/services/go/pkg/baseserv"
 	"github.com/*company-data-covered*/services/go/pkg/eventstreaming"
@@ -16,7 +19,8 @@ type MessageLogger struct {
 }
 
 func (p *MessageLogger) ProcessMessage(message eventstreaming.ConsumerMessage) error {
-	log.Printf("Message claimed: value = %s, timestamp = %v, topic = %s", string(message.Value()), message.Timestamp(), message.Topic())
+	log.Printf("Message claimed: value = %s, timestamp = %v, topic = %s, key = %s, partition = %d, offset = %d, headers = %v",
+		string(message.Value()), message.Timestamp(), message.Topic(), string(message.Key()), message.Partition(), message.Offset(), message.Headers())
 	return nil
 }
 
@@ -39,6 +43,11 @@ func main() {
 
 	consumerGroup.Start(ctx)
 
+	// Without Kafka, there is no example producer process to consume from, so produce in-process.
+	if consumerConfig.MemoryBroker != nil {
+		go produceInMemory(ctx, consumerConfig.MemoryBroker)
+	}
+
 	executionLoop(ctx, consumerGroup)
 
 	if err := consumerGroup.Stop(); err != nil {
@@ -46,6 +55,36 @@ func main() {
 	}
 }
 
+func produceInMemory(ctx context.Context, broker *eventstreaming.MemoryBroker) {
+	producer, err := eventstreaming.NewMessageProducer(&eventstreaming.ClientConfig{
+		MemoryBroker: broker,
+		Producer:     &eventstreaming.ProducerConfig{},
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+	defer producer.Close()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for i := 0; ; i++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			topic := fmt.Sprintf("test-topic-%d", i%3+1)
+			err := producer.SendMessage(ctx, &eventstreaming.ProducerMessage{
+				Topic: topic,
+				Value: []byte(fmt.Sprintf("TEST MSG #%d - %s", i, time.Now())),
+				Key:   []byte(strconv.Itoa(i % 10)),
+			})
+			if err != nil {
+				log.Printf("cannot send message: %v", err)
+			}
+		}
+	}
+}
+
 func executionLoop(ctx context.Context, consumerGroup eventstreaming.ConsumerGroup) {
 	sigusr1 := make(chan os.Signal, 1)
 	signal.Notify(sigusr1, syscall.SIGUSR1)
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"sync"
 	"syscall"
 	"time"
@@ -18,6 +19,7 @@ func main() {
 	log.Println("Starting event streaming producer example")
 
 	producerConfig := baseserv.DefaultEventStreamingProducerConfig()
+	producerConfig.Producer = &eventstreaming.ProducerConfig{}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -41,7 +43,7 @@ func main() {
 				case <-ctx.Done():
 					return
 				default:
-					produceTestRecords(producer, topic, 1000)
+					produceTestRecords(ctx, producer, topic, 1000)
 				}
 			}
 		}()
@@ -57,11 +59,18 @@ func main() {
 	}
 }
 
-func produceTestRecords(producer eventstreaming.MessageProducer, topic string, records int) {
+func produceTestRecords(ctx context.Context, producer eventstreaming.MessageProducer, topic string, records int) {
 	for i := 0; i < records; i++ {
 		now := fmt.Sprint(time.Now())
 		value := []byte(fmt.Sprintf("TEST MSG #%d - %s", i, now))
-		err := producer.SendMessage(&eventstreaming.ProducerMessage{Topic: topic, Value: value})
+		err := producer.SendMessage(ctx, &eventstreaming.ProducerMessage{
+			Topic: topic,
+			Value: value,
+			Key:   []byte(strconv.Itoa(i % 10)),
+			Headers: map[string]string{
+				"correlation-id": fmt.Sprintf("%s-%d", topic, i),
+			},
+		})
 		if err != nil {
 			fmt.Printf("cannot send message: %v", err)
 			return
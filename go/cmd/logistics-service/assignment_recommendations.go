@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
+	"github.com/*company-data-covered*/services/go/pkg/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/optimizer/optimizersettings"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/recommendations"
+	"github.com/*company-data-covered*/services/go/pkg/monitoring"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const rankingPolicyTag = "ranking_policy"
+
+// Order of shift teams by assignability, before their ranking cost.
+var assignableShiftTeamStatusOrder = map[logisticspb.AssignableShiftTeamResult_Status]int{
+	logisticspb.AssignableShiftTeamResult_STATUS_ASSIGNABLE:          0,
+	logisticspb.AssignableShiftTeamResult_STATUS_OVERRIDE_ASSIGNABLE: 1,
+	logisticspb.AssignableShiftTeamResult_STATUS_NOT_ASSIGNABLE:      2,
+	logisticspb.AssignableShiftTeamResult_STATUS_UNSPECIFIED:         3,
+}
+
+// careRequestsVisitParams returns the latest location and service duration of the care requests,
+// by care request ID.
+func (s *GRPCServer) careRequestsVisitParams(
+	ctx context.Context,
+	careRequestIDs []int64,
+	now time.Time,
+) (map[int64]recommendations.VisitParams, error) {
+	res := map[int64]recommendations.VisitParams{}
+	if len(careRequestIDs) == 0 {
+		return res, nil
+	}
+
+	careRequests, err := s.LogisticsDB.GetLatestCareRequestsDataForDiagnostics(ctx, careRequestIDs, now)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to get care requests data: %v", err)
+	}
+	for _, careRequest := range careRequests {
+		if careRequest.CareRequestID == nil {
+			continue
+		}
+
+		var params recommendations.VisitParams
+		if careRequest.ServiceDurationSec != nil {
+			params.ServiceDuration = time.Duration(*careRequest.ServiceDurationSec) * time.Second
+		}
+		if loc := careRequest.VisitLocation; loc != nil {
+			params.Location = &logistics.LatLng{LatE6: loc.LatitudeE6, LngE6: loc.LongitudeE6}
+		}
+		res[*careRequest.CareRequestID] = params
+	}
+	return res, nil
+}
+
+// shiftTeamRoutesForDate returns the routes of the latest schedule of the service date, by shift team ID.
+// There are no routes if the service date has not been scheduled yet.
+func (s *GRPCServer) shiftTeamRoutesForDate(
+	ctx context.Context,
+	serviceRegionID int64,
+	serviceDate time.Time,
+	now time.Time,
+) (map[int64]*logisticspb.ShiftTeamRoute, error) {
+	routes := map[int64]*logisticspb.ShiftTeamRoute{}
+	schedule, err := s.LogisticsDB.GetLatestScheduleForServiceRegionDate(ctx, serviceRegionID, serviceDate, now)
+	if errors.Is(err, logisticsdb.ErrScheduleNotFound) {
+		return routes, nil
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to get shift team schedules: %v", err)
+	}
+
+	for _, shiftTeamSchedule := range schedule.GetSchedules() {
+		routes[shiftTeamSchedule.GetShiftTeamId()] = shiftTeamSchedule.GetRoute()
+	}
+	return routes, nil
+}
+
+// driveDurations returns the drive durations to explain recommendations with,
+// or none if they could not be fetched, so that they are estimated instead.
+func (s *GRPCServer) driveDurations(ctx context.Context, params logisticsdb.GetDriveDurationsParams) logisticsdb.DriveDurations {
+	driveDurations, err := s.LogisticsDB.GetDriveDurations(ctx, params)
+	if err != nil {
+		s.logger().Warnw("Could not get drive durations for recommendations, estimating them",
+			"service_region_id", params.ServiceRegionID,
+			zap.Error(err))
+		return nil
+	}
+	return driveDurations
+}
+
+// logAssignmentRecommendations logs the recommendations shown. Failures are only logged,
+// as they do not affect the recommendations.
+func (s *GRPCServer) logAssignmentRecommendations(ctx context.Context, params logisticsdb.AddAssignmentRecommendationsParams) {
+	err := s.LogisticsDB.AddAssignmentRecommendations(ctx, params)
+	if err != nil {
+		s.logger().Errorw("Could not log assignment recommendations",
+			"ranking_policy", params.RankingPolicy.String(),
+			zap.Error(err))
+	}
+}
+
+func attributeMismatches(missingRequired, includedForbidden, missingPreferred, includedUnwanted int) (hard int, soft int) {
+	return missingRequired + includedForbidden, missingPreferred + includedUnwanted
+}
+
+type explainAssignableShiftTeamsParams struct {
+	visit           *logisticspb.AssignableVisit
+	arrivalTW       logisticsdb.TimeWindow
+	serviceRegionID int64
+	serviceDate     time.Time
+	rankingPolicy   logisticspb.AssignmentRankingPolicy
+	now             time.Time
+}
+
+// explainAssignableShiftTeams explains the assignable shift teams for the visit, ranks them by the ranking policy,
+// and logs them as recommendations shown for the visit. Drive times use the map service distances.
+//
+// Explanations are best effort: if the data to explain with can not be fetched, the results are left
+// unexplained in the optimizer order.
+func (s *GRPCServer) explainAssignableShiftTeams(
+	ctx context.Context,
+	params explainAssignableShiftTeamsParams,
+	results []*logisticspb.AssignableShiftTeamResult,
+) {
+	visitParams := recommendations.VisitParams{ArrivalTimeWindow: params.arrivalTW}
+	if params.visit.Id != nil {
+		careRequestsParams, err := s.careRequestsVisitParams(ctx, []int64{params.visit.GetId()}, params.now)
+		if err != nil {
+			s.logger().Warnw("Could not get care request to explain assignable shift teams",
+				"care_request_id", params.visit.GetId(),
+				zap.Error(err))
+			return
+		}
+		if careRequestParams, ok := careRequestsParams[params.visit.GetId()]; ok {
+			careRequestParams.ArrivalTimeWindow = params.arrivalTW
+			visitParams = careRequestParams
+		}
+	}
+
+	routes, err := s.shiftTeamRoutesForDate(ctx, params.serviceRegionID, params.serviceDate, params.now)
+	if err != nil {
+		s.logger().Warnw("Could not get shift team routes to explain assignable shift teams",
+			"service_region_id", params.serviceRegionID,
+			zap.Error(err))
+		return
+	}
+
+	var driveDurations logisticsdb.DriveDurations
+	if visitParams.Location != nil {
+		driveDurationsParams := logisticsdb.GetDriveDurationsParams{
+			ServiceRegionID: params.serviceRegionID,
+			ServiceDate:     params.serviceDate,
+			Locations:       []logistics.LatLng{*visitParams.Location},
+			Now:             params.now,
+		}
+		for _, result := range results {
+			if route := routes[result.GetShiftTeam().GetId()]; route != nil {
+				driveDurationsParams.Routes = append(driveDurationsParams.Routes, recommendations.RouteLocations(route, params.now))
+			}
+		}
+		driveDurations = s.driveDurations(ctx, driveDurationsParams)
+	}
+
+	explanations := make([]*logisticspb.AssignmentExplanation, len(results))
+	for i, result := range results {
+		hard, soft := attributeMismatches(
+			len(result.MissingRequiredAttributes),
+			len(result.IncludedForbiddenAttributes),
+			len(result.MissingPreferredAttributes),
+			len(result.IncludedUnwantedAttributes),
+		)
+		result.Explanation = recommendations.Explain(recommendations.ExplainParams{
+			Visit:                   visitParams,
+			Route:                   routes[result.GetShiftTeam().GetId()],
+			DriveDurations:          driveDurations,
+			HardAttributeMismatches: hard,
+			SoftAttributeMismatches: soft,
+			Now:                     params.now,
+		})
+		explanations[i] = result.Explanation
+	}
+
+	if recommendations.SetRankingCosts(params.rankingPolicy, explanations...) {
+		monitoring.AddGRPCTag(ctx, rankingPolicyTag, params.rankingPolicy.String())
+		sort.SliceStable(results, func(i, j int) bool {
+			iOrder := assignableShiftTeamStatusOrder[results[i].GetStatus()]
+			jOrder := assignableShiftTeamStatusOrder[results[j].GetStatus()]
+			if iOrder != jOrder {
+				return iOrder < jOrder
+			}
+			return results[i].GetExplanation().GetRankingCost() < results[j].GetExplanation().GetRankingCost()
+		})
+	}
+
+	if params.visit.Id == nil {
+		return
+	}
+	shown := make([]*logisticsdb.AssignmentRecommendation, len(results))
+	for i, result := range results {
+		shown[i] = &logisticsdb.AssignmentRecommendation{
+			CareRequestID: params.visit.GetId(),
+			ShiftTeamID:   result.GetShiftTeam().GetId(),
+			Explanation:   result.Explanation,
+		}
+	}
+	s.logAssignmentRecommendations(ctx, logisticsdb.AddAssignmentRecommendationsParams{
+		RankingPolicy:   params.rankingPolicy,
+		Recommendations: shown,
+	})
+}
+
+type explainAssignableVisitsParams struct {
+	shiftTeamID   *int64
+	rankingPolicy logisticspb.AssignmentRankingPolicy
+	now           time.Time
+}
+
+// explainAssignableVisits explains the assignable visits for the shift team, ranks them by the ranking policy,
+// and logs them as recommendations shown for the shift team.
+//
+// Drive times and effects on committed visits are only explained for a given shift team.
+// Explanations are best effort: if the data to explain with can not be fetched, the visits are
+// returned unexplained in the optimizer order.
+func (s *GRPCServer) explainAssignableVisits(
+	ctx context.Context,
+	params explainAssignableVisitsParams,
+	visits []*optimizerpb.AssignableVisitResult,
+) []*logisticspb.AssignableVisitResult {
+	if len(visits) == 0 {
+		return nil
+	}
+
+	careRequestIDs := make([]int64, len(visits))
+	unexplained := make([]*logisticspb.AssignableVisitResult, len(visits))
+	for i, visit := range visits {
+		careRequestIDs[i] = visit.GetVisit().GetId()
+		unexplained[i] = &logisticspb.AssignableVisitResult{CareRequestId: careRequestIDs[i]}
+	}
+
+	var route *logisticspb.ShiftTeamRoute
+	var driveDurations logisticsdb.DriveDurations
+	careRequestsParams := map[int64]recommendations.VisitParams{}
+	if params.shiftTeamID != nil {
+		schedule, err := s.LogisticsDB.GetLatestShiftTeamSchedule(
+			ctx,
+			*params.shiftTeamID,
+			logisticsdb.TimeWindow{
+				Start: params.now.Add(-optimizersettings.DefaultSnapshotsLookbackDuration()),
+				End:   params.now,
+			},
+		)
+		if err != nil {
+			s.logger().Warnw("Could not get shift team schedule to explain assignable visits",
+				"shift_team_id", *params.shiftTeamID,
+				zap.Error(err))
+			return unexplained
+		}
+		route = schedule.Schedule.GetRoute()
+
+		careRequestsParams, err = s.careRequestsVisitParams(ctx, careRequestIDs, params.now)
+		if err != nil {
+			s.logger().Warnw("Could not get care requests to explain assignable visits",
+				"shift_team_id", *params.shiftTeamID,
+				zap.Error(err))
+			return unexplained
+		}
+
+		if route != nil {
+			serviceDate := params.now
+			if date := logisticsdb.ProtoDateToTime(schedule.Metadata.GetServiceDate()); date != nil {
+				serviceDate = *date
+			}
+			driveDurationsParams := logisticsdb.GetDriveDurationsParams{
+				ServiceRegionID: schedule.ServiceRegionID,
+				ServiceDate:     serviceDate,
+				Routes:          [][]logistics.LatLng{recommendations.RouteLocations(route, params.now)},
+				Now:             params.now,
+			}
+			for _, careRequestParams := range careRequestsParams {
+				if careRequestParams.Location != nil {
+					driveDurationsParams.Locations = append(driveDurationsParams.Locations, *careRequestParams.Location)
+				}
+			}
+			driveDurations = s.driveDurations(ctx, driveDurationsParams)
+		}
+	}
+
+	results := make([]*logisticspb.AssignableVisitResult, len(visits))
+	explanations := make([]*logisticspb.AssignmentExplanation, len(visits))
+	for i, visit := range visits {
+		visitParams := careRequestsParams[careRequestIDs[i]]
+		arrivalTW := visit.GetVisit().GetArrivalTimeWindow()
+		visitParams.ArrivalTimeWindow = logisticsdb.TimeWindow{
+			Start: time.Unix(arrivalTW.GetStartTimestampSec(), 0),
+			End:   time.Unix(arrivalTW.GetEndTimestampSec(), 0),
+		}
+
+		hard, soft := attributeMismatches(
+			len(visit.MissingRequiredAttributes),
+			len(visit.IncludedForbiddenAttributes),
+			len(visit.MissingPreferredAttributes),
+			len(visit.IncludedUnwantedAttributes),
+		)
+		explanations[i] = recommendations.Explain(recommendations.ExplainParams{
+			Visit:                   visitParams,
+			Route:                   route,
+			DriveDurations:          driveDurations,
+			HardAttributeMismatches: hard,
+			SoftAttributeMismatches: soft,
+			Now:                     params.now,
+		})
+		results[i] = &logisticspb.AssignableVisitResult{
+			CareRequestId: careRequestIDs[i],
+			Explanation:   explanations[i],
+		}
+	}
+
+	if recommendations.SetRankingCosts(params.rankingPolicy, explanations...) {
+		monitoring.AddGRPCTag(ctx, rankingPolicyTag, params.rankingPolicy.String())
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].GetExplanation().GetRankingCost() < results[j].GetExplanation().GetRankingCost()
+		})
+	}
+
+	if params.shiftTeamID == nil {
+		return results
+	}
+	shown := make([]*logisticsdb.AssignmentRecommendation, len(results))
+	for i, result := range results {
+		shown[i] = &logisticsdb.AssignmentRecommendation{
+			CareRequestID: result.GetCareRequestId(),
+			ShiftTeamID:   *params.shiftTeamID,
+			Explanation:   result.Explanation,
+		}
+	}
+	s.logAssignmentRecommendations(ctx, logisticsdb.AddAssignmentRecommendationsParams{
+		RankingPolicy:   params.rankingPolicy,
+		Recommendations: shown,
+	})
+	return results
+}
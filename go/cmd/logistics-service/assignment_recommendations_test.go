@@ -0,0 +1,388 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	commonpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/common"
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"google.golang.org/protobuf/proto"
+)
+
+func assignmentRecommendationsTestRoute(departure time.Time, lngE6 int32) *logisticspb.ShiftTeamRoute {
+	return &logisticspb.ShiftTeamRoute{
+		BaseLocation:                      &commonpb.Location{LatitudeE6: 0, LongitudeE6: lngE6},
+		BaseLocationDepartureTimestampSec: proto.Int64(departure.Unix()),
+	}
+}
+
+func TestGRPCServer_explainAssignableShiftTeams(t *testing.T) {
+	now := time.Date(2023, time.September, 16, 7, 0, 0, 0, time.UTC)
+	serviceDate := time.Date(2023, time.September, 16, 0, 0, 0, 0, time.UTC)
+	openHoursStart := now.Add(time.Hour)
+	careRequestID := int64(1)
+	visit := &logisticspb.AssignableVisit{Id: proto.Int64(careRequestID), MarketId: proto.Int64(2)}
+	arrivalTW := logisticsdb.TimeWindow{Start: openHoursStart, End: openHoursStart.Add(4 * time.Hour)}
+
+	validLDB := func() *MockLogisticsDB {
+		return &MockLogisticsDB{
+			GetLatestCareRequestsDataForDiagnosticsResult: []*logisticsdb.CareRequestDiagnostics{
+				{
+					CareRequestID:      proto.Int64(careRequestID),
+					ServiceDurationSec: proto.Int64(1800),
+					VisitLocation:      &logisticssql.Location{LatitudeE6: 0, LongitudeE6: 0},
+				},
+			},
+			GetLatestScheduleForServiceRegionDateResult: &logisticspb.ServiceRegionDateSchedule{
+				Meta: &logisticspb.ScheduleMetadata{ServiceDate: logisticsdb.TimeToProtoDate(&serviceDate)},
+				Schedules: []*logisticspb.ShiftTeamSchedule{
+					{ShiftTeamId: 1, Route: assignmentRecommendationsTestRoute(openHoursStart, 10000)},
+					{ShiftTeamId: 2, Route: assignmentRecommendationsTestRoute(openHoursStart, 50000)},
+					{ShiftTeamId: 3, Route: assignmentRecommendationsTestRoute(openHoursStart, 20000)},
+				},
+			},
+		}
+	}
+	results := func() []*logisticspb.AssignableShiftTeamResult {
+		return []*logisticspb.AssignableShiftTeamResult{
+			{
+				ShiftTeam: &logisticspb.AssignableShiftTeam{Id: proto.Int64(1)},
+				Status:    logisticspb.AssignableShiftTeamResult_STATUS_NOT_ASSIGNABLE.Enum(),
+			},
+			{
+				ShiftTeam: &logisticspb.AssignableShiftTeam{Id: proto.Int64(2)},
+				Status:    logisticspb.AssignableShiftTeamResult_STATUS_ASSIGNABLE.Enum(),
+			},
+			{
+				ShiftTeam: &logisticspb.AssignableShiftTeam{Id: proto.Int64(3)},
+				Status:    logisticspb.AssignableShiftTeamResult_STATUS_ASSIGNABLE.Enum(),
+			},
+		}
+	}
+
+	tcs := []struct {
+		Desc          string
+		Visit         *logisticspb.AssignableVisit
+		RankingPolicy logisticspb.AssignmentRankingPolicy
+		LDB           *MockLogisticsDB
+
+		ExpectedShiftTeamIDs   []int64
+		ExpectedUnexplained    bool
+		ExpectedDriveDurations bool
+		ExpectedRankingCosts   bool
+		ExpectedLogged         bool
+	}{
+		{
+			Desc:          "ranked",
+			Visit:         visit,
+			RankingPolicy: logisticspb.AssignmentRankingPolicy_ASSIGNMENT_RANKING_POLICY_MINIMIZE_DRIVE_TIME,
+			LDB:           validLDB(),
+
+			ExpectedShiftTeamIDs:   []int64{3, 2, 1},
+			ExpectedDriveDurations: true,
+			ExpectedRankingCosts:   true,
+			ExpectedLogged:         true,
+		},
+		{
+			Desc:  "unspecified policy keeps optimizer order",
+			Visit: visit,
+			LDB:   validLDB(),
+
+			ExpectedShiftTeamIDs:   []int64{1, 2, 3},
+			ExpectedDriveDurations: true,
+			ExpectedLogged:         true,
+		},
+		{
+			Desc:          "visit without care request is not logged",
+			Visit:         &logisticspb.AssignableVisit{MarketId: visit.MarketId},
+			RankingPolicy: logisticspb.AssignmentRankingPolicy_ASSIGNMENT_RANKING_POLICY_BALANCED,
+			LDB:           validLDB(),
+
+			ExpectedShiftTeamIDs: []int64{2, 3, 1},
+			ExpectedRankingCosts: true,
+		},
+		{
+			Desc:          "schedule error leaves shift teams unexplained",
+			Visit:         visit,
+			RankingPolicy: logisticspb.AssignmentRankingPolicy_ASSIGNMENT_RANKING_POLICY_MINIMIZE_DRIVE_TIME,
+			LDB: func() *MockLogisticsDB {
+				ldb := validLDB()
+				ldb.GetLatestScheduleForServiceRegionDateErr = errors.New("boo")
+				return ldb
+			}(),
+
+			ExpectedShiftTeamIDs: []int64{1, 2, 3},
+			ExpectedUnexplained:  true,
+		},
+		{
+			Desc:          "care request error leaves shift teams unexplained",
+			Visit:         visit,
+			RankingPolicy: logisticspb.AssignmentRankingPolicy_ASSIGNMENT_RANKING_POLICY_MINIMIZE_DRIVE_TIME,
+			LDB: func() *MockLogisticsDB {
+				ldb := validLDB()
+				ldb.GetLatestCareRequestsDataForDiagnosticsError = errors.New("boo")
+				return ldb
+			}(),
+
+			ExpectedShiftTeamIDs: []int64{1, 2, 3},
+			ExpectedUnexplained:  true,
+		},
+		{
+			Desc:  "unscheduled service date explains without routes",
+			Visit: visit,
+			LDB: func() *MockLogisticsDB {
+				ldb := validLDB()
+				ldb.GetLatestScheduleForServiceRegionDateResult = nil
+				ldb.GetLatestScheduleForServiceRegionDateErr = logisticsdb.ErrScheduleNotFound
+				return ldb
+			}(),
+
+			ExpectedShiftTeamIDs: []int64{1, 2, 3},
+			ExpectedLogged:       true,
+		},
+		{
+			Desc:          "ranked by known drive durations",
+			Visit:         visit,
+			RankingPolicy: logisticspb.AssignmentRankingPolicy_ASSIGNMENT_RANKING_POLICY_MINIMIZE_DRIVE_TIME,
+			LDB: func() *MockLogisticsDB {
+				ldb := validLDB()
+				visitLocation := logistics.LatLng{LatE6: 0, LngE6: 0}
+				farBaseLocation := logistics.LatLng{LatE6: 0, LngE6: 50000}
+				ldb.GetDriveDurationsResult = logisticsdb.DriveDurations{
+					{From: farBaseLocation, To: visitLocation}: time.Minute,
+					{From: visitLocation, To: farBaseLocation}: time.Minute,
+				}
+				return ldb
+			}(),
+
+			ExpectedShiftTeamIDs:   []int64{2, 3, 1},
+			ExpectedDriveDurations: true,
+			ExpectedRankingCosts:   true,
+			ExpectedLogged:         true,
+		},
+		{
+			Desc:  "drive durations error estimates them",
+			Visit: visit,
+			LDB: func() *MockLogisticsDB {
+				ldb := validLDB()
+				ldb.GetDriveDurationsErr = errors.New("boo")
+				return ldb
+			}(),
+
+			ExpectedShiftTeamIDs:   []int64{1, 2, 3},
+			ExpectedDriveDurations: true,
+			ExpectedLogged:         true,
+		},
+		{
+			Desc:  "log error still explains",
+			Visit: visit,
+			LDB: func() *MockLogisticsDB {
+				ldb := validLDB()
+				ldb.AddAssignmentRecommendationsErr = errors.New("boo")
+				return ldb
+			}(),
+
+			ExpectedShiftTeamIDs:   []int64{1, 2, 3},
+			ExpectedDriveDurations: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			s := &GRPCServer{LogisticsDB: tc.LDB}
+			shiftTeams := results()
+
+			s.explainAssignableShiftTeams(context.Background(), explainAssignableShiftTeamsParams{
+				visit:         tc.Visit,
+				arrivalTW:     arrivalTW,
+				serviceDate:   serviceDate,
+				rankingPolicy: tc.RankingPolicy,
+				now:           now,
+			}, shiftTeams)
+
+			var shiftTeamIDs []int64
+			for _, shiftTeam := range shiftTeams {
+				shiftTeamIDs = append(shiftTeamIDs, shiftTeam.GetShiftTeam().GetId())
+				testutils.MustMatch(t, tc.ExpectedUnexplained, shiftTeam.Explanation == nil)
+				if shiftTeam.Explanation == nil {
+					continue
+				}
+				testutils.MustMatch(t, tc.ExpectedDriveDurations, shiftTeam.GetExplanation().AddedDriveDurationSec != nil)
+				testutils.MustMatch(t, tc.ExpectedRankingCosts, shiftTeam.GetExplanation().RankingCost != nil)
+			}
+			testutils.MustMatch(t, tc.ExpectedShiftTeamIDs, shiftTeamIDs)
+
+			if !tc.ExpectedLogged {
+				testutils.MustMatch(t, 0, len(tc.LDB.AddedAssignmentRecommendations))
+				return
+			}
+			testutils.MustMatch(t, 1, len(tc.LDB.AddedAssignmentRecommendations))
+			logged := tc.LDB.AddedAssignmentRecommendations[0]
+			testutils.MustMatch(t, tc.RankingPolicy, logged.RankingPolicy)
+			for i, recommendation := range logged.Recommendations {
+				testutils.MustMatch(t, careRequestID, recommendation.CareRequestID)
+				testutils.MustMatch(t, tc.ExpectedShiftTeamIDs[i], recommendation.ShiftTeamID)
+			}
+		})
+	}
+}
+
+func TestGRPCServer_explainAssignableVisits(t *testing.T) {
+	now := time.Date(2023, time.September, 16, 7, 0, 0, 0, time.UTC)
+	openHoursStart := now.Add(time.Hour)
+	shiftTeamID := int64(3)
+	arrivalTW := &optimizerpb.VRPTimeWindow{
+		StartTimestampSec: proto.Int64(openHoursStart.Unix()),
+		EndTimestampSec:   proto.Int64(openHoursStart.Add(4 * time.Hour).Unix()),
+	}
+	visits := []*optimizerpb.AssignableVisitResult{
+		{
+			Visit:                     &optimizerpb.AssignableVisit{Id: proto.Int64(1), ArrivalTimeWindow: arrivalTW},
+			Status:                    optimizerpb.AssignableStatus_ASSIGNABLE_STATUS_OVERRIDE_ASSIGNABLE,
+			MissingRequiredAttributes: []*optimizerpb.VRPAttribute{{Id: "required"}},
+		},
+		{
+			Visit:  &optimizerpb.AssignableVisit{Id: proto.Int64(2), ArrivalTimeWindow: arrivalTW},
+			Status: optimizerpb.AssignableStatus_ASSIGNABLE_STATUS_ASSIGNABLE,
+		},
+	}
+	validLDB := func() *MockLogisticsDB {
+		return &MockLogisticsDB{
+			GetLatestShiftTeamScheduleResult: &logisticsdb.ShiftTeamSchedule{
+				Schedule: &logisticspb.ShiftTeamSchedule{
+					ShiftTeamId: shiftTeamID,
+					Route:       assignmentRecommendationsTestRoute(openHoursStart, 0),
+				},
+			},
+			GetLatestCareRequestsDataForDiagnosticsResult: []*logisticsdb.CareRequestDiagnostics{
+				{
+					CareRequestID: proto.Int64(1),
+					VisitLocation: &logisticssql.Location{LatitudeE6: 0, LongitudeE6: 10000},
+				},
+				{
+					CareRequestID: proto.Int64(2),
+					VisitLocation: &logisticssql.Location{LatitudeE6: 0, LongitudeE6: 20000},
+				},
+			},
+		}
+	}
+
+	tcs := []struct {
+		Desc          string
+		ShiftTeamID   *int64
+		RankingPolicy logisticspb.AssignmentRankingPolicy
+		LDB           *MockLogisticsDB
+
+		ExpectedCareRequestIDs []int64
+		ExpectedUnexplained    bool
+		ExpectedDriveDurations bool
+		ExpectedLogged         bool
+	}{
+		{
+			Desc:          "ranked for shift team",
+			ShiftTeamID:   proto.Int64(shiftTeamID),
+			RankingPolicy: logisticspb.AssignmentRankingPolicy_ASSIGNMENT_RANKING_POLICY_BALANCED,
+			LDB:           validLDB(),
+
+			ExpectedCareRequestIDs: []int64{2, 1},
+			ExpectedDriveDurations: true,
+			ExpectedLogged:         true,
+		},
+		{
+			Desc:          "ranked without shift team",
+			RankingPolicy: logisticspb.AssignmentRankingPolicy_ASSIGNMENT_RANKING_POLICY_BALANCED,
+			LDB:           &MockLogisticsDB{},
+
+			ExpectedCareRequestIDs: []int64{2, 1},
+		},
+		{
+			Desc:        "unspecified policy keeps optimizer order",
+			ShiftTeamID: proto.Int64(shiftTeamID),
+			LDB:         validLDB(),
+
+			ExpectedCareRequestIDs: []int64{1, 2},
+			ExpectedDriveDurations: true,
+			ExpectedLogged:         true,
+		},
+		{
+			Desc:          "schedule error leaves visits unexplained",
+			ShiftTeamID:   proto.Int64(shiftTeamID),
+			RankingPolicy: logisticspb.AssignmentRankingPolicy_ASSIGNMENT_RANKING_POLICY_BALANCED,
+			LDB:           &MockLogisticsDB{GetLatestShiftTeamScheduleErr: errors.New("boo")},
+
+			ExpectedCareRequestIDs: []int64{1, 2},
+			ExpectedUnexplained:    true,
+		},
+		{
+			Desc:          "care requests error leaves visits unexplained",
+			ShiftTeamID:   proto.Int64(shiftTeamID),
+			RankingPolicy: logisticspb.AssignmentRankingPolicy_ASSIGNMENT_RANKING_POLICY_BALANCED,
+			LDB: func() *MockLogisticsDB {
+				ldb := validLDB()
+				ldb.GetLatestCareRequestsDataForDiagnosticsError = errors.New("boo")
+				return ldb
+			}(),
+
+			ExpectedCareRequestIDs: []int64{1, 2},
+			ExpectedUnexplained:    true,
+		},
+		{
+			Desc:        "log error still explains",
+			ShiftTeamID: proto.Int64(shiftTeamID),
+			LDB: func() *MockLogisticsDB {
+				ldb := validLDB()
+				ldb.AddAssignmentRecommendationsErr = errors.New("boo")
+				return ldb
+			}(),
+
+			ExpectedCareRequestIDs: []int64{1, 2},
+			ExpectedDriveDurations: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			s := &GRPCServer{LogisticsDB: tc.LDB}
+
+			results := s.explainAssignableVisits(context.Background(), explainAssignableVisitsParams{
+				shiftTeamID:   tc.ShiftTeamID,
+				rankingPolicy: tc.RankingPolicy,
+				now:           now,
+			}, visits)
+
+			var careRequestIDs []int64
+			for _, result := range results {
+				careRequestIDs = append(careRequestIDs, result.GetCareRequestId())
+				testutils.MustMatch(t, tc.ExpectedUnexplained, result.Explanation == nil)
+				if result.Explanation == nil {
+					continue
+				}
+				testutils.MustMatch(t, tc.ExpectedDriveDurations, result.GetExplanation().AddedDriveDurationSec != nil)
+
+				expectedHardAttributeMismatches := int32(0)
+				if result.GetCareRequestId() == 1 {
+					expectedHardAttributeMismatches = 1
+				}
+				testutils.MustMatch(t, expectedHardAttributeMismatches, result.GetExplanation().GetHardAttributeMismatches())
+			}
+			testutils.MustMatch(t, tc.ExpectedCareRequestIDs, careRequestIDs)
+
+			if !tc.ExpectedLogged {
+				testutils.MustMatch(t, 0, len(tc.LDB.AddedAssignmentRecommendations))
+				return
+			}
+			testutils.MustMatch(t, 1, len(tc.LDB.AddedAssignmentRecommendations))
+			for i, recommendation := range tc.LDB.AddedAssignmentRecommendations[0].Recommendations {
+				testutils.MustMatch(t, shiftTeamID, recommendation.ShiftTeamID)
+				testutils.MustMatch(t, tc.ExpectedCareRequestIDs[i], recommendation.CareRequestID)
+			}
+		})
+	}
+}
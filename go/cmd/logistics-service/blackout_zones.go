@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/generated/proto/common"
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Time window of blackout zones listed when none is requested.
+const defaultBlackoutZonesListDuration = 30 * 24 * time.Hour
+
+func blackoutZoneParamsFromProto(zone *logisticspb.BlackoutZone) (logisticsdb.BlackoutZoneParams, error) {
+	if zone == nil {
+		return logisticsdb.BlackoutZoneParams{}, status.Errorf(codes.InvalidArgument, "blackout zone required")
+	}
+	if zone.StartTimestampSec == nil || zone.EndTimestampSec == nil {
+		return logisticsdb.BlackoutZoneParams{}, status.Errorf(codes.InvalidArgument, "blackout zone time window required")
+	}
+
+	return logisticsdb.BlackoutZoneParams{
+		Name:     zone.GetName(),
+		Vertices: zone.GetVertices(),
+		TimeWindow: logisticsdb.TimeWindow{
+			Start: time.Unix(zone.GetStartTimestampSec(), 0),
+			End:   time.Unix(zone.GetEndTimestampSec(), 0),
+		},
+	}, nil
+}
+
+func blackoutZoneError(err error, msgHeader string) error {
+	switch {
+	case errors.Is(err, logisticsdb.ErrBlackoutZoneNotFound):
+		return status.Errorf(codes.NotFound, "%s: %v", msgHeader, err)
+	case errors.Is(err, logisticsdb.ErrInvalidBlackoutZonePolygon), errors.Is(err, logisticsdb.ErrInvalidBlackoutZoneWindow):
+		return status.Errorf(codes.InvalidArgument, "%s: %v", msgHeader, err)
+	}
+	return status.Errorf(codes.Internal, "%s: %v", msgHeader, err)
+}
+
+func (s *GRPCServer) CreateBlackoutZone(
+	ctx context.Context,
+	req *logisticspb.CreateBlackoutZoneRequest,
+) (*logisticspb.CreateBlackoutZoneResponse, error) {
+	if req.MarketId == nil {
+		return nil, errMarketIDRequired
+	}
+	params, err := blackoutZoneParamsFromProto(req.GetZone())
+	if err != nil {
+		return nil, err
+	}
+
+	serviceRegion, err := s.LogisticsDB.GetServiceRegionForStationMarketID(ctx, req.GetMarketId())
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, serviceRegionNotFoundForMarketID, err)
+	}
+
+	zone, err := s.LogisticsDB.AddBlackoutZone(ctx, serviceRegion.ID, params)
+	if err != nil {
+		return nil, blackoutZoneError(err, "could not create blackout zone")
+	}
+
+	return &logisticspb.CreateBlackoutZoneResponse{Zone: zone.ToProto()}, nil
+}
+
+func (s *GRPCServer) UpdateBlackoutZone(
+	ctx context.Context,
+	req *logisticspb.UpdateBlackoutZoneRequest,
+) (*logisticspb.UpdateBlackoutZoneResponse, error) {
+	if req.GetZone().GetId() == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "blackout zone id required")
+	}
+	params, err := blackoutZoneParamsFromProto(req.GetZone())
+	if err != nil {
+		return nil, err
+	}
+
+	zone, err := s.LogisticsDB.UpdateBlackoutZone(ctx, req.GetZone().GetId(), params)
+	if err != nil {
+		return nil, blackoutZoneError(err, "could not update blackout zone")
+	}
+
+	return &logisticspb.UpdateBlackoutZoneResponse{Zone: zone.ToProto()}, nil
+}
+
+func (s *GRPCServer) DeleteBlackoutZone(
+	ctx context.Context,
+	req *logisticspb.DeleteBlackoutZoneRequest,
+) (*logisticspb.DeleteBlackoutZoneResponse, error) {
+	if req.BlackoutZoneId == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "blackout zone id required")
+	}
+
+	err := s.LogisticsDB.DeleteBlackoutZone(ctx, req.GetBlackoutZoneId())
+	if err != nil {
+		return nil, blackoutZoneError(err, "could not delete blackout zone")
+	}
+
+	return &logisticspb.DeleteBlackoutZoneResponse{}, nil
+}
+
+func (s *GRPCServer) ListBlackoutZones(
+	ctx context.Context,
+	req *logisticspb.ListBlackoutZonesRequest,
+) (*logisticspb.ListBlackoutZonesResponse, error) {
+	if req.MarketId == nil {
+		return nil, errMarketIDRequired
+	}
+
+	now := s.now()
+	tw := logisticsdb.TimeWindow{Start: now, End: now.Add(defaultBlackoutZonesListDuration)}
+	if req.StartTimestampSec != nil {
+		tw.Start = time.Unix(req.GetStartTimestampSec(), 0)
+	}
+	if req.EndTimestampSec != nil {
+		tw.End = time.Unix(req.GetEndTimestampSec(), 0)
+	}
+	if !tw.Start.Before(tw.End) {
+		return nil, status.Errorf(codes.InvalidArgument, "start of the time window must be before its end")
+	}
+
+	serviceRegion, err := s.LogisticsDB.GetServiceRegionForStationMarketID(ctx, req.GetMarketId())
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, serviceRegionNotFoundForMarketID, err)
+	}
+
+	zones, err := s.LogisticsDB.GetBlackoutZonesInServiceRegion(ctx, serviceRegion.ID, tw)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not list blackout zones: %s", err)
+	}
+
+	res := make([]*logisticspb.BlackoutZone, len(zones))
+	for i, zone := range zones {
+		res[i] = zone.ToProto()
+	}
+	return &logisticspb.ListBlackoutZonesResponse{Zones: res}, nil
+}
+
+// checkFeasibilityVisitArrivalTimeWindow returns the arrival time window of the visit clamped to its longest part
+// outside the blackout zones containing the visit location, as the optimizer does for the visits it schedules.
+// Visits without arrival time window are checked during the open hours, and get a nil time window.
+// If zones are active during the whole time window, it returns the first of them instead.
+func checkFeasibilityVisitArrivalTimeWindow(
+	visit *logisticspb.CheckFeasibilityVisit,
+	vrpData *logisticsdb.ServiceRegionVRPData,
+) (*common.TimeWindow, *logisticsdb.BlackoutZone, error) {
+	arrivalTW := *vrpData.OpenHoursTW
+	tw := visit.GetArrivalTimeWindow()
+	if tw != nil {
+		vrpTW, err := logisticsdb.VRPTimeWindowFromTimeWindow(tw)
+		if err != nil {
+			return nil, nil, err
+		}
+		arrivalTW = logisticsdb.TimeWindow{
+			Start: time.Unix(vrpTW.GetStartTimestampSec(), 0),
+			End:   time.Unix(vrpTW.GetEndTimestampSec(), 0),
+		}
+	}
+
+	location := visit.GetLocation()
+	if location == nil || len(vrpData.BlackoutZones) == 0 {
+		return tw, nil, nil
+	}
+
+	availableTW, zone := vrpData.BlackoutZones.AvailableTimeWindow(
+		logistics.LatLng{LatE6: location.GetLatitudeE6(), LngE6: location.GetLongitudeE6()},
+		arrivalTW,
+	)
+	if availableTW == nil {
+		return nil, zone, nil
+	}
+	if tw == nil || *availableTW == arrivalTW {
+		return tw, nil, nil
+	}
+
+	tz, err := time.LoadLocation(tw.GetStartDatetime().GetTimeZone().GetId())
+	if err != nil {
+		return nil, nil, err
+	}
+	start := availableTW.Start.In(tz)
+	end := availableTW.End.In(tz)
+	return &common.TimeWindow{
+		StartDatetime: logisticsdb.TimeToProtoDateTime(&start),
+		EndDatetime:   logisticsdb.TimeToProtoDateTime(&end),
+	}, nil, nil
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	commonpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/common"
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func blackoutZonesTestZone(start time.Time) *logisticsdb.BlackoutZone {
+	return &logisticsdb.BlackoutZone{
+		ID:   1,
+		Name: "Flooding",
+		Polygon: logistics.Polygon{
+			{LatE6: 0, LngE6: 0},
+			{LatE6: 0, LngE6: 10},
+			{LatE6: 10, LngE6: 10},
+			{LatE6: 10, LngE6: 0},
+		},
+		TimeWindow: logisticsdb.TimeWindow{Start: start, End: start.Add(2 * time.Hour)},
+	}
+}
+
+func TestCheckFeasibilityVisitArrivalTimeWindow(t *testing.T) {
+	openHoursStart := time.Date(2023, time.September, 15, 8, 0, 0, 0, time.UTC)
+	zone := blackoutZonesTestZone(openHoursStart.Add(4 * time.Hour))
+	vrpData := &logisticsdb.ServiceRegionVRPData{
+		OpenHoursTW:   &logisticsdb.TimeWindow{Start: openHoursStart, End: openHoursStart.Add(10 * time.Hour)},
+		BlackoutZones: logisticsdb.BlackoutZones{zone},
+	}
+	timeWindow := func(start time.Time, end time.Time) *commonpb.TimeWindow {
+		return &commonpb.TimeWindow{
+			StartDatetime: logisticsdb.TimeToProtoDateTime(&start),
+			EndDatetime:   logisticsdb.TimeToProtoDateTime(&end),
+		}
+	}
+	arrivalTimeWindow := func(start time.Time) *logisticspb.CheckFeasibilityVisit_ArrivalTimeWindow {
+		return &logisticspb.CheckFeasibilityVisit_ArrivalTimeWindow{
+			ArrivalTimeWindow: timeWindow(start, start.Add(2*time.Hour)),
+		}
+	}
+	inside := &commonpb.Location{LatitudeE6: 5, LongitudeE6: 5}
+
+	tcs := []struct {
+		Desc  string
+		Visit *logisticspb.CheckFeasibilityVisit
+
+		ExpectedTimeWindow *commonpb.TimeWindow
+		ExpectedZone       *logisticsdb.BlackoutZone
+	}{
+		{
+			Desc: "inside zone during whole arrival time window",
+			Visit: &logisticspb.CheckFeasibilityVisit{
+				Location:                 inside,
+				ArrivalTimeSpecification: arrivalTimeWindow(openHoursStart.Add(4 * time.Hour)),
+			},
+
+			ExpectedZone: zone,
+		},
+		{
+			Desc: "inside zone during part of arrival time window",
+			Visit: &logisticspb.CheckFeasibilityVisit{
+				Location:                 inside,
+				ArrivalTimeSpecification: arrivalTimeWindow(openHoursStart.Add(3 * time.Hour)),
+			},
+
+			ExpectedTimeWindow: timeWindow(openHoursStart.Add(3*time.Hour), zone.TimeWindow.Start),
+		},
+		{
+			Desc: "inside zone before it is active",
+			Visit: &logisticspb.CheckFeasibilityVisit{
+				Location:                 inside,
+				ArrivalTimeSpecification: arrivalTimeWindow(openHoursStart),
+			},
+
+			ExpectedTimeWindow: arrivalTimeWindow(openHoursStart).ArrivalTimeWindow,
+		},
+		{
+			Desc: "inside zone during part of open hours",
+			Visit: &logisticspb.CheckFeasibilityVisit{
+				Location: inside,
+				ArrivalTimeSpecification: &logisticspb.CheckFeasibilityVisit_ArrivalDate{
+					ArrivalDate: &commonpb.Date{Year: 2023, Month: 9, Day: 15},
+				},
+			},
+		},
+		{
+			Desc: "outside zone",
+			Visit: &logisticspb.CheckFeasibilityVisit{
+				Location:                 &commonpb.Location{LatitudeE6: 50, LongitudeE6: 5},
+				ArrivalTimeSpecification: arrivalTimeWindow(openHoursStart.Add(4 * time.Hour)),
+			},
+
+			ExpectedTimeWindow: arrivalTimeWindow(openHoursStart.Add(4 * time.Hour)).ArrivalTimeWindow,
+		},
+		{
+			Desc: "no location",
+			Visit: &logisticspb.CheckFeasibilityVisit{
+				ArrivalTimeSpecification: arrivalTimeWindow(openHoursStart.Add(4 * time.Hour)),
+			},
+
+			ExpectedTimeWindow: arrivalTimeWindow(openHoursStart.Add(4 * time.Hour)).ArrivalTimeWindow,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			tw, zone, err := checkFeasibilityVisitArrivalTimeWindow(tc.Visit, vrpData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			testutils.MustMatch(t, tc.ExpectedTimeWindow, tw)
+			testutils.MustMatch(t, tc.ExpectedZone, zone)
+		})
+	}
+}
+
+func TestCheckFeasibilityBlackedOutLocation(t *testing.T) {
+	openHoursStart := time.Date(2023, time.September, 15, 8, 0, 0, 0, time.UTC)
+	zone := blackoutZonesTestZone(openHoursStart)
+	zone.TimeWindow.End = openHoursStart.Add(10 * time.Hour)
+	req := &logisticspb.CheckFeasibilityRequest{
+		Visits: []*logisticspb.CheckFeasibilityVisit{{
+			MarketId: proto.Int64(1),
+			ArrivalTimeSpecification: &logisticspb.CheckFeasibilityVisit_ArrivalDate{
+				ArrivalDate: &commonpb.Date{Year: 2023, Month: 9, Day: 15},
+			},
+			Location:           &commonpb.Location{LatitudeE6: 5, LongitudeE6: 5},
+			IsManualAdjustment: true,
+		}},
+	}
+	s := &GRPCServer{
+		LogisticsDB: &MockLogisticsDB{
+			GetServiceRegionForStationMarketIDResult: &logisticssql.ServiceRegion{ID: 2, IanaTimeZoneName: "America/Denver"},
+			GetServiceRegionVRPDataResult: &logisticsdb.ServiceRegionVRPData{
+				OpenHoursTW:   &logisticsdb.TimeWindow{Start: openHoursStart, End: openHoursStart.Add(10 * time.Hour)},
+				BlackoutZones: logisticsdb.BlackoutZones{zone},
+				CheckFeasibilityData: &logisticsdb.CheckFeasibilityVRPDataResult{
+					Visits: req.Visits,
+					LocIDs: []int64{3},
+				},
+			},
+		},
+	}
+
+	resp, err := s.CheckFeasibility(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testutils.MustMatch(t, &logisticspb.CheckFeasibilityResponse{
+		Status:       logisticspb.CheckFeasibilityResponse_STATUS_LOCATION_BLACKED_OUT,
+		BlackoutZone: zone.ToProto(),
+	}, resp)
+}
+
+func TestGRPCServer_CreateBlackoutZone(t *testing.T) {
+	start := time.Date(2023, time.September, 15, 8, 0, 0, 0, time.UTC)
+	zone := blackoutZonesTestZone(start)
+	validReq := &logisticspb.CreateBlackoutZoneRequest{
+		MarketId: proto.Int64(1),
+		Zone:     zone.ToProto(),
+	}
+	validLDB := &MockLogisticsDB{
+		GetServiceRegionForStationMarketIDResult: &logisticssql.ServiceRegion{ID: 2},
+		AddBlackoutZoneResult:                    zone,
+	}
+
+	tcs := []struct {
+		Desc string
+		Req  *logisticspb.CreateBlackoutZoneRequest
+		LDB  *MockLogisticsDB
+
+		ErrCode      codes.Code
+		ExpectedResp *logisticspb.CreateBlackoutZoneResponse
+	}{
+		{
+			Desc: "base case",
+			Req:  validReq,
+			LDB:  validLDB,
+
+			ExpectedResp: &logisticspb.CreateBlackoutZoneResponse{Zone: zone.ToProto()},
+		},
+		{
+			Desc: "no market id",
+			Req:  &logisticspb.CreateBlackoutZoneRequest{Zone: validReq.Zone},
+			LDB:  validLDB,
+
+			ErrCode: codes.InvalidArgument,
+		},
+		{
+			Desc: "no time window",
+			Req: &logisticspb.CreateBlackoutZoneRequest{
+				MarketId: validReq.MarketId,
+				Zone:     &logisticspb.BlackoutZone{Vertices: validReq.Zone.Vertices},
+			},
+			LDB: validLDB,
+
+			ErrCode: codes.InvalidArgument,
+		},
+		{
+			Desc: "no service region for market id",
+			Req:  validReq,
+			LDB:  &MockLogisticsDB{GetServiceRegionForStationMarketIDErr: errors.New("bad mkt")},
+
+			ErrCode: codes.NotFound,
+		},
+		{
+			Desc: "invalid polygon",
+			Req:  validReq,
+			LDB: &MockLogisticsDB{
+				GetServiceRegionForStationMarketIDResult: &logisticssql.ServiceRegion{ID: 2},
+				AddBlackoutZoneErr:                       logisticsdb.ErrInvalidBlackoutZonePolygon,
+			},
+
+			ErrCode: codes.InvalidArgument,
+		},
+		{
+			Desc: "add fails",
+			Req:  validReq,
+			LDB: &MockLogisticsDB{
+				GetServiceRegionForStationMarketIDResult: &logisticssql.ServiceRegion{ID: 2},
+				AddBlackoutZoneErr:                       errors.New("no add"),
+			},
+
+			ErrCode: codes.Internal,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			s := &GRPCServer{LogisticsDB: tc.LDB}
+
+			resp, err := s.CreateBlackoutZone(context.Background(), tc.Req)
+			testutils.MustMatch(t, tc.ErrCode, status.Code(err))
+			testutils.MustMatch(t, tc.ExpectedResp, resp)
+		})
+	}
+}
+
+func TestGRPCServer_UpdateAndDeleteBlackoutZone(t *testing.T) {
+	zone := blackoutZonesTestZone(time.Date(2023, time.September, 15, 8, 0, 0, 0, time.UTC))
+
+	tcs := []struct {
+		Desc   string
+		ID     *int64
+		NoZone bool
+		LDB    *MockLogisticsDB
+
+		ErrCode codes.Code
+	}{
+		{
+			Desc: "base case",
+			ID:   proto.Int64(zone.ID),
+			LDB:  &MockLogisticsDB{UpdateBlackoutZoneResult: zone},
+		},
+		{
+			Desc: "no id",
+			LDB:  &MockLogisticsDB{UpdateBlackoutZoneResult: zone},
+
+			ErrCode: codes.InvalidArgument,
+		},
+		{
+			Desc:   "no zone",
+			NoZone: true,
+			LDB:    &MockLogisticsDB{UpdateBlackoutZoneResult: zone},
+
+			ErrCode: codes.InvalidArgument,
+		},
+		{
+			Desc: "unknown zone",
+			ID:   proto.Int64(zone.ID),
+			LDB: &MockLogisticsDB{
+				UpdateBlackoutZoneErr: logisticsdb.ErrBlackoutZoneNotFound,
+				DeleteBlackoutZoneErr: logisticsdb.ErrBlackoutZoneNotFound,
+			},
+
+			ErrCode: codes.NotFound,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			s := &GRPCServer{LogisticsDB: tc.LDB}
+			zoneProto := zone.ToProto()
+			zoneProto.Id = tc.ID
+			if tc.NoZone {
+				zoneProto = nil
+			}
+
+			_, err := s.UpdateBlackoutZone(context.Background(), &logisticspb.UpdateBlackoutZoneRequest{Zone: zoneProto})
+			testutils.MustMatch(t, tc.ErrCode, status.Code(err))
+
+			_, err = s.DeleteBlackoutZone(context.Background(), &logisticspb.DeleteBlackoutZoneRequest{BlackoutZoneId: tc.ID})
+			testutils.MustMatch(t, tc.ErrCode, status.Code(err))
+		})
+	}
+}
+
+func TestGRPCServer_ListBlackoutZones(t *testing.T) {
+	zone := blackoutZonesTestZone(time.Date(2023, time.September, 15, 8, 0, 0, 0, time.UTC))
+	validLDB := &MockLogisticsDB{
+		GetServiceRegionForStationMarketIDResult: &logisticssql.ServiceRegion{ID: 2},
+		GetBlackoutZonesInServiceRegionResult:    logisticsdb.BlackoutZones{zone},
+	}
+
+	tcs := []struct {
+		Desc string
+		Req  *logisticspb.ListBlackoutZonesRequest
+		LDB  *MockLogisticsDB
+
+		ErrCode      codes.Code
+		ExpectedResp *logisticspb.ListBlackoutZonesResponse
+	}{
+		{
+			Desc: "base case",
+			Req:  &logisticspb.ListBlackoutZonesRequest{MarketId: proto.Int64(1)},
+			LDB:  validLDB,
+
+			ExpectedResp: &logisticspb.ListBlackoutZonesResponse{Zones: []*logisticspb.BlackoutZone{zone.ToProto()}},
+		},
+		{
+			Desc: "no market id",
+			Req:  &logisticspb.ListBlackoutZonesRequest{},
+			LDB:  validLDB,
+
+			ErrCode: codes.InvalidArgument,
+		},
+		{
+			Desc: "invalid time window",
+			Req: &logisticspb.ListBlackoutZonesRequest{
+				MarketId:          proto.Int64(1),
+				StartTimestampSec: proto.Int64(10),
+				EndTimestampSec:   proto.Int64(5),
+			},
+			LDB: validLDB,
+
+			ErrCode: codes.InvalidArgument,
+		},
+		{
+			Desc: "list fails",
+			Req:  &logisticspb.ListBlackoutZonesRequest{MarketId: proto.Int64(1)},
+			LDB: &MockLogisticsDB{
+				GetServiceRegionForStationMarketIDResult: &logisticssql.ServiceRegion{ID: 2},
+				GetBlackoutZonesInServiceRegionErr:       errors.New("no list"),
+			},
+
+			ErrCode: codes.Internal,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			s := &GRPCServer{LogisticsDB: tc.LDB}
+
+			resp, err := s.ListBlackoutZones(context.Background(), tc.Req)
+			testutils.MustMatch(t, tc.ErrCode, status.Code(err))
+			testutils.MustMatch(t, tc.ExpectedResp, resp)
+		})
+	}
+}
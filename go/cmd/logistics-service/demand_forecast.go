@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/demandforecast"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultDemandForecastDays         = 7
+	maxDemandForecastDays             = 28
+	defaultDemandForecastHistoryWeeks = 4
+	maxDemandForecastHistoryWeeks     = 12
+)
+
+func demandForecastHourToProto(hour *demandforecast.Hour) *logisticspb.DemandForecastHour {
+	return &logisticspb.DemandForecastHour{
+		StartTimestampSec:           hour.Hour.Unix(),
+		RequiredAttributeNames:      hour.AttributeNames,
+		ExpectedAvailabilityQueries: hour.AvailabilityQueries,
+		ExpectedFeasibilityQueries:  hour.FeasibilityQueries,
+		ExpectedFailureRate:         hour.FailureRate,
+		ExpectedCompletedVisits:     hour.CompletedVisits,
+	}
+}
+
+// GetServiceRegionDemandForecast forecasts the hourly demand of the service region for the next days,
+// from the demand recorded on the same weekdays of the previous weeks.
+func (s *GRPCServer) GetServiceRegionDemandForecast(
+	ctx context.Context,
+	req *logisticspb.GetServiceRegionDemandForecastRequest,
+) (*logisticspb.GetServiceRegionDemandForecastResponse, error) {
+	if req.MarketId == nil {
+		return nil, errMarketIDRequired
+	}
+	days := defaultDemandForecastDays
+	if req.Days != nil {
+		days = int(req.GetDays())
+		if days <= 0 || days > maxDemandForecastDays {
+			return nil, status.Errorf(codes.InvalidArgument, "days must be between 1 and %d", maxDemandForecastDays)
+		}
+	}
+	historyWeeks := defaultDemandForecastHistoryWeeks
+	if req.HistoryWeeks != nil {
+		historyWeeks = int(req.GetHistoryWeeks())
+		if historyWeeks <= 0 || historyWeeks > maxDemandForecastHistoryWeeks {
+			return nil, status.Errorf(codes.InvalidArgument, "history weeks must be between 1 and %d", maxDemandForecastHistoryWeeks)
+		}
+	}
+
+	serviceRegion, err := s.LogisticsDB.GetServiceRegionForStationMarketID(ctx, req.GetMarketId())
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, serviceRegionNotFoundForMarketID, err)
+	}
+	tz, err := time.LoadLocation(serviceRegion.IanaTimeZoneName)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "invalid service region time zone: %v", err)
+	}
+
+	start := dateInTimezone(s.now(), tz)
+	history, err := s.LogisticsDB.GetServiceRegionDemandHistory(ctx, serviceRegion.ID, demandforecast.HistoryWindow(start, historyWeeks))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not get demand history: %v", err)
+	}
+
+	hours := demandforecast.Forecast(demandforecast.Params{
+		History:      history,
+		HistoryWeeks: historyWeeks,
+		Start:        start,
+		Days:         days,
+		Location:     tz,
+	})
+	res := make([]*logisticspb.DemandForecastHour, len(hours))
+	for i, hour := range hours {
+		res[i] = demandForecastHourToProto(hour)
+	}
+	return &logisticspb.GetServiceRegionDemandForecastResponse{Hours: res}, nil
+}
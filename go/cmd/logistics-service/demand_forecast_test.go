@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestGRPCServer_GetServiceRegionDemandForecast(t *testing.T) {
+	tz, err := time.LoadLocation("America/Denver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2023, time.September, 16, 10, 0, 0, 0, tz)
+	today := time.Date(2023, time.September, 16, 0, 0, 0, 0, tz)
+	serviceRegion := &logisticssql.ServiceRegion{ID: 2, IanaTimeZoneName: "America/Denver"}
+	history := []*logisticsdb.DemandHour{
+		{
+			Hour:                      today.AddDate(0, 0, -7).Add(9 * time.Hour),
+			AttributeNames:            []string{"covid"},
+			AvailabilityQueries:       8,
+			FailedAvailabilityQueries: 2,
+			CompletedVisits:           4,
+		},
+	}
+
+	tcs := []struct {
+		Desc string
+		Req  *logisticspb.GetServiceRegionDemandForecastRequest
+		LDB  *MockLogisticsDB
+
+		ErrCode      codes.Code
+		ExpectedResp *logisticspb.GetServiceRegionDemandForecastResponse
+	}{
+		{
+			Desc: "base case",
+			Req: &logisticspb.GetServiceRegionDemandForecastRequest{
+				MarketId:     proto.Int64(1),
+				Days:         proto.Int32(8),
+				HistoryWeeks: proto.Int32(2),
+			},
+			LDB: &MockLogisticsDB{
+				GetServiceRegionForStationMarketIDResult: serviceRegion,
+				GetServiceRegionDemandHistoryResult:      history,
+			},
+
+			ErrCode: codes.OK,
+			ExpectedResp: &logisticspb.GetServiceRegionDemandForecastResponse{
+				Hours: []*logisticspb.DemandForecastHour{
+					{
+						StartTimestampSec:           today.Add(9 * time.Hour).Unix(),
+						RequiredAttributeNames:      []string{"covid"},
+						ExpectedAvailabilityQueries: 4,
+						ExpectedFailureRate:         0.25,
+						ExpectedCompletedVisits:     2,
+					},
+					{
+						StartTimestampSec:           today.AddDate(0, 0, 7).Add(9 * time.Hour).Unix(),
+						RequiredAttributeNames:      []string{"covid"},
+						ExpectedAvailabilityQueries: 4,
+						ExpectedFailureRate:         0.25,
+						ExpectedCompletedVisits:     2,
+					},
+				},
+			},
+		},
+		{
+			Desc: "no history",
+			Req:  &logisticspb.GetServiceRegionDemandForecastRequest{MarketId: proto.Int64(1)},
+			LDB: &MockLogisticsDB{
+				GetServiceRegionForStationMarketIDResult: serviceRegion,
+			},
+
+			ErrCode:      codes.OK,
+			ExpectedResp: &logisticspb.GetServiceRegionDemandForecastResponse{Hours: []*logisticspb.DemandForecastHour{}},
+		},
+		{
+			Desc: "missing market id",
+			Req:  &logisticspb.GetServiceRegionDemandForecastRequest{},
+			LDB:  &MockLogisticsDB{},
+
+			ErrCode: codes.InvalidArgument,
+		},
+		{
+			Desc: "too many days",
+			Req: &logisticspb.GetServiceRegionDemandForecastRequest{
+				MarketId: proto.Int64(1),
+				Days:     proto.Int32(maxDemandForecastDays + 1),
+			},
+			LDB: &MockLogisticsDB{},
+
+			ErrCode: codes.InvalidArgument,
+		},
+		{
+			Desc: "invalid history weeks",
+			Req: &logisticspb.GetServiceRegionDemandForecastRequest{
+				MarketId:     proto.Int64(1),
+				HistoryWeeks: proto.Int32(0),
+			},
+			LDB: &MockLogisticsDB{},
+
+			ErrCode: codes.InvalidArgument,
+		},
+		{
+			Desc: "unknown market",
+			Req:  &logisticspb.GetServiceRegionDemandForecastRequest{MarketId: proto.Int64(1)},
+			LDB: &MockLogisticsDB{
+				GetServiceRegionForStationMarketIDErr: errors.New("boo"),
+			},
+
+			ErrCode: codes.NotFound,
+		},
+		{
+			Desc: "demand history error",
+			Req:  &logisticspb.GetServiceRegionDemandForecastRequest{MarketId: proto.Int64(1)},
+			LDB: &MockLogisticsDB{
+				GetServiceRegionForStationMarketIDResult: serviceRegion,
+				GetServiceRegionDemandHistoryErr:         errors.New("boo"),
+			},
+
+			ErrCode: codes.Internal,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			s := &GRPCServer{LogisticsDB: tc.LDB, Clock: MockClock(now)}
+
+			resp, err := s.GetServiceRegionDemandForecast(context.Background(), tc.Req)
+			testutils.MustMatch(t, tc.ErrCode, status.Code(err))
+			testutils.MustMatch(t, tc.ExpectedResp, resp)
+		})
+	}
+}
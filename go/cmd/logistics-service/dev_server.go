@@ -4,6 +4,7 @@ import (
 	"crypto/sha1"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"math/rand"
@@ -14,6 +15,7 @@ import (
 	"time"
 
 	"github.com/*company-data-covered*/services/go/pkg/buildinfo"
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
 	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
 	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
 	"github.com/*company-data-covered*/services/go/pkg/logistics"
@@ -106,6 +108,73 @@ func (s *DevServer) exampleVRPBounds(w http.ResponseWriter, r *http.Request) {
 	w.Write(buf)
 }
 
+func (s *DevServer) blackoutZones(w http.ResponseWriter, r *http.Request) {
+	marketID, err := strconv.ParseInt(r.URL.Query().Get("market_id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	resp, err := s.GRPCServer.ListBlackoutZones(r.Context(), &logisticspb.ListBlackoutZonesRequest{
+		MarketId: proto.Int64(marketID),
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	buf, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(resp)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(contentTypeHeader, jsonContentType)
+	w.Write(buf)
+}
+
+func (s *DevServer) routePlayback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var params logisticsdb.RoutePlaybackParams
+	var err error
+	switch {
+	case query.Get("schedule_id") != "":
+		params.ScheduleID, err = strconv.ParseInt(query.Get("schedule_id"), 10, 64)
+	case query.Get("optimizer_run_id") != "":
+		params.OptimizerRunID, err = strconv.ParseInt(query.Get("optimizer_run_id"), 10, 64)
+	default:
+		err = errors.New("schedule_id or optimizer_run_id is required")
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	playback, err := s.LogisticsDB.GetRoutePlayback(r.Context(), params)
+	if err != nil {
+		if errors.Is(err, logisticsdb.ErrScheduleNotFound) || errors.Is(err, logisticsdb.ErrUnknownOptimizerRunID) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	buf, err := json.Marshal(playback)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(contentTypeHeader, jsonContentType)
+	w.Write(buf)
+}
+
 func (s *DevServer) exampleVRP(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultETAStreamDebounce        = 5 * time.Second
+	defaultETAStreamRefreshInterval = 1 * time.Minute
+	defaultETAStreamChangeThreshold = 2 * time.Minute
+)
+
+// ETANotifier notifies the ETA streams of this server of new shift team locations and care request updates.
+// Safe for concurrent use; a nil ETANotifier never notifies.
+type ETANotifier struct {
+	mu              sync.Mutex
+	careRequestSubs map[int64]map[*etaSubscription]bool
+	shiftTeamSubs   map[int64]map[*etaSubscription]bool
+	subShiftTeamIDs map[*etaSubscription]int64
+}
+
+func NewETANotifier() *ETANotifier {
+	return &ETANotifier{
+		careRequestSubs: map[int64]map[*etaSubscription]bool{},
+		shiftTeamSubs:   map[int64]map[*etaSubscription]bool{},
+		subShiftTeamIDs: map[*etaSubscription]int64{},
+	}
+}
+
+type etaSubscription struct {
+	careRequestID int64
+	// Pending notification; notifications received while one is pending are coalesced.
+	c chan struct{}
+}
+
+func (sub *etaSubscription) notify() {
+	select {
+	case sub.c <- struct{}{}:
+	default:
+	}
+}
+
+func (n *ETANotifier) subscribe(careRequestID int64) *etaSubscription {
+	sub := &etaSubscription{
+		careRequestID: careRequestID,
+		c:             make(chan struct{}, 1),
+	}
+	if n == nil {
+		return sub
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	addETASubscription(n.careRequestSubs, careRequestID, sub)
+	return sub
+}
+
+// watchShiftTeam makes the subscription also follow the locations of shiftTeamID, instead of any previous shift team.
+func (n *ETANotifier) watchShiftTeam(sub *etaSubscription, shiftTeamID int64) {
+	if n == nil {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if prevShiftTeamID, ok := n.subShiftTeamIDs[sub]; ok {
+		if prevShiftTeamID == shiftTeamID {
+			return
+		}
+		removeETASubscription(n.shiftTeamSubs, prevShiftTeamID, sub)
+	}
+	n.subShiftTeamIDs[sub] = shiftTeamID
+	addETASubscription(n.shiftTeamSubs, shiftTeamID, sub)
+}
+
+func (n *ETANotifier) unsubscribe(sub *etaSubscription) {
+	if n == nil {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	removeETASubscription(n.careRequestSubs, sub.careRequestID, sub)
+	if shiftTeamID, ok := n.subShiftTeamIDs[sub]; ok {
+		removeETASubscription(n.shiftTeamSubs, shiftTeamID, sub)
+		delete(n.subShiftTeamIDs, sub)
+	}
+}
+
+// NotifyShiftTeam notifies the streams following the shift team of a new location.
+func (n *ETANotifier) NotifyShiftTeam(shiftTeamID int64) {
+	if n == nil {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for sub := range n.shiftTeamSubs[shiftTeamID] {
+		sub.notify()
+	}
+}
+
+// NotifyCareRequest notifies the streams of the care request of an update, such as a visit phase change.
+func (n *ETANotifier) NotifyCareRequest(careRequestID int64) {
+	if n == nil {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for sub := range n.careRequestSubs[careRequestID] {
+		sub.notify()
+	}
+}
+
+func addETASubscription(subs map[int64]map[*etaSubscription]bool, id int64, sub *etaSubscription) {
+	if subs[id] == nil {
+		subs[id] = map[*etaSubscription]bool{}
+	}
+	subs[id][sub] = true
+}
+
+func removeETASubscription(subs map[int64]map[*etaSubscription]bool, id int64, sub *etaSubscription) {
+	delete(subs[id], sub)
+	if len(subs[id]) == 0 {
+		delete(subs, id)
+	}
+}
+
+func (cfg *GRPCServerConfig) etaStreamDebounce() time.Duration {
+	if cfg.ETAStreamDebounce > 0 {
+		return cfg.ETAStreamDebounce
+	}
+	return defaultETAStreamDebounce
+}
+
+func (cfg *GRPCServerConfig) etaStreamRefreshInterval() time.Duration {
+	if cfg.ETAStreamRefreshInterval > 0 {
+		return cfg.ETAStreamRefreshInterval
+	}
+	return defaultETAStreamRefreshInterval
+}
+
+func (cfg *GRPCServerConfig) etaStreamChangeThreshold() time.Duration {
+	if cfg.ETAStreamChangeThreshold > 0 {
+		return cfg.ETAStreamChangeThreshold
+	}
+	return defaultETAStreamChangeThreshold
+}
+
+// StreamCareRequestETA sends an update whenever the ETA or the visit phase of a care request changes.
+// ETAs are recomputed when this server records a new location of the care request's shift team or
+// a care request update, at most once per debounce interval, and at least once per refresh interval
+// to catch updates recorded by other servers.
+//
+// The stream only ends once the visit is no longer scheduled; recomputations that fail are skipped,
+// keeping the last ETA sent.
+func (s *GRPCServer) StreamCareRequestETA(
+	req *logisticspb.StreamCareRequestETARequest,
+	stream logisticspb.LogisticsService_StreamCareRequestETAServer,
+) error {
+	if req.CareRequestId == nil {
+		return status.Error(codes.InvalidArgument, "care request id must be set")
+	}
+	if req.GetSignificantChangeThresholdSec() < 0 {
+		return status.Error(codes.InvalidArgument, "significant change threshold must not be negative")
+	}
+
+	ctx := stream.Context()
+	careRequestID := req.GetCareRequestId()
+	threshold := s.Cfg.etaStreamChangeThreshold()
+	if req.SignificantChangeThresholdSec != nil {
+		threshold = time.Duration(req.GetSignificantChangeThresholdSec()) * time.Second
+	}
+
+	sub := s.ETANotifier.subscribe(careRequestID)
+	defer s.ETANotifier.unsubscribe(sub)
+
+	tracker := &etaChangeTracker{threshold: threshold}
+	for {
+		computedAt := time.Now()
+		update, done, err := s.careRequestETAUpdate(ctx, sub, tracker)
+		if err != nil {
+			return err
+		}
+		if update != nil {
+			if err := stream.Send(update); err != nil {
+				return err
+			}
+		}
+		if done {
+			return nil
+		}
+
+		if err := waitForETAUpdate(ctx, sub, computedAt, s.Cfg.etaStreamDebounce(), s.Cfg.etaStreamRefreshInterval()); err != nil {
+			return status.FromContextError(err).Err()
+		}
+	}
+}
+
+// careRequestETAUpdate returns the next update to send, if any, and whether the stream is done.
+// Only an unknown care request is an error; other failures are logged and produce no update.
+func (s *GRPCServer) careRequestETAUpdate(
+	ctx context.Context,
+	sub *etaSubscription,
+	tracker *etaChangeTracker,
+) (*logisticspb.StreamCareRequestETAResponse, bool, error) {
+	latestTimestamp := s.now()
+	careRequestLatestInfo, err := s.LogisticsDB.GetLatestInfoForCareRequest(ctx, sub.careRequestID, latestTimestamp)
+	if err != nil {
+		if errors.Is(err, logisticsdb.ErrUnknownCareRequest) {
+			return nil, false, status.Errorf(codes.NotFound, "unknown care request: %v", err)
+		}
+		s.logger().Warnw("Could not get care request for ETA stream, skipping update",
+			"care_request_id", sub.careRequestID,
+			zap.Error(err))
+		return nil, false, nil
+	}
+	s.ETANotifier.watchShiftTeam(sub, careRequestLatestInfo.ShiftTeamID)
+
+	visitPhaseShortName := logisticsdb.VisitPhaseShortName(careRequestLatestInfo.VisitPhaseShortName)
+	phase, ok := logisticsdb.VisitPhaseShortNameToPhases[visitPhaseShortName]
+	if !ok {
+		s.logger().Warnw("Unknown visit phase for ETA stream, skipping update",
+			"care_request_id", sub.careRequestID,
+			"visit_phase", visitPhaseShortName.String())
+		return nil, false, nil
+	}
+
+	var eta *logisticspb.GetCareRequestETAResponse
+	var done bool
+	switch visitPhaseShortName {
+	case logisticsdb.VisitPhaseTypeShortNameUncommitted,
+		logisticsdb.VisitPhaseTypeShortNameCommitted,
+		logisticsdb.VisitPhaseTypeShortNameEnRoute:
+		eta, err = s.etaFromCareRequestLatestInfo(ctx, careRequestLatestInfo, latestTimestamp)
+		if err != nil {
+			s.logger().Warnw("Could not compute ETA for ETA stream, skipping update",
+				"care_request_id", sub.careRequestID,
+				zap.Error(err))
+			return nil, false, nil
+		}
+	case logisticsdb.VisitPhaseTypeShortNameOnScene,
+		logisticsdb.VisitPhaseTypeShortNameCompleted,
+		logisticsdb.VisitPhaseTypeShortNameCancelled:
+		done = true
+	}
+
+	return tracker.update(phase, eta), done, nil
+}
+
+// waitForETAUpdate waits for a notification or the refresh interval, keeping at least the debounce interval since lastComputedAt.
+func waitForETAUpdate(ctx context.Context, sub *etaSubscription, lastComputedAt time.Time, debounce, refreshInterval time.Duration) error {
+	refresh := time.NewTimer(time.Until(lastComputedAt.Add(refreshInterval)))
+	defer refresh.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-refresh.C:
+		return nil
+	case <-sub.c:
+	}
+
+	debounceTimer := time.NewTimer(time.Until(lastComputedAt.Add(debounce)))
+	defer debounceTimer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-debounceTimer.C:
+		// Notifications received while debouncing are covered by the next computation.
+		select {
+		case <-sub.c:
+		default:
+		}
+		return nil
+	}
+}
+
+// etaChangeTracker tracks the updates sent on an ETA stream.
+type etaChangeTracker struct {
+	threshold time.Duration
+
+	lastSent        *logisticspb.StreamCareRequestETAResponse
+	lastSignificant *logisticspb.StreamCareRequestETAResponse
+}
+
+// update returns the update to send for the latest phase and ETA, or nil if nothing changed since the last update.
+func (t *etaChangeTracker) update(phase logisticspb.VisitPhase, eta *logisticspb.GetCareRequestETAResponse) *logisticspb.StreamCareRequestETAResponse {
+	update := &logisticspb.StreamCareRequestETAResponse{
+		Eta:   eta,
+		Phase: phase.Enum(),
+	}
+	if t.lastSent != nil && proto.Equal(t.lastSent.Eta, eta) && t.lastSent.GetPhase() == phase {
+		return nil
+	}
+
+	significant := t.lastSignificant == nil ||
+		t.lastSignificant.GetPhase() != phase ||
+		(t.lastSignificant.Eta == nil) != (eta == nil)
+	if t.lastSignificant != nil && t.lastSignificant.Eta != nil && eta != nil {
+		changeSec := eta.GetEstimatedArrivalTimestampSec() - t.lastSignificant.Eta.GetEstimatedArrivalTimestampSec()
+		update.EtaChangeSec = proto.Int64(changeSec)
+		if changeSec < 0 {
+			changeSec = -changeSec
+		}
+		significant = significant || time.Duration(changeSec)*time.Second >= t.threshold
+	}
+	update.SignificantChange = proto.Bool(significant)
+
+	t.lastSent = update
+	if significant {
+		t.lastSignificant = update
+	}
+
+	return update
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+	"github.com/*company-data-covered*/services/go/pkg/monitoring"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+type mockStreamCareRequestETAStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	maxSent int
+	sent    []*logisticspb.StreamCareRequestETAResponse
+}
+
+func newMockStreamCareRequestETAStream(maxSent int) *mockStreamCareRequestETAStream {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &mockStreamCareRequestETAStream{
+		ctx:     ctx,
+		cancel:  cancel,
+		maxSent: maxSent,
+	}
+}
+
+func (m *mockStreamCareRequestETAStream) Context() context.Context {
+	return m.ctx
+}
+
+func (m *mockStreamCareRequestETAStream) Send(resp *logisticspb.StreamCareRequestETAResponse) error {
+	m.sent = append(m.sent, resp)
+	if len(m.sent) >= m.maxSent {
+		m.cancel()
+	}
+	return nil
+}
+
+func TestStreamCareRequestETA(t *testing.T) {
+	careRequestID := proto.Int64(1)
+	duration := 1 * time.Hour
+	now := time.Now()
+
+	tcs := []struct {
+		Desc   string
+		Input  *logisticspb.StreamCareRequestETARequest
+		MockDB *MockLogisticsDB
+
+		ExpectedStatusCode codes.Code
+		ExpectedResponses  []*logisticspb.StreamCareRequestETAResponse
+	}{
+		{
+			Desc:  "en route care request streams realtime ETA",
+			Input: &logisticspb.StreamCareRequestETARequest{CareRequestId: careRequestID},
+			MockDB: &MockLogisticsDB{
+				GetLatestInfoForCareRequestResult: &logisticsdb.CareRequestLatestInfo{
+					VisitPhaseShortName: logisticsdb.VisitPhaseTypeShortNameEnRoute.String(),
+					ShiftTeamID:         2,
+					VisitLocation:       &logisticssql.Location{LatitudeE6: 1, LongitudeE6: 2},
+					ShiftTeamLocation:   &logisticssql.Location{LatitudeE6: 3, LongitudeE6: 4},
+				}},
+
+			ExpectedStatusCode: codes.Canceled,
+			ExpectedResponses: []*logisticspb.StreamCareRequestETAResponse{
+				{
+					Eta: &logisticspb.GetCareRequestETAResponse{
+						EstimatedArrivalTimestampSec: proto.Int64(now.Add(duration).Unix()),
+						Precision:                    logisticspb.GetCareRequestETAResponse_PRECISION_EN_ROUTE_REALTIME.Enum(),
+					},
+					Phase:             logisticspb.VisitPhase_VISIT_PHASE_EN_ROUTE.Enum(),
+					SignificantChange: proto.Bool(true),
+				},
+			},
+		},
+		{
+			Desc:  "on scene care request ends stream",
+			Input: &logisticspb.StreamCareRequestETARequest{CareRequestId: careRequestID},
+			MockDB: &MockLogisticsDB{
+				GetLatestInfoForCareRequestResult: &logisticsdb.CareRequestLatestInfo{
+					VisitPhaseShortName: logisticsdb.VisitPhaseTypeShortNameOnScene.String(),
+				}},
+
+			ExpectedStatusCode: codes.OK,
+			ExpectedResponses: []*logisticspb.StreamCareRequestETAResponse{
+				{
+					Phase:             logisticspb.VisitPhase_VISIT_PHASE_ON_SCENE.Enum(),
+					SignificantChange: proto.Bool(true),
+				},
+			},
+		},
+		{
+			Desc:  "CareRequest ID missing",
+			Input: &logisticspb.StreamCareRequestETARequest{},
+
+			ExpectedStatusCode: codes.InvalidArgument,
+		},
+		{
+			Desc: "negative threshold",
+			Input: &logisticspb.StreamCareRequestETARequest{
+				CareRequestId:                 careRequestID,
+				SignificantChangeThresholdSec: proto.Int64(-1),
+			},
+
+			ExpectedStatusCode: codes.InvalidArgument,
+		},
+		{
+			Desc:   "unknown care request",
+			Input:  &logisticspb.StreamCareRequestETARequest{CareRequestId: careRequestID},
+			MockDB: &MockLogisticsDB{GetLatestInfoForCareRequestErr: logisticsdb.ErrUnknownCareRequest},
+
+			ExpectedStatusCode: codes.NotFound,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			mapService := &MockMapService{GetRouteResult: &logistics.Route{Distance: logistics.Distance{Duration: duration}}}
+			s := GRPCServer{
+				LogisticsDB:      tc.MockDB,
+				MapServicePicker: logistics.NewMapServicePicker(mapService, nil, mapService, mockSettingsService, monitoring.NewMockScope()),
+				ETANotifier:      NewETANotifier(),
+				Clock:            MockClock(now),
+			}
+			stream := newMockStreamCareRequestETAStream(1)
+			err := s.StreamCareRequestETA(tc.Input, stream)
+			testutils.MustMatch(t, tc.ExpectedStatusCode, status.Code(err))
+			testutils.MustMatch(t, tc.ExpectedResponses, stream.sent)
+			testutils.MustMatch(t, 0, len(s.ETANotifier.careRequestSubs)+len(s.ETANotifier.shiftTeamSubs), "unsubscribed")
+		})
+	}
+}
+
+// flakyRouteMapService fails the first failures GetRoute calls.
+type flakyRouteMapService struct {
+	*MockMapService
+
+	mu       sync.Mutex
+	failures int
+	calls    int
+}
+
+func (m *flakyRouteMapService) GetRoute(ctx context.Context, tags monitoring.Tags, latLngs ...logistics.LatLng) (*logistics.Route, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls++
+	if m.calls <= m.failures {
+		return nil, errors.New("map service unavailable")
+	}
+	return m.MockMapService.GetRoute(ctx, tags, latLngs...)
+}
+
+func TestStreamCareRequestETA_SkipsFailedUpdates(t *testing.T) {
+	now := time.Now()
+	duration := 1 * time.Hour
+	mapService := &flakyRouteMapService{
+		MockMapService: &MockMapService{GetRouteResult: &logistics.Route{Distance: logistics.Distance{Duration: duration}}},
+		failures:       2,
+	}
+	s := GRPCServer{
+		LogisticsDB: &MockLogisticsDB{
+			GetLatestInfoForCareRequestResult: &logisticsdb.CareRequestLatestInfo{
+				VisitPhaseShortName: logisticsdb.VisitPhaseTypeShortNameEnRoute.String(),
+				ShiftTeamID:         2,
+				VisitLocation:       &logisticssql.Location{LatitudeE6: 1, LongitudeE6: 2},
+				ShiftTeamLocation:   &logisticssql.Location{LatitudeE6: 3, LongitudeE6: 4},
+			}},
+		MapServicePicker: logistics.NewMapServicePicker(mapService, nil, mapService, mockSettingsService, monitoring.NewMockScope()),
+		ETANotifier:      NewETANotifier(),
+		Clock:            MockClock(now),
+		Cfg: GRPCServerConfig{
+			ETAStreamDebounce:        time.Millisecond,
+			ETAStreamRefreshInterval: 10 * time.Millisecond,
+		},
+	}
+	stream := newMockStreamCareRequestETAStream(1)
+
+	err := s.StreamCareRequestETA(&logisticspb.StreamCareRequestETARequest{CareRequestId: proto.Int64(1)}, stream)
+
+	testutils.MustMatch(t, codes.Canceled, status.Code(err), "map service errors should not end the stream")
+	testutils.MustMatch(t, 3, mapService.calls)
+	testutils.MustMatch(t, []*logisticspb.StreamCareRequestETAResponse{
+		{
+			Eta: &logisticspb.GetCareRequestETAResponse{
+				EstimatedArrivalTimestampSec: proto.Int64(now.Add(duration).Unix()),
+				Precision:                    logisticspb.GetCareRequestETAResponse_PRECISION_EN_ROUTE_REALTIME.Enum(),
+			},
+			Phase:             logisticspb.VisitPhase_VISIT_PHASE_EN_ROUTE.Enum(),
+			SignificantChange: proto.Bool(true),
+		},
+	}, stream.sent)
+}
+
+func TestETANotifier(t *testing.T) {
+	careRequestID := int64(1)
+	shiftTeamID := int64(2)
+	otherShiftTeamID := int64(3)
+
+	n := NewETANotifier()
+	sub := n.subscribe(careRequestID)
+	n.watchShiftTeam(sub, shiftTeamID)
+
+	n.NotifyShiftTeam(shiftTeamID)
+	n.NotifyCareRequest(careRequestID)
+	testutils.MustMatch(t, 1, len(sub.c), "notifications are coalesced")
+	<-sub.c
+
+	n.watchShiftTeam(sub, otherShiftTeamID)
+	n.NotifyShiftTeam(shiftTeamID)
+	testutils.MustMatch(t, 0, len(sub.c), "previous shift team is not followed")
+	n.NotifyShiftTeam(otherShiftTeamID)
+	testutils.MustMatch(t, 1, len(sub.c))
+	<-sub.c
+
+	n.unsubscribe(sub)
+	n.NotifyCareRequest(careRequestID)
+	testutils.MustMatch(t, 0, len(sub.c))
+	testutils.MustMatch(t, 0, len(n.careRequestSubs)+len(n.shiftTeamSubs)+len(n.subShiftTeamIDs))
+
+	var nilNotifier *ETANotifier
+	nilNotifier.NotifyShiftTeam(shiftTeamID)
+	nilNotifier.unsubscribe(nilNotifier.subscribe(careRequestID))
+}
+
+func TestETAChangeTracker(t *testing.T) {
+	enRoute := logisticspb.VisitPhase_VISIT_PHASE_EN_ROUTE
+	onScene := logisticspb.VisitPhase_VISIT_PHASE_ON_SCENE
+	eta := func(sec int64) *logisticspb.GetCareRequestETAResponse {
+		return &logisticspb.GetCareRequestETAResponse{
+			EstimatedArrivalTimestampSec: proto.Int64(sec),
+			Precision:                    logisticspb.GetCareRequestETAResponse_PRECISION_EN_ROUTE_REALTIME.Enum(),
+		}
+	}
+
+	tracker := &etaChangeTracker{threshold: time.Minute}
+	steps := []struct {
+		Desc  string
+		Phase logisticspb.VisitPhase
+		ETA   *logisticspb.GetCareRequestETAResponse
+
+		Want *logisticspb.StreamCareRequestETAResponse
+	}{
+		{
+			Desc:  "first update",
+			Phase: enRoute,
+			ETA:   eta(1000),
+
+			Want: &logisticspb.StreamCareRequestETAResponse{
+				Eta:               eta(1000),
+				Phase:             enRoute.Enum(),
+				SignificantChange: proto.Bool(true),
+			},
+		},
+		{
+			Desc:  "no change",
+			Phase: enRoute,
+			ETA:   eta(1000),
+		},
+		{
+			Desc:  "small change",
+			Phase: enRoute,
+			ETA:   eta(1030),
+
+			Want: &logisticspb.StreamCareRequestETAResponse{
+				Eta:               eta(1030),
+				Phase:             enRoute.Enum(),
+				EtaChangeSec:      proto.Int64(30),
+				SignificantChange: proto.Bool(false),
+			},
+		},
+		{
+			Desc:  "small changes add up",
+			Phase: enRoute,
+			ETA:   eta(940),
+
+			Want: &logisticspb.StreamCareRequestETAResponse{
+				Eta:               eta(940),
+				Phase:             enRoute.Enum(),
+				EtaChangeSec:      proto.Int64(-60),
+				SignificantChange: proto.Bool(true),
+			},
+		},
+		{
+			Desc:  "phase change",
+			Phase: onScene,
+
+			Want: &logisticspb.StreamCareRequestETAResponse{
+				Phase:             onScene.Enum(),
+				SignificantChange: proto.Bool(true),
+			},
+		},
+	}
+
+	for _, step := range steps {
+		testutils.MustMatch(t, step.Want, tracker.update(step.Phase, step.ETA), step.Desc)
+	}
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/*company-data-covered*/services/go/pkg/logistics/validation"
 	"github.com/*company-data-covered*/services/go/pkg/monitoring"
 	"github.com/*company-data-covered*/services/go/pkg/protoconv"
+	"go.uber.org/zap"
 	"golang.org/x/exp/slices"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -56,6 +57,7 @@ const (
 	serviceDateTag          = "service_date"
 	feasibilityTag          = "feasibility"
 	etaPrecisionTag         = "eta_precision"
+	distanceSourceIDTag     = "distance_source_id"
 	visitPhaseTag           = "visit_phase"
 
 	unknownServiceDateStr            = "unknown"
@@ -109,7 +111,7 @@ type LogisticsDB interface {
 	GetCheckFeasibilityCareRequestHistory(ctx context.Context, id int64) ([]*logisticspb.CheckFeasibilityCareRequestDiagnostic, error)
 	UpsertVisitLocations(context.Context, []*logisticspb.CheckFeasibilityVisit) error
 	GetVisitLocations(context.Context, []*logisticspb.CheckFeasibilityVisit) ([]*logisticssql.Location, error)
-	WriteShiftTeamSnapshot(context.Context, int64, *shiftteampb.GetShiftTeamResponse) (*logisticssql.ShiftTeamSnapshot, error)
+	WriteShiftTeamSnapshot(context.Context, int64, *shiftteampb.GetShiftTeamResponse, *logisticspb.ShiftTeamEndpoints) (*logisticssql.ShiftTeamSnapshot, error)
 	HasAnyNewInfoInRegionSince(context.Context, logisticsdb.NewInfoParams) (bool, error)
 	GetServiceRegionByID(context.Context, int64) (*logisticssql.ServiceRegion, error)
 	GetServiceRegionVisitServiceDurations(ctx context.Context, params logisticssql.GetServiceRegionCanonicalVisitDurationsParams) (logisticsdb.VisitServiceDurations, error)
@@ -124,6 +126,7 @@ type LogisticsDB interface {
 	GetOpenHoursScheduleForServiceRegion(ctx context.Context, serviceRegionID int64, beforeCreatedAt time.Time) ([]*common.ScheduleDay, error)
 	GetLatestShiftTeamSnapshotsInRegion(context.Context, int64, time.Time, time.Time, time.Time) ([]*logisticssql.ShiftTeamSnapshot, error)
 	GetLatestShiftTeamSchedulesInServiceRegion(context.Context, int64, time.Time, bool) (*logisticsdb.LatestShiftTeamSchedulesResponse, error)
+	GetLatestScheduleForServiceRegionDate(ctx context.Context, serviceRegionID int64, serviceDate time.Time, latestTimestamp time.Time) (*logisticspb.ServiceRegionDateSchedule, error)
 	GetLatestShiftTeamSchedule(context.Context, int64, logisticsdb.TimeWindow) (*logisticsdb.ShiftTeamSchedule, error)
 	GetLatestInfoForCareRequest(context.Context, int64, time.Time) (*logisticsdb.CareRequestLatestInfo, error)
 	GetLatestShiftTeamLocationID(context.Context, int64, time.Time) (int64, error)
@@ -136,10 +139,19 @@ type LogisticsDB interface {
 	GetShiftTeamsSchedulesFromScheduleID(ctx context.Context, scheduleID int64, latestTimestamp time.Time, includeDebug bool) (*logisticsdb.ScheduleAndDebugScore, error)
 	VisitArrivalTimestampsForSchedule(ctx context.Context, scheduleID int64) (map[int64]time.Time, error)
 	VRPProblemDataForSchedule(ctx context.Context, scheduleID int64) (*logisticsdb.VRPProblemData, error)
+	GetLatestScheduleIDForOptimizerRun(ctx context.Context, optimizerRunID int64) (int64, error)
 	GetServiceRegionVRPData(ctx context.Context, params *logisticsdb.ServiceRegionVRPDataParams) (*logisticsdb.ServiceRegionVRPData, error)
 	ServiceRegionAvailability(ctx context.Context, params logisticsdb.ServiceRegionAvailabilityParams) (*logisticsdb.ServiceRegionAvailability, error)
 	AddServiceRegionAvailabilityQueries(ctx context.Context, params logisticssql.AddServiceRegionAvailabilityQueriesParams) ([]*logisticssql.ServiceRegionAvailabilityQuery, error)
 	AddServiceRegionAvailabilityQueryAttributes(ctx context.Context, availabilityQueries []*logisticssql.ServiceRegionAvailabilityQuery, attributeNames []string) ([]*logisticssql.ServiceRegionAvailabilityQueryAttribute, error)
+	AddBlackoutZone(ctx context.Context, serviceRegionID int64, params logisticsdb.BlackoutZoneParams) (*logisticsdb.BlackoutZone, error)
+	UpdateBlackoutZone(ctx context.Context, id int64, params logisticsdb.BlackoutZoneParams) (*logisticsdb.BlackoutZone, error)
+	DeleteBlackoutZone(ctx context.Context, id int64) error
+	GetBlackoutZonesInServiceRegion(ctx context.Context, serviceRegionID int64, tw logisticsdb.TimeWindow) (logisticsdb.BlackoutZones, error)
+	AddAssignmentRecommendations(ctx context.Context, params logisticsdb.AddAssignmentRecommendationsParams) error
+	GetDriveDurations(ctx context.Context, params logisticsdb.GetDriveDurationsParams) (logisticsdb.DriveDurations, error)
+	GetShiftTeamRouteLegs(ctx context.Context, params logisticsdb.ShiftTeamRouteLegsParams) ([]*logisticsdb.RouteLeg, error)
+	GetServiceRegionDemandHistory(ctx context.Context, serviceRegionID int64, tw logisticsdb.TimeWindow) ([]*logisticsdb.DemandHour, error)
 }
 
 // a compile-time assertion that our assumed implementation satisfies the above interface.
@@ -148,6 +160,13 @@ var _ LogisticsDB = (*logisticsdb.LogisticsDB)(nil)
 type GRPCServerConfig struct {
 	MaxRestBreaksPerShiftTeamPerDay   int64
 	EnableCheckFeasibilityDiagnostics bool
+
+	// Minimum duration between ETA computations of a StreamCareRequestETA stream. 0 uses the default.
+	ETAStreamDebounce time.Duration
+	// Maximum duration between ETA computations of a StreamCareRequestETA stream. 0 uses the default.
+	ETAStreamRefreshInterval time.Duration
+	// Default ETA change for a StreamCareRequestETA update to be significant. 0 uses the default.
+	ETAStreamChangeThreshold time.Duration
 }
 
 type GRPCServer struct {
@@ -163,11 +182,17 @@ type GRPCServer struct {
 	SettingsService  optimizersettings.Service
 	StatsigProvider  *providers.StatsigProvider
 
+	// ETANotifier notifies ETA streams of updates recorded by this server. Nil ETANotifier only refreshes streams periodically.
+	ETANotifier *ETANotifier
+
 	// Cfg is required, and configures rpc-specific logic.
 	Cfg GRPCServerConfig
 
 	// Clock for mocking in tests. Nil clock will use the system clock.
 	Clock Clock
+
+	// Logger for errors that do not fail requests. Nil Logger discards them.
+	Logger *zap.SugaredLogger
 }
 
 func (s *GRPCServer) Validate() error {
@@ -189,6 +214,14 @@ func (s *GRPCServer) now() time.Time {
 	return time.Now()
 }
 
+func (s *GRPCServer) logger() *zap.SugaredLogger {
+	if s.Logger != nil {
+		return s.Logger
+	}
+
+	return zap.NewNop().Sugar()
+}
+
 var (
 	errMarketIDRequired = status.Errorf(codes.InvalidArgument, "market id required")
 )
@@ -220,6 +253,7 @@ func (s *GRPCServer) UpdateShiftTeamLoc(
 		return nil, status.Errorf(codes.Internal, "Unable to update location: %v", err)
 	}
 	monitoring.AddGRPCTag(ctx, serviceRegionTag, logisticsdb.I64ToA(*updateLocation.ServiceRegionID))
+	s.ETANotifier.NotifyShiftTeam(req.ShiftTeamId)
 	return &logisticspb.UpdateShiftTeamLocResponse{}, nil
 }
 
@@ -390,12 +424,26 @@ func buildCareRequestsDiagnosticsProtos(careRequestsDiagnostics []*logisticsdb.C
 			UnwantedAttributes: attributes.Attributes(
 				protoconv.AttributeToCommonAttribute(diagnostic.UnwantedAttributes),
 			).ToExternal().ToCommon(),
-			ArrivalTimestampSec: diagnostic.ArrivalTimestampSec,
+			ArrivalTimestampSec:  diagnostic.ArrivalTimestampSec,
+			AttributePreferences: attributePreferencesProtos(diagnostic.AttributePreferences),
 		})
 	}
 	return careRequestsDiagnosticsProtos
 }
 
+func attributePreferencesProtos(prefs []*logisticssql.GetAttributePreferencesForVisitSnapshotsRow) []*common.AttributePreference {
+	var res []*common.AttributePreference
+	for _, pref := range prefs {
+		internal := &attributes.InternalAttribute{Name: pref.Name}
+		res = append(res, &common.AttributePreference{
+			Attribute:       &common.Attribute{Name: internal.ToExternal().Name},
+			IsUnwanted:      pref.IsUnwanted,
+			PenaltyUsdCents: pref.PenaltyUsdCents,
+		})
+	}
+	return res
+}
+
 func (s *GRPCServer) GetOptimizerRunDiagnostics(ctx context.Context,
 	req *logisticspb.GetOptimizerRunDiagnosticsRequest,
 ) (*logisticspb.GetOptimizerRunDiagnosticsResponse, error) {
@@ -582,6 +630,7 @@ func (s *GRPCServer) RemoveCareRequest(
 		return nil, status.Errorf(codes.Internal, "error deleting visit snapshots for care request: %s", err)
 	}
 	monitoring.AddGRPCTag(ctx, serviceRegionTag, logisticsdb.I64ToA(deletedVisit.ServiceRegionID))
+	s.ETANotifier.NotifyCareRequest(req.CareRequestId)
 	return &logisticspb.RemoveCareRequestResponse{}, nil
 }
 
@@ -589,6 +638,11 @@ func (s *GRPCServer) UpsertShiftTeam(ctx context.Context, req *logisticspb.Upser
 	if req.ShiftTeamId == 0 {
 		return nil, status.Errorf(codes.InvalidArgument, "ShiftTeamID is required")
 	}
+	endpoints := req.GetEndpoints()
+	if endpoints != nil && endpoints.EarliestDepartureTimestampSec != nil && endpoints.ReturnByTimestampSec != nil &&
+		endpoints.GetEarliestDepartureTimestampSec() >= endpoints.GetReturnByTimestampSec() {
+		return nil, status.Errorf(codes.InvalidArgument, "earliest departure must be before return by time")
+	}
 
 	resp, err := s.ShiftTeamService.GetShiftTeam(ctx, &shiftteampb.GetShiftTeamRequest{
 		Id: req.ShiftTeamId,
@@ -602,7 +656,7 @@ func (s *GRPCServer) UpsertShiftTeam(ctx context.Context, req *logisticspb.Upser
 	}
 
 	monitoring.AddGRPCTag(ctx, marketTag, logisticsdb.I64ToA(resp.GetShiftTeam().GetMarketId()))
-	_, err = s.LogisticsDB.WriteShiftTeamSnapshot(ctx, req.ShiftTeamId, resp)
+	_, err = s.LogisticsDB.WriteShiftTeamSnapshot(ctx, req.ShiftTeamId, resp, endpoints)
 	if err != nil {
 		return nil, err
 	}
@@ -643,6 +697,7 @@ func (s *GRPCServer) UpsertCareRequest(ctx context.Context, req *logisticspb.Ups
 		}
 		return nil, err
 	}
+	s.ETANotifier.NotifyCareRequest(req.CareRequestId)
 
 	return &logisticspb.UpsertCareRequestResponse{}, nil
 }
@@ -844,6 +899,7 @@ func (s *GRPCServer) VRPProblemParamsFromCheckFeasibilityRequest(
 			// TODO: use statsig after implementing duration type flag;
 			// or support for binding nested config access to the flag variable for ergonomic use.
 			RestBreakDuration: 30 * time.Minute,
+			RestBreakPolicy:   settings.RestBreakPolicy,
 		},
 		ValidationConfig: validation.Config{
 			FailOnRecoverableError: false,
@@ -1031,6 +1087,35 @@ func (s *GRPCServer) CheckFeasibility(
 		}
 	}
 
+	arrivalTW, blackoutZone, err := checkFeasibilityVisitArrivalTimeWindow(templateVisit, vrpData)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "couldn't determine time window for visit: %s", err)
+	}
+	if blackoutZone != nil {
+		cfResponse := &logisticspb.CheckFeasibilityResponse{
+			Status:       logisticspb.CheckFeasibilityResponse_STATUS_LOCATION_BLACKED_OUT,
+			BlackoutZone: blackoutZone.ToProto(),
+		}
+		err = s.recordFeasibilityQuery(ctx, &recordFeasibilityQueryParams{
+			serviceRegionID: serviceRegion.ID,
+			responseStatus:  cfResponse.Status,
+			visit:           templateVisit,
+			serviceDate:     serviceDate,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return cfResponse, nil
+	}
+	// Check the feasibility of the visit during the part of its arrival time window outside blackout zones.
+	if arrivalTW != nil && !proto.Equal(arrivalTW, templateVisit.GetArrivalTimeWindow()) {
+		clampedVisits := make([]*logisticspb.CheckFeasibilityVisit, len(vrpData.CheckFeasibilityData.Visits))
+		for i, cfVisit := range vrpData.CheckFeasibilityData.Visits {
+			clampedVisits[i] = visitWithArrivalTimeWindow(cfVisit, arrivalTW)
+		}
+		vrpData.CheckFeasibilityData.Visits = clampedVisits
+	}
+
 	monitoring.AddGRPCTags(ctx, monitoring.Tags{
 		marketTag:        logisticsdb.I64ToA(templateVisit.GetMarketId()),
 		serviceRegionTag: logisticsdb.I64ToA(serviceRegion.ID),
@@ -1993,10 +2078,21 @@ func (s *GRPCServer) etaFromCareRequestLatestInfo(
 	tags := monitoring.Tags{
 		serviceRegionTag: logisticsdb.I64ToA(careRequestLatestInfo.ServiceRegionID),
 	}
-	route, err := mapService.GetRoute(ctx, tags, origin, destination)
+	var route *logistics.Route
+	answeringMapService := mapService
+	if fallback, ok := mapService.(*logistics.FallbackMapService); ok {
+		answeringMapService, err = fallback.Do(ctx, tags, func(ms logistics.MapService) error {
+			var routeErr error
+			route, routeErr = ms.GetRoute(ctx, tags, origin, destination)
+			return routeErr
+		})
+	} else {
+		route, err = mapService.GetRoute(ctx, tags, origin, destination)
+	}
 	if err != nil {
 		return nil, status.Errorf(codes.FailedPrecondition, "unable to get ETA for care request: %v", err)
 	}
+	monitoring.AddGRPCTag(ctx, distanceSourceIDTag, logisticsdb.I64ToA(answeringMapService.GetDistanceSourceID()))
 
 	precision := logisticspb.GetCareRequestETAResponse_PRECISION_EN_ROUTE_REALTIME
 	monitoring.AddGRPCTag(ctx, etaPrecisionTag, precision.String())
@@ -2097,7 +2193,17 @@ func (s *GRPCServer) GetAssignableShiftTeams(
 		return nil, status.Errorf(status.Code(err), "unable to get assignable shift teams: %v", err)
 	}
 
-	return assignableShiftTeamResponseFromOptimizerResponse(ctx, assignableShiftTeamResponse), nil
+	resp := assignableShiftTeamResponseFromOptimizerResponse(ctx, assignableShiftTeamResponse)
+	s.explainAssignableShiftTeams(ctx, explainAssignableShiftTeamsParams{
+		visit:           visit,
+		arrivalTW:       logisticsdb.TimeWindow{Start: *startTime, End: *endTime},
+		serviceRegionID: serviceRegion.ID,
+		serviceDate:     serviceDate,
+		rankingPolicy:   req.RankingPolicy,
+		now:             latestSnapshot,
+	}, resp.ShiftTeams)
+
+	return resp, nil
 }
 
 // TODO: Refactor to use in various date sanitization usages.
@@ -2211,15 +2317,19 @@ func (s *GRPCServer) GetAssignableVisits(
 		return nil, status.Errorf(status.Code(err), "unable to get optimizer assignable visits: %v", err)
 	}
 
-	var assignableVisits []*logisticspb.AssignableVisitResult
+	var optimizerVisits []*optimizerpb.AssignableVisitResult
 	for _, visit := range optimizerAssignableVisits.Visits {
 		if assignableVisitFilterStatuses[visit.Status] {
-			assignableVisits = append(assignableVisits, &logisticspb.AssignableVisitResult{
-				CareRequestId: *visit.Visit.Id,
-			})
+			optimizerVisits = append(optimizerVisits, visit)
 		}
 	}
 
+	assignableVisits := s.explainAssignableVisits(ctx, explainAssignableVisitsParams{
+		shiftTeamID:   req.ShiftTeamId,
+		rankingPolicy: req.RankingPolicy,
+		now:           latestSnapshot,
+	}, optimizerVisits)
+
 	return &logisticspb.GetAssignableVisitsResponse{
 		Visits: assignableVisits,
 	}, nil
@@ -2505,9 +2615,35 @@ func (s *GRPCServer) CompareScheduleCounterfactual(
 			"error in applyCounterfactualConstraintsToProblem: %s", err.Error())
 	}
 
+	result, err := s.solveCounterfactualVRP(ctx, counterfactualProblem)
+	if err != nil {
+		return nil, err
+	}
+
+	counterfactualSchedule, err := (&logisticsdb.CounterfactualSchedule{
+		CounterfactualSolution: result.Response.GetSolution(),
+		EntityMappings:         actualProblemData.EntityMappings,
+		ServiceDate:            actualSchedule.Schedule.Meta.ServiceDate,
+	}).Schedule()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "error in scheduleFromVRPSolution: %s", err.Error())
+	}
+
+	return &logisticspb.CompareScheduleCounterfactualResponse{
+		// Original Data:
+		OriginalSchedule: actualSchedule.Schedule,
+		OriginalScore:    actualSchedule.Score,
+		// Counterfactual Data:
+		CounterfactualSchedule: counterfactualSchedule.Schedule,
+		CounterfactualScore:    counterfactualSchedule.Score,
+	}, nil
+}
+
+// solveCounterfactualVRP solves a hypothetical problem, without writing the result to the database.
+func (s *GRPCServer) solveCounterfactualVRP(ctx context.Context, problem *optimizerpb.VRPProblem) (*optimizer.WrappedSolveVRPResp, error) {
 	respChan, err := s.VRPSolver.SolveVRP(ctx, &optimizer.SolveVRPParams{
 		SolveVRPRequest: &optimizerpb.SolveVRPRequest{
-			Problem: counterfactualProblem,
+			Problem: problem,
 			// TODO(https://github.com/*company-data-covered*/services/pull/1523): Also resolve this config similarly.
 			Config: &optimizerpb.VRPConfig{
 				// TODO: Add feasibility from settings.
@@ -2534,23 +2670,7 @@ func (s *GRPCServer) CompareScheduleCounterfactual(
 		return nil, status.Error(codes.Unavailable, "SolveVRP returned no solution in time for problem")
 	}
 
-	counterfactualSchedule, err := (&logisticsdb.CounterfactualSchedule{
-		CounterfactualSolution: result.Response.GetSolution(),
-		EntityMappings:         actualProblemData.EntityMappings,
-		ServiceDate:            actualSchedule.Schedule.Meta.ServiceDate,
-	}).Schedule()
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, "error in scheduleFromVRPSolution: %s", err.Error())
-	}
-
-	return &logisticspb.CompareScheduleCounterfactualResponse{
-		// Original Data:
-		OriginalSchedule: actualSchedule.Schedule,
-		OriginalScore:    actualSchedule.Score,
-		// Counterfactual Data:
-		CounterfactualSchedule: counterfactualSchedule.Schedule,
-		CounterfactualScore:    counterfactualSchedule.Score,
-	}, nil
+	return result, nil
 }
 
 func scheduleTokenFromOpaqueToken(opaqueToken []byte) (*logisticspb.ScheduleToken, error) {
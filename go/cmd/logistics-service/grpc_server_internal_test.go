@@ -29,6 +29,8 @@ type MockVRPSolver struct {
 	nextScoreIndex int
 	solution       *optimizerpb.VRPSolution
 	err            error
+
+	solveVRPRequests []*optimizerpb.SolveVRPRequest
 }
 
 func (m *MockVRPSolver) SolveVRP(ctx context.Context, solveVRPParams *optimizer.SolveVRPParams) (<-chan *optimizer.WrappedSolveVRPResp, error) {
@@ -39,6 +41,7 @@ func (m *MockVRPSolver) SolveVRP(ctx context.Context, solveVRPParams *optimizer.
 	m.mx.Lock()
 	i := m.nextScoreIndex
 	m.nextScoreIndex++
+	m.solveVRPRequests = append(m.solveVRPRequests, solveVRPParams.SolveVRPRequest)
 	m.mx.Unlock()
 
 	solveVRPResps := make(chan *optimizer.WrappedSolveVRPResp, 1)
@@ -408,7 +408,7 @@ func TestCheckFeasibility(t *testing.T) {
 				LogisticsDB:      tc.MockDB,
 				VRPSolver:        tc.MockVRPSolver,
 				SettingsService:  tc.MockSettings,
-				MapServicePicker: logistics.NewMapServicePicker(mockMapService, mockMapService, validMockSettingsService),
+				MapServicePicker: logistics.NewMapServicePicker(mockMapService, nil, mockMapService, validMockSettingsService, monitoring.NewMockScope()),
 			}
 			_, err := s.CheckFeasibility(context.Background(), proto.Clone(tc.Input).(*logisticspb.CheckFeasibilityRequest))
 			if status.Code(err) != tc.ExpectedStatusCode {
@@ -996,19 +996,59 @@ func TestUpsertShiftTeam(t *testing.T) {
 		ExpectedStatusCode codes.Code
 	}{
 		{
-			Desc:                 "Base case",
+			Desc:                 "Base case without endpoints",
 			Input:                &logisticspb.UpsertShiftTeamRequest{ShiftTeamId: shiftTeamID},
 			MockDB:               &MockLogisticsDB{},
 			MockShiftTeamService: &MockShiftTeamServiceClient{GetShiftTeamResult: &shiftteampb.GetShiftTeamResponse{ShiftTeam: &shiftteampb.ShiftTeam{Id: shiftTeamID, MarketId: &marketID}}},
 
 			ExpectedStatusCode: codes.OK,
 		},
+		{
+			Desc: "Endpoints without return by time",
+			Input: &logisticspb.UpsertShiftTeamRequest{
+				ShiftTeamId: shiftTeamID,
+				Endpoints: &logisticspb.ShiftTeamEndpoints{
+					EarliestDepartureTimestampSec: proto.Int64(2),
+				},
+			},
+			MockDB:               &MockLogisticsDB{},
+			MockShiftTeamService: &MockShiftTeamServiceClient{GetShiftTeamResult: &shiftteampb.GetShiftTeamResponse{ShiftTeam: &shiftteampb.ShiftTeam{Id: shiftTeamID, MarketId: &marketID}}},
+
+			ExpectedStatusCode: codes.OK,
+		},
+		{
+			Desc: "Base case with endpoints",
+			Input: &logisticspb.UpsertShiftTeamRequest{
+				ShiftTeamId: shiftTeamID,
+				Endpoints: &logisticspb.ShiftTeamEndpoints{
+					StartLocation:                 &commonpb.Location{LatitudeE6: 1, LongitudeE6: 1},
+					EarliestDepartureTimestampSec: proto.Int64(1),
+					ReturnByTimestampSec:          proto.Int64(2),
+				},
+			},
+			MockDB:               &MockLogisticsDB{},
+			MockShiftTeamService: &MockShiftTeamServiceClient{GetShiftTeamResult: &shiftteampb.GetShiftTeamResponse{ShiftTeam: &shiftteampb.ShiftTeam{Id: shiftTeamID, MarketId: &marketID}}},
+
+			ExpectedStatusCode: codes.OK,
+		},
 		{
 			Desc:  "Shift Team not supplied error",
 			Input: &logisticspb.UpsertShiftTeamRequest{},
 
 			ExpectedStatusCode: codes.InvalidArgument,
 		},
+		{
+			Desc: "Return by before earliest departure error",
+			Input: &logisticspb.UpsertShiftTeamRequest{
+				ShiftTeamId: shiftTeamID,
+				Endpoints: &logisticspb.ShiftTeamEndpoints{
+					EarliestDepartureTimestampSec: proto.Int64(2),
+					ReturnByTimestampSec:          proto.Int64(1),
+				},
+			},
+
+			ExpectedStatusCode: codes.InvalidArgument,
+		},
 		{
 			Desc:                 "Error getting Shift Team",
 			Input:                &logisticspb.UpsertShiftTeamRequest{ShiftTeamId: shiftTeamID},
@@ -1404,7 +1444,7 @@ func TestGetCareRequestETA(t *testing.T) {
 
 			s := GRPCServer{
 				LogisticsDB:      tc.MockDB,
-				MapServicePicker: logistics.NewMapServicePicker(mapService, mapService, mockSettingsService),
+				MapServicePicker: logistics.NewMapServicePicker(mapService, nil, mapService, mockSettingsService, monitoring.NewMockScope()),
 				Clock:            MockClock(now),
 			}
 			resp, err := s.GetCareRequestETA(context.Background(), tc.Input)
@@ -1841,6 +1881,7 @@ func TestGRPCServer_GetAssignableShiftTeams(t *testing.T) {
 				GetServiceRegionForStationMarketIDResult: &logisticssql.ServiceRegion{
 					IanaTimeZoneName: "America/Denver",
 				},
+				GetLatestScheduleForServiceRegionDateResult: &logisticspb.ServiceRegionDateSchedule{},
 			},
 			MockOptimizerService: &MockOptimizerService{
 				mockGetAssignableShiftTeamsResp: mockOptimizerResponse,
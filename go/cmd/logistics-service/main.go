@@ -44,6 +44,7 @@ var (
 	grpcAddr = flag.String("grpc-listen-addr", ":8081", "GRPC address to listen to")
 
 	osrmAddr          = flag.String("osrm-addr", "http://127.0.0.1:5050", "OSRM Backend http address")
+	valhallaAddr      = flag.String("valhalla-addr", "", "Valhalla Backend http address. Empty disables Valhalla")
 	optimizerGRPCAddr = flag.String("optimizer-grpc-addr", "", "Optimizer GRPC address")
 	stationGRPCAddr   = flag.String("station-grpc-addr", "", "Station GRPC address")
 	enableGoogleMaps  = flag.Bool("use-google-maps", false, "Use Google Maps for distance matrix and routing instead of OSRM")
@@ -79,8 +80,22 @@ var (
 	refreshTokenInterval            = flag.Duration("grpc-refresh-token-interval", 1*time.Hour, "time interval for refreshing M2M tokens")
 	maxRestBreaksPerShiftTeamPerDay = flag.Uint("max-rest-breaks-per-shift-team-per-day", 1, "number of rest breaks a shift team can take in a day")
 
+	etaStreamDebounce        = flag.Duration("eta-stream-debounce", defaultETAStreamDebounce, "Minimum time between ETA computations of a care request ETA stream.")
+	etaStreamRefreshInterval = flag.Duration("eta-stream-refresh-interval", defaultETAStreamRefreshInterval, "Maximum time between ETA computations of a care request ETA stream, to catch updates recorded by other instances.")
+	etaStreamChangeThreshold = flag.Duration("eta-stream-change-threshold", defaultETAStreamChangeThreshold, "Default ETA change for a care request ETA stream update to be marked as significant.")
+
 	statsigOptimizerSettingsRefreshInterval = flag.Duration("statsig-optimizer-settings-refresh-interval", 1*time.Minute, "time interval for refreshing optimizer settings from Statsig. 0 means do not use Statsig for optimizer settings.")
 	getLatestDistancesForLocationsBatchSize = flag.Uint("get-latest-distances-for-locations-batch-size", 0, "batch size for BatchGetLatestDistancesForLocations")
+
+	shiftTeamLocationRetention         = flag.Duration("shift-team-location-retention", 0, "Time to keep shift team locations for route analytics before deleting them. 0 means keep them forever.")
+	shiftTeamLocationRetentionInterval = flag.Duration("shift-team-location-retention-interval", 1*time.Hour, "Interval between deletions of shift team locations older than --shift-team-location-retention.")
+
+	exportVRPProblemsOptimizerRunIDs = flag.String("export-vrp-problems-optimizer-run-ids", "", "Comma-separated list of optimizer run IDs to export VRP problems of to --vrp-problems-dir, then exit.")
+	replayVRPProblems                = flag.Bool("replay-vrp-problems", false, "Re-solve the exported VRP problems in --vrp-problems-dir and report score deltas, then exit.")
+	vrpProblemsDir                   = flag.String("vrp-problems-dir", "vrp_problems", "Directory of exported VRP problems")
+	vrpProblemsFormat                = flag.String("vrp-problems-format", vrpProblemsJSONFormat,
+		fmt.Sprintf("Format of exported VRP problems. One of %v", []string{vrpProblemsJSONFormat, vrpProblemsBinaryFormat}))
+	replayVRPProblemsTerminationDuration = flag.Duration("replay-vrp-problems-termination-duration", 0, "Termination duration for replayed VRP problems. 0 means use the exported config.")
 )
 
 const (
@@ -235,6 +250,19 @@ func main() {
 	osrmMapService := logistics.NewOSRMService(*osrmAddr, osrmSource.ID, mapScope.With("", monitoring.Tags{"service": "osrm"}, nil))
 	osrmMapServiceHealthChecker := HealthChecker(osrmMapService)
 
+	var valhallaMapService logistics.MapService
+	var valhallaMapServiceHealthChecker HealthChecker
+	if *valhallaAddr != "" {
+		valhallaSource, err := mapSrcLDB.GetDistanceSourceByShortName(ctx, logisticsdb.ValhallaMapsSourceShortName)
+		if err != nil {
+			logger.Panicw("could not find map source", "short_name", logisticsdb.ValhallaMapsSourceShortName, zap.Error(err))
+		}
+		valhallaService := logistics.NewValhallaService(*valhallaAddr, valhallaSource.ID, mapScope.With("", monitoring.Tags{"service": "valhalla"}, nil))
+
+		valhallaMapService = valhallaService
+		valhallaMapServiceHealthChecker = valhallaService
+	}
+
 	mapService := logistics.MapService(osrmMapService)
 
 	var gmapsServiceHealthChecker HealthChecker
@@ -271,7 +299,7 @@ func main() {
 
 	defer lockDB.Close()
 
-	mapServicePicker := logistics.NewMapServicePicker(osrmMapService, mapService, statsigSettingsSvc)
+	mapServicePicker := logistics.NewMapServicePicker(osrmMapService, valhallaMapService, mapService, statsigSettingsSvc, mapScope)
 	ldb := logisticsdb.NewLogisticsDB(db, mapServicePicker, statsigSettingsSvc, ldbScope)
 	ldb.QuerySettings = logisticsdb.QuerySettings{
 		GetLatestDistancesForLocationsBatchSize: *getLatestDistancesForLocationsBatchSize,
@@ -292,18 +320,30 @@ func main() {
 		SettingsService:  statsigSettingsSvc,
 		StatsigProvider:  statsigProvider,
 		MapServicePicker: mapServicePicker,
+		ETANotifier:      NewETANotifier(),
 
 		Cfg: GRPCServerConfig{
 			MaxRestBreaksPerShiftTeamPerDay:   int64(*maxRestBreaksPerShiftTeamPerDay),
 			EnableCheckFeasibilityDiagnostics: os.Getenv(checkFeasibilityDiagnosticsEnableKey) == "true",
+			ETAStreamDebounce:                 *etaStreamDebounce,
+			ETAStreamRefreshInterval:          *etaStreamRefreshInterval,
+			ETAStreamChangeThreshold:          *etaStreamChangeThreshold,
 		},
 		LogisticsDB: ldb,
 		LockDB:      logisticsLocker,
+		Logger:      logger,
 	}
 	if err := serverConfig.Validate(); err != nil {
 		logger.Panicw("invalid server config", zap.Error(err))
 	}
 
+	if *exportVRPProblemsOptimizerRunIDs != "" || *replayVRPProblems {
+		if err := runVRPProblemsCommand(ctx, serverConfig, os.Stdout); err != nil {
+			logger.Panicw("VRP problems command failed", zap.Error(err))
+		}
+		return
+	}
+
 	go func() {
 		err := server.ServeGRPC(func(grpcServer *grpc.Server) {
 			logisticspb.RegisterLogisticsServiceServer(grpcServer, serverConfig)
@@ -324,11 +364,20 @@ func main() {
 
 		GoogleMapsHealthChecker: gmapsServiceHealthChecker,
 		OSRMHealthChecker:       osrmMapServiceHealthChecker,
+		ValhallaHealthChecker:   valhallaMapServiceHealthChecker,
 	}
 
 	if *optimizerSettingsPollInterval > 0 {
 		optimizerRunner := optimizer.NewRunner(ldb, optimizerGRPC, logger, runnerScope, *optimizerSettingsPollInterval, statsigSettingsSvc)
 		optimizerRunner.Start(ctx)
+
+		distancePrewarmer := optimizer.NewDistancePrewarmer(ldb, logger, runnerScope, *optimizerSettingsPollInterval, statsigSettingsSvc)
+		distancePrewarmer.Start(ctx)
+	}
+
+	if *shiftTeamLocationRetention > 0 {
+		locationRetention := NewShiftTeamLocationRetention(ldb, *shiftTeamLocationRetention, *shiftTeamLocationRetentionInterval, logger, runnerScope)
+		locationRetention.Start(ctx)
 	}
 
 	router := http.NewServeMux()
@@ -343,6 +392,8 @@ func main() {
 		devServer := *NewDevServer(httpServer)
 		router.HandleFunc("/api/example-vrp", handleMethod(http.MethodGet, devServer.exampleVRP))
 		router.HandleFunc("/api/example-vrp-bounds", handleMethod(http.MethodGet, devServer.exampleVRPBounds))
+		router.HandleFunc("/api/blackout-zones", handleMethod(http.MethodGet, devServer.blackoutZones))
+		router.HandleFunc("/api/route-playback", handleMethod(http.MethodGet, devServer.routePlayback))
 		router.HandleFunc("/api/solve-vrp", handleMethod(http.MethodPost, devServer.solveVRP))
 		router.HandleFunc("/api/solve-vrp-status", handleMethod(http.MethodGet, devServer.solveVRPStatus))
 
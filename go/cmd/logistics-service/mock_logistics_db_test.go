@@ -66,6 +66,8 @@ type MockLogisticsDB struct {
 	UpsertMarketAndServiceRegionFromStationMarketErr  error
 	GetLatestShiftTeamSchedulesInServiceRegionResult  *logisticsdb.LatestShiftTeamSchedulesResponse
 	GetLatestShiftTeamSchedulesInServiceRegionErr     error
+	GetLatestScheduleForServiceRegionDateResult       *logisticspb.ServiceRegionDateSchedule
+	GetLatestScheduleForServiceRegionDateErr          error
 	GetLatestShiftTeamScheduleResult                  *logisticsdb.ShiftTeamSchedule
 	GetLatestShiftTeamScheduleErr                     error
 	GetLatestInfoForCareRequestResult                 *logisticsdb.CareRequestLatestInfo
@@ -94,6 +96,8 @@ type MockLogisticsDB struct {
 	GetShiftTeamsSchedulesFromScheduleIDErr           error
 	VRPProblemDataForScheduleResult                   *logisticsdb.VRPProblemData
 	VRPProblemDataForScheduleErr                      error
+	GetLatestScheduleIDForOptimizerRunResult          int64
+	GetLatestScheduleIDForOptimizerRunErr             error
 	VisitArrivalTimestampsForScheduleResult           map[int64]time.Time
 	VisitArrivalTimestampsForScheduleErr              error
 	GetServiceRegionVRPDataResult                     *logisticsdb.ServiceRegionVRPData
@@ -104,6 +108,23 @@ type MockLogisticsDB struct {
 	AddServiceRegionAvailabilityQueriesErr            error
 	AddServiceRegionAvailabilityQueryAttributesResult []*logisticssql.ServiceRegionAvailabilityQueryAttribute
 	AddServiceRegionAvailabilityQueryAttributesErr    error
+	AddBlackoutZoneResult                             *logisticsdb.BlackoutZone
+	AddBlackoutZoneErr                                error
+	UpdateBlackoutZoneResult                          *logisticsdb.BlackoutZone
+	UpdateBlackoutZoneErr                             error
+	DeleteBlackoutZoneErr                             error
+	GetBlackoutZonesInServiceRegionResult             logisticsdb.BlackoutZones
+	GetBlackoutZonesInServiceRegionErr                error
+	AddAssignmentRecommendationsErr                   error
+	GetDriveDurationsResult                           logisticsdb.DriveDurations
+	GetDriveDurationsErr                              error
+	GetShiftTeamRouteLegsResult                       []*logisticsdb.RouteLeg
+	GetShiftTeamRouteLegsErr                          error
+	GetServiceRegionDemandHistoryResult               []*logisticsdb.DemandHour
+	GetServiceRegionDemandHistoryErr                  error
+
+	// AddedAssignmentRecommendations records the recommendations logged by AddAssignmentRecommendations.
+	AddedAssignmentRecommendations []logisticsdb.AddAssignmentRecommendationsParams
 }
 
 func (m *MockLogisticsDB) WithVRPProblemDataForScheduleErr(err error) *MockLogisticsDB {
@@ -122,6 +143,10 @@ func (m *MockLogisticsDB) VRPProblemDataForSchedule(ctx context.Context, schedul
 	return m.VRPProblemDataForScheduleResult, m.VRPProblemDataForScheduleErr
 }
 
+func (m *MockLogisticsDB) GetLatestScheduleIDForOptimizerRun(ctx context.Context, optimizerRunID int64) (int64, error) {
+	return m.GetLatestScheduleIDForOptimizerRunResult, m.GetLatestScheduleIDForOptimizerRunErr
+}
+
 func (m *MockLogisticsDB) GetShiftTeamsSchedulesFromScheduleID(context.Context, int64, time.Time, bool) (*logisticsdb.ScheduleAndDebugScore, error) {
 	return m.GetShiftTeamsSchedulesFromScheduleIDResult, m.GetShiftTeamsSchedulesFromScheduleIDErr
 }
@@ -158,7 +183,7 @@ func (m *MockLogisticsDB) GetVisitLocations(context.Context, []*logisticspb.Chec
 	return m.GetVisitLocationsResult, m.GetVisitLocationsErr
 }
 
-func (m *MockLogisticsDB) WriteShiftTeamSnapshot(context.Context, int64, *shiftteampb.GetShiftTeamResponse) (*logisticssql.ShiftTeamSnapshot, error) {
+func (m *MockLogisticsDB) WriteShiftTeamSnapshot(context.Context, int64, *shiftteampb.GetShiftTeamResponse, *logisticspb.ShiftTeamEndpoints) (*logisticssql.ShiftTeamSnapshot, error) {
 	return m.WriteShiftTeamSnapshotResult, m.WriteShiftTeamSnapshotErr
 }
 
@@ -214,6 +239,10 @@ func (m *MockLogisticsDB) GetLatestShiftTeamSchedulesInServiceRegion(context.Con
 	return m.GetLatestShiftTeamSchedulesInServiceRegionResult, m.GetLatestShiftTeamSchedulesInServiceRegionErr
 }
 
+func (m *MockLogisticsDB) GetLatestScheduleForServiceRegionDate(context.Context, int64, time.Time, time.Time) (*logisticspb.ServiceRegionDateSchedule, error) {
+	return m.GetLatestScheduleForServiceRegionDateResult, m.GetLatestScheduleForServiceRegionDateErr
+}
+
 func (m *MockLogisticsDB) GetLatestShiftTeamSnapshotsInRegion(context.Context, int64, time.Time, time.Time, time.Time) ([]*logisticssql.ShiftTeamSnapshot, error) {
 	return m.GetLatestShiftTeamSnapshotsInRegionResult, m.GetLatestShiftTeamSnapshotsInRegionErr
 }
@@ -291,3 +320,39 @@ func (m *MockLogisticsDB) AddServiceRegionAvailabilityQueryAttributes(ctx contex
 }
 
 var _ LogisticsDB = (*MockLogisticsDB)(nil)
+
+func (m *MockLogisticsDB) AddBlackoutZone(ctx context.Context, serviceRegionID int64, params logisticsdb.BlackoutZoneParams) (*logisticsdb.BlackoutZone, error) {
+	return m.AddBlackoutZoneResult, m.AddBlackoutZoneErr
+}
+
+func (m *MockLogisticsDB) UpdateBlackoutZone(ctx context.Context, id int64, params logisticsdb.BlackoutZoneParams) (*logisticsdb.BlackoutZone, error) {
+	return m.UpdateBlackoutZoneResult, m.UpdateBlackoutZoneErr
+}
+
+func (m *MockLogisticsDB) DeleteBlackoutZone(ctx context.Context, id int64) error {
+	return m.DeleteBlackoutZoneErr
+}
+
+func (m *MockLogisticsDB) GetBlackoutZonesInServiceRegion(ctx context.Context, serviceRegionID int64, tw logisticsdb.TimeWindow) (logisticsdb.BlackoutZones, error) {
+	return m.GetBlackoutZonesInServiceRegionResult, m.GetBlackoutZonesInServiceRegionErr
+}
+
+func (m *MockLogisticsDB) AddAssignmentRecommendations(ctx context.Context, params logisticsdb.AddAssignmentRecommendationsParams) error {
+	if m.AddAssignmentRecommendationsErr != nil {
+		return m.AddAssignmentRecommendationsErr
+	}
+	m.AddedAssignmentRecommendations = append(m.AddedAssignmentRecommendations, params)
+	return nil
+}
+
+func (m *MockLogisticsDB) GetDriveDurations(ctx context.Context, params logisticsdb.GetDriveDurationsParams) (logisticsdb.DriveDurations, error) {
+	return m.GetDriveDurationsResult, m.GetDriveDurationsErr
+}
+
+func (m *MockLogisticsDB) GetShiftTeamRouteLegs(ctx context.Context, params logisticsdb.ShiftTeamRouteLegsParams) ([]*logisticsdb.RouteLeg, error) {
+	return m.GetShiftTeamRouteLegsResult, m.GetShiftTeamRouteLegsErr
+}
+
+func (m *MockLogisticsDB) GetServiceRegionDemandHistory(ctx context.Context, serviceRegionID int64, tw logisticsdb.TimeWindow) ([]*logisticsdb.DemandHour, error) {
+	return m.GetServiceRegionDemandHistoryResult, m.GetServiceRegionDemandHistoryErr
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/optimizer/optimizersettings"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func routeLegToProto(leg *logisticsdb.RouteLeg) *logisticspb.ShiftTeamRouteLegComparison {
+	res := &logisticspb.ShiftTeamRouteLegComparison{
+		FromCareRequestId: leg.FromCareRequestID,
+		ToCareRequestId:   leg.ToCareRequestID,
+		MatchConfidence:   leg.MatchConfidence,
+	}
+	if leg.Planned != nil {
+		res.PlannedDurationSec = proto.Int64(int64(leg.Planned.Duration.Seconds()))
+		res.PlannedDistanceMeters = proto.Int64(leg.Planned.LengthMeters)
+	}
+	if !leg.DepartedAt.IsZero() {
+		res.DepartureTimestampSec = proto.Int64(leg.DepartedAt.Unix())
+	}
+	if !leg.ArrivedAt.IsZero() {
+		res.ArrivalTimestampSec = proto.Int64(leg.ArrivedAt.Unix())
+	}
+	if leg.Actual != nil {
+		res.ActualDurationSec = proto.Int64(int64(leg.Actual.Duration.Seconds()))
+		res.ActualDistanceMeters = proto.Int64(leg.Actual.LengthMeters)
+	}
+	return res
+}
+
+func (s *GRPCServer) GetShiftTeamRouteLegs(
+	ctx context.Context,
+	req *logisticspb.GetShiftTeamRouteLegsRequest,
+) (*logisticspb.GetShiftTeamRouteLegsResponse, error) {
+	latestSnapshot := s.now()
+	schedule, err := s.LogisticsDB.GetLatestShiftTeamSchedule(
+		ctx,
+		req.ShiftTeamId,
+		logisticsdb.TimeWindow{
+			Start: latestSnapshot.Add(-optimizersettings.DefaultSnapshotsLookbackDuration()),
+			End:   latestSnapshot,
+		},
+	)
+	if err != nil {
+		if errors.Is(err, logisticsdb.ErrUnknownShiftTeam) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "could not get schedule: %s", err)
+	}
+
+	route := schedule.Schedule.GetRoute()
+	if route == nil || schedule.Metadata == nil {
+		return &logisticspb.GetShiftTeamRouteLegsResponse{}, nil
+	}
+	scheduleToken, err := scheduleTokenFromOpaqueToken(schedule.Metadata.ScheduleToken)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "invalid schedule token: %s", err)
+	}
+
+	legs, err := s.LogisticsDB.GetShiftTeamRouteLegs(ctx, logisticsdb.ShiftTeamRouteLegsParams{
+		ShiftTeamID:        req.ShiftTeamId,
+		ScheduleID:         scheduleToken.GetScheduleId(),
+		Route:              route,
+		LatestSnapshotTime: latestSnapshot,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not get route legs: %s", err)
+	}
+
+	res := make([]*logisticspb.ShiftTeamRouteLegComparison, len(legs))
+	for i, leg := range legs {
+		res[i] = routeLegToProto(leg)
+	}
+	return &logisticspb.GetShiftTeamRouteLegsResponse{Legs: res}, nil
+}
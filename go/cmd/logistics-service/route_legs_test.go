@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestGRPCServer_GetShiftTeamRouteLegs(t *testing.T) {
+	departedAt := time.Date(2023, time.September, 16, 10, 0, 0, 0, time.UTC)
+	scheduleToken, err := proto.Marshal(&logisticspb.ScheduleToken{ScheduleId: proto.Int64(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schedule := &logisticsdb.ShiftTeamSchedule{
+		Metadata: &logisticspb.ScheduleMetadata{ScheduleToken: scheduleToken},
+		Schedule: &logisticspb.ShiftTeamSchedule{
+			ShiftTeamId: 2,
+			Route:       &logisticspb.ShiftTeamRoute{},
+		},
+	}
+	legs := []*logisticsdb.RouteLeg{
+		{
+			FromCareRequestID: 3,
+			ToCareRequestID:   4,
+			Planned:           &logistics.Distance{Duration: 10 * time.Minute, LengthMeters: 5000},
+			DepartedAt:        departedAt,
+			ArrivedAt:         departedAt.Add(15 * time.Minute),
+			Actual:            &logistics.Distance{Duration: 15 * time.Minute, LengthMeters: 6000},
+			MatchConfidence:   0.9,
+		},
+		{
+			FromCareRequestID: 4,
+			ToCareRequestID:   5,
+		},
+	}
+
+	tcs := []struct {
+		Desc string
+		LDB  *MockLogisticsDB
+
+		ErrCode      codes.Code
+		ExpectedResp *logisticspb.GetShiftTeamRouteLegsResponse
+	}{
+		{
+			Desc: "base case",
+			LDB: &MockLogisticsDB{
+				GetLatestShiftTeamScheduleResult: schedule,
+				GetShiftTeamRouteLegsResult:      legs,
+			},
+
+			ErrCode: codes.OK,
+			ExpectedResp: &logisticspb.GetShiftTeamRouteLegsResponse{
+				Legs: []*logisticspb.ShiftTeamRouteLegComparison{
+					{
+						FromCareRequestId:     3,
+						ToCareRequestId:       4,
+						PlannedDurationSec:    proto.Int64(600),
+						PlannedDistanceMeters: proto.Int64(5000),
+						DepartureTimestampSec: proto.Int64(departedAt.Unix()),
+						ArrivalTimestampSec:   proto.Int64(departedAt.Add(15 * time.Minute).Unix()),
+						ActualDurationSec:     proto.Int64(900),
+						ActualDistanceMeters:  proto.Int64(6000),
+						MatchConfidence:       0.9,
+					},
+					{
+						FromCareRequestId: 4,
+						ToCareRequestId:   5,
+					},
+				},
+			},
+		},
+		{
+			Desc: "no schedule",
+			LDB: &MockLogisticsDB{
+				GetLatestShiftTeamScheduleResult: &logisticsdb.ShiftTeamSchedule{},
+			},
+
+			ErrCode:      codes.OK,
+			ExpectedResp: &logisticspb.GetShiftTeamRouteLegsResponse{},
+		},
+		{
+			Desc: "unknown shift team",
+			LDB: &MockLogisticsDB{
+				GetLatestShiftTeamScheduleErr: logisticsdb.ErrUnknownShiftTeam,
+			},
+
+			ErrCode: codes.NotFound,
+		},
+		{
+			Desc: "route legs error",
+			LDB: &MockLogisticsDB{
+				GetLatestShiftTeamScheduleResult: schedule,
+				GetShiftTeamRouteLegsErr:         errors.New("boo"),
+			},
+
+			ErrCode: codes.Internal,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			s := &GRPCServer{LogisticsDB: tc.LDB}
+
+			resp, err := s.GetShiftTeamRouteLegs(context.Background(), &logisticspb.GetShiftTeamRouteLegsRequest{ShiftTeamId: 2})
+			testutils.MustMatch(t, tc.ErrCode, status.Code(err))
+			testutils.MustMatch(t, tc.ExpectedResp, resp)
+		})
+	}
+}
@@ -0,0 +1,582 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// scheduleScenario applies ScheduleScenarioMutations to a copy of an optimizer run's problem.
+type scheduleScenario struct {
+	problem        *optimizerpb.VRPProblem
+	entityMappings logisticsdb.EntityMappings
+
+	openHoursStartTimestampSec int64
+	openHoursEndTimestampSec   int64
+
+	shiftTeamsByShiftTeamID map[int64]*optimizerpb.VRPShiftTeam
+	visitsByCareRequestID   map[int64]*optimizerpb.VRPVisit
+	restBreaksByID          map[int64]*optimizerpb.VRPRestBreak
+
+	startedShiftTeamIDs  map[int64]bool
+	servedCareRequestIDs map[int64]bool
+	startedRestBreakIDs  map[int64]bool
+
+	addedShiftTeamsCount        int64
+	removedShiftTeamSnapshotIDs map[int64]bool
+	removedVisitSnapshotIDs     map[int64]bool
+	removedRestBreakIDs         map[int64]bool
+}
+
+func newScheduleScenario(problemData *logisticsdb.VRPProblemData) (*scheduleScenario, error) {
+	s := &scheduleScenario{
+		problem: proto.Clone(problemData.VRPProblem).(*optimizerpb.VRPProblem),
+		entityMappings: logisticsdb.EntityMappings{
+			CareRequests: make(map[logisticsdb.VisitSnapshotID]logisticsdb.CareRequestID, len(problemData.EntityMappings.CareRequests)),
+			ShiftTeams:   make(map[logisticsdb.ShiftTeamSnapshotID]logisticsdb.ShiftTeamID, len(problemData.EntityMappings.ShiftTeams)),
+		},
+		shiftTeamsByShiftTeamID:     map[int64]*optimizerpb.VRPShiftTeam{},
+		visitsByCareRequestID:       map[int64]*optimizerpb.VRPVisit{},
+		restBreaksByID:              map[int64]*optimizerpb.VRPRestBreak{},
+		startedShiftTeamIDs:         map[int64]bool{},
+		servedCareRequestIDs:        map[int64]bool{},
+		startedRestBreakIDs:         map[int64]bool{},
+		removedShiftTeamSnapshotIDs: map[int64]bool{},
+		removedVisitSnapshotIDs:     map[int64]bool{},
+		removedRestBreakIDs:         map[int64]bool{},
+	}
+	for k, v := range problemData.EntityMappings.CareRequests {
+		s.entityMappings.CareRequests[k] = v
+	}
+	for k, v := range problemData.EntityMappings.ShiftTeams {
+		s.entityMappings.ShiftTeams[k] = v
+	}
+	if problemData.OptimizerRun != nil {
+		s.openHoursStartTimestampSec = problemData.OptimizerRun.OpenHoursStartTimestampSec
+		s.openHoursEndTimestampSec = problemData.OptimizerRun.OpenHoursEndTimestampSec
+	}
+
+	desc := s.problem.GetDescription()
+	for _, v := range desc.GetVisits() {
+		crID, ok := s.entityMappings.CareRequests[logisticsdb.VisitSnapshotID(v.GetId())]
+		if !ok {
+			return nil, fmt.Errorf("invalid problemData with no care request ID for visit snapshot: %d", v.GetId())
+		}
+		s.visitsByCareRequestID[crID.Int64()] = v
+	}
+	for _, rb := range desc.GetRestBreaks() {
+		s.restBreaksByID[rb.GetId()] = rb
+	}
+	for _, st := range desc.GetShiftTeams() {
+		stID, ok := s.entityMappings.ShiftTeams[logisticsdb.ShiftTeamSnapshotID(st.GetId())]
+		if !ok {
+			return nil, fmt.Errorf("invalid problemData with no shift team ID for shift team snapshot: %d", st.GetId())
+		}
+		s.shiftTeamsByShiftTeamID[stID.Int64()] = st
+
+		for _, stop := range st.GetRouteHistory().GetStops() {
+			s.startedShiftTeamIDs[stID.Int64()] = true
+			if v := stop.GetVisit(); v != nil {
+				s.servedCareRequestIDs[s.entityMappings.CareRequests[logisticsdb.VisitSnapshotID(v.GetVisitId())].Int64()] = true
+			}
+			if rb := stop.GetRestBreak(); rb != nil {
+				s.startedRestBreakIDs[rb.GetRestBreakId()] = true
+			}
+		}
+	}
+
+	return s, nil
+}
+
+func (s *scheduleScenario) apply(mutation *logisticspb.ScheduleScenarioMutation) error {
+	switch m := mutation.GetMutation().(type) {
+	case *logisticspb.ScheduleScenarioMutation_AddShiftTeam_:
+		return s.addShiftTeam(m.AddShiftTeam)
+	case *logisticspb.ScheduleScenarioMutation_RemoveShiftTeam_:
+		return s.removeShiftTeam(m.RemoveShiftTeam)
+	case *logisticspb.ScheduleScenarioMutation_UpdateShiftTeam_:
+		return s.updateShiftTeam(m.UpdateShiftTeam)
+	case *logisticspb.ScheduleScenarioMutation_RemoveVisit_:
+		return s.removeVisit(m.RemoveVisit)
+	case *logisticspb.ScheduleScenarioMutation_UpdateVisit_:
+		return s.updateVisit(m.UpdateVisit)
+	case *logisticspb.ScheduleScenarioMutation_UpdateOpenHours_:
+		return s.updateOpenHours(m.UpdateOpenHours)
+	case *logisticspb.ScheduleScenarioMutation_RemoveRestBreak_:
+		return s.removeRestBreak(m.RemoveRestBreak)
+	case *logisticspb.ScheduleScenarioMutation_UpdateRestBreak_:
+		return s.updateRestBreak(m.UpdateRestBreak)
+	default:
+		return errors.New("unhandled ScheduleScenarioMutation type")
+	}
+}
+
+func validateTimestamps(startTimestampSec, endTimestampSec int64) error {
+	if startTimestampSec >= endTimestampSec {
+		return fmt.Errorf("start timestamp (%d) must be before end timestamp (%d)", startTimestampSec, endTimestampSec)
+	}
+	return nil
+}
+
+func cloneTimeWindow(tw *optimizerpb.VRPTimeWindow) *optimizerpb.VRPTimeWindow {
+	if tw == nil {
+		return &optimizerpb.VRPTimeWindow{}
+	}
+	return proto.Clone(tw).(*optimizerpb.VRPTimeWindow)
+}
+
+func (s *scheduleScenario) addShiftTeam(m *logisticspb.ScheduleScenarioMutation_AddShiftTeam) error {
+	template, ok := s.shiftTeamsByShiftTeamID[m.GetTemplateShiftTeamId()]
+	if !ok {
+		return fmt.Errorf("unknown template shift team ID for add shift team mutation: %d", m.GetTemplateShiftTeamId())
+	}
+	if err := validateTimestamps(m.GetStartTimestampSec(), m.GetEndTimestampSec()); err != nil {
+		return fmt.Errorf("invalid add shift team mutation: %w", err)
+	}
+
+	s.addedShiftTeamsCount++
+	id := -s.addedShiftTeamsCount
+	shiftTeam := &optimizerpb.VRPShiftTeam{
+		Id:                 proto.Int64(id),
+		DepotLocationId:    template.DepotLocationId,
+		EndDepotLocationId: template.EndDepotLocationId,
+		AvailableTimeWindow: &optimizerpb.VRPTimeWindow{
+			StartTimestampSec: proto.Int64(m.GetStartTimestampSec()),
+			EndTimestampSec:   proto.Int64(m.GetEndTimestampSec()),
+		},
+		AllowedCapacityRatio:   template.AllowedCapacityRatio,
+		Attributes:             template.Attributes,
+		AppHourlyCostUsdCents:  template.AppHourlyCostUsdCents,
+		DhmtHourlyCostUsdCents: template.DhmtHourlyCostUsdCents,
+		NumDhmtMembers:         template.NumDhmtMembers,
+		NumAppMembers:          template.NumAppMembers,
+	}
+	shiftTeam = proto.Clone(shiftTeam).(*optimizerpb.VRPShiftTeam)
+
+	desc := s.problem.GetDescription()
+	desc.ShiftTeams = append(desc.ShiftTeams, shiftTeam)
+	s.entityMappings.ShiftTeams[logisticsdb.ShiftTeamSnapshotID(id)] = logisticsdb.ShiftTeamID(id)
+	s.shiftTeamsByShiftTeamID[id] = shiftTeam
+	return nil
+}
+
+func (s *scheduleScenario) removeShiftTeam(m *logisticspb.ScheduleScenarioMutation_RemoveShiftTeam) error {
+	shiftTeam, ok := s.shiftTeamsByShiftTeamID[m.GetShiftTeamId()]
+	if !ok {
+		return fmt.Errorf("unknown shift team ID for remove shift team mutation: %d", m.GetShiftTeamId())
+	}
+	if s.startedShiftTeamIDs[m.GetShiftTeamId()] {
+		return fmt.Errorf("shift team with route history cannot be removed: %d", m.GetShiftTeamId())
+	}
+
+	delete(s.shiftTeamsByShiftTeamID, m.GetShiftTeamId())
+	s.removedShiftTeamSnapshotIDs[shiftTeam.GetId()] = true
+	for id, rb := range s.restBreaksByID {
+		if rb.GetShiftTeamId() == shiftTeam.GetId() {
+			delete(s.restBreaksByID, id)
+			s.removedRestBreakIDs[id] = true
+		}
+	}
+	return nil
+}
+
+func (s *scheduleScenario) updateShiftTeam(m *logisticspb.ScheduleScenarioMutation_UpdateShiftTeam) error {
+	shiftTeam, ok := s.shiftTeamsByShiftTeamID[m.GetShiftTeamId()]
+	if !ok {
+		return fmt.Errorf("unknown shift team ID for update shift team mutation: %d", m.GetShiftTeamId())
+	}
+
+	tw := cloneTimeWindow(shiftTeam.GetAvailableTimeWindow())
+	if m.StartTimestampSec != nil {
+		tw.StartTimestampSec = proto.Int64(m.GetStartTimestampSec())
+	}
+	if m.EndTimestampSec != nil {
+		tw.EndTimestampSec = proto.Int64(m.GetEndTimestampSec())
+	}
+	if err := validateTimestamps(tw.GetStartTimestampSec(), tw.GetEndTimestampSec()); err != nil {
+		return fmt.Errorf("invalid update shift team mutation: %w", err)
+	}
+	shiftTeam.AvailableTimeWindow = tw
+	return nil
+}
+
+func (s *scheduleScenario) removeVisit(m *logisticspb.ScheduleScenarioMutation_RemoveVisit) error {
+	visit, ok := s.visitsByCareRequestID[m.GetCareRequestId()]
+	if !ok {
+		return fmt.Errorf("unknown care request ID for remove visit mutation: %d", m.GetCareRequestId())
+	}
+	if s.servedCareRequestIDs[m.GetCareRequestId()] {
+		return fmt.Errorf("historical (already served) care request ID cannot be removed: %d", m.GetCareRequestId())
+	}
+
+	delete(s.visitsByCareRequestID, m.GetCareRequestId())
+	s.removedVisitSnapshotIDs[visit.GetId()] = true
+	return nil
+}
+
+func (s *scheduleScenario) updateVisit(m *logisticspb.ScheduleScenarioMutation_UpdateVisit) error {
+	visit, ok := s.visitsByCareRequestID[m.GetCareRequestId()]
+	if !ok {
+		return fmt.Errorf("unknown care request ID for update visit mutation: %d", m.GetCareRequestId())
+	}
+	if s.servedCareRequestIDs[m.GetCareRequestId()] {
+		return fmt.Errorf("historical (already served) care request ID cannot be updated: %d", m.GetCareRequestId())
+	}
+
+	tw := cloneTimeWindow(visit.GetArrivalTimeWindow())
+	if m.ArrivalStartTimestampSec != nil {
+		tw.StartTimestampSec = proto.Int64(m.GetArrivalStartTimestampSec())
+	}
+	if m.ArrivalEndTimestampSec != nil {
+		tw.EndTimestampSec = proto.Int64(m.GetArrivalEndTimestampSec())
+	}
+	if err := validateTimestamps(tw.GetStartTimestampSec(), tw.GetEndTimestampSec()); err != nil {
+		return fmt.Errorf("invalid update visit mutation: %w", err)
+	}
+	if m.GetServiceDurationSec() < 0 {
+		return fmt.Errorf("invalid update visit mutation: negative service duration: %d", m.GetServiceDurationSec())
+	}
+
+	visit.ArrivalTimeWindow = tw
+	if m.ServiceDurationSec != nil {
+		visit.ServiceDurationSec = proto.Int64(m.GetServiceDurationSec())
+	}
+	return nil
+}
+
+func (s *scheduleScenario) updateOpenHours(m *logisticspb.ScheduleScenarioMutation_UpdateOpenHours) error {
+	if s.openHoursStartTimestampSec == 0 && s.openHoursEndTimestampSec == 0 {
+		return errors.New("unknown open hours for update open hours mutation")
+	}
+
+	start, end := s.openHoursStartTimestampSec, s.openHoursEndTimestampSec
+	if m.StartTimestampSec != nil {
+		start = m.GetStartTimestampSec()
+	}
+	if m.EndTimestampSec != nil {
+		end = m.GetEndTimestampSec()
+	}
+	if err := validateTimestamps(start, end); err != nil {
+		return fmt.Errorf("invalid update open hours mutation: %w", err)
+	}
+
+	moveTimeWindow := func(tw *optimizerpb.VRPTimeWindow) {
+		if tw == nil {
+			return
+		}
+		if tw.GetStartTimestampSec() == s.openHoursStartTimestampSec {
+			tw.StartTimestampSec = proto.Int64(start)
+		}
+		if tw.GetEndTimestampSec() == s.openHoursEndTimestampSec {
+			tw.EndTimestampSec = proto.Int64(end)
+		}
+	}
+	for id, shiftTeam := range s.shiftTeamsByShiftTeamID {
+		if !s.startedShiftTeamIDs[id] {
+			moveTimeWindow(shiftTeam.AvailableTimeWindow)
+			continue
+		}
+		// Shift teams that started their route keep their start.
+		if shiftTeam.GetAvailableTimeWindow().GetEndTimestampSec() == s.openHoursEndTimestampSec {
+			shiftTeam.AvailableTimeWindow.EndTimestampSec = proto.Int64(end)
+		}
+	}
+	for careRequestID, visit := range s.visitsByCareRequestID {
+		if !s.servedCareRequestIDs[careRequestID] {
+			moveTimeWindow(visit.ArrivalTimeWindow)
+		}
+	}
+
+	s.openHoursStartTimestampSec = start
+	s.openHoursEndTimestampSec = end
+	return nil
+}
+
+func (s *scheduleScenario) removeRestBreak(m *logisticspb.ScheduleScenarioMutation_RemoveRestBreak) error {
+	if _, ok := s.restBreaksByID[m.GetRestBreakId()]; !ok {
+		return fmt.Errorf("unknown rest break ID for remove rest break mutation: %d", m.GetRestBreakId())
+	}
+	if s.startedRestBreakIDs[m.GetRestBreakId()] {
+		return fmt.Errorf("historical (already started) rest break ID cannot be removed: %d", m.GetRestBreakId())
+	}
+
+	delete(s.restBreaksByID, m.GetRestBreakId())
+	s.removedRestBreakIDs[m.GetRestBreakId()] = true
+	return nil
+}
+
+func (s *scheduleScenario) updateRestBreak(m *logisticspb.ScheduleScenarioMutation_UpdateRestBreak) error {
+	restBreak, ok := s.restBreaksByID[m.GetRestBreakId()]
+	if !ok {
+		return fmt.Errorf("unknown rest break ID for update rest break mutation: %d", m.GetRestBreakId())
+	}
+	if s.startedRestBreakIDs[m.GetRestBreakId()] {
+		return fmt.Errorf("historical (already started) rest break ID cannot be updated: %d", m.GetRestBreakId())
+	}
+	if restBreak.GetUnrequested() && m.StartTimestampSec != nil {
+		return fmt.Errorf("start of unrequested rest break ID cannot be updated: %d", m.GetRestBreakId())
+	}
+	if m.DurationSec != nil && m.GetDurationSec() <= 0 {
+		return fmt.Errorf("invalid update rest break mutation: non-positive duration: %d", m.GetDurationSec())
+	}
+
+	if m.StartTimestampSec != nil {
+		restBreak.StartTimestampSec = proto.Int64(m.GetStartTimestampSec())
+	}
+	if m.DurationSec != nil {
+		restBreak.DurationSec = proto.Int64(m.GetDurationSec())
+	}
+	return nil
+}
+
+// vrpProblem returns the problem with all the applied mutations.
+func (s *scheduleScenario) vrpProblem() *optimizerpb.VRPProblem {
+	desc := s.problem.GetDescription()
+	if desc == nil {
+		return s.problem
+	}
+
+	var shiftTeams []*optimizerpb.VRPShiftTeam
+	for _, shiftTeam := range desc.ShiftTeams {
+		if s.removedShiftTeamSnapshotIDs[shiftTeam.GetId()] {
+			continue
+		}
+		if shiftTeam.UpcomingCommitments != nil {
+			var commitments []*optimizerpb.VRPShiftTeamCommitment
+			for _, commitment := range shiftTeam.UpcomingCommitments.Commitments {
+				if !s.removedVisitSnapshotIDs[commitment.GetVisitId()] {
+					commitments = append(commitments, commitment)
+				}
+			}
+			shiftTeam.UpcomingCommitments.Commitments = commitments
+		}
+		if shiftTeam.Route != nil {
+			var stops []*optimizerpb.VRPShiftTeamRouteStop
+			for _, stop := range shiftTeam.Route.Stops {
+				if s.removedVisitSnapshotIDs[stop.GetVisit().GetVisitId()] ||
+					s.removedRestBreakIDs[stop.GetRestBreak().GetRestBreakId()] {
+					continue
+				}
+				stops = append(stops, stop)
+			}
+			shiftTeam.Route.Stops = stops
+		}
+		shiftTeams = append(shiftTeams, shiftTeam)
+	}
+	desc.ShiftTeams = shiftTeams
+
+	var visits []*optimizerpb.VRPVisit
+	for _, visit := range desc.Visits {
+		if !s.removedVisitSnapshotIDs[visit.GetId()] {
+			visits = append(visits, visit)
+		}
+	}
+	desc.Visits = visits
+
+	var unassignedVisits []*optimizerpb.VRPUnassignedVisit
+	for _, uv := range desc.UnassignedVisits {
+		if !s.removedVisitSnapshotIDs[uv.GetVisitId()] {
+			unassignedVisits = append(unassignedVisits, uv)
+		}
+	}
+	desc.UnassignedVisits = unassignedVisits
+
+	var restBreaks []*optimizerpb.VRPRestBreak
+	for _, restBreak := range desc.RestBreaks {
+		if !s.removedRestBreakIDs[restBreak.GetId()] {
+			restBreaks = append(restBreaks, restBreak)
+		}
+	}
+	desc.RestBreaks = restBreaks
+
+	return s.problem
+}
+
+// applyScheduleScenarioMutations returns a copy of the problemData.VRPProblem with the mutations applied,
+// and the entity mappings including the added shift teams.
+func applyScheduleScenarioMutations(
+	mutations []*logisticspb.ScheduleScenarioMutation,
+	problemData *logisticsdb.VRPProblemData,
+) (*optimizerpb.VRPProblem, logisticsdb.EntityMappings, error) {
+	scenario, err := newScheduleScenario(problemData)
+	if err != nil {
+		return nil, logisticsdb.EntityMappings{}, err
+	}
+
+	for i, mutation := range mutations {
+		if err := scenario.apply(mutation); err != nil {
+			return nil, logisticsdb.EntityMappings{}, fmt.Errorf("mutation %d: %w", i, err)
+		}
+	}
+
+	return scenario.vrpProblem(), scenario.entityMappings, nil
+}
+
+func scheduleCareRequestIDs(schedule *logisticspb.ServiceRegionDateSchedule) map[int64][]int64 {
+	res := make(map[int64][]int64, len(schedule.GetSchedules()))
+	for _, shiftTeamSchedule := range schedule.GetSchedules() {
+		careRequestIDs := []int64{}
+		for _, stop := range shiftTeamSchedule.GetRoute().GetStops() {
+			if visit := stop.GetVisit(); visit != nil {
+				careRequestIDs = append(careRequestIDs, visit.GetCareRequestId())
+			}
+		}
+		res[shiftTeamSchedule.GetShiftTeamId()] = careRequestIDs
+	}
+	return res
+}
+
+func sortedInt64s(ids []int64) []int64 {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// diffScheduleRoutes returns the differences of the scenario schedule routes with the baseline schedule routes.
+func diffScheduleRoutes(baseline, scenario *logisticspb.ServiceRegionDateSchedule) *logisticspb.ScheduleRouteDiff {
+	baselineRoutes := scheduleCareRequestIDs(baseline)
+	scenarioRoutes := scheduleCareRequestIDs(scenario)
+
+	var shiftTeamIDs []int64
+	for id := range baselineRoutes {
+		shiftTeamIDs = append(shiftTeamIDs, id)
+	}
+	for id := range scenarioRoutes {
+		if _, ok := baselineRoutes[id]; !ok {
+			shiftTeamIDs = append(shiftTeamIDs, id)
+		}
+	}
+
+	diff := &logisticspb.ScheduleRouteDiff{}
+	baselineAssigned := map[int64]bool{}
+	scenarioAssigned := map[int64]bool{}
+	for _, shiftTeamID := range sortedInt64s(shiftTeamIDs) {
+		baselineRoute := baselineRoutes[shiftTeamID]
+		scenarioRoute := scenarioRoutes[shiftTeamID]
+		inBaseline := make(map[int64]bool, len(baselineRoute))
+		for _, id := range baselineRoute {
+			inBaseline[id] = true
+			baselineAssigned[id] = true
+		}
+		inScenario := make(map[int64]bool, len(scenarioRoute))
+		for _, id := range scenarioRoute {
+			inScenario[id] = true
+			scenarioAssigned[id] = true
+		}
+
+		routeDiff := &logisticspb.ShiftTeamRouteDiff{ShiftTeamId: shiftTeamID}
+		var commonBaseline, commonScenario []int64
+		for _, id := range scenarioRoute {
+			if inBaseline[id] {
+				commonScenario = append(commonScenario, id)
+			} else {
+				routeDiff.AddedCareRequestIds = append(routeDiff.AddedCareRequestIds, id)
+			}
+		}
+		for _, id := range baselineRoute {
+			if inScenario[id] {
+				commonBaseline = append(commonBaseline, id)
+			} else {
+				routeDiff.RemovedCareRequestIds = append(routeDiff.RemovedCareRequestIds, id)
+			}
+		}
+		for i := range commonBaseline {
+			if commonBaseline[i] != commonScenario[i] {
+				routeDiff.Reordered = true
+				break
+			}
+		}
+
+		if len(routeDiff.AddedCareRequestIds) > 0 || len(routeDiff.RemovedCareRequestIds) > 0 || routeDiff.Reordered {
+			diff.ShiftTeams = append(diff.ShiftTeams, routeDiff)
+		}
+	}
+
+	for id := range baselineAssigned {
+		if !scenarioAssigned[id] {
+			diff.NewlyUnassignedCareRequestIds = append(diff.NewlyUnassignedCareRequestIds, id)
+		}
+	}
+	for _, uv := range baseline.GetUnassignableVisits() {
+		if scenarioAssigned[uv.GetCareRequestId()] {
+			diff.NewlyAssignedCareRequestIds = append(diff.NewlyAssignedCareRequestIds, uv.GetCareRequestId())
+		}
+	}
+	sortedInt64s(diff.NewlyUnassignedCareRequestIds)
+	sortedInt64s(diff.NewlyAssignedCareRequestIds)
+
+	return diff
+}
+
+// SimulateScheduleScenario re-solves an optimizer run's problem with hypothetical changes, such as
+// an additional shift team or longer open hours, and compares the result with the optimizer run's schedule.
+func (s *GRPCServer) SimulateScheduleScenario(
+	ctx context.Context,
+	req *logisticspb.SimulateScheduleScenarioRequest,
+) (*logisticspb.SimulateScheduleScenarioResponse, error) {
+	if req.OptimizerRunId == nil {
+		return nil, status.Error(codes.InvalidArgument, "optimizer run id must be set")
+	}
+	if len(req.Mutations) == 0 {
+		return nil, status.Error(codes.InvalidArgument,
+			"must supply mutations for the scenario to be any different from the baseline schedule")
+	}
+
+	scheduleID, err := s.LogisticsDB.GetLatestScheduleIDForOptimizerRun(ctx, req.GetOptimizerRunId())
+	if err != nil {
+		if errors.Is(err, logisticsdb.ErrScheduleNotFound) {
+			return nil, status.Errorf(codes.NotFound, "schedule for optimizer run(%d) not found", req.GetOptimizerRunId())
+		}
+		return nil, status.Errorf(codes.Internal, "error in GetLatestScheduleIDForOptimizerRun: %s", err.Error())
+	}
+
+	baselineSchedule, err := s.LogisticsDB.GetShiftTeamsSchedulesFromScheduleID(ctx, scheduleID, s.now(), true)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal,
+			"error in GetShiftTeamsSchedulesFromScheduleID: %s",
+			err.Error())
+	}
+
+	baselineProblemData, err := s.LogisticsDB.VRPProblemDataForSchedule(ctx, scheduleID)
+	if err != nil {
+		if errors.Is(err, logisticsdb.ErrScheduleNotFound) {
+			return nil, status.Errorf(codes.NotFound, "schedule(%d) not found", scheduleID)
+		}
+		return nil, status.Errorf(codes.Internal, "error in VRPProblemDataForSchedule: %s", err.Error())
+	}
+
+	scenarioProblem, scenarioEntityMappings, err := applyScheduleScenarioMutations(req.Mutations, baselineProblemData)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"request inconsistent with optimizer run data: %s", err.Error())
+	}
+
+	result, err := s.solveCounterfactualVRP(ctx, scenarioProblem)
+	if err != nil {
+		return nil, err
+	}
+
+	scenarioSchedule, err := (&logisticsdb.CounterfactualSchedule{
+		CounterfactualSolution: result.Response.GetSolution(),
+		EntityMappings:         scenarioEntityMappings,
+		ServiceDate:            baselineSchedule.Schedule.GetMeta().GetServiceDate(),
+	}).Schedule()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "error in scheduleFromVRPSolution: %s", err.Error())
+	}
+
+	return &logisticspb.SimulateScheduleScenarioResponse{
+		BaselineSchedule: baselineSchedule.Schedule,
+		BaselineScore:    baselineSchedule.Score,
+		ScenarioSchedule: scenarioSchedule.Schedule,
+		ScenarioScore:    scenarioSchedule.Score,
+		RouteDiff:        diffScheduleRoutes(baselineSchedule.Schedule, scenarioSchedule.Schedule),
+	}, nil
+}
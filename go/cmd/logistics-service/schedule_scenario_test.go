@@ -0,0 +1,510 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func scheduleScenarioTestProblemData() *logisticsdb.VRPProblemData {
+	// NOTE: snapshot IDs are the station IDs + 100, for readability.
+	return &logisticsdb.VRPProblemData{
+		VRPProblem: &optimizerpb.VRPProblem{Description: &optimizerpb.VRPDescription{
+			ShiftTeams: []*optimizerpb.VRPShiftTeam{
+				{
+					Id:              proto.Int64(101),
+					DepotLocationId: proto.Int64(1),
+					AvailableTimeWindow: &optimizerpb.VRPTimeWindow{
+						StartTimestampSec: proto.Int64(1000),
+						EndTimestampSec:   proto.Int64(5000),
+					},
+					Attributes: []*optimizerpb.VRPAttribute{{Id: "attr"}},
+					RouteHistory: &optimizerpb.VRPShiftTeamRouteHistory{
+						Stops: []*optimizerpb.VRPShiftTeamRouteStop{
+							{Stop: &optimizerpb.VRPShiftTeamRouteStop_Visit{Visit: &optimizerpb.VRPShiftTeamVisit{VisitId: proto.Int64(111)}}},
+						},
+					},
+				},
+				{
+					Id:              proto.Int64(102),
+					DepotLocationId: proto.Int64(2),
+					AvailableTimeWindow: &optimizerpb.VRPTimeWindow{
+						StartTimestampSec: proto.Int64(2000),
+						EndTimestampSec:   proto.Int64(5000),
+					},
+					UpcomingCommitments: &optimizerpb.VRPShiftTeamCommitments{
+						Commitments: []*optimizerpb.VRPShiftTeamCommitment{{VisitId: proto.Int64(112)}},
+					},
+				},
+			},
+			Visits: []*optimizerpb.VRPVisit{
+				{
+					Id: proto.Int64(111),
+					ArrivalTimeWindow: &optimizerpb.VRPTimeWindow{
+						StartTimestampSec: proto.Int64(1000),
+						EndTimestampSec:   proto.Int64(2000),
+					},
+				},
+				{
+					Id: proto.Int64(112),
+					ArrivalTimeWindow: &optimizerpb.VRPTimeWindow{
+						StartTimestampSec: proto.Int64(3000),
+						EndTimestampSec:   proto.Int64(5000),
+					},
+					ServiceDurationSec: proto.Int64(600),
+				},
+			},
+			RestBreaks: []*optimizerpb.VRPRestBreak{
+				{Id: proto.Int64(21), ShiftTeamId: proto.Int64(102), DurationSec: proto.Int64(1800), StartTimestampSec: proto.Int64(4000)},
+			},
+			UnassignedVisits: []*optimizerpb.VRPUnassignedVisit{{VisitId: proto.Int64(112)}},
+		}},
+		OptimizerRun: &logisticssql.OptimizerRun{
+			OpenHoursStartTimestampSec: 1000,
+			OpenHoursEndTimestampSec:   5000,
+		},
+		EntityMappings: logisticsdb.EntityMappings{
+			CareRequests: map[logisticsdb.VisitSnapshotID]logisticsdb.CareRequestID{
+				111: 11,
+				112: 12,
+			},
+			ShiftTeams: map[logisticsdb.ShiftTeamSnapshotID]logisticsdb.ShiftTeamID{
+				101: 1,
+				102: 2,
+			},
+		},
+	}
+}
+
+func TestApplyScheduleScenarioMutations(t *testing.T) {
+	mutation := func(m any) *logisticspb.ScheduleScenarioMutation {
+		switch m := m.(type) {
+		case *logisticspb.ScheduleScenarioMutation_AddShiftTeam:
+			return &logisticspb.ScheduleScenarioMutation{Mutation: &logisticspb.ScheduleScenarioMutation_AddShiftTeam_{AddShiftTeam: m}}
+		case *logisticspb.ScheduleScenarioMutation_RemoveShiftTeam:
+			return &logisticspb.ScheduleScenarioMutation{Mutation: &logisticspb.ScheduleScenarioMutation_RemoveShiftTeam_{RemoveShiftTeam: m}}
+		case *logisticspb.ScheduleScenarioMutation_UpdateShiftTeam:
+			return &logisticspb.ScheduleScenarioMutation{Mutation: &logisticspb.ScheduleScenarioMutation_UpdateShiftTeam_{UpdateShiftTeam: m}}
+		case *logisticspb.ScheduleScenarioMutation_RemoveVisit:
+			return &logisticspb.ScheduleScenarioMutation{Mutation: &logisticspb.ScheduleScenarioMutation_RemoveVisit_{RemoveVisit: m}}
+		case *logisticspb.ScheduleScenarioMutation_UpdateVisit:
+			return &logisticspb.ScheduleScenarioMutation{Mutation: &logisticspb.ScheduleScenarioMutation_UpdateVisit_{UpdateVisit: m}}
+		case *logisticspb.ScheduleScenarioMutation_UpdateOpenHours:
+			return &logisticspb.ScheduleScenarioMutation{Mutation: &logisticspb.ScheduleScenarioMutation_UpdateOpenHours_{UpdateOpenHours: m}}
+		case *logisticspb.ScheduleScenarioMutation_RemoveRestBreak:
+			return &logisticspb.ScheduleScenarioMutation{Mutation: &logisticspb.ScheduleScenarioMutation_RemoveRestBreak_{RemoveRestBreak: m}}
+		case *logisticspb.ScheduleScenarioMutation_UpdateRestBreak:
+			return &logisticspb.ScheduleScenarioMutation{Mutation: &logisticspb.ScheduleScenarioMutation_UpdateRestBreak_{UpdateRestBreak: m}}
+		}
+		return &logisticspb.ScheduleScenarioMutation{}
+	}
+
+	tcs := []struct {
+		Desc      string
+		Mutations []*logisticspb.ScheduleScenarioMutation
+
+		HasErr      bool
+		Expected    func(desc *optimizerpb.VRPDescription)
+		ExpectedMap map[logisticsdb.ShiftTeamSnapshotID]logisticsdb.ShiftTeamID
+	}{
+		{
+			Desc: "add shift team",
+			Mutations: []*logisticspb.ScheduleScenarioMutation{
+				mutation(&logisticspb.ScheduleScenarioMutation_AddShiftTeam{TemplateShiftTeamId: 1, StartTimestampSec: 1500, EndTimestampSec: 4500}),
+				mutation(&logisticspb.ScheduleScenarioMutation_AddShiftTeam{TemplateShiftTeamId: 2, StartTimestampSec: 1500, EndTimestampSec: 4500}),
+			},
+
+			Expected: func(desc *optimizerpb.VRPDescription) {
+				desc.ShiftTeams = append(desc.ShiftTeams,
+					&optimizerpb.VRPShiftTeam{
+						Id:              proto.Int64(-1),
+						DepotLocationId: proto.Int64(1),
+						AvailableTimeWindow: &optimizerpb.VRPTimeWindow{
+							StartTimestampSec: proto.Int64(1500),
+							EndTimestampSec:   proto.Int64(4500),
+						},
+						Attributes: []*optimizerpb.VRPAttribute{{Id: "attr"}},
+					},
+					&optimizerpb.VRPShiftTeam{
+						Id:              proto.Int64(-2),
+						DepotLocationId: proto.Int64(2),
+						AvailableTimeWindow: &optimizerpb.VRPTimeWindow{
+							StartTimestampSec: proto.Int64(1500),
+							EndTimestampSec:   proto.Int64(4500),
+						},
+					})
+			},
+			ExpectedMap: map[logisticsdb.ShiftTeamSnapshotID]logisticsdb.ShiftTeamID{101: 1, 102: 2, -1: -1, -2: -2},
+		},
+		{
+			Desc: "remove shift team",
+			Mutations: []*logisticspb.ScheduleScenarioMutation{
+				mutation(&logisticspb.ScheduleScenarioMutation_RemoveShiftTeam{ShiftTeamId: 2}),
+			},
+
+			Expected: func(desc *optimizerpb.VRPDescription) {
+				desc.ShiftTeams = desc.ShiftTeams[:1]
+				desc.RestBreaks = nil
+			},
+		},
+		{
+			Desc: "update shift team",
+			Mutations: []*logisticspb.ScheduleScenarioMutation{
+				mutation(&logisticspb.ScheduleScenarioMutation_UpdateShiftTeam{ShiftTeamId: 2, EndTimestampSec: proto.Int64(6000)}),
+			},
+
+			Expected: func(desc *optimizerpb.VRPDescription) {
+				desc.ShiftTeams[1].AvailableTimeWindow.EndTimestampSec = proto.Int64(6000)
+			},
+		},
+		{
+			Desc: "remove visit",
+			Mutations: []*logisticspb.ScheduleScenarioMutation{
+				mutation(&logisticspb.ScheduleScenarioMutation_RemoveVisit{CareRequestId: 12}),
+			},
+
+			Expected: func(desc *optimizerpb.VRPDescription) {
+				desc.Visits = desc.Visits[:1]
+				desc.UnassignedVisits = nil
+				desc.ShiftTeams[1].UpcomingCommitments.Commitments = nil
+			},
+		},
+		{
+			Desc: "update visit",
+			Mutations: []*logisticspb.ScheduleScenarioMutation{
+				mutation(&logisticspb.ScheduleScenarioMutation_UpdateVisit{
+					CareRequestId:            12,
+					ArrivalStartTimestampSec: proto.Int64(2500),
+					ServiceDurationSec:       proto.Int64(1200),
+				}),
+			},
+
+			Expected: func(desc *optimizerpb.VRPDescription) {
+				desc.Visits[1].ArrivalTimeWindow.StartTimestampSec = proto.Int64(2500)
+				desc.Visits[1].ServiceDurationSec = proto.Int64(1200)
+			},
+		},
+		{
+			Desc: "update open hours",
+			Mutations: []*logisticspb.ScheduleScenarioMutation{
+				mutation(&logisticspb.ScheduleScenarioMutation_UpdateOpenHours{
+					StartTimestampSec: proto.Int64(500),
+					EndTimestampSec:   proto.Int64(6000),
+				}),
+			},
+
+			Expected: func(desc *optimizerpb.VRPDescription) {
+				// Started shift team and served visit keep their start.
+				desc.ShiftTeams[0].AvailableTimeWindow.EndTimestampSec = proto.Int64(6000)
+				desc.ShiftTeams[1].AvailableTimeWindow.EndTimestampSec = proto.Int64(6000)
+				desc.Visits[1].ArrivalTimeWindow.EndTimestampSec = proto.Int64(6000)
+			},
+		},
+		{
+			Desc: "update rest break",
+			Mutations: []*logisticspb.ScheduleScenarioMutation{
+				mutation(&logisticspb.ScheduleScenarioMutation_UpdateRestBreak{RestBreakId: 21, StartTimestampSec: proto.Int64(3500)}),
+			},
+
+			Expected: func(desc *optimizerpb.VRPDescription) {
+				desc.RestBreaks[0].StartTimestampSec = proto.Int64(3500)
+			},
+		},
+		{
+			Desc: "remove rest break",
+			Mutations: []*logisticspb.ScheduleScenarioMutation{
+				mutation(&logisticspb.ScheduleScenarioMutation_RemoveRestBreak{RestBreakId: 21}),
+			},
+
+			Expected: func(desc *optimizerpb.VRPDescription) {
+				desc.RestBreaks = nil
+			},
+		},
+		{
+			Desc: "unknown template shift team",
+			Mutations: []*logisticspb.ScheduleScenarioMutation{
+				mutation(&logisticspb.ScheduleScenarioMutation_AddShiftTeam{TemplateShiftTeamId: 3, StartTimestampSec: 1500, EndTimestampSec: 4500}),
+			},
+
+			HasErr: true,
+		},
+		{
+			Desc: "invalid shift team time window",
+			Mutations: []*logisticspb.ScheduleScenarioMutation{
+				mutation(&logisticspb.ScheduleScenarioMutation_UpdateShiftTeam{ShiftTeamId: 2, EndTimestampSec: proto.Int64(1000)}),
+			},
+
+			HasErr: true,
+		},
+		{
+			Desc: "started shift team cannot be removed",
+			Mutations: []*logisticspb.ScheduleScenarioMutation{
+				mutation(&logisticspb.ScheduleScenarioMutation_RemoveShiftTeam{ShiftTeamId: 1}),
+			},
+
+			HasErr: true,
+		},
+		{
+			Desc: "served visit cannot be removed",
+			Mutations: []*logisticspb.ScheduleScenarioMutation{
+				mutation(&logisticspb.ScheduleScenarioMutation_RemoveVisit{CareRequestId: 11}),
+			},
+
+			HasErr: true,
+		},
+		{
+			Desc: "rest break of removed shift team cannot be updated",
+			Mutations: []*logisticspb.ScheduleScenarioMutation{
+				mutation(&logisticspb.ScheduleScenarioMutation_RemoveShiftTeam{ShiftTeamId: 2}),
+				mutation(&logisticspb.ScheduleScenarioMutation_UpdateRestBreak{RestBreakId: 21, DurationSec: proto.Int64(600)}),
+			},
+
+			HasErr: true,
+		},
+		{
+			Desc:      "unhandled mutation",
+			Mutations: []*logisticspb.ScheduleScenarioMutation{{}},
+
+			HasErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			problemData := scheduleScenarioTestProblemData()
+			originalProblem := proto.Clone(problemData.VRPProblem).(*optimizerpb.VRPProblem)
+
+			problem, entityMappings, err := applyScheduleScenarioMutations(tc.Mutations, problemData)
+			testutils.MustMatch(t, tc.HasErr, err != nil, err)
+			testutils.MustMatch(t, originalProblem, problemData.VRPProblem, "baseline problem is not modified")
+			if tc.HasErr {
+				return
+			}
+
+			expected := proto.Clone(originalProblem).(*optimizerpb.VRPProblem)
+			tc.Expected(expected.Description)
+			testutils.MustMatch(t, expected, problem)
+
+			expectedMap := tc.ExpectedMap
+			if expectedMap == nil {
+				expectedMap = problemData.EntityMappings.ShiftTeams
+			}
+			testutils.MustMatch(t, expectedMap, entityMappings.ShiftTeams)
+		})
+	}
+}
+
+func TestDiffScheduleRoutes(t *testing.T) {
+	schedule := func(routes map[int64][]int64, unassigned ...int64) *logisticspb.ServiceRegionDateSchedule {
+		s := &logisticspb.ServiceRegionDateSchedule{}
+		for shiftTeamID, careRequestIDs := range routes {
+			route := &logisticspb.ShiftTeamRoute{}
+			for _, id := range careRequestIDs {
+				route.Stops = append(route.Stops, &logisticspb.ShiftTeamRouteStop{
+					Stop: &logisticspb.ShiftTeamRouteStop_Visit{Visit: &logisticspb.ShiftTeamVisit{CareRequestId: proto.Int64(id)}},
+				})
+			}
+			route.Stops = append(route.Stops, &logisticspb.ShiftTeamRouteStop{
+				Stop: &logisticspb.ShiftTeamRouteStop_RestBreak{RestBreak: &logisticspb.ShiftTeamRestBreak{RestBreakId: 1}},
+			})
+			s.Schedules = append(s.Schedules, &logisticspb.ShiftTeamSchedule{ShiftTeamId: shiftTeamID, Route: route})
+		}
+		for _, id := range unassigned {
+			s.UnassignableVisits = append(s.UnassignableVisits, &logisticspb.UnassignableVisit{CareRequestId: proto.Int64(id)})
+		}
+		return s
+	}
+
+	tcs := []struct {
+		Desc     string
+		Baseline *logisticspb.ServiceRegionDateSchedule
+		Scenario *logisticspb.ServiceRegionDateSchedule
+
+		Expected *logisticspb.ScheduleRouteDiff
+	}{
+		{
+			Desc:     "same schedule",
+			Baseline: schedule(map[int64][]int64{1: {10, 11}}, 12),
+			Scenario: schedule(map[int64][]int64{1: {10, 11}}, 12),
+
+			Expected: &logisticspb.ScheduleRouteDiff{},
+		},
+		{
+			Desc:     "reordered",
+			Baseline: schedule(map[int64][]int64{1: {10, 11, 12}}),
+			Scenario: schedule(map[int64][]int64{1: {11, 13, 10, 12}}),
+
+			Expected: &logisticspb.ScheduleRouteDiff{
+				ShiftTeams: []*logisticspb.ShiftTeamRouteDiff{
+					{ShiftTeamId: 1, AddedCareRequestIds: []int64{13}, Reordered: true},
+				},
+			},
+		},
+		{
+			Desc:     "moved to added shift team",
+			Baseline: schedule(map[int64][]int64{1: {10, 11}}, 12),
+			Scenario: schedule(map[int64][]int64{1: {10}, -1: {12, 11}}),
+
+			Expected: &logisticspb.ScheduleRouteDiff{
+				ShiftTeams: []*logisticspb.ShiftTeamRouteDiff{
+					{ShiftTeamId: -1, AddedCareRequestIds: []int64{12, 11}},
+					{ShiftTeamId: 1, RemovedCareRequestIds: []int64{11}},
+				},
+				NewlyAssignedCareRequestIds: []int64{12},
+			},
+		},
+		{
+			Desc:     "removed shift team",
+			Baseline: schedule(map[int64][]int64{1: {10}, 2: {11, 12}}),
+			Scenario: schedule(map[int64][]int64{1: {10, 12}}, 11),
+
+			Expected: &logisticspb.ScheduleRouteDiff{
+				ShiftTeams: []*logisticspb.ShiftTeamRouteDiff{
+					{ShiftTeamId: 1, AddedCareRequestIds: []int64{12}},
+					{ShiftTeamId: 2, RemovedCareRequestIds: []int64{11, 12}},
+				},
+				NewlyUnassignedCareRequestIds: []int64{11},
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			testutils.MustMatch(t, tc.Expected, diffScheduleRoutes(tc.Baseline, tc.Scenario))
+		})
+	}
+}
+
+func TestGRPCServer_SimulateScheduleScenario(t *testing.T) {
+	ctx := context.Background()
+	optimizerRunID := int64(1)
+
+	baselineSchedule := &logisticsdb.ScheduleAndDebugScore{
+		Schedule: &logisticspb.ServiceRegionDateSchedule{
+			Schedules: []*logisticspb.ShiftTeamSchedule{
+				{ShiftTeamId: 2, Route: &logisticspb.ShiftTeamRoute{Stops: []*logisticspb.ShiftTeamRouteStop{
+					{Stop: &logisticspb.ShiftTeamRouteStop_Visit{
+						Visit: &logisticspb.ShiftTeamVisit{CareRequestId: proto.Int64(12)},
+					}},
+				}}},
+			}},
+		Score: &optimizerpb.VRPScore{IsValid: proto.Bool(true), HardScore: proto.Int64(1)},
+	}
+	validLDB := &MockLogisticsDB{
+		GetLatestScheduleIDForOptimizerRunResult:   3,
+		GetShiftTeamsSchedulesFromScheduleIDResult: baselineSchedule,
+		VRPProblemDataForScheduleResult:            scheduleScenarioTestProblemData(),
+	}
+	validReq := &logisticspb.SimulateScheduleScenarioRequest{
+		OptimizerRunId: proto.Int64(optimizerRunID),
+		Mutations: []*logisticspb.ScheduleScenarioMutation{
+			{Mutation: &logisticspb.ScheduleScenarioMutation_RemoveShiftTeam_{
+				RemoveShiftTeam: &logisticspb.ScheduleScenarioMutation_RemoveShiftTeam{ShiftTeamId: 2},
+			}},
+		},
+	}
+	scheduleNotFoundLDB := *validLDB
+	scheduleNotFoundLDB.GetLatestScheduleIDForOptimizerRunErr = logisticsdb.ErrScheduleNotFound
+
+	tcs := []struct {
+		Desc      string
+		Req       *logisticspb.SimulateScheduleScenarioRequest
+		LDB       LogisticsDB
+		VRPSolver *MockVRPSolver
+
+		ExpectedCode codes.Code
+	}{
+		{
+			Desc:      "base case",
+			Req:       validReq,
+			LDB:       validLDB,
+			VRPSolver: &MockVRPSolver{hardScores: []int64{0}, isValidScore: true},
+
+			ExpectedCode: codes.OK,
+		},
+		{
+			Desc:      "no optimizer run",
+			Req:       &logisticspb.SimulateScheduleScenarioRequest{Mutations: validReq.Mutations},
+			LDB:       validLDB,
+			VRPSolver: &MockVRPSolver{hardScores: []int64{0}, isValidScore: true},
+
+			ExpectedCode: codes.InvalidArgument,
+		},
+		{
+			Desc:      "no mutations",
+			Req:       &logisticspb.SimulateScheduleScenarioRequest{OptimizerRunId: proto.Int64(optimizerRunID)},
+			LDB:       validLDB,
+			VRPSolver: &MockVRPSolver{hardScores: []int64{0}, isValidScore: true},
+
+			ExpectedCode: codes.InvalidArgument,
+		},
+		{
+			Desc: "inconsistent mutation",
+			Req: &logisticspb.SimulateScheduleScenarioRequest{
+				OptimizerRunId: proto.Int64(optimizerRunID),
+				Mutations: []*logisticspb.ScheduleScenarioMutation{
+					{Mutation: &logisticspb.ScheduleScenarioMutation_RemoveShiftTeam_{
+						RemoveShiftTeam: &logisticspb.ScheduleScenarioMutation_RemoveShiftTeam{ShiftTeamId: 999},
+					}},
+				},
+			},
+			LDB:       validLDB,
+			VRPSolver: &MockVRPSolver{hardScores: []int64{0}, isValidScore: true},
+
+			ExpectedCode: codes.InvalidArgument,
+		},
+		{
+			Desc:      "schedule not found",
+			Req:       validReq,
+			LDB:       &scheduleNotFoundLDB,
+			VRPSolver: &MockVRPSolver{hardScores: []int64{0}, isValidScore: true},
+
+			ExpectedCode: codes.NotFound,
+		},
+		{
+			Desc:      "LDB internal error VRPProblemDataForSchedule",
+			Req:       validReq,
+			LDB:       validLDB.WithVRPProblemDataForScheduleErr(errors.New("unknown error maps to Internal")),
+			VRPSolver: &MockVRPSolver{hardScores: []int64{0}, isValidScore: true},
+
+			ExpectedCode: codes.Internal,
+		},
+		{
+			Desc:      "SolveVRP error",
+			Req:       validReq,
+			LDB:       validLDB,
+			VRPSolver: &MockVRPSolver{err: errors.New("solve failed")},
+
+			ExpectedCode: codes.Internal,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			s := &GRPCServer{LogisticsDB: tc.LDB, VRPSolver: tc.VRPSolver}
+			resp, err := s.SimulateScheduleScenario(ctx, tc.Req)
+			testutils.MustMatch(t, tc.ExpectedCode, status.Code(err), err)
+			if tc.ExpectedCode != codes.OK {
+				return
+			}
+
+			testutils.MustMatch(t, baselineSchedule.Schedule, resp.BaselineSchedule)
+			testutils.MustMatch(t, baselineSchedule.Score, resp.BaselineScore)
+			testutils.MustMatch(t, &logisticspb.ScheduleRouteDiff{
+				ShiftTeams: []*logisticspb.ShiftTeamRouteDiff{
+					{ShiftTeamId: 2, RemovedCareRequestIds: []int64{12}},
+				},
+				NewlyUnassignedCareRequestIds: []int64{12},
+			}, resp.RouteDiff)
+		})
+	}
+}
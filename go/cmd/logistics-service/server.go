@@ -28,6 +28,7 @@ type Server struct {
 
 	GoogleMapsHealthChecker HealthChecker
 	OSRMHealthChecker       HealthChecker
+	ValhallaHealthChecker   HealthChecker
 }
 
 // TODO: Move this generic handler to a shared package once fleshed out.
@@ -201,6 +202,9 @@ func (s *Server) healthCheck(w http.ResponseWriter, r *http.Request) {
 	if s.OSRMHealthChecker != nil {
 		status.Diagnostics.OSRM = s.OSRMHealthChecker.IsHealthy(ctx)
 	}
+	if s.ValhallaHealthChecker != nil {
+		status.Diagnostics.Valhalla = s.ValhallaHealthChecker.IsHealthy(ctx)
+	}
 
 	buf, err := json.MarshalIndent(status, "", "  ")
 	if err != nil {
@@ -235,6 +239,7 @@ type healthCheckStatus struct {
 	Diagnostics struct {
 		GoogleMaps bool   `json:"googlemaps"`
 		OSRM       bool   `json:"osrm"`
+		Valhalla   bool   `json:"valhalla"`
 		Version    string `json:"version"`
 	} `json:"diagnostics"`
 }
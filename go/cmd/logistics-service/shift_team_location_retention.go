@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/monitoring"
+	"go.uber.org/zap"
+)
+
+const (
+	shiftTeamLocationRetentionMeasurementName = "shift_team_location_retention"
+
+	deletedLocationsField = "deleted_locations"
+)
+
+type ShiftTeamLocationRetentionLogisticsDB interface {
+	DeleteShiftTeamLocationsCreatedBefore(ctx context.Context, createdBefore time.Time) (int64, error)
+}
+
+// ShiftTeamLocationRetention periodically deletes the shift team locations older than the retention window,
+// keeping the latest location of each shift team.
+// Locations within the window are kept as breadcrumbs for actual vs planned route analytics.
+type ShiftTeamLocationRetention struct {
+	ldb       ShiftTeamLocationRetentionLogisticsDB
+	retention time.Duration
+	interval  time.Duration
+	logger    *zap.SugaredLogger
+	metrics   monitoring.Scope
+
+	// Clock for mocking in tests. Nil clock will use the system clock.
+	clock Clock
+}
+
+func NewShiftTeamLocationRetention(
+	ldb ShiftTeamLocationRetentionLogisticsDB,
+	retention time.Duration,
+	interval time.Duration,
+	logger *zap.SugaredLogger,
+	metrics monitoring.Scope,
+) *ShiftTeamLocationRetention {
+	return &ShiftTeamLocationRetention{
+		ldb:       ldb,
+		retention: retention,
+		interval:  interval,
+		logger:    logger.Named("shift_team_location_retention"),
+		metrics:   metrics,
+	}
+}
+
+func (r *ShiftTeamLocationRetention) now() time.Time {
+	if r.clock != nil {
+		return r.clock.Now()
+	}
+
+	return time.Now()
+}
+
+func (r *ShiftTeamLocationRetention) Start(ctx context.Context) {
+	go func() {
+		for {
+			r.deleteExpiredLocations(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+
+			case <-time.After(r.interval):
+				continue
+			}
+		}
+	}()
+}
+
+func (r *ShiftTeamLocationRetention) deleteExpiredLocations(ctx context.Context) {
+	createdBefore := r.now().Add(-r.retention)
+	deleted, err := r.ldb.DeleteShiftTeamLocationsCreatedBefore(ctx, createdBefore)
+	if err != nil {
+		r.logger.Errorw("Could not delete expired shift team locations",
+			"created_before", createdBefore,
+			zap.Error(err))
+	}
+	r.metrics.WritePoint(shiftTeamLocationRetentionMeasurementName, nil, monitoring.Fields{
+		deletedLocationsField: deleted,
+	})
+}
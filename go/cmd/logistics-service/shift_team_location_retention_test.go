@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/monitoring"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"go.uber.org/zap"
+)
+
+type mockShiftTeamLocationRetentionLDB struct {
+	deleted int64
+	err     error
+
+	createdBefore []time.Time
+}
+
+func (m *mockShiftTeamLocationRetentionLDB) DeleteShiftTeamLocationsCreatedBefore(ctx context.Context, createdBefore time.Time) (int64, error) {
+	m.createdBefore = append(m.createdBefore, createdBefore)
+	return m.deleted, m.err
+}
+
+func TestShiftTeamLocationRetention_deleteExpiredLocations(t *testing.T) {
+	now := time.Date(2023, time.September, 16, 10, 0, 0, 0, time.UTC)
+
+	tcs := []struct {
+		Desc string
+		LDB  *mockShiftTeamLocationRetentionLDB
+	}{
+		{
+			Desc: "base case",
+			LDB:  &mockShiftTeamLocationRetentionLDB{deleted: 3},
+		},
+		{
+			Desc: "delete error",
+			LDB:  &mockShiftTeamLocationRetentionLDB{err: errors.New("boo")},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			r := NewShiftTeamLocationRetention(tc.LDB, 24*time.Hour, time.Hour, zap.NewNop().Sugar(), monitoring.NewMockScope())
+			r.clock = MockClock(now)
+
+			r.deleteExpiredLocations(context.Background())
+
+			testutils.MustMatch(t, []time.Time{now.Add(-24 * time.Hour)}, tc.LDB.createdBefore)
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/generated/proto/common"
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/checkfeasibility"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Maximum number of candidate time windows checked in a single slot offering request.
+const maxVisitSlotCandidates = 48
+
+// visitSlotCandidate is a candidate arrival time window of a visit, with the position of its feasibility problems
+// in the slot offering tree.
+type visitSlotCandidate struct {
+	timeWindow *common.TimeWindow
+	// index of the candidate in the request, to break ties between equally scored slots.
+	index int
+
+	childIndex    int
+	baselineIndex int
+	numLeaves     int
+}
+
+// visitWithArrivalDate returns a copy of the visit arriving on the service date.
+func visitWithArrivalDate(visit *logisticspb.CheckFeasibilityVisit, serviceDate time.Time) *logisticspb.CheckFeasibilityVisit {
+	res := proto.Clone(visit).(*logisticspb.CheckFeasibilityVisit)
+	res.ArrivalTimeSpecification = &logisticspb.CheckFeasibilityVisit_ArrivalDate{ArrivalDate: logisticsdb.TimeToProtoDate(&serviceDate)}
+	return res
+}
+
+// visitWithArrivalTimeWindow returns a copy of the visit arriving in the time window.
+func visitWithArrivalTimeWindow(visit *logisticspb.CheckFeasibilityVisit, tw *common.TimeWindow) *logisticspb.CheckFeasibilityVisit {
+	res := proto.Clone(visit).(*logisticspb.CheckFeasibilityVisit)
+	res.ArrivalTimeSpecification = &logisticspb.CheckFeasibilityVisit_ArrivalTimeWindow{ArrivalTimeWindow: tw}
+	return res
+}
+
+// OfferVisitSlots checks the feasibility of a visit in each of the candidate time windows, building the VRP problem
+// and distance matrix once per service date, and returns the feasible ones ranked by how little they degrade the schedule.
+func (s *GRPCServer) OfferVisitSlots(
+	ctx context.Context,
+	req *logisticspb.OfferVisitSlotsRequest,
+) (*logisticspb.OfferVisitSlotsResponse, error) {
+	visit := req.GetVisit()
+	if visit == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "visit is required")
+	}
+	if visit.MarketId == nil {
+		return nil, errMarketIDRequired
+	}
+	candidateTWs := req.GetCandidateTimeWindows()
+	if len(candidateTWs) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "at least one candidate time window is required")
+	}
+	if len(candidateTWs) > maxVisitSlotCandidates {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d candidate time windows are allowed", maxVisitSlotCandidates)
+	}
+	if req.MaxSlots != nil && req.GetMaxSlots() <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "max slots must be positive")
+	}
+
+	serviceRegion, err := s.LogisticsDB.GetServiceRegionForStationMarketID(ctx, visit.GetMarketId())
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, serviceRegionNotFoundForMarketID, err)
+	}
+
+	var serviceDates []time.Time
+	candidatesByDate := map[time.Time][]*visitSlotCandidate{}
+	for i, tw := range candidateTWs {
+		serviceDate, err := dateForCheckFeasibilityVisit(visitWithArrivalTimeWindow(visit, tw), serviceRegion.IanaTimeZoneName)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, couldNotDetermineDateForVisit, err)
+		}
+		if _, ok := candidatesByDate[*serviceDate]; !ok {
+			serviceDates = append(serviceDates, *serviceDate)
+		}
+		candidatesByDate[*serviceDate] = append(candidatesByDate[*serviceDate], &visitSlotCandidate{timeWindow: tw, index: i})
+	}
+
+	now := s.now()
+	var children []*checkfeasibility.FeasibilityTree
+	var candidates []*visitSlotCandidate
+	for _, serviceDate := range serviceDates {
+		dateCandidates := candidatesByDate[serviceDate]
+		// The problem of the date does not depend on the candidates, whose time windows are only set on the feasibility visits.
+		vrpData, err := s.LogisticsDB.GetServiceRegionVRPData(ctx, &logisticsdb.ServiceRegionVRPDataParams{
+			ServiceRegionID:       serviceRegion.ID,
+			ServiceDate:           serviceDate,
+			CheckFeasibilityVisit: visitWithArrivalDate(visit, serviceDate),
+			SnapshotTime:          now,
+		})
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "error getting service region VRP data %s", err)
+		}
+		cfData := vrpData.CheckFeasibilityData
+		if cfData == nil {
+			continue
+		}
+
+		baseVRPInput, err := s.createFeasibilityVRPRequest(ctx, vrpData)
+		if err != nil {
+			if errors.Is(err, errFeasibilityWindowHasPassed) {
+				continue
+			}
+			return nil, status.Errorf(codes.Internal, "error in createFeasibilityVRPRequest: %s", err)
+		}
+
+		baselineIndex := len(children)
+		children = append(children, checkfeasibility.NewFeasibilityLeaf(baseVRPInput))
+		for _, candidate := range dateCandidates {
+			arrivalTW, blackoutZone, err := checkFeasibilityVisitArrivalTimeWindow(visitWithArrivalTimeWindow(visit, candidate.timeWindow), vrpData)
+			if err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "couldn't determine time window for visit: %s", err)
+			}
+			if blackoutZone != nil {
+				continue
+			}
+
+			candidateVisits := make([]*logisticspb.CheckFeasibilityVisit, len(cfData.Visits))
+			for i, cfVisit := range cfData.Visits {
+				candidateVisits[i] = visitWithArrivalTimeWindow(cfVisit, arrivalTW)
+			}
+			leaves, _, err := s.feasibilityLeavesForFeasibilityVisits(candidateVisits, baseVRPInput, cfData.LocIDs)
+			if err != nil {
+				if errors.Is(err, logisticsdb.ErrFeasibilityInThePast) {
+					continue
+				}
+				return nil, status.Errorf(codes.Internal, "error creating feasibility problems: %s", err)
+			}
+			if len(leaves) == 0 {
+				continue
+			}
+
+			candidate.childIndex = len(children)
+			candidate.baselineIndex = baselineIndex
+			candidate.numLeaves = len(leaves)
+			candidates = append(candidates, candidate)
+			children = append(children, checkfeasibility.NewFeasibilityTree(
+				logisticspb.CheckFeasibilityResponse_STATUS_MARKET_PARTIALLY_FEASIBLE_LOCATION_LIMITED,
+				leaves...,
+			))
+		}
+	}
+	if len(candidates) == 0 {
+		return &logisticspb.OfferVisitSlotsResponse{}, nil
+	}
+
+	results, err := checkfeasibility.NewFeasibilityTree(
+		logisticspb.CheckFeasibilityResponse_STATUS_UNSPECIFIED,
+		children...,
+	).EvaluateChildren(ctx, s.VRPSolver, false)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "check feasibility error: %s", err)
+	}
+
+	var slots []*logisticspb.VisitSlot
+	var slotIndices []int
+	for _, candidate := range candidates {
+		result := results[candidate.childIndex]
+		if result.Status != logisticspb.CheckFeasibilityResponse_STATUS_FEASIBLE {
+			continue
+		}
+
+		numLeaves := int64(candidate.numLeaves)
+		baselineSoftScore := results[candidate.baselineIndex].SoftScore
+		slots = append(slots, &logisticspb.VisitSlot{
+			TimeWindow: candidate.timeWindow,
+			Score:      (result.SoftScore - numLeaves*baselineSoftScore) / numLeaves,
+		})
+		slotIndices = append(slotIndices, candidate.index)
+	}
+
+	sort.Sort(visitSlotsByScore{slots: slots, indices: slotIndices})
+	if req.MaxSlots != nil && len(slots) > int(req.GetMaxSlots()) {
+		slots = slots[:req.GetMaxSlots()]
+	}
+
+	return &logisticspb.OfferVisitSlotsResponse{Slots: slots}, nil
+}
+
+// visitSlotsByScore sorts slots best first, then by their order in the request.
+type visitSlotsByScore struct {
+	slots   []*logisticspb.VisitSlot
+	indices []int
+}
+
+func (s visitSlotsByScore) Len() int {
+	return len(s.slots)
+}
+
+func (s visitSlotsByScore) Less(i, j int) bool {
+	if s.slots[i].Score != s.slots[j].Score {
+		return s.slots[i].Score > s.slots[j].Score
+	}
+	return s.indices[i] < s.indices[j]
+}
+
+func (s visitSlotsByScore) Swap(i, j int) {
+	s.slots[i], s.slots[j] = s.slots[j], s.slots[i]
+	s.indices[i], s.indices[j] = s.indices[j], s.indices[i]
+}
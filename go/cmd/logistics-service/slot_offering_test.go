@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	commonpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/common"
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/optimizer/optimizersettings"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func slotOfferingTestTimeWindow(start time.Time, duration time.Duration) *commonpb.TimeWindow {
+	end := start.Add(duration)
+	return &commonpb.TimeWindow{
+		StartDatetime: logisticsdb.TimeToProtoDateTime(&start),
+		EndDatetime:   logisticsdb.TimeToProtoDateTime(&end),
+	}
+}
+
+// pastCandidatesLogisticsDB fails to attach feasibility visits whose time windows ended by now, as logisticsdb does.
+type pastCandidatesLogisticsDB struct {
+	*MockLogisticsDB
+
+	now time.Time
+}
+
+func (m *pastCandidatesLogisticsDB) AttachCheckFeasibilityRequestToProblem(p *logisticsdb.VRPProblemData, cfVisits []*logisticspb.CheckFeasibilityVisit, cfLocIDs []int64) (*logisticsdb.VRPProblemData, error) {
+	for _, cfVisit := range cfVisits {
+		end, err := logisticsdb.ProtoDateTimeToTime(cfVisit.GetArrivalTimeWindow().GetEndDatetime())
+		if err != nil {
+			return nil, err
+		}
+		if !end.After(m.now) {
+			return nil, fmt.Errorf("error in feasibilityVisitsWithLocationIDs: %w", logisticsdb.ErrFeasibilityInThePast)
+		}
+	}
+	return m.MockLogisticsDB.AttachCheckFeasibilityRequestToProblem(p, cfVisits, cfLocIDs)
+}
+
+func TestGRPCServer_OfferVisitSlots(t *testing.T) {
+	openHoursStart := time.Date(2023, time.September, 15, 8, 0, 0, 0, time.UTC)
+	zone := blackoutZonesTestZone(openHoursStart.Add(4 * time.Hour))
+	visit := &logisticspb.CheckFeasibilityVisit{
+		MarketId:           proto.Int64(1),
+		Location:           &commonpb.Location{LatitudeE6: 5, LongitudeE6: 5},
+		IsManualAdjustment: true,
+	}
+	morningTW := slotOfferingTestTimeWindow(openHoursStart, 2*time.Hour)
+	blackedOutTW := slotOfferingTestTimeWindow(openHoursStart.Add(4*time.Hour), 2*time.Hour)
+	afternoonTW := slotOfferingTestTimeWindow(openHoursStart.Add(7*time.Hour), 2*time.Hour)
+	nextDayTW := slotOfferingTestTimeWindow(openHoursStart.Add(28*time.Hour), 2*time.Hour)
+	validReq := &logisticspb.OfferVisitSlotsRequest{
+		Visit:                visit,
+		CandidateTimeWindows: []*commonpb.TimeWindow{morningTW, blackedOutTW, nextDayTW},
+	}
+
+	optimizerRun := &logisticssql.OptimizerRun{ID: 1, SnapshotTimestamp: openHoursStart}
+	problemData := &logisticsdb.VRPProblemData{
+		VRPProblem: &optimizerpb.VRPProblem{
+			Description: &optimizerpb.VRPDescription{
+				ShiftTeams: []*optimizerpb.VRPShiftTeam{{Id: proto.Int64(1)}},
+			},
+		},
+		OptimizerRun: optimizerRun,
+	}
+	validLDB := &MockLogisticsDB{
+		GetServiceRegionForStationMarketIDResult: &logisticssql.ServiceRegion{ID: 2, IanaTimeZoneName: "UTC"},
+		GetServiceRegionVRPDataResult: &logisticsdb.ServiceRegionVRPData{
+			ServiceRegionID: 2,
+			Settings:        &optimizersettings.Settings{},
+			OpenHoursTW:     &logisticsdb.TimeWindow{Start: openHoursStart, End: openHoursStart.Add(10 * time.Hour)},
+			BlackoutZones:   logisticsdb.BlackoutZones{zone},
+			CheckFeasibilityData: &logisticsdb.CheckFeasibilityVRPDataResult{
+				Visits: []*logisticspb.CheckFeasibilityVisit{visit},
+				LocIDs: []int64{3},
+			},
+		},
+		CreateVRPProblemResult:                       problemData,
+		AttachCheckFeasibilityRequestToProblemResult: problemData,
+	}
+
+	tcs := []struct {
+		Desc      string
+		Req       *logisticspb.OfferVisitSlotsRequest
+		LDB       LogisticsDB
+		VRPSolver *MockVRPSolver
+
+		ErrCode      codes.Code
+		ExpectedResp *logisticspb.OfferVisitSlotsResponse
+	}{
+		{
+			Desc:      "base case",
+			Req:       validReq,
+			LDB:       validLDB,
+			VRPSolver: &MockVRPSolver{hardScores: []int64{0, 0, 0, 0}, isValidScore: true},
+
+			ExpectedResp: &logisticspb.OfferVisitSlotsResponse{
+				Slots: []*logisticspb.VisitSlot{
+					{TimeWindow: morningTW},
+					{TimeWindow: nextDayTW},
+				},
+			},
+		},
+		{
+			Desc: "max slots",
+			Req: &logisticspb.OfferVisitSlotsRequest{
+				Visit:                visit,
+				CandidateTimeWindows: validReq.CandidateTimeWindows,
+				MaxSlots:             proto.Int32(1),
+			},
+			LDB:       validLDB,
+			VRPSolver: &MockVRPSolver{hardScores: []int64{0, 0, 0, 0}, isValidScore: true},
+
+			ExpectedResp: &logisticspb.OfferVisitSlotsResponse{
+				Slots: []*logisticspb.VisitSlot{{TimeWindow: morningTW}},
+			},
+		},
+		{
+			Desc: "passed candidates",
+			Req: &logisticspb.OfferVisitSlotsRequest{
+				Visit:                visit,
+				CandidateTimeWindows: []*commonpb.TimeWindow{morningTW, afternoonTW},
+			},
+			LDB: &pastCandidatesLogisticsDB{
+				MockLogisticsDB: validLDB,
+				now:             openHoursStart.Add(3 * time.Hour),
+			},
+			VRPSolver: &MockVRPSolver{hardScores: []int64{0, 0}, isValidScore: true},
+
+			ExpectedResp: &logisticspb.OfferVisitSlotsResponse{
+				Slots: []*logisticspb.VisitSlot{{TimeWindow: afternoonTW}},
+			},
+		},
+		{
+			Desc:      "infeasible candidates",
+			Req:       validReq,
+			LDB:       validLDB,
+			VRPSolver: &MockVRPSolver{hardScores: []int64{1, 1, 1, 1}, isValidScore: true},
+
+			ExpectedResp: &logisticspb.OfferVisitSlotsResponse{},
+		},
+		{
+			Desc: "no visit",
+			Req:  &logisticspb.OfferVisitSlotsRequest{CandidateTimeWindows: validReq.CandidateTimeWindows},
+			LDB:  validLDB,
+
+			ErrCode: codes.InvalidArgument,
+		},
+		{
+			Desc: "no market id",
+			Req: &logisticspb.OfferVisitSlotsRequest{
+				Visit:                &logisticspb.CheckFeasibilityVisit{Location: visit.Location},
+				CandidateTimeWindows: validReq.CandidateTimeWindows,
+			},
+			LDB: validLDB,
+
+			ErrCode: codes.InvalidArgument,
+		},
+		{
+			Desc: "no candidate time windows",
+			Req:  &logisticspb.OfferVisitSlotsRequest{Visit: visit},
+			LDB:  validLDB,
+
+			ErrCode: codes.InvalidArgument,
+		},
+		{
+			Desc: "too many candidate time windows",
+			Req: &logisticspb.OfferVisitSlotsRequest{
+				Visit:                visit,
+				CandidateTimeWindows: make([]*commonpb.TimeWindow, maxVisitSlotCandidates+1),
+			},
+			LDB: validLDB,
+
+			ErrCode: codes.InvalidArgument,
+		},
+		{
+			Desc: "non positive max slots",
+			Req: &logisticspb.OfferVisitSlotsRequest{
+				Visit:                visit,
+				CandidateTimeWindows: validReq.CandidateTimeWindows,
+				MaxSlots:             proto.Int32(0),
+			},
+			LDB: validLDB,
+
+			ErrCode: codes.InvalidArgument,
+		},
+		{
+			Desc: "candidate time window spanning multiple days",
+			Req: &logisticspb.OfferVisitSlotsRequest{
+				Visit:                visit,
+				CandidateTimeWindows: []*commonpb.TimeWindow{slotOfferingTestTimeWindow(openHoursStart, 24*time.Hour)},
+			},
+			LDB: validLDB,
+
+			ErrCode: codes.InvalidArgument,
+		},
+		{
+			Desc: "no service region for market id",
+			Req:  validReq,
+			LDB:  &MockLogisticsDB{GetServiceRegionForStationMarketIDErr: errors.New("bad mkt")},
+
+			ErrCode: codes.NotFound,
+		},
+		{
+			Desc: "optimizer error",
+			Req:  validReq,
+			LDB:  validLDB,
+			VRPSolver: &MockVRPSolver{
+				err: errors.New("optimizer failed"),
+			},
+
+			ErrCode: codes.Internal,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			s := &GRPCServer{
+				LogisticsDB: tc.LDB,
+				VRPSolver:   tc.VRPSolver,
+			}
+
+			resp, err := s.OfferVisitSlots(context.Background(), tc.Req)
+			if status.Code(err) != tc.ErrCode {
+				t.Fatalf("unexpected error: %v, expected code: %s", err, tc.ErrCode)
+			}
+
+			testutils.MustMatch(t, tc.ExpectedResp, resp)
+		})
+	}
+}
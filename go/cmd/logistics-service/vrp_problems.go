@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/optimizer"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	vrpProblemsJSONFormat   = "json"
+	vrpProblemsBinaryFormat = "binary"
+
+	vrpProblemJSONExtension   = ".json"
+	vrpProblemBinaryExtension = ".pb"
+)
+
+var vrpProblemFileExtensions = map[string]string{
+	vrpProblemsJSONFormat:   vrpProblemJSONExtension,
+	vrpProblemsBinaryFormat: vrpProblemBinaryExtension,
+}
+
+// scrubVRPProblem removes patient and shift team identifying data from the problem.
+//
+// All location coordinates are cleared, as the optimizer only uses the distance matrix between locations.
+// Besides visits, locations include shift team current positions and start and end locations, which may
+// not be depots.
+func scrubVRPProblem(problem *optimizerpb.VRPProblem) {
+	for _, location := range problem.GetDescription().GetLocations() {
+		location.LatitudeE6 = proto.Int32(0)
+		location.LongitudeE6 = proto.Int32(0)
+	}
+}
+
+func parseOptimizerRunIDs(ids string) ([]int64, error) {
+	var res []int64
+	for _, idStr := range strings.Split(ids, ",") {
+		idStr = strings.TrimSpace(idStr)
+		if idStr == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid optimizer run ID %q: %w", idStr, err)
+		}
+		res = append(res, id)
+	}
+	return res, nil
+}
+
+func marshalExportedVRPProblem(problem *logisticspb.ExportedVRPProblem, format string) ([]byte, error) {
+	switch format {
+	case vrpProblemsJSONFormat:
+		return protojson.MarshalOptions{Multiline: true, Indent: "  ", UseProtoNames: true}.Marshal(problem)
+	case vrpProblemsBinaryFormat:
+		return proto.Marshal(problem)
+	default:
+		return nil, fmt.Errorf("unknown VRP problems format: %s", format)
+	}
+}
+
+func unmarshalExportedVRPProblem(path string, data []byte) (*logisticspb.ExportedVRPProblem, error) {
+	problem := &logisticspb.ExportedVRPProblem{}
+	switch filepath.Ext(path) {
+	case vrpProblemJSONExtension:
+		if err := protojson.Unmarshal(data, problem); err != nil {
+			return nil, err
+		}
+	case vrpProblemBinaryExtension:
+		if err := proto.Unmarshal(data, problem); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown VRP problem file extension: %s", path)
+	}
+	return problem, nil
+}
+
+// exportVRPProblem returns the SolveVRP request of the optimizer run, with patient identifying data scrubbed.
+func (s *GRPCServer) exportVRPProblem(ctx context.Context, optimizerRunID int64) (*logisticspb.ExportedVRPProblem, error) {
+	diagnostics, err := s.GetOptimizerRunDiagnostics(ctx, &logisticspb.GetOptimizerRunDiagnosticsRequest{
+		OptimizerRunId: optimizerRunID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var score *optimizerpb.VRPScore
+	scheduleID, err := s.LogisticsDB.GetLatestScheduleIDForOptimizerRun(ctx, optimizerRunID)
+	if err != nil && !errors.Is(err, logisticsdb.ErrScheduleNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		schedule, err := s.LogisticsDB.GetShiftTeamsSchedulesFromScheduleID(ctx, scheduleID, s.now(), false)
+		if err != nil {
+			return nil, fmt.Errorf("error in GetShiftTeamsSchedulesFromScheduleID: %w", err)
+		}
+		score = schedule.Score
+	}
+
+	req := diagnostics.GetSolveVrpRequest()
+	scrubVRPProblem(req.GetProblem())
+
+	return &logisticspb.ExportedVRPProblem{
+		OptimizerRunId:  optimizerRunID,
+		SolveVrpRequest: req,
+		Score:           score,
+		Revisions:       diagnostics.GetRevisions(),
+	}, nil
+}
+
+type exportVRPProblemsParams struct {
+	OptimizerRunIDs []int64
+	Dir             string
+	Format          string
+}
+
+// exportVRPProblems writes the problems of the optimizer runs to files in the directory.
+func (s *GRPCServer) exportVRPProblems(ctx context.Context, params exportVRPProblemsParams) ([]string, error) {
+	extension, ok := vrpProblemFileExtensions[params.Format]
+	if !ok {
+		return nil, fmt.Errorf("unknown VRP problems format: %s", params.Format)
+	}
+	if err := os.MkdirAll(params.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, optimizerRunID := range params.OptimizerRunIDs {
+		problem, err := s.exportVRPProblem(ctx, optimizerRunID)
+		if err != nil {
+			return nil, fmt.Errorf("error exporting optimizer run(%d): %w", optimizerRunID, err)
+		}
+		data, err := marshalExportedVRPProblem(problem, params.Format)
+		if err != nil {
+			return nil, err
+		}
+
+		path := filepath.Join(params.Dir, fmt.Sprintf("optimizer_run_%d%s", optimizerRunID, extension))
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+type vrpProblemReplayResult struct {
+	Path           string
+	OptimizerRunID int64
+	BaselineScore  *optimizerpb.VRPScore
+	ReplayScore    *optimizerpb.VRPScore
+	Err            error
+}
+
+type replayVRPProblemsParams struct {
+	Dir string
+	// Overrides the termination duration of the exported requests, if positive.
+	TerminationDuration time.Duration
+}
+
+// replayVRPProblems re-solves the exported problems in the directory.
+func (s *GRPCServer) replayVRPProblems(ctx context.Context, params replayVRPProblemsParams) ([]*vrpProblemReplayResult, error) {
+	entries, err := os.ReadDir(params.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*vrpProblemReplayResult
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != vrpProblemJSONExtension && ext != vrpProblemBinaryExtension) {
+			continue
+		}
+
+		path := filepath.Join(params.Dir, entry.Name())
+		result := &vrpProblemReplayResult{Path: path}
+		results = append(results, result)
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			result.Err = err
+			continue
+		}
+		problem, err := unmarshalExportedVRPProblem(path, data)
+		if err != nil {
+			result.Err = err
+			continue
+		}
+		result.OptimizerRunID = problem.GetOptimizerRunId()
+		result.BaselineScore = problem.GetScore()
+
+		req := problem.GetSolveVrpRequest()
+		if req == nil {
+			result.Err = errors.New("exported VRP problem has no solve VRP request")
+			continue
+		}
+		if params.TerminationDuration > 0 {
+			if req.Config == nil {
+				req.Config = &optimizerpb.VRPConfig{}
+			}
+			req.Config.TerminationDurationMs = proto.Int64(params.TerminationDuration.Milliseconds())
+		}
+		result.ReplayScore, result.Err = s.replayVRPProblem(ctx, req)
+	}
+
+	return results, nil
+}
+
+func (s *GRPCServer) replayVRPProblem(ctx context.Context, req *optimizerpb.SolveVRPRequest) (*optimizerpb.VRPScore, error) {
+	respChan, err := s.VRPSolver.SolveVRP(ctx, &optimizer.SolveVRPParams{
+		SolveVRPRequest:  req,
+		OptimizerRunType: logisticsdb.ServiceRegionScheduleRunType,
+		WriteToDatabase:  false,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var last *optimizer.WrappedSolveVRPResp
+	for resp := range respChan {
+		last = resp
+	}
+	if last == nil {
+		return nil, errors.New("SolveVRP returned no solution")
+	}
+
+	return last.Response.GetSolution().GetScore(), nil
+}
+
+// writeVRPProblemReplayReport writes the score deltas of the replayed problems against their baseline scores.
+func writeVRPProblemReplayReport(w io.Writer, results []*vrpProblemReplayResult) error {
+	sort.Slice(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "optimizer_run_id\thard_delta\tunassigned_visits_delta\tsoft_delta\terror\t")
+
+	var hardDelta, unassignedVisitsDelta, softDelta int64
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Fprintf(tw, "%d\t\t\t\t%s: %s\t\n", result.OptimizerRunID, filepath.Base(result.Path), result.Err)
+			continue
+		}
+		if result.BaselineScore == nil {
+			fmt.Fprintf(tw, "%d\t\t\t\t%s: no baseline score\t\n", result.OptimizerRunID, filepath.Base(result.Path))
+			continue
+		}
+
+		hard := result.ReplayScore.GetHardScore() - result.BaselineScore.GetHardScore()
+		unassignedVisits := result.ReplayScore.GetUnassignedVisitsScore() - result.BaselineScore.GetUnassignedVisitsScore()
+		soft := result.ReplayScore.GetSoftScore() - result.BaselineScore.GetSoftScore()
+		hardDelta += hard
+		unassignedVisitsDelta += unassignedVisits
+		softDelta += soft
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t\t\n", result.OptimizerRunID, hard, unassignedVisits, soft)
+	}
+	fmt.Fprintf(tw, "total\t%d\t%d\t%d\t\t\n", hardDelta, unassignedVisitsDelta, softDelta)
+
+	return tw.Flush()
+}
+
+// runVRPProblemsCommand exports or replays VRP problems, according to the flags.
+func runVRPProblemsCommand(ctx context.Context, s *GRPCServer, w io.Writer) error {
+	if *exportVRPProblemsOptimizerRunIDs != "" {
+		optimizerRunIDs, err := parseOptimizerRunIDs(*exportVRPProblemsOptimizerRunIDs)
+		if err != nil {
+			return err
+		}
+		paths, err := s.exportVRPProblems(ctx, exportVRPProblemsParams{
+			OptimizerRunIDs: optimizerRunIDs,
+			Dir:             *vrpProblemsDir,
+			Format:          *vrpProblemsFormat,
+		})
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			fmt.Fprintln(w, path)
+		}
+	}
+
+	if *replayVRPProblems {
+		results, err := s.replayVRPProblems(ctx, replayVRPProblemsParams{
+			Dir:                 *vrpProblemsDir,
+			TerminationDuration: *replayVRPProblemsTerminationDuration,
+		})
+		if err != nil {
+			return err
+		}
+		return writeVRPProblemReplayReport(w, results)
+	}
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"google.golang.org/protobuf/proto"
+)
+
+func vrpProblemsTestExportedProblem() *logisticspb.ExportedVRPProblem {
+	return &logisticspb.ExportedVRPProblem{
+		OptimizerRunId: 1,
+		SolveVrpRequest: &optimizerpb.SolveVRPRequest{
+			Problem: &optimizerpb.VRPProblem{Description: &optimizerpb.VRPDescription{
+				ShiftTeams: []*optimizerpb.VRPShiftTeam{{
+					Id:              proto.Int64(1),
+					DepotLocationId: proto.Int64(1),
+					RouteHistory: &optimizerpb.VRPShiftTeamRouteHistory{
+						CurrentPosition: &optimizerpb.VRPShiftTeamPosition{LocationId: 3},
+					},
+				}},
+				Visits: []*optimizerpb.VRPVisit{{Id: proto.Int64(2), LocationId: proto.Int64(2)}},
+				Locations: []*optimizerpb.VRPLocation{
+					{Id: proto.Int64(1), LatitudeE6: proto.Int32(1), LongitudeE6: proto.Int32(1)},
+					{Id: proto.Int64(2), LatitudeE6: proto.Int32(2), LongitudeE6: proto.Int32(2)},
+					{Id: proto.Int64(3), LatitudeE6: proto.Int32(3), LongitudeE6: proto.Int32(3)},
+				},
+			}},
+			Config: &optimizerpb.VRPConfig{TerminationDurationMs: proto.Int64(1000)},
+		},
+		Score: &optimizerpb.VRPScore{HardScore: proto.Int64(-1), SoftScore: proto.Int64(-100)},
+	}
+}
+
+func TestScrubVRPProblem(t *testing.T) {
+	problem := vrpProblemsTestExportedProblem().GetSolveVrpRequest().GetProblem()
+
+	scrubVRPProblem(problem)
+
+	testutils.MustMatch(t, []*optimizerpb.VRPLocation{
+		{Id: proto.Int64(1), LatitudeE6: proto.Int32(0), LongitudeE6: proto.Int32(0)},
+		{Id: proto.Int64(2), LatitudeE6: proto.Int32(0), LongitudeE6: proto.Int32(0)},
+		{Id: proto.Int64(3), LatitudeE6: proto.Int32(0), LongitudeE6: proto.Int32(0)},
+	}, problem.GetDescription().GetLocations(), "all locations should be scrubbed")
+}
+
+func TestParseOptimizerRunIDs(t *testing.T) {
+	tcs := []struct {
+		Desc   string
+		IDs    string
+		HasErr bool
+
+		ExpectedIDs []int64
+	}{
+		{
+			Desc: "base case",
+			IDs:  "1, 2,3,",
+
+			ExpectedIDs: []int64{1, 2, 3},
+		},
+		{
+			Desc:   "invalid ID",
+			IDs:    "1,abc",
+			HasErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			ids, err := parseOptimizerRunIDs(tc.IDs)
+			if (err != nil) != tc.HasErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			testutils.MustMatch(t, tc.ExpectedIDs, ids)
+		})
+	}
+}
+
+func TestMarshalExportedVRPProblem(t *testing.T) {
+	for format, extension := range vrpProblemFileExtensions {
+		t.Run(format, func(t *testing.T) {
+			problem := vrpProblemsTestExportedProblem()
+
+			data, err := marshalExportedVRPProblem(problem, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			unmarshaled, err := unmarshalExportedVRPProblem("optimizer_run_1"+extension, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			testutils.MustMatch(t, problem, unmarshaled)
+		})
+	}
+
+	if _, err := marshalExportedVRPProblem(vrpProblemsTestExportedProblem(), "xml"); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
+
+func TestReplayVRPProblems(t *testing.T) {
+	dir := t.TempDir()
+	for format, extension := range vrpProblemFileExtensions {
+		data, err := marshalExportedVRPProblem(vrpProblemsTestExportedProblem(), format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "optimizer_run_1"+extension), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("ignored"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	replayScore := &optimizerpb.VRPScore{HardScore: proto.Int64(0), SoftScore: proto.Int64(-50)}
+	s := &GRPCServer{
+		VRPSolver: &MockVRPSolver{solution: &optimizerpb.VRPSolution{Score: replayScore}},
+	}
+
+	results, err := s.replayVRPProblems(context.Background(), replayVRPProblemsParams{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baselineScore := vrpProblemsTestExportedProblem().GetScore()
+	testutils.MustMatch(t, []*vrpProblemReplayResult{
+		{
+			Path:           filepath.Join(dir, "optimizer_run_1.json"),
+			OptimizerRunID: 1,
+			BaselineScore:  baselineScore,
+			ReplayScore:    replayScore,
+		},
+		{
+			Path:           filepath.Join(dir, "optimizer_run_1.pb"),
+			OptimizerRunID: 1,
+			BaselineScore:  baselineScore,
+			ReplayScore:    replayScore,
+		},
+	}, results)
+}
+
+func TestReplayVRPProblems_TerminationDuration(t *testing.T) {
+	withConfig := vrpProblemsTestExportedProblem()
+	withoutConfig := vrpProblemsTestExportedProblem()
+	withoutConfig.SolveVrpRequest.Config = nil
+	withoutRequest := vrpProblemsTestExportedProblem()
+	withoutRequest.SolveVrpRequest = nil
+
+	dir := t.TempDir()
+	for name, problem := range map[string]*logisticspb.ExportedVRPProblem{
+		"optimizer_run_1.pb": withConfig,
+		"optimizer_run_2.pb": withoutConfig,
+		"optimizer_run_3.pb": withoutRequest,
+	} {
+		data, err := marshalExportedVRPProblem(problem, vrpProblemsBinaryFormat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	solver := &MockVRPSolver{solution: &optimizerpb.VRPSolution{}}
+	s := &GRPCServer{VRPSolver: solver}
+
+	results, err := s.replayVRPProblems(context.Background(), replayVRPProblemsParams{
+		Dir:                 dir,
+		TerminationDuration: 2 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testutils.MustMatch(t, 3, len(results))
+	testutils.MustMatch(t, true, results[0].Err == nil)
+	testutils.MustMatch(t, true, results[1].Err == nil)
+	testutils.MustMatch(t, true, results[2].Err != nil, "problem without request should fail")
+
+	testutils.MustMatch(t, 2, len(solver.solveVRPRequests))
+	for _, req := range solver.solveVRPRequests {
+		testutils.MustMatch(t, int64(2000), req.GetConfig().GetTerminationDurationMs())
+	}
+}
+
+func TestWriteVRPProblemReplayReport(t *testing.T) {
+	results := []*vrpProblemReplayResult{
+		{
+			Path:           "optimizer_run_2.json",
+			OptimizerRunID: 2,
+			BaselineScore:  &optimizerpb.VRPScore{HardScore: proto.Int64(-1), SoftScore: proto.Int64(-100)},
+			ReplayScore:    &optimizerpb.VRPScore{HardScore: proto.Int64(0), SoftScore: proto.Int64(-50)},
+		},
+		{
+			Path:           "optimizer_run_1.json",
+			OptimizerRunID: 1,
+			BaselineScore:  &optimizerpb.VRPScore{HardScore: proto.Int64(0), SoftScore: proto.Int64(-100)},
+			ReplayScore:    &optimizerpb.VRPScore{HardScore: proto.Int64(0), SoftScore: proto.Int64(-120)},
+		},
+		{
+			Path: "optimizer_run_3.pb",
+			Err:  errors.New("solver failed"),
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeVRPProblemReplayReport(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	testutils.MustMatch(t, 5, len(lines))
+	testutils.MustMatch(t, []string{"1", "0", "0", "-20"}, strings.Fields(lines[1]))
+	testutils.MustMatch(t, []string{"2", "1", "0", "50"}, strings.Fields(lines[2]))
+	testutils.MustMatch(t, true, strings.HasSuffix(strings.TrimSpace(lines[3]), "optimizer_run_3.pb: solver failed"))
+	testutils.MustMatch(t, []string{"total", "1", "0", "30"}, strings.Fields(lines[4]))
+}
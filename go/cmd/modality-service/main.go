@@ -23,10 +23,12 @@ import (
 	"github.com/*company-data-covered*/services/go/pkg/baseserv"
 	"github.com/*company-data-covered*/services/go/pkg/buildinfo"
 	"github.com/*company-data-covered*/services/go/pkg/cors"
+	"github.com/*company-data-covered*/services/go/pkg/eventstreaming"
 	insurancepb "github.com/*company-data-covered*/services/go/pkg/generated/proto/insurance"
 	modalitypb "github.com/*company-data-covered*/services/go/pkg/generated/proto/modality"
 	"github.com/*company-data-covered*/services/go/pkg/modality/modalitydb"
 	"github.com/*company-data-covered*/services/go/pkg/monitoring"
+	"github.com/*company-data-covered*/services/go/pkg/outbox"
 )
 
 const (
@@ -52,6 +54,7 @@ var (
 	allowedHTTPMethods     = []string{http.MethodGet, http.MethodPatch, http.MethodPost}
 	refreshTokenInterval   = flag.Duration("grpc-refresh-token-interval", 1*time.Hour, "time interval for refreshing M2M tokens")
 	insuranceAuth0Audience = flag.String("insurance-auth0-audience", auth.LogicalAPIAudience, "auth0 audience for insurance service")
+	configsChangedTopic    = flag.String("configs-changed-topic", modalitydb.DefaultConfigsChangedTopic, "Kafka topic modality configs changed events are published to")
 )
 
 func main() {
@@ -128,7 +131,23 @@ func main() {
 		mdbScope = ir.With("ModalityDB", nil, nil)
 	}
 
-	modalityDB := modalitydb.NewModalityDB(db, mdbScope)
+	modalityDB := modalitydb.NewModalityDB(db, mdbScope).WithConfigsChangedTopic(*configsChangedTopic)
+
+	producerConfig := baseserv.DefaultEventStreamingProducerConfig()
+	producerConfig.Producer = &eventstreaming.ProducerConfig{}
+	producer, err := eventstreaming.NewMessageProducer(&producerConfig)
+	if err != nil {
+		logger.Panicw("could not create outbox producer", zap.Error(err))
+	}
+	defer producer.Close()
+
+	relay := outbox.NewRelay(outbox.RelayParams{
+		Config:   baseserv.DefaultEnvOutboxRelayConfig(),
+		DB:       db,
+		Producer: producer,
+		Logger:   logger,
+	})
+	relay.Start(ctx)
 
 	serverConfig := &ModalityGRPCServer{
 		ModalityDB:       modalityDB,
@@ -13,6 +13,7 @@ import (
 	"github.com/*company-data-covered*/services/go/pkg/eventstreaming"
 	examplepb "github.com/*company-data-covered*/services/go/pkg/generated/proto/example"
 	"github.com/*company-data-covered*/services/go/pkg/monitoring"
+	"github.com/*company-data-covered*/services/go/pkg/outbox"
 	"github.com/*company-data-covered*/services/go/pkg/testutils"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
@@ -338,6 +339,7 @@ func TestDefaultEventstreamingConsumerConfig(t *testing.T) {
 	testutils.MustMatch(t, []string{"localhost:9999"}, config.Brokers)
 	testutils.MustMatch(t, eventstreaming.AssignmentStrategy(eventstreaming.StickyAssignment), config.Consumer.AssignmentStrategy)
 	testutils.MustMatch(t, false, config.Consumer.ConsumeFromOldest)
+	testutils.MustMatch(t, true, config.MemoryBroker == nil)
 }
 
 func TestDefaultEventstreamingProducerConfig(t *testing.T) {
@@ -349,6 +351,29 @@ func TestDefaultEventstreamingProducerConfig(t *testing.T) {
 	testutils.MustMatch(t, "2.0.0", config.KafkaVersion)
 	testutils.MustMatch(t, true, config.VerboseLogging)
 	testutils.MustMatch(t, []string{"localhost:9999"}, config.Brokers)
+	testutils.MustMatch(t, true, config.MemoryBroker == nil)
+}
+
+func TestDefaultEventstreamingConfigInMemory(t *testing.T) {
+	t.Setenv("KAFKA_IN_MEMORY", "true")
+
+	consumerConfig := baseserv.DefaultEventStreamingConsumerConfig()
+	producerConfig := baseserv.DefaultEventStreamingProducerConfig()
+	testutils.MustMatch(t, true, consumerConfig.MemoryBroker == eventstreaming.DefaultMemoryBroker())
+	testutils.MustMatch(t, true, producerConfig.MemoryBroker == eventstreaming.DefaultMemoryBroker())
+}
+
+func TestDefaultEnvOutboxRelayConfig(t *testing.T) {
+	t.Setenv("OUTBOX_RELAY_BATCH_SIZE", "50")
+	t.Setenv("OUTBOX_RELAY_POLL_INTERVAL_MS", "500")
+	t.Setenv("OUTBOX_RELAY_MAX_BACKOFF_MS", "10000")
+
+	config := baseserv.DefaultEnvOutboxRelayConfig()
+	testutils.MustMatch(t, outbox.RelayConfig{
+		BatchSize:    50,
+		PollInterval: 500 * time.Millisecond,
+		MaxBackoff:   10 * time.Second,
+	}, config)
 }
 
 func TestCreateAuthedGRPCConnection(t *testing.T) {
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/*company-data-covered*/services/go/pkg/baselogger"
 	"github.com/*company-data-covered*/services/go/pkg/buildinfo"
@@ -13,6 +14,7 @@ import (
 	"github.com/*company-data-covered*/services/go/pkg/featureflags"
 	"github.com/*company-data-covered*/services/go/pkg/featureflags/providers"
 	"github.com/*company-data-covered*/services/go/pkg/monitoring"
+	"github.com/*company-data-covered*/services/go/pkg/outbox"
 )
 
 var (
@@ -20,6 +22,8 @@ var (
 	versionSetting = featureflags.NewStringFlag("KAFKA_VERSION", "3.2.3")
 	loggingSetting = featureflags.NewBooleanFlag("KAFKA_LOGGING_VERBOSE", false)
 	brokersSetting = featureflags.NewStringFlag("KAFKA_BROKERS", "localhost:9092")
+	// inMemorySetting runs event streaming on the in-process memory broker instead of Kafka, for local development.
+	inMemorySetting = featureflags.NewBooleanFlag("KAFKA_IN_MEMORY", false)
 )
 
 func DefaultEnvStatsigProviderConfig() *providers.StatsigProviderConfig {
@@ -66,6 +70,7 @@ func DefaultEventStreamingConsumerConfig() eventstreaming.ClientConfig {
 		KafkaVersion:   versionSetting.Get(provider),
 		LoggingOptions: baselogger.LoggerOptions{},
 		VerboseLogging: loggingSetting.Get(provider),
+		MemoryBroker:   defaultMemoryBroker(),
 		Consumer: &eventstreaming.ConsumerConfig{
 			GroupID:            groupSetting.Get(provider),
 			AssignmentStrategy: eventstreaming.AssignmentStrategy(assignmentStrategySetting.Get(provider)),
@@ -79,5 +84,25 @@ func DefaultEventStreamingProducerConfig() eventstreaming.ClientConfig {
 		Brokers:        strings.Split(brokersSetting.Get(provider), ","),
 		KafkaVersion:   versionSetting.Get(provider),
 		VerboseLogging: loggingSetting.Get(provider),
+		MemoryBroker:   defaultMemoryBroker(),
+	}
+}
+
+func defaultMemoryBroker() *eventstreaming.MemoryBroker {
+	if !inMemorySetting.Get(provider) {
+		return nil
+	}
+	return eventstreaming.DefaultMemoryBroker()
+}
+
+func DefaultEnvOutboxRelayConfig() outbox.RelayConfig {
+	batchSizeSetting := featureflags.NewIntFlag("OUTBOX_RELAY_BATCH_SIZE", 100)
+	pollIntervalMsSetting := featureflags.NewIntFlag("OUTBOX_RELAY_POLL_INTERVAL_MS", 1000)
+	maxBackoffMsSetting := featureflags.NewIntFlag("OUTBOX_RELAY_MAX_BACKOFF_MS", 60000)
+
+	return outbox.RelayConfig{
+		BatchSize:    batchSizeSetting.Get(provider),
+		PollInterval: time.Duration(pollIntervalMsSetting.Get(provider)) * time.Millisecond,
+		MaxBackoff:   time.Duration(maxBackoffMsSetting.Get(provider)) * time.Millisecond,
 	}
 }
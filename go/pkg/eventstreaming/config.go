@@ -58,6 +58,10 @@ type ClientConfig struct {
 
 	// Produucer config options
 	Consumer *ConsumerConfig
+
+	// MemoryBroker, if set, is used instead of a Kafka cluster, in which case Brokers are not required.
+	// Intended for tests and local development.
+	MemoryBroker *MemoryBroker
 }
 
 type ClientCerts struct {
@@ -102,10 +106,25 @@ type ConsumerConfig struct {
 	// Messages for a given topic will be appended one after the other thereby creating a log file of messages for that given
 	// topic creating a logical segregation analogous to how data is segregated in a database by table.
 	Topics map[string]MessageProcessor
+
+	// RetryPolicies defines how messages of each topic that fail processing are retried,
+	// and the dead-letter topics they are produced to once retries are exhausted.
+	// Failed messages of topics without a retry policy are not retried and are dropped:
+	// they are not marked as consumed, but the offsets of later messages of their partitions are.
+	RetryPolicies map[string]*RetryPolicy
+}
+
+func (config *ConsumerConfig) hasDeadLetterTopics() bool {
+	for _, policy := range config.RetryPolicies {
+		if policy != nil && policy.DeadLetterTopic != "" {
+			return true
+		}
+	}
+	return false
 }
 
 func (config *ClientConfig) Validate() error {
-	if len(config.Brokers) == 0 {
+	if len(config.Brokers) == 0 && config.MemoryBroker == nil {
 		return errConfigNoBrokers
 	}
 
@@ -168,6 +187,18 @@ func (config *ClientConfig) consumerConfigValidate() error {
 		}
 	}
 
+	for topic, policy := range config.Consumer.RetryPolicies {
+		if _, ok := config.Consumer.Topics[topic]; !ok {
+			return fmt.Errorf("retry policy is configured for the %s topic that is not consumed", topic)
+		}
+		if policy == nil {
+			return fmt.Errorf("no retry policy is configured for the %s topic", topic)
+		}
+		if err := policy.validate(topic); err != nil {
+			return err
+		}
+	}
+
 	if bs := balanceStrategiesMap[config.Consumer.AssignmentStrategy]; bs == nil {
 		return errConfigInvalidBalanceStrategy
 	}
@@ -54,6 +54,13 @@ func TestConfigValidate(t *testing.T) {
 
 			WantErr: errors.New("no Kafka bootstrap brokers are defined"),
 		},
+		{
+			Desc: "memory broker without brokers",
+			Config: &ClientConfig{
+				MemoryBroker: NewMemoryBroker(),
+				Producer:     newTestProducerConfig(),
+			},
+		},
 		{
 			Desc: "Missing RootCA fails if others provided",
 			Config: &ClientConfig{
@@ -213,6 +220,116 @@ func TestConsumerConfigValidate(t *testing.T) {
 
 			WantErr: errors.New("invalid consumer assignment strategy"),
 		},
+		{
+			Desc: "retry policy with dead-letter topic",
+			Config: &ClientConfig{
+				Brokers: []string{"localhost:9095"},
+				Consumer: &ConsumerConfig{
+					GroupID:            "consumerGroupID",
+					Topics:             testTopics,
+					AssignmentStrategy: StickyAssignment,
+					RetryPolicies: map[string]*RetryPolicy{
+						"test-topic-1": {MaxRetries: 3, InitialBackoff: time.Second, DeadLetterTopic: "test-topic-1-dlq"},
+					},
+				},
+			},
+		},
+		{
+			Desc: "retry policy for topic that is not consumed",
+			Config: &ClientConfig{
+				Brokers: []string{"localhost:9095"},
+				Consumer: &ConsumerConfig{
+					GroupID:            "consumerGroupID",
+					Topics:             testTopics,
+					AssignmentStrategy: StickyAssignment,
+					RetryPolicies: map[string]*RetryPolicy{
+						"other-topic": {MaxRetries: 3},
+					},
+				},
+			},
+
+			WantErr: errors.New("retry policy is configured for the other-topic topic that is not consumed"),
+		},
+		{
+			Desc: "missing retry policy",
+			Config: &ClientConfig{
+				Brokers: []string{"localhost:9095"},
+				Consumer: &ConsumerConfig{
+					GroupID:            "consumerGroupID",
+					Topics:             testTopics,
+					AssignmentStrategy: StickyAssignment,
+					RetryPolicies: map[string]*RetryPolicy{
+						"test-topic-1": nil,
+					},
+				},
+			},
+
+			WantErr: errors.New("no retry policy is configured for the test-topic-1 topic"),
+		},
+		{
+			Desc: "negative max retries",
+			Config: &ClientConfig{
+				Brokers: []string{"localhost:9095"},
+				Consumer: &ConsumerConfig{
+					GroupID:            "consumerGroupID",
+					Topics:             testTopics,
+					AssignmentStrategy: StickyAssignment,
+					RetryPolicies: map[string]*RetryPolicy{
+						"test-topic-1": {MaxRetries: -1},
+					},
+				},
+			},
+
+			WantErr: errors.New("negative max retries for the test-topic-1 topic retry policy"),
+		},
+		{
+			Desc: "negative backoff",
+			Config: &ClientConfig{
+				Brokers: []string{"localhost:9095"},
+				Consumer: &ConsumerConfig{
+					GroupID:            "consumerGroupID",
+					Topics:             testTopics,
+					AssignmentStrategy: StickyAssignment,
+					RetryPolicies: map[string]*RetryPolicy{
+						"test-topic-1": {MaxRetries: 1, InitialBackoff: -time.Second},
+					},
+				},
+			},
+
+			WantErr: errors.New("negative backoff for the test-topic-1 topic retry policy"),
+		},
+		{
+			Desc: "retry policy without dead-letter topic",
+			Config: &ClientConfig{
+				Brokers: []string{"localhost:9095"},
+				Consumer: &ConsumerConfig{
+					GroupID:            "consumerGroupID",
+					Topics:             testTopics,
+					AssignmentStrategy: StickyAssignment,
+					RetryPolicies: map[string]*RetryPolicy{
+						"test-topic-1": {MaxRetries: 1},
+					},
+				},
+			},
+
+			WantErr: errors.New("no dead-letter topic for the test-topic-1 topic retry policy"),
+		},
+		{
+			Desc: "dead-letter topic is the consumed topic",
+			Config: &ClientConfig{
+				Brokers: []string{"localhost:9095"},
+				Consumer: &ConsumerConfig{
+					GroupID:            "consumerGroupID",
+					Topics:             testTopics,
+					AssignmentStrategy: StickyAssignment,
+					RetryPolicies: map[string]*RetryPolicy{
+						"test-topic-1": {MaxRetries: 1, DeadLetterTopic: "test-topic-1"},
+					},
+				},
+			},
+
+			WantErr: errors.New("dead-letter topic is the same as the test-topic-1 topic"),
+		},
 	}
 
 	for _, tc := range tcs {
@@ -52,6 +52,9 @@ type consumerGroupClient struct {
 	cancel  context.CancelFunc
 	errChan chan error
 	logger  *zap.SugaredLogger
+
+	// deadLetterProducer produces messages to the dead-letter topics of the retry policies, if any.
+	deadLetterProducer MessageProducer
 }
 
 func (cg *consumerGroupClient) Error() chan error {
@@ -60,7 +63,12 @@ func (cg *consumerGroupClient) Error() chan error {
 
 func (cg *consumerGroupClient) Start(ctx context.Context) {
 	topicsProcessor := newTopicsProcessor(cg.config.Topics)
-	consumer := newConsumerGroupHandler(topicsProcessor)
+	consumer := newConsumerGroupHandler(consumerGroupHandlerParams{
+		topicsProcessor:    topicsProcessor,
+		retryPolicies:      cg.config.RetryPolicies,
+		deadLetterProducer: cg.deadLetterProducer,
+		logger:             cg.logger,
+	})
 
 	ctx, cancel := context.WithCancel(ctx)
 	cg.cancel = cancel
@@ -100,6 +108,12 @@ func (cg *consumerGroupClient) Stop() error {
 		return fmt.Errorf("error closing client: %w", err)
 	}
 
+	if cg.deadLetterProducer != nil {
+		if err := cg.deadLetterProducer.Close(); err != nil {
+			return fmt.Errorf("error closing dead-letter producer: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -122,6 +136,34 @@ func NewConsumerGroup(config *ClientConfig) (ConsumerGroup, error) {
 		return nil, errors.New("no consumer config provided")
 	}
 
+	client, err := newConsumerGroupClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating consumer group client: %w", err)
+	}
+
+	var deadLetterProducer MessageProducer
+	if config.Consumer.hasDeadLetterTopics() {
+		deadLetterProducer, err = deadLetterProducerProvider(config)
+		if err != nil {
+			client.Close()
+			return nil, fmt.Errorf("error creating dead-letter producer: %w", err)
+		}
+	}
+
+	return &consumerGroupClient{
+		config:             config.Consumer,
+		client:             client,
+		errChan:            make(chan error),
+		logger:             baselogger.NewSugaredLogger(config.LoggingOptions),
+		deadLetterProducer: deadLetterProducer,
+	}, nil
+}
+
+func newConsumerGroupClient(config *ClientConfig) (sarama.ConsumerGroup, error) {
+	if config.MemoryBroker != nil {
+		return config.MemoryBroker.newConsumerGroup(config.Consumer), nil
+	}
+
 	clientConfig, err := toSaramaConfig(config)
 	if err != nil {
 		return nil, err
@@ -131,17 +173,19 @@ func NewConsumerGroup(config *ClientConfig) (ConsumerGroup, error) {
 		sarama.Logger = log.New(os.Stdout, "[eventstreaming] ", log.LstdFlags)
 	}
 
-	client, err := consumerGroupProvider(config.Brokers, config.Consumer.GroupID, clientConfig)
-	if err != nil {
-		return nil, fmt.Errorf("error creating consumer group client: %w", err)
-	}
+	return consumerGroupProvider(config.Brokers, config.Consumer.GroupID, clientConfig)
+}
 
-	return &consumerGroupClient{
-		config:  config.Consumer,
-		client:  client,
-		errChan: make(chan error),
-		logger:  baselogger.NewSugaredLogger(config.LoggingOptions),
-	}, nil
+// newDeadLetterProducer creates a sync producer sharing the consumer group client config,
+// so that failures to produce dead-letter messages are surfaced.
+func newDeadLetterProducer(config *ClientConfig) (MessageProducer, error) {
+	producerConfig := *config
+	producerConfig.Consumer = nil
+	producerConfig.Producer = &ProducerConfig{Async: false}
+	if config.MemoryBroker != nil {
+		return &memoryProducer{broker: config.MemoryBroker}, nil
+	}
+	return newSyncProducer(&producerConfig)
 }
 
 // TODO: remove when Kafka is running in CI/CD
@@ -149,3 +193,5 @@ func NewConsumerGroup(config *ClientConfig) (ConsumerGroup, error) {
 // Testing can substitute this provider with its own mock.
 var defaultConsumerGroupProvider = sarama.NewConsumerGroup
 var consumerGroupProvider = defaultConsumerGroupProvider
+var defaultDeadLetterProducerProvider = newDeadLetterProducer
+var deadLetterProducerProvider = defaultDeadLetterProducerProvider
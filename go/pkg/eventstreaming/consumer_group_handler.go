@@ -1,9 +1,18 @@
 package eventstreaming
 
 import (
+	"context"
+	"time"
+
 	"github.com/Shopify/sarama"
+	"go.uber.org/zap"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 )
 
+// defaultDeadLetterBackoff is the wait between attempts to produce a message to a dead-letter topic,
+// for retry policies without backoff.
+const defaultDeadLetterBackoff = time.Second
+
 // consumerGroupHandler implements sarama.ConsumerGroupHandler.
 // Instances of consumerGroupHandler are used to handle individual topic/partition claims.
 // It also provides hooks for your consumer group session life-cycle and allow you to trigger logic before or after the consume loop(s).
@@ -12,6 +21,12 @@ import (
 type consumerGroupHandler struct {
 	topicsProcessor *topicsProcessor
 	readyChan       chan bool
+
+	// retryPolicies by topic, for messages that fail processing.
+	retryPolicies map[string]*RetryPolicy
+	// deadLetterProducer produces messages to the dead-letter topics of retryPolicies.
+	deadLetterProducer MessageProducer
+	logger             *zap.SugaredLogger
 }
 
 // ready indicates whether the consumer client is in a ready state to begin consuming messages.
@@ -52,9 +67,11 @@ func (cgh *consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSessio
 	for {
 		select {
 		case message := <-claim.Messages():
-			err := cgh.topicsProcessor.ProcessMessage(&consumerMessage{message})
-			if err == nil {
+			if cgh.processMessage(session.Context(), message) {
 				session.MarkMessage(message, "")
+			} else if session.Context().Err() != nil {
+				// Stop before a later message of the claim is marked past the unmarked one.
+				return nil
 			}
 
 		// The `for` should return when `session.Context()` is done. Without monitoring for `session.Context.Done()
@@ -66,9 +83,109 @@ func (cgh *consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSessio
 	}
 }
 
-func newConsumerGroupHandler(processors *topicsProcessor) *consumerGroupHandler {
+// processMessage processes the message, retrying it per the retry policy of its topic,
+// and producing it to the dead-letter topic of the policy once retries are exhausted.
+// Returns whether the message should be marked as consumed.
+func (cgh *consumerGroupHandler) processMessage(ctx context.Context, message *sarama.ConsumerMessage) bool {
+	span, ctx := startConsumeSpan(ctx, message)
+	marked, err := cgh.processMessageWithRetries(ctx, message)
+	span.Finish(tracer.WithError(err))
+	return marked
+}
+
+// processMessageWithRetries returns whether the message should be marked as consumed,
+// and the last processing error if any.
+// Messages of topics with a retry policy are only marked once processed or produced to the dead-letter topic,
+// and are otherwise consumed again from their uncommitted offset by the next session.
+func (cgh *consumerGroupHandler) processMessageWithRetries(ctx context.Context, message *sarama.ConsumerMessage) (bool, error) {
+	cm := &consumerMessage{consumerMessage: message, ctx: ctx}
+	err := cgh.topicsProcessor.ProcessMessage(cm)
+	if err == nil {
+		return true, nil
+	}
+
+	policy := cgh.retryPolicies[message.Topic]
+	if policy == nil {
+		cgh.logger.Errorw("failed to process message of topic without retry policy",
+			"topic", message.Topic,
+			"partition", message.Partition,
+			"offset", message.Offset,
+			zap.Error(err))
+		return false, err
+	}
+
+	attempts := 1
+	for retry := 1; retry <= policy.MaxRetries && retryable(err); retry++ {
+		if waitErr := waitForRetry(ctx, policy.Backoff(retry)); waitErr != nil {
+			return false, err
+		}
+
+		attempts++
+		err = cgh.topicsProcessor.ProcessMessage(cm)
+		if err == nil {
+			return true, nil
+		}
+	}
+
+	if dlqErr := cgh.produceDeadLetter(ctx, message, policy, attempts, err); dlqErr != nil {
+		return false, err
+	}
+
+	cgh.logger.Warnw("produced message to dead-letter topic",
+		"topic", message.Topic,
+		"partition", message.Partition,
+		"offset", message.Offset,
+		"dead_letter_topic", policy.DeadLetterTopic,
+		"attempts", attempts,
+		zap.Error(err))
+	return true, err
+}
+
+// produceDeadLetter produces the message to the dead-letter topic of the policy,
+// retrying with backoff until it succeeds or ctx is done, as the message must not be marked before it is parked.
+func (cgh *consumerGroupHandler) produceDeadLetter(ctx context.Context, message *sarama.ConsumerMessage, policy *RetryPolicy, attempts int, err error) error {
+	deadLetter := deadLetterMessage(message, policy.DeadLetterTopic, attempts, err, time.Now())
+	for retry := 1; ; retry++ {
+		dlqErr := cgh.deadLetterProducer.SendMessage(ctx, deadLetter)
+		if dlqErr == nil {
+			return nil
+		}
+
+		cgh.logger.Errorw("failed to produce message to dead-letter topic",
+			"topic", message.Topic,
+			"partition", message.Partition,
+			"offset", message.Offset,
+			"dead_letter_topic", policy.DeadLetterTopic,
+			"retry", retry,
+			zap.Error(dlqErr))
+
+		backoff := policy.Backoff(retry)
+		if backoff <= 0 {
+			backoff = defaultDeadLetterBackoff
+		}
+		if waitErr := waitForRetry(ctx, backoff); waitErr != nil {
+			return dlqErr
+		}
+	}
+}
+
+type consumerGroupHandlerParams struct {
+	topicsProcessor    *topicsProcessor
+	retryPolicies      map[string]*RetryPolicy
+	deadLetterProducer MessageProducer
+	logger             *zap.SugaredLogger
+}
+
+func newConsumerGroupHandler(params consumerGroupHandlerParams) *consumerGroupHandler {
+	logger := params.logger
+	if logger == nil {
+		logger = zap.NewNop().Sugar()
+	}
 	return &consumerGroupHandler{
-		readyChan:       make(chan bool),
-		topicsProcessor: processors,
+		readyChan:          make(chan bool),
+		topicsProcessor:    params.topicsProcessor,
+		retryPolicies:      params.retryPolicies,
+		deadLetterProducer: params.deadLetterProducer,
+		logger:             logger,
 	}
 }
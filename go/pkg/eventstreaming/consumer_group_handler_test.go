@@ -3,7 +3,10 @@ package eventstreaming
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/*company-data-covered*/services/go/pkg/testutils"
 	"github.com/Shopify/sarama"
@@ -66,12 +69,14 @@ func TestConsumerGroupHandler_ConsumeClaim(t *testing.T) {
 	testTopic1Processor := testMessageCounter{}
 	testTopic2Processor := testMessageCounter{}
 	testTopic4Processor := testMessageCounter{err: errors.New("ima error")}
-	cgh := newConsumerGroupHandler(newTopicsProcessor(map[string]MessageProcessor{
-		"test-topic-1": &testTopic1Processor,
-		"test-topic-2": &testTopic2Processor,
-		"test-topic-3": newTestMessageLogger(),
-		"test-topic-4": &testTopic4Processor,
-	}))
+	cgh := newConsumerGroupHandler(consumerGroupHandlerParams{
+		topicsProcessor: newTopicsProcessor(map[string]MessageProcessor{
+			"test-topic-1": &testTopic1Processor,
+			"test-topic-2": &testTopic2Processor,
+			"test-topic-3": newTestMessageLogger(),
+			"test-topic-4": &testTopic4Processor,
+		}),
+	})
 	session := newMockConsumerGroupSession()
 	claim := MockConsumerGroupClaim{
 		messages: make(chan *sarama.ConsumerMessage),
@@ -93,3 +98,224 @@ func TestConsumerGroupHandler_ConsumeClaim(t *testing.T) {
 	// Test topic 4 should not increase len of markedMessages because ProcessMessage errors
 	testutils.MustMatch(t, 4, len(session.markedMessages))
 }
+
+type testMessageProducer struct {
+	messages []*ProducerMessage
+	err      error
+	// errCount limits err to the first errCount calls to SendMessage, if positive.
+	errCount int
+	calls    int
+	closed   bool
+}
+
+func (p *testMessageProducer) SendMessage(_ context.Context, message *ProducerMessage) error {
+	p.calls++
+	if p.err != nil && (p.errCount <= 0 || p.calls <= p.errCount) {
+		return p.err
+	}
+	p.messages = append(p.messages, message)
+	return nil
+}
+
+func (p *testMessageProducer) Close() error {
+	p.closed = true
+	return nil
+}
+
+// testFlakyProcessor fails the first failures calls to ProcessMessage.
+type testFlakyProcessor struct {
+	failures int
+	// err is returned for failures, instead of a flaky error.
+	err   error
+	calls int
+}
+
+func (p *testFlakyProcessor) ProcessMessage(message ConsumerMessage) error {
+	p.calls++
+	if p.calls <= p.failures {
+		if p.err != nil {
+			return p.err
+		}
+		return errors.New("flaky error " + strconv.Itoa(p.calls))
+	}
+	return nil
+}
+
+func TestConsumerGroupHandler_ProcessMessageWithRetryPolicy(t *testing.T) {
+	message := &sarama.ConsumerMessage{
+		Topic:     "test-topic",
+		Partition: 2,
+		Offset:    42,
+		Value:     []byte("lab result"),
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte("correlation-id"), Value: []byte("abc")},
+		},
+	}
+
+	tcs := []struct {
+		Desc             string
+		Failures         int
+		FailureErr       error
+		Policy           *RetryPolicy
+		ProducerErr      error
+		ProducerErrCount int
+
+		ExpectedMarked             bool
+		ExpectedCalls              int
+		ExpectedProducerCalls      int
+		ExpectedDeadLetterMessages []*ProducerMessage
+	}{
+		{
+			Desc:     "no retry policy",
+			Failures: 1,
+
+			ExpectedMarked: false,
+			ExpectedCalls:  1,
+		},
+		{
+			Desc:     "succeeds on retry",
+			Failures: 2,
+			Policy:   &RetryPolicy{MaxRetries: 2, DeadLetterTopic: "test-topic-dlq"},
+
+			ExpectedMarked: true,
+			ExpectedCalls:  3,
+		},
+		{
+			Desc:     "retries exhausted with dead-letter topic",
+			Failures: 5,
+			Policy:   &RetryPolicy{MaxRetries: 2, DeadLetterTopic: "test-topic-dlq"},
+
+			ExpectedMarked:        true,
+			ExpectedCalls:         3,
+			ExpectedProducerCalls: 1,
+			ExpectedDeadLetterMessages: []*ProducerMessage{
+				{
+					Topic: "test-topic-dlq",
+					Value: []byte("lab result"),
+					Headers: map[string]string{
+						"correlation-id":                "abc",
+						DeadLetterSourceTopicHeader:     "test-topic",
+						DeadLetterSourcePartitionHeader: "2",
+						DeadLetterSourceOffsetHeader:    "42",
+						DeadLetterErrorHeader:           "flaky error 3",
+						DeadLetterAttemptsHeader:        "3",
+					},
+				},
+			},
+		},
+		{
+			Desc:       "unknown schema is not retried",
+			Failures:   5,
+			FailureErr: fmt.Errorf("%w: test", ErrUnknownSchema),
+			Policy:     &RetryPolicy{MaxRetries: 2, DeadLetterTopic: "test-topic-dlq"},
+
+			ExpectedMarked:        true,
+			ExpectedCalls:         1,
+			ExpectedProducerCalls: 1,
+			ExpectedDeadLetterMessages: []*ProducerMessage{
+				{
+					Topic: "test-topic-dlq",
+					Value: []byte("lab result"),
+					Headers: map[string]string{
+						"correlation-id":                "abc",
+						DeadLetterSourceTopicHeader:     "test-topic",
+						DeadLetterSourcePartitionHeader: "2",
+						DeadLetterSourceOffsetHeader:    "42",
+						DeadLetterErrorHeader:           "unknown message schema: test",
+						DeadLetterAttemptsHeader:        "1",
+					},
+				},
+			},
+		},
+		{
+			Desc:             "dead-letter producer error is retried",
+			Failures:         5,
+			Policy:           &RetryPolicy{InitialBackoff: time.Millisecond, DeadLetterTopic: "test-topic-dlq"},
+			ProducerErr:      errors.New("boo"),
+			ProducerErrCount: 2,
+
+			ExpectedMarked:        true,
+			ExpectedCalls:         1,
+			ExpectedProducerCalls: 3,
+			ExpectedDeadLetterMessages: []*ProducerMessage{
+				{
+					Topic: "test-topic-dlq",
+					Value: []byte("lab result"),
+					Headers: map[string]string{
+						"correlation-id":                "abc",
+						DeadLetterSourceTopicHeader:     "test-topic",
+						DeadLetterSourcePartitionHeader: "2",
+						DeadLetterSourceOffsetHeader:    "42",
+						DeadLetterErrorHeader:           "flaky error 1",
+						DeadLetterAttemptsHeader:        "1",
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			processor := &testFlakyProcessor{failures: tc.Failures, err: tc.FailureErr}
+			producer := &testMessageProducer{err: tc.ProducerErr, errCount: tc.ProducerErrCount}
+			retryPolicies := map[string]*RetryPolicy{}
+			if tc.Policy != nil {
+				retryPolicies["test-topic"] = tc.Policy
+			}
+			cgh := newConsumerGroupHandler(consumerGroupHandlerParams{
+				topicsProcessor:    newTopicsProcessor(map[string]MessageProcessor{"test-topic": processor}),
+				retryPolicies:      retryPolicies,
+				deadLetterProducer: producer,
+			})
+
+			marked := cgh.processMessage(context.Background(), message)
+
+			for _, m := range producer.messages {
+				if _, err := time.Parse(time.RFC3339, m.Headers[DeadLetterFailedAtHeader]); err != nil {
+					t.Fatalf("invalid failed at header: %v", err)
+				}
+				delete(m.Headers, DeadLetterFailedAtHeader)
+			}
+			testutils.MustMatch(t, tc.ExpectedMarked, marked)
+			testutils.MustMatch(t, tc.ExpectedCalls, processor.calls)
+			testutils.MustMatch(t, tc.ExpectedProducerCalls, producer.calls)
+			testutils.MustMatch(t, tc.ExpectedDeadLetterMessages, producer.messages)
+		})
+	}
+}
+
+func TestConsumerGroupHandler_ProcessMessageCanceledRetry(t *testing.T) {
+	processor := &testFlakyProcessor{failures: 5}
+	cgh := newConsumerGroupHandler(consumerGroupHandlerParams{
+		topicsProcessor: newTopicsProcessor(map[string]MessageProcessor{"test-topic": processor}),
+		retryPolicies: map[string]*RetryPolicy{
+			"test-topic": {MaxRetries: 3, InitialBackoff: time.Hour, DeadLetterTopic: "test-topic-dlq"},
+		},
+		deadLetterProducer: &testMessageProducer{},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	marked := cgh.processMessage(ctx, &sarama.ConsumerMessage{Topic: "test-topic"})
+	testutils.MustMatch(t, false, marked)
+	testutils.MustMatch(t, 1, processor.calls)
+}
+
+func TestConsumerGroupHandler_ProcessMessageCanceledDeadLetter(t *testing.T) {
+	processor := &testFlakyProcessor{failures: 5}
+	producer := &testMessageProducer{err: errors.New("boo")}
+	cgh := newConsumerGroupHandler(consumerGroupHandlerParams{
+		topicsProcessor: newTopicsProcessor(map[string]MessageProcessor{"test-topic": processor}),
+		retryPolicies: map[string]*RetryPolicy{
+			"test-topic": {InitialBackoff: time.Hour, DeadLetterTopic: "test-topic-dlq"},
+		},
+		deadLetterProducer: producer,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	marked := cgh.processMessage(ctx, &sarama.ConsumerMessage{Topic: "test-topic"})
+	testutils.MustMatch(t, false, marked)
+	testutils.MustMatch(t, 1, processor.calls)
+	testutils.MustMatch(t, 1, producer.calls)
+}
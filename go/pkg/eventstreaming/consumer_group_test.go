@@ -85,6 +85,24 @@ func TestNewConsumerGroup(t *testing.T) {
 				},
 			},
 
+			UseMockProvider: true,
+			HasError:        false,
+		},
+		{
+			Desc: "configuration with dead-letter topic",
+			Config: &ClientConfig{
+				Brokers: []string{"localhost:9092"},
+				Consumer: &ConsumerConfig{
+					GroupID:            "consumerGroupID",
+					AssignmentStrategy: RoundRobinAssignment,
+					ConsumeFromOldest:  true,
+					Topics:             topics,
+					RetryPolicies: map[string]*RetryPolicy{
+						"test-topic-1": {MaxRetries: 3, DeadLetterTopic: "test-topic-1-dlq"},
+					},
+				},
+			},
+
 			UseMockProvider: true,
 			HasError:        false,
 		},
@@ -93,12 +111,16 @@ func TestNewConsumerGroup(t *testing.T) {
 	for _, tc := range tcs {
 		if tc.UseMockProvider {
 			consumerGroupProvider = NewMockConsumerGroup
+			deadLetterProducerProvider = func(*ClientConfig) (MessageProducer, error) {
+				return &testMessageProducer{}, nil
+			}
 		}
 
 		consumerGroup, err := NewConsumerGroup(tc.Config)
 
 		if tc.UseMockProvider {
 			consumerGroupProvider = defaultConsumerGroupProvider
+			deadLetterProducerProvider = defaultDeadLetterProducerProvider
 		}
 
 		if err != nil && !tc.HasError {
@@ -127,6 +149,18 @@ func TestConsumerGroup_Stop(t *testing.T) {
 	testutils.MustMatch(t, errors.New("error closing client: close failed").Error(), err.Error())
 }
 
+func TestConsumerGroup_StopClosesDeadLetterProducer(t *testing.T) {
+	producer := &testMessageProducer{}
+	consumerGroup := consumerGroupClient{
+		config:             &testConsumerConfig,
+		client:             &MockConsumerGroupClient{},
+		deadLetterProducer: producer,
+	}
+	err := consumerGroup.Stop()
+	testutils.MustMatch(t, nil, err)
+	testutils.MustMatch(t, true, producer.closed)
+}
+
 func TestConsumerGroupPause(t *testing.T) {
 	mockConsumerGroupClient := MockConsumerGroupClient{}
 	consumerGroup := consumerGroupClient{
@@ -0,0 +1,141 @@
+package eventstreaming
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// Headers of messages produced to dead-letter topics.
+const (
+	DeadLetterSourceTopicHeader     = "x-dead-letter-source-topic"
+	DeadLetterSourcePartitionHeader = "x-dead-letter-source-partition"
+	DeadLetterSourceOffsetHeader    = "x-dead-letter-source-offset"
+	DeadLetterErrorHeader           = "x-dead-letter-error"
+	DeadLetterAttemptsHeader        = "x-dead-letter-attempts"
+	DeadLetterFailedAtHeader        = "x-dead-letter-failed-at"
+
+	deadLetterHeaderPrefix = "x-dead-letter-"
+)
+
+var errNoDeadLetterSourceTopic = errors.New("dead-letter message has no source topic header")
+
+// deadLetterMessage returns the message to produce to the dead-letter topic for a message that failed processing.
+// Headers of the original message are kept.
+func deadLetterMessage(message *sarama.ConsumerMessage, topic string, attempts int, err error, failedAt time.Time) *ProducerMessage {
+	headers := recordHeadersMap(message.Headers)
+	headers[DeadLetterSourceTopicHeader] = message.Topic
+	headers[DeadLetterSourcePartitionHeader] = strconv.FormatInt(int64(message.Partition), 10)
+	headers[DeadLetterSourceOffsetHeader] = strconv.FormatInt(message.Offset, 10)
+	headers[DeadLetterErrorHeader] = err.Error()
+	headers[DeadLetterAttemptsHeader] = strconv.Itoa(attempts)
+	headers[DeadLetterFailedAtHeader] = failedAt.UTC().Format(time.RFC3339)
+
+	return &ProducerMessage{
+		Topic:   topic,
+		Value:   message.Value,
+		Key:     message.Key,
+		Headers: headers,
+	}
+}
+
+// DeadLetterRedriver implements MessageProcessor, producing messages consumed from a dead-letter topic
+// back onto their source topic, without the dead-letter headers.
+type DeadLetterRedriver struct {
+	producer MessageProducer
+
+	// sourceTopic, if set, limits re-driving to messages from this source topic.
+	sourceTopic string
+
+	clock func() time.Time
+
+	mu              sync.Mutex
+	redrivenByTopic map[string]int
+	skipped         int
+	lastMessageAt   time.Time
+}
+
+// NewDeadLetterRedriver returns a DeadLetterRedriver producing with producer.
+// If sourceTopic is set, messages from other source topics are skipped, and so marked consumed:
+// consume with a consumer group dedicated to sourceTopic.
+func NewDeadLetterRedriver(producer MessageProducer, sourceTopic string) *DeadLetterRedriver {
+	return &DeadLetterRedriver{
+		producer:        producer,
+		sourceTopic:     sourceTopic,
+		clock:           time.Now,
+		redrivenByTopic: map[string]int{},
+	}
+}
+
+func (r *DeadLetterRedriver) ProcessMessage(message ConsumerMessage) error {
+	headers := message.Headers()
+
+	r.mu.Lock()
+	r.lastMessageAt = r.clock()
+	r.mu.Unlock()
+
+	sourceTopic := headers[DeadLetterSourceTopicHeader]
+	if sourceTopic == "" {
+		return errNoDeadLetterSourceTopic
+	}
+	if r.sourceTopic != "" && sourceTopic != r.sourceTopic {
+		r.mu.Lock()
+		r.skipped++
+		r.mu.Unlock()
+		return nil
+	}
+
+	redriveHeaders := make(map[string]string, len(headers))
+	for k, v := range headers {
+		if !strings.HasPrefix(k, deadLetterHeaderPrefix) {
+			redriveHeaders[k] = v
+		}
+	}
+	err := r.producer.SendMessage(message.Context(), &ProducerMessage{
+		Topic:   sourceTopic,
+		Value:   message.Value(),
+		Key:     message.Key(),
+		Headers: redriveHeaders,
+	})
+	if err != nil {
+		return fmt.Errorf("error re-driving message to %s topic: %w", sourceTopic, err)
+	}
+
+	r.mu.Lock()
+	r.redrivenByTopic[sourceTopic]++
+	r.mu.Unlock()
+	return nil
+}
+
+// DeadLetterRedriveStats summarizes the messages processed by a DeadLetterRedriver.
+type DeadLetterRedriveStats struct {
+	// Redriven is the number of messages produced back onto their source topic.
+	Redriven int
+	// RedrivenByTopic is the number of messages produced back onto each source topic.
+	RedrivenByTopic map[string]int
+	// Skipped is the number of messages from other source topics.
+	Skipped int
+	// LastMessageAt is when the last message was processed, or zero if none was.
+	LastMessageAt time.Time
+}
+
+func (r *DeadLetterRedriver) Stats() DeadLetterRedriveStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := DeadLetterRedriveStats{
+		RedrivenByTopic: make(map[string]int, len(r.redrivenByTopic)),
+		Skipped:         r.skipped,
+		LastMessageAt:   r.lastMessageAt,
+	}
+	for topic, count := range r.redrivenByTopic {
+		stats.RedrivenByTopic[topic] = count
+		stats.Redriven += count
+	}
+	return stats
+}
@@ -0,0 +1,161 @@
+package eventstreaming
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"github.com/Shopify/sarama"
+)
+
+func TestDeadLetterMessage(t *testing.T) {
+	message := &sarama.ConsumerMessage{
+		Topic:     "test-topic",
+		Partition: 1,
+		Offset:    7,
+		Key:       []byte("key"),
+		Value:     []byte("value"),
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte("correlation-id"), Value: []byte("abc")},
+		},
+	}
+	failedAt := time.Date(2022, 10, 3, 12, 30, 0, 0, time.UTC)
+
+	got := deadLetterMessage(message, "test-topic-dlq", 4, errors.New("boo"), failedAt)
+
+	testutils.MustMatch(t, &ProducerMessage{
+		Topic: "test-topic-dlq",
+		Value: []byte("value"),
+		Key:   []byte("key"),
+		Headers: map[string]string{
+			"correlation-id":                "abc",
+			DeadLetterSourceTopicHeader:     "test-topic",
+			DeadLetterSourcePartitionHeader: "1",
+			DeadLetterSourceOffsetHeader:    "7",
+			DeadLetterErrorHeader:           "boo",
+			DeadLetterAttemptsHeader:        "4",
+			DeadLetterFailedAtHeader:        "2022-10-03T12:30:00Z",
+		},
+	}, got)
+}
+
+func TestDeadLetterRedriver_ProcessMessage(t *testing.T) {
+	now := time.Date(2022, 10, 3, 12, 30, 0, 0, time.UTC)
+	newDeadLetterConsumerMessage := func(sourceTopic string) ConsumerMessage {
+		headers := []*sarama.RecordHeader{
+			{Key: []byte("correlation-id"), Value: []byte("abc")},
+			{Key: []byte(DeadLetterErrorHeader), Value: []byte("boo")},
+			{Key: []byte(DeadLetterAttemptsHeader), Value: []byte("4")},
+		}
+		if sourceTopic != "" {
+			headers = append(headers, &sarama.RecordHeader{
+				Key:   []byte(DeadLetterSourceTopicHeader),
+				Value: []byte(sourceTopic),
+			})
+		}
+		return &consumerMessage{consumerMessage: &sarama.ConsumerMessage{
+			Topic:   "dlq",
+			Value:   []byte("value"),
+			Headers: headers,
+		}}
+	}
+
+	tcs := []struct {
+		Desc        string
+		SourceTopic string
+		Messages    []ConsumerMessage
+		ProducerErr error
+
+		WantErr         error
+		WantProduced    []*ProducerMessage
+		WantRedrivenSum int
+		WantRedriven    map[string]int
+		WantSkipped     int
+		WantLastMessage time.Time
+	}{
+		{
+			Desc: "re-drives all source topics",
+			Messages: []ConsumerMessage{
+				newDeadLetterConsumerMessage("topic-1"),
+				newDeadLetterConsumerMessage("topic-2"),
+				newDeadLetterConsumerMessage("topic-1"),
+			},
+
+			WantProduced: []*ProducerMessage{
+				{Topic: "topic-1", Value: []byte("value"), Headers: map[string]string{"correlation-id": "abc"}},
+				{Topic: "topic-2", Value: []byte("value"), Headers: map[string]string{"correlation-id": "abc"}},
+				{Topic: "topic-1", Value: []byte("value"), Headers: map[string]string{"correlation-id": "abc"}},
+			},
+			WantRedriven:    map[string]int{"topic-1": 2, "topic-2": 1},
+			WantRedrivenSum: 3,
+			WantLastMessage: now,
+		},
+		{
+			Desc:        "skips other source topics",
+			SourceTopic: "topic-2",
+			Messages: []ConsumerMessage{
+				newDeadLetterConsumerMessage("topic-1"),
+				newDeadLetterConsumerMessage("topic-2"),
+			},
+
+			WantProduced: []*ProducerMessage{
+				{Topic: "topic-2", Value: []byte("value"), Headers: map[string]string{"correlation-id": "abc"}},
+			},
+			WantRedriven:    map[string]int{"topic-2": 1},
+			WantRedrivenSum: 1,
+			WantSkipped:     1,
+			WantLastMessage: now,
+		},
+		{
+			Desc:     "missing source topic header",
+			Messages: []ConsumerMessage{newDeadLetterConsumerMessage("")},
+
+			WantErr:         errNoDeadLetterSourceTopic,
+			WantRedriven:    map[string]int{},
+			WantLastMessage: now,
+		},
+		{
+			Desc:        "producer error",
+			Messages:    []ConsumerMessage{newDeadLetterConsumerMessage("topic-1")},
+			ProducerErr: errors.New("boo"),
+
+			WantErr:         errors.New("error re-driving message to topic-1 topic: boo"),
+			WantRedriven:    map[string]int{},
+			WantLastMessage: now,
+		},
+		{
+			Desc: "no messages",
+
+			WantRedriven: map[string]int{},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			producer := &testMessageProducer{err: tc.ProducerErr}
+			redriver := NewDeadLetterRedriver(producer, tc.SourceTopic)
+			redriver.clock = func() time.Time { return now }
+
+			var err error
+			for _, m := range tc.Messages {
+				if err = redriver.ProcessMessage(m); err != nil {
+					break
+				}
+			}
+
+			if tc.WantErr != nil {
+				testutils.MustMatch(t, tc.WantErr.Error(), err.Error())
+			} else {
+				testutils.MustMatch(t, nil, err)
+			}
+			testutils.MustMatch(t, tc.WantProduced, producer.messages)
+			testutils.MustMatch(t, DeadLetterRedriveStats{
+				Redriven:        tc.WantRedrivenSum,
+				RedrivenByTopic: tc.WantRedriven,
+				Skipped:         tc.WantSkipped,
+				LastMessageAt:   tc.WantLastMessage,
+			}, redriver.Stats())
+		})
+	}
+}
@@ -0,0 +1,565 @@
+package eventstreaming
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+const defaultMemoryTopicPartitions = 1
+
+var defaultMemoryBroker = NewMemoryBroker()
+
+// DefaultMemoryBroker returns the MemoryBroker shared by the clients of the process.
+func DefaultMemoryBroker() *MemoryBroker {
+	return defaultMemoryBroker
+}
+
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+// MemoryBroker is an in-process broker with topics, consumer groups and committed offsets,
+// for tests and local development without a Kafka cluster.
+// Set ClientConfig.MemoryBroker to use it with NewConsumerGroup and NewMessageProducer.
+//
+// Partitions are claimed by the first member of a consumer group consuming them, until its session ends,
+// instead of being balanced between members. When a member releases partitions, the sessions of the other members
+// of its group end, so that they claim the released partitions in their next session.
+type MemoryBroker struct {
+	mu sync.Mutex
+
+	// topics are the messages of each partition of topics, by topic.
+	topics map[string][][]*sarama.ConsumerMessage
+	// nextPartitions are the partitions that messages without keys are produced to next, by topic.
+	nextPartitions map[string]int32
+	// offsets are the committed offsets of consumer groups, by group.
+	offsets map[string]map[topicPartition]int64
+	// owners are the members of consumer groups claiming partitions, by group.
+	owners map[string]map[topicPartition]string
+	// rebalances are closed and replaced whenever a member of a consumer group releases partitions, by group.
+	rebalances map[string]chan struct{}
+
+	memberCount int
+	// changed is closed and replaced whenever consumers may have new messages to consume.
+	changed chan struct{}
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		topics:         map[string][][]*sarama.ConsumerMessage{},
+		nextPartitions: map[string]int32{},
+		offsets:        map[string]map[topicPartition]int64{},
+		owners:         map[string]map[topicPartition]string{},
+		rebalances:     map[string]chan struct{}{},
+		changed:        make(chan struct{}),
+	}
+}
+
+// CreateTopic creates a topic with the number of partitions.
+// Topics are otherwise created with a single partition when first produced to or consumed.
+func (b *MemoryBroker) CreateTopic(topic string, partitions int32) error {
+	if partitions < 1 {
+		return fmt.Errorf("invalid partition count %d for the %s topic", partitions, topic)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.topics[topic]; ok {
+		return fmt.Errorf("topic %s already exists", topic)
+	}
+	b.topics[topic] = make([][]*sarama.ConsumerMessage, partitions)
+	return nil
+}
+
+// Messages returns the messages produced to the topic, ordered by partition and offset.
+func (b *MemoryBroker) Messages(topic string) []*sarama.ConsumerMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var messages []*sarama.ConsumerMessage
+	for _, partitionMessages := range b.topics[topic] {
+		messages = append(messages, partitionMessages...)
+	}
+	return messages
+}
+
+// CommittedOffset returns the next offset of the partition to be consumed by the consumer group,
+// and whether the group committed an offset for the partition.
+func (b *MemoryBroker) CommittedOffset(groupID string, topic string, partition int32) (int64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	offset, ok := b.offsets[groupID][topicPartition{topic: topic, partition: partition}]
+	return offset, ok
+}
+
+// partitionsLocked returns the partitions of the topic, creating it if needed.
+func (b *MemoryBroker) partitionsLocked(topic string) [][]*sarama.ConsumerMessage {
+	partitions, ok := b.topics[topic]
+	if !ok {
+		partitions = make([][]*sarama.ConsumerMessage, defaultMemoryTopicPartitions)
+		b.topics[topic] = partitions
+	}
+	return partitions
+}
+
+func (b *MemoryBroker) notifyLocked() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+func (b *MemoryBroker) notify() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.notifyLocked()
+}
+
+// produce appends the message to a partition of its topic, returning its partition and offset.
+// Messages with keys are partitioned by key hash, and others round robin.
+func (b *MemoryBroker) produce(message *sarama.ProducerMessage) (int32, int64, error) {
+	key, err := encodeMemoryMessage(message.Key)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error encoding message key: %w", err)
+	}
+	value, err := encodeMemoryMessage(message.Value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error encoding message value: %w", err)
+	}
+	headers := make([]*sarama.RecordHeader, len(message.Headers))
+	for i := range message.Headers {
+		header := message.Headers[i]
+		headers[i] = &header
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	partitions := b.partitionsLocked(message.Topic)
+	var partition int32
+	if key != nil {
+		hash := fnv.New32a()
+		hash.Write(key)
+		partition = int32(hash.Sum32() % uint32(len(partitions)))
+	} else {
+		partition = b.nextPartitions[message.Topic] % int32(len(partitions))
+		b.nextPartitions[message.Topic] = partition + 1
+	}
+
+	offset := int64(len(partitions[partition]))
+	partitions[partition] = append(partitions[partition], &sarama.ConsumerMessage{
+		Topic:     message.Topic,
+		Partition: partition,
+		Offset:    offset,
+		Key:       key,
+		Value:     value,
+		Headers:   headers,
+		Timestamp: time.Now(),
+	})
+	b.notifyLocked()
+	return partition, offset, nil
+}
+
+func encodeMemoryMessage(encoder sarama.Encoder) ([]byte, error) {
+	if encoder == nil {
+		return nil, nil
+	}
+	return encoder.Encode()
+}
+
+// claim claims the unclaimed partitions of the topics for a member of the consumer group,
+// returning the claimed partitions, the offsets to start consuming them from,
+// and a channel that is closed once another member of the group releases partitions.
+func (b *MemoryBroker) claim(groupID string, memberID string, topics []string, consumeFromOldest bool) (map[string][]int32, map[topicPartition]int64, <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.owners[groupID] == nil {
+		b.owners[groupID] = map[topicPartition]string{}
+	}
+	if b.offsets[groupID] == nil {
+		b.offsets[groupID] = map[topicPartition]int64{}
+	}
+	if b.rebalances[groupID] == nil {
+		b.rebalances[groupID] = make(chan struct{})
+	}
+
+	claims := map[string][]int32{}
+	initialOffsets := map[topicPartition]int64{}
+	for _, topic := range topics {
+		for i, messages := range b.partitionsLocked(topic) {
+			tp := topicPartition{topic: topic, partition: int32(i)}
+			if _, ok := b.owners[groupID][tp]; ok {
+				continue
+			}
+			b.owners[groupID][tp] = memberID
+
+			offset, ok := b.offsets[groupID][tp]
+			if !ok && !consumeFromOldest {
+				offset = int64(len(messages))
+			}
+			claims[topic] = append(claims[topic], tp.partition)
+			initialOffsets[tp] = offset
+		}
+	}
+	return claims, initialOffsets, b.rebalances[groupID]
+}
+
+// release releases the partitions claimed by a member of the consumer group.
+// Unless the member is leaving for a rebalance itself, the other members are notified to rebalance.
+func (b *MemoryBroker) release(groupID string, memberID string, rebalance bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	released := false
+	for tp, owner := range b.owners[groupID] {
+		if owner == memberID {
+			delete(b.owners[groupID], tp)
+			released = true
+		}
+	}
+	if released && rebalance {
+		close(b.rebalances[groupID])
+		b.rebalances[groupID] = make(chan struct{})
+	}
+}
+
+func (b *MemoryBroker) newMemberID(groupID string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.memberCount++
+	return groupID + "-" + strconv.Itoa(b.memberCount)
+}
+
+// commit commits the offset of the partition for the consumer group.
+// Unless reset, offsets only move forward.
+func (b *MemoryBroker) commit(groupID string, tp topicPartition, offset int64, reset bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.offsets[groupID] == nil {
+		b.offsets[groupID] = map[topicPartition]int64{}
+	}
+	if current, ok := b.offsets[groupID][tp]; ok && offset < current && !reset {
+		return
+	}
+	b.offsets[groupID][tp] = offset
+}
+
+// next returns the message of the partition at offset, or nil if there is none yet,
+// and a channel that is closed once consumers may have new messages to consume.
+func (b *MemoryBroker) next(tp topicPartition, offset int64) (*sarama.ConsumerMessage, <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	partitions := b.topics[tp.topic]
+	if int(tp.partition) < len(partitions) && offset < int64(len(partitions[tp.partition])) {
+		return partitions[tp.partition][offset], b.changed
+	}
+	return nil, b.changed
+}
+
+func (b *MemoryBroker) highWaterMark(tp topicPartition) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	partitions := b.topics[tp.topic]
+	if int(tp.partition) >= len(partitions) {
+		return 0
+	}
+	return int64(len(partitions[tp.partition]))
+}
+
+// memoryConsumerGroup implements sarama.ConsumerGroup with a MemoryBroker.
+type memoryConsumerGroup struct {
+	broker            *MemoryBroker
+	groupID           string
+	consumeFromOldest bool
+
+	// errs is never sent to, as consume errors are returned by Consume.
+	errs chan error
+
+	mu        sync.Mutex
+	closed    bool
+	closeChan chan struct{}
+	pausedAll bool
+	paused    map[topicPartition]bool
+}
+
+func (b *MemoryBroker) newConsumerGroup(config *ConsumerConfig) *memoryConsumerGroup {
+	return &memoryConsumerGroup{
+		broker:            b,
+		groupID:           config.GroupID,
+		consumeFromOldest: config.ConsumeFromOldest,
+		errs:              make(chan error),
+		closeChan:         make(chan struct{}),
+		paused:            map[topicPartition]bool{},
+	}
+}
+
+// Consume joins the consumer group and consumes the topics with handler, until ctx is done,
+// the consumer group is closed, another member releases partitions, or a claim is no longer consumed by handler.
+// As with sarama consumer groups, Consume must be called again to rejoin the group after a rebalance.
+func (g *memoryConsumerGroup) Consume(ctx context.Context, topics []string, handler sarama.ConsumerGroupHandler) error {
+	g.mu.Lock()
+	closed := g.closed
+	g.mu.Unlock()
+	if closed {
+		return sarama.ErrClosedConsumerGroup
+	}
+
+	memberID := g.broker.newMemberID(g.groupID)
+	claims, initialOffsets, rebalance := g.broker.claim(g.groupID, memberID, topics, g.consumeFromOldest)
+	rebalanced := false
+	defer func() {
+		// Members leaving for a rebalance all rejoin, so they do not trigger another one.
+		g.broker.release(g.groupID, memberID, !rebalanced)
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-g.closeChan:
+			cancel()
+		case <-rebalance:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	session := &memoryConsumerGroupSession{
+		ctx:      ctx,
+		memberID: memberID,
+		claims:   claims,
+		group:    g,
+	}
+	if err := handler.Setup(session); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var consumeErr error
+	for tp, offset := range initialOffsets {
+		claim := &memoryConsumerGroupClaim{
+			tp:            tp,
+			initialOffset: offset,
+			broker:        g.broker,
+			messages:      make(chan *sarama.ConsumerMessage),
+		}
+
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			g.feed(ctx, claim)
+		}()
+		go func() {
+			defer wg.Done()
+			// The session ends once a claim is no longer consumed, as with sarama consumer groups.
+			defer cancel()
+			if err := handler.ConsumeClaim(session, claim); err != nil {
+				errOnce.Do(func() { consumeErr = err })
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	wg.Wait()
+	select {
+	case <-rebalance:
+		rebalanced = true
+	default:
+	}
+
+	if err := handler.Cleanup(session); err != nil {
+		return err
+	}
+	return consumeErr
+}
+
+// feed sends the messages of the claimed partition to the claim, while not paused, until ctx is done.
+// The messages channel is not closed, so that handlers only stop consuming once the session context is done.
+func (g *memoryConsumerGroup) feed(ctx context.Context, claim *memoryConsumerGroupClaim) {
+	offset := claim.initialOffset
+	for {
+		// Read the changed channel before checking the pause state, to not miss a resume.
+		message, changed := g.broker.next(claim.tp, offset)
+		if message == nil || g.isPaused(claim.tp) {
+			select {
+			case <-changed:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case claim.messages <- message:
+			offset++
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (g *memoryConsumerGroup) isPaused(tp topicPartition) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.pausedAll || g.paused[tp]
+}
+
+// Errors returns a channel without errors, as consume errors are returned by Consume. It is closed by Close.
+func (g *memoryConsumerGroup) Errors() <-chan error {
+	return g.errs
+}
+
+func (g *memoryConsumerGroup) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return errors.New("consumer group is already closed")
+	}
+	g.closed = true
+	close(g.closeChan)
+	close(g.errs)
+	return nil
+}
+
+func (g *memoryConsumerGroup) Pause(partitions map[string][]int32) {
+	g.setPaused(partitions, true)
+}
+
+func (g *memoryConsumerGroup) Resume(partitions map[string][]int32) {
+	g.setPaused(partitions, false)
+}
+
+func (g *memoryConsumerGroup) setPaused(partitions map[string][]int32, paused bool) {
+	g.mu.Lock()
+	for topic, ps := range partitions {
+		for _, p := range ps {
+			tp := topicPartition{topic: topic, partition: p}
+			if paused {
+				g.paused[tp] = true
+			} else {
+				delete(g.paused, tp)
+			}
+		}
+	}
+	g.mu.Unlock()
+
+	g.broker.notify()
+}
+
+func (g *memoryConsumerGroup) PauseAll() {
+	g.mu.Lock()
+	g.pausedAll = true
+	g.mu.Unlock()
+}
+
+func (g *memoryConsumerGroup) ResumeAll() {
+	g.mu.Lock()
+	g.pausedAll = false
+	g.paused = map[topicPartition]bool{}
+	g.mu.Unlock()
+
+	g.broker.notify()
+}
+
+// memoryConsumerGroupSession implements sarama.ConsumerGroupSession, committing marked offsets immediately.
+type memoryConsumerGroupSession struct {
+	ctx      context.Context
+	memberID string
+	claims   map[string][]int32
+	group    *memoryConsumerGroup
+}
+
+func (s *memoryConsumerGroupSession) Claims() map[string][]int32 {
+	claims := make(map[string][]int32, len(s.claims))
+	for topic, partitions := range s.claims {
+		claims[topic] = append([]int32(nil), partitions...)
+		sort.Slice(claims[topic], func(i, j int) bool { return claims[topic][i] < claims[topic][j] })
+	}
+	return claims
+}
+
+func (s *memoryConsumerGroupSession) MemberID() string {
+	return s.memberID
+}
+
+func (s *memoryConsumerGroupSession) GenerationID() int32 {
+	return 1
+}
+
+func (s *memoryConsumerGroupSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+	s.group.broker.commit(s.group.groupID, topicPartition{topic: topic, partition: partition}, offset, false)
+}
+
+func (s *memoryConsumerGroupSession) Commit() {}
+
+func (s *memoryConsumerGroupSession) ResetOffset(topic string, partition int32, offset int64, metadata string) {
+	s.group.broker.commit(s.group.groupID, topicPartition{topic: topic, partition: partition}, offset, true)
+}
+
+func (s *memoryConsumerGroupSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.MarkOffset(msg.Topic, msg.Partition, msg.Offset+1, metadata)
+}
+
+func (s *memoryConsumerGroupSession) Context() context.Context {
+	return s.ctx
+}
+
+// memoryConsumerGroupClaim implements sarama.ConsumerGroupClaim.
+type memoryConsumerGroupClaim struct {
+	tp            topicPartition
+	initialOffset int64
+	broker        *MemoryBroker
+	messages      chan *sarama.ConsumerMessage
+}
+
+func (c *memoryConsumerGroupClaim) Topic() string {
+	return c.tp.topic
+}
+
+func (c *memoryConsumerGroupClaim) Partition() int32 {
+	return c.tp.partition
+}
+
+func (c *memoryConsumerGroupClaim) InitialOffset() int64 {
+	return c.initialOffset
+}
+
+func (c *memoryConsumerGroupClaim) HighWaterMarkOffset() int64 {
+	return c.broker.highWaterMark(c.tp)
+}
+
+func (c *memoryConsumerGroupClaim) Messages() <-chan *sarama.ConsumerMessage {
+	return c.messages
+}
+
+// memoryProducer implements MessageProducer with a MemoryBroker.
+// Messages are appended to their topic before SendMessage returns, also when the producer is configured as async.
+type memoryProducer struct {
+	broker *MemoryBroker
+}
+
+func (p *memoryProducer) SendMessage(ctx context.Context, message *ProducerMessage) error {
+	return sendMessage(ctx, message, p.broker.produce)
+}
+
+func (p *memoryProducer) Close() error {
+	return nil
+}
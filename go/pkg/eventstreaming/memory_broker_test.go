@@ -0,0 +1,324 @@
+package eventstreaming
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"github.com/Shopify/sarama"
+)
+
+// testChannelProcessor sends processed messages to a channel, failing with err if set.
+type testChannelProcessor struct {
+	messages chan ConsumerMessage
+	err      error
+}
+
+func newTestChannelProcessor() *testChannelProcessor {
+	return &testChannelProcessor{messages: make(chan ConsumerMessage, 10)}
+}
+
+func (p *testChannelProcessor) ProcessMessage(message ConsumerMessage) error {
+	p.messages <- message
+	return p.err
+}
+
+func (p *testChannelProcessor) receive(t *testing.T) ConsumerMessage {
+	t.Helper()
+	select {
+	case message := <-p.messages:
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for message")
+		return nil
+	}
+}
+
+func (p *testChannelProcessor) expectNone(t *testing.T) {
+	t.Helper()
+	select {
+	case message := <-p.messages:
+		t.Fatalf("unexpected message: %s", message.Value())
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func waitFor(t *testing.T, desc string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", desc)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (b *MemoryBroker) hasClaims(groupID string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.owners[groupID]) > 0
+}
+
+func newTestMemoryProducer(t *testing.T, broker *MemoryBroker) MessageProducer {
+	t.Helper()
+	producer, err := NewMessageProducer(&ClientConfig{
+		MemoryBroker: broker,
+		Producer:     &ProducerConfig{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return producer
+}
+
+func startTestMemoryConsumerGroup(t *testing.T, broker *MemoryBroker, consumerConfig *ConsumerConfig) ConsumerGroup {
+	t.Helper()
+	if consumerConfig.AssignmentStrategy == "" {
+		consumerConfig.AssignmentStrategy = StickyAssignment
+	}
+	consumerGroup, err := NewConsumerGroup(&ClientConfig{
+		MemoryBroker: broker,
+		Consumer:     consumerConfig,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	consumerGroup.Start(context.Background())
+	return consumerGroup
+}
+
+func stopTestMemoryConsumerGroup(t *testing.T, broker *MemoryBroker, consumerGroup ConsumerGroup, groupID string) {
+	t.Helper()
+	if err := consumerGroup.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "claims to be released", func() bool { return !broker.hasClaims(groupID) })
+}
+
+func TestMemoryBroker_ProduceAndConsume(t *testing.T) {
+	broker := NewMemoryBroker()
+	producer := newTestMemoryProducer(t, broker)
+	processor := newTestChannelProcessor()
+	consumerGroup := startTestMemoryConsumerGroup(t, broker, &ConsumerConfig{
+		GroupID: "group",
+		Topics:  map[string]MessageProcessor{"topic": processor},
+	})
+
+	messages := []*ProducerMessage{
+		{Topic: "topic", Value: []byte("first"), Key: []byte("key"), Headers: map[string]string{"correlation-id": "1"}},
+		{Topic: "topic", Value: []byte("second")},
+	}
+	for i, message := range messages {
+		if err := producer.SendMessage(context.Background(), message); err != nil {
+			t.Fatal(err)
+		}
+		testutils.MustMatch(t, int32(0), message.Partition)
+		testutils.MustMatch(t, int64(i), message.Offset)
+	}
+
+	first := processor.receive(t)
+	testutils.MustMatch(t, "first", string(first.Value()))
+	testutils.MustMatch(t, "key", string(first.Key()))
+	testutils.MustMatch(t, "1", first.Headers()["correlation-id"])
+	testutils.MustMatch(t, int64(0), first.Offset())
+
+	second := processor.receive(t)
+	testutils.MustMatch(t, "second", string(second.Value()))
+	testutils.MustMatch(t, []byte(nil), second.Key())
+	testutils.MustMatch(t, int64(1), second.Offset())
+
+	waitFor(t, "offsets to be committed", func() bool {
+		offset, _ := broker.CommittedOffset("group", "topic", 0)
+		return offset == 2
+	})
+	stopTestMemoryConsumerGroup(t, broker, consumerGroup, "group")
+	testutils.MustMatch(t, 2, len(broker.Messages("topic")))
+}
+
+func TestMemoryBroker_ConsumerGroupOffsets(t *testing.T) {
+	broker := NewMemoryBroker()
+	producer := newTestMemoryProducer(t, broker)
+	for _, value := range []string{"first", "second"} {
+		if err := producer.SendMessage(context.Background(), &ProducerMessage{Topic: "topic", Value: []byte(value)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	processor := newTestChannelProcessor()
+	consumerGroup := startTestMemoryConsumerGroup(t, broker, &ConsumerConfig{
+		GroupID:           "group",
+		ConsumeFromOldest: true,
+		Topics:            map[string]MessageProcessor{"topic": processor},
+	})
+	testutils.MustMatch(t, "first", string(processor.receive(t).Value()))
+	testutils.MustMatch(t, "second", string(processor.receive(t).Value()))
+	waitFor(t, "offsets to be committed", func() bool {
+		offset, _ := broker.CommittedOffset("group", "topic", 0)
+		return offset == 2
+	})
+	stopTestMemoryConsumerGroup(t, broker, consumerGroup, "group")
+
+	if err := producer.SendMessage(context.Background(), &ProducerMessage{Topic: "topic", Value: []byte("third")}); err != nil {
+		t.Fatal(err)
+	}
+
+	consumerGroup = startTestMemoryConsumerGroup(t, broker, &ConsumerConfig{
+		GroupID:           "group",
+		ConsumeFromOldest: true,
+		Topics:            map[string]MessageProcessor{"topic": processor},
+	})
+	testutils.MustMatch(t, "third", string(processor.receive(t).Value()), "consumer group resumes from committed offset")
+	processor.expectNone(t)
+	stopTestMemoryConsumerGroup(t, broker, consumerGroup, "group")
+
+	newestProcessor := newTestChannelProcessor()
+	consumerGroup = startTestMemoryConsumerGroup(t, broker, &ConsumerConfig{
+		GroupID: "other-group",
+		Topics:  map[string]MessageProcessor{"topic": newestProcessor},
+	})
+	newestProcessor.expectNone(t)
+	stopTestMemoryConsumerGroup(t, broker, consumerGroup, "other-group")
+
+	_, ok := broker.CommittedOffset("other-group", "topic", 0)
+	testutils.MustMatch(t, false, ok)
+}
+
+func TestMemoryBroker_Partitions(t *testing.T) {
+	broker := NewMemoryBroker()
+	if err := broker.CreateTopic("topic", 4); err != nil {
+		t.Fatal(err)
+	}
+	testutils.MustMatch(t, errors.New("topic topic already exists").Error(), broker.CreateTopic("topic", 4).Error())
+	testutils.MustMatch(t, errors.New("invalid partition count 0 for the other-topic topic").Error(), broker.CreateTopic("other-topic", 0).Error())
+
+	producer := newTestMemoryProducer(t, broker)
+
+	var keyedPartitions []int32
+	for i := 0; i < 3; i++ {
+		message := &ProducerMessage{Topic: "topic", Value: []byte("keyed"), Key: []byte("key")}
+		if err := producer.SendMessage(context.Background(), message); err != nil {
+			t.Fatal(err)
+		}
+		keyedPartitions = append(keyedPartitions, message.Partition)
+		testutils.MustMatch(t, int64(i), message.Offset)
+	}
+	testutils.MustMatch(t, []int32{keyedPartitions[0], keyedPartitions[0], keyedPartitions[0]}, keyedPartitions)
+
+	var partitions []int32
+	for i := 0; i < 4; i++ {
+		message := &ProducerMessage{Topic: "topic", Value: []byte("unkeyed")}
+		if err := producer.SendMessage(context.Background(), message); err != nil {
+			t.Fatal(err)
+		}
+		partitions = append(partitions, message.Partition)
+	}
+	testutils.MustMatch(t, []int32{0, 1, 2, 3}, partitions)
+	testutils.MustMatch(t, 7, len(broker.Messages("topic")))
+}
+
+func TestMemoryBroker_PauseAndResume(t *testing.T) {
+	broker := NewMemoryBroker()
+	producer := newTestMemoryProducer(t, broker)
+	processor := newTestChannelProcessor()
+	consumerGroup := startTestMemoryConsumerGroup(t, broker, &ConsumerConfig{
+		GroupID: "group",
+		Topics:  map[string]MessageProcessor{"topic": processor},
+	})
+
+	consumerGroup.Pause()
+	if err := producer.SendMessage(context.Background(), &ProducerMessage{Topic: "topic", Value: []byte("value")}); err != nil {
+		t.Fatal(err)
+	}
+	processor.expectNone(t)
+
+	consumerGroup.Resume()
+	testutils.MustMatch(t, "value", string(processor.receive(t).Value()))
+	stopTestMemoryConsumerGroup(t, broker, consumerGroup, "group")
+}
+
+func TestMemoryBroker_Rebalance(t *testing.T) {
+	broker := NewMemoryBroker()
+	producer := newTestMemoryProducer(t, broker)
+	firstProcessor := newTestChannelProcessor()
+	first := startTestMemoryConsumerGroup(t, broker, &ConsumerConfig{
+		GroupID: "group",
+		Topics:  map[string]MessageProcessor{"topic": firstProcessor},
+	})
+	waitFor(t, "first member to claim the partition", func() bool { return broker.hasClaims("group") })
+
+	secondProcessor := newTestChannelProcessor()
+	second := startTestMemoryConsumerGroup(t, broker, &ConsumerConfig{
+		GroupID: "group",
+		Topics:  map[string]MessageProcessor{"topic": secondProcessor},
+	})
+
+	if err := producer.SendMessage(context.Background(), &ProducerMessage{Topic: "topic", Value: []byte("first")}); err != nil {
+		t.Fatal(err)
+	}
+	testutils.MustMatch(t, "first", string(firstProcessor.receive(t).Value()))
+	secondProcessor.expectNone(t)
+	waitFor(t, "offsets to be committed", func() bool {
+		offset, _ := broker.CommittedOffset("group", "topic", 0)
+		return offset == 1
+	})
+
+	if err := first.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := producer.SendMessage(context.Background(), &ProducerMessage{Topic: "topic", Value: []byte("second")}); err != nil {
+		t.Fatal(err)
+	}
+	testutils.MustMatch(t, "second", string(secondProcessor.receive(t).Value()), "remaining member claims the released partition")
+	firstProcessor.expectNone(t)
+	stopTestMemoryConsumerGroup(t, broker, second, "group")
+}
+
+func TestMemoryConsumerGroup_Close(t *testing.T) {
+	consumerGroup := NewMemoryBroker().newConsumerGroup(&ConsumerConfig{GroupID: "group"})
+	errs := consumerGroup.Errors()
+	if consumerGroup.Errors() != errs {
+		t.Fatal("expected a single errors channel")
+	}
+
+	if err := consumerGroup.Close(); err != nil {
+		t.Fatal(err)
+	}
+	_, ok := <-errs
+	testutils.MustMatch(t, false, ok, "errors channel is closed")
+	if err := consumerGroup.Consume(context.Background(), []string{"topic"}, nil); !errors.Is(err, sarama.ErrClosedConsumerGroup) {
+		t.Fatalf("unexpected consume error after close: %v", err)
+	}
+}
+
+func TestMemoryBroker_DeadLetterTopic(t *testing.T) {
+	broker := NewMemoryBroker()
+	producer := newTestMemoryProducer(t, broker)
+	processor := newTestChannelProcessor()
+	processor.err = errors.New("processing failed")
+	consumerGroup := startTestMemoryConsumerGroup(t, broker, &ConsumerConfig{
+		GroupID: "group",
+		Topics:  map[string]MessageProcessor{"topic": processor},
+		RetryPolicies: map[string]*RetryPolicy{
+			"topic": {MaxRetries: 1, DeadLetterTopic: "topic-dlq"},
+		},
+	})
+
+	if err := producer.SendMessage(context.Background(), &ProducerMessage{Topic: "topic", Value: []byte("value")}); err != nil {
+		t.Fatal(err)
+	}
+	processor.receive(t)
+	processor.receive(t)
+	waitFor(t, "dead-letter message", func() bool { return len(broker.Messages("topic-dlq")) == 1 })
+	stopTestMemoryConsumerGroup(t, broker, consumerGroup, "group")
+
+	deadLetter := broker.Messages("topic-dlq")[0]
+	headers := recordHeadersMap(deadLetter.Headers)
+	testutils.MustMatch(t, "value", string(deadLetter.Value))
+	testutils.MustMatch(t, "topic", headers[DeadLetterSourceTopicHeader])
+	testutils.MustMatch(t, "processing failed", headers[DeadLetterErrorHeader])
+	testutils.MustMatch(t, "2", headers[DeadLetterAttemptsHeader])
+}
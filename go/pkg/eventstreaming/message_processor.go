@@ -1,6 +1,7 @@
 package eventstreaming
 
 import (
+	"context"
 	"time"
 
 	"github.com/Shopify/sarama"
@@ -10,11 +11,19 @@ type ConsumerMessage interface {
 	Topic() string
 	Value() []byte
 	Timestamp() time.Time
+	Key() []byte
+	Headers() map[string]string
+	Partition() int32
+	Offset() int64
+
+	// Context carries the trace of the message consumption, continuing the trace of its producer if any.
+	Context() context.Context
 }
 
 // ConsumerMessage is processed by MessageProcessor.ProcessMessage.
 type consumerMessage struct {
 	consumerMessage *sarama.ConsumerMessage
+	ctx             context.Context
 }
 
 func (cm *consumerMessage) Topic() string {
@@ -26,6 +35,35 @@ func (cm *consumerMessage) Value() []byte {
 func (cm *consumerMessage) Timestamp() time.Time {
 	return cm.consumerMessage.Timestamp
 }
+func (cm *consumerMessage) Key() []byte {
+	return cm.consumerMessage.Key
+}
+func (cm *consumerMessage) Headers() map[string]string {
+	return recordHeadersMap(cm.consumerMessage.Headers)
+}
+func (cm *consumerMessage) Partition() int32 {
+	return cm.consumerMessage.Partition
+}
+func (cm *consumerMessage) Offset() int64 {
+	return cm.consumerMessage.Offset
+}
+func (cm *consumerMessage) Context() context.Context {
+	if cm.ctx == nil {
+		return context.Background()
+	}
+	return cm.ctx
+}
+
+func recordHeadersMap(recordHeaders []*sarama.RecordHeader) map[string]string {
+	headers := make(map[string]string, len(recordHeaders))
+	for _, h := range recordHeaders {
+		if h == nil {
+			continue
+		}
+		headers[string(h.Key)] = string(h.Value)
+	}
+	return headers
+}
 
 // MessageProcessor performs some useful work on a retrieved message via its ProcessMessage implementation.
 type MessageProcessor interface {
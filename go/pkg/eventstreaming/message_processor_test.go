@@ -1,6 +1,7 @@
 package eventstreaming
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -9,12 +10,17 @@ import (
 	"time"
 
 	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"github.com/Shopify/sarama"
 )
 
 type MockConsumerMessage struct {
 	topic     string
 	value     []byte
 	timestamp time.Time
+	key       []byte
+	headers   map[string]string
+	partition int32
+	offset    int64
 }
 
 func (cm *MockConsumerMessage) Topic() string {
@@ -26,6 +32,21 @@ func (cm *MockConsumerMessage) Value() []byte {
 func (cm *MockConsumerMessage) Timestamp() time.Time {
 	return cm.timestamp
 }
+func (cm *MockConsumerMessage) Key() []byte {
+	return cm.key
+}
+func (cm *MockConsumerMessage) Headers() map[string]string {
+	return cm.headers
+}
+func (cm *MockConsumerMessage) Partition() int32 {
+	return cm.partition
+}
+func (cm *MockConsumerMessage) Offset() int64 {
+	return cm.offset
+}
+func (cm *MockConsumerMessage) Context() context.Context {
+	return context.Background()
+}
 
 type testMessageCounter struct {
 	count int
@@ -151,3 +172,37 @@ func TestTopicsProcessor_Topics(t *testing.T) {
 
 	testutils.MustMatch(t, control, topics, "received incorrect topics")
 }
+
+func TestConsumerMessage(t *testing.T) {
+	timestamp := time.Date(2022, 10, 3, 12, 30, 0, 0, time.UTC)
+	ctx := context.WithValue(context.Background(), testContextKey{}, "value")
+	message := &consumerMessage{
+		consumerMessage: &sarama.ConsumerMessage{
+			Topic:     "test-topic",
+			Value:     []byte("value"),
+			Key:       []byte("key"),
+			Timestamp: timestamp,
+			Partition: 2,
+			Offset:    42,
+			Headers: []*sarama.RecordHeader{
+				{Key: []byte("correlation-id"), Value: []byte("abc")},
+				nil,
+			},
+		},
+		ctx: ctx,
+	}
+
+	testutils.MustMatch(t, "test-topic", message.Topic())
+	testutils.MustMatch(t, []byte("value"), message.Value())
+	testutils.MustMatch(t, []byte("key"), message.Key())
+	testutils.MustMatch(t, timestamp, message.Timestamp())
+	testutils.MustMatch(t, int32(2), message.Partition())
+	testutils.MustMatch(t, int64(42), message.Offset())
+	testutils.MustMatch(t, map[string]string{"correlation-id": "abc"}, message.Headers())
+	testutils.MustMatch(t, "value", message.Context().Value(testContextKey{}))
+
+	message.ctx = nil
+	testutils.MustMatch(t, nil, message.Context().Err())
+}
+
+type testContextKey struct{}
@@ -1,23 +1,65 @@
 package eventstreaming
 
 import (
+	"context"
 	"errors"
 	"log"
 	"os"
+	"sort"
 
 	"github.com/Shopify/sarama"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 )
 
 type ProducerMessage struct {
 	Topic string
 	Value []byte
+
+	// Key, if set, partitions the message, so that messages with the same key are consumed in order.
+	Key []byte
+
+	// Headers are sent as Kafka record headers, such as correlation IDs or error metadata for dead-letter messages.
+	// Trace context headers are added when the message is sent.
+	Headers map[string]string
+
+	// Partition and Offset are set once the message is sent by a sync or memory broker producer.
+	Partition int32
+	Offset    int64
+}
+
+func (message *ProducerMessage) toSaramaMessage() *sarama.ProducerMessage {
+	saramaMessage := &sarama.ProducerMessage{
+		Topic: message.Topic,
+		Value: sarama.ByteEncoder(message.Value),
+	}
+	if message.Key != nil {
+		saramaMessage.Key = sarama.ByteEncoder(message.Key)
+	}
+	if len(message.Headers) == 0 {
+		return saramaMessage
+	}
+
+	keys := make([]string, 0, len(message.Headers))
+	for k := range message.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	saramaMessage.Headers = make([]sarama.RecordHeader, len(keys))
+	for i, k := range keys {
+		saramaMessage.Headers[i] = sarama.RecordHeader{
+			Key:   []byte(k),
+			Value: []byte(message.Headers[k]),
+		}
+	}
+	return saramaMessage
 }
 
 type MessageProducer interface {
 	// SendMessage produces a given message.
 	// In sync mode it will return on success or failure with an error if it failed.
 	// In async mode it will immediately return with an always nil error.
-	SendMessage(message *ProducerMessage) error
+	// The trace of ctx is continued by consumers of the message.
+	SendMessage(ctx context.Context, message *ProducerMessage) error
 
 	// Close shuts down the producer; you must call this function before a producer
 	// object passes out of scope, as it may otherwise leak memory.
@@ -32,12 +74,26 @@ type asyncProducer struct {
 	producer sarama.AsyncProducer
 }
 
-func (producer *syncProducer) SendMessage(message *ProducerMessage) error {
-	_, _, err := producer.producer.SendMessage(&sarama.ProducerMessage{
-		Topic: message.Topic,
-		Value: sarama.ByteEncoder(message.Value),
-	})
-	return err
+func (producer *syncProducer) SendMessage(ctx context.Context, message *ProducerMessage) error {
+	return sendMessage(ctx, message, producer.producer.SendMessage)
+}
+
+// sendMessage traces sending the message with send, and sets the partition and offset it was sent to.
+func sendMessage(ctx context.Context, message *ProducerMessage, send func(*sarama.ProducerMessage) (int32, int64, error)) error {
+	saramaMessage := message.toSaramaMessage()
+	span := startProduceSpan(ctx, saramaMessage)
+	partition, offset, err := send(saramaMessage)
+	if err != nil {
+		span.Finish(tracer.WithError(err))
+		return err
+	}
+
+	span.SetTag(partitionSpanTag, partition)
+	span.SetTag(offsetSpanTag, offset)
+	span.Finish()
+	message.Partition = partition
+	message.Offset = offset
+	return nil
 }
 
 func (producer *syncProducer) Close() error {
@@ -47,11 +103,11 @@ func (producer *syncProducer) Close() error {
 	return nil
 }
 
-func (producer *asyncProducer) SendMessage(message *ProducerMessage) error {
-	producer.producer.Input() <- (&sarama.ProducerMessage{
-		Topic: message.Topic,
-		Value: sarama.ByteEncoder(message.Value),
-	})
+func (producer *asyncProducer) SendMessage(ctx context.Context, message *ProducerMessage) error {
+	saramaMessage := message.toSaramaMessage()
+	span := startProduceSpan(ctx, saramaMessage)
+	producer.producer.Input() <- saramaMessage
+	span.Finish()
 	return nil
 }
 
@@ -106,6 +162,10 @@ func NewMessageProducer(config *ClientConfig) (MessageProducer, error) {
 		return nil, errors.New("no producer config provided")
 	}
 
+	if config.MemoryBroker != nil {
+		return &memoryProducer{broker: config.MemoryBroker}, nil
+	}
+
 	var err error
 	var producer MessageProducer
 	if config.Producer.Async {
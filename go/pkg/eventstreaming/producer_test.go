@@ -1,11 +1,13 @@
 package eventstreaming
 
 import (
+	"context"
 	"errors"
 	"io"
 	"testing"
 	"time"
 
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
 	"github.com/Shopify/sarama"
 	"github.com/Shopify/sarama/mocks"
 )
@@ -145,7 +147,7 @@ func TestSendMessageSync(t *testing.T) {
 		producer := syncProducer{
 			producer: mockProducer,
 		}
-		err := producer.SendMessage(tc.Message)
+		err := producer.SendMessage(context.Background(), tc.Message)
 
 		if tc.FailSend && err == nil {
 			t.Fatalf("Expected error when message fails to send")
@@ -157,6 +159,28 @@ func TestSendMessageSync(t *testing.T) {
 	}
 }
 
+func TestProducerMessage_toSaramaMessage(t *testing.T) {
+	message := &ProducerMessage{
+		Topic: "my_topic",
+		Value: []byte("test message"),
+		Key:   []byte("patient-1"),
+		Headers: map[string]string{
+			"b-header": "b",
+			"a-header": "a",
+		},
+	}
+
+	testutils.MustMatch(t, &sarama.ProducerMessage{
+		Topic: "my_topic",
+		Value: sarama.ByteEncoder("test message"),
+		Key:   sarama.ByteEncoder("patient-1"),
+		Headers: []sarama.RecordHeader{
+			{Key: []byte("a-header"), Value: []byte("a")},
+			{Key: []byte("b-header"), Value: []byte("b")},
+		},
+	}, message.toSaramaMessage())
+}
+
 func TestSendMessageAsync(t *testing.T) {
 	tcs := []struct {
 		Desc     string
@@ -193,7 +217,7 @@ func TestSendMessageAsync(t *testing.T) {
 		producer := asyncProducer{
 			producer: mockProducer,
 		}
-		err := producer.SendMessage(tc.Message)
+		err := producer.SendMessage(context.Background(), tc.Message)
 
 		if tc.FailSend && err != nil {
 			t.Fatalf("Expected error when message fails to send")
@@ -0,0 +1,99 @@
+package eventstreaming
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const defaultBackoffMultiplier = 2
+
+// nonRetryableErrors are errors of the consumed message itself, that processing it again cannot fix.
+var nonRetryableErrors = []error{ErrUnknownSchema, ErrUnknownSchemaVersion, ErrEmptyMessageValue}
+
+// retryable returns whether processing a message that failed with err again may succeed.
+func retryable(err error) bool {
+	for _, nonRetryableErr := range nonRetryableErrors {
+		if errors.Is(err, nonRetryableErr) {
+			return false
+		}
+	}
+	return true
+}
+
+// RetryPolicy defines how messages of a topic that fail processing are retried,
+// and where they are parked once retries are exhausted.
+//
+// Messages with an unknown schema or schema version, or without a value, are not retried,
+// and are parked right away.
+type RetryPolicy struct {
+	// MaxRetries is the number of times a failed message is processed again.
+	MaxRetries int
+
+	// InitialBackoff is the wait before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the wait between retries. 0 means no cap.
+	MaxBackoff time.Duration
+
+	// BackoffMultiplier multiplies the wait for each later retry. 0 defaults to 2.
+	BackoffMultiplier float64
+
+	// DeadLetterTopic is the topic that messages are produced to once retries are exhausted,
+	// with error metadata in their headers, after which they are marked as consumed.
+	// Producing to it is retried until it succeeds or the session ends. Required.
+	DeadLetterTopic string
+}
+
+// Backoff returns the wait before the given retry, starting at 1.
+func (p *RetryPolicy) Backoff(retry int) time.Duration {
+	if retry < 1 || p.InitialBackoff <= 0 {
+		return 0
+	}
+
+	multiplier := p.BackoffMultiplier
+	if multiplier <= 0 {
+		multiplier = defaultBackoffMultiplier
+	}
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < retry; i++ {
+		backoff *= multiplier
+		if p.MaxBackoff > 0 && backoff >= float64(p.MaxBackoff) {
+			return p.MaxBackoff
+		}
+	}
+	return time.Duration(backoff)
+}
+
+func (p *RetryPolicy) validate(topic string) error {
+	if p.MaxRetries < 0 {
+		return fmt.Errorf("negative max retries for the %s topic retry policy", topic)
+	}
+	if p.InitialBackoff < 0 || p.MaxBackoff < 0 || p.BackoffMultiplier < 0 {
+		return fmt.Errorf("negative backoff for the %s topic retry policy", topic)
+	}
+	if p.DeadLetterTopic == "" {
+		return fmt.Errorf("no dead-letter topic for the %s topic retry policy", topic)
+	}
+	if p.DeadLetterTopic == topic {
+		return fmt.Errorf("dead-letter topic is the same as the %s topic", topic)
+	}
+	return nil
+}
+
+// waitForRetry waits for the backoff duration, or returns an error if the context is done first.
+func waitForRetry(ctx context.Context, backoff time.Duration) error {
+	if backoff <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,114 @@
+package eventstreaming
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	tcs := []struct {
+		Desc   string
+		Policy RetryPolicy
+		Retry  int
+
+		Want time.Duration
+	}{
+		{
+			Desc:   "no initial backoff",
+			Policy: RetryPolicy{MaxRetries: 3},
+			Retry:  2,
+
+			Want: 0,
+		},
+		{
+			Desc:   "first retry",
+			Policy: RetryPolicy{InitialBackoff: time.Second},
+			Retry:  1,
+
+			Want: time.Second,
+		},
+		{
+			Desc:   "default multiplier",
+			Policy: RetryPolicy{InitialBackoff: time.Second},
+			Retry:  3,
+
+			Want: 4 * time.Second,
+		},
+		{
+			Desc:   "custom multiplier",
+			Policy: RetryPolicy{InitialBackoff: time.Second, BackoffMultiplier: 1.5},
+			Retry:  3,
+
+			Want: 2250 * time.Millisecond,
+		},
+		{
+			Desc:   "capped by max backoff",
+			Policy: RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second},
+			Retry:  10,
+
+			Want: 5 * time.Second,
+		},
+		{
+			Desc:   "invalid retry",
+			Policy: RetryPolicy{InitialBackoff: time.Second},
+			Retry:  0,
+
+			Want: 0,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			testutils.MustMatch(t, tc.Want, tc.Policy.Backoff(tc.Retry))
+		})
+	}
+}
+
+func TestWaitForRetry(t *testing.T) {
+	err := waitForRetry(context.Background(), time.Millisecond)
+	testutils.MustMatch(t, nil, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = waitForRetry(ctx, time.Hour)
+	testutils.MustMatch(t, context.Canceled, err)
+}
+
+func TestRetryable(t *testing.T) {
+	tcs := []struct {
+		Desc string
+		Err  error
+
+		Want bool
+	}{
+		{
+			Desc: "processing error",
+			Err:  errors.New("boo"),
+
+			Want: true,
+		},
+		{
+			Desc: "unknown schema",
+			Err:  fmt.Errorf("%w: test", ErrUnknownSchema),
+		},
+		{
+			Desc: "unknown schema version",
+			Err:  fmt.Errorf("%w: test", ErrUnknownSchemaVersion),
+		},
+		{
+			Desc: "empty value",
+			Err:  fmt.Errorf("%w for the test topic", ErrEmptyMessageValue),
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			testutils.MustMatch(t, tc.Want, retryable(tc.Err))
+		})
+	}
+}
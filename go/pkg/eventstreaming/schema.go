@@ -0,0 +1,114 @@
+package eventstreaming
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Headers identifying the schema of messages produced by a TypedProducer.
+const (
+	SchemaHeader        = "x-schema"
+	SchemaVersionHeader = "x-schema-version"
+)
+
+var (
+	// ErrUnknownSchema is returned for messages of a different type than the schema of their topic.
+	ErrUnknownSchema = errors.New("unknown message schema")
+	// ErrUnknownSchemaVersion is returned for messages with a schema version that is not accepted for their topic.
+	ErrUnknownSchemaVersion = errors.New("unknown message schema version")
+
+	errNoTopicSchema = errors.New("no schema is registered for topic")
+)
+
+// TopicSchema is the schema of the messages of a topic.
+type TopicSchema struct {
+	// Message is the proto message of the topic.
+	Message proto.Message
+
+	// Version is the schema version that is produced.
+	// Bump it, keeping the previous version in AcceptedVersions, when the meaning of the message changes.
+	Version int
+
+	// AcceptedVersions are schema versions consumed in addition to Version.
+	AcceptedVersions []int
+
+	// AcceptUnversioned consumes messages without schema headers as Version,
+	// for topics with producers not yet using a TypedProducer.
+	AcceptUnversioned bool
+}
+
+func (s TopicSchema) messageName() protoreflect.FullName {
+	return s.Message.ProtoReflect().Descriptor().FullName()
+}
+
+func (s TopicSchema) acceptsVersion(version int) bool {
+	if version == s.Version {
+		return true
+	}
+	for _, v := range s.AcceptedVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// SchemaRegistry holds the schemas of topics, shared by their producers and consumers.
+type SchemaRegistry struct {
+	schemas map[string]TopicSchema
+}
+
+// NewSchemaRegistry returns a SchemaRegistry with schemas by topic.
+func NewSchemaRegistry(schemas map[string]TopicSchema) *SchemaRegistry {
+	return &SchemaRegistry{
+		schemas: schemas,
+	}
+}
+
+// topicSchema returns the schema of the topic, checking that it is for messages of type T.
+func topicSchema[T proto.Message](registry *SchemaRegistry, topic string) (TopicSchema, error) {
+	schema, ok := registry.schemas[topic]
+	if !ok || schema.Message == nil {
+		return TopicSchema{}, fmt.Errorf("%w: %s", errNoTopicSchema, topic)
+	}
+
+	var message T
+	if name := message.ProtoReflect().Descriptor().FullName(); name != schema.messageName() {
+		return TopicSchema{}, fmt.Errorf("%w: %s for the %s topic, registered schema is %s", ErrUnknownSchema, name, topic, schema.messageName())
+	}
+	return schema, nil
+}
+
+// schemaHeaders returns the headers identifying the schema of messages produced to a topic.
+func (s TopicSchema) schemaHeaders() map[string]string {
+	return map[string]string{
+		SchemaHeader:        string(s.messageName()),
+		SchemaVersionHeader: strconv.Itoa(s.Version),
+	}
+}
+
+// checkHeaders returns an error if the schema headers of a message consumed from topic are not accepted.
+func (s TopicSchema) checkHeaders(topic string, headers map[string]string) error {
+	name, hasName := headers[SchemaHeader]
+	version, hasVersion := headers[SchemaVersionHeader]
+	if !hasName && !hasVersion && s.AcceptUnversioned {
+		return nil
+	}
+
+	if name != string(s.messageName()) {
+		return fmt.Errorf("%w: %q for the %s topic, want %s", ErrUnknownSchema, name, topic, s.messageName())
+	}
+	v, err := strconv.Atoi(version)
+	if err != nil || !s.acceptsVersion(v) {
+		return fmt.Errorf("%w: %q of %s for the %s topic, accepted versions are %v", ErrUnknownSchemaVersion, version, name, topic, s.acceptedVersions())
+	}
+	return nil
+}
+
+func (s TopicSchema) acceptedVersions() []int {
+	return append([]int{s.Version}, s.AcceptedVersions...)
+}
@@ -0,0 +1,116 @@
+package eventstreaming
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestTopicSchema(t *testing.T) {
+	registry := NewSchemaRegistry(map[string]TopicSchema{
+		"test-topic": {Message: &wrapperspb.StringValue{}, Version: 1},
+	})
+
+	tcs := []struct {
+		Desc  string
+		Topic string
+		Get   func(*SchemaRegistry, string) (TopicSchema, error)
+
+		WantErr error
+	}{
+		{
+			Desc:  "registered schema",
+			Topic: "test-topic",
+			Get:   topicSchema[*wrapperspb.StringValue],
+		},
+		{
+			Desc:  "unregistered topic",
+			Topic: "other-topic",
+			Get:   topicSchema[*wrapperspb.StringValue],
+
+			WantErr: errNoTopicSchema,
+		},
+		{
+			Desc:  "different message type",
+			Topic: "test-topic",
+			Get:   topicSchema[*wrapperspb.Int64Value],
+
+			WantErr: ErrUnknownSchema,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			_, err := tc.Get(registry, tc.Topic)
+			testutils.MustMatch(t, true, errors.Is(err, tc.WantErr), "unexpected error")
+		})
+	}
+}
+
+func TestTopicSchema_CheckHeaders(t *testing.T) {
+	schema := TopicSchema{
+		Message:          &wrapperspb.StringValue{},
+		Version:          2,
+		AcceptedVersions: []int{1},
+	}
+
+	tcs := []struct {
+		Desc              string
+		Headers           map[string]string
+		AcceptUnversioned bool
+
+		WantErr error
+	}{
+		{
+			Desc:    "current version",
+			Headers: map[string]string{SchemaHeader: "google.protobuf.StringValue", SchemaVersionHeader: "2"},
+		},
+		{
+			Desc:    "accepted version",
+			Headers: map[string]string{SchemaHeader: "google.protobuf.StringValue", SchemaVersionHeader: "1"},
+		},
+		{
+			Desc:    "unknown version",
+			Headers: map[string]string{SchemaHeader: "google.protobuf.StringValue", SchemaVersionHeader: "3"},
+
+			WantErr: errors.New(`unknown message schema version: "3" of google.protobuf.StringValue for the test-topic topic, accepted versions are [2 1]`),
+		},
+		{
+			Desc:    "invalid version",
+			Headers: map[string]string{SchemaHeader: "google.protobuf.StringValue", SchemaVersionHeader: "v2"},
+
+			WantErr: errors.New(`unknown message schema version: "v2" of google.protobuf.StringValue for the test-topic topic, accepted versions are [2 1]`),
+		},
+		{
+			Desc:    "different message",
+			Headers: map[string]string{SchemaHeader: "google.protobuf.Int64Value", SchemaVersionHeader: "2"},
+
+			WantErr: errors.New(`unknown message schema: "google.protobuf.Int64Value" for the test-topic topic, want google.protobuf.StringValue`),
+		},
+		{
+			Desc: "unversioned",
+
+			WantErr: errors.New(`unknown message schema: "" for the test-topic topic, want google.protobuf.StringValue`),
+		},
+		{
+			Desc:              "accepted unversioned",
+			AcceptUnversioned: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			s := schema
+			s.AcceptUnversioned = tc.AcceptUnversioned
+
+			err := s.checkHeaders("test-topic", tc.Headers)
+			if tc.WantErr == nil {
+				testutils.MustMatch(t, nil, err)
+				return
+			}
+			testutils.MustMatch(t, tc.WantErr.Error(), err.Error())
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package eventstreaming
+
+import (
+	"context"
+
+	"github.com/Shopify/sarama"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+const (
+	produceSpanName = "kafka.produce"
+	consumeSpanName = "kafka.consume"
+
+	partitionSpanTag = "kafka.partition"
+	offsetSpanTag    = "kafka.offset"
+)
+
+// producerMessageCarrier injects trace context into the headers of a sarama.ProducerMessage.
+type producerMessageCarrier struct {
+	message *sarama.ProducerMessage
+}
+
+// Set implements tracer.TextMapWriter, replacing any existing header with the same key.
+func (c producerMessageCarrier) Set(key, val string) {
+	for i, h := range c.message.Headers {
+		if string(h.Key) == key {
+			c.message.Headers[i].Value = []byte(val)
+			return
+		}
+	}
+	c.message.Headers = append(c.message.Headers, sarama.RecordHeader{
+		Key:   []byte(key),
+		Value: []byte(val),
+	})
+}
+
+// startProduceSpan starts a span for producing the message, and injects its trace context into the message headers,
+// so that consumers continue the trace.
+func startProduceSpan(ctx context.Context, message *sarama.ProducerMessage) ddtrace.Span {
+	span, _ := tracer.StartSpanFromContext(
+		ctx,
+		produceSpanName,
+		tracer.ResourceName("Produce Topic "+message.Topic),
+		tracer.SpanType(ext.SpanTypeMessageProducer),
+	)
+	// Injection only fails for invalid span contexts, which are not worth failing the message for.
+	_ = tracer.Inject(span.Context(), producerMessageCarrier{message: message})
+	return span
+}
+
+// startConsumeSpan starts a span for consuming the message, continuing the trace of its producer if any.
+func startConsumeSpan(ctx context.Context, message *sarama.ConsumerMessage) (ddtrace.Span, context.Context) {
+	opts := []tracer.StartSpanOption{
+		tracer.ResourceName("Consume Topic " + message.Topic),
+		tracer.SpanType(ext.SpanTypeMessageConsumer),
+		tracer.Tag(partitionSpanTag, message.Partition),
+		tracer.Tag(offsetSpanTag, message.Offset),
+	}
+	if spanCtx, err := tracer.Extract(tracer.TextMapCarrier(recordHeadersMap(message.Headers))); err == nil {
+		opts = append(opts, tracer.ChildOf(spanCtx))
+	}
+	return tracer.StartSpanFromContext(ctx, consumeSpanName, opts...)
+}
@@ -0,0 +1,80 @@
+package eventstreaming
+
+import (
+	"context"
+	"testing"
+
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"github.com/Shopify/sarama"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+func TestProducerMessageCarrier_Set(t *testing.T) {
+	message := &sarama.ProducerMessage{
+		Headers: []sarama.RecordHeader{
+			{Key: []byte("a-header"), Value: []byte("a")},
+		},
+	}
+	carrier := producerMessageCarrier{message: message}
+
+	carrier.Set("b-header", "b")
+	carrier.Set("a-header", "new a")
+
+	testutils.MustMatch(t, []sarama.RecordHeader{
+		{Key: []byte("a-header"), Value: []byte("new a")},
+		{Key: []byte("b-header"), Value: []byte("b")},
+	}, message.Headers)
+}
+
+func TestTracePropagation(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	parentSpan, ctx := tracer.StartSpanFromContext(context.Background(), "parent")
+	producerMessage := (&ProducerMessage{
+		Topic:   "test-topic",
+		Value:   []byte("value"),
+		Headers: map[string]string{"correlation-id": "abc"},
+	}).toSaramaMessage()
+	produceSpan := startProduceSpan(ctx, producerMessage)
+	produceSpan.Finish()
+	parentSpan.Finish()
+
+	headers := make([]*sarama.RecordHeader, len(producerMessage.Headers))
+	for i := range producerMessage.Headers {
+		headers[i] = &producerMessage.Headers[i]
+	}
+	consumeSpan, consumeCtx := startConsumeSpan(context.Background(), &sarama.ConsumerMessage{
+		Topic:     "test-topic",
+		Partition: 3,
+		Offset:    10,
+		Headers:   headers,
+	})
+	consumeSpan.Finish()
+
+	testutils.MustMatch(t, parentSpan.Context().TraceID(), consumeSpan.Context().TraceID())
+	spanFromCtx, ok := tracer.SpanFromContext(consumeCtx)
+	testutils.MustMatch(t, true, ok)
+	testutils.MustMatch(t, consumeSpan.Context().SpanID(), spanFromCtx.Context().SpanID())
+
+	spans := mt.FinishedSpans()
+	testutils.MustMatch(t, 3, len(spans))
+	consumed := spans[2]
+	testutils.MustMatch(t, consumeSpanName, consumed.OperationName())
+	testutils.MustMatch(t, produceSpan.Context().SpanID(), consumed.ParentID())
+	testutils.MustMatch(t, int32(3), consumed.Tag(partitionSpanTag))
+	testutils.MustMatch(t, int64(10), consumed.Tag(offsetSpanTag))
+}
+
+func TestStartConsumeSpan_NoTraceHeaders(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	span, _ := startConsumeSpan(context.Background(), &sarama.ConsumerMessage{Topic: "test-topic"})
+	span.Finish()
+
+	spans := mt.FinishedSpans()
+	testutils.MustMatch(t, 1, len(spans))
+	testutils.MustMatch(t, uint64(0), spans[0].ParentID())
+}
@@ -0,0 +1,108 @@
+package eventstreaming
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrEmptyMessageValue is returned for consumed messages without a value.
+var ErrEmptyMessageValue = errors.New("empty message value")
+
+// TypedProducerMessage is a message of type T to be produced by a TypedProducer.
+type TypedProducerMessage[T proto.Message] struct {
+	Message T
+
+	// Key, if set, partitions the message, so that messages with the same key are consumed in order.
+	Key []byte
+
+	// Headers are sent as Kafka record headers, in addition to the schema headers.
+	Headers map[string]string
+}
+
+// TypedProducer produces proto messages of type T to a topic, with headers identifying their schema.
+type TypedProducer[T proto.Message] struct {
+	producer MessageProducer
+	topic    string
+	schema   TopicSchema
+}
+
+// NewTypedProducer returns a TypedProducer of the topic, whose schema in registry must be for messages of type T.
+func NewTypedProducer[T proto.Message](producer MessageProducer, registry *SchemaRegistry, topic string) (*TypedProducer[T], error) {
+	schema, err := topicSchema[T](registry, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TypedProducer[T]{
+		producer: producer,
+		topic:    topic,
+		schema:   schema,
+	}, nil
+}
+
+// SendMessage marshals and produces the message. See MessageProducer.SendMessage.
+func (p *TypedProducer[T]) SendMessage(ctx context.Context, message *TypedProducerMessage[T]) error {
+	value, err := proto.Marshal(message.Message)
+	if err != nil {
+		return fmt.Errorf("error marshaling message for the %s topic: %w", p.topic, err)
+	}
+
+	headers := p.schema.schemaHeaders()
+	for k, v := range message.Headers {
+		if _, ok := headers[k]; !ok {
+			headers[k] = v
+		}
+	}
+	return p.producer.SendMessage(ctx, &ProducerMessage{
+		Topic:   p.topic,
+		Value:   value,
+		Key:     message.Key,
+		Headers: headers,
+	})
+}
+
+// TypedMessageProcessor performs some useful work on a consumed proto message of type T.
+type TypedMessageProcessor[T proto.Message] interface {
+	ProcessTypedMessage(message ConsumerMessage, value T) error
+}
+
+// TypedProcessor implements MessageProcessor, checking the schema headers of consumed messages
+// and unmarshaling them into T for a TypedMessageProcessor.
+type TypedProcessor[T proto.Message] struct {
+	processor TypedMessageProcessor[T]
+	topic     string
+	schema    TopicSchema
+}
+
+// NewTypedProcessor returns a TypedProcessor of the topic, whose schema in registry must be for messages of type T.
+func NewTypedProcessor[T proto.Message](registry *SchemaRegistry, topic string, processor TypedMessageProcessor[T]) (*TypedProcessor[T], error) {
+	schema, err := topicSchema[T](registry, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TypedProcessor[T]{
+		processor: processor,
+		topic:     topic,
+		schema:    schema,
+	}, nil
+}
+
+func (p *TypedProcessor[T]) ProcessMessage(message ConsumerMessage) error {
+	if err := p.schema.checkHeaders(p.topic, message.Headers()); err != nil {
+		return err
+	}
+	if message.Value() == nil {
+		return fmt.Errorf("%w for the %s topic", ErrEmptyMessageValue, p.topic)
+	}
+
+	value := p.schema.Message.ProtoReflect().New().Interface().(T)
+	if err := proto.Unmarshal(message.Value(), value); err != nil {
+		return fmt.Errorf("error unmarshaling message of the %s topic: %w", p.topic, err)
+	}
+
+	return p.processor.ProcessTypedMessage(message, value)
+}
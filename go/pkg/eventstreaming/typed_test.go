@@ -0,0 +1,153 @@
+package eventstreaming
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+var testSchemaRegistry = NewSchemaRegistry(map[string]TopicSchema{
+	"test-topic": {Message: &wrapperspb.StringValue{}, Version: 2, AcceptedVersions: []int{1}},
+})
+
+type testStringValueProcessor struct {
+	values []string
+}
+
+func (p *testStringValueProcessor) ProcessTypedMessage(message ConsumerMessage, value *wrapperspb.StringValue) error {
+	p.values = append(p.values, value.GetValue())
+	return nil
+}
+
+func TestTypedProducer_SendMessage(t *testing.T) {
+	producer := &testMessageProducer{}
+	typedProducer, err := NewTypedProducer[*wrapperspb.StringValue](producer, testSchemaRegistry, "test-topic")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = typedProducer.SendMessage(context.Background(), &TypedProducerMessage[*wrapperspb.StringValue]{
+		Message: wrapperspb.String("hello"),
+		Key:     []byte("key"),
+		Headers: map[string]string{
+			"correlation-id":    "abc",
+			SchemaVersionHeader: "7",
+		},
+	})
+	testutils.MustMatch(t, nil, err)
+
+	value, err := proto.Marshal(wrapperspb.String("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutils.MustMatch(t, []*ProducerMessage{
+		{
+			Topic: "test-topic",
+			Value: value,
+			Key:   []byte("key"),
+			Headers: map[string]string{
+				"correlation-id":    "abc",
+				SchemaHeader:        "google.protobuf.StringValue",
+				SchemaVersionHeader: "2",
+			},
+		},
+	}, producer.messages)
+}
+
+func TestNewTypedProducer_UnknownSchema(t *testing.T) {
+	_, err := NewTypedProducer[*wrapperspb.Int64Value](&testMessageProducer{}, testSchemaRegistry, "test-topic")
+	testutils.MustMatch(t, true, errors.Is(err, ErrUnknownSchema))
+}
+
+func TestTypedProcessor_ProcessMessage(t *testing.T) {
+	value, err := proto.Marshal(wrapperspb.String("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcs := []struct {
+		Desc    string
+		Message ConsumerMessage
+
+		WantErr    error
+		WantValues []string
+	}{
+		{
+			Desc: "current version",
+			Message: &MockConsumerMessage{
+				topic:   "test-topic",
+				value:   value,
+				headers: map[string]string{SchemaHeader: "google.protobuf.StringValue", SchemaVersionHeader: "2"},
+			},
+
+			WantValues: []string{"hello"},
+		},
+		{
+			Desc: "accepted version",
+			Message: &MockConsumerMessage{
+				topic:   "test-topic",
+				value:   value,
+				headers: map[string]string{SchemaHeader: "google.protobuf.StringValue", SchemaVersionHeader: "1"},
+			},
+
+			WantValues: []string{"hello"},
+		},
+		{
+			Desc: "unknown version",
+			Message: &MockConsumerMessage{
+				topic:   "test-topic",
+				value:   value,
+				headers: map[string]string{SchemaHeader: "google.protobuf.StringValue", SchemaVersionHeader: "3"},
+			},
+
+			WantErr: ErrUnknownSchemaVersion,
+		},
+		{
+			Desc: "empty value",
+			Message: &MockConsumerMessage{
+				topic:   "test-topic",
+				headers: map[string]string{SchemaHeader: "google.protobuf.StringValue", SchemaVersionHeader: "2"},
+			},
+
+			WantErr: ErrEmptyMessageValue,
+		},
+		{
+			Desc: "invalid value",
+			Message: &MockConsumerMessage{
+				topic:   "test-topic",
+				value:   []byte("not a proto"),
+				headers: map[string]string{SchemaHeader: "google.protobuf.StringValue", SchemaVersionHeader: "2"},
+			},
+
+			WantErr: errors.New("error unmarshaling message of the test-topic topic"),
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			processor := &testStringValueProcessor{}
+			typedProcessor, err := NewTypedProcessor[*wrapperspb.StringValue](testSchemaRegistry, "test-topic", processor)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = typedProcessor.ProcessMessage(tc.Message)
+
+			switch {
+			case tc.WantErr == nil:
+				testutils.MustMatch(t, nil, err)
+			case errors.Is(err, tc.WantErr):
+			default:
+				if err == nil || !strings.HasPrefix(err.Error(), tc.WantErr.Error()) {
+					t.Fatalf("want error %v, got %v", tc.WantErr, err)
+				}
+			}
+			testutils.MustMatch(t, tc.WantValues, processor.values)
+		})
+	}
+}
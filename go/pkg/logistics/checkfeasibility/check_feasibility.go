@@ -85,6 +85,23 @@ func (f *FeasibilityTree) Evaluate(
 		return f.evaluateLeaf(ctx, vrpSolver, enableDebugMarshaling)
 	}
 
+	results, err := f.EvaluateChildren(ctx, vrpSolver, enableDebugMarshaling)
+	if err != nil {
+		return FeasibilityResult{Status: logisticspb.CheckFeasibilityResponse_STATUS_INFEASIBLE}, err
+	}
+
+	return results.collectResult(f.PartiallyFeasibleResult, f.PropagateInfeasibility), nil
+}
+
+// EvaluateChildren evaluates the children of the node concurrently, returning their results in order.
+//
+// It allows checking many independent feasibility problems at once, such as candidate time windows of a visit,
+// without collecting them into a single result.
+func (f *FeasibilityTree) EvaluateChildren(
+	ctx context.Context,
+	vrpSolver VRPSolver,
+	enableDebugMarshaling bool,
+) (FeasibilityResults, error) {
 	results := make(FeasibilityResults, len(f.Children))
 	eg, ctx := errgroup.WithContext(ctx)
 	for i, child := range f.Children {
@@ -99,10 +116,10 @@ func (f *FeasibilityTree) Evaluate(
 		})
 	}
 	if err := eg.Wait(); err != nil {
-		return FeasibilityResult{Status: logisticspb.CheckFeasibilityResponse_STATUS_INFEASIBLE}, err
+		return nil, err
 	}
 
-	return results.collectResult(f.PartiallyFeasibleResult, f.PropagateInfeasibility), nil
+	return results, nil
 }
 
 var (
@@ -172,6 +189,7 @@ func (f *FeasibilityTree) evaluateLeaf(
 	return FeasibilityResult{
 		Status:                 result,
 		Diagnostics:            diagnosticsData,
+		SoftScore:              score.GetSoftScore(),
 		propagateInfeasibility: f.PropagateInfeasibility,
 	}, nil
 }
@@ -192,8 +210,11 @@ type SolveVRPInput struct {
 
 type FeasibilityResults []FeasibilityResult
 type FeasibilityResult struct {
-	Status                  logisticspb.CheckFeasibilityResponse_Status
-	Diagnostics             DiagnosticsData
+	Status      logisticspb.CheckFeasibilityResponse_Status
+	Diagnostics DiagnosticsData
+	// SoftScore is the soft score of the solution of a leaf, or the sum of the soft scores of the leaves of a node.
+	SoftScore int64
+
 	propagateInfeasibility  bool
 	partiallyFeasibleStatus logisticspb.CheckFeasibilityResponse_Status
 }
@@ -205,6 +226,7 @@ func (frs FeasibilityResults) collectResult(
 	var hasFeasible, hasInfeasible, hasPartiallyFeasible bool
 	var hasInfeasibleOverride bool
 	var collectedDebugData []*logisticspb.CheckFeasibilityVRPDebugData
+	var softScore int64
 	if len(frs) == 0 {
 		return FeasibilityResult{
 			Status: logisticspb.CheckFeasibilityResponse_STATUS_FEASIBLE,
@@ -228,12 +250,14 @@ func (frs FeasibilityResults) collectResult(
 			}
 		}
 		collectedDebugData = append(collectedDebugData, fr.Diagnostics.DebugData...)
+		softScore += fr.SoftScore
 	}
 	diagnosticsData := DiagnosticsData{DebugData: collectedDebugData}
 	if hasInfeasibleOverride {
 		return FeasibilityResult{
 			Status:                 logisticspb.CheckFeasibilityResponse_STATUS_INFEASIBLE,
 			Diagnostics:            diagnosticsData,
+			SoftScore:              softScore,
 			propagateInfeasibility: true,
 		}
 	}
@@ -241,6 +265,7 @@ func (frs FeasibilityResults) collectResult(
 		return FeasibilityResult{
 			Status:                  partiallyFeasibleStatus,
 			Diagnostics:             diagnosticsData,
+			SoftScore:               softScore,
 			partiallyFeasibleStatus: partiallyFeasibleStatus,
 		}
 	}
@@ -248,11 +273,13 @@ func (frs FeasibilityResults) collectResult(
 		return FeasibilityResult{
 			Status:      logisticspb.CheckFeasibilityResponse_STATUS_FEASIBLE,
 			Diagnostics: diagnosticsData,
+			SoftScore:   softScore,
 		}
 	}
 	return FeasibilityResult{
 		Status:                 logisticspb.CheckFeasibilityResponse_STATUS_INFEASIBLE,
 		Diagnostics:            diagnosticsData,
+		SoftScore:              softScore,
 		propagateInfeasibility: propagateInfeasibility,
 	}
 }
@@ -319,6 +346,8 @@ func AvailabilitiesFromServiceRegionAvailabilityData(
 	return availabilities
 }
 
+// filterVisitsByAttributesSet keeps the visits with all attributes of any of the required attributes sets.
+// Only hard visit attributes are considered, as attribute preferences never make a visit infeasible.
 func filterVisitsByAttributesSet(
 	visits []*logisticssql.ServiceRegionAvailabilityVisit,
 	attributesMap logisticsdb.VisitsAttributesMap,
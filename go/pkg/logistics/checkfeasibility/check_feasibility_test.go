@@ -161,6 +161,49 @@ func TestFeasibilityTreeResolution(t *testing.T) {
 	}
 }
 
+func TestFeasibilityTreeEvaluateChildren(t *testing.T) {
+	shiftTeams := []*optimizerpb.VRPShiftTeam{{ /* we need a shift team to not short circuit*/ }}
+	req := func(seed int64) *optimizerpb.SolveVRPRequest {
+		return &optimizerpb.SolveVRPRequest{
+			Config:  &optimizerpb.VRPConfig{RandomSeed: proto.Int64(seed)},
+			Problem: &optimizerpb.VRPProblem{Description: &optimizerpb.VRPDescription{ShiftTeams: shiftTeams}},
+		}
+	}
+	feasibleReq, otherFeasibleReq, infeasibleReq := req(1), req(2), req(3)
+	mockVRPSolver := &MockVRPSolverForRequest{recvByReq: map[*optimizerpb.SolveVRPRequest]*optimizerpb.SolveVRPResponse{
+		feasibleReq: {Solution: &optimizerpb.VRPSolution{Score: &optimizerpb.VRPScore{
+			IsValid: proto.Bool(true), HardScore: proto.Int64(0), SoftScore: proto.Int64(-10),
+		}}},
+		otherFeasibleReq: {Solution: &optimizerpb.VRPSolution{Score: &optimizerpb.VRPScore{
+			IsValid: proto.Bool(true), HardScore: proto.Int64(0), SoftScore: proto.Int64(-5),
+		}}},
+		infeasibleReq: {Solution: &optimizerpb.VRPSolution{Score: &optimizerpb.VRPScore{
+			IsValid: proto.Bool(true), HardScore: proto.Int64(100), SoftScore: proto.Int64(-1),
+		}}},
+	}}
+
+	tree := NewFeasibilityTree(
+		logisticspb.CheckFeasibilityResponse_STATUS_UNSPECIFIED,
+		NewFeasibilityLeaf(&SolveVRPInput{VRPRequest: infeasibleReq}),
+		NewFeasibilityTree(
+			logisticspb.CheckFeasibilityResponse_STATUS_MARKET_PARTIALLY_FEASIBLE_LOCATION_LIMITED,
+			NewFeasibilityLeaf(&SolveVRPInput{VRPRequest: feasibleReq}),
+			NewFeasibilityLeaf(&SolveVRPInput{VRPRequest: otherFeasibleReq}),
+		),
+	)
+
+	results, err := tree.EvaluateChildren(context.Background(), mockVRPSolver, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testutils.MustMatch(t, 2, len(results))
+	testutils.MustMatch(t, logisticspb.CheckFeasibilityResponse_STATUS_INFEASIBLE, results[0].Status)
+	testutils.MustMatch(t, int64(-1), results[0].SoftScore)
+	testutils.MustMatch(t, logisticspb.CheckFeasibilityResponse_STATUS_FEASIBLE, results[1].Status)
+	testutils.MustMatch(t, int64(-15), results[1].SoftScore, "soft scores of leaves should be summed")
+}
+
 func Test_isFeasibleScore(t *testing.T) {
 	tcs := []struct {
 		Desc           string
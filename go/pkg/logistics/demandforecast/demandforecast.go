@@ -0,0 +1,127 @@
+package demandforecast
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+)
+
+const daysPerWeek = 7
+
+// Params are the inputs to forecast the demand of a service region.
+type Params struct {
+	// History of the demand recorded during the HistoryWeeks weeks before Start.
+	History      []*logisticsdb.DemandHour
+	HistoryWeeks int
+
+	// Start of the first forecast day, at midnight in Location.
+	Start    time.Time
+	Days     int
+	Location *time.Location
+}
+
+// Hour is the expected demand for visits with a set of required attributes during an hour.
+type Hour struct {
+	// Hour is the start of the hour.
+	Hour time.Time
+	// AttributeNames are the sorted names of the required attributes of the demand.
+	AttributeNames []string
+
+	AvailabilityQueries float64
+	FeasibilityQueries  float64
+	// FailureRate is the ratio of availability and feasibility queries that were not fully available or feasible.
+	FailureRate     float64
+	CompletedVisits float64
+}
+
+// seasonalKey identifies demand that recurs weekly, in local time.
+type seasonalKey struct {
+	weekday       time.Weekday
+	hour          int
+	attributesKey string
+}
+
+type seasonalDemand struct {
+	attributeNames []string
+
+	availabilityQueries       int64
+	failedAvailabilityQueries int64
+	feasibilityQueries        int64
+	failedFeasibilityQueries  int64
+	completedVisits           int64
+}
+
+func (d *seasonalDemand) failureRate() float64 {
+	queries := d.availabilityQueries + d.feasibilityQueries
+	if queries == 0 {
+		return 0
+	}
+	return float64(d.failedAvailabilityQueries+d.failedFeasibilityQueries) / float64(queries)
+}
+
+// Forecast returns the hourly demand expected for the forecast days, as the average demand recorded
+// on the same weekday and local hour over the history weeks.
+//
+// Hours without any recorded demand are omitted. Forecast hours are sorted by hour, then by attributes.
+func Forecast(params Params) []*Hour {
+	if params.HistoryWeeks <= 0 || params.Days <= 0 {
+		return nil
+	}
+
+	seasonal := map[seasonalKey]*seasonalDemand{}
+	weekdayKeys := map[time.Weekday][]seasonalKey{}
+	for _, demand := range params.History {
+		local := demand.Hour.In(params.Location)
+		key := seasonalKey{
+			weekday:       local.Weekday(),
+			hour:          local.Hour(),
+			attributesKey: demand.AttributesKey(),
+		}
+		s, ok := seasonal[key]
+		if !ok {
+			s = &seasonalDemand{attributeNames: demand.AttributeNames}
+			seasonal[key] = s
+			weekdayKeys[key.weekday] = append(weekdayKeys[key.weekday], key)
+		}
+		s.availabilityQueries += demand.AvailabilityQueries
+		s.failedAvailabilityQueries += demand.FailedAvailabilityQueries
+		s.feasibilityQueries += demand.FeasibilityQueries
+		s.failedFeasibilityQueries += demand.FailedFeasibilityQueries
+		s.completedVisits += demand.CompletedVisits
+	}
+
+	weeks := float64(params.HistoryWeeks)
+	var res []*Hour
+	for day := 0; day < params.Days; day++ {
+		date := params.Start.In(params.Location).AddDate(0, 0, day)
+		for _, key := range weekdayKeys[date.Weekday()] {
+			s := seasonal[key]
+			res = append(res, &Hour{
+				Hour:                time.Date(date.Year(), date.Month(), date.Day(), key.hour, 0, 0, 0, params.Location),
+				AttributeNames:      s.attributeNames,
+				AvailabilityQueries: float64(s.availabilityQueries) / weeks,
+				FeasibilityQueries:  float64(s.feasibilityQueries) / weeks,
+				FailureRate:         s.failureRate(),
+				CompletedVisits:     float64(s.completedVisits) / weeks,
+			})
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if !res[i].Hour.Equal(res[j].Hour) {
+			return res[i].Hour.Before(res[j].Hour)
+		}
+		return strings.Join(res[i].AttributeNames, ",") < strings.Join(res[j].AttributeNames, ",")
+	})
+	return res
+}
+
+// HistoryWindow returns the time window of the history weeks before the start of the forecast.
+func HistoryWindow(start time.Time, historyWeeks int) logisticsdb.TimeWindow {
+	return logisticsdb.TimeWindow{
+		Start: start.AddDate(0, 0, -daysPerWeek*historyWeeks),
+		End:   start,
+	}
+}
@@ -0,0 +1,117 @@
+package demandforecast
+
+import (
+	"testing"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+)
+
+func TestForecast(t *testing.T) {
+	loc, err := time.LoadLocation("America/Denver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Saturday.
+	start := time.Date(2023, time.September, 16, 0, 0, 0, 0, loc)
+	lastSaturday := start.AddDate(0, 0, -7)
+	lastSunday := start.AddDate(0, 0, -6)
+	twoSaturdaysAgo := start.AddDate(0, 0, -14)
+	history := []*logisticsdb.DemandHour{
+		{
+			Hour:                      twoSaturdaysAgo.Add(9 * time.Hour),
+			AvailabilityQueries:       4,
+			FailedAvailabilityQueries: 1,
+			CompletedVisits:           2,
+		},
+		{
+			Hour:                      lastSaturday.Add(9 * time.Hour),
+			AvailabilityQueries:       6,
+			FailedAvailabilityQueries: 1,
+			FeasibilityQueries:        2,
+			FailedFeasibilityQueries:  2,
+			CompletedVisits:           4,
+		},
+		{
+			Hour:               lastSaturday.Add(9 * time.Hour),
+			AttributeNames:     []string{"covid", "service_name:Acute"},
+			FeasibilityQueries: 2,
+		},
+		{
+			Hour:            lastSunday.Add(14 * time.Hour),
+			CompletedVisits: 1,
+		},
+	}
+
+	tcs := []struct {
+		Desc   string
+		Params Params
+
+		ExpectedHours []*Hour
+	}{
+		{
+			Desc: "base case",
+			Params: Params{
+				History:      history,
+				HistoryWeeks: 2,
+				Start:        start,
+				Days:         2,
+				Location:     loc,
+			},
+
+			ExpectedHours: []*Hour{
+				{
+					Hour:                start.Add(9 * time.Hour),
+					AvailabilityQueries: 5,
+					FeasibilityQueries:  1,
+					FailureRate:         0.3333333333333333,
+					CompletedVisits:     3,
+				},
+				{
+					Hour:               start.Add(9 * time.Hour),
+					AttributeNames:     []string{"covid", "service_name:Acute"},
+					FeasibilityQueries: 1,
+				},
+				{
+					Hour:            start.AddDate(0, 0, 1).Add(14 * time.Hour),
+					CompletedVisits: 0.5,
+				},
+			},
+		},
+		{
+			Desc: "day without history",
+			Params: Params{
+				History:      history,
+				HistoryWeeks: 2,
+				Start:        start.AddDate(0, 0, 2),
+				Days:         1,
+				Location:     loc,
+			},
+		},
+		{
+			Desc: "no history weeks",
+			Params: Params{
+				History:  history,
+				Start:    start,
+				Days:     2,
+				Location: loc,
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			testutils.MustMatch(t, tc.ExpectedHours, Forecast(tc.Params))
+		})
+	}
+}
+
+func TestHistoryWindow(t *testing.T) {
+	start := time.Date(2023, time.September, 16, 0, 0, 0, 0, time.UTC)
+
+	testutils.MustMatch(t, logisticsdb.TimeWindow{
+		Start: time.Date(2023, time.August, 19, 0, 0, 0, 0, time.UTC),
+		End:   start,
+	}, HistoryWindow(start, 4))
+}
@@ -74,9 +74,14 @@ L.tileLayer('https://{s}.tile.openstreetmap.org/{z}/{x}/{y}.png', {
 const mapLayer = L.layerGroup().addTo(map);
 const locationsLayer = L.layerGroup().addTo(mapLayer);
 const polylinesLayer = L.layerGroup().addTo(mapLayer);
+const blackoutZonesLayer = L.layerGroup().addTo(mapLayer);
+const playbackLayer = L.layerGroup().addTo(mapLayer);
 
 const urlParams = new URLSearchParams(window.location.search);
 const autoSolve = urlParams.get('auto_solve') != '0';
+const marketId = urlParams.get('market_id');
+const playbackOptimizerRunId = urlParams.get('playback_optimizer_run_id');
+const playbackScheduleId = urlParams.get('playback_schedule_id');
 
 const getBounds = () => {
   fetch(`/api/example-vrp-bounds${window.location.search}`)
@@ -87,6 +92,215 @@ const getBounds = () => {
     });
 };
 
+const formatTimestampSec = (timestampSec) =>
+  new Date(Number(timestampSec) * 1000).toLocaleString();
+
+const getBlackoutZones = () => {
+  blackoutZonesLayer.clearLayers();
+  if (!marketId) {
+    return;
+  }
+
+  fetch(`/api/blackout-zones?market_id=${marketId}`)
+    .then((resp) => resp.json())
+    .then(({ zones = [] }) => {
+      zones.forEach(
+        ({ id, name, vertices, start_timestamp_sec, end_timestamp_sec }) => {
+          L.polygon(vertices.map(fromServerLatLng), {
+            color: 'black',
+            fillColor: 'black',
+            fillOpacity: 0.3,
+            dashArray: '4',
+          })
+            .bindPopup(
+              prettify({
+                id,
+                name,
+                start: formatTimestampSec(start_timestamp_sec),
+                end: formatTimestampSec(end_timestamp_sec),
+              })
+            )
+            .addTo(blackoutZonesLayer);
+        }
+      );
+    });
+};
+
+const playbackStepSec = 60;
+const playbackTickMs = 100;
+const visitPhaseColors = {
+  requested: 'gray',
+  uncommitted: 'gray',
+  committed: 'cornflowerblue',
+  en_route: 'orange',
+  on_scene: 'crimson',
+  completed: 'forestgreen',
+  cancelled: 'black',
+};
+
+let playback = null;
+let playbackTimer = null;
+
+// Returns the last item of a time ordered list at or before the time.
+const latestAt = (items, timestampSec, itemTimestampSec) => {
+  let latest = null;
+  for (const item of items || []) {
+    if (itemTimestampSec(item) > timestampSec) {
+      break;
+    }
+    latest = item;
+  }
+  return latest;
+};
+
+const isoTimestampSec = (iso) => new Date(iso).getTime() / 1000;
+
+const displayPlayback = (timestampSec) => {
+  playbackLayer.clearLayers();
+  document.querySelector('#playback-time').textContent =
+    formatTimestampSec(timestampSec);
+
+  const schedule = latestAt(
+    playback.schedules,
+    timestampSec,
+    ({ created_at }) => isoTimestampSec(created_at)
+  );
+  if (schedule) {
+    Object.entries(schedule.routes).forEach(([shiftTeamId, stops]) => {
+      const latLngs = stops
+        .map(({ care_request_id }) => playback.visit_locations[care_request_id])
+        .filter((latLng) => latLng)
+        .map(fromServerLatLng);
+      L.polyline(latLngs, {
+        color: colorFromId(shiftTeamId),
+        weight: 2,
+        dashArray: '6',
+      }).addTo(playbackLayer);
+    });
+  }
+  document.querySelector('#playback-schedule').value = schedule
+    ? prettify({
+        schedule_id: schedule.schedule_id,
+        optimizer_run_id: schedule.optimizer_run_id,
+        created_at: schedule.created_at,
+        changes: schedule.changes,
+        unassigned_visits: schedule.unassigned_visits,
+      })
+    : '';
+
+  Object.entries(playback.visit_locations).forEach(
+    ([careRequestId, latLng]) => {
+      const phase = latestAt(
+        playback.visit_phases[careRequestId],
+        timestampSec,
+        ({ timestamp }) => isoTimestampSec(timestamp)
+      );
+      const phaseName = phase ? phase.phase : 'requested';
+      L.circleMarker(fromServerLatLng(latLng), {
+        color: visitPhaseColors[phaseName] || 'gray',
+        fillOpacity: 0.8,
+        radius: 7,
+      })
+        .bindPopup(prettify({ care_request_id: careRequestId, phase }))
+        .addTo(playbackLayer);
+    }
+  );
+
+  Object.entries(playback.shift_team_locations).forEach(
+    ([shiftTeamId, locations]) => {
+      const trail = (locations || []).filter(
+        ({ timestamp }) => isoTimestampSec(timestamp) <= timestampSec
+      );
+      if (trail.length == 0) {
+        return;
+      }
+      const latLngs = trail.map(fromServerLatLng);
+      L.polyline(latLngs, {
+        color: colorFromId(shiftTeamId),
+        weight: 4,
+      }).addTo(playbackLayer);
+      L.marker(latLngs[latLngs.length - 1], {
+        icon: L.IconMaterial.icon({
+          icon: 'local_shipping',
+          markerColor: colorFromId(shiftTeamId),
+          outlineColor: '#000',
+          outlineWidth: 1,
+          iconSize: [31, 42],
+        }),
+      })
+        .bindPopup(
+          prettify({
+            shift_team_id: shiftTeamId,
+            timestamp: trail[trail.length - 1].timestamp,
+          })
+        )
+        .addTo(playbackLayer);
+    }
+  );
+};
+
+const stopPlayback = () => {
+  clearInterval(playbackTimer);
+  playbackTimer = null;
+  document.querySelector('#play-playback-btn').textContent = 'Play';
+};
+
+const togglePlayback = () => {
+  if (playbackTimer) {
+    stopPlayback();
+    return;
+  }
+
+  const slider = document.querySelector('#playback-slider');
+  document.querySelector('#play-playback-btn').textContent = 'Pause';
+  playbackTimer = setInterval(() => {
+    const next = Number(slider.value) + playbackStepSec;
+    if (next > Number(slider.max)) {
+      stopPlayback();
+      return;
+    }
+    slider.value = next;
+    displayPlayback(next);
+  }, playbackTickMs);
+};
+
+const getPlayback = (idType, id) => {
+  stopPlayback();
+  locationsLayer.clearLayers();
+  polylinesLayer.clearLayers();
+  playbackLayer.clearLayers();
+  setDisabled(true, '#play-playback-btn', '#playback-slider');
+
+  fetch(`/api/route-playback?${idType}=${id}`)
+    .then((resp) => {
+      if (resp.status != 200) {
+        throw new Error(resp.statusText);
+      }
+      return resp.json();
+    })
+    .then((data) => {
+      playback = data;
+
+      const slider = document.querySelector('#playback-slider');
+      slider.min = data.open_hours_start_timestamp_sec;
+      slider.max = data.open_hours_end_timestamp_sec;
+      slider.step = playbackStepSec;
+      slider.value = data.open_hours_start_timestamp_sec;
+      setDisabled(false, '#play-playback-btn', '#playback-slider');
+
+      const latLngs = Object.values(data.visit_locations).map(
+        fromServerLatLng
+      );
+      if (latLngs.length > 0) {
+        map.fitBounds(latLngs);
+      }
+      displayPlayback(Number(slider.value));
+    })
+    .catch((e) => {
+      console.error(e);
+    });
+};
+
 let vrpData = {};
 let vrpResp = {};
 
@@ -208,6 +422,34 @@ const withVrpStatusResponseHandler = async (responsePromise) => {
 
 document.querySelector('#get-vrp-btn').addEventListener('click', getVrp);
 
+document.querySelector('#load-playback-btn').addEventListener('click', () => {
+  getPlayback(
+    document.querySelector('#playback-id-type').value,
+    document.querySelector('#playback-id-input').value
+  );
+});
+
+document
+  .querySelector('#play-playback-btn')
+  .addEventListener('click', togglePlayback);
+
+document
+  .querySelector('#playback-slider')
+  .addEventListener('input', ({ target }) => {
+    stopPlayback();
+    displayPlayback(Number(target.value));
+  });
+
+document
+  .querySelector('#blackout-zones-checkbox')
+  .addEventListener('change', ({ target }) => {
+    if (target.checked) {
+      blackoutZonesLayer.addTo(mapLayer);
+    } else {
+      blackoutZonesLayer.remove();
+    }
+  });
+
 document.querySelector('#run-solver-btn').addEventListener('click', () => {
   const data = JSON.parse(document.querySelector('#vrp-json-textarea').value);
 
@@ -216,4 +458,11 @@ document.querySelector('#run-solver-btn').addEventListener('click', () => {
 });
 
 getBounds();
-getVrp();
+getBlackoutZones();
+if (playbackOptimizerRunId) {
+  getPlayback('optimizer_run_id', playbackOptimizerRunId);
+} else if (playbackScheduleId) {
+  getPlayback('schedule_id', playbackScheduleId);
+} else {
+  getVrp();
+}
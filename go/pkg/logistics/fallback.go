@@ -0,0 +1,278 @@
+package logistics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/monitoring"
+)
+
+const (
+	mapServiceFallbackMeasurementName = "map_service_fallback"
+
+	primaryDistanceSourceIDTag = "primary_distance_source_id"
+	distanceSourceIDTag        = "distance_source_id"
+	fallbackTag                = "fallback"
+	skippedMapServicesField    = "skipped_map_services"
+)
+
+var (
+	// ErrMapServiceUnsupported is returned (wrapped) by calls that a MapService cannot answer by design,
+	// for example time dependent distances from a MapService that is not a TimeDependentMapService.
+	// FallbackMapService skips the MapService without counting it as a failure.
+	ErrMapServiceUnsupported = errors.New("map service does not support request")
+
+	errNoMapServiceAvailable = errors.New("no map service available")
+)
+
+// CircuitBreakerConfig configures when a CircuitBreaker trips.
+type CircuitBreakerConfig struct {
+	// Number of consecutive failures that opens the circuit.
+	FailureThreshold int
+	// Duration that the circuit stays open, before letting a single trial call through.
+	OpenDuration time.Duration
+}
+
+var DefaultCircuitBreakerConfig = CircuitBreakerConfig{
+	FailureThreshold: 5,
+	OpenDuration:     30 * time.Second,
+}
+
+// CircuitBreaker tracks consecutive failures of a MapService.
+// Safe for concurrent use.
+type CircuitBreaker struct {
+	cfg CircuitBreakerConfig
+	now func() time.Time
+
+	mu                  sync.Mutex
+	consecutiveFailures int
+	openUntil           time.Time
+	trialInFlight       bool
+}
+
+func NewCircuitBreaker(cfg CircuitBreakerConfig) *CircuitBreaker {
+	return &CircuitBreaker{
+		cfg: cfg,
+		now: time.Now,
+	}
+}
+
+// Allow returns whether a call should be attempted.
+// Once OpenDuration has passed on an open circuit, only a single trial call is allowed until it reports back.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.consecutiveFailures < b.cfg.FailureThreshold {
+		return true
+	}
+	if b.trialInFlight || b.now().Before(b.openUntil) {
+		return false
+	}
+
+	b.trialInFlight = true
+	return true
+}
+
+// Record reports the result of an allowed call.
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trialInFlight = false
+	if err == nil {
+		b.consecutiveFailures = 0
+		return
+	}
+
+	b.consecutiveFailures++
+	if b.consecutiveFailures >= b.cfg.FailureThreshold {
+		b.openUntil = b.now().Add(b.cfg.OpenDuration)
+	}
+}
+
+// Ignore reports that an allowed call finished without telling anything about the health of the map service,
+// for example when the context was canceled.
+func (b *CircuitBreaker) Ignore() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trialInFlight = false
+}
+
+// IsOpen returns whether the circuit is currently tripped.
+func (b *CircuitBreaker) IsOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.consecutiveFailures >= b.cfg.FailureThreshold
+}
+
+type fallbackMapServiceEntry struct {
+	mapService MapService
+	breaker    *CircuitBreaker
+}
+
+// FallbackMapService is a MapService that tries its map services in order,
+// falling back to the next one when a map service fails or its circuit breaker is open.
+// The first map service is the primary one, and gives the distance source ID.
+// Must be initialized with NewFallbackMapService.
+type FallbackMapService struct {
+	entries []fallbackMapServiceEntry
+	scope   monitoring.Scope
+}
+
+// NewFallbackMapService returns a FallbackMapService; breakers must have one CircuitBreaker per map service.
+func NewFallbackMapService(mapServices []MapService, breakers []*CircuitBreaker, scope monitoring.Scope) *FallbackMapService {
+	entries := make([]fallbackMapServiceEntry, len(mapServices))
+	for i, ms := range mapServices {
+		entries[i] = fallbackMapServiceEntry{
+			mapService: ms,
+			breaker:    breakers[i],
+		}
+	}
+
+	return &FallbackMapService{
+		entries: entries,
+		scope:   scope,
+	}
+}
+
+// MapServices returns the map services in fallback order.
+func (s *FallbackMapService) MapServices() []MapService {
+	mapServices := make([]MapService, len(s.entries))
+	for i, e := range s.entries {
+		mapServices[i] = e.mapService
+	}
+
+	return mapServices
+}
+
+// Primary returns the map service tried first.
+func (s *FallbackMapService) Primary() MapService {
+	return s.entries[0].mapService
+}
+
+// Do calls f with each map service in fallback order until one succeeds, and returns the map service that answered.
+// Context errors are returned right away, without falling back.
+func (s *FallbackMapService) Do(ctx context.Context, tags monitoring.Tags, f func(MapService) error) (MapService, error) {
+	var skipped int
+	var lastErr error
+	for i, e := range s.entries {
+		if !e.breaker.Allow() {
+			skipped++
+			continue
+		}
+
+		err := f(e.mapService)
+		switch {
+		case err == nil:
+			e.breaker.Record(nil)
+			if i > 0 || skipped > 0 {
+				s.writeFallbackPoint(tags, e.mapService, skipped, nil)
+			}
+			return e.mapService, nil
+
+		case errors.Is(err, ErrMapServiceUnsupported):
+			e.breaker.Ignore()
+			skipped++
+			lastErr = err
+
+		case ctx.Err() != nil:
+			e.breaker.Ignore()
+			return nil, err
+
+		default:
+			e.breaker.Record(err)
+			lastErr = err
+		}
+	}
+
+	err := fmt.Errorf("%w: primary_distance_source_id(%d), last error: %v", errNoMapServiceAvailable, s.GetDistanceSourceID(), lastErr)
+	s.writeFallbackPoint(tags, nil, skipped, err)
+	return nil, err
+}
+
+func (s *FallbackMapService) writeFallbackPoint(tags monitoring.Tags, answered MapService, skipped int, err error) {
+	pointTags := tags.Clone()
+	pointTags[primaryDistanceSourceIDTag] = strconv.FormatInt(s.GetDistanceSourceID(), 10)
+	pointTags[fallbackTag] = strconv.FormatBool(answered != s.Primary())
+	pointTags[statusTag] = statusTagSuccess
+
+	var errorValue string
+	if err != nil {
+		pointTags[statusTag] = statusTagError
+		errorValue = err.Error()
+	}
+	if answered != nil {
+		pointTags[distanceSourceIDTag] = strconv.FormatInt(answered.GetDistanceSourceID(), 10)
+	}
+
+	s.scope.WritePoint(mapServiceFallbackMeasurementName, pointTags, monitoring.Fields{
+		skippedMapServicesField: skipped,
+		errorField:              errorValue,
+	})
+}
+
+func (s *FallbackMapService) GetDistanceMatrix(ctx context.Context, tags monitoring.Tags, origins, destinations []LatLng) (DistanceMatrix, error) {
+	var m DistanceMatrix
+	_, err := s.Do(ctx, tags, func(ms MapService) error {
+		var err error
+		m, err = ms.GetDistanceMatrix(ctx, tags, origins, destinations)
+		return err
+	})
+
+	return m, err
+}
+
+func (s *FallbackMapService) GetPathDistanceMatrix(ctx context.Context, tags monitoring.Tags, path ...LatLng) (DistanceMatrix, error) {
+	var m DistanceMatrix
+	_, err := s.Do(ctx, tags, func(ms MapService) error {
+		var err error
+		m, err = ms.GetPathDistanceMatrix(ctx, tags, path...)
+		return err
+	})
+
+	return m, err
+}
+
+func (s *FallbackMapService) GetRoute(ctx context.Context, tags monitoring.Tags, latLngs ...LatLng) (*Route, error) {
+	var route *Route
+	_, err := s.Do(ctx, tags, func(ms MapService) error {
+		var err error
+		route, err = ms.GetRoute(ctx, tags, latLngs...)
+		return err
+	})
+
+	return route, err
+}
+
+// GetDistanceSourceID returns the distance source ID of the primary map service.
+// Use Do to know which map service answered a request.
+func (s *FallbackMapService) GetDistanceSourceID() int64 {
+	return s.Primary().GetDistanceSourceID()
+}
+
+// IsHealthy returns whether any of the map services is healthy.
+func (s *FallbackMapService) IsHealthy(ctx context.Context) bool {
+	for _, e := range s.entries {
+		if e.mapService.IsHealthy(ctx) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PrimaryMapService returns the primary map service of a FallbackMapService, or the map service itself otherwise.
+func PrimaryMapService(ms MapService) MapService {
+	if fallback, ok := ms.(*FallbackMapService); ok {
+		return fallback.Primary()
+	}
+
+	return ms
+}
@@ -0,0 +1,230 @@
+package logistics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/monitoring"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+)
+
+type mockFallbackMapService struct {
+	distanceSourceID int64
+	err              error
+	calls            int
+}
+
+func (m *mockFallbackMapService) GetDistanceMatrix(ctx context.Context, tags monitoring.Tags, origins, destinations []LatLng) (DistanceMatrix, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return DistanceMatrix{
+		origins[0]: {
+			destinations[0]: {LengthMeters: m.distanceSourceID},
+		},
+	}, nil
+}
+
+func (m *mockFallbackMapService) GetPathDistanceMatrix(ctx context.Context, tags monitoring.Tags, path ...LatLng) (DistanceMatrix, error) {
+	return m.GetDistanceMatrix(ctx, tags, path[:1], path[1:])
+}
+
+func (m *mockFallbackMapService) GetRoute(ctx context.Context, tags monitoring.Tags, latLngs ...LatLng) (*Route, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &Route{Distance: Distance{LengthMeters: m.distanceSourceID}}, nil
+}
+
+func (m *mockFallbackMapService) GetDistanceSourceID() int64 {
+	return m.distanceSourceID
+}
+
+func (m *mockFallbackMapService) IsHealthy(context.Context) bool {
+	return m.err == nil
+}
+
+func newTestFallbackMapService(scope monitoring.Scope, cfg CircuitBreakerConfig, mapServices ...MapService) *FallbackMapService {
+	breakers := make([]*CircuitBreaker, len(mapServices))
+	for i := range mapServices {
+		breakers[i] = NewCircuitBreaker(cfg)
+	}
+
+	return NewFallbackMapService(mapServices, breakers, scope)
+}
+
+func TestFallbackMapService_GetDistanceMatrix(t *testing.T) {
+	ll1 := NewLatLng(1, 2)
+	ll2 := NewLatLng(3, 4)
+
+	tcs := []struct {
+		Desc        string
+		PrimaryErr  error
+		FallbackErr error
+
+		Want             DistanceMatrix
+		WantPointWritten bool
+		HasErr           bool
+	}{
+		{
+			Desc: "primary answers",
+
+			Want: DistanceMatrix{ll1: {ll2: {LengthMeters: 1}}},
+		},
+		{
+			Desc:       "fallback answers when primary fails",
+			PrimaryErr: errors.New("primary down"),
+
+			Want:             DistanceMatrix{ll1: {ll2: {LengthMeters: 2}}},
+			WantPointWritten: true,
+		},
+		{
+			Desc:        "all map services fail",
+			PrimaryErr:  errors.New("primary down"),
+			FallbackErr: errors.New("fallback down"),
+
+			WantPointWritten: true,
+			HasErr:           true,
+		},
+		{
+			Desc:       "unsupported map service is skipped",
+			PrimaryErr: fmt.Errorf("%w: departure times", ErrMapServiceUnsupported),
+
+			Want:             DistanceMatrix{ll1: {ll2: {LengthMeters: 2}}},
+			WantPointWritten: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			scope := &MockMonitoringScope{}
+			s := newTestFallbackMapService(scope, DefaultCircuitBreakerConfig,
+				&mockFallbackMapService{distanceSourceID: 1, err: tc.PrimaryErr},
+				&mockFallbackMapService{distanceSourceID: 2, err: tc.FallbackErr},
+			)
+
+			m, err := s.GetDistanceMatrix(context.Background(), nil, []LatLng{ll1}, []LatLng{ll2})
+			if (err != nil) != tc.HasErr {
+				t.Fatal(err)
+			}
+			testutils.MustMatch(t, tc.Want, m)
+			testutils.MustMatch(t, tc.WantPointWritten, scope.MeasurementName == mapServiceFallbackMeasurementName)
+		})
+	}
+}
+
+func TestFallbackMapService_Do(t *testing.T) {
+	primary := &mockFallbackMapService{distanceSourceID: 1}
+	fallback := &mockFallbackMapService{distanceSourceID: 2}
+	s := newTestFallbackMapService(&monitoring.NoopScope{}, DefaultCircuitBreakerConfig, primary, fallback)
+
+	answered, err := s.Do(context.Background(), nil, func(ms MapService) error {
+		if ms == primary {
+			return errors.New("primary down")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testutils.MustMatch(t, int64(2), answered.GetDistanceSourceID())
+	testutils.MustMatch(t, int64(1), s.GetDistanceSourceID())
+}
+
+func TestFallbackMapService_ContextCanceled(t *testing.T) {
+	primary := &mockFallbackMapService{distanceSourceID: 1}
+	fallback := &mockFallbackMapService{distanceSourceID: 2}
+	s := newTestFallbackMapService(&monitoring.NoopScope{}, CircuitBreakerConfig{FailureThreshold: 1, OpenDuration: time.Minute}, primary, fallback)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := s.Do(ctx, nil, func(ms MapService) error {
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context canceled, got: %v", err)
+	}
+
+	testutils.MustMatch(t, false, s.entries[0].breaker.IsOpen())
+}
+
+func TestFallbackMapService_CircuitBreaker(t *testing.T) {
+	ll1 := NewLatLng(1, 2)
+	ll2 := NewLatLng(3, 4)
+	now := time.Date(2023, time.September, 5, 8, 0, 0, 0, time.UTC)
+	cfg := CircuitBreakerConfig{
+		FailureThreshold: 2,
+		OpenDuration:     time.Minute,
+	}
+
+	primary := &mockFallbackMapService{distanceSourceID: 1, err: errors.New("primary down")}
+	fallback := &mockFallbackMapService{distanceSourceID: 2}
+	s := newTestFallbackMapService(&monitoring.NoopScope{}, cfg, primary, fallback)
+	for _, e := range s.entries {
+		e.breaker.now = func() time.Time { return now }
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		route, err := s.GetRoute(ctx, nil, ll1, ll2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testutils.MustMatch(t, int64(2), route.Distance.LengthMeters)
+	}
+	testutils.MustMatch(t, cfg.FailureThreshold, primary.calls, "primary is skipped once the circuit is open")
+
+	now = now.Add(cfg.OpenDuration)
+	primary.err = nil
+	route, err := s.GetRoute(ctx, nil, ll1, ll2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutils.MustMatch(t, int64(1), route.Distance.LengthMeters, "primary is tried again after the open duration")
+	testutils.MustMatch(t, false, s.entries[0].breaker.IsOpen())
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2023, time.September, 5, 8, 0, 0, 0, time.UTC)
+	b := NewCircuitBreaker(CircuitBreakerConfig{
+		FailureThreshold: 2,
+		OpenDuration:     time.Minute,
+	})
+	b.now = func() time.Time { return now }
+
+	testutils.MustMatch(t, true, b.Allow())
+	b.Record(errors.New("bad"))
+	testutils.MustMatch(t, true, b.Allow())
+	b.Record(errors.New("bad"))
+	testutils.MustMatch(t, true, b.IsOpen())
+	testutils.MustMatch(t, false, b.Allow())
+
+	now = now.Add(time.Minute)
+	testutils.MustMatch(t, true, b.Allow(), "trial call after open duration")
+	testutils.MustMatch(t, false, b.Allow(), "only one trial call at a time")
+
+	b.Record(errors.New("still bad"))
+	testutils.MustMatch(t, false, b.Allow(), "failed trial reopens the circuit")
+
+	now = now.Add(time.Minute)
+	testutils.MustMatch(t, true, b.Allow())
+	b.Record(nil)
+	testutils.MustMatch(t, false, b.IsOpen())
+	testutils.MustMatch(t, true, b.Allow())
+}
+
+func TestPrimaryMapService(t *testing.T) {
+	primary := &mockFallbackMapService{distanceSourceID: 1}
+	fallback := &mockFallbackMapService{distanceSourceID: 2}
+
+	testutils.MustMatch(t, MapService(primary), PrimaryMapService(primary))
+	testutils.MustMatch(t, MapService(primary), PrimaryMapService(newTestFallbackMapService(&monitoring.NoopScope{}, DefaultCircuitBreakerConfig, primary, fallback)))
+}
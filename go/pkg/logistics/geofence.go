@@ -0,0 +1,96 @@
+package logistics
+
+import (
+	"math"
+	"time"
+)
+
+const earthRadiusMeters = 6371008.8
+
+// HaversineDistanceMeters returns the great-circle distance between two points.
+func HaversineDistanceMeters(a, b LatLng) float64 {
+	lat1 := a.Latitude() * math.Pi / 180
+	lat2 := b.Latitude() * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := (b.Longitude() - a.Longitude()) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// TimedLatLng is a LatLng reported at a given time.
+type TimedLatLng struct {
+	LatLng
+	Timestamp time.Time
+}
+
+// Geofence is a circular area around a location.
+type Geofence struct {
+	Center       LatLng
+	RadiusMeters float64
+}
+
+func (g Geofence) Contains(ll LatLng) bool {
+	return HaversineDistanceMeters(g.Center, ll) <= g.RadiusMeters
+}
+
+// GeofenceDwell is a stay inside a Geofence.
+type GeofenceDwell struct {
+	// Arrival is the time of the first location inside the geofence.
+	Arrival time.Time
+	// Departure is the time of the first location outside the geofence after the stay.
+	// Zero if the last known location is still inside.
+	Departure time.Time
+}
+
+// FirstGeofenceDwell returns the first stay of at least minDwell inside the geofence,
+// from locations sorted by time, or nil if there is none yet.
+func FirstGeofenceDwell(locations []TimedLatLng, geofence Geofence, minDwell time.Duration) *GeofenceDwell {
+	var dwell *GeofenceDwell
+	var lastInside time.Time
+	for _, loc := range locations {
+		inside := geofence.Contains(loc.LatLng)
+		switch {
+		case inside && dwell == nil:
+			dwell = &GeofenceDwell{Arrival: loc.Timestamp}
+			lastInside = loc.Timestamp
+		case inside:
+			lastInside = loc.Timestamp
+		case dwell != nil:
+			if lastInside.Sub(dwell.Arrival) >= minDwell {
+				dwell.Departure = loc.Timestamp
+				return dwell
+			}
+			dwell = nil
+		}
+	}
+
+	if dwell != nil && lastInside.Sub(dwell.Arrival) >= minDwell {
+		return dwell
+	}
+	return nil
+}
+
+// Polygon is an area bounded by vertices, in order, with the last vertex connected to the first.
+type Polygon []LatLng
+
+// Contains returns whether the location is inside the polygon, using ray casting.
+//
+// Edges are treated as straight lines in latitude/longitude, which is accurate enough for city scale areas.
+func (p Polygon) Contains(ll LatLng) bool {
+	if len(p) < 3 {
+		return false
+	}
+
+	inside := false
+	lat, lng := ll.Latitude(), ll.Longitude()
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		latI, lngI := p[i].Latitude(), p[i].Longitude()
+		latJ, lngJ := p[j].Latitude(), p[j].Longitude()
+		if (latI > lat) != (latJ > lat) &&
+			lng < (lngJ-lngI)*(lat-latI)/(latJ-latI)+lngI {
+			inside = !inside
+		}
+	}
+	return inside
+}
@@ -0,0 +1,185 @@
+package logistics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+)
+
+func TestHaversineDistanceMeters(t *testing.T) {
+	tcs := []struct {
+		Desc string
+		A    LatLng
+		B    LatLng
+
+		Want float64
+	}{
+		{
+			Desc: "same point",
+			A:    NewLatLng(40, -105),
+			B:    NewLatLng(40, -105),
+
+			Want: 0,
+		},
+		{
+			Desc: "one degree of latitude",
+			A:    NewLatLng(40, -105),
+			B:    NewLatLng(41, -105),
+
+			Want: 111195,
+		},
+		{
+			Desc: "Denver to Boulder",
+			A:    NewLatLng(39.7392, -104.9903),
+			B:    NewLatLng(40.0150, -105.2705),
+
+			Want: 38887,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			testutils.MustMatch(t, tc.Want, math.Round(HaversineDistanceMeters(tc.A, tc.B)))
+		})
+	}
+}
+
+func TestFirstGeofenceDwell(t *testing.T) {
+	start := time.Date(2023, time.September, 11, 10, 0, 0, 0, time.UTC)
+	geofence := Geofence{
+		Center:       NewLatLng(40, -105),
+		RadiusMeters: 100,
+	}
+	inside := NewLatLng(40.0005, -105)
+	outside := NewLatLng(40.01, -105)
+	at := func(ll LatLng, minutes int) TimedLatLng {
+		return TimedLatLng{LatLng: ll, Timestamp: start.Add(time.Duration(minutes) * time.Minute)}
+	}
+
+	tcs := []struct {
+		Desc      string
+		Locations []TimedLatLng
+
+		Want *GeofenceDwell
+	}{
+		{
+			Desc: "no locations",
+		},
+		{
+			Desc: "never inside",
+			Locations: []TimedLatLng{
+				at(outside, 0),
+				at(outside, 5),
+			},
+		},
+		{
+			Desc: "still inside, dwell too short",
+			Locations: []TimedLatLng{
+				at(outside, 0),
+				at(inside, 1),
+				at(inside, 3),
+			},
+		},
+		{
+			Desc: "still inside",
+			Locations: []TimedLatLng{
+				at(outside, 0),
+				at(inside, 1),
+				at(inside, 7),
+			},
+
+			Want: &GeofenceDwell{Arrival: start.Add(1 * time.Minute)},
+		},
+		{
+			Desc: "departed",
+			Locations: []TimedLatLng{
+				at(outside, 0),
+				at(inside, 1),
+				at(inside, 30),
+				at(outside, 31),
+				at(inside, 40),
+			},
+
+			Want: &GeofenceDwell{
+				Arrival:   start.Add(1 * time.Minute),
+				Departure: start.Add(31 * time.Minute),
+			},
+		},
+		{
+			Desc: "passing by is ignored",
+			Locations: []TimedLatLng{
+				at(inside, 0),
+				at(outside, 1),
+				at(inside, 10),
+				at(inside, 20),
+			},
+
+			Want: &GeofenceDwell{Arrival: start.Add(10 * time.Minute)},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			testutils.MustMatch(t, tc.Want, FirstGeofenceDwell(tc.Locations, geofence, 5*time.Minute))
+		})
+	}
+}
+
+func TestPolygonContains(t *testing.T) {
+	square := Polygon{
+		NewLatLng(40, -105),
+		NewLatLng(40, -104),
+		NewLatLng(41, -104),
+		NewLatLng(41, -105),
+	}
+	tcs := []struct {
+		Desc    string
+		Polygon Polygon
+		LatLng  LatLng
+
+		Want bool
+	}{
+		{
+			Desc:    "inside",
+			Polygon: square,
+			LatLng:  NewLatLng(40.5, -104.5),
+
+			Want: true,
+		},
+		{
+			Desc:    "outside",
+			Polygon: square,
+			LatLng:  NewLatLng(41.5, -104.5),
+
+			Want: false,
+		},
+		{
+			Desc: "inside concave notch",
+			Polygon: Polygon{
+				NewLatLng(40, -105),
+				NewLatLng(40, -104),
+				NewLatLng(41, -104),
+				NewLatLng(40.5, -104.5),
+				NewLatLng(41, -105),
+			},
+			LatLng: NewLatLng(40.9, -104.5),
+
+			Want: false,
+		},
+		{
+			Desc:    "not enough vertices",
+			Polygon: Polygon{NewLatLng(40, -105), NewLatLng(41, -104)},
+			LatLng:  NewLatLng(40.5, -104.5),
+
+			Want: false,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			testutils.MustMatch(t, tc.Want, tc.Polygon.Contains(tc.LatLng))
+		})
+	}
+}
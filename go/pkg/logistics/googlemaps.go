@@ -68,6 +68,9 @@ type PathDistanceMatrixRequest struct {
 
 	RouteModifiers    *routingpb.RouteModifiers
 	RoutingPreference routingpb.RoutingPreference
+
+	// DepartureTime for traffic aware requests; nil departs now.
+	DepartureTime *time.Time
 }
 
 func (r *PathDistanceMatrixRequest) ComputeRoutesRequest() *routingpb.ComputeRoutesRequest {
@@ -80,6 +83,7 @@ func (r *PathDistanceMatrixRequest) ComputeRoutesRequest() *routingpb.ComputeRou
 		RouteModifiers:           r.RouteModifiers,
 		RoutingPreference:        r.RoutingPreference,
 		Units:                    routingpb.Units_METRIC,
+		DepartureTime:            departureTimestamp(r.DepartureTime),
 	}
 }
 
@@ -425,12 +429,24 @@ func (s *GoogleMapsService) PathDistanceMatrixReqs(latLngs ...LatLng) []*PathDis
 }
 
 func (s *GoogleMapsService) GetPathDistanceMatrix(ctx context.Context, mapsTags monitoring.Tags, latLngs ...LatLng) (DistanceMatrix, error) {
+	return s.getPathDistanceMatrix(ctx, mapsTags, nil, latLngs...)
+}
+
+// GetPathDistanceMatrixAtTime returns the traffic aware path distances when departing at departureTime.
+func (s *GoogleMapsService) GetPathDistanceMatrixAtTime(ctx context.Context, mapsTags monitoring.Tags, departureTime time.Time, latLngs ...LatLng) (DistanceMatrix, error) {
+	return s.getPathDistanceMatrix(ctx, mapsTags, &departureTime, latLngs...)
+}
+
+func (s *GoogleMapsService) getPathDistanceMatrix(ctx context.Context, mapsTags monitoring.Tags, departureTime *time.Time, latLngs ...LatLng) (DistanceMatrix, error) {
 	startTime := time.Now()
 
 	reqs := s.PathDistanceMatrixReqs(latLngs...)
 	if len(reqs) == 0 {
 		return DistanceMatrix{}, nil
 	}
+	for _, req := range reqs {
+		req.DepartureTime = departureTime
+	}
 	respDMs := make([]DistanceMatrix, len(reqs))
 
 	var returnErr error
@@ -474,8 +490,20 @@ func (s *GoogleMapsService) GetPathDistanceMatrix(ctx context.Context, mapsTags
 }
 
 func (s *GoogleMapsService) GetDistanceMatrix(ctx context.Context, mapsTags monitoring.Tags, origins, destinations []LatLng) (DistanceMatrix, error) {
+	return s.getDistanceMatrix(ctx, mapsTags, nil, origins, destinations)
+}
+
+// GetDistanceMatrixAtTime returns the traffic aware distance matrix when departing at departureTime.
+func (s *GoogleMapsService) GetDistanceMatrixAtTime(ctx context.Context, mapsTags monitoring.Tags, departureTime time.Time, origins, destinations []LatLng) (DistanceMatrix, error) {
+	return s.getDistanceMatrix(ctx, mapsTags, &departureTime, origins, destinations)
+}
+
+func (s *GoogleMapsService) getDistanceMatrix(ctx context.Context, mapsTags monitoring.Tags, departureTime *time.Time, origins, destinations []LatLng) (DistanceMatrix, error) {
 	startTime := time.Now()
 	reqs := s.DistanceMatrixRequests(origins, destinations)
+	for _, req := range reqs {
+		req.Req.DepartureTime = departureTime
+	}
 	resps := make([]*TiledResponse, len(reqs))
 
 	var returnErr error
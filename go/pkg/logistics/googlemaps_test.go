@@ -113,9 +113,12 @@ func TestGoogleMapsService_GetDistanceMatrix(t *testing.T) {
 
 type mockPathDistanceMatrixClient struct {
 	matrix DistanceMatrix
+
+	departureTime *time.Time
 }
 
 func (c *mockPathDistanceMatrixClient) PathDistanceMatrix(ctx context.Context, req *PathDistanceMatrixRequest) (DistanceMatrix, error) {
+	c.departureTime = req.DepartureTime
 	return c.matrix, nil
 }
 
@@ -150,6 +153,40 @@ func TestGoogleMapsService_GetPathDistanceMatrix(t *testing.T) {
 	testutils.MustMatch(t, matrix, resp)
 }
 
+func TestGoogleMapsService_GetPathDistanceMatrixAtTime(t *testing.T) {
+	influxRecorder, influxServer, err := newTestInfluxRecorder(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer influxServer.Close()
+
+	ll1, ll2 := LatLng{1, 1}, LatLng{2, 2}
+	lls := []LatLng{ll1, ll2}
+	departureTime := time.Date(2023, 9, 5, 8, 0, 0, 0, time.UTC)
+
+	matrix := GenDistanceMatrix([]LatLng{ll1}, []LatLng{ll2})
+	client := &mockPathDistanceMatrixClient{
+		matrix: matrix,
+	}
+
+	s := GoogleMapsService{
+		Limits: GoogleMapsLimits{
+			maxRouteWaypoints: 2,
+		},
+
+		RouteThrottler:           NewThrottler(0, 0, GoogleMapsLimits{}, RouteThrottlerErrors),
+		PathDistanceMatrixClient: client,
+		ScopedMetrics:            influxRecorder.With("test", make(monitoring.Tags), make(monitoring.Fields)),
+	}
+	resp, err := s.GetPathDistanceMatrixAtTime(context.Background(), monitoring.Tags{}, departureTime, lls...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testutils.MustMatch(t, matrix, resp)
+	testutils.MustMatch(t, &departureTime, client.departureTime)
+}
+
 func TestGoogleMapsService_PathDistanceMatrixReqs(t *testing.T) {
 	ll1, ll2, ll3, ll4 := LatLng{1, 2}, LatLng{3, 4}, LatLng{5, 6}, LatLng{7, 8}
 	tcs := []struct {
@@ -0,0 +1,104 @@
+package logisticsdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/jackc/pgtype"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var (
+	assignmentExplanationMarshaller = protojson.MarshalOptions{
+		UseProtoNames: true,
+	}
+	assignmentExplanationUnmarshaller = protojson.UnmarshalOptions{}
+)
+
+// AssignmentRecommendation is a recommendation of a visit for a shift team shown to a dispatcher.
+type AssignmentRecommendation struct {
+	CareRequestID int64
+	ShiftTeamID   int64
+	Explanation   *logisticspb.AssignmentExplanation
+
+	// Fields set when read back.
+	RankingPolicy logisticspb.AssignmentRankingPolicy
+	Rank          int32
+	CreatedAt     time.Time
+}
+
+type AddAssignmentRecommendationsParams struct {
+	RankingPolicy logisticspb.AssignmentRankingPolicy
+	// Recommendations in the order shown.
+	Recommendations []*AssignmentRecommendation
+}
+
+// AddAssignmentRecommendations logs recommendations shown to a dispatcher, to measure how often they are followed.
+func (ldb *LogisticsDB) AddAssignmentRecommendations(ctx context.Context, params AddAssignmentRecommendationsParams) error {
+	numRecommendations := len(params.Recommendations)
+	if numRecommendations == 0 {
+		return nil
+	}
+
+	careRequestIDs := make([]int64, numRecommendations)
+	shiftTeamIDs := make([]int64, numRecommendations)
+	ranks := make([]int32, numRecommendations)
+	explanations := make([]string, numRecommendations)
+	for i, recommendation := range params.Recommendations {
+		buf, err := assignmentExplanationMarshaller.Marshal(recommendation.Explanation)
+		if err != nil {
+			return err
+		}
+
+		careRequestIDs[i] = recommendation.CareRequestID
+		shiftTeamIDs[i] = recommendation.ShiftTeamID
+		ranks[i] = int32(i)
+		explanations[i] = string(buf)
+	}
+
+	_, err := ldb.queries.AddAssignmentRecommendations(ctx, logisticssql.AddAssignmentRecommendationsParams{
+		CareRequestIds: careRequestIDs,
+		ShiftTeamIds:   shiftTeamIDs,
+		RankingPolicy:  params.RankingPolicy.String(),
+		Ranks:          ranks,
+		Explanations:   explanations,
+	})
+	if err != nil {
+		return fmt.Errorf("error in AddAssignmentRecommendations: %w", err)
+	}
+
+	return nil
+}
+
+// GetAssignmentRecommendationsForCareRequest returns the recommendations shown for a care request, latest first.
+func (ldb *LogisticsDB) GetAssignmentRecommendationsForCareRequest(ctx context.Context, careRequestID int64) ([]*AssignmentRecommendation, error) {
+	rows, err := ldb.queries.GetAssignmentRecommendationsForCareRequest(ctx, careRequestID)
+	if err != nil {
+		return nil, fmt.Errorf("error in GetAssignmentRecommendationsForCareRequest: %w", err)
+	}
+
+	recommendations := make([]*AssignmentRecommendation, len(rows))
+	for i, row := range rows {
+		explanation := &logisticspb.AssignmentExplanation{}
+		if row.Explanation.Status == pgtype.Present {
+			err = assignmentExplanationUnmarshaller.Unmarshal(row.Explanation.Bytes, explanation)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		recommendations[i] = &AssignmentRecommendation{
+			CareRequestID: row.CareRequestID,
+			ShiftTeamID:   row.ShiftTeamID,
+			Explanation:   explanation,
+			RankingPolicy: logisticspb.AssignmentRankingPolicy(logisticspb.AssignmentRankingPolicy_value[row.RankingPolicy]),
+			Rank:          row.Rank,
+			CreatedAt:     row.CreatedAt,
+		}
+	}
+
+	return recommendations, nil
+}
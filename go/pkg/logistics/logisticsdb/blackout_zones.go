@@ -0,0 +1,332 @@
+package logisticsdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	commonpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/common"
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/collections"
+	"github.com/*company-data-covered*/services/go/pkg/sqltypes"
+	"github.com/jackc/pgx/v4"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	ErrBlackoutZoneNotFound       = errors.New("blackout zone not found")
+	ErrInvalidBlackoutZonePolygon = errors.New("blackout zone requires at least 3 vertices")
+	ErrInvalidBlackoutZoneWindow  = errors.New("blackout zone must start before it ends")
+)
+
+// BlackoutZone is an area of a service region closed to visits for a time window,
+// such as for weather or safety closures.
+type BlackoutZone struct {
+	ID              int64
+	ServiceRegionID int64
+	Name            string
+	Polygon         logistics.Polygon
+	TimeWindow      TimeWindow
+}
+
+func newBlackoutZone(row *logisticssql.BlackoutZone) *BlackoutZone {
+	polygon := make(logistics.Polygon, len(row.LatitudesE6))
+	for i := range row.LatitudesE6 {
+		polygon[i] = logistics.LatLng{LatE6: row.LatitudesE6[i], LngE6: row.LongitudesE6[i]}
+	}
+
+	return &BlackoutZone{
+		ID:              row.ID,
+		ServiceRegionID: row.ServiceRegionID,
+		Name:            row.Name,
+		Polygon:         polygon,
+		TimeWindow: TimeWindow{
+			Start: time.Unix(row.StartTimestampSec, 0),
+			End:   time.Unix(row.EndTimestampSec, 0),
+		},
+	}
+}
+
+// isActive returns whether the zone is active at some point of the time window.
+func (z *BlackoutZone) isActive(tw TimeWindow) bool {
+	return z.TimeWindow.Start.Before(tw.End) && z.TimeWindow.End.After(tw.Start)
+}
+
+// ToProto returns the proto of the zone.
+func (z *BlackoutZone) ToProto() *logisticspb.BlackoutZone {
+	vertices := make([]*commonpb.Location, len(z.Polygon))
+	for i, ll := range z.Polygon {
+		vertices[i] = &commonpb.Location{LatitudeE6: ll.LatE6, LongitudeE6: ll.LngE6}
+	}
+
+	return &logisticspb.BlackoutZone{
+		Id:                proto.Int64(z.ID),
+		Name:              proto.String(z.Name),
+		Vertices:          vertices,
+		StartTimestampSec: proto.Int64(z.TimeWindow.Start.Unix()),
+		EndTimestampSec:   proto.Int64(z.TimeWindow.End.Unix()),
+	}
+}
+
+// BlackoutZones are the blackout zones of a service region.
+type BlackoutZones []*BlackoutZone
+
+// AvailableTimeWindow returns the longest part of the time window during which no zone containing the location is active,
+// so the time window itself if no zone applies.
+// If zones are active during the whole time window, it returns nil and the first of them.
+func (zs BlackoutZones) AvailableTimeWindow(ll logistics.LatLng, tw TimeWindow) (*TimeWindow, *BlackoutZone) {
+	var active BlackoutZones
+	for _, zone := range zs {
+		if zone.isActive(tw) && zone.Polygon.Contains(ll) {
+			active = append(active, zone)
+		}
+	}
+	if len(active) == 0 {
+		return &tw, nil
+	}
+	sort.SliceStable(active, func(i, j int) bool {
+		return active[i].TimeWindow.Start.Before(active[j].TimeWindow.Start)
+	})
+
+	var available *TimeWindow
+	addGap := func(start, end time.Time) {
+		if !start.Before(end) {
+			return
+		}
+		if available == nil || end.Sub(start) > available.End.Sub(available.Start) {
+			available = &TimeWindow{Start: start, End: end}
+		}
+	}
+	cursor := tw.Start
+	for _, zone := range active {
+		addGap(cursor, zone.TimeWindow.Start)
+		if zone.TimeWindow.End.After(cursor) {
+			cursor = zone.TimeWindow.End
+		}
+	}
+	addGap(cursor, tw.End)
+
+	if available == nil {
+		return nil, active[0]
+	}
+	return available, nil
+}
+
+// BlackoutZoneParams are the fields of a blackout zone to write.
+type BlackoutZoneParams struct {
+	Name       string
+	Vertices   []*commonpb.Location
+	TimeWindow TimeWindow
+}
+
+func (p BlackoutZoneParams) validate() error {
+	if len(p.Vertices) < 3 {
+		return ErrInvalidBlackoutZonePolygon
+	}
+	if !p.TimeWindow.Start.Before(p.TimeWindow.End) {
+		return ErrInvalidBlackoutZoneWindow
+	}
+	return nil
+}
+
+func (p BlackoutZoneParams) latLngsE6() (latitudesE6 []int32, longitudesE6 []int32) {
+	latitudesE6 = make([]int32, len(p.Vertices))
+	longitudesE6 = make([]int32, len(p.Vertices))
+	for i, vertex := range p.Vertices {
+		latitudesE6[i] = vertex.GetLatitudeE6()
+		longitudesE6[i] = vertex.GetLongitudeE6()
+	}
+	return latitudesE6, longitudesE6
+}
+
+func (ldb *LogisticsDB) AddBlackoutZone(ctx context.Context, serviceRegionID int64, params BlackoutZoneParams) (*BlackoutZone, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	latitudesE6, longitudesE6 := params.latLngsE6()
+	row, err := ldb.queries.AddBlackoutZone(ctx, logisticssql.AddBlackoutZoneParams{
+		ServiceRegionID:   serviceRegionID,
+		Name:              params.Name,
+		LatitudesE6:       latitudesE6,
+		LongitudesE6:      longitudesE6,
+		StartTimestampSec: params.TimeWindow.Start.Unix(),
+		EndTimestampSec:   params.TimeWindow.End.Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error in AddBlackoutZone: %w", err)
+	}
+
+	return newBlackoutZone(row), nil
+}
+
+func (ldb *LogisticsDB) UpdateBlackoutZone(ctx context.Context, id int64, params BlackoutZoneParams) (*BlackoutZone, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	latitudesE6, longitudesE6 := params.latLngsE6()
+	row, err := ldb.queries.UpdateBlackoutZone(ctx, logisticssql.UpdateBlackoutZoneParams{
+		ID:                id,
+		Name:              params.Name,
+		LatitudesE6:       latitudesE6,
+		LongitudesE6:      longitudesE6,
+		StartTimestampSec: params.TimeWindow.Start.Unix(),
+		EndTimestampSec:   params.TimeWindow.End.Unix(),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrBlackoutZoneNotFound
+		}
+		return nil, fmt.Errorf("error in UpdateBlackoutZone: %w", err)
+	}
+
+	return newBlackoutZone(row), nil
+}
+
+func (ldb *LogisticsDB) DeleteBlackoutZone(ctx context.Context, id int64) error {
+	_, err := ldb.queries.DeleteBlackoutZone(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrBlackoutZoneNotFound
+		}
+		return fmt.Errorf("error in DeleteBlackoutZone: %w", err)
+	}
+	return nil
+}
+
+// GetBlackoutZonesInServiceRegion returns the blackout zones of the service region active at some point of the time window.
+func (ldb *LogisticsDB) GetBlackoutZonesInServiceRegion(ctx context.Context, serviceRegionID int64, tw TimeWindow) (BlackoutZones, error) {
+	rows, err := ldb.queries.GetBlackoutZonesInServiceRegion(ctx, logisticssql.GetBlackoutZonesInServiceRegionParams{
+		ServiceRegionID:   serviceRegionID,
+		StartTimestampSec: tw.Start.Unix(),
+		EndTimestampSec:   tw.End.Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error in GetBlackoutZonesInServiceRegion: %w", err)
+	}
+
+	zones := make(BlackoutZones, len(rows))
+	for i, row := range rows {
+		zones[i] = newBlackoutZone(row)
+	}
+	return zones, nil
+}
+
+// BlackedOutVisits are the visits removed from a VRP problem, by the blackout zone containing them.
+type BlackedOutVisits map[VisitSnapshotID]*BlackoutZone
+
+// visitArrivalTimeWindow returns the arrival time window of the visit, defaulting to the open hours.
+func visitArrivalTimeWindow(visit *logisticssql.GetLatestVisitSnapshotsInRegionRow, openHoursTW *TimeWindow) TimeWindow {
+	tw := *openHoursTW
+	if visit.ArrivalStartTimestampSec.Valid {
+		tw.Start = time.Unix(visit.ArrivalStartTimestampSec.Int64, 0)
+	}
+	if visit.ArrivalEndTimestampSec.Valid {
+		tw.End = time.Unix(visit.ArrivalEndTimestampSec.Int64, 0)
+	}
+	return tw
+}
+
+// clampVisitsToBlackoutZones clamps the arrival time window of the visits located in an active blackout zone
+// to its longest part outside the zones, and removes the visits with no such part, as they cannot be serviced.
+// Pinned visits, such as committed or started visits, are never clamped nor blacked out.
+func clampVisitsToBlackoutZones(
+	visits []*logisticssql.GetLatestVisitSnapshotsInRegionRow,
+	locations map[int64]logistics.LatLng,
+	zones BlackoutZones,
+	openHoursTW *TimeWindow,
+) ([]*logisticssql.GetLatestVisitSnapshotsInRegionRow, BlackedOutVisits) {
+	res := make([]*logisticssql.GetLatestVisitSnapshotsInRegionRow, 0, len(visits))
+	var blackedOut BlackedOutVisits
+	for _, visit := range visits {
+		ll, ok := locations[visit.LocationID]
+		if !ok || IsPinnedVisitPhase[VisitPhaseShortName(visit.VisitPhaseTypeShortName)] {
+			res = append(res, visit)
+			continue
+		}
+
+		arrivalTW := visitArrivalTimeWindow(visit, openHoursTW)
+		availableTW, zone := zones.AvailableTimeWindow(ll, arrivalTW)
+		if availableTW == nil {
+			if blackedOut == nil {
+				blackedOut = BlackedOutVisits{}
+			}
+			blackedOut[VisitSnapshotID(visit.ID)] = zone
+			continue
+		}
+		if *availableTW != arrivalTW {
+			clamped := *visit
+			clamped.ArrivalStartTimestampSec = sqltypes.ToValidNullInt64(availableTW.Start.Unix())
+			clamped.ArrivalEndTimestampSec = sqltypes.ToValidNullInt64(availableTW.End.Unix())
+			visit = &clamped
+		}
+		res = append(res, visit)
+	}
+	return res, blackedOut
+}
+
+type visitsOutsideBlackoutZonesParams struct {
+	visits      []*logisticssql.GetLatestVisitSnapshotsInRegionRow
+	zones       BlackoutZones
+	openHoursTW *TimeWindow
+}
+
+// visitsOutsideBlackoutZones clamps the arrival time window of the visits to the part outside active blackout zones,
+// removing the visits located in a blackout zone active during their whole arrival time window.
+func (ldb *LogisticsDB) visitsOutsideBlackoutZones(
+	ctx context.Context,
+	params visitsOutsideBlackoutZonesParams,
+) ([]*logisticssql.GetLatestVisitSnapshotsInRegionRow, BlackedOutVisits, error) {
+	if len(params.zones) == 0 || len(params.visits) == 0 {
+		return params.visits, nil, nil
+	}
+
+	locIDs := collections.NewLinkedInt64Set(len(params.visits))
+	for _, visit := range params.visits {
+		locIDs.Add(visit.LocationID)
+	}
+	locs, err := ldb.GetLocationsByIDs(ctx, locIDs.Elems())
+	if err != nil {
+		return nil, nil, err
+	}
+	locations := make(map[int64]logistics.LatLng, len(locs))
+	for _, loc := range locs {
+		locations[loc.ID] = logistics.LatLng{LatE6: loc.LatitudeE6, LngE6: loc.LongitudeE6}
+	}
+
+	visits, blackedOut := clampVisitsToBlackoutZones(params.visits, locations, params.zones, params.openHoursTW)
+	return visits, blackedOut, nil
+}
+
+// AddOptimizerRunBlackedOutVisits records the visits removed from the problem of an optimizer run by blackout zones.
+func (ldb *LogisticsDB) AddOptimizerRunBlackedOutVisits(ctx context.Context, optimizerRunID int64, blackedOut BlackedOutVisits) error {
+	if len(blackedOut) == 0 {
+		return nil
+	}
+
+	visitSnapshotIDs := make([]int64, 0, len(blackedOut))
+	for visitID := range blackedOut {
+		visitSnapshotIDs = append(visitSnapshotIDs, int64(visitID))
+	}
+	sort.Slice(visitSnapshotIDs, func(i, j int) bool { return visitSnapshotIDs[i] < visitSnapshotIDs[j] })
+	blackoutZoneIDs := make([]int64, len(visitSnapshotIDs))
+	for i, visitID := range visitSnapshotIDs {
+		blackoutZoneIDs[i] = blackedOut[VisitSnapshotID(visitID)].ID
+	}
+
+	_, err := ldb.queries.AddOptimizerRunBlackedOutVisits(ctx, logisticssql.AddOptimizerRunBlackedOutVisitsParams{
+		OptimizerRunID:   optimizerRunID,
+		VisitSnapshotIds: visitSnapshotIDs,
+		BlackoutZoneIds:  blackoutZoneIDs,
+	})
+	if err != nil {
+		return fmt.Errorf("error in AddOptimizerRunBlackedOutVisits: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package logisticsdb
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+)
+
+func TestBlackedOutVisits(t *testing.T) {
+	openHoursStart := time.Date(2023, time.September, 15, 8, 0, 0, 0, time.UTC)
+	openHoursTW := &TimeWindow{Start: openHoursStart, End: openHoursStart.Add(10 * time.Hour)}
+	zone := &BlackoutZone{
+		ID: 1,
+		Polygon: logistics.Polygon{
+			{LatE6: 0, LngE6: 0},
+			{LatE6: 0, LngE6: 10},
+			{LatE6: 10, LngE6: 10},
+			{LatE6: 10, LngE6: 0},
+		},
+		TimeWindow: TimeWindow{Start: openHoursStart.Add(2 * time.Hour), End: openHoursStart.Add(6 * time.Hour)},
+	}
+	visit := func(id int64, locationID int64, arrivalStart time.Duration, phase VisitPhaseShortName) *logisticssql.GetLatestVisitSnapshotsInRegionRow {
+		return &logisticssql.GetLatestVisitSnapshotsInRegionRow{
+			ID:                       id,
+			LocationID:               locationID,
+			ArrivalStartTimestampSec: sql.NullInt64{Int64: openHoursStart.Add(arrivalStart).Unix(), Valid: true},
+			ArrivalEndTimestampSec:   sql.NullInt64{Int64: openHoursStart.Add(arrivalStart + 2*time.Hour).Unix(), Valid: true},
+			VisitPhaseTypeShortName:  phase.String(),
+		}
+	}
+	insideLocationID := int64(10)
+	outsideLocationID := int64(20)
+	withArrivalTimeWindow := func(visit *logisticssql.GetLatestVisitSnapshotsInRegionRow, start, end time.Duration) *logisticssql.GetLatestVisitSnapshotsInRegionRow {
+		clamped := *visit
+		clamped.ArrivalStartTimestampSec = sql.NullInt64{Int64: openHoursStart.Add(start).Unix(), Valid: true}
+		clamped.ArrivalEndTimestampSec = sql.NullInt64{Int64: openHoursStart.Add(end).Unix(), Valid: true}
+		return &clamped
+	}
+
+	// Inside the zone while active for the whole arrival time window.
+	coveredVisit := visit(1, insideLocationID, 3*time.Hour, VisitPhaseTypeShortNameUncommitted)
+	// Inside the zone before it is active.
+	beforeZoneVisit := visit(2, insideLocationID, 0, VisitPhaseTypeShortNameUncommitted)
+	// Outside the zone.
+	outsideVisit := visit(3, outsideLocationID, 3*time.Hour, VisitPhaseTypeShortNameUncommitted)
+	// Inside the zone without arrival time window, so during open hours, which outlast the zone.
+	openHoursVisit := &logisticssql.GetLatestVisitSnapshotsInRegionRow{
+		ID:                      4,
+		LocationID:              insideLocationID,
+		VisitPhaseTypeShortName: VisitPhaseTypeShortNameUncommitted.String(),
+	}
+	// Inside the zone while active for part of the arrival time window.
+	partiallyCoveredVisit := visit(5, insideLocationID, time.Hour, VisitPhaseTypeShortNameUncommitted)
+	// Inside the zone while active, but committed.
+	committedVisit := visit(6, insideLocationID, 3*time.Hour, VisitPhaseTypeShortNameCommitted)
+	// Inside the zone while active, but already on scene.
+	onSceneVisit := visit(7, insideLocationID, 3*time.Hour, VisitPhaseTypeShortNameOnScene)
+
+	visits, blackedOut := clampVisitsToBlackoutZones(
+		[]*logisticssql.GetLatestVisitSnapshotsInRegionRow{
+			coveredVisit,
+			beforeZoneVisit,
+			outsideVisit,
+			openHoursVisit,
+			partiallyCoveredVisit,
+			committedVisit,
+			onSceneVisit,
+		},
+		map[int64]logistics.LatLng{
+			insideLocationID:  {LatE6: 5, LngE6: 5},
+			outsideLocationID: {LatE6: 50, LngE6: 5},
+		},
+		BlackoutZones{zone},
+		openHoursTW,
+	)
+
+	testutils.MustMatch(t, BlackedOutVisits{1: zone}, blackedOut)
+	testutils.MustMatch(t, []*logisticssql.GetLatestVisitSnapshotsInRegionRow{
+		beforeZoneVisit,
+		outsideVisit,
+		// Clamped to after the zone, the longest part of open hours outside it.
+		withArrivalTimeWindow(openHoursVisit, 6*time.Hour, 10*time.Hour),
+		// Clamped to before the zone.
+		withArrivalTimeWindow(partiallyCoveredVisit, time.Hour, 2*time.Hour),
+		committedVisit,
+		onSceneVisit,
+	}, visits)
+}
+
+func TestBlackoutZonesAvailableTimeWindow(t *testing.T) {
+	start := time.Date(2023, time.September, 15, 8, 0, 0, 0, time.UTC)
+	polygon := logistics.Polygon{
+		{LatE6: 0, LngE6: 0},
+		{LatE6: 0, LngE6: 10},
+		{LatE6: 10, LngE6: 10},
+		{LatE6: 10, LngE6: 0},
+	}
+	zone := func(id int64, zoneStart, zoneEnd time.Duration) *BlackoutZone {
+		return &BlackoutZone{
+			ID:         id,
+			Polygon:    polygon,
+			TimeWindow: TimeWindow{Start: start.Add(zoneStart), End: start.Add(zoneEnd)},
+		}
+	}
+	tw := func(twStart, twEnd time.Duration) *TimeWindow {
+		return &TimeWindow{Start: start.Add(twStart), End: start.Add(twEnd)}
+	}
+	inside := logistics.LatLng{LatE6: 5, LngE6: 5}
+
+	tcs := []struct {
+		Desc     string
+		Zones    BlackoutZones
+		Location logistics.LatLng
+
+		ExpectedTimeWindow *TimeWindow
+		ExpectedZone       *BlackoutZone
+	}{
+		{
+			Desc:     "no zones",
+			Location: inside,
+
+			ExpectedTimeWindow: tw(0, 4*time.Hour),
+		},
+		{
+			Desc:     "outside zone",
+			Zones:    BlackoutZones{zone(1, 0, 4*time.Hour)},
+			Location: logistics.LatLng{LatE6: 50, LngE6: 5},
+
+			ExpectedTimeWindow: tw(0, 4*time.Hour),
+		},
+		{
+			Desc:     "zone active at the start",
+			Zones:    BlackoutZones{zone(1, -time.Hour, time.Hour)},
+			Location: inside,
+
+			ExpectedTimeWindow: tw(time.Hour, 4*time.Hour),
+		},
+		{
+			Desc:     "zone active in the middle",
+			Zones:    BlackoutZones{zone(1, time.Hour, 2*time.Hour)},
+			Location: inside,
+
+			ExpectedTimeWindow: tw(2*time.Hour, 4*time.Hour),
+		},
+		{
+			Desc:     "overlapping zones",
+			Zones:    BlackoutZones{zone(1, 3*time.Hour, 5*time.Hour), zone(2, time.Hour, 3*time.Hour+30*time.Minute)},
+			Location: inside,
+
+			ExpectedTimeWindow: tw(0, time.Hour),
+		},
+		{
+			Desc:     "zones covering the time window",
+			Zones:    BlackoutZones{zone(1, 2*time.Hour, 5*time.Hour), zone(2, -time.Hour, 2*time.Hour)},
+			Location: inside,
+
+			ExpectedZone: zone(2, -time.Hour, 2*time.Hour),
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			availableTW, zone := tc.Zones.AvailableTimeWindow(tc.Location, *tw(0, 4*time.Hour))
+			testutils.MustMatch(t, tc.ExpectedTimeWindow, availableTW)
+			testutils.MustMatch(t, tc.ExpectedZone, zone)
+		})
+	}
+}
@@ -156,7 +156,11 @@ func (s *CounterfactualSchedule) shiftTeamRoute(shiftTeam *optimizerpb.VRPShiftT
 	}
 
 	location := s.vrpLocationsMap[*shiftTeam.DepotLocationId]
-	return &logisticspb.ShiftTeamRoute{
+	endLocation := location
+	if shiftTeam.EndDepotLocationId != nil {
+		endLocation = s.vrpLocationsMap[*shiftTeam.EndDepotLocationId]
+	}
+	route := &logisticspb.ShiftTeamRoute{
 		Stops:                             stops,
 		BaseLocationDepartureTimestampSec: shiftTeam.Route.DepotDepartureTimestampSec,
 		BaseLocationArrivalTimestampSec:   shiftTeam.Route.DepotArrivalTimestampSec,
@@ -164,7 +168,13 @@ func (s *CounterfactualSchedule) shiftTeamRoute(shiftTeam *optimizerpb.VRPShiftT
 			LatitudeE6:  *location.LatitudeE6,
 			LongitudeE6: *location.LongitudeE6,
 		},
-	}, nil
+		EndLocation: &common.Location{
+			LatitudeE6:  *endLocation.LatitudeE6,
+			LongitudeE6: *endLocation.LongitudeE6,
+		},
+	}
+	shiftTeamRouteLegs(route)
+	return route, nil
 }
 
 func (s *CounterfactualSchedule) unassignedVisits() []*logisticspb.UnassignableVisit {
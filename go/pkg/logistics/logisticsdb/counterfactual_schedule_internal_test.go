@@ -103,6 +103,16 @@ func TestScheduleFromVRPSolution_Schedule(t *testing.T) {
 				ShiftTeamId: shiftTeamID,
 				Route: &logisticspb.ShiftTeamRoute{
 					BaseLocation: location,
+					EndLocation:  location,
+					FirstLeg: &logisticspb.ShiftTeamRouteLeg{
+						StartLocation:       location,
+						EndLocation:         location,
+						ArrivalTimestampSec: proto.Int64(1),
+					},
+					LastLeg: &logisticspb.ShiftTeamRouteLeg{
+						StartLocation: location,
+						EndLocation:   location,
+					},
 					Stops: []*logisticspb.ShiftTeamRouteStop{
 						{
 							Stop: &logisticspb.ShiftTeamRouteStop_Visit{
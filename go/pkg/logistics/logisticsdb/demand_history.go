@@ -0,0 +1,123 @@
+package logisticsdb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/sqltypes"
+)
+
+var (
+	// Statuses of availability queries that found the service region available.
+	// Availability queries without care requests are recorded with check feasibility statuses.
+	availableAvailabilityQueryStatuses = []string{
+		logisticspb.ServiceRegionAvailability_STATUS_AVAILABLE.String(),
+		logisticspb.CheckFeasibilityResponse_STATUS_FEASIBLE.String(),
+	}
+	feasibleCheckFeasibilityQueryStatuses = []string{
+		logisticspb.CheckFeasibilityResponse_STATUS_FEASIBLE.String(),
+	}
+)
+
+// DemandHour is the demand recorded in a service region during an hour, for visits with a set of required attributes.
+type DemandHour struct {
+	// Hour is the start of the hour.
+	Hour time.Time
+	// AttributeNames are the sorted names of the required attributes of the demand.
+	AttributeNames []string
+
+	AvailabilityQueries       int64
+	FailedAvailabilityQueries int64
+	FeasibilityQueries        int64
+	FailedFeasibilityQueries  int64
+	CompletedVisits           int64
+}
+
+// AttributesKey identifies the set of required attributes of the demand.
+func (d *DemandHour) AttributesKey() string {
+	return strings.Join(d.AttributeNames, ",")
+}
+
+type demandHours struct {
+	byKey map[string]*DemandHour
+}
+
+func (h *demandHours) get(hour time.Time, attributeNames []string) *DemandHour {
+	demand := &DemandHour{Hour: hour, AttributeNames: attributeNames}
+	key := fmt.Sprintf("%d|%s", hour.Unix(), demand.AttributesKey())
+	if existing, ok := h.byKey[key]; ok {
+		return existing
+	}
+	h.byKey[key] = demand
+	return demand
+}
+
+func (h *demandHours) sorted() []*DemandHour {
+	res := make([]*DemandHour, 0, len(h.byKey))
+	for _, demand := range h.byKey {
+		res = append(res, demand)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if !res[i].Hour.Equal(res[j].Hour) {
+			return res[i].Hour.Before(res[j].Hour)
+		}
+		return res[i].AttributesKey() < res[j].AttributesKey()
+	})
+	return res
+}
+
+// GetServiceRegionDemandHistory returns the hourly demand recorded in the service region during the time window,
+// from its availability queries, check feasibility queries and completed visits.
+func (ldb *LogisticsDB) GetServiceRegionDemandHistory(ctx context.Context, serviceRegionID int64, tw TimeWindow) ([]*DemandHour, error) {
+	hours := &demandHours{byKey: map[string]*DemandHour{}}
+
+	availabilityRows, err := ldb.queries.GetServiceRegionAvailabilityQueryDemand(ctx, logisticssql.GetServiceRegionAvailabilityQueryDemandParams{
+		ServiceRegionID:   serviceRegionID,
+		StartTime:         tw.Start,
+		EndTime:           tw.End,
+		AvailableStatuses: availableAvailabilityQueryStatuses,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error in GetServiceRegionAvailabilityQueryDemand: %w", err)
+	}
+	for _, row := range availabilityRows {
+		demand := hours.get(row.Hour, row.AttributeNames)
+		demand.AvailabilityQueries = row.QueryCount
+		demand.FailedAvailabilityQueries = row.FailedQueryCount
+	}
+
+	feasibilityRows, err := ldb.queries.GetServiceRegionCheckFeasibilityQueryDemand(ctx, logisticssql.GetServiceRegionCheckFeasibilityQueryDemandParams{
+		ServiceRegionID:  sqltypes.ToNullInt64(&serviceRegionID),
+		StartTime:        tw.Start,
+		EndTime:          tw.End,
+		FeasibleStatuses: feasibleCheckFeasibilityQueryStatuses,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error in GetServiceRegionCheckFeasibilityQueryDemand: %w", err)
+	}
+	for _, row := range feasibilityRows {
+		demand := hours.get(row.Hour, row.AttributeNames)
+		demand.FeasibilityQueries = row.QueryCount
+		demand.FailedFeasibilityQueries = row.FailedQueryCount
+	}
+
+	visitRows, err := ldb.queries.GetServiceRegionCompletedVisitDemand(ctx, logisticssql.GetServiceRegionCompletedVisitDemandParams{
+		ServiceRegionID:           serviceRegionID,
+		StartTime:                 tw.Start,
+		EndTime:                   tw.End,
+		CompletedVisitPhaseTypeID: VisitPhaseTypeShortNameCompleted.PhaseTypeID(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error in GetServiceRegionCompletedVisitDemand: %w", err)
+	}
+	for _, row := range visitRows {
+		hours.get(row.Hour, row.AttributeNames).CompletedVisits = row.VisitCount
+	}
+
+	return hours.sorted(), nil
+}
@@ -13,10 +13,19 @@ import (
 	"github.com/*company-data-covered*/services/go/pkg/logistics"
 	"github.com/*company-data-covered*/services/go/pkg/logistics/optimizer/optimizersettings"
 	"github.com/*company-data-covered*/services/go/pkg/monitoring"
+	"github.com/*company-data-covered*/services/go/pkg/sqltypes"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/protobuf/proto"
 )
 
+const (
+	departureTimeBucketDistancesErrorMetric = "departure_time_bucket_distances_error"
+
+	// minDepartureTimeLead is how far in the future departure times sent to map services are at least,
+	// as map services reject departure times in the past.
+	minDepartureTimeLead = time.Minute
+)
+
 type buildDistanceMatrixReqsParams struct {
 	routeHistoryPaths  []DistanceMatrixRequest
 	tailLocationIDs    []int64
@@ -47,8 +56,8 @@ func buildDistanceMatrixReqs(params buildDistanceMatrixReqsParams) []DistanceMat
 			FromLocationIDs: params.tailLocationIDs,
 			ToLocationIDs:   params.planningStopLocIDs},
 		// (C) Tail position sets of shift teams, Current Position / End of Route History --> Depots.
-		// NOTE: this could maybe be cleaned up to support the (currently non-existent) case where there are
-		// multiple depots; as this approach computes extra distances in that case.
+		// NOTE: depots are the start and end locations of all shift teams, so this computes extra distances
+		// when shift teams start and end at different locations, such as for home-based shift teams.
 		&RectDistancesReq{
 			FromLocationIDs: params.tailLocationIDs,
 			ToLocationIDs:   params.depotLocIDs},
@@ -70,6 +79,8 @@ func buildDistanceMatrixReqs(params buildDistanceMatrixReqsParams) []DistanceMat
 type DistanceMatrixMapsTags struct {
 	ServiceRegionID int64
 	ServiceDate     time.Time
+	// Use of the distances, if not for the optimizer or feasibility checks directly.
+	Use string
 }
 
 type DistanceMatrixRequest interface {
@@ -178,13 +189,57 @@ type GetDistanceMatrixParams struct {
 	Reqs           []DistanceMatrixRequest
 	AfterCreatedAt time.Time
 
-	// Primary map service
+	// Primary map service, which may be a logistics.FallbackMapService
 	MapService logistics.MapService
 	// Other map services for best effort research
 	ResearchMapServices []logistics.MapService
 
 	MapsTags *DistanceMatrixMapsTags
 	Settings optimizersettings.Settings
+
+	// Departure time buckets to also get time dependent distances for.
+	// Only used if MapService, or its primary map service, is a logistics.TimeDependentMapService.
+	DepartureTimeBuckets *DepartureTimeBuckets
+}
+
+// DepartureTimeBuckets are consecutive departure time buckets of the same duration.
+type DepartureTimeBuckets struct {
+	BucketDuration time.Duration
+	StartTimes     []time.Time
+}
+
+// NewDepartureTimeBuckets returns the departure time buckets covering tw, aligned to bucketDuration,
+// skipping buckets that ended by now, as map services do not have distances for past departure times.
+// Returns nil if bucketDuration is not positive, or tw is empty or has passed.
+func NewDepartureTimeBuckets(tw TimeWindow, bucketDuration time.Duration, now time.Time) *DepartureTimeBuckets {
+	if bucketDuration <= 0 || !tw.End.After(tw.Start) {
+		return nil
+	}
+
+	var startTimes []time.Time
+	for startTime := tw.Start.Truncate(bucketDuration); startTime.Before(tw.End); startTime = startTime.Add(bucketDuration) {
+		if !startTime.Add(bucketDuration).After(now) {
+			continue
+		}
+		startTimes = append(startTimes, startTime)
+	}
+	if len(startTimes) == 0 {
+		return nil
+	}
+
+	return &DepartureTimeBuckets{
+		BucketDuration: bucketDuration,
+		StartTimes:     startTimes,
+	}
+}
+
+func (params GetDistanceMatrixParams) useDepartureTimeBuckets() bool {
+	if params.DepartureTimeBuckets == nil || len(params.DepartureTimeBuckets.StartTimes) == 0 {
+		return false
+	}
+
+	_, ok := logistics.PrimaryMapService(params.MapService).(logistics.TimeDependentMapService)
+	return ok
 }
 
 // GetDistanceMatrix computes a single (not-necessarily square) matrix composed of the results
@@ -204,12 +259,63 @@ type GetDistanceMatrixParams struct {
 //	   (0, 1);  (0, 2)  // from the 1st request
 //	   (3, 3), (3, 4), (3, 5); // from the 2nd request, from=3
 //	   (4, 3), (4, 4), (4, 5)  // from the 2nd request, from=4
+//
+// If DepartureTimeBuckets are set, each distance also carries the time dependent distances for every bucket.
+// Buckets are best effort: distances of buckets that failed to be fetched only have the time independent distance.
 func (ldb *LogisticsDB) GetDistanceMatrix(ctx context.Context, params GetDistanceMatrixParams) (*optimizerpb.VRPDistanceMatrix, *TimeWindow, error) {
-	reqs := params.Reqs
-	if allDistanceMatrixRequestsEmpty(reqs) {
+	if allDistanceMatrixRequestsEmpty(params.Reqs) {
 		return &optimizerpb.VRPDistanceMatrix{}, &TimeWindow{}, nil
 	}
 
+	distances, err := ldb.getLatestDistances(ctx, params, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	matrix := distanceMatrixForDistances(distances)
+	if !params.useDepartureTimeBuckets() {
+		return matrix, getDistanceTW(distances), nil
+	}
+
+	buckets := params.DepartureTimeBuckets
+	bucketDistances := make([][]*logisticssql.BatchGetLatestDistancesForLocationsRow, len(buckets.StartTimes))
+	var eg errgroup.Group
+	for i, startTime := range buckets.StartTimes {
+		i, startTime := i, startTime
+		eg.Go(func() error {
+			d, err := ldb.getLatestDistances(ctx, params, &startTime)
+			if err != nil {
+				ldb.scope.WritePoint(departureTimeBucketDistancesErrorMetric, monitoring.Tags{
+					serviceRegionTag:       I64ToA(params.MapsTags.ServiceRegionID),
+					departureTimeBucketTag: startTime.Format(departureTimeBucketTagLayout),
+				}, monitoring.Fields{"error": err.Error()})
+				return nil
+			}
+
+			bucketDistances[i] = d
+			return nil
+		})
+	}
+	_ = eg.Wait()
+
+	addDepartureTimeBucketsToDistanceMatrix(matrix, buckets, bucketDistances)
+
+	allDistances := distances
+	for _, d := range bucketDistances {
+		allDistances = append(allDistances, d...)
+	}
+
+	return matrix, getDistanceTW(allDistances), nil
+}
+
+// getLatestDistances returns the latest distances for all the requests, adding any missing distances from the map service.
+// A nil departureBucketStart gets time independent distances.
+func (ldb *LogisticsDB) getLatestDistances(
+	ctx context.Context,
+	params GetDistanceMatrixParams,
+	departureBucketStart *time.Time,
+) ([]*logisticssql.BatchGetLatestDistancesForLocationsRow, error) {
+	reqs := params.Reqs
 	queries := ldb.queries
 
 	reqWantLocIDPairSets := make([]*collections.LinkedSet[locIDPair], len(reqs))
@@ -220,43 +326,16 @@ func (ldb *LogisticsDB) GetDistanceMatrix(ctx context.Context, params GetDistanc
 	wantLocIDPairSet := collections.NewLinkedSet[locIDPair](0)
 	wantLocIDPairSet.AddSet(reqWantLocIDPairSets...)
 
-	batchSize := ldb.QuerySettings.GetLatestDistancesForLocationsBatchSize
-	if batchSize == 0 {
-		batchSize = uint(wantLocIDPairSet.Size())
-	}
-	batches := int(math.Ceil(float64(wantLocIDPairSet.Size()) / float64(batchSize)))
-	latestDistanceParams := make([]logisticssql.BatchGetLatestDistancesForLocationsParams, batches)
-
-	for i := 0; i < batches; i++ {
-		latestDistanceParams[i].AfterCreatedAt = params.AfterCreatedAt
-		latestDistanceParams[i].FromLocationIds = make([]int64, 0, batchSize)
-		latestDistanceParams[i].ToLocationIds = make([]int64, 0, batchSize)
-		latestDistanceParams[i].SourceIds = make([]int64, 0, batchSize)
-	}
-
-	i := uint(0)
-	batch := 0
 	sourceID := params.MapService.GetDistanceSourceID()
-	wantLocIDPairSet.Map(func(pair locIDPair) {
-		latestDistanceParam := &latestDistanceParams[batch]
-		latestDistanceParam.FromLocationIds = append(latestDistanceParam.FromLocationIds, pair.from)
-		latestDistanceParam.ToLocationIds = append(latestDistanceParam.ToLocationIds, pair.to)
-		latestDistanceParam.SourceIds = append(latestDistanceParam.SourceIds, sourceID)
-
-		i++
-		if i == batchSize {
-			i = 0
-			batch++
-		}
-	})
+	latestDistanceParams := ldb.latestDistancesParams(wantLocIDPairSet, sourceID, params.AfterCreatedAt, departureBucketStart)
 
 	distances, err := ldb.batchGetLatestDistancesForLocations(ctx, latestDistanceParams)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	if len(distances) == wantLocIDPairSet.Size() {
-		return distanceMatrixForDistances(distances), getDistanceTW(distances), nil
+		return distances, nil
 	}
 
 	currentLocIDPairSet := collections.NewLinkedSet[locIDPair](len(distances))
@@ -271,7 +350,7 @@ func (ldb *LogisticsDB) GetDistanceMatrix(ctx context.Context, params GetDistanc
 
 		missingLocsReqs, err := missingLocIDPairs(wantLocIDPairSet, currAndRequestedLocIDPairs, req)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 
 		allMissingLocReqs = append(allMissingLocReqs, missingLocsReqs...)
@@ -289,24 +368,31 @@ func (ldb *LogisticsDB) GetDistanceMatrix(ctx context.Context, params GetDistanc
 			for _, l := range allLocations {
 				found.Add(l.ID)
 			}
-			return nil, nil, fmt.Errorf("%w: found: %v, wanted: %v", err, found.Elems(), allLocIDs.Elems())
+			return nil, fmt.Errorf("%w: found: %v, wanted: %v", err, found.Elems(), allLocIDs.Elems())
 		}
-		return nil, nil, err
+		return nil, err
 	}
 
 	mapper := newLatLngMapper(allLocations)
-	err = ldb.addMissingDistances(ctx, queries, allMissingLocReqs, mapper, params)
+	err = ldb.addMissingDistances(ctx, queries, allMissingLocReqs, mapper, params, departureBucketStart)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	distances, err = ldb.batchGetLatestDistancesForLocations(ctx, latestDistanceParams)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+
+	if fallback, ok := params.MapService.(*logistics.FallbackMapService); ok && len(distances) != wantLocIDPairSet.Size() {
+		distances, err = ldb.addFallbackDistances(ctx, distances, wantLocIDPairSet, fallback, params.AfterCreatedAt, departureBucketStart)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if len(distances) != wantLocIDPairSet.Size() {
-		return nil, nil, fmt.Errorf("not enough distances, after adding missing distances: "+
+		return nil, fmt.Errorf("not enough distances, after adding missing distances: "+
 			"service_region_id(%d), "+
 			"num_distances(%d), "+
 			"wanted(%d), "+
@@ -318,16 +404,99 @@ func (ldb *LogisticsDB) GetDistanceMatrix(ctx context.Context, params GetDistanc
 		)
 	}
 
-	return distanceMatrixForDistances(distances), getDistanceTW(distances), nil
+	return distances, nil
+}
+
+// latestDistancesParams returns the batched query params to get the latest distances of sourceID for all pairs.
+func (ldb *LogisticsDB) latestDistancesParams(
+	pairs *collections.LinkedSet[locIDPair],
+	sourceID int64,
+	afterCreatedAt time.Time,
+	departureBucketStart *time.Time,
+) []logisticssql.BatchGetLatestDistancesForLocationsParams {
+	batchSize := ldb.QuerySettings.GetLatestDistancesForLocationsBatchSize
+	if batchSize == 0 {
+		batchSize = uint(pairs.Size())
+	}
+	batches := int(math.Ceil(float64(pairs.Size()) / float64(batchSize)))
+	latestDistanceParams := make([]logisticssql.BatchGetLatestDistancesForLocationsParams, batches)
+
+	for i := 0; i < batches; i++ {
+		latestDistanceParams[i].AfterCreatedAt = afterCreatedAt
+		latestDistanceParams[i].DepartureBucketStart = sqltypes.ToNullTime(departureBucketStart)
+		latestDistanceParams[i].FromLocationIds = make([]int64, 0, batchSize)
+		latestDistanceParams[i].ToLocationIds = make([]int64, 0, batchSize)
+		latestDistanceParams[i].SourceIds = make([]int64, 0, batchSize)
+	}
+
+	i := uint(0)
+	batch := 0
+	pairs.Map(func(pair locIDPair) {
+		latestDistanceParam := &latestDistanceParams[batch]
+		latestDistanceParam.FromLocationIds = append(latestDistanceParam.FromLocationIds, pair.from)
+		latestDistanceParam.ToLocationIds = append(latestDistanceParam.ToLocationIds, pair.to)
+		latestDistanceParam.SourceIds = append(latestDistanceParam.SourceIds, sourceID)
+
+		i++
+		if i == batchSize {
+			i = 0
+			batch++
+		}
+	})
+
+	return latestDistanceParams
+}
+
+// addFallbackDistances adds to distances the latest distances of the fallback map services,
+// for the wanted pairs that the primary map service has no distances for.
+func (ldb *LogisticsDB) addFallbackDistances(
+	ctx context.Context,
+	distances []*logisticssql.BatchGetLatestDistancesForLocationsRow,
+	wantLocIDPairSet *collections.LinkedSet[locIDPair],
+	fallback *logistics.FallbackMapService,
+	afterCreatedAt time.Time,
+	departureBucketStart *time.Time,
+) ([]*logisticssql.BatchGetLatestDistancesForLocationsRow, error) {
+	foundLocIDPairSet := collections.NewLinkedSet[locIDPair](len(distances))
+	for _, d := range distances {
+		foundLocIDPairSet.Add(locIDPair{d.FromLocationID, d.ToLocationID})
+	}
+
+	for _, mapService := range fallback.MapServices()[1:] {
+		missingLocIDPairSet := collections.NewLinkedSet[locIDPair](wantLocIDPairSet.Size() - foundLocIDPairSet.Size())
+		wantLocIDPairSet.Map(func(pair locIDPair) {
+			if !foundLocIDPairSet.Has(pair) {
+				missingLocIDPairSet.Add(pair)
+			}
+		})
+		if missingLocIDPairSet.Size() == 0 {
+			break
+		}
+
+		latestDistanceParams := ldb.latestDistancesParams(missingLocIDPairSet, mapService.GetDistanceSourceID(), afterCreatedAt, departureBucketStart)
+		fallbackDistances, err := ldb.batchGetLatestDistancesForLocations(ctx, latestDistanceParams)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, d := range fallbackDistances {
+			foundLocIDPairSet.Add(locIDPair{d.FromLocationID, d.ToLocationID})
+		}
+		distances = append(distances, fallbackDistances...)
+	}
+
+	return distances, nil
 }
 
 // addMissingDistances queries the map service and writes to the DB for the all the missingLocationsReqs.
+// A non nil departureBucketStart fills in the time dependent distances for that departure time bucket.
 func (ldb *LogisticsDB) addMissingDistances(
 	ctx context.Context,
 	queries *logisticssql.Queries,
 	missingLocationsReqs []MissingLocReq,
 	mapper *latLngMapper,
-	params GetDistanceMatrixParams) error {
+	params GetDistanceMatrixParams,
+	departureBucketStart *time.Time) error {
 	if len(missingLocationsReqs) == 0 {
 		return nil
 	}
@@ -339,28 +508,36 @@ func (ldb *LogisticsDB) addMissingDistances(
 		serviceDateTag:   mapsTags.ServiceDate.Format(dateLayout),
 	}
 
+	var departureTime *time.Time
+	if departureBucketStart != nil {
+		tags[departureTimeBucketTag] = departureBucketStart.Format(departureTimeBucketTagLayout)
+		t := mapServiceDepartureTime(*departureBucketStart, time.Now())
+		departureTime = &t
+	}
+	if mapsTags.Use != "" {
+		tags[distanceMatrixUseTag] = mapsTags.Use
+	}
+
 	fetch := func(ctx context.Context, req MissingLocReq, mapService logistics.MapService, tags monitoring.Tags) func() error {
 		return func() error {
 			var m logistics.DistanceMatrix
+			answeringMapService := mapService
 			var err error
-			switch r := req.DistanceMatrixRequest.(type) {
-			case *RectDistancesReq:
-				origins, destinations := r.LatLngs(mapper)
-				m, err = mapService.GetDistanceMatrix(ctx, tags, origins, destinations)
-
-			case PathDistancesReq:
-				path := r.LatLngs(mapper)
-				m, err = mapService.GetPathDistanceMatrix(ctx, tags, path...)
-
-			default:
-				return fmt.Errorf("unhandled DistanceMatrixRequest type: %v", r)
+			if fallback, ok := mapService.(*logistics.FallbackMapService); ok {
+				answeringMapService, err = fallback.Do(ctx, tags, func(ms logistics.MapService) error {
+					var fetchErr error
+					m, fetchErr = fetchDistanceMatrix(ctx, req, ms, mapper, tags, departureTime)
+					return fetchErr
+				})
+			} else {
+				m, err = fetchDistanceMatrix(ctx, req, mapService, mapper, tags, departureTime)
 			}
-
 			if err != nil {
 				return err
 			}
 
-			addDistancesParams := addDistancesParamsFromMatrix(m, mapper, mapService.GetDistanceSourceID())
+			addDistancesParams := addDistancesParamsFromMatrix(m, mapper, answeringMapService.GetDistanceSourceID())
+			addDistancesParams.DepartureBucketStart = sqltypes.ToNullTime(departureBucketStart)
 			_, err = queries.AddDistances(ctx, addDistancesParams)
 
 			return err
@@ -383,6 +560,9 @@ func (ldb *LogisticsDB) addMissingDistances(
 		for _, req := range missingLocationsReqs {
 			req := req
 			for _, mapService := range params.ResearchMapServices {
+				if _, ok := mapService.(logistics.TimeDependentMapService); departureBucketStart != nil && !ok {
+					continue
+				}
 				//nolint: contextcheck
 				bestEffortEG.Go(fetch(bestEffortEGCtx, req, mapService, researchTags))
 			}
@@ -403,6 +583,54 @@ func (ldb *LogisticsDB) addMissingDistances(
 	return err
 }
 
+// mapServiceDepartureTime returns the departure time to get the distances of the bucket starting at bucketStart with,
+// which is clamped to shortly after now for the bucket in progress.
+func mapServiceDepartureTime(bucketStart, now time.Time) time.Time {
+	earliest := now.Add(minDepartureTimeLead)
+	if bucketStart.Before(earliest) {
+		return earliest
+	}
+	return bucketStart
+}
+
+// fetchDistanceMatrix gets the distances of req from mapService.
+// A non nil departureTime requires mapService to be a logistics.TimeDependentMapService.
+func fetchDistanceMatrix(
+	ctx context.Context,
+	req MissingLocReq,
+	mapService logistics.MapService,
+	mapper *latLngMapper,
+	tags monitoring.Tags,
+	departureTime *time.Time) (logistics.DistanceMatrix, error) {
+	var timeDependentMapService logistics.TimeDependentMapService
+	if departureTime != nil {
+		var ok bool
+		timeDependentMapService, ok = mapService.(logistics.TimeDependentMapService)
+		if !ok {
+			return nil, fmt.Errorf("%w: departure times: distance_source_id(%d)", logistics.ErrMapServiceUnsupported, mapService.GetDistanceSourceID())
+		}
+	}
+
+	switch r := req.DistanceMatrixRequest.(type) {
+	case *RectDistancesReq:
+		origins, destinations := r.LatLngs(mapper)
+		if timeDependentMapService != nil {
+			return timeDependentMapService.GetDistanceMatrixAtTime(ctx, tags, *departureTime, origins, destinations)
+		}
+		return mapService.GetDistanceMatrix(ctx, tags, origins, destinations)
+
+	case PathDistancesReq:
+		path := r.LatLngs(mapper)
+		if timeDependentMapService != nil {
+			return timeDependentMapService.GetPathDistanceMatrixAtTime(ctx, tags, *departureTime, path...)
+		}
+		return mapService.GetPathDistanceMatrix(ctx, tags, path...)
+
+	default:
+		return nil, fmt.Errorf("unhandled DistanceMatrixRequest type: %v", r)
+	}
+}
+
 func addDistancesParamsFromMatrix(m logistics.DistanceMatrix, mapper *latLngMapper, sourceID int64) logisticssql.AddDistancesParams {
 	var params logisticssql.AddDistancesParams
 	for fromLatLng, toLatLngDistances := range m {
@@ -566,6 +794,46 @@ func distanceMatrixForDistances(distancesForLocations []*logisticssql.BatchGetLa
 	return &optimizerpb.VRPDistanceMatrix{Distances: distances}
 }
 
+// addDepartureTimeBucketsToDistanceMatrix adds the time dependent distances of each departure time bucket
+// to the matching distances in matrix.
+func addDepartureTimeBucketsToDistanceMatrix(
+	matrix *optimizerpb.VRPDistanceMatrix,
+	buckets *DepartureTimeBuckets,
+	bucketDistances [][]*logisticssql.BatchGetLatestDistancesForLocationsRow) {
+	vrpDistances := make(map[locIDPair]*optimizerpb.VRPDistance, len(matrix.Distances))
+	for _, d := range matrix.Distances {
+		vrpDistances[locIDPair{from: d.GetFromLocationId(), to: d.GetToLocationId()}] = d
+	}
+
+	for i, distances := range bucketDistances {
+		startTimestampSec := buckets.StartTimes[i].Unix()
+		seenDistances := collections.NewLinkedSet[locIDPair](len(distances))
+		for _, distance := range distances {
+			key := locIDPair{
+				from: distance.FromLocationID,
+				to:   distance.ToLocationID,
+			}
+			if seenDistances.Has(key) {
+				continue
+			}
+			seenDistances.Add(key)
+
+			vrpDistance, ok := vrpDistances[key]
+			if !ok {
+				continue
+			}
+
+			vrpDistance.DepartureTimeBuckets = append(vrpDistance.DepartureTimeBuckets, &optimizerpb.VRPTimeDependentDistance{
+				DepartureBucketStartTimestampSec: proto.Int64(startTimestampSec),
+				LengthMeters:                     proto.Int64(int64(distance.DistanceMeters)),
+				DurationSec:                      proto.Int64(int64(distance.DurationSeconds)),
+			})
+		}
+	}
+
+	matrix.DepartureTimeBucketDurationSec = proto.Int64(int64(buckets.BucketDuration.Seconds()))
+}
+
 func missingLocIDPairs(
 	wantLocIDPairSet *collections.LinkedSet[locIDPair],
 	hasLocIDPairSet collections.Set[locIDPair],
@@ -347,3 +347,204 @@ func TestGetDistanceMatrix(t *testing.T) {
 		})
 	}
 }
+
+type mockTimeDependentDistanceMatrix struct {
+	mockDistanceMatrix
+
+	departureTimeErr error
+}
+
+func (m *mockTimeDependentDistanceMatrix) GetDistanceMatrixAtTime(ctx context.Context, mapsTags monitoring.Tags, departureTime time.Time, origins, destinations []logistics.LatLng) (logistics.DistanceMatrix, error) {
+	if m.departureTimeErr != nil {
+		return nil, m.departureTimeErr
+	}
+	matrix, err := m.GetDistanceMatrix(ctx, mapsTags, origins, destinations)
+	if err != nil {
+		return nil, err
+	}
+
+	return withDepartureTimeDurations(matrix, departureTime), nil
+}
+
+func (m *mockTimeDependentDistanceMatrix) GetPathDistanceMatrixAtTime(ctx context.Context, mapsTags monitoring.Tags, departureTime time.Time, path ...logistics.LatLng) (logistics.DistanceMatrix, error) {
+	if m.departureTimeErr != nil {
+		return nil, m.departureTimeErr
+	}
+	matrix, err := m.GetPathDistanceMatrix(ctx, mapsTags, path...)
+	if err != nil {
+		return nil, err
+	}
+
+	return withDepartureTimeDurations(matrix, departureTime), nil
+}
+
+// withDepartureTimeDurations sets each duration to the departure hour, in seconds.
+func withDepartureTimeDurations(matrix logistics.DistanceMatrix, departureTime time.Time) logistics.DistanceMatrix {
+	for _, distances := range matrix {
+		for toLL, distance := range distances {
+			distance.Duration = time.Duration(departureTime.Hour()) * time.Second
+			distances[toLL] = distance
+		}
+	}
+
+	return matrix
+}
+
+func TestGetDistanceMatrixDepartureTimeBuckets(t *testing.T) {
+	ctx, db, queries, done := setupDBTest(t)
+	defer done()
+
+	afterCreatedAt := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	sourceID := time.Now().UnixNano()
+	failingSourceID := sourceID + 1
+	ldb := logisticsdb.NewLogisticsDB(db, nil, mockSettingsService, nil)
+
+	locIDs := addLocationIDs(ctx, t, queries, 3)
+	addDistances(ctx, t, queries, locIDs, locIDs, sourceID)
+	addDistances(ctx, t, queries, locIDs, locIDs, failingSourceID)
+
+	// Buckets are in the future, as map services are not queried for past departure times.
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	buckets := logisticsdb.NewDepartureTimeBuckets(logisticsdb.TimeWindow{
+		Start: start,
+		End:   start.Add(3 * time.Hour),
+	}, time.Hour, time.Now())
+
+	tcs := []struct {
+		Desc       string
+		MapService logistics.MapService
+
+		WantBuckets          bool
+		WantNoBucketDistance bool
+	}{
+		{
+			Desc: "time dependent map service",
+			MapService: &mockTimeDependentDistanceMatrix{
+				mockDistanceMatrix: mockDistanceMatrix{distanceSourceID: sourceID},
+			},
+
+			WantBuckets: true,
+		},
+		{
+			Desc:       "map service without departure times ignores buckets",
+			MapService: &mockDistanceMatrix{distanceSourceID: sourceID},
+		},
+		{
+			Desc: "failing departure times fall back to time independent distances",
+			MapService: &mockTimeDependentDistanceMatrix{
+				mockDistanceMatrix: mockDistanceMatrix{distanceSourceID: failingSourceID},
+				departureTimeErr:   errors.New("departure time in the past"),
+			},
+
+			WantBuckets:          true,
+			WantNoBucketDistance: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			matrix, _, err := ldb.GetDistanceMatrix(ctx, logisticsdb.GetDistanceMatrixParams{
+				Reqs: []logisticsdb.DistanceMatrixRequest{
+					&logisticsdb.RectDistancesReq{FromLocationIDs: locIDs, ToLocationIDs: locIDs},
+				},
+				AfterCreatedAt:       afterCreatedAt,
+				MapService:           tc.MapService,
+				MapsTags:             &logisticsdb.DistanceMatrixMapsTags{},
+				DepartureTimeBuckets: buckets,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(matrix.Distances) != len(locIDs)*len(locIDs) {
+				t.Fatalf("returned matrix size is not correct: %d", len(matrix.Distances))
+			}
+
+			if !tc.WantBuckets {
+				if matrix.DepartureTimeBucketDurationSec != nil {
+					t.Fatalf("unexpected departure time buckets: %+v", matrix)
+				}
+				return
+			}
+
+			if matrix.GetDepartureTimeBucketDurationSec() != int64(time.Hour.Seconds()) {
+				t.Fatalf("bad departure time bucket duration: %+v", matrix)
+			}
+			for _, distance := range matrix.Distances {
+				if distance.GetDurationSec() != int64(preloadedDurationSec) {
+					t.Fatalf("time independent distance should be preloaded: %+v", distance)
+				}
+
+				if tc.WantNoBucketDistance {
+					if len(distance.DepartureTimeBuckets) != 0 {
+						t.Fatalf("unexpected departure time bucket distances: %+v", distance)
+					}
+					continue
+				}
+				if len(distance.DepartureTimeBuckets) != len(buckets.StartTimes) {
+					t.Fatalf("missing departure time buckets: %+v", distance)
+				}
+				for i, bucket := range distance.DepartureTimeBuckets {
+					startTime := buckets.StartTimes[i]
+					if bucket.GetDepartureBucketStartTimestampSec() != startTime.Unix() {
+						t.Fatalf("bad departure bucket start: %+v", bucket)
+					}
+
+					if bucket.GetDurationSec() != int64(startTime.Hour()) {
+						t.Fatalf("bad departure bucket duration: %+v", bucket)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGetDistanceMatrixFallbackMapService(t *testing.T) {
+	ctx, db, queries, done := setupDBTest(t)
+	defer done()
+
+	afterCreatedAt := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	primarySourceID := time.Now().UnixNano()
+	fallbackSourceID := primarySourceID + 1
+	ldb := logisticsdb.NewLogisticsDB(db, nil, mockSettingsService, nil)
+
+	locIDs := addLocationIDs(ctx, t, queries, 3)
+	reqs := []logisticsdb.DistanceMatrixRequest{
+		&logisticsdb.RectDistancesReq{FromLocationIDs: locIDs, ToLocationIDs: locIDs},
+	}
+
+	primary := &mockDistanceMatrix{distanceSourceID: primarySourceID, err: errors.New("primary is down")}
+	fallback := &mockDistanceMatrix{distanceSourceID: fallbackSourceID}
+	fallbackMapService := logistics.NewFallbackMapService(
+		[]logistics.MapService{primary, fallback},
+		[]*logistics.CircuitBreaker{
+			logistics.NewCircuitBreaker(logistics.DefaultCircuitBreakerConfig),
+			logistics.NewCircuitBreaker(logistics.DefaultCircuitBreakerConfig),
+		},
+		monitoring.NewMockScope(),
+	)
+
+	matrix, _, err := ldb.GetDistanceMatrix(ctx, logisticsdb.GetDistanceMatrixParams{
+		Reqs:           reqs,
+		AfterCreatedAt: afterCreatedAt,
+		MapService:     fallbackMapService,
+		MapsTags:       &logisticsdb.DistanceMatrixMapsTags{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matrix.Distances) != len(locIDs)*len(locIDs) {
+		t.Fatalf("returned matrix size is not correct: %d", len(matrix.Distances))
+	}
+
+	// Distances are recorded with the fallback distance source ID, so they are found without fetching again.
+	_, _, err = ldb.GetDistanceMatrix(ctx, logisticsdb.GetDistanceMatrixParams{
+		Reqs:           reqs,
+		AfterCreatedAt: afterCreatedAt,
+		MapService:     &mockDistanceMatrix{distanceSourceID: fallbackSourceID, err: errors.New("should not fetch")},
+		MapsTags:       &logisticsdb.DistanceMatrixMapsTags{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,8 +2,12 @@ package logisticsdb
 
 import (
 	"testing"
+	"time"
 
+	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
 	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestBuildDistanceMatrixReqs(t *testing.T) {
@@ -63,3 +67,153 @@ func TestBuildDistanceMatrixReqs(t *testing.T) {
 		})
 	}
 }
+
+func TestNewDepartureTimeBuckets(t *testing.T) {
+	start := time.Date(2023, time.September, 5, 8, 30, 0, 0, time.UTC)
+
+	tcs := []struct {
+		desc           string
+		tw             TimeWindow
+		bucketDuration time.Duration
+		now            time.Time
+
+		want *DepartureTimeBuckets
+	}{
+		{
+			desc:           "aligned hourly buckets covering time window",
+			tw:             TimeWindow{Start: start, End: start.Add(2 * time.Hour)},
+			bucketDuration: time.Hour,
+			now:            start.Add(-time.Hour),
+
+			want: &DepartureTimeBuckets{
+				BucketDuration: time.Hour,
+				StartTimes: []time.Time{
+					time.Date(2023, time.September, 5, 8, 0, 0, 0, time.UTC),
+					time.Date(2023, time.September, 5, 9, 0, 0, 0, time.UTC),
+					time.Date(2023, time.September, 5, 10, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			desc:           "skips buckets that ended",
+			tw:             TimeWindow{Start: start, End: start.Add(2 * time.Hour)},
+			bucketDuration: time.Hour,
+			now:            start.Add(time.Hour),
+
+			want: &DepartureTimeBuckets{
+				BucketDuration: time.Hour,
+				StartTimes: []time.Time{
+					time.Date(2023, time.September, 5, 9, 0, 0, 0, time.UTC),
+					time.Date(2023, time.September, 5, 10, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			desc:           "time window has passed",
+			tw:             TimeWindow{Start: start, End: start.Add(2 * time.Hour)},
+			bucketDuration: time.Hour,
+			now:            start.Add(3 * time.Hour),
+		},
+		{
+			desc:           "no bucket duration",
+			tw:             TimeWindow{Start: start, End: start.Add(2 * time.Hour)},
+			bucketDuration: 0,
+		},
+		{
+			desc:           "empty time window",
+			tw:             TimeWindow{Start: start, End: start},
+			bucketDuration: time.Hour,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.desc, func(t *testing.T) {
+			testutils.MustMatch(t, tc.want, NewDepartureTimeBuckets(tc.tw, tc.bucketDuration, tc.now))
+		})
+	}
+}
+
+func TestMapServiceDepartureTime(t *testing.T) {
+	now := time.Date(2023, time.September, 5, 8, 30, 0, 0, time.UTC)
+
+	tcs := []struct {
+		desc        string
+		bucketStart time.Time
+
+		want time.Time
+	}{
+		{
+			desc:        "future bucket",
+			bucketStart: time.Date(2023, time.September, 5, 9, 0, 0, 0, time.UTC),
+
+			want: time.Date(2023, time.September, 5, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:        "bucket in progress",
+			bucketStart: time.Date(2023, time.September, 5, 8, 0, 0, 0, time.UTC),
+
+			want: now.Add(minDepartureTimeLead),
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.desc, func(t *testing.T) {
+			testutils.MustMatch(t, tc.want, mapServiceDepartureTime(tc.bucketStart, now))
+		})
+	}
+}
+
+func TestAddDepartureTimeBucketsToDistanceMatrix(t *testing.T) {
+	bucketStart := time.Date(2023, time.September, 5, 8, 0, 0, 0, time.UTC)
+	buckets := &DepartureTimeBuckets{
+		BucketDuration: time.Hour,
+		StartTimes:     []time.Time{bucketStart, bucketStart.Add(time.Hour)},
+	}
+	matrix := &optimizerpb.VRPDistanceMatrix{
+		Distances: []*optimizerpb.VRPDistance{
+			{
+				FromLocationId: proto.Int64(1),
+				ToLocationId:   proto.Int64(2),
+				LengthMeters:   proto.Int64(1000),
+				DurationSec:    proto.Int64(60),
+			},
+		},
+	}
+
+	addDepartureTimeBucketsToDistanceMatrix(matrix, buckets, [][]*logisticssql.BatchGetLatestDistancesForLocationsRow{
+		{
+			{FromLocationID: 1, ToLocationID: 2, DistanceMeters: 1100, DurationSeconds: 120},
+			// Older duplicate distance is ignored.
+			{FromLocationID: 1, ToLocationID: 2, DistanceMeters: 1, DurationSeconds: 1},
+			// Distance not in the matrix is ignored.
+			{FromLocationID: 2, ToLocationID: 1, DistanceMeters: 1, DurationSeconds: 1},
+		},
+		{
+			{FromLocationID: 1, ToLocationID: 2, DistanceMeters: 1000, DurationSeconds: 90},
+		},
+	})
+
+	testutils.MustMatch(t, &optimizerpb.VRPDistanceMatrix{
+		Distances: []*optimizerpb.VRPDistance{
+			{
+				FromLocationId: proto.Int64(1),
+				ToLocationId:   proto.Int64(2),
+				LengthMeters:   proto.Int64(1000),
+				DurationSec:    proto.Int64(60),
+				DepartureTimeBuckets: []*optimizerpb.VRPTimeDependentDistance{
+					{
+						DepartureBucketStartTimestampSec: proto.Int64(bucketStart.Unix()),
+						LengthMeters:                     proto.Int64(1100),
+						DurationSec:                      proto.Int64(120),
+					},
+					{
+						DepartureBucketStartTimestampSec: proto.Int64(bucketStart.Add(time.Hour).Unix()),
+						LengthMeters:                     proto.Int64(1000),
+						DurationSec:                      proto.Int64(90),
+					},
+				},
+			},
+		},
+		DepartureTimeBucketDurationSec: proto.Int64(3600),
+	}, matrix)
+}
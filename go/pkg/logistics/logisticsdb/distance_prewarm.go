@@ -0,0 +1,142 @@
+package logisticsdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/collections"
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/jackc/pgx/v4"
+)
+
+type PrewarmDistancesParams struct {
+	ServiceRegionID int64
+	ServiceDate     time.Time
+	SnapshotTime    time.Time
+
+	// Distances created after this time are considered fresh, and are not fetched again.
+	AfterCreatedAt time.Time
+	// Maximum number of distance matrix elements to prewarm. 0 means no limit.
+	MaxElements int64
+}
+
+type PrewarmDistancesResult struct {
+	NumLocations int
+	NumElements  int
+	// Whether some locations were left out to respect MaxElements.
+	Truncated bool
+}
+
+// PrewarmServiceRegionDistances fetches and stores any missing or stale distances between
+// the canonical locations and the active shift team locations of a service region,
+// so that they are already in the DB when the optimizer or feasibility checks need them.
+func (ldb *LogisticsDB) PrewarmServiceRegionDistances(ctx context.Context, params PrewarmDistancesParams) (*PrewarmDistancesResult, error) {
+	settings, err := ldb.settingsService.ServiceRegionSettings(ctx, params.ServiceRegionID)
+	if err != nil {
+		return nil, err
+	}
+
+	canonicalLocations, err := ldb.GetServiceRegionCanonicalLocations(ctx, params.ServiceRegionID)
+	if err != nil {
+		return nil, err
+	}
+
+	shiftTeamLocIDs, err := ldb.activeShiftTeamLocationIDs(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Shift team locations go first, so that they are kept when truncating to MaxElements.
+	locIDs := collections.NewLinkedInt64Set(len(shiftTeamLocIDs) + len(canonicalLocations))
+	locIDs.Add(shiftTeamLocIDs...)
+	for _, loc := range canonicalLocations {
+		locIDs.Add(loc.ID)
+	}
+
+	req, truncated := prewarmDistancesReq(locIDs.Elems(), params.MaxElements)
+	result := &PrewarmDistancesResult{
+		NumLocations: locIDs.Size(),
+		NumElements:  len(req.FromLocationIDs) * len(req.ToLocationIDs),
+		Truncated:    truncated,
+	}
+	if req.IsEmpty() {
+		return result, nil
+	}
+
+	mapService, err := ldb.mapServicePicker.MapServiceForRegion(ctx, params.ServiceRegionID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, err = ldb.GetDistanceMatrix(ctx, GetDistanceMatrixParams{
+		Reqs:           []DistanceMatrixRequest{req},
+		AfterCreatedAt: params.AfterCreatedAt,
+		MapService:     mapService,
+		MapsTags: &DistanceMatrixMapsTags{
+			ServiceRegionID: params.ServiceRegionID,
+			ServiceDate:     params.ServiceDate,
+			Use:             distanceMatrixUseTagPrewarm,
+		},
+		Settings: *settings,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error prewarming distances: %w", err)
+	}
+
+	return result, nil
+}
+
+// activeShiftTeamLocationIDs returns the base and latest reported location IDs
+// of the shift teams working on the service date.
+func (ldb *LogisticsDB) activeShiftTeamLocationIDs(ctx context.Context, params PrewarmDistancesParams) ([]int64, error) {
+	openHoursTW, _, err := ldb.GetServiceRegionOpenHoursForDate(ctx, GetServiceRegionOpenHoursForDateParams{
+		ServiceRegionID: params.ServiceRegionID,
+		Date:            params.ServiceDate,
+		SnapshotTime:    params.SnapshotTime,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error in GetServiceRegionOpenHoursForDate: %w", err)
+	}
+
+	shiftTeams, err := ldb.GetLatestShiftTeamSnapshotsInRegion(ctx, params.ServiceRegionID, params.SnapshotTime, openHoursTW.Start, openHoursTW.End)
+	if err != nil {
+		return nil, err
+	}
+
+	locIDs := collections.NewLinkedInt64Set(2 * len(shiftTeams))
+	for _, shiftTeam := range shiftTeams {
+		locIDs.Add(shiftTeamStartLocationID(shiftTeam), shiftTeamEndLocationID(shiftTeam))
+
+		shiftTeamLocation, err := ldb.queries.GetLatestShiftTeamLocation(ctx, logisticssql.GetLatestShiftTeamLocationParams{
+			ShiftTeamSnapshotID: shiftTeam.ID,
+			CreatedAt:           params.SnapshotTime,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue
+			}
+			return nil, fmt.Errorf("error in GetLatestShiftTeamLocation: %w", err)
+		}
+		locIDs.Add(shiftTeamLocation.LocationID)
+	}
+
+	return locIDs.Elems(), nil
+}
+
+// prewarmDistancesReq returns the square distances request between locIDs,
+// dropping origins from the end of locIDs to stay within maxElements.
+func prewarmDistancesReq(locIDs []int64, maxElements int64) (*RectDistancesReq, bool) {
+	fromLocIDs := locIDs
+	toLocIDs := locIDs
+	if maxElements > 0 && int64(len(fromLocIDs)*len(toLocIDs)) > maxElements {
+		if int64(len(toLocIDs)) > maxElements {
+			toLocIDs = toLocIDs[:maxElements]
+		}
+		fromLocIDs = fromLocIDs[:maxElements/int64(len(toLocIDs))]
+		return &RectDistancesReq{FromLocationIDs: fromLocIDs, ToLocationIDs: toLocIDs}, true
+	}
+
+	return &RectDistancesReq{FromLocationIDs: fromLocIDs, ToLocationIDs: toLocIDs}, false
+}
@@ -0,0 +1,69 @@
+package logisticsdb
+
+import (
+	"testing"
+
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+)
+
+func TestPrewarmDistancesReq(t *testing.T) {
+	locIDs := []int64{1, 2, 3, 4, 5}
+
+	tcs := []struct {
+		Desc        string
+		LocIDs      []int64
+		MaxElements int64
+
+		Want          *RectDistancesReq
+		WantTruncated bool
+	}{
+		{
+			Desc:   "no limit",
+			LocIDs: locIDs,
+
+			Want: &RectDistancesReq{FromLocationIDs: locIDs, ToLocationIDs: locIDs},
+		},
+		{
+			Desc:        "within limit",
+			LocIDs:      locIDs,
+			MaxElements: 25,
+
+			Want: &RectDistancesReq{FromLocationIDs: locIDs, ToLocationIDs: locIDs},
+		},
+		{
+			Desc:        "drops origins",
+			LocIDs:      locIDs,
+			MaxElements: 12,
+
+			Want: &RectDistancesReq{
+				FromLocationIDs: []int64{1, 2},
+				ToLocationIDs:   locIDs,
+			},
+			WantTruncated: true,
+		},
+		{
+			Desc:        "drops destinations too",
+			LocIDs:      locIDs,
+			MaxElements: 3,
+
+			Want: &RectDistancesReq{
+				FromLocationIDs: []int64{1},
+				ToLocationIDs:   []int64{1, 2, 3},
+			},
+			WantTruncated: true,
+		},
+		{
+			Desc: "no locations",
+
+			Want: &RectDistancesReq{},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			req, truncated := prewarmDistancesReq(tc.LocIDs, tc.MaxElements)
+			testutils.MustMatch(t, tc.Want, req)
+			testutils.MustMatch(t, tc.WantTruncated, truncated)
+		})
+	}
+}
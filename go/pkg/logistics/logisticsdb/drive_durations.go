@@ -0,0 +1,121 @@
+package logisticsdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/collections"
+	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics"
+)
+
+// LatLngPair is a pair of locations to drive from and to.
+type LatLngPair struct {
+	From logistics.LatLng
+	To   logistics.LatLng
+}
+
+// DriveDurations are the drive durations between pairs of locations.
+type DriveDurations map[LatLngPair]time.Duration
+
+type GetDriveDurationsParams struct {
+	ServiceRegionID int64
+	ServiceDate     time.Time
+	// Locations to insert into the routes, to get the drive durations to and from every route location.
+	Locations []logistics.LatLng
+	// Routes to get the drive durations between consecutive locations of.
+	Routes [][]logistics.LatLng
+	Now    time.Time
+}
+
+// GetDriveDurations returns the drive durations between the locations and the route locations, and
+// between consecutive route locations, fetching the missing or stale ones from the map service of the service region.
+func (ldb *LogisticsDB) GetDriveDurations(ctx context.Context, params GetDriveDurationsParams) (DriveDurations, error) {
+	if len(params.Locations) == 0 && len(params.Routes) == 0 {
+		return DriveDurations{}, nil
+	}
+
+	settings, err := ldb.settingsService.ServiceRegionSettings(ctx, params.ServiceRegionID)
+	if err != nil {
+		return nil, err
+	}
+
+	mapService, err := ldb.mapServicePicker.MapServiceForRegion(ctx, params.ServiceRegionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var latLngs []logistics.LatLng
+	latLngs = append(latLngs, params.Locations...)
+	for _, route := range params.Routes {
+		latLngs = append(latLngs, route...)
+	}
+	latLngSet := collections.NewLinkedSet[logistics.LatLng](len(latLngs))
+	latLngSet.Add(latLngs...)
+	locs, err := UpsertLocations(ctx, ldb.queries, latLngSet.Elems())
+	if err != nil {
+		return nil, fmt.Errorf("error upserting locations: %w", err)
+	}
+	locIDs := make(map[logistics.LatLng]int64, len(locs))
+	for _, loc := range locs {
+		locIDs[logistics.LatLng{LatE6: loc.LatitudeE6, LngE6: loc.LongitudeE6}] = loc.ID
+	}
+	toLocIDs := func(latLngs []logistics.LatLng) []int64 {
+		res := make([]int64, len(latLngs))
+		for i, latLng := range latLngs {
+			res[i] = locIDs[latLng]
+		}
+		return res
+	}
+
+	locationIDs := toLocIDs(params.Locations)
+	routeLocationIDs := toLocIDs(latLngs[len(params.Locations):])
+	reqs := []DistanceMatrixRequest{
+		&RectDistancesReq{FromLocationIDs: locationIDs, ToLocationIDs: routeLocationIDs},
+		&RectDistancesReq{FromLocationIDs: routeLocationIDs, ToLocationIDs: locationIDs},
+	}
+	for _, route := range params.Routes {
+		reqs = append(reqs, PathDistancesReq(toLocIDs(route)))
+	}
+
+	matrix, _, err := ldb.GetDistanceMatrix(ctx, GetDistanceMatrixParams{
+		Reqs:           reqs,
+		AfterCreatedAt: params.Now.Add(-time.Duration(settings.DistanceValiditySec) * time.Second),
+		MapService:     mapService,
+		MapsTags: &DistanceMatrixMapsTags{
+			ServiceRegionID: params.ServiceRegionID,
+			ServiceDate:     params.ServiceDate,
+			Use:             distanceMatrixUseTagRecommendations,
+		},
+		Settings: *settings,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting distance matrix: %w", err)
+	}
+
+	return driveDurationsForDistanceMatrix(locs, matrix), nil
+}
+
+// driveDurationsForDistanceMatrix returns the drive durations of the distance matrix between the locations.
+func driveDurationsForDistanceMatrix(locs []*logisticssql.Location, matrix *optimizerpb.VRPDistanceMatrix) DriveDurations {
+	latLngs := make(map[int64]logistics.LatLng, len(locs))
+	for _, loc := range locs {
+		latLngs[loc.ID] = logistics.LatLng{LatE6: loc.LatitudeE6, LngE6: loc.LongitudeE6}
+	}
+
+	res := make(DriveDurations, len(matrix.GetDistances()))
+	for _, distance := range matrix.GetDistances() {
+		from, ok := latLngs[distance.GetFromLocationId()]
+		if !ok {
+			continue
+		}
+		to, ok := latLngs[distance.GetToLocationId()]
+		if !ok {
+			continue
+		}
+		res[LatLngPair{From: from, To: to}] = time.Duration(distance.GetDurationSec()) * time.Second
+	}
+	return res
+}
@@ -0,0 +1,31 @@
+package logisticsdb
+
+import (
+	"testing"
+	"time"
+
+	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDriveDurationsForDistanceMatrix(t *testing.T) {
+	locs := []*logisticssql.Location{
+		{ID: 1, LatitudeE6: 10, LongitudeE6: 11},
+		{ID: 2, LatitudeE6: 20, LongitudeE6: 21},
+	}
+	matrix := &optimizerpb.VRPDistanceMatrix{
+		Distances: []*optimizerpb.VRPDistance{
+			{FromLocationId: proto.Int64(1), ToLocationId: proto.Int64(2), DurationSec: proto.Int64(60)},
+			{FromLocationId: proto.Int64(2), ToLocationId: proto.Int64(1), DurationSec: proto.Int64(90)},
+			{FromLocationId: proto.Int64(1), ToLocationId: proto.Int64(3), DurationSec: proto.Int64(120)},
+		},
+	}
+
+	testutils.MustMatch(t, DriveDurations{
+		{From: logistics.LatLng{LatE6: 10, LngE6: 11}, To: logistics.LatLng{LatE6: 20, LngE6: 21}}: time.Minute,
+		{From: logistics.LatLng{LatE6: 20, LngE6: 21}, To: logistics.LatLng{LatE6: 10, LngE6: 11}}: 90 * time.Second,
+	}, driveDurationsForDistanceMatrix(locs, matrix), "distances of unknown locations should be skipped")
+}
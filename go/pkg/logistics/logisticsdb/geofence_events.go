@@ -0,0 +1,354 @@
+package logisticsdb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/monitoring"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	GeofenceEventTypeArrival   = "arrival"
+	GeofenceEventTypeDeparture = "departure"
+
+	// Lookback for visits of a shift team that may still need geofence events.
+	geofenceCandidateVisitsLookback = 12 * time.Hour
+	// Min interval between geofence detections of a shift team, as location updates can arrive every few seconds.
+	// Detection replays the locations since the visits started, so throttled updates are caught up by the next detection,
+	// which runs when the interval ends if no update arrives by then.
+	geofenceDetectionInterval = time.Minute
+
+	geofenceDetectionErrorMetric = "geofence_detection_error"
+	geofenceEventsMetric         = "geofence_events"
+)
+
+var geofenceArrivalVisitPhases = map[VisitPhaseShortName]bool{
+	VisitPhaseTypeShortNameCommitted: true,
+	VisitPhaseTypeShortNameEnRoute:   true,
+	VisitPhaseTypeShortNameOnScene:   true,
+	VisitPhaseTypeShortNameCompleted: true,
+}
+
+// geofenceDetectionThrottle limits geofence detections to one per shift team per interval.
+type geofenceDetectionThrottle struct {
+	interval time.Duration
+
+	mu            sync.Mutex
+	lastDetection map[int64]time.Time
+	// Retries of throttled detections, by shift team.
+	pending map[int64]*time.Timer
+}
+
+func newGeofenceDetectionThrottle(interval time.Duration) *geofenceDetectionThrottle {
+	return &geofenceDetectionThrottle{
+		interval:      interval,
+		lastDetection: map[int64]time.Time{},
+		pending:       map[int64]*time.Timer{},
+	}
+}
+
+// allow returns whether geofence detection should run for the shift team at the given time, and records it if so.
+// A throttled detection is retried by calling retry with the end of the interval, unless another detection
+// of the shift team is allowed first; throttled detections within an interval share a single retry.
+// A nil throttle allows every detection.
+func (t *geofenceDetectionThrottle) allow(shiftTeamID int64, now time.Time, retry func(at time.Time)) bool {
+	if t == nil {
+		return true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lastDetection, ok := t.lastDetection[shiftTeamID]
+	if ok && now.Before(lastDetection.Add(t.interval)) {
+		if _, pending := t.pending[shiftTeamID]; !pending && retry != nil {
+			at := lastDetection.Add(t.interval)
+			var timer *time.Timer
+			timer = time.AfterFunc(at.Sub(now), func() {
+				t.mu.Lock()
+				current := t.pending[shiftTeamID] == timer
+				if current {
+					delete(t.pending, shiftTeamID)
+				}
+				t.mu.Unlock()
+
+				if current {
+					retry(at)
+				}
+			})
+			t.pending[shiftTeamID] = timer
+		}
+		return false
+	}
+	if timer, pending := t.pending[shiftTeamID]; pending {
+		timer.Stop()
+		delete(t.pending, shiftTeamID)
+	}
+	if !ok {
+		// Shift teams are added through the day, so forget the ones that stopped sending locations.
+		for id, detection := range t.lastDetection {
+			if now.Sub(detection) >= t.interval {
+				delete(t.lastDetection, id)
+			}
+		}
+	}
+	t.lastDetection[shiftTeamID] = now
+	return true
+}
+
+// detectPendingShiftTeamGeofenceEvents runs a throttled geofence detection of the shift team once its interval ended.
+func (ldb *LogisticsDB) detectPendingShiftTeamGeofenceEvents(serviceRegionID int64, shiftTeamID int64, at time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), geofenceDetectionInterval)
+	defer cancel()
+
+	_, err := ldb.detectShiftTeamGeofenceEvents(ctx, serviceRegionID, shiftTeamID, at)
+	if err != nil {
+		ldb.writeGeofenceDetectionError(shiftTeamID, err)
+	}
+}
+
+func (ldb *LogisticsDB) writeGeofenceDetectionError(shiftTeamID int64, err error) {
+	ldb.scope.WritePoint(geofenceDetectionErrorMetric, nil, monitoring.Fields{"shift_team": shiftTeamID, "error": err.Error()})
+}
+
+// detectShiftTeamGeofenceEvents records the arrivals and departures of the shift team at the visits of its
+// current schedule, inferred from its locations staying within the geofence of the visit locations.
+// Detections are throttled per shift team, returning no events when throttled.
+func (ldb *LogisticsDB) detectShiftTeamGeofenceEvents(ctx context.Context, serviceRegionID int64, shiftTeamID int64, latestSnapshotTime time.Time) ([]*logisticssql.ShiftTeamGeofenceEvent, error) {
+	retry := func(at time.Time) {
+		ldb.detectPendingShiftTeamGeofenceEvents(serviceRegionID, shiftTeamID, at)
+	}
+	if !ldb.geofenceThrottle.allow(shiftTeamID, latestSnapshotTime, retry) {
+		return nil, nil
+	}
+
+	settings, err := ldb.settingsService.ServiceRegionSettings(ctx, serviceRegionID)
+	if err != nil {
+		return nil, err
+	}
+	if settings.GeofenceRadiusMeters <= 0 {
+		return nil, nil
+	}
+
+	scheduledCareRequestIDs, err := ldb.scheduledShiftTeamCareRequestIDs(ctx, shiftTeamID, latestSnapshotTime)
+	if err != nil {
+		return nil, err
+	}
+	if len(scheduledCareRequestIDs) == 0 {
+		return nil, nil
+	}
+
+	allCandidates, err := ldb.queries.GetGeofenceCandidateVisitsForShiftTeam(ctx, logisticssql.GetGeofenceCandidateVisitsForShiftTeamParams{
+		ShiftTeamID:        shiftTeamID,
+		StatusCreatedAfter: latestSnapshotTime.Add(-geofenceCandidateVisitsLookback),
+		LatestSnapshotTime: latestSnapshotTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Visits reassigned to other shift teams keep phase snapshots of this one, so only the scheduled ones are candidates.
+	var candidates []*logisticssql.GetGeofenceCandidateVisitsForShiftTeamRow
+	for _, candidate := range allCandidates {
+		if scheduledCareRequestIDs[candidate.CareRequestID] {
+			candidates = append(candidates, candidate)
+		}
+	}
+	var careRequestIDs, locationIDs []int64
+	var startTime time.Time
+	for _, candidate := range candidates {
+		if !geofenceArrivalVisitPhases[VisitPhaseShortName(candidate.VisitPhaseShortName)] {
+			continue
+		}
+		careRequestIDs = append(careRequestIDs, candidate.CareRequestID)
+		locationIDs = append(locationIDs, candidate.LocationID)
+		if startTime.IsZero() || candidate.FirstStatusCreatedAt.Before(startTime) {
+			startTime = candidate.FirstStatusCreatedAt
+		}
+	}
+	if len(careRequestIDs) == 0 {
+		return nil, nil
+	}
+
+	existingEvents, err := ldb.queries.GetLatestShiftTeamGeofenceEvents(ctx, logisticssql.GetLatestShiftTeamGeofenceEventsParams{
+		CareRequestIds:     careRequestIDs,
+		LatestSnapshotTime: latestSnapshotTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+	existingEventTypes := map[int64]map[string]bool{}
+	for _, event := range existingEvents {
+		if existingEventTypes[event.CareRequestID] == nil {
+			existingEventTypes[event.CareRequestID] = map[string]bool{}
+		}
+		existingEventTypes[event.CareRequestID][event.EventType] = true
+	}
+
+	locations, err := ldb.queries.GetLocationsByIDs(ctx, locationIDs)
+	if err != nil {
+		return nil, err
+	}
+	locationsByID := make(map[int64]*logisticssql.Location, len(locations))
+	for _, location := range locations {
+		locationsByID[location.ID] = location
+	}
+
+	shiftTeamLocationRows, err := ldb.queries.GetShiftTeamLocationsInTimeRange(ctx, logisticssql.GetShiftTeamLocationsInTimeRangeParams{
+		ShiftTeamID: shiftTeamID,
+		StartTime:   startTime,
+		EndTime:     latestSnapshotTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+	shiftTeamLocations := make([]logistics.TimedLatLng, len(shiftTeamLocationRows))
+	for i, row := range shiftTeamLocationRows {
+		shiftTeamLocations[i] = logistics.TimedLatLng{
+			LatLng:    logistics.LatLng{LatE6: row.LatitudeE6, LngE6: row.LongitudeE6},
+			Timestamp: row.CreatedAt,
+		}
+	}
+
+	minDwell := time.Duration(settings.GeofenceMinDwellSec) * time.Second
+	var events []*logisticssql.ShiftTeamGeofenceEvent
+	for _, candidate := range candidates {
+		eventTypes := existingEventTypes[candidate.CareRequestID]
+		location, ok := locationsByID[candidate.LocationID]
+		if !ok || eventTypes[GeofenceEventTypeDeparture] ||
+			!geofenceArrivalVisitPhases[VisitPhaseShortName(candidate.VisitPhaseShortName)] {
+			continue
+		}
+
+		geofence := logistics.Geofence{
+			Center:       logistics.LatLng{LatE6: location.LatitudeE6, LngE6: location.LongitudeE6},
+			RadiusMeters: float64(settings.GeofenceRadiusMeters),
+		}
+		dwell := logistics.FirstGeofenceDwell(shiftTeamLocationsSince(shiftTeamLocations, candidate.FirstStatusCreatedAt), geofence, minDwell)
+		if dwell == nil {
+			continue
+		}
+
+		var newEvents []logisticssql.AddShiftTeamGeofenceEventParams
+		if !eventTypes[GeofenceEventTypeArrival] {
+			newEvents = append(newEvents, logisticssql.AddShiftTeamGeofenceEventParams{
+				ShiftTeamID:    shiftTeamID,
+				CareRequestID:  candidate.CareRequestID,
+				EventType:      GeofenceEventTypeArrival,
+				EventTimestamp: dwell.Arrival,
+			})
+		}
+		if !dwell.Departure.IsZero() {
+			newEvents = append(newEvents, logisticssql.AddShiftTeamGeofenceEventParams{
+				ShiftTeamID:    shiftTeamID,
+				CareRequestID:  candidate.CareRequestID,
+				EventType:      GeofenceEventTypeDeparture,
+				EventTimestamp: dwell.Departure,
+			})
+		}
+		for _, params := range newEvents {
+			event, err := ldb.queries.AddShiftTeamGeofenceEvent(ctx, params)
+			if err != nil {
+				return nil, err
+			}
+			ldb.scope.WritePoint(geofenceEventsMetric,
+				monitoring.Tags{
+					"event_type": event.EventType,
+					phaseTypeTag: candidate.VisitPhaseShortName,
+				},
+				monitoring.Fields{
+					careRequestField: event.CareRequestID,
+					"shift_team":     event.ShiftTeamID,
+				})
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+// scheduledShiftTeamCareRequestIDs returns the care requests of the visits in the latest schedule route of the shift team.
+func (ldb *LogisticsDB) scheduledShiftTeamCareRequestIDs(ctx context.Context, shiftTeamID int64, latestSnapshotTime time.Time) (map[int64]bool, error) {
+	route, err := ldb.queries.GetLatestScheduleRouteForShiftTeamID(ctx, logisticssql.GetLatestScheduleRouteForShiftTeamIDParams{
+		ShiftTeamID:               shiftTeamID,
+		LatestScheduleTimestamp:   latestSnapshotTime,
+		EarliestScheduleTimestamp: latestSnapshotTime.Add(-geofenceCandidateVisitsLookback),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	careRequestIDs, err := ldb.queries.GetScheduleRouteCareRequestIDs(ctx, route.RouteID)
+	if err != nil {
+		return nil, err
+	}
+	scheduled := make(map[int64]bool, len(careRequestIDs))
+	for _, careRequestID := range careRequestIDs {
+		scheduled[careRequestID] = true
+	}
+	return scheduled, nil
+}
+
+func shiftTeamLocationsSince(locations []logistics.TimedLatLng, since time.Time) []logistics.TimedLatLng {
+	for i, location := range locations {
+		if !location.Timestamp.Before(since) {
+			return locations[i:]
+		}
+	}
+	return nil
+}
+
+// geofenceActuals merges arrivals and departures inferred from geofence events into
+// the Station actuals, when the Station timestamps are missing or later than the inferred ones.
+type geofenceActuals struct {
+	careRequestToCurrentVisitPhase map[CareRequestID]VisitPhaseShortName
+	stationShiftTeamIDs            map[CareRequestID]ShiftTeamID
+
+	arrivedAtByCareReqID   map[CareRequestID]time.Time
+	completedAtByCareReqID map[CareRequestID]time.Time
+	shiftTeamActuals       *ShiftTeamActuals
+}
+
+func (g *geofenceActuals) merge(events []*logisticssql.ShiftTeamGeofenceEvent) {
+	for _, event := range events {
+		careRequestID := CareRequestID(event.CareRequestID)
+		shiftTeamID := ShiftTeamID(event.ShiftTeamID)
+		if stationShiftTeamID, ok := g.stationShiftTeamIDs[careRequestID]; ok && stationShiftTeamID != shiftTeamID {
+			continue
+		}
+		pair := EntityIDPair{ShiftTeamID: shiftTeamID, CareRequestID: careRequestID}
+
+		phase := g.careRequestToCurrentVisitPhase[careRequestID]
+		switch event.EventType {
+		case GeofenceEventTypeArrival:
+			// Same trust as Station arrivals: only for care requests that are currently on scene or completed.
+			if phase != VisitPhaseTypeShortNameOnScene && phase != VisitPhaseTypeShortNameCompleted {
+				continue
+			}
+			if arrivedAt, ok := g.arrivedAtByCareReqID[careRequestID]; ok && !event.EventTimestamp.Before(arrivedAt) {
+				continue
+			}
+			g.arrivedAtByCareReqID[careRequestID] = event.EventTimestamp
+			g.shiftTeamActuals.AddArrival(pair, event.EventTimestamp)
+		case GeofenceEventTypeDeparture:
+			if phase != VisitPhaseTypeShortNameCompleted {
+				continue
+			}
+			if completedAt, ok := g.completedAtByCareReqID[careRequestID]; ok && !event.EventTimestamp.Before(completedAt) {
+				continue
+			}
+			if arrivedAt, ok := g.arrivedAtByCareReqID[careRequestID]; ok && event.EventTimestamp.Before(arrivedAt) {
+				continue
+			}
+			g.completedAtByCareReqID[careRequestID] = event.EventTimestamp
+			g.shiftTeamActuals.AddCompletion(pair, event.EventTimestamp)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package logisticsdb
+
+import (
+	"testing"
+	"time"
+
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+)
+
+func TestGeofenceActualsMerge(t *testing.T) {
+	now := time.Now()
+	shiftTeamID := ShiftTeamID(1)
+	otherShiftTeamID := ShiftTeamID(2)
+	careRequestID := CareRequestID(3)
+	event := func(eventType string, ts time.Time) *logisticssql.ShiftTeamGeofenceEvent {
+		return &logisticssql.ShiftTeamGeofenceEvent{
+			ShiftTeamID:    int64(shiftTeamID),
+			CareRequestID:  int64(careRequestID),
+			EventType:      eventType,
+			EventTimestamp: ts,
+		}
+	}
+
+	tcs := []struct {
+		Desc                string
+		Phase               VisitPhaseShortName
+		StationShiftTeamID  *ShiftTeamID
+		StationArrivedAt    *time.Time
+		StationCompletedAt  *time.Time
+		Events              []*logisticssql.ShiftTeamGeofenceEvent
+		ExpectedArrivedAt   *time.Time
+		ExpectedCompletedAt *time.Time
+	}{
+		{
+			Desc:  "missing station arrival",
+			Phase: VisitPhaseTypeShortNameOnScene,
+			Events: []*logisticssql.ShiftTeamGeofenceEvent{
+				event(GeofenceEventTypeArrival, now),
+			},
+
+			ExpectedArrivedAt: &now,
+		},
+		{
+			Desc:               "late station timestamps",
+			Phase:              VisitPhaseTypeShortNameCompleted,
+			StationShiftTeamID: &shiftTeamID,
+			StationArrivedAt:   timePtr(now.Add(10 * time.Minute)),
+			StationCompletedAt: timePtr(now.Add(50 * time.Minute)),
+			Events: []*logisticssql.ShiftTeamGeofenceEvent{
+				event(GeofenceEventTypeArrival, now),
+				event(GeofenceEventTypeDeparture, now.Add(40*time.Minute)),
+			},
+
+			ExpectedArrivedAt:   &now,
+			ExpectedCompletedAt: timePtr(now.Add(40 * time.Minute)),
+		},
+		{
+			Desc:               "earlier station timestamps",
+			Phase:              VisitPhaseTypeShortNameCompleted,
+			StationShiftTeamID: &shiftTeamID,
+			StationArrivedAt:   timePtr(now.Add(-10 * time.Minute)),
+			StationCompletedAt: timePtr(now.Add(20 * time.Minute)),
+			Events: []*logisticssql.ShiftTeamGeofenceEvent{
+				event(GeofenceEventTypeArrival, now),
+				event(GeofenceEventTypeDeparture, now.Add(40*time.Minute)),
+			},
+
+			ExpectedArrivedAt:   timePtr(now.Add(-10 * time.Minute)),
+			ExpectedCompletedAt: timePtr(now.Add(20 * time.Minute)),
+		},
+		{
+			Desc:  "departure is not trusted for on scene care requests",
+			Phase: VisitPhaseTypeShortNameOnScene,
+			Events: []*logisticssql.ShiftTeamGeofenceEvent{
+				event(GeofenceEventTypeArrival, now),
+				event(GeofenceEventTypeDeparture, now.Add(40*time.Minute)),
+			},
+
+			ExpectedArrivedAt: &now,
+		},
+		{
+			Desc:  "events are not trusted for en route care requests",
+			Phase: VisitPhaseTypeShortNameEnRoute,
+			Events: []*logisticssql.ShiftTeamGeofenceEvent{
+				event(GeofenceEventTypeArrival, now),
+			},
+		},
+		{
+			Desc:               "events of another shift team are ignored",
+			Phase:              VisitPhaseTypeShortNameOnScene,
+			StationShiftTeamID: &otherShiftTeamID,
+			StationArrivedAt:   timePtr(now.Add(10 * time.Minute)),
+			Events: []*logisticssql.ShiftTeamGeofenceEvent{
+				event(GeofenceEventTypeArrival, now),
+			},
+
+			ExpectedArrivedAt: timePtr(now.Add(10 * time.Minute)),
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			g := &geofenceActuals{
+				careRequestToCurrentVisitPhase: map[CareRequestID]VisitPhaseShortName{careRequestID: tc.Phase},
+				stationShiftTeamIDs:            map[CareRequestID]ShiftTeamID{},
+				arrivedAtByCareReqID:           map[CareRequestID]time.Time{},
+				completedAtByCareReqID:         map[CareRequestID]time.Time{},
+				shiftTeamActuals:               NewShiftTeamActuals(),
+			}
+			if tc.StationShiftTeamID != nil {
+				g.stationShiftTeamIDs[careRequestID] = *tc.StationShiftTeamID
+			}
+			if tc.StationArrivedAt != nil {
+				g.arrivedAtByCareReqID[careRequestID] = *tc.StationArrivedAt
+			}
+			if tc.StationCompletedAt != nil {
+				g.completedAtByCareReqID[careRequestID] = *tc.StationCompletedAt
+			}
+
+			g.merge(tc.Events)
+
+			arrivedAt, ok := g.arrivedAtByCareReqID[careRequestID]
+			testutils.MustMatch(t, tc.ExpectedArrivedAt != nil, ok, "arrival")
+			if ok {
+				testutils.MustMatch(t, *tc.ExpectedArrivedAt, arrivedAt, "arrival")
+			}
+			completedAt, ok := g.completedAtByCareReqID[careRequestID]
+			testutils.MustMatch(t, tc.ExpectedCompletedAt != nil, ok, "completion")
+			if ok {
+				testutils.MustMatch(t, *tc.ExpectedCompletedAt, completedAt, "completion")
+			}
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestGeofenceDetectionThrottleAllow(t *testing.T) {
+	now := time.Now()
+	interval := geofenceDetectionInterval
+	shiftTeamID := int64(1)
+	otherShiftTeamID := int64(2)
+
+	throttle := newGeofenceDetectionThrottle(interval)
+	testutils.MustMatch(t, true, throttle.allow(shiftTeamID, now, nil), "first detection")
+	testutils.MustMatch(t, false, throttle.allow(shiftTeamID, now.Add(interval/2), nil), "detection within the interval")
+	testutils.MustMatch(t, true, throttle.allow(otherShiftTeamID, now.Add(interval/2), nil), "detection of another shift team")
+	testutils.MustMatch(t, true, throttle.allow(shiftTeamID, now.Add(interval), nil), "detection after the interval")
+
+	testutils.MustMatch(t, true, throttle.allow(3, now.Add(2*interval), nil), "new shift team")
+	testutils.MustMatch(t, map[int64]time.Time{3: now.Add(2 * interval)}, throttle.lastDetection, "stale shift teams should be forgotten")
+
+	var nilThrottle *geofenceDetectionThrottle
+	testutils.MustMatch(t, true, nilThrottle.allow(shiftTeamID, now, nil), "nil throttle")
+}
+
+func TestGeofenceDetectionThrottleRetry(t *testing.T) {
+	interval := 100 * time.Millisecond
+	shiftTeamID := int64(1)
+	retries := make(chan time.Time, 2)
+	retry := func(at time.Time) {
+		retries <- at
+	}
+	expectNoRetry := func(msg string) {
+		select {
+		case <-retries:
+			t.Fatal(msg)
+		case <-time.After(2 * interval):
+		}
+	}
+
+	throttle := newGeofenceDetectionThrottle(interval)
+	now := time.Now()
+	testutils.MustMatch(t, true, throttle.allow(shiftTeamID, now, retry), "first detection")
+	testutils.MustMatch(t, false, throttle.allow(shiftTeamID, now.Add(interval/4), retry), "throttled detection")
+	testutils.MustMatch(t, false, throttle.allow(shiftTeamID, now.Add(interval/2), retry), "throttled detection sharing the retry")
+
+	select {
+	case at := <-retries:
+		testutils.MustMatch(t, now.Add(interval), at, "retry at the end of the interval")
+	case <-time.After(10 * interval):
+		t.Fatal("throttled detection was not retried")
+	}
+	testutils.MustMatch(t, true, throttle.allow(shiftTeamID, now.Add(interval), retry), "retried detection")
+	expectNoRetry("throttled detections should be retried once")
+
+	now = time.Now()
+	testutils.MustMatch(t, true, throttle.allow(shiftTeamID, now, retry), "detection")
+	testutils.MustMatch(t, false, throttle.allow(shiftTeamID, now.Add(interval/2), retry), "throttled detection")
+	testutils.MustMatch(t, true, throttle.allow(shiftTeamID, now.Add(interval), retry), "next update after the interval")
+	expectNoRetry("next update should cancel the retry")
+	testutils.MustMatch(t, 0, len(throttle.pending))
+}
@@ -70,16 +70,21 @@ const (
 )
 
 const (
-	OSRMMapsSourceShortName   = "osrm"
-	GoogleMapsSourceShortName = "google_maps"
+	OSRMMapsSourceShortName     = "osrm"
+	GoogleMapsSourceShortName   = "google_maps"
+	ValhallaMapsSourceShortName = "valhalla"
 )
 
 const (
 	serviceRegionTag = "service_region"
 	serviceDateTag   = "service_date"
 
-	distanceMatrixUseTag         = "use"
-	distancematrixUseTagResearch = "research"
+	distanceMatrixUseTag                = "use"
+	distancematrixUseTagResearch        = "research"
+	distanceMatrixUseTagPrewarm         = "prewarm"
+	distanceMatrixUseTagRecommendations = "recommendations"
+	departureTimeBucketTag              = "departure_time_bucket"
+	departureTimeBucketTagLayout        = "15:04"
 
 	dateLayout = "2006-01-02"
 )
@@ -141,6 +146,9 @@ type LogisticsDB struct {
 	QuerySettings    QuerySettings
 
 	queries *logisticssql.Queries
+
+	// Shared by the copies of WithScope.
+	geofenceThrottle *geofenceDetectionThrottle
 }
 
 type QuerySettings struct {
@@ -158,7 +166,8 @@ func NewLogisticsDB(db DBTX, mapServicePicker *logistics.MapServicePicker, setti
 		settingsService:  settingsService,
 		scope:            scope,
 
-		queries: logisticssql.New(mdb),
+		queries:          logisticssql.New(mdb),
+		geofenceThrottle: newGeofenceDetectionThrottle(geofenceDetectionInterval),
 	}
 }
 
@@ -284,6 +293,12 @@ func (ldb *LogisticsDB) writeVisitSnapshotTransactionally(ctx context.Context, c
 				return fmt.Errorf("error upserting visit unwanted attributes: %w", err)
 			}
 		}
+		if prefs := careRequest.AttributePreferences; len(prefs) > 0 {
+			err := addAttributePreferences(ctx, queries, visitSnapshot.ID, prefs)
+			if err != nil {
+				return fmt.Errorf("error adding visit attribute preferences: %w", err)
+			}
+		}
 		return nil
 	})
 	if err != nil {
@@ -469,6 +484,42 @@ func clinicalUrgencyLevelIDForEnum(enum commonpb.ClinicalUrgencyLevel) (sql.Null
 	return sqltypes.ToValidNullInt64(id), nil
 }
 
+func addAttributePreferences(ctx context.Context, queries *logisticssql.Queries, visitSnapshotID int64, prefs []*commonpb.AttributePreference) error {
+	attributeNames := make([]string, len(prefs))
+	for i, pref := range prefs {
+		attributeNames[i] = pref.GetAttribute().GetName()
+	}
+
+	_, err := queries.UpsertAttributes(ctx, attributeNames)
+	if err != nil {
+		return err
+	}
+
+	attributes, err := queries.GetAttributesForNames(ctx, attributeNames)
+	if err != nil {
+		return err
+	}
+	attributeIDs := make(map[string]int64, len(attributes))
+	for _, attribute := range attributes {
+		attributeIDs[attribute.Name] = attribute.ID
+	}
+
+	params := logisticssql.AddVisitAttributePreferencesParams{
+		VisitSnapshotID: visitSnapshotID,
+		AttributeIds:    make([]int64, len(prefs)),
+		IsUnwanteds:     make([]bool, len(prefs)),
+		PenaltyUsdCents: make([]int64, len(prefs)),
+	}
+	for i, pref := range prefs {
+		params.AttributeIds[i] = attributeIDs[pref.GetAttribute().GetName()]
+		params.IsUnwanteds[i] = pref.GetIsUnwanted()
+		params.PenaltyUsdCents[i] = pref.GetPenaltyUsdCents()
+	}
+
+	_, err = queries.AddVisitAttributePreferences(ctx, params)
+	return err
+}
+
 type attributeType int
 
 const (
@@ -787,9 +838,11 @@ func (ldb *LogisticsDB) CareRequestActualsFromCareRequestIDs(ctx context.Context
 	}
 
 	arrivedAtByCareReqID := map[CareRequestID]time.Time{}
+	stationShiftTeamIDs := map[CareRequestID]ShiftTeamID{}
 	for _, arrivedAt := range arrivedAtSecFromCareRequestIDs {
 		arrivedAtByCareReqID[CareRequestID(arrivedAt.CareRequestID)] = arrivedAt.StatusCreatedAt
 		if arrivedAt.ShiftTeamID.Valid {
+			stationShiftTeamIDs[CareRequestID(arrivedAt.CareRequestID)] = ShiftTeamID(arrivedAt.ShiftTeamID.Int64)
 			shiftTeamActuals.AddArrival(
 				EntityIDPair{
 					ShiftTeamID:   ShiftTeamID(arrivedAt.ShiftTeamID.Int64),
@@ -824,6 +877,7 @@ func (ldb *LogisticsDB) CareRequestActualsFromCareRequestIDs(ctx context.Context
 	for _, completedAt := range completedAtSecFromCareRequestIDs {
 		completedAtByCareReqID[CareRequestID(completedAt.CareRequestID)] = completedAt.StatusCreatedAt
 		if completedAt.ShiftTeamID.Valid {
+			stationShiftTeamIDs[CareRequestID(completedAt.CareRequestID)] = ShiftTeamID(completedAt.ShiftTeamID.Int64)
 			shiftTeamActuals.AddCompletion(
 				EntityIDPair{
 					ShiftTeamID:   ShiftTeamID(completedAt.ShiftTeamID.Int64),
@@ -839,6 +893,22 @@ func (ldb *LogisticsDB) CareRequestActualsFromCareRequestIDs(ctx context.Context
 		}
 	}
 
+	// Arrival and completion times inferred from geofences, when Station timestamps are missing or late:
+	geofenceEvents, err := ldb.queries.GetLatestShiftTeamGeofenceEvents(ctx, logisticssql.GetLatestShiftTeamGeofenceEventsParams{
+		CareRequestIds:     onSceneOrCompletedCareRequestIDs,
+		LatestSnapshotTime: latestSnapshotTime,
+	})
+	if err != nil {
+		return CareRequestActuals{}, err
+	}
+	(&geofenceActuals{
+		careRequestToCurrentVisitPhase: careRequestToCurrentVisitPhase,
+		stationShiftTeamIDs:            stationShiftTeamIDs,
+		arrivedAtByCareReqID:           arrivedAtByCareReqID,
+		completedAtByCareReqID:         completedAtByCareReqID,
+		shiftTeamActuals:               shiftTeamActuals,
+	}).merge(geofenceEvents)
+
 	// EnRoute start Times:
 	enRouteSecFromCareRequestIDs, err := ldb.queries.GetLatestStatusCreatedAtForPhaseTypeID(ctx, logisticssql.GetLatestStatusCreatedAtForPhaseTypeIDParams{
 		CareRequestIds: filterCareRequestIDs(careRequestIDs, func(id int64) bool {
@@ -1329,6 +1399,9 @@ type UnrequestedRestBreakConfig struct {
 	IncludeUnrequestedRestBreaks bool
 	// RestBreakDuration is the duration of such an unrequested rest break.
 	RestBreakDuration time.Duration
+	// RestBreakPolicy, if set, injects the unrequested rest breaks required by the policy
+	// for every shift team instead, regardless of IncludeUnrequestedRestBreaks.
+	RestBreakPolicy *optimizersettings.RestBreakPolicy
 }
 
 func (cfg UnrequestedRestBreakConfig) shouldAddUnrequestedRestBreak(currentTime time.Time, snapshot *logisticssql.ShiftTeamSnapshot) bool {
@@ -1468,8 +1541,12 @@ func (ldb *LogisticsDB) UpsertVisitLocations(ctx context.Context, visits []*logi
 	return nil
 }
 
+// WriteShiftTeamSnapshot writes a snapshot of the shift team.
+//
+// Endpoints override where and when the shift team starts and ends its shift; endpoints of the latest
+// snapshot are kept if nil.
 func (ldb *LogisticsDB) WriteShiftTeamSnapshot(ctx context.Context,
-	shiftTeamID int64, resp *shiftteampb.GetShiftTeamResponse) (*logisticssql.ShiftTeamSnapshot, error) {
+	shiftTeamID int64, resp *shiftteampb.GetShiftTeamResponse, endpoints *logisticspb.ShiftTeamEndpoints) (*logisticssql.ShiftTeamSnapshot, error) {
 	if resp.ShiftTeam == nil {
 		return nil, errors.New("no shift team")
 	}
@@ -1525,16 +1602,24 @@ func (ldb *LogisticsDB) WriteShiftTeamSnapshot(ctx context.Context,
 
 			deletedAt = sql.NullTime{Valid: true, Time: *deletedTime}
 		}
+		snapshotEndpoints, err := shiftTeamSnapshotEndpoints(ctx, queries, shiftTeamID, endpoints)
+		if err != nil {
+			return err
+		}
 
 		shiftTeamSnapshot, err = queries.AddShiftTeamSnapshot(ctx, logisticssql.AddShiftTeamSnapshotParams{
-			ShiftTeamID:       shiftTeamID,
-			ServiceRegionID:   serviceRegion.ID,
-			BaseLocationID:    baseLocation.ID,
-			StartTimestampSec: startTime.Unix(),
-			EndTimestampSec:   endTime.Unix(),
-			DeletedAt:         deletedAt,
-			NumAppMembers:     shiftTeam.GetAdvancedPracticeProviderCount(),
-			NumDhmtMembers:    shiftTeam.Get*company-data-covered*MedicalTechnicianCount(),
+			ShiftTeamID:                   shiftTeamID,
+			ServiceRegionID:               serviceRegion.ID,
+			BaseLocationID:                baseLocation.ID,
+			StartTimestampSec:             startTime.Unix(),
+			EndTimestampSec:               endTime.Unix(),
+			DeletedAt:                     deletedAt,
+			NumAppMembers:                 shiftTeam.GetAdvancedPracticeProviderCount(),
+			NumDhmtMembers:                shiftTeam.Get*company-data-covered*MedicalTechnicianCount(),
+			StartLocationID:               snapshotEndpoints.StartLocationID,
+			EndLocationID:                 snapshotEndpoints.EndLocationID,
+			EarliestDepartureTimestampSec: snapshotEndpoints.EarliestDepartureTimestampSec,
+			ReturnByTimestampSec:          snapshotEndpoints.ReturnByTimestampSec,
 		})
 		if err != nil {
 			return err
@@ -1656,6 +1741,20 @@ func (ldb *LogisticsDB) HasAnyNewInfoInRegionSince(ctx context.Context, params N
 		return true, nil
 	}
 
+	// Service dates chosen for multi-day visits on any date may add or remove visits from the other dates.
+	vals, err = queries.HasAnyCareRequestServiceDatesInRegionSince(ctx, logisticssql.HasAnyCareRequestServiceDatesInRegionSinceParams{
+		ServiceRegionID:    params.ServiceRegionID,
+		SinceSnapshotTime:  params.SinceSnapshotTime,
+		LatestSnapshotTime: params.LatestSnapshotTime,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if len(vals) > 0 {
+		return true, nil
+	}
+
 	return false, nil
 }
 
@@ -1931,6 +2030,8 @@ func (ldb *LogisticsDB) GetLatestShiftTeamLocationID(ctx context.Context, shiftT
 
 type UpdateShiftTeamLocationResponse struct {
 	ServiceRegionID *int64
+	// GeofenceEvents are the visit arrivals and departures newly inferred from the shift team locations.
+	GeofenceEvents []*logisticssql.ShiftTeamGeofenceEvent
 }
 
 func (ldb *LogisticsDB) UpdateShiftTeamLocation(ctx context.Context, latestSnapshotTimestamp time.Time, shiftTeamID int64, latLng logistics.LatLng) (*UpdateShiftTeamLocationResponse, error) {
@@ -1954,8 +2055,20 @@ func (ldb *LogisticsDB) UpdateShiftTeamLocation(ctx context.Context, latestSnaps
 			ShiftTeamSnapshotID: *snapshotResponse.ShiftTeamSnapshotID,
 			LocationID:          location.ID,
 		})
+	if err != nil {
+		return nil, err
+	}
 
-	return &UpdateShiftTeamLocationResponse{ServiceRegionID: snapshotResponse.ServiceRegionID}, err
+	resp := &UpdateShiftTeamLocationResponse{ServiceRegionID: snapshotResponse.ServiceRegionID}
+	if snapshotResponse.ServiceRegionID != nil {
+		// Geofence detection is best effort, and must not fail location updates.
+		resp.GeofenceEvents, err = ldb.detectShiftTeamGeofenceEvents(ctx, *snapshotResponse.ServiceRegionID, shiftTeamID, latestSnapshotTimestamp)
+		if err != nil {
+			ldb.writeGeofenceDetectionError(shiftTeamID, err)
+		}
+	}
+
+	return resp, nil
 }
 
 func UpsertLocation(ctx context.Context, queries *logisticssql.Queries, latLng logistics.LatLng) (*logisticssql.Location, error) {
@@ -2252,14 +2365,19 @@ func (ldb *LogisticsDB) getShiftTeamScheduleForScheduleRoute(ctx context.Context
 	scheduleRouteData := params.scheduleRouteData
 	latestScheduleTimestamp := params.latestScheduleTimestamp
 
-	baseLocations, err := ldb.GetLocationsByIDs(ctx, []int64{scheduleRouteData.ShiftTeamBaseLocationID})
+	endpointLocationIDs := collections.LinkedInt64SetWithElems(scheduleRouteData.ShiftTeamBaseLocationID, scheduleRouteData.ShiftTeamEndLocationID)
+	endpointLocations, err := ldb.GetLocationsByIDs(ctx, endpointLocationIDs.Elems())
 	if err != nil {
 		return nil, fmt.Errorf("base location not found: %w", err)
 	}
-
+	endpointLocationIndex := indexLocationsByID(endpointLocations)
 	baseLocation := &commonpb.Location{
-		LatitudeE6:  baseLocations[0].LatitudeE6,
-		LongitudeE6: baseLocations[0].LongitudeE6,
+		LatitudeE6:  endpointLocationIndex[scheduleRouteData.ShiftTeamBaseLocationID].LatitudeE6,
+		LongitudeE6: endpointLocationIndex[scheduleRouteData.ShiftTeamBaseLocationID].LongitudeE6,
+	}
+	endLocation := &commonpb.Location{
+		LatitudeE6:  endpointLocationIndex[scheduleRouteData.ShiftTeamEndLocationID].LatitudeE6,
+		LongitudeE6: endpointLocationIndex[scheduleRouteData.ShiftTeamEndLocationID].LongitudeE6,
 	}
 
 	openHoursTW, _, err := ldb.GetServiceRegionOpenHoursForDate(ctx, GetServiceRegionOpenHoursForDateParams{
@@ -2351,11 +2469,13 @@ func (ldb *LogisticsDB) getShiftTeamScheduleForScheduleRoute(ctx context.Context
 		ShiftTeamId: scheduleRouteData.ShiftTeamID,
 		Route: &logisticspb.ShiftTeamRoute{
 			BaseLocation:                    baseLocation,
+			EndLocation:                     endLocation,
 			BaseLocationArrivalTimestampSec: depotArrivalTimeSec,
 			// TODO(LOG-1648): Implement BaseLocationDepartureTimestampSec
 			Stops: stops,
 		},
 	}
+	shiftTeamRouteLegs(schedule.Route)
 	shiftTeamSchedule := &ShiftTeamSchedule{
 		ServiceRegionID: scheduleRouteData.ServiceRegionID,
 		Schedule:        schedule,
@@ -2594,15 +2714,19 @@ func (ldb *LogisticsDB) GetShiftTeamsSchedulesFromScheduleID(ctx context.Context
 	}
 
 	locationIDSet := collections.NewLinkedInt64Set(len(scheduleRouteStops))
+	routeStopLocationIDs := map[int64][]int64{}
 	for _, stop := range scheduleRouteStops {
+		var locationID int64
 		switch {
 		case stop.VisitLocationID.Valid:
-			locationIDSet.Add(stop.VisitLocationID.Int64)
+			locationID = stop.VisitLocationID.Int64
 		case stop.BreakRequestLocationID.Valid:
-			locationIDSet.Add(stop.BreakRequestLocationID.Int64)
+			locationID = stop.BreakRequestLocationID.Int64
 		default:
 			return nil, fmt.Errorf("missing location for stored stop(%d)", stop.ID)
 		}
+		locationIDSet.Add(locationID)
+		routeStopLocationIDs[stop.ScheduleRouteID] = append(routeStopLocationIDs[stop.ScheduleRouteID], locationID)
 	}
 
 	locs, err := ldb.GetLocationsByIDs(ctx, locationIDSet.Elems())
@@ -2634,20 +2758,47 @@ func (ldb *LogisticsDB) GetShiftTeamsSchedulesFromScheduleID(ctx context.Context
 		routeStops[stop.ScheduleRouteID] = append(stops, shiftTeamRouteStop)
 	}
 
+	var routeDrives map[int64][]time.Duration
+	if settings.RestBreakPolicy != nil {
+		routeLocationIDs := make(map[int64][]int64, len(routes))
+		for _, route := range routes {
+			locationIDs := append([]int64{route.BaseLocationID}, routeStopLocationIDs[route.ID]...)
+			routeLocationIDs[route.ID] = append(locationIDs, route.EndLocationID)
+		}
+		routeDrives, err = ldb.routeDriveDurations(ctx, routeLocationIDs, optimizerRun.DistanceSourceID, optimizerRun.EarliestDistanceTimestamp)
+		if err != nil {
+			return nil, fmt.Errorf("error getting route drive durations: %w", err)
+		}
+	}
+
 	var schedules []*logisticspb.ShiftTeamSchedule
+	var policyViolations []*logisticspb.RestBreakPolicyViolation
 	for _, route := range routes {
+		shiftTeamRoute := &logisticspb.ShiftTeamRoute{
+			Stops: routeStops[route.ID],
+			BaseLocation: &commonpb.Location{
+				LatitudeE6:  route.BaseLocationLatitudeE6,
+				LongitudeE6: route.BaseLocationLongitudeE6,
+			},
+			EndLocation: &commonpb.Location{
+				LatitudeE6:  route.EndLocationLatitudeE6,
+				LongitudeE6: route.EndLocationLongitudeE6,
+			},
+			BaseLocationArrivalTimestampSec: proto.Int64(route.DepotArrivalTimestampSec),
+			// TODO(LOG-1648): Implement BaseLocationDepartureTimestampSec
+		}
+		shiftTeamRouteLegs(shiftTeamRoute)
 		schedules = append(schedules, &logisticspb.ShiftTeamSchedule{
 			ShiftTeamId: route.ShiftTeamID,
-			Route: &logisticspb.ShiftTeamRoute{
-				Stops: routeStops[route.ID],
-				BaseLocation: &commonpb.Location{
-					LatitudeE6:  route.BaseLocationLatitudeE6,
-					LongitudeE6: route.BaseLocationLongitudeE6,
-				},
-				BaseLocationArrivalTimestampSec: proto.Int64(route.DepotArrivalTimestampSec),
-				// TODO(LOG-1648): Implement BaseLocationDepartureTimestampSec
-			},
+			Route:       shiftTeamRoute,
 		})
+		policyViolations = append(policyViolations, restBreakPolicyViolations(
+			settings.RestBreakPolicy,
+			route.ShiftTeamID,
+			time.Unix(route.ShiftStartTimestampSec, 0),
+			time.Unix(route.ShiftEndTimestampSec, 0),
+			shiftTeamRoute,
+			routeDrives[route.ID])...)
 	}
 
 	restBreakRequests, err := queries.GetShiftTeamRestBreakRequestsForShiftTeams(ctx,
@@ -2681,6 +2832,60 @@ func (ldb *LogisticsDB) GetShiftTeamsSchedulesFromScheduleID(ctx context.Context
 		})
 	}
 
+	droppedVisits, err := queries.GetValidationRepairDroppedVisitsForScheduleID(ctx, logisticssql.GetValidationRepairDroppedVisitsForScheduleIDParams{
+		ScheduleID:         scheduleID,
+		LatestSnapshotTime: latestTimestamp,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting validation repair dropped visits: %w", err)
+	}
+	var excludedVisits []*logisticspb.ExcludedVisit
+	for _, dv := range droppedVisits {
+		converter := &rowToVisitAcuityConverter{
+			arrivalStartTimestampSec:              dv.ArrivalStartTimestampSec,
+			arrivalEndTimestampSec:                dv.ArrivalEndTimestampSec,
+			visitClinicalUrgencyWindowDurationSec: dv.ClinicalUrgencyWindowDurationSec,
+			visitClinicalUrgencyLevelID:           dv.ClinicalUrgencyLevelID,
+		}
+		// Dropped visits are not in the problem, so they are not in the unassigned schedule visits.
+		unassignableVisits = append(unassignableVisits, &logisticspb.UnassignableVisit{
+			CareRequestId: proto.Int64(dv.CareRequestID),
+			Acuity:        converter.toVisitAcuity(),
+		})
+		excludedVisits = append(excludedVisits, &logisticspb.ExcludedVisit{
+			CareRequestId: dv.CareRequestID,
+			Reason:        logisticspb.ExcludedVisit_REASON_VALIDATION_REPAIR,
+			Description:   dv.Description,
+		})
+	}
+
+	blackedOutVisits, err := queries.GetBlackedOutVisitsForScheduleID(ctx, logisticssql.GetBlackedOutVisitsForScheduleIDParams{
+		ScheduleID:         scheduleID,
+		LatestSnapshotTime: latestTimestamp,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting blacked out visits: %w", err)
+	}
+	for _, bv := range blackedOutVisits {
+		converter := &rowToVisitAcuityConverter{
+			arrivalStartTimestampSec:              bv.ArrivalStartTimestampSec,
+			arrivalEndTimestampSec:                bv.ArrivalEndTimestampSec,
+			visitClinicalUrgencyWindowDurationSec: bv.ClinicalUrgencyWindowDurationSec,
+			visitClinicalUrgencyLevelID:           bv.ClinicalUrgencyLevelID,
+		}
+		// Blacked out visits are not in the problem either.
+		unassignableVisits = append(unassignableVisits, &logisticspb.UnassignableVisit{
+			CareRequestId: proto.Int64(bv.CareRequestID),
+			Acuity:        converter.toVisitAcuity(),
+		})
+		excludedVisits = append(excludedVisits, &logisticspb.ExcludedVisit{
+			CareRequestId: bv.CareRequestID,
+			Reason:        logisticspb.ExcludedVisit_REASON_BLACKOUT_ZONE,
+			// Names of blackout zones explain their closures.
+			Description: bv.BlackoutZoneName,
+		})
+	}
+
 	result := &ScheduleAndDebugScore{Schedule: &logisticspb.ServiceRegionDateSchedule{
 		UnassignableVisits: unassignableVisits,
 		Schedules:          schedules,
@@ -2691,6 +2896,12 @@ func (ldb *LogisticsDB) GetShiftTeamsSchedulesFromScheduleID(ctx context.Context
 			// Would require queries against what was in visit snapshots for a region but not yet in a schedule.
 		},
 	}}
+	if settings.RestBreakPolicy != nil || len(excludedVisits) > 0 {
+		result.Schedule.Diagnostics = &logisticspb.ScheduleDiagnostics{
+			RestBreakPolicyViolations: policyViolations,
+			ExcludedVisits:            excludedVisits,
+		}
+	}
 	if includeDebug {
 		schedule, err := queries.GetSchedule(ctx, scheduleID)
 		if err != nil {
@@ -2921,6 +3132,30 @@ func (ldb *LogisticsDB) DeleteVisitSnapshotForCareRequestID(ctx context.Context,
 	return &DeleteVisitSnapshotForCareRequestIDResponse{ServiceRegionID: snapshot.ServiceRegionID}, nil
 }
 
+// GetLatestScheduleForServiceRegionDate returns the latest service region schedule of the service date,
+// created before latestTimestamp, or ErrScheduleNotFound if there is none.
+func (ldb *LogisticsDB) GetLatestScheduleForServiceRegionDate(ctx context.Context, serviceRegionID int64, serviceDate time.Time, latestTimestamp time.Time) (*logisticspb.ServiceRegionDateSchedule, error) {
+	scheduleInfo, err := ldb.queries.GetLatestScheduleInfoForServiceRegionDate(ctx,
+		logisticssql.GetLatestScheduleInfoForServiceRegionDateParams{
+			ServiceRegionID:  serviceRegionID,
+			ServiceDate:      serviceDate,
+			OptimizerRunType: string(ServiceRegionScheduleRunType),
+			CreatedBefore:    latestTimestamp,
+		})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrScheduleNotFound
+		}
+		return nil, fmt.Errorf("error in GetLatestScheduleInfoForServiceRegionDate: %w", err)
+	}
+
+	schedule, err := ldb.GetShiftTeamsSchedulesFromScheduleID(ctx, scheduleInfo.ScheduleID, latestTimestamp, false)
+	if err != nil {
+		return nil, err
+	}
+	return schedule.Schedule, nil
+}
+
 type HasNewScheduleParams struct {
 	ServiceRegionID    int64
 	ServiceDate        time.Time
@@ -3136,6 +3371,10 @@ type WriteScheduleForVRPSolutionParams struct {
 
 	AvailabilityVisitIDMap       AvailabilityVisitIDMap
 	LastScheduleUnassignedVisits []*logisticssql.GetUnassignedScheduleVisitsForScheduleIDRow
+
+	// Service date of the optimizer run, for persisting the service dates chosen for MultiDayVisits.
+	ServiceDate    time.Time
+	MultiDayVisits MultiDayVisits
 }
 
 func (ldb *LogisticsDB) WriteScheduleForVRPSolution(
@@ -3216,8 +3455,10 @@ func (ldb *LogisticsDB) WriteScheduleForVRPSolution(
 			shiftRouteIDs[route.ShiftTeamSnapshotID] = route.ID
 		}
 
+		restBreakLocator := newScheduleRestBreakLocator(solution.GetDescription())
 		for _, shiftTeam := range shiftTeams {
 			routeID := shiftRouteIDs[*shiftTeam.Id]
+			previousLocationID := shiftTeam.GetDepotLocationId()
 			for i, stop := range shiftTeam.Route.GetStops() {
 				// Note: we don't serialize the actuals to the DB for stops,
 				// as those are sourced from the snapshot data directly.
@@ -3233,6 +3474,7 @@ func (ldb *LogisticsDB) WriteScheduleForVRPSolution(
 					if err != nil {
 						return err
 					}
+					previousLocationID = restBreakLocator.visitLocationID(visit.GetVisitId())
 
 					addVisitStopsParams.ScheduleIds = append(addVisitStopsParams.ScheduleIds, schedule.ID)
 					addVisitStopsParams.ScheduleRouteIds = append(addVisitStopsParams.ScheduleRouteIds, routeID)
@@ -3240,12 +3482,9 @@ func (ldb *LogisticsDB) WriteScheduleForVRPSolution(
 					addVisitStopsParams.ScheduleVisitIds = append(addVisitStopsParams.ScheduleVisitIds, scheduleVisit.ID)
 
 				case *optimizerpb.VRPShiftTeamRouteStop_RestBreak:
-					restBreak := stop.GetRestBreak()
-					scheduleRestBreak, err := queries.AddScheduleRestBreak(ctx, logisticssql.AddScheduleRestBreakParams{
-						ScheduleID:              schedule.ID,
-						ScheduleRouteID:         routeID,
-						ShiftTeamBreakRequestID: restBreak.GetRestBreakId(),
-					})
+					var restBreakParams logisticssql.AddScheduleRestBreakParams
+					restBreakParams, previousLocationID = restBreakLocator.addScheduleRestBreakParams(schedule.ID, routeID, stop.GetRestBreak(), previousLocationID)
+					scheduleRestBreak, err := queries.AddScheduleRestBreak(ctx, restBreakParams)
 					if err != nil {
 						return err
 					}
@@ -3273,6 +3512,16 @@ func (ldb *LogisticsDB) WriteScheduleForVRPSolution(
 			}
 		}
 
+		err = ldb.writeCareRequestServiceDates(ctx, queries, writeCareRequestServiceDatesParams{
+			schedule:       schedule,
+			serviceDate:    params.ServiceDate,
+			solution:       solution,
+			multiDayVisits: params.MultiDayVisits,
+		})
+		if err != nil {
+			return fmt.Errorf("error in writeCareRequestServiceDates: %w", err)
+		}
+
 		return nil
 	})
 
@@ -3693,6 +3942,7 @@ type CareRequestLatestInfo struct {
 	VisitPhaseShortName string
 	CareRequestEtaSec   int64
 	ServiceRegionID     int64
+	ShiftTeamID         int64
 	VisitLocation       *logisticssql.Location
 	ShiftTeamLocation   *logisticssql.Location
 }
@@ -3721,6 +3971,7 @@ type CareRequestDiagnostics struct {
 	PreferredAttributes      []*logisticssql.Attribute
 	ForbiddenAttributes      []*logisticssql.Attribute
 	UnwantedAttributes       []*logisticssql.Attribute
+	AttributePreferences     []*logisticssql.GetAttributePreferencesForVisitSnapshotsRow
 }
 
 func (ldb *LogisticsDB) AddOptimizerRunError(ctx context.Context, params logisticssql.AddOptimizerRunErrorParams) error {
@@ -3728,6 +3979,67 @@ func (ldb *LogisticsDB) AddOptimizerRunError(ctx context.Context, params logisti
 	return err
 }
 
+// AddOptimizerRunValidationRepairs records the repairs applied to the problem of an optimizer run in validation repair mode.
+func (ldb *LogisticsDB) AddOptimizerRunValidationRepairs(ctx context.Context, optimizerRunID int64, report *validation.RepairReport) error {
+	if report == nil || len(report.Repairs) == 0 {
+		return nil
+	}
+
+	errorNames := make([]string, len(report.Repairs))
+	errorMessages := make([]string, len(report.Repairs))
+	descriptions := make([]string, len(report.Repairs))
+	visitSnapshotIDs := make([]int64, len(report.Repairs))
+	for i, repair := range report.Repairs {
+		errorNames[i] = repair.ErrorName
+		errorMessages[i] = repair.ErrorMsg
+		descriptions[i] = repair.Description
+		if repair.DroppedVisitID != nil {
+			visitSnapshotIDs[i] = *repair.DroppedVisitID
+		}
+	}
+	_, err := ldb.queries.AddOptimizerRunValidationRepairs(ctx, logisticssql.AddOptimizerRunValidationRepairsParams{
+		OptimizerRunID:   optimizerRunID,
+		ErrorNames:       errorNames,
+		ErrorMessages:    errorMessages,
+		Descriptions:     descriptions,
+		VisitSnapshotIds: visitSnapshotIDs,
+	})
+	if err != nil {
+		return fmt.Errorf("error in AddOptimizerRunValidationRepairs: %w", err)
+	}
+
+	return nil
+}
+
+// AddScheduleStabilityRejection records a solution of an optimizer run that was not written,
+// as it was not stable enough compared to the previous schedule.
+func (ldb *LogisticsDB) AddScheduleStabilityRejection(ctx context.Context, params logisticssql.AddScheduleStabilityRejectionParams) error {
+	_, err := ldb.queries.AddScheduleStabilityRejection(ctx, params)
+	if err != nil {
+		return fmt.Errorf("error in AddScheduleStabilityRejection: %w", err)
+	}
+	return nil
+}
+
+// GetOptimizerRunValidationRepairReport returns the report of the repairs applied to the problem of an optimizer run.
+func (ldb *LogisticsDB) GetOptimizerRunValidationRepairReport(ctx context.Context, optimizerRunID int64) (*validation.RepairReport, error) {
+	rows, err := ldb.queries.GetOptimizerRunValidationRepairs(ctx, optimizerRunID)
+	if err != nil {
+		return nil, fmt.Errorf("error in GetOptimizerRunValidationRepairs: %w", err)
+	}
+
+	report := &validation.RepairReport{}
+	for _, row := range rows {
+		report.Repairs = append(report.Repairs, &validation.RepairRecord{
+			ErrorName:      row.ErrorName,
+			ErrorMsg:       row.ErrorMessage,
+			Description:    row.Description,
+			DroppedVisitID: sqltypes.ToProtoInt64(row.VisitSnapshotID),
+		})
+	}
+	return report, nil
+}
+
 func (ldb *LogisticsDB) GetLatestCareRequestsDataForDiagnostics(ctx context.Context, careRequestIDs []int64, createdBefore time.Time) ([]*CareRequestDiagnostics, error) {
 	queries := ldb.queries
 	careRequestsDiagnosticsRows, err := queries.GetLatestCareRequestsDataForDiagnostics(ctx, logisticssql.GetLatestCareRequestsDataForDiagnosticsParams{
@@ -3764,6 +4076,14 @@ func (ldb *LogisticsDB) GetLatestCareRequestsDataForDiagnostics(ctx context.Cont
 			snapshotUnwantedAttrs[attr.VisitSnapshotID] = append(snapshotUnwantedAttrs[attr.VisitSnapshotID], &logisticssql.Attribute{ID: attr.ID, Name: attr.Name})
 		}
 	}
+	visitAttrPrefs, err := queries.GetAttributePreferencesForVisitSnapshots(ctx, visitSnapshotIDs)
+	if err != nil {
+		return nil, err
+	}
+	snapshotAttrPrefs := map[int64][]*logisticssql.GetAttributePreferencesForVisitSnapshotsRow{}
+	for _, pref := range visitAttrPrefs {
+		snapshotAttrPrefs[pref.VisitSnapshotID] = append(snapshotAttrPrefs[pref.VisitSnapshotID], pref)
+	}
 
 	var locationIDs []int64
 	uniqueLocationIDs := make(map[int64]bool)
@@ -3798,6 +4118,7 @@ func (ldb *LogisticsDB) GetLatestCareRequestsDataForDiagnostics(ctx context.Cont
 			PreferredAttributes:      snapshotPreferredAttrs[row.VisitSnapshotID],
 			ForbiddenAttributes:      snapshotForbiddenAttrs[row.VisitSnapshotID],
 			UnwantedAttributes:       snapshotUnwantedAttrs[row.VisitSnapshotID],
+			AttributePreferences:     snapshotAttrPrefs[row.VisitSnapshotID],
 		})
 	}
 	return careRequestsDiagnostics, nil
@@ -3892,6 +4213,19 @@ func (ldb *LogisticsDB) GetOptimizerRunDiagnostics(ctx context.Context, optimize
 	}, nil
 }
 
+// GetLatestScheduleIDForOptimizerRun returns the ID of the latest schedule written for the optimizer run.
+func (ldb *LogisticsDB) GetLatestScheduleIDForOptimizerRun(ctx context.Context, optimizerRunID int64) (int64, error) {
+	schedule, err := ldb.queries.GetLatestScheduleForOptimizerRunID(ctx, optimizerRunID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrScheduleNotFound
+		}
+		return 0, fmt.Errorf("error in GetLatestScheduleForOptimizerRunID: %w", err)
+	}
+
+	return schedule.ID, nil
+}
+
 func (ldb *LogisticsDB) VRPProblemDataForSchedule(ctx context.Context, scheduleID int64) (*VRPProblemData, error) {
 	schedule, err := ldb.queries.GetSchedule(ctx, scheduleID)
 	if err != nil {
@@ -4024,6 +4358,7 @@ func (ldb *LogisticsDB) GetLatestInfoForCareRequest(ctx context.Context, careReq
 		VisitPhaseShortName: scheduleVisit.VisitPhaseShortName,
 		CareRequestEtaSec:   scheduleVisit.ArrivalTimestampSec,
 		ServiceRegionID:     scheduleVisit.ServiceRegionID,
+		ShiftTeamID:         shiftTeamID,
 		ShiftTeamLocation:   locationsMap[shiftTeamLocationID],
 		VisitLocation:       locationsMap[scheduleVisit.LocationID],
 	}, nil
@@ -4556,6 +4891,97 @@ func (ldb *LogisticsDB) VisitArrivalTimestampsForSchedule(ctx context.Context, s
 	return arrivalTimestampByVisitID, nil
 }
 
+type ScheduleRouteAssignmentsParams struct {
+	ServiceRegionID int64
+	ServiceDate     time.Time
+	CreatedBefore   time.Time
+}
+
+// ScheduleRouteAssignments are the care requests assigned to each shift team route of a schedule.
+type ScheduleRouteAssignments struct {
+	ScheduleID int64
+	Score      *optimizerpb.VRPScore
+
+	// Care requests in route order, by shift team.
+	Routes map[ShiftTeamID][]CareRequestID
+	// Care requests left unassigned.
+	UnassignedCareRequestIDs []CareRequestID
+}
+
+// GetLatestScheduleRouteAssignments returns the route assignments of the latest schedule for the service region date,
+// or nil if there is no such schedule.
+func (ldb *LogisticsDB) GetLatestScheduleRouteAssignments(ctx context.Context, params ScheduleRouteAssignmentsParams) (*ScheduleRouteAssignments, error) {
+	scheduleInfo, err := ldb.queries.GetLatestScheduleInfoForServiceRegionDate(ctx,
+		logisticssql.GetLatestScheduleInfoForServiceRegionDateParams{
+			ServiceRegionID:  params.ServiceRegionID,
+			ServiceDate:      params.ServiceDate,
+			OptimizerRunType: string(ServiceRegionScheduleRunType),
+			CreatedBefore:    params.CreatedBefore,
+		})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error in GetLatestScheduleInfoForServiceRegionDate: %w", err)
+	}
+
+	schedule, err := ldb.queries.GetSchedule(ctx, scheduleInfo.ScheduleID)
+	if err != nil {
+		return nil, fmt.Errorf("error in GetSchedule: %w", err)
+	}
+
+	routes, err := ldb.queries.GetScheduleRoutesForSchedule(ctx, schedule.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error in GetScheduleRoutesForSchedule: %w", err)
+	}
+	shiftTeamIDsByRouteID := make(map[int64]ShiftTeamID, len(routes))
+	assignments := &ScheduleRouteAssignments{
+		ScheduleID: schedule.ID,
+		Score: &optimizerpb.VRPScore{
+			HardScore:             proto.Int64(schedule.HardScore),
+			UnassignedVisitsScore: proto.Int64(schedule.UnassignedVisitsScore),
+			SoftScore:             proto.Int64(schedule.SoftScore),
+		},
+		Routes: make(map[ShiftTeamID][]CareRequestID, len(routes)),
+	}
+	for _, route := range routes {
+		shiftTeamID := ShiftTeamID(route.ShiftTeamID)
+		shiftTeamIDsByRouteID[route.ID] = shiftTeamID
+		assignments.Routes[shiftTeamID] = []CareRequestID{}
+	}
+
+	stops, err := ldb.queries.GetScheduleRouteStopsForSchedule(ctx, logisticssql.GetScheduleRouteStopsForScheduleParams{
+		ScheduleID:         schedule.ID,
+		LatestSnapshotTime: scheduleInfo.SnapshotTimestamp,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error in GetScheduleRouteStopsForSchedule: %w", err)
+	}
+	for _, stop := range stops {
+		if !stop.CareRequestID.Valid {
+			continue
+		}
+		shiftTeamID, ok := shiftTeamIDsByRouteID[stop.ScheduleRouteID]
+		if !ok {
+			return nil, fmt.Errorf("unknown schedule_route(%d) for schedule(%d)", stop.ScheduleRouteID, schedule.ID)
+		}
+		assignments.Routes[shiftTeamID] = append(assignments.Routes[shiftTeamID], CareRequestID(stop.CareRequestID.Int64))
+	}
+
+	unassignedVisits, err := ldb.queries.GetUnassignedScheduleVisitsForScheduleID(ctx, logisticssql.GetUnassignedScheduleVisitsForScheduleIDParams{
+		ScheduleID:         schedule.ID,
+		LatestSnapshotTime: scheduleInfo.SnapshotTimestamp,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error in GetUnassignedScheduleVisitsForScheduleID: %w", err)
+	}
+	for _, visit := range unassignedVisits {
+		assignments.UnassignedCareRequestIDs = append(assignments.UnassignedCareRequestIDs, CareRequestID(visit.CareRequestID))
+	}
+
+	return assignments, nil
+}
+
 type ServiceRegionAvailabilityParams struct {
 	StationMarketID   int64
 	ServiceDate       time.Time
@@ -669,6 +669,50 @@ func TestVRPVisitsForVisitSnapshots(t *testing.T) {
 	}
 }
 
+func TestVRPVisitsForVisitSnapshotsAttributePreferences(t *testing.T) {
+	snapshots := []*logisticssql.GetLatestVisitSnapshotsInRegionRow{
+		{
+			ID:                       1,
+			ArrivalStartTimestampSec: sqltypes.ToValidNullInt64(0),
+			ArrivalEndTimestampSec:   sqltypes.ToValidNullInt64(1),
+		},
+		{
+			ID:                       2,
+			ArrivalStartTimestampSec: sqltypes.ToValidNullInt64(0),
+			ArrivalEndTimestampSec:   sqltypes.ToValidNullInt64(1),
+		},
+	}
+
+	visits, err := vrpVisitsForVisitSnapshots(vrpVisitsForVisitSnapshotsParams{
+		visitSnapshots: snapshots,
+		attrs: []*logisticssql.GetAttributesForVisitSnapshotsRow{
+			{VisitSnapshotID: 1, IsRequired: true, Name: "required"},
+		},
+		attrPrefs: []*logisticssql.GetAttributePreferencesForVisitSnapshotsRow{
+			{VisitSnapshotID: 1, Name: "language:spanish", PenaltyUsdCents: 1000},
+			{VisitSnapshotID: 1, Name: "car:bariatric", IsUnwanted: true, PenaltyUsdCents: 2000},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testutils.MustMatch(t, []*optimizerpb.VRPAttribute{{Id: "required"}}, visits[0].RequiredAttributes, "attribute preferences should not be hard constraints")
+	testutils.MustMatch(t, []*optimizerpb.VRPAttributePreference{
+		{
+			Attribute:       &optimizerpb.VRPAttribute{Id: "language:spanish"},
+			IsUnwanted:      proto.Bool(false),
+			PenaltyUsdCents: proto.Int64(1000),
+		},
+		{
+			Attribute:       &optimizerpb.VRPAttribute{Id: "car:bariatric"},
+			IsUnwanted:      proto.Bool(true),
+			PenaltyUsdCents: proto.Int64(2000),
+		},
+	}, visits[0].AttributePreferences)
+	testutils.MustMatch(t, []*optimizerpb.VRPAttributePreference(nil), visits[1].AttributePreferences)
+}
+
 func TestLogisticsDBWithScope(t *testing.T) {
 	mockScope := monitoring.NewMockScope()
 	ldb := &LogisticsDB{scope: mockScope, queries: &logisticssql.Queries{}}
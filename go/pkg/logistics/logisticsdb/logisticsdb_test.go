@@ -23,6 +23,7 @@ import (
 	"github.com/*company-data-covered*/services/go/pkg/logistics"
 	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
 	"github.com/*company-data-covered*/services/go/pkg/logistics/optimizer/optimizersettings"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/validation"
 	"github.com/*company-data-covered*/services/go/pkg/monitoring"
 	"github.com/*company-data-covered*/services/go/pkg/protoconv"
 	"github.com/*company-data-covered*/services/go/pkg/sqltypes"
@@ -707,6 +708,73 @@ func TestLDB_GetLatestShiftTeamLocationID(t *testing.T) {
 	}
 }
 
+func TestLDB_DeleteShiftTeamLocationsCreatedBefore(t *testing.T) {
+	ctx, db, queries, done := setupDBTest(t)
+	defer done()
+
+	ldb := logisticsdb.NewLogisticsDB(db, nil, noSettingsService, monitoring.NewMockScope())
+	serviceRegionID := time.Now().UnixNano()
+	startTime := time.Now().Add(-time.Minute)
+
+	addShiftTeamLocations := func(shiftTeamID int64, latLngs ...logistics.LatLng) {
+		snapshot, err := queries.AddShiftTeamSnapshot(ctx, logisticssql.AddShiftTeamSnapshotParams{
+			ShiftTeamID:       shiftTeamID,
+			ServiceRegionID:   serviceRegionID,
+			StartTimestampSec: 0,
+			EndTimestampSec:   1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, latLng := range latLngs {
+			location, err := logisticsdb.UpsertLocation(ctx, queries, latLng)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = queries.AddShiftTeamLocation(ctx, logisticssql.AddShiftTeamLocationParams{
+				ShiftTeamSnapshotID: snapshot.ID,
+				LocationID:          location.ID,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	shiftTeamLocations := func(shiftTeamID int64) []logistics.LatLng {
+		rows, err := queries.GetShiftTeamLocationsInTimeRange(ctx, logisticssql.GetShiftTeamLocationsInTimeRangeParams{
+			ShiftTeamID: shiftTeamID,
+			StartTime:   startTime,
+			EndTime:     time.Now().Add(time.Minute),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		latLngs := make([]logistics.LatLng, len(rows))
+		for i, row := range rows {
+			latLngs[i] = logistics.LatLng{LatE6: row.LatitudeE6, LngE6: row.LongitudeE6}
+		}
+		return latLngs
+	}
+
+	shiftTeamID := time.Now().UnixNano()
+	addShiftTeamLocations(shiftTeamID, logistics.LatLng{LatE6: 1, LngE6: 1}, logistics.LatLng{LatE6: 2, LngE6: 2})
+	addShiftTeamLocations(shiftTeamID, logistics.LatLng{LatE6: 3, LngE6: 3})
+	idleShiftTeamID := shiftTeamID + 1
+	addShiftTeamLocations(idleShiftTeamID, logistics.LatLng{LatE6: 4, LngE6: 4})
+	addShiftTeamLocations(idleShiftTeamID)
+
+	deleted, err := ldb.DeleteShiftTeamLocationsCreatedBefore(ctx, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted < 2 {
+		t.Fatalf("expected at least 2 deleted locations, got %d", deleted)
+	}
+
+	testutils.MustMatch(t, []logistics.LatLng{{LatE6: 3, LngE6: 3}}, shiftTeamLocations(shiftTeamID), "latest location of the shift team should be kept")
+	testutils.MustMatch(t, []logistics.LatLng{{LatE6: 4, LngE6: 4}}, shiftTeamLocations(idleShiftTeamID), "latest location of a previous shift team snapshot should be kept")
+}
+
 func TestLDB_GetLatestShiftTeamSnapshotID(t *testing.T) {
 	ctx, db, queries, done := setupDBTest(t)
 	defer done()
@@ -1162,11 +1230,86 @@ func TestLDB_AddOptimizerRunErrorRoundtrip(t *testing.T) {
 	testutils.MustMatch(t, params.ErrorValue, back.ErrorValue)
 }
 
+func TestLDB_OptimizerRunValidationRepairsRoundtrip(t *testing.T) {
+	ctx, db, _, done := setupDBTest(t)
+	defer done()
+	ldb := logisticsdb.NewLogisticsDB(db, nil, noSettingsService, monitoring.NewMockScope())
+	optimizerRunID := time.Now().UnixNano()
+	report := &validation.RepairReport{
+		Repairs: []*validation.RepairRecord{
+			{ErrorName: "unknown_visit_location", ErrorMsg: "some error", Description: "dropped visit 1", DroppedVisitID: proto.Int64(1)},
+			{ErrorName: "inverted_visit_arrival_time_window", ErrorMsg: "some error", Description: "clamped visit 2"},
+		},
+	}
+
+	err := ldb.AddOptimizerRunValidationRepairs(ctx, optimizerRunID, report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ldb.AddOptimizerRunValidationRepairs(ctx, optimizerRunID+1, &validation.RepairReport{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	back, err := ldb.GetOptimizerRunValidationRepairReport(ctx, optimizerRunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutils.MustMatch(t, report, back)
+
+	back, err = ldb.GetOptimizerRunValidationRepairReport(ctx, optimizerRunID+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutils.MustMatch(t, &validation.RepairReport{}, back, "empty reports are not stored")
+}
+
+func TestLDB_AddScheduleStabilityRejection(t *testing.T) {
+	ctx, db, queries, done := setupDBTest(t)
+	defer done()
+	ldb := logisticsdb.NewLogisticsDB(db, nil, noSettingsService, monitoring.NewMockScope())
+	optimizerRunID := time.Now().UnixNano()
+	params := logisticssql.AddScheduleStabilityRejectionParams{
+		OptimizerRunID:                       optimizerRunID,
+		PreviousScheduleID:                   2,
+		HardScore:                            0,
+		UnassignedVisitsScore:                -10,
+		SoftScore:                            1050,
+		SoftScoreImprovement:                 50,
+		MovedCareRequestIds:                  []int64{11, 21},
+		MovedCommittedAdjacentCareRequestIds: []int64{11},
+	}
+
+	err := ldb.AddScheduleStabilityRejection(ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rejections, err := queries.GetScheduleStabilityRejectionsForOptimizerRun(ctx, optimizerRunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rejections) != 1 {
+		t.Fatalf("unexpected rejections: %+v", rejections)
+	}
+	rejection := rejections[0]
+	testutils.MustMatch(t, params, logisticssql.AddScheduleStabilityRejectionParams{
+		OptimizerRunID:                       rejection.OptimizerRunID,
+		PreviousScheduleID:                   rejection.PreviousScheduleID,
+		HardScore:                            rejection.HardScore,
+		UnassignedVisitsScore:                rejection.UnassignedVisitsScore,
+		SoftScore:                            rejection.SoftScore,
+		SoftScoreImprovement:                 rejection.SoftScoreImprovement,
+		MovedCareRequestIds:                  rejection.MovedCareRequestIds,
+		MovedCommittedAdjacentCareRequestIds: rejection.MovedCommittedAdjacentCareRequestIds,
+	})
+}
+
 func TestLDB_VRPProblemDataForSchedule(t *testing.T) {
 	ctx, db, queries, done := setupDBTest(t)
 	defer done()
 	mapService := &mockDistanceMatrix{}
-	ldb := logisticsdb.NewLogisticsDB(db, logistics.NewMapServicePicker(mapService, mapService, mockSettingsService), mockSettingsService, monitoring.NewMockScope())
+	ldb := logisticsdb.NewLogisticsDB(db, logistics.NewMapServicePicker(mapService, nil, mapService, mockSettingsService, monitoring.NewMockScope()), mockSettingsService, monitoring.NewMockScope())
 
 	serviceRegion := setupServiceRegionForCreateVRPDescription(ctx, t, queries)
 	logisticsVersion := "<some logistics SHA>"
@@ -1225,7 +1368,7 @@ func TestLDB_GetOptimizerRunDiagnostics(t *testing.T) {
 	ctx, db, queries, done := setupDBTest(t)
 	defer done()
 	mapService := &mockDistanceMatrix{}
-	ldb := logisticsdb.NewLogisticsDB(db, logistics.NewMapServicePicker(mapService, mapService, mockSettingsService), mockSettingsService, monitoring.NewMockScope())
+	ldb := logisticsdb.NewLogisticsDB(db, logistics.NewMapServicePicker(mapService, nil, mapService, mockSettingsService, monitoring.NewMockScope()), mockSettingsService, monitoring.NewMockScope())
 
 	serviceRegion := setupServiceRegionForCreateVRPDescription(ctx, t, queries)
 	logisticsVersion := "<some logistics SHA>"
@@ -1550,6 +1693,32 @@ func TestLDB_GetLatestShiftTeamSchedule(t *testing.T) {
 					LatitudeE6:  baseLocation.LatitudeE6,
 					LongitudeE6: baseLocation.LongitudeE6,
 				},
+				EndLocation: &commonpb.Location{
+					LatitudeE6:  baseLocation.LatitudeE6,
+					LongitudeE6: baseLocation.LongitudeE6,
+				},
+				FirstLeg: &logisticspb.ShiftTeamRouteLeg{
+					StartLocation: &commonpb.Location{
+						LatitudeE6:  baseLocation.LatitudeE6,
+						LongitudeE6: baseLocation.LongitudeE6,
+					},
+					EndLocation: &commonpb.Location{
+						LatitudeE6:  visitLocation.LatitudeE6,
+						LongitudeE6: visitLocation.LongitudeE6,
+					},
+					ArrivalTimestampSec: &arrivalTimestampSec,
+				},
+				LastLeg: &logisticspb.ShiftTeamRouteLeg{
+					StartLocation: &commonpb.Location{
+						LatitudeE6:  visitLocation2.LatitudeE6,
+						LongitudeE6: visitLocation2.LongitudeE6,
+					},
+					EndLocation: &commonpb.Location{
+						LatitudeE6:  baseLocation.LatitudeE6,
+						LongitudeE6: baseLocation.LongitudeE6,
+					},
+					ArrivalTimestampSec: &startTimestampSec,
+				},
 				BaseLocationArrivalTimestampSec: &startTimestampSec,
 				Stops: []*logisticspb.ShiftTeamRouteStop{
 					{
@@ -1605,6 +1774,10 @@ func TestLDB_GetLatestShiftTeamSchedule(t *testing.T) {
 					LatitudeE6:  baseLocation.LatitudeE6,
 					LongitudeE6: baseLocation.LongitudeE6,
 				},
+				EndLocation: &commonpb.Location{
+					LatitudeE6:  baseLocation.LatitudeE6,
+					LongitudeE6: baseLocation.LongitudeE6,
+				},
 			},
 		},
 		PendingUpdates: &logisticspb.SchedulePendingUpdates{},
@@ -2114,6 +2287,71 @@ func TestLDB_GetLatestShiftTeamSchedulesInServiceRegion(t *testing.T) {
 		VisitSnapshotIds: []int64{unassignableVisitSnapshotLastDay.ID},
 	}))
 
+	droppedCareRequestIDLastDay := careRequestIDFirstDay + 3
+	droppedVisitSnapshotLastDay, err := queries.AddVisitSnapshot(ctx, logisticssql.AddVisitSnapshotParams{
+		CareRequestID:            droppedCareRequestIDLastDay,
+		ServiceRegionID:          serviceRegion.ID,
+		LocationID:               locations[0].ID,
+		ArrivalStartTimestampSec: sqltypes.ToValidNullInt64(lastDayUnassignableVisitArrivalStartTimestampSec),
+		ArrivalEndTimestampSec:   sqltypes.ToValidNullInt64(lastDayUnassignableVisitArrivalEndTimestampSec),
+		ServiceDurationSec:       serviceDurationSec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ldb.AddOptimizerRunValidationRepairs(ctx, optimizerRunLastDay.ID, &validation.RepairReport{
+		Repairs: []*validation.RepairRecord{
+			{
+				ErrorName:      "unknown_visit_location",
+				ErrorMsg:       "visit locations must be in the problem locations",
+				Description:    "dropped visit",
+				DroppedVisitID: proto.Int64(droppedVisitSnapshotLastDay.ID),
+			},
+			{
+				ErrorName:   "inverted_visit_arrival_time_window",
+				ErrorMsg:    "visit arrival time windows must start before they end",
+				Description: "clamped visit",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blackedOutCareRequestIDLastDay := careRequestIDFirstDay + 4
+	blackedOutVisitSnapshotLastDay, err := queries.AddVisitSnapshot(ctx, logisticssql.AddVisitSnapshotParams{
+		CareRequestID:            blackedOutCareRequestIDLastDay,
+		ServiceRegionID:          serviceRegion.ID,
+		LocationID:               locations[0].ID,
+		ArrivalStartTimestampSec: sqltypes.ToValidNullInt64(lastDayUnassignableVisitArrivalStartTimestampSec),
+		ArrivalEndTimestampSec:   sqltypes.ToValidNullInt64(lastDayUnassignableVisitArrivalEndTimestampSec),
+		ServiceDurationSec:       serviceDurationSec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blackoutZone, err := ldb.AddBlackoutZone(ctx, serviceRegion.ID, logisticsdb.BlackoutZoneParams{
+		Name: "flooding",
+		Vertices: []*commonpb.Location{
+			{LatitudeE6: 0, LongitudeE6: 0},
+			{LatitudeE6: 0, LongitudeE6: 1},
+			{LatitudeE6: 1, LongitudeE6: 1},
+		},
+		TimeWindow: logisticsdb.TimeWindow{
+			Start: time.Unix(lastDayUnassignableVisitArrivalStartTimestampSec, 0),
+			End:   time.Unix(lastDayUnassignableVisitArrivalEndTimestampSec, 0),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ldb.AddOptimizerRunBlackedOutVisits(ctx, optimizerRunLastDay.ID, logisticsdb.BlackedOutVisits{
+		logisticsdb.VisitSnapshotID(blackedOutVisitSnapshotLastDay.ID): blackoutZone,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	_, err = queries.AddScheduleVisitStops(ctx, logisticssql.AddScheduleVisitStopsParams{
 		ScheduleIds:      []int64{visit1.ScheduleID, visit2.ScheduleID},
 		ScheduleRouteIds: []int64{visit1.ScheduleRouteID, visit2.ScheduleRouteID},
@@ -2168,6 +2406,32 @@ func TestLDB_GetLatestShiftTeamSchedulesInServiceRegion(t *testing.T) {
 								LatitudeE6:  baseLocationFirstDay.LatitudeE6,
 								LongitudeE6: baseLocationFirstDay.LongitudeE6,
 							},
+							EndLocation: &commonpb.Location{
+								LatitudeE6:  baseLocationFirstDay.LatitudeE6,
+								LongitudeE6: baseLocationFirstDay.LongitudeE6,
+							},
+							FirstLeg: &logisticspb.ShiftTeamRouteLeg{
+								StartLocation: &commonpb.Location{
+									LatitudeE6:  baseLocationFirstDay.LatitudeE6,
+									LongitudeE6: baseLocationFirstDay.LongitudeE6,
+								},
+								EndLocation: &commonpb.Location{
+									LatitudeE6:  locations[0].LatitudeE6,
+									LongitudeE6: locations[0].LongitudeE6,
+								},
+								ArrivalTimestampSec: proto.Int64(firstDayVisitArrivalTimestampSec),
+							},
+							LastLeg: &logisticspb.ShiftTeamRouteLeg{
+								StartLocation: &commonpb.Location{
+									LatitudeE6:  locations[0].LatitudeE6,
+									LongitudeE6: locations[0].LongitudeE6,
+								},
+								EndLocation: &commonpb.Location{
+									LatitudeE6:  baseLocationFirstDay.LatitudeE6,
+									LongitudeE6: baseLocationFirstDay.LongitudeE6,
+								},
+								ArrivalTimestampSec: proto.Int64(firstDayEndTimestampSec),
+							},
 							Stops: []*logisticspb.ShiftTeamRouteStop{
 								{
 									Stop: &logisticspb.ShiftTeamRouteStop_Visit{
@@ -2225,6 +2489,26 @@ func TestLDB_GetLatestShiftTeamSchedulesInServiceRegion(t *testing.T) {
 							},
 						},
 					},
+					{
+						CareRequestId: proto.Int64(droppedCareRequestIDLastDay),
+					},
+					{
+						CareRequestId: proto.Int64(blackedOutCareRequestIDLastDay),
+					},
+				},
+				Diagnostics: &logisticspb.ScheduleDiagnostics{
+					ExcludedVisits: []*logisticspb.ExcludedVisit{
+						{
+							CareRequestId: droppedCareRequestIDLastDay,
+							Reason:        logisticspb.ExcludedVisit_REASON_VALIDATION_REPAIR,
+							Description:   "dropped visit",
+						},
+						{
+							CareRequestId: blackedOutCareRequestIDLastDay,
+							Reason:        logisticspb.ExcludedVisit_REASON_BLACKOUT_ZONE,
+							Description:   "flooding",
+						},
+					},
 				},
 				Schedules: []*logisticspb.ShiftTeamSchedule{
 					{
@@ -2235,6 +2519,32 @@ func TestLDB_GetLatestShiftTeamSchedulesInServiceRegion(t *testing.T) {
 								LatitudeE6:  baseLocationLastDay.LatitudeE6,
 								LongitudeE6: baseLocationLastDay.LongitudeE6,
 							},
+							EndLocation: &commonpb.Location{
+								LatitudeE6:  baseLocationLastDay.LatitudeE6,
+								LongitudeE6: baseLocationLastDay.LongitudeE6,
+							},
+							FirstLeg: &logisticspb.ShiftTeamRouteLeg{
+								StartLocation: &commonpb.Location{
+									LatitudeE6:  baseLocationLastDay.LatitudeE6,
+									LongitudeE6: baseLocationLastDay.LongitudeE6,
+								},
+								EndLocation: &commonpb.Location{
+									LatitudeE6:  locations[1].LatitudeE6,
+									LongitudeE6: locations[1].LongitudeE6,
+								},
+								ArrivalTimestampSec: proto.Int64(lastDayVisitArrivalTimestampSec),
+							},
+							LastLeg: &logisticspb.ShiftTeamRouteLeg{
+								StartLocation: &commonpb.Location{
+									LatitudeE6:  locations[1].LatitudeE6,
+									LongitudeE6: locations[1].LongitudeE6,
+								},
+								EndLocation: &commonpb.Location{
+									LatitudeE6:  baseLocationLastDay.LatitudeE6,
+									LongitudeE6: baseLocationLastDay.LongitudeE6,
+								},
+								ArrivalTimestampSec: proto.Int64(lastDayEndTimestampSec),
+							},
 							Stops: []*logisticspb.ShiftTeamRouteStop{
 								{
 									Stop: &logisticspb.ShiftTeamRouteStop_Visit{
@@ -2342,7 +2652,7 @@ func writeTeamShiftSnapshot(ctx context.Context, t *testing.T, params *writeTeam
 		ShiftTeamAttributes: []*commonpb.Attribute{{Name: "attr1"}},
 	}
 
-	snapshot, err := ldb.WriteShiftTeamSnapshot(ctx, shiftTeamID, &shiftteampb.GetShiftTeamResponse{ShiftTeam: shiftTeam})
+	snapshot, err := ldb.WriteShiftTeamSnapshot(ctx, shiftTeamID, &shiftteampb.GetShiftTeamResponse{ShiftTeam: shiftTeam}, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2604,6 +2914,123 @@ func TestLDB_WriteScheduleForVRPSolutionRoundtrip(t *testing.T) {
 	testutils.MustMatch(t, resp.Solution, newDescription, "solution doesn't match")
 }
 
+func TestLDB_WriteScheduleForVRPSolutionPolicyRestBreakRoundtrip(t *testing.T) {
+	ctx, db, queries, done := setupDBTest(t)
+	defer done()
+
+	ldb := logisticsdb.NewLogisticsDB(db, nil, mockSettingsService, monitoring.NewMockScope())
+
+	stationMarketID := time.Now().UnixNano()
+	region, _ := addStationMarket(ctx, t, queries, stationMarketID)
+	run, err := queries.AddOptimizerRun(ctx, logisticssql.AddOptimizerRunParams{
+		ServiceRegionID:   region.ID,
+		ServiceDate:       time.Date(2023, time.September, 20, 0, 0, 0, 0, time.UTC),
+		SnapshotTimestamp: time.Now(),
+		OptimizerRunType:  string(logisticsdb.ServiceRegionScheduleRunType),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	depotLoc, err := queries.AddLocation(ctx, logisticssql.AddLocationParams{
+		LatitudeE6:  int32(time.Now().UnixNano()),
+		LongitudeE6: int32(time.Now().UnixNano()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shiftTeam, err := queries.AddShiftTeamSnapshot(ctx, logisticssql.AddShiftTeamSnapshotParams{
+		ShiftTeamID:       time.Now().UnixNano(),
+		ServiceRegionID:   region.ID,
+		BaseLocationID:    depotLoc.ID,
+		StartTimestampSec: 0,
+		EndTimestampSec:   1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	visit := writeVisitSnapshot(ctx, t, time.Now().UnixNano(), stationMarketID, ldb, logisticsdb.VisitPhaseTypeShortNameUncommitted, nil)
+	visitLocs, err := ldb.GetLocationsByIDs(ctx, []int64{visit.LocationID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policyRestBreakID := -shiftTeam.ShiftTeamID
+	restBreakStartTimestampSec := time.Now().Add(time.Hour).Unix()
+	route := &optimizerpb.VRPShiftTeamRoute{
+		Stops: []*optimizerpb.VRPShiftTeamRouteStop{
+			{
+				Stop: &optimizerpb.VRPShiftTeamRouteStop_Visit{Visit: &optimizerpb.VRPShiftTeamVisit{
+					VisitId:             proto.Int64(visit.ID),
+					ArrivalTimestampSec: proto.Int64(restBreakStartTimestampSec - 600),
+				}},
+				Pinned: proto.Bool(false),
+			},
+			{
+				Stop: &optimizerpb.VRPShiftTeamRouteStop_RestBreak{RestBreak: &optimizerpb.VRPShiftTeamRestBreak{
+					RestBreakId:       proto.Int64(policyRestBreakID),
+					StartTimestampSec: proto.Int64(restBreakStartTimestampSec),
+				}},
+				Pinned: proto.Bool(false),
+			},
+		},
+	}
+	schedule, err := ldb.WriteScheduleForVRPSolution(ctx, &logisticsdb.WriteScheduleForVRPSolutionParams{
+		ServiceRegionID:  region.ID,
+		OptimizerRunID:   run.ID,
+		OptimizerVersion: "version",
+		Solution: &optimizerpb.VRPSolution{
+			Score: &optimizerpb.VRPScore{},
+			Description: &optimizerpb.VRPDescription{
+				Visits: []*optimizerpb.VRPVisit{
+					{Id: proto.Int64(visit.ID), LocationId: proto.Int64(visit.LocationID)},
+				},
+				RestBreaks: []*optimizerpb.VRPRestBreak{
+					{
+						Id:          proto.Int64(policyRestBreakID),
+						ShiftTeamId: proto.Int64(shiftTeam.ID),
+						DurationSec: proto.Int64(1800),
+						Unrequested: proto.Bool(true),
+					},
+				},
+				ShiftTeams: []*optimizerpb.VRPShiftTeam{
+					{
+						Id:                  proto.Int64(shiftTeam.ID),
+						DepotLocationId:     proto.Int64(depotLoc.ID),
+						RouteHistory:        &optimizerpb.VRPShiftTeamRouteHistory{},
+						UpcomingCommitments: &optimizerpb.VRPShiftTeamCommitments{},
+						Route:               route,
+					},
+				},
+			},
+			TotalStats: &optimizerpb.VRPStats{},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	solution, err := ldb.GetVRPSolutionFromScheduleID(ctx, schedule.ID, run.SnapshotTimestamp, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutils.MustMatch(t, route, solution.GetDescription().GetShiftTeams()[0].GetRoute(), "policy rest break should be read back from the VRP solution")
+
+	scheduleAndScore, err := ldb.GetShiftTeamsSchedulesFromScheduleID(ctx, schedule.ID, run.SnapshotTimestamp, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stops := scheduleAndScore.Schedule.GetSchedules()[0].GetRoute().GetStops()
+	testutils.MustMatch(t, &logisticspb.ShiftTeamRestBreak{
+		RestBreakId:       policyRestBreakID,
+		StartTimestampSec: proto.Int64(restBreakStartTimestampSec),
+		DurationSec:       proto.Int64(1800),
+		Location: &commonpb.Location{
+			LatitudeE6:  visitLocs[0].LatitudeE6,
+			LongitudeE6: visitLocs[0].LongitudeE6,
+		},
+	}, stops[1].GetRestBreak(), "policy rest break should be taken at the previous visit location")
+}
+
 func TestLDB_WriteScheduleForVRPSolutionAvailabilityRun(t *testing.T) {
 	ctx, db, queries, done := setupDBTest(t)
 	defer done()
@@ -3409,6 +3836,7 @@ func TestWriteShiftTeamSnapshot(t *testing.T) {
 	tcs := []struct {
 		Desc      string
 		ShiftTeam *shiftteampb.GetShiftTeamResponse
+		Endpoints *logisticspb.ShiftTeamEndpoints
 
 		HasErr bool
 	}{
@@ -3423,6 +3851,22 @@ func TestWriteShiftTeamSnapshot(t *testing.T) {
 				},
 			},
 		},
+		{
+			Desc: "base case with endpoints",
+			ShiftTeam: &shiftteampb.GetShiftTeamResponse{
+				ShiftTeam: &shiftteampb.ShiftTeam{
+					Id:              shiftTeamID,
+					MarketId:        &stationMarketID,
+					BaseLocation:    loc,
+					ShiftTimeWindow: timeWindow,
+				},
+			},
+			Endpoints: &logisticspb.ShiftTeamEndpoints{
+				StartLocation:        loc,
+				EndLocation:          loc,
+				ReturnByTimestampSec: proto.Int64(now1.Unix()),
+			},
+		},
 		{
 			Desc: "base case with current location",
 			ShiftTeam: &shiftteampb.GetShiftTeamResponse{
@@ -3498,13 +3942,61 @@ func TestWriteShiftTeamSnapshot(t *testing.T) {
 
 	for _, tc := range tcs {
 		t.Run(tc.Desc, func(t *testing.T) {
-			_, err := ldb.WriteShiftTeamSnapshot(ctx, shiftTeamID, tc.ShiftTeam)
+			_, err := ldb.WriteShiftTeamSnapshot(ctx, shiftTeamID, tc.ShiftTeam, tc.Endpoints)
 
 			testutils.MustMatch(t, tc.HasErr, err != nil, "errors don't match")
 		})
 	}
 }
 
+func TestWriteShiftTeamSnapshotKeepsEndpoints(t *testing.T) {
+	ctx, db, queries, done := setupDBTest(t)
+	defer done()
+
+	ldb := logisticsdb.NewLogisticsDB(db, nil, noSettingsService, monitoring.NewMockScope())
+
+	stationMarketID := time.Now().UnixNano()
+	addStationMarket(ctx, t, queries, stationMarketID)
+
+	shiftTeamID := time.Now().UnixNano()
+	now := time.Now()
+	end := now.Add(8 * time.Hour)
+	resp := &shiftteampb.GetShiftTeamResponse{
+		ShiftTeam: &shiftteampb.ShiftTeam{
+			Id:           shiftTeamID,
+			MarketId:     &stationMarketID,
+			BaseLocation: &commonpb.Location{LatitudeE6: int32(now.UnixNano()), LongitudeE6: int32(now.UnixNano())},
+			ShiftTimeWindow: &commonpb.TimeWindow{
+				StartDatetime: logisticsdb.TimeToProtoDateTime(&now),
+				EndDatetime:   logisticsdb.TimeToProtoDateTime(&end),
+			},
+		},
+	}
+
+	snapshot, err := ldb.WriteShiftTeamSnapshot(ctx, shiftTeamID, resp, &logisticspb.ShiftTeamEndpoints{
+		StartLocation:                 &commonpb.Location{LatitudeE6: int32(now.UnixNano()) + 1, LongitudeE6: int32(now.UnixNano()) + 1},
+		EndLocation:                   &commonpb.Location{LatitudeE6: int32(now.UnixNano()) + 2, LongitudeE6: int32(now.UnixNano()) + 2},
+		EarliestDepartureTimestampSec: proto.Int64(now.Add(time.Hour).Unix()),
+		ReturnByTimestampSec:          proto.Int64(end.Add(-time.Hour).Unix()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !snapshot.StartLocationID.Valid || !snapshot.EndLocationID.Valid {
+		t.Fatal("expected start and end locations")
+	}
+
+	nextSnapshot, err := ldb.WriteShiftTeamSnapshot(ctx, shiftTeamID, resp, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testutils.MustMatch(t, snapshot.StartLocationID, nextSnapshot.StartLocationID)
+	testutils.MustMatch(t, snapshot.EndLocationID, nextSnapshot.EndLocationID)
+	testutils.MustMatch(t, snapshot.EarliestDepartureTimestampSec, nextSnapshot.EarliestDepartureTimestampSec)
+	testutils.MustMatch(t, snapshot.ReturnByTimestampSec, nextSnapshot.ReturnByTimestampSec)
+}
+
 func TestWriteVisitSnapshot(t *testing.T) {
 	ctx, db, queries, done := setupDBTest(t)
 	defer done()
@@ -4022,7 +4514,7 @@ func TestLogisticsDB_GetAssignableShiftTeamsCandidatesForDate(t *testing.T) {
 							ShiftTeamAttributes: []*commonpb.Attribute{
 								{Name: "test_skill"},
 							},
-						}})
+						}}, nil)
 				if err != nil {
 					t.Fatal(err)
 				}
@@ -4137,6 +4629,222 @@ func TestLDB_GetDistanceSourceByShortName(t *testing.T) {
 	}
 }
 
+func TestLDB_GetRoutePlayback(t *testing.T) {
+	ctx, db, queries, done := setupDBTest(t)
+	defer done()
+	ldb := logisticsdb.NewLogisticsDB(db, nil, mockSettingsService, monitoring.NewMockScope())
+
+	serviceDate := time.Date(2023, time.September, 18, 0, 0, 0, 0, time.UTC)
+	serviceRegionID := time.Now().UnixNano()
+	optimizerRun, err := queries.AddOptimizerRun(ctx, logisticssql.AddOptimizerRunParams{
+		ServiceRegionID:            serviceRegionID,
+		ServiceDate:                serviceDate,
+		OpenHoursStartTimestampSec: serviceDate.Add(8 * time.Hour).Unix(),
+		OpenHoursEndTimestampSec:   serviceDate.Add(20 * time.Hour).Unix(),
+		OptimizerRunType:           string(logisticsdb.ServiceRegionScheduleRunType),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	playback, err := ldb.GetRoutePlayback(ctx, logisticsdb.RoutePlaybackParams{OptimizerRunID: optimizerRun.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutils.MustMatch(t, &logisticsdb.RoutePlayback{
+		ServiceRegionID:            serviceRegionID,
+		ServiceDate:                serviceDate,
+		OpenHoursStartTimestampSec: optimizerRun.OpenHoursStartTimestampSec,
+		OpenHoursEndTimestampSec:   optimizerRun.OpenHoursEndTimestampSec,
+		Schedules:                  []*logisticsdb.PlaybackSchedule{},
+		ShiftTeamLocations:         map[logisticsdb.ShiftTeamID][]*logisticsdb.PlaybackLocation{},
+		VisitLocations:             map[logisticsdb.CareRequestID]logistics.LatLng{},
+		VisitPhases:                map[logisticsdb.CareRequestID][]*logisticsdb.PlaybackVisitPhase{},
+	}, playback, "optimizer run without schedules")
+
+	_, err = ldb.GetRoutePlayback(ctx, logisticsdb.RoutePlaybackParams{OptimizerRunID: optimizerRun.ID + 1})
+	testutils.MustMatch(t, logisticsdb.ErrUnknownOptimizerRunID, err)
+
+	_, err = ldb.GetRoutePlayback(ctx, logisticsdb.RoutePlaybackParams{ScheduleID: time.Now().UnixNano()})
+	testutils.MustMatch(t, logisticsdb.ErrScheduleNotFound, err)
+
+	stationMarketID := time.Now().UnixNano()
+	region, _ := addStationMarket(ctx, t, queries, stationMarketID)
+	loc, err := queries.AddLocation(ctx, logisticssql.AddLocationParams{
+		LatitudeE6:  int32(time.Now().UnixNano()),
+		LongitudeE6: int32(time.Now().UnixNano()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addShiftTeam := func() *logisticssql.ShiftTeamSnapshot {
+		shiftTeam, err := queries.AddShiftTeamSnapshot(ctx, logisticssql.AddShiftTeamSnapshotParams{
+			ShiftTeamID:       time.Now().UnixNano(),
+			ServiceRegionID:   region.ID,
+			BaseLocationID:    loc.ID,
+			StartTimestampSec: 0,
+			EndTimestampSec:   1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return shiftTeam
+	}
+	shiftTeam1 := addShiftTeam()
+	shiftTeam2 := addShiftTeam()
+	shiftTeamID1 := logisticsdb.ShiftTeamID(shiftTeam1.ShiftTeamID)
+	shiftTeamID2 := logisticsdb.ShiftTeamID(shiftTeam2.ShiftTeamID)
+
+	baseCareRequestID := time.Now().UnixNano()
+	careRequestID1 := logisticsdb.CareRequestID(baseCareRequestID)
+	careRequestID2 := logisticsdb.CareRequestID(baseCareRequestID + 1)
+	visit1 := writeVisitSnapshot(ctx, t, baseCareRequestID, stationMarketID, ldb, logisticsdb.VisitPhaseTypeShortNameCommitted, nil)
+	visit2 := writeVisitSnapshot(ctx, t, baseCareRequestID+1, stationMarketID, ldb, logisticsdb.VisitPhaseTypeShortNameCommitted, nil)
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	addScheduleForRoutes := func(routes map[*logisticssql.ShiftTeamSnapshot][]*logisticssql.VisitSnapshot) *logisticssql.Schedule {
+		run, err := queries.AddOptimizerRun(ctx, logisticssql.AddOptimizerRunParams{
+			ServiceRegionID:            region.ID,
+			ServiceDate:                today,
+			OpenHoursStartTimestampSec: now.Add(-time.Hour).Unix(),
+			OpenHoursEndTimestampSec:   now.Add(time.Hour).Unix(),
+			SnapshotTimestamp:          time.Now(),
+			OptimizerRunType:           string(logisticsdb.ServiceRegionScheduleRunType),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var shiftTeams []*optimizerpb.VRPShiftTeam
+		for _, shiftTeam := range []*logisticssql.ShiftTeamSnapshot{shiftTeam1, shiftTeam2} {
+			var stops []*optimizerpb.VRPShiftTeamRouteStop
+			for i, visit := range routes[shiftTeam] {
+				stops = append(stops, &optimizerpb.VRPShiftTeamRouteStop{
+					Stop: &optimizerpb.VRPShiftTeamRouteStop_Visit{Visit: &optimizerpb.VRPShiftTeamVisit{
+						VisitId:             proto.Int64(visit.ID),
+						ArrivalTimestampSec: proto.Int64(now.Unix() + int64(i)),
+					}},
+					Pinned: proto.Bool(false),
+				})
+			}
+			shiftTeams = append(shiftTeams, &optimizerpb.VRPShiftTeam{
+				Id:                  proto.Int64(shiftTeam.ID),
+				RouteHistory:        &optimizerpb.VRPShiftTeamRouteHistory{},
+				UpcomingCommitments: &optimizerpb.VRPShiftTeamCommitments{},
+				Route:               &optimizerpb.VRPShiftTeamRoute{Stops: stops},
+			})
+		}
+		schedule, err := ldb.WriteScheduleForVRPSolution(ctx, &logisticsdb.WriteScheduleForVRPSolutionParams{
+			ServiceRegionID:  region.ID,
+			OptimizerRunID:   run.ID,
+			OptimizerVersion: "version",
+			Solution: &optimizerpb.VRPSolution{
+				Score:       &optimizerpb.VRPScore{},
+				Description: &optimizerpb.VRPDescription{ShiftTeams: shiftTeams},
+				TotalStats:  &optimizerpb.VRPStats{},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return schedule
+	}
+
+	schedule1 := addScheduleForRoutes(map[*logisticssql.ShiftTeamSnapshot][]*logisticssql.VisitSnapshot{
+		shiftTeam1: {visit1},
+		shiftTeam2: {visit2},
+	})
+	enRouteVisit1 := writeVisitSnapshot(ctx, t, baseCareRequestID, stationMarketID, ldb, logisticsdb.VisitPhaseTypeShortNameEnRoute, &shiftTeam2.ShiftTeamID)
+	schedule2 := addScheduleForRoutes(map[*logisticssql.ShiftTeamSnapshot][]*logisticssql.VisitSnapshot{
+		shiftTeam2: {enRouteVisit1, visit2},
+	})
+
+	shiftTeamLatLngs := []logistics.LatLng{{LatE6: 1, LngE6: 2}, {LatE6: 3, LngE6: 4}}
+	for _, latLng := range shiftTeamLatLngs {
+		testutils.MustFn(t)(ldb.UpdateShiftTeamLocation(ctx, time.Now(), shiftTeam1.ShiftTeamID, latLng))
+	}
+
+	visitLocations := map[logisticsdb.CareRequestID]logistics.LatLng{}
+	for _, visit := range []*logisticssql.VisitSnapshot{enRouteVisit1, visit2} {
+		visitLocs, err := ldb.GetLocationsByIDs(ctx, []int64{visit.LocationID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		visitLocations[logisticsdb.CareRequestID(visit.CareRequestID)] = logistics.LatLng{LatE6: visitLocs[0].LatitudeE6, LngE6: visitLocs[0].LongitudeE6}
+	}
+
+	playback, err = ldb.GetRoutePlayback(ctx, logisticsdb.RoutePlaybackParams{ScheduleID: schedule1.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testutils.MustMatch(t, []*logisticsdb.PlaybackSchedule{
+		{
+			ScheduleID:     schedule1.ID,
+			OptimizerRunID: schedule1.OptimizerRunID,
+			CreatedAt:      schedule1.CreatedAt,
+			Routes: map[logisticsdb.ShiftTeamID][]*logisticsdb.PlaybackStop{
+				shiftTeamID1: {{CareRequestID: careRequestID1, ArrivalTimestampSec: now.Unix()}},
+				shiftTeamID2: {{CareRequestID: careRequestID2, ArrivalTimestampSec: now.Unix()}},
+			},
+			UnassignedVisits: []logisticsdb.CareRequestID{},
+			Changes: []*logisticsdb.ScheduleChange{
+				{CareRequestID: careRequestID1, ToShiftTeamID: &shiftTeamID1},
+				{CareRequestID: careRequestID2, ToShiftTeamID: &shiftTeamID2},
+			},
+		},
+		{
+			ScheduleID:     schedule2.ID,
+			OptimizerRunID: schedule2.OptimizerRunID,
+			CreatedAt:      schedule2.CreatedAt,
+			Routes: map[logisticsdb.ShiftTeamID][]*logisticsdb.PlaybackStop{
+				shiftTeamID1: {},
+				shiftTeamID2: {
+					{CareRequestID: careRequestID1, ArrivalTimestampSec: now.Unix()},
+					{CareRequestID: careRequestID2, ArrivalTimestampSec: now.Unix() + 1},
+				},
+			},
+			UnassignedVisits: []logisticsdb.CareRequestID{},
+			Changes: []*logisticsdb.ScheduleChange{
+				{CareRequestID: careRequestID1, FromShiftTeamID: &shiftTeamID1, ToShiftTeamID: &shiftTeamID2},
+			},
+		},
+	}, playback.Schedules, "reassigned visit should be a change of the second schedule")
+	testutils.MustMatch(t, visitLocations, playback.VisitLocations)
+
+	type phase struct {
+		Phase       string
+		ShiftTeamID *logisticsdb.ShiftTeamID
+	}
+	visitPhases := map[logisticsdb.CareRequestID][]phase{}
+	for careRequestID, playbackPhases := range playback.VisitPhases {
+		for _, playbackPhase := range playbackPhases {
+			visitPhases[careRequestID] = append(visitPhases[careRequestID], phase{Phase: playbackPhase.Phase, ShiftTeamID: playbackPhase.ShiftTeamID})
+		}
+	}
+	testutils.MustMatch(t, map[logisticsdb.CareRequestID][]phase{
+		careRequestID1: {
+			{Phase: logisticsdb.VisitPhaseTypeShortNameCommitted.String()},
+			{Phase: logisticsdb.VisitPhaseTypeShortNameEnRoute.String(), ShiftTeamID: &shiftTeamID2},
+		},
+		careRequestID2: {
+			{Phase: logisticsdb.VisitPhaseTypeShortNameCommitted.String()},
+		},
+	}, visitPhases)
+
+	shiftTeamLocations := map[logisticsdb.ShiftTeamID][]logistics.LatLng{}
+	for shiftTeamID, playbackLocations := range playback.ShiftTeamLocations {
+		shiftTeamLocations[shiftTeamID] = []logistics.LatLng{}
+		for _, playbackLocation := range playbackLocations {
+			shiftTeamLocations[shiftTeamID] = append(shiftTeamLocations[shiftTeamID], playbackLocation.LatLng)
+		}
+	}
+	testutils.MustMatch(t, map[logisticsdb.ShiftTeamID][]logistics.LatLng{
+		shiftTeamID1: shiftTeamLatLngs,
+		shiftTeamID2: {},
+	}, shiftTeamLocations)
+}
+
 func TestGetLatestOptimizerRunForRegionDate(t *testing.T) {
 	ctx, db, queries, done := setupDBTest(t)
 	defer done()
@@ -4597,7 +5305,7 @@ func TestLDB_AddServiceRegionAvailabilityQuery(t *testing.T) {
 	ctx, db, _, done := setupDBTest(t)
 	defer done()
 	mapService := &mockDistanceMatrix{}
-	ldb := logisticsdb.NewLogisticsDB(db, logistics.NewMapServicePicker(mapService, mapService, mockSettingsService), mockSettingsService, monitoring.NewMockScope())
+	ldb := logisticsdb.NewLogisticsDB(db, logistics.NewMapServicePicker(mapService, nil, mapService, mockSettingsService, monitoring.NewMockScope()), mockSettingsService, monitoring.NewMockScope())
 
 	serviceRegionID := int64(100)
 	serviceDate := time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
@@ -4624,7 +5332,7 @@ func TestLogisticsDB_AddServiceRegionAvailabilityQueries(t *testing.T) {
 	ctx, db, _, done := setupDBTest(t)
 	defer done()
 	mapService := &mockDistanceMatrix{}
-	ldb := logisticsdb.NewLogisticsDB(db, logistics.NewMapServicePicker(mapService, mapService, mockSettingsService), mockSettingsService, monitoring.NewMockScope())
+	ldb := logisticsdb.NewLogisticsDB(db, logistics.NewMapServicePicker(mapService, nil, mapService, mockSettingsService, monitoring.NewMockScope()), mockSettingsService, monitoring.NewMockScope())
 
 	serviceRegionID := int64(100)
 	serviceDate := time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
@@ -4736,7 +5444,7 @@ func TestLDB_GetCheckFeasibilityCareRequestHistory(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	ldb := logisticsdb.NewLogisticsDB(db, logistics.NewMapServicePicker(mapService, mapService, mockSettingsService), mockSettingsService, monitoring.NewMockScope())
+	ldb := logisticsdb.NewLogisticsDB(db, logistics.NewMapServicePicker(mapService, nil, mapService, mockSettingsService, monitoring.NewMockScope()), mockSettingsService, monitoring.NewMockScope())
 	now := time.Now().UTC()
 	fakeID := now.UnixNano()
 	arrivalTWStart := now
@@ -5229,6 +5937,111 @@ func TestLDB_GetCheckFeasibilityQueriesForCareRequest(t *testing.T) {
 	testutils.MustMatch(t, wantAttributes, gotCfqData[1].Attributes, "values don't match")
 }
 
+func TestLDB_AddAssignmentRecommendations(t *testing.T) {
+	ctx, db, _, done := setupDBTest(t)
+	defer done()
+
+	ldb := logisticsdb.NewLogisticsDB(db, nil, noSettingsService, monitoring.NewMockScope())
+	careRequestID := time.Now().UnixNano()
+	recommendations := []*logisticsdb.AssignmentRecommendation{
+		{
+			CareRequestID: careRequestID,
+			ShiftTeamID:   2,
+			Explanation: &logisticspb.AssignmentExplanation{
+				AddedDriveDurationSec: proto.Int64(600),
+				LatenessRisk:          logisticspb.AssignmentExplanation_LATENESS_RISK_LOW,
+				RankingCost:           proto.Float64(10),
+			},
+		},
+		{
+			CareRequestID: careRequestID,
+			ShiftTeamID:   1,
+			Explanation: &logisticspb.AssignmentExplanation{
+				SoftAttributeMismatches: 1,
+				RankingCost:             proto.Float64(20),
+			},
+		},
+	}
+
+	err := ldb.AddAssignmentRecommendations(ctx, logisticsdb.AddAssignmentRecommendationsParams{
+		RankingPolicy:   logisticspb.AssignmentRankingPolicy_ASSIGNMENT_RANKING_POLICY_BALANCED,
+		Recommendations: recommendations,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logged, err := ldb.GetAssignmentRecommendationsForCareRequest(ctx, careRequestID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logged) != len(recommendations) {
+		t.Fatalf("expected %d recommendations, got %d", len(recommendations), len(logged))
+	}
+	for i, recommendation := range logged {
+		testutils.MustMatch(t, int32(i), recommendation.Rank)
+		testutils.MustMatch(t, recommendations[i].ShiftTeamID, recommendation.ShiftTeamID)
+		testutils.MustMatch(t, logisticspb.AssignmentRankingPolicy_ASSIGNMENT_RANKING_POLICY_BALANCED, recommendation.RankingPolicy)
+		testutils.MustMatchProto(t, recommendations[i].Explanation, recommendation.Explanation)
+	}
+}
+
+func TestLDB_GetServiceRegionDemandHistory(t *testing.T) {
+	ctx, db, _, done := setupDBTest(t)
+	defer done()
+
+	ldb := logisticsdb.NewLogisticsDB(db, nil, noSettingsService, monitoring.NewMockScope())
+	now := time.Now()
+	serviceRegionID := now.UnixNano()
+	serviceDate := time.Date(2023, time.September, 17, 0, 0, 0, 0, time.UTC)
+
+	for _, status := range []string{
+		logisticspb.ServiceRegionAvailability_STATUS_AVAILABLE.String(),
+		logisticspb.ServiceRegionAvailability_STATUS_UNAVAILABLE.String(),
+	} {
+		_, err := ldb.AddServiceRegionAvailabilityQuery(ctx, &logisticsdb.ServiceRegionAvailabilityQueryParams{
+			ServiceRegionID:   serviceRegionID,
+			ServiceDate:       serviceDate,
+			FeasibilityStatus: status,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := ldb.AddCheckFeasibilityQuery(ctx, logisticsdb.CheckFeasibilityQueryParams{
+		CareRequestID:   serviceRegionID,
+		ServiceRegionID: serviceRegionID,
+		ServiceDate:     serviceDate,
+		ResponseStatus:  logisticspb.CheckFeasibilityResponse_STATUS_INFEASIBLE.String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hours, err := ldb.GetServiceRegionDemandHistory(ctx, serviceRegionID, logisticsdb.TimeWindow{
+		Start: now.Add(-time.Hour),
+		End:   now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var total logisticsdb.DemandHour
+	for _, hour := range hours {
+		testutils.MustMatch(t, "", hour.AttributesKey())
+		total.AvailabilityQueries += hour.AvailabilityQueries
+		total.FailedAvailabilityQueries += hour.FailedAvailabilityQueries
+		total.FeasibilityQueries += hour.FeasibilityQueries
+		total.FailedFeasibilityQueries += hour.FailedFeasibilityQueries
+	}
+	testutils.MustMatch(t, logisticsdb.DemandHour{
+		AvailabilityQueries:       2,
+		FailedAvailabilityQueries: 1,
+		FeasibilityQueries:        1,
+		FailedFeasibilityQueries:  1,
+	}, total)
+}
+
 func TestLogisticsDB_GetAssignableVisitsForDate(t *testing.T) {
 	ctx, db, queries, done := setupDBTest(t)
 	defer done()
@@ -6182,3 +6995,60 @@ func TestLogisticsDB_HasAnyNewScheduleSinceLastAvailabilityRun(t *testing.T) {
 
 	testutils.MustMatch(t, false, result)
 }
+
+func TestLogisticsDB_GetLatestScheduleForServiceRegionDate(t *testing.T) {
+	ctx, db, queries, done := setupDBTest(t)
+	defer done()
+
+	ldb := logisticsdb.NewLogisticsDB(db, nil, mockSettingsService, monitoring.NewMockScope())
+
+	serviceRegionID := time.Now().UnixNano()
+	serviceDate := time.Date(2023, time.October, 31, 0, 0, 0, 0, time.UTC)
+	snapshotTime := time.Now().Truncate(time.Second)
+
+	_, err := ldb.GetLatestScheduleForServiceRegionDate(ctx, serviceRegionID, serviceDate, snapshotTime)
+	if !errors.Is(err, logisticsdb.ErrScheduleNotFound) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var schedules []*logisticssql.Schedule
+	for _, runType := range []logisticsdb.OptimizerRunType{
+		logisticsdb.ServiceRegionScheduleRunType,
+		logisticsdb.ServiceRegionAvailabilityRunType,
+	} {
+		optimizerRun, err := queries.AddOptimizerRun(ctx, logisticssql.AddOptimizerRunParams{
+			ServiceRegionID:   serviceRegionID,
+			ServiceDate:       serviceDate,
+			SnapshotTimestamp: snapshotTime,
+			OptimizerRunType:  string(runType),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		schedule, err := queries.AddSchedule(ctx, logisticssql.AddScheduleParams{
+			ServiceRegionID: serviceRegionID,
+			OptimizerRunID:  optimizerRun.ID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	_, err = ldb.GetLatestScheduleForServiceRegionDate(ctx, serviceRegionID, serviceDate.AddDate(0, 0, 1), schedules[1].CreatedAt)
+	if !errors.Is(err, logisticsdb.ErrScheduleNotFound) {
+		t.Fatalf("unexpected error for other service date: %v", err)
+	}
+
+	schedule, err := ldb.GetLatestScheduleForServiceRegionDate(ctx, serviceRegionID, serviceDate, schedules[1].CreatedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedSchedule, err := ldb.GetShiftTeamsSchedulesFromScheduleID(ctx, schedules[0].ID, schedules[1].CreatedAt, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutils.MustMatch(t, expectedSchedule.Schedule, schedule)
+}
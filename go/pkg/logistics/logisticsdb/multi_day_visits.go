@@ -0,0 +1,209 @@
+package logisticsdb
+
+import (
+	"context"
+	"time"
+
+	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+)
+
+// Minimum arrival time window of visits that may be scheduled on any service date of their window,
+// such as follow ups due within a few days.
+const multiDayVisitMinArrivalWindow = 24 * time.Hour
+
+// MultiDayVisit is a visit with an arrival time window spanning multiple service dates.
+type MultiDayVisit struct {
+	CareRequestID CareRequestID
+
+	// First and last service dates of the arrival time window, in the service region time zone.
+	StartServiceDate time.Time
+	EndServiceDate   time.Time
+}
+
+// MultiDayVisits are the multi-day visits of a VRP problem.
+type MultiDayVisits map[VisitSnapshotID]*MultiDayVisit
+
+func newMultiDayVisit(visit *logisticssql.GetLatestVisitSnapshotsInRegionRow, tz *time.Location) *MultiDayVisit {
+	if !visit.ArrivalStartTimestampSec.Valid || !visit.ArrivalEndTimestampSec.Valid {
+		return nil
+	}
+	arrivalStart := time.Unix(visit.ArrivalStartTimestampSec.Int64, 0)
+	arrivalEnd := time.Unix(visit.ArrivalEndTimestampSec.Int64, 0)
+	if arrivalEnd.Sub(arrivalStart) < multiDayVisitMinArrivalWindow {
+		return nil
+	}
+
+	return &MultiDayVisit{
+		CareRequestID:    CareRequestID(visit.CareRequestID),
+		StartServiceDate: TimestampToDate(arrivalStart.In(tz)),
+		EndServiceDate:   TimestampToDate(arrivalEnd.In(tz)),
+	}
+}
+
+func newMultiDayVisits(visits []*logisticssql.GetLatestVisitSnapshotsInRegionRow, tz *time.Location) MultiDayVisits {
+	res := MultiDayVisits{}
+	for _, visit := range visits {
+		if multiDayVisit := newMultiDayVisit(visit, tz); multiDayVisit != nil {
+			res[VisitSnapshotID(visit.ID)] = multiDayVisit
+		}
+	}
+	return res
+}
+
+func (v *MultiDayVisit) hasServiceDate(serviceDate time.Time) bool {
+	return !serviceDate.Before(v.StartServiceDate) && !serviceDate.After(v.EndServiceDate)
+}
+
+// scheduledOnOtherServiceDate returns whether the latest service date of the visit is another date of its arrival time window.
+//
+// Service dates outside of the arrival time window, such as after the window was changed, are ignored.
+func (v *MultiDayVisit) scheduledOnOtherServiceDate(latest *logisticssql.CareRequestServiceDate, serviceDate time.Time) bool {
+	if latest == nil || latest.IsReleased || latest.ServiceDate.Equal(serviceDate) {
+		return false
+	}
+
+	return v.hasServiceDate(latest.ServiceDate)
+}
+
+func (ldb *LogisticsDB) latestCareRequestServiceDates(
+	ctx context.Context,
+	queries *logisticssql.Queries,
+	multiDayVisits MultiDayVisits,
+	latestSnapshotTime time.Time,
+) (map[CareRequestID]*logisticssql.CareRequestServiceDate, error) {
+	careRequestIDs := make([]int64, 0, len(multiDayVisits))
+	for _, visit := range multiDayVisits {
+		careRequestIDs = append(careRequestIDs, int64(visit.CareRequestID))
+	}
+	rows, err := queries.GetLatestCareRequestServiceDates(ctx, logisticssql.GetLatestCareRequestServiceDatesParams{
+		CareRequestIds:     careRequestIDs,
+		LatestSnapshotTime: latestSnapshotTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[CareRequestID]*logisticssql.CareRequestServiceDate, len(rows))
+	for _, row := range rows {
+		res[CareRequestID(row.CareRequestID)] = row
+	}
+	return res, nil
+}
+
+type visitsForServiceDateParams struct {
+	visits       []*logisticssql.GetLatestVisitSnapshotsInRegionRow
+	serviceDate  time.Time
+	tz           *time.Location
+	snapshotTime time.Time
+}
+
+// visitsForServiceDate removes the multi-day visits already scheduled on another service date of their window.
+func (ldb *LogisticsDB) visitsForServiceDate(ctx context.Context, params visitsForServiceDateParams) ([]*logisticssql.GetLatestVisitSnapshotsInRegionRow, error) {
+	multiDayVisits := newMultiDayVisits(params.visits, params.tz)
+	if len(multiDayVisits) == 0 {
+		return params.visits, nil
+	}
+
+	latestServiceDates, err := ldb.latestCareRequestServiceDates(ctx, ldb.queries, multiDayVisits, params.snapshotTime)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*logisticssql.GetLatestVisitSnapshotsInRegionRow
+	for _, visit := range params.visits {
+		multiDayVisit, ok := multiDayVisits[VisitSnapshotID(visit.ID)]
+		if ok && multiDayVisit.scheduledOnOtherServiceDate(latestServiceDates[multiDayVisit.CareRequestID], params.serviceDate) {
+			continue
+		}
+		res = append(res, visit)
+	}
+	return res, nil
+}
+
+type careRequestServiceDateChangesParams struct {
+	solution           *optimizerpb.VRPSolution
+	multiDayVisits     MultiDayVisits
+	latestServiceDates map[CareRequestID]*logisticssql.CareRequestServiceDate
+	serviceDate        time.Time
+}
+
+// careRequestServiceDateChanges returns the service date changes of the multi-day visits in the solution.
+//
+// Assigned visits are scheduled on the service date, unless another date was chosen first.
+// Unassigned visits scheduled on the service date are released, so other dates of their window may assign them.
+func careRequestServiceDateChanges(params careRequestServiceDateChangesParams) (careRequestIDs []int64, isReleased []bool) {
+	desc := params.solution.GetDescription()
+	for _, shiftTeam := range desc.GetShiftTeams() {
+		for _, stop := range shiftTeam.GetRoute().GetStops() {
+			visit := stop.GetVisit()
+			if visit == nil {
+				continue
+			}
+			multiDayVisit, ok := params.multiDayVisits[VisitSnapshotID(visit.GetVisitId())]
+			if !ok {
+				continue
+			}
+			latest := params.latestServiceDates[multiDayVisit.CareRequestID]
+			if multiDayVisit.scheduledOnOtherServiceDate(latest, params.serviceDate) {
+				continue
+			}
+			if latest != nil && !latest.IsReleased && latest.ServiceDate.Equal(params.serviceDate) {
+				continue
+			}
+			careRequestIDs = append(careRequestIDs, int64(multiDayVisit.CareRequestID))
+			isReleased = append(isReleased, false)
+		}
+	}
+
+	for _, unassignedVisit := range desc.GetUnassignedVisits() {
+		multiDayVisit, ok := params.multiDayVisits[VisitSnapshotID(unassignedVisit.GetVisitId())]
+		if !ok {
+			continue
+		}
+		latest := params.latestServiceDates[multiDayVisit.CareRequestID]
+		if latest == nil || latest.IsReleased || !latest.ServiceDate.Equal(params.serviceDate) {
+			continue
+		}
+		careRequestIDs = append(careRequestIDs, int64(multiDayVisit.CareRequestID))
+		isReleased = append(isReleased, true)
+	}
+
+	return careRequestIDs, isReleased
+}
+
+type writeCareRequestServiceDatesParams struct {
+	schedule       *logisticssql.Schedule
+	serviceDate    time.Time
+	solution       *optimizerpb.VRPSolution
+	multiDayVisits MultiDayVisits
+}
+
+func (ldb *LogisticsDB) writeCareRequestServiceDates(ctx context.Context, queries *logisticssql.Queries, params writeCareRequestServiceDatesParams) error {
+	if len(params.multiDayVisits) == 0 {
+		return nil
+	}
+
+	latestServiceDates, err := ldb.latestCareRequestServiceDates(ctx, queries, params.multiDayVisits, params.schedule.CreatedAt)
+	if err != nil {
+		return err
+	}
+	careRequestIDs, isReleased := careRequestServiceDateChanges(careRequestServiceDateChangesParams{
+		solution:           params.solution,
+		multiDayVisits:     params.multiDayVisits,
+		latestServiceDates: latestServiceDates,
+		serviceDate:        params.serviceDate,
+	})
+	if len(careRequestIDs) == 0 {
+		return nil
+	}
+
+	_, err = queries.AddCareRequestServiceDates(ctx, logisticssql.AddCareRequestServiceDatesParams{
+		CareRequestIds:  careRequestIDs,
+		ServiceRegionID: params.schedule.ServiceRegionID,
+		ServiceDate:     params.serviceDate,
+		ScheduleID:      params.schedule.ID,
+		IsReleased:      isReleased,
+	})
+	return err
+}
@@ -0,0 +1,150 @@
+package logisticsdb
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestNewMultiDayVisits(t *testing.T) {
+	tz, err := time.LoadLocation("America/Denver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2023, time.September, 12, 20, 0, 0, 0, tz)
+	visit := func(id int64, arrivalWindow time.Duration) *logisticssql.GetLatestVisitSnapshotsInRegionRow {
+		return &logisticssql.GetLatestVisitSnapshotsInRegionRow{
+			ID:                       id,
+			CareRequestID:            id + 100,
+			ArrivalStartTimestampSec: sql.NullInt64{Int64: start.Unix(), Valid: true},
+			ArrivalEndTimestampSec:   sql.NullInt64{Int64: start.Add(arrivalWindow).Unix(), Valid: true},
+		}
+	}
+
+	multiDayVisits := newMultiDayVisits([]*logisticssql.GetLatestVisitSnapshotsInRegionRow{
+		visit(1, 4*time.Hour),
+		visit(2, 3*24*time.Hour),
+		{ID: 3, CareRequestID: 103},
+	}, tz)
+
+	testutils.MustMatch(t, MultiDayVisits{
+		2: {
+			CareRequestID: 102,
+			// Service dates are in the service region time zone, even though the window starts on 09/13 in UTC.
+			StartServiceDate: time.Date(2023, time.September, 12, 0, 0, 0, 0, time.UTC),
+			EndServiceDate:   time.Date(2023, time.September, 15, 0, 0, 0, 0, time.UTC),
+		},
+	}, multiDayVisits)
+}
+
+func TestMultiDayVisitScheduledOnOtherServiceDate(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2023, time.September, d, 0, 0, 0, 0, time.UTC)
+	}
+	visit := &MultiDayVisit{CareRequestID: 1, StartServiceDate: day(12), EndServiceDate: day(14)}
+
+	tcs := []struct {
+		Desc   string
+		Latest *logisticssql.CareRequestServiceDate
+
+		Expected bool
+	}{
+		{
+			Desc: "no service date",
+
+			Expected: false,
+		},
+		{
+			Desc:   "same service date",
+			Latest: &logisticssql.CareRequestServiceDate{ServiceDate: day(12)},
+
+			Expected: false,
+		},
+		{
+			Desc:   "other service date",
+			Latest: &logisticssql.CareRequestServiceDate{ServiceDate: day(13)},
+
+			Expected: true,
+		},
+		{
+			Desc:   "released other service date",
+			Latest: &logisticssql.CareRequestServiceDate{ServiceDate: day(13), IsReleased: true},
+
+			Expected: false,
+		},
+		{
+			Desc:   "other service date outside of arrival window",
+			Latest: &logisticssql.CareRequestServiceDate{ServiceDate: day(15)},
+
+			Expected: false,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			testutils.MustMatch(t, tc.Expected, visit.scheduledOnOtherServiceDate(tc.Latest, day(12)))
+		})
+	}
+}
+
+func TestCareRequestServiceDateChanges(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2023, time.September, d, 0, 0, 0, 0, time.UTC)
+	}
+	routeStop := func(visitID int64) *optimizerpb.VRPShiftTeamRouteStop {
+		return &optimizerpb.VRPShiftTeamRouteStop{
+			Stop: &optimizerpb.VRPShiftTeamRouteStop_Visit{Visit: &optimizerpb.VRPShiftTeamVisit{VisitId: proto.Int64(visitID)}},
+		}
+	}
+	multiDayVisits := MultiDayVisits{}
+	for id := int64(1); id <= 6; id++ {
+		multiDayVisits[VisitSnapshotID(id)] = &MultiDayVisit{CareRequestID: CareRequestID(id + 100), StartServiceDate: day(12), EndServiceDate: day(14)}
+	}
+	solution := &optimizerpb.VRPSolution{
+		Description: &optimizerpb.VRPDescription{
+			ShiftTeams: []*optimizerpb.VRPShiftTeam{
+				{
+					Id: proto.Int64(1),
+					Route: &optimizerpb.VRPShiftTeamRoute{Stops: []*optimizerpb.VRPShiftTeamRouteStop{
+						// Not a multi-day visit.
+						routeStop(10),
+						// New service date.
+						routeStop(1),
+						// Already scheduled on the service date.
+						routeStop(2),
+						// Scheduled on another service date first.
+						routeStop(3),
+					}},
+				},
+			},
+			UnassignedVisits: []*optimizerpb.VRPUnassignedVisit{
+				// Released from the service date.
+				{VisitId: proto.Int64(4)},
+				// Scheduled on another service date.
+				{VisitId: proto.Int64(5)},
+				// Never scheduled.
+				{VisitId: proto.Int64(6)},
+			},
+		},
+	}
+
+	careRequestIDs, isReleased := careRequestServiceDateChanges(careRequestServiceDateChangesParams{
+		solution:       solution,
+		multiDayVisits: multiDayVisits,
+		latestServiceDates: map[CareRequestID]*logisticssql.CareRequestServiceDate{
+			102: {ServiceDate: day(12)},
+			103: {ServiceDate: day(13)},
+			104: {ServiceDate: day(12)},
+			105: {ServiceDate: day(13)},
+		},
+		serviceDate: day(12),
+	})
+
+	testutils.MustMatch(t, []int64{101, 104}, careRequestIDs)
+	testutils.MustMatch(t, []bool{false, true}, isReleased)
+}
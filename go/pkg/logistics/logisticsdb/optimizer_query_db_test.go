@@ -5,6 +5,7 @@ package logisticsdb_test
 import (
 	"database/sql"
 	"reflect"
+	"sort"
 	"strconv"
 	"testing"
 	"time"
@@ -817,3 +818,91 @@ func TestGetAttributesForCheckFeasibilityQuery(t *testing.T) {
 
 	testutils.MustMatch(t, attributeNames, resultAttributeNames, "values don't match")
 }
+
+func TestGetLatestShiftTeamGeofenceEvents(t *testing.T) {
+	ctx, _, queries, done := setupDBTest(t)
+	defer done()
+
+	shiftTeamID := time.Now().UnixNano()
+	careRequestID := time.Now().UnixNano()
+	eventTimestamp := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	arrival, err := queries.AddShiftTeamGeofenceEvent(ctx, logisticssql.AddShiftTeamGeofenceEventParams{
+		ShiftTeamID:    shiftTeamID,
+		CareRequestID:  careRequestID,
+		EventType:      "arrival",
+		EventTimestamp: eventTimestamp,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	departure, err := queries.AddShiftTeamGeofenceEvent(ctx, logisticssql.AddShiftTeamGeofenceEventParams{
+		ShiftTeamID:    shiftTeamID,
+		CareRequestID:  careRequestID,
+		EventType:      "departure",
+		EventTimestamp: eventTimestamp.Add(30 * time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := queries.GetLatestShiftTeamGeofenceEvents(ctx, logisticssql.GetLatestShiftTeamGeofenceEventsParams{
+		CareRequestIds:     []int64{careRequestID},
+		LatestSnapshotTime: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutils.MustMatch(t, []*logisticssql.ShiftTeamGeofenceEvent{arrival, departure}, events)
+
+	events, err = queries.GetLatestShiftTeamGeofenceEvents(ctx, logisticssql.GetLatestShiftTeamGeofenceEventsParams{
+		CareRequestIds:     []int64{careRequestID},
+		LatestSnapshotTime: arrival.CreatedAt.Add(-time.Second),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutils.MustMatch(t, 0, len(events), "events created after the snapshot time are ignored")
+}
+
+func TestGetScheduleRouteCareRequestIDs(t *testing.T) {
+	ctx, _, queries, done := setupDBTest(t)
+	defer done()
+
+	baseID := time.Now().UnixNano()
+	scheduleID := baseID
+	scheduleRouteID := baseID + 1
+	otherScheduleRouteID := baseID + 2
+	careRequestIDs := []int64{baseID + 3, baseID + 4}
+	otherCareRequestID := baseID + 5
+
+	addScheduleVisit := func(careRequestID, scheduleRouteID int64) {
+		visitSnapshot, err := queries.AddVisitSnapshot(ctx, logisticssql.AddVisitSnapshotParams{
+			CareRequestID:   careRequestID,
+			ServiceRegionID: baseID,
+			LocationID:      baseID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = queries.AddScheduleVisit(ctx, logisticssql.AddScheduleVisitParams{
+			ScheduleID:      scheduleID,
+			ScheduleRouteID: scheduleRouteID,
+			VisitSnapshotID: sqltypes.ToValidNullInt64(visitSnapshot.ID),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, careRequestID := range careRequestIDs {
+		addScheduleVisit(careRequestID, scheduleRouteID)
+	}
+	addScheduleVisit(otherCareRequestID, otherScheduleRouteID)
+
+	routeCareRequestIDs, err := queries.GetScheduleRouteCareRequestIDs(ctx, scheduleRouteID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(routeCareRequestIDs, func(i, j int) bool { return routeCareRequestIDs[i] < routeCareRequestIDs[j] })
+	testutils.MustMatch(t, careRequestIDs, routeCareRequestIDs)
+}
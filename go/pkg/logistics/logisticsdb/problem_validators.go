@@ -1,9 +1,12 @@
 package logisticsdb
 
 import (
+	"fmt"
+
 	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
 	"github.com/*company-data-covered*/services/go/pkg/logistics/validation"
 	"github.com/*company-data-covered*/services/go/pkg/monitoring"
+	"google.golang.org/protobuf/proto"
 )
 
 const (
@@ -31,10 +34,23 @@ var (
 		h.validateArrivalsAreBeforeCompletions,
 		h.validateAtMostOneEnRouteStop,
 		h.validateEnRouteStopMustBeAtEndOfRouteHistory,
+		validateUnassignedVisitsAreInProblem,
 		// TODO: validate current position timestamp after these mods?
 		// TODO: validate current position invariants.
 		// TODO: validate that a given visit only shows up in one place.
 	}
+
+	// RepairableProblemValidators are the DefaultProblemValidators, and the validators of visits
+	// whose errors can be repaired in validation repair mode.
+	RepairableProblemValidators = append(
+		append([]validation.ProblemValidator{}, DefaultProblemValidators...),
+		validateVisitsHaveArrivalTimeWindows,
+		validateVisitArrivalTimeWindowsAreOrdered,
+		validateVisitLocations,
+		validateVisitRequiredAttributes,
+		// Run again, as the solver requires unassigned visits to be in the repaired problem.
+		validateUnassignedVisitsAreInProblem,
+	)
 )
 
 func hasDescription(problem *optimizerpb.VRPProblem) *validation.Error {
@@ -195,3 +211,209 @@ func (h routeHistoryValidator) validateAtMostOneEnRouteStop(problem *optimizerpb
 	}
 	return err
 }
+
+func visitIDsInRouteHistory(desc *optimizerpb.VRPDescription) map[int64]bool {
+	res := map[int64]bool{}
+	for _, st := range desc.GetShiftTeams() {
+		for _, stop := range st.GetRouteHistory().GetStops() {
+			if visit := stop.GetVisit(); visit != nil {
+				res[visit.GetVisitId()] = true
+			}
+		}
+	}
+	return res
+}
+
+// dropVisitRepair drops a visit from the problem, so that the rest of the problem can still be optimized.
+// The visit is not added to the unassigned visits, as the solver requires those to be in the problem;
+// it is surfaced as unassignable from the repair report instead.
+func dropVisitRepair(visitID int64, reason string) *validation.Repair {
+	return &validation.Repair{
+		Description: fmt.Sprintf("dropped visit %d as unassignable: %s", visitID, reason),
+		Apply: func(problem *optimizerpb.VRPProblem) {
+			desc := problem.GetDescription()
+
+			var visits []*optimizerpb.VRPVisit
+			for _, visit := range desc.GetVisits() {
+				if visit.GetId() != visitID {
+					visits = append(visits, visit)
+				}
+			}
+			desc.Visits = visits
+
+			for _, st := range desc.GetShiftTeams() {
+				if st.GetUpcomingCommitments() == nil {
+					continue
+				}
+				var commitments []*optimizerpb.VRPShiftTeamCommitment
+				for _, commitment := range st.GetUpcomingCommitments().GetCommitments() {
+					if commitment.GetVisitId() != visitID {
+						commitments = append(commitments, commitment)
+					}
+				}
+				st.UpcomingCommitments.Commitments = commitments
+			}
+
+			var unassignedVisits []*optimizerpb.VRPUnassignedVisit
+			for _, unassigned := range desc.GetUnassignedVisits() {
+				if unassigned.GetVisitId() != visitID {
+					unassignedVisits = append(unassignedVisits, unassigned)
+				}
+			}
+			desc.UnassignedVisits = unassignedVisits
+		},
+		DroppedVisitID: proto.Int64(visitID),
+	}
+}
+
+// validateUnassignedVisitsAreInProblem validates that unassigned visits reference visits of the problem,
+// as the solver looks them up to pin them. Unassigned visits that do not are removed.
+func validateUnassignedVisitsAreInProblem(problem *optimizerpb.VRPProblem) *validation.Error {
+	desc := problem.GetDescription()
+	visitIDs := map[int64]bool{}
+	for _, visit := range desc.GetVisits() {
+		visitIDs[visit.GetId()] = true
+	}
+
+	var err *validation.Error
+	var unassignedVisits []*optimizerpb.VRPUnassignedVisit
+	for _, unassigned := range desc.GetUnassignedVisits() {
+		if !visitIDs[unassigned.GetVisitId()] {
+			err = &validation.Error{
+				Name:        "unknown_unassigned_visit",
+				Msg:         "unassigned visits must be in the problem visits",
+				Recoverable: true,
+				Fields:      monitoring.Fields{visitSnapshotIDField: unassigned.GetVisitId()},
+			}
+			continue
+		}
+		unassignedVisits = append(unassignedVisits, unassigned)
+	}
+	if err != nil {
+		desc.UnassignedVisits = unassignedVisits
+	}
+	return err
+}
+
+// invalidVisitsError returns an error for invalid visits, that can be repaired by dropping the visits
+// that are not in any route history yet.
+func invalidVisitsError(name, msg string, invalidVisitIDs []int64, routeHistoryVisitIDs map[int64]bool) *validation.Error {
+	if len(invalidVisitIDs) == 0 {
+		return nil
+	}
+
+	var repairs []*validation.Repair
+	for _, visitID := range invalidVisitIDs {
+		if routeHistoryVisitIDs[visitID] {
+			// Visits that were already started cannot be dropped.
+			return &validation.Error{
+				Name:   name,
+				Msg:    msg,
+				Fields: monitoring.Fields{visitSnapshotIDField: visitID},
+			}
+		}
+		repairs = append(repairs, dropVisitRepair(visitID, msg))
+	}
+	return &validation.Error{
+		Name:    name,
+		Msg:     msg,
+		Fields:  monitoring.Fields{visitSnapshotIDField: invalidVisitIDs[0]},
+		Repairs: repairs,
+	}
+}
+
+func validateVisitsHaveArrivalTimeWindows(problem *optimizerpb.VRPProblem) *validation.Error {
+	desc := problem.GetDescription()
+
+	var invalidVisitIDs []int64
+	for _, visit := range desc.GetVisits() {
+		tw := visit.GetArrivalTimeWindow()
+		if tw == nil || tw.StartTimestampSec == nil || tw.EndTimestampSec == nil {
+			invalidVisitIDs = append(invalidVisitIDs, visit.GetId())
+		}
+	}
+	return invalidVisitsError(
+		"missing_visit_arrival_time_window",
+		"visits must have an arrival time window",
+		invalidVisitIDs,
+		visitIDsInRouteHistory(desc),
+	)
+}
+
+func validateVisitArrivalTimeWindowsAreOrdered(problem *optimizerpb.VRPProblem) *validation.Error {
+	var repairs []*validation.Repair
+	var firstVisitID int64
+	for _, visit := range problem.GetDescription().GetVisits() {
+		tw := visit.GetArrivalTimeWindow()
+		if tw.GetStartTimestampSec() <= tw.GetEndTimestampSec() {
+			continue
+		}
+
+		if repairs == nil {
+			firstVisitID = visit.GetId()
+		}
+		visitID := visit.GetId()
+		end := tw.GetEndTimestampSec()
+		repairs = append(repairs, &validation.Repair{
+			Description: fmt.Sprintf("clamped inverted arrival time window of visit %d to its end %d", visitID, end),
+			Apply: func(problem *optimizerpb.VRPProblem) {
+				for _, visit := range problem.GetDescription().GetVisits() {
+					if visit.GetId() == visitID {
+						visit.ArrivalTimeWindow.StartTimestampSec = proto.Int64(end)
+					}
+				}
+			},
+		})
+	}
+	if repairs == nil {
+		return nil
+	}
+
+	return &validation.Error{
+		Name:    "inverted_visit_arrival_time_window",
+		Msg:     "visit arrival time windows must start before they end",
+		Fields:  monitoring.Fields{visitSnapshotIDField: firstVisitID},
+		Repairs: repairs,
+	}
+}
+
+func validateVisitLocations(problem *optimizerpb.VRPProblem) *validation.Error {
+	desc := problem.GetDescription()
+	locationIDs := map[int64]bool{}
+	for _, loc := range desc.GetLocations() {
+		locationIDs[loc.GetId()] = true
+	}
+
+	var invalidVisitIDs []int64
+	for _, visit := range desc.GetVisits() {
+		if !locationIDs[visit.GetLocationId()] {
+			invalidVisitIDs = append(invalidVisitIDs, visit.GetId())
+		}
+	}
+	return invalidVisitsError(
+		"unknown_visit_location",
+		"visit locations must be in the problem locations",
+		invalidVisitIDs,
+		visitIDsInRouteHistory(desc),
+	)
+}
+
+func validateVisitRequiredAttributes(problem *optimizerpb.VRPProblem) *validation.Error {
+	desc := problem.GetDescription()
+
+	var invalidVisitIDs []int64
+	for _, visit := range desc.GetVisits() {
+		for _, attr := range visit.GetRequiredAttributes() {
+			if attr.GetId() == "" {
+				invalidVisitIDs = append(invalidVisitIDs, visit.GetId())
+				break
+			}
+		}
+	}
+	return invalidVisitsError(
+		"unknown_visit_required_attribute",
+		"visit required attributes must have an id",
+		invalidVisitIDs,
+		visitIDsInRouteHistory(desc),
+	)
+}
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/validation"
+	"github.com/*company-data-covered*/services/go/pkg/monitoring"
 	"github.com/*company-data-covered*/services/go/pkg/testutils"
 	"google.golang.org/protobuf/proto"
 )
@@ -109,3 +111,216 @@ func TestValidateEnRouteStopMustBeAtEndOfRouteHistory(t *testing.T) {
 	testutils.MustMatch(t, true, err != nil, "should have an error")
 	testutils.MustMatch(t, true, err.Recoverable, "and should be recoverable")
 }
+
+func repairedProblem(t *testing.T, problem *optimizerpb.VRPProblem, err *validation.Error) *optimizerpb.VRPProblem {
+	t.Helper()
+
+	repaired := proto.Clone(problem).(*optimizerpb.VRPProblem)
+	for _, repair := range err.Repairs {
+		repair.Apply(repaired)
+	}
+	return repaired
+}
+
+func TestValidateVisitsHaveArrivalTimeWindows(t *testing.T) {
+	validVisit := &optimizerpb.VRPVisit{
+		Id: proto.Int64(1),
+		ArrivalTimeWindow: &optimizerpb.VRPTimeWindow{
+			StartTimestampSec: proto.Int64(10),
+			EndTimestampSec:   proto.Int64(20),
+		},
+	}
+	err := validateVisitsHaveArrivalTimeWindows(&optimizerpb.VRPProblem{Description: &optimizerpb.VRPDescription{
+		Visits: []*optimizerpb.VRPVisit{validVisit},
+	}})
+	testutils.MustMatch(t, true, err == nil, "no error for valid visit")
+
+	problem := &optimizerpb.VRPProblem{Description: &optimizerpb.VRPDescription{
+		Visits: []*optimizerpb.VRPVisit{validVisit, {Id: proto.Int64(2)}},
+		ShiftTeams: []*optimizerpb.VRPShiftTeam{
+			{
+				UpcomingCommitments: &optimizerpb.VRPShiftTeamCommitments{
+					Commitments: []*optimizerpb.VRPShiftTeamCommitment{
+						{VisitId: proto.Int64(2)},
+						{VisitId: proto.Int64(1)},
+					},
+				},
+			},
+		},
+	}}
+	err = validateVisitsHaveArrivalTimeWindows(problem)
+	testutils.MustMatch(t, true, err != nil, "has error")
+	testutils.MustMatch(t, false, err.Recoverable, "and should not be recoverable")
+	testutils.MustMatch(t, 1, len(err.Repairs), "but can be repaired")
+	testutils.MustMatch(t, &optimizerpb.VRPProblem{Description: &optimizerpb.VRPDescription{
+		Visits: []*optimizerpb.VRPVisit{validVisit},
+		ShiftTeams: []*optimizerpb.VRPShiftTeam{
+			{
+				UpcomingCommitments: &optimizerpb.VRPShiftTeamCommitments{
+					Commitments: []*optimizerpb.VRPShiftTeamCommitment{
+						{VisitId: proto.Int64(1)},
+					},
+				},
+			},
+		},
+	}}, repairedProblem(t, problem, err), "visit is dropped, without being added to the unassigned visits")
+	testutils.MustMatch(t, proto.Int64(2), err.Repairs[0].DroppedVisitID, "dropped visit is reported")
+
+	problem.Description.ShiftTeams = []*optimizerpb.VRPShiftTeam{
+		{
+			RouteHistory: &optimizerpb.VRPShiftTeamRouteHistory{
+				Stops: []*optimizerpb.VRPShiftTeamRouteStop{
+					{Stop: &optimizerpb.VRPShiftTeamRouteStop_Visit{Visit: &optimizerpb.VRPShiftTeamVisit{VisitId: proto.Int64(2)}}},
+				},
+			},
+		},
+	}
+	err = validateVisitsHaveArrivalTimeWindows(problem)
+	testutils.MustMatch(t, true, err != nil, "has error")
+	testutils.MustMatch(t, 0, len(err.Repairs), "and cannot be repaired for visits in route history")
+}
+
+func TestValidateVisitArrivalTimeWindowsAreOrdered(t *testing.T) {
+	problem := &optimizerpb.VRPProblem{Description: &optimizerpb.VRPDescription{
+		Visits: []*optimizerpb.VRPVisit{
+			{
+				Id: proto.Int64(1),
+				ArrivalTimeWindow: &optimizerpb.VRPTimeWindow{
+					StartTimestampSec: proto.Int64(30),
+					EndTimestampSec:   proto.Int64(20),
+				},
+			},
+		},
+	}}
+	err := validateVisitArrivalTimeWindowsAreOrdered(problem)
+	testutils.MustMatch(t, true, err != nil, "has error")
+	testutils.MustMatch(t, false, err.Recoverable, "and should not be recoverable")
+
+	repaired := repairedProblem(t, problem, err)
+	testutils.MustMatch(t, &optimizerpb.VRPTimeWindow{
+		StartTimestampSec: proto.Int64(20),
+		EndTimestampSec:   proto.Int64(20),
+	}, repaired.Description.Visits[0].ArrivalTimeWindow, "window is clamped to its end")
+
+	err = validateVisitArrivalTimeWindowsAreOrdered(repaired)
+	testutils.MustMatch(t, true, err == nil, "no error once repaired")
+}
+
+func TestValidateVisitLocations(t *testing.T) {
+	problem := &optimizerpb.VRPProblem{Description: &optimizerpb.VRPDescription{
+		Locations: []*optimizerpb.VRPLocation{{Id: proto.Int64(1)}},
+		Visits: []*optimizerpb.VRPVisit{
+			{Id: proto.Int64(1), LocationId: proto.Int64(1)},
+			{Id: proto.Int64(2), LocationId: proto.Int64(2)},
+		},
+	}}
+	err := validateVisitLocations(problem)
+	testutils.MustMatch(t, true, err != nil, "has error")
+	testutils.MustMatch(t, false, err.Recoverable, "and should not be recoverable")
+
+	repaired := repairedProblem(t, problem, err)
+	testutils.MustMatch(t, []int64{1}, problemVisitIDs(repaired), "visit with unknown location is dropped")
+
+	err = validateVisitLocations(repaired)
+	testutils.MustMatch(t, true, err == nil, "no error once repaired")
+}
+
+func TestValidateVisitRequiredAttributes(t *testing.T) {
+	problem := &optimizerpb.VRPProblem{Description: &optimizerpb.VRPDescription{
+		Visits: []*optimizerpb.VRPVisit{
+			{Id: proto.Int64(1), RequiredAttributes: []*optimizerpb.VRPAttribute{{Id: "covid"}}},
+			{Id: proto.Int64(2), RequiredAttributes: []*optimizerpb.VRPAttribute{{Id: "covid"}, {Id: ""}}},
+		},
+	}}
+	err := validateVisitRequiredAttributes(problem)
+	testutils.MustMatch(t, true, err != nil, "has error")
+	testutils.MustMatch(t, false, err.Recoverable, "and should not be recoverable")
+
+	repaired := repairedProblem(t, problem, err)
+	testutils.MustMatch(t, []int64{1}, problemVisitIDs(repaired), "visit with unknown attribute is dropped")
+}
+
+func TestValidateUnassignedVisitsAreInProblem(t *testing.T) {
+	problem := &optimizerpb.VRPProblem{Description: &optimizerpb.VRPDescription{
+		Visits: []*optimizerpb.VRPVisit{{Id: proto.Int64(1)}},
+		UnassignedVisits: []*optimizerpb.VRPUnassignedVisit{
+			{VisitId: proto.Int64(1), Pinned: proto.Bool(true)},
+			{VisitId: proto.Int64(2), Pinned: proto.Bool(true)},
+		},
+	}}
+	err := validateUnassignedVisitsAreInProblem(problem)
+	testutils.MustMatch(t, true, err != nil, "has error")
+	testutils.MustMatch(t, true, err.Recoverable, "and should be recoverable")
+	testutils.MustMatch(t, []*optimizerpb.VRPUnassignedVisit{
+		{VisitId: proto.Int64(1), Pinned: proto.Bool(true)},
+	}, problem.Description.UnassignedVisits, "unknown unassigned visit is removed")
+
+	err = validateUnassignedVisitsAreInProblem(problem)
+	testutils.MustMatch(t, true, err == nil, "no error once fixed")
+}
+
+func TestRepairableProblemValidators_RepairedProblemIsValid(t *testing.T) {
+	problem := &optimizerpb.VRPProblem{Description: &optimizerpb.VRPDescription{
+		Locations: []*optimizerpb.VRPLocation{{Id: proto.Int64(1)}},
+		Visits: []*optimizerpb.VRPVisit{
+			{
+				Id:         proto.Int64(1),
+				LocationId: proto.Int64(1),
+				ArrivalTimeWindow: &optimizerpb.VRPTimeWindow{
+					StartTimestampSec: proto.Int64(10),
+					EndTimestampSec:   proto.Int64(20),
+				},
+			},
+			{
+				Id:         proto.Int64(2),
+				LocationId: proto.Int64(2),
+				ArrivalTimeWindow: &optimizerpb.VRPTimeWindow{
+					StartTimestampSec: proto.Int64(10),
+					EndTimestampSec:   proto.Int64(20),
+				},
+			},
+		},
+		ShiftTeams: []*optimizerpb.VRPShiftTeam{
+			{
+				RouteHistory:        &optimizerpb.VRPShiftTeamRouteHistory{},
+				UpcomingCommitments: &optimizerpb.VRPShiftTeamCommitments{},
+			},
+		},
+		UnassignedVisits: []*optimizerpb.VRPUnassignedVisit{
+			{VisitId: proto.Int64(1), Pinned: proto.Bool(true)},
+			{VisitId: proto.Int64(2), Pinned: proto.Bool(true)},
+		},
+	}}
+
+	validator := validation.NewValidator(monitoring.NewMockScope(), validation.Config{
+		FailOnRecoverableError: true,
+		RepairMode:             true,
+		ProblemValidators:      RepairableProblemValidators,
+	})
+	report, err := validator.ValidateAndRepair(problem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testutils.MustMatch(t, []int64{1}, problemVisitIDs(problem), "visit with unknown location is dropped")
+	testutils.MustMatch(t, 1, len(report.Repairs))
+	testutils.MustMatch(t, proto.Int64(2), report.Repairs[0].DroppedVisitID, "dropped visit is reported")
+	// The solver pins unassigned visits by looking them up in the problem visits.
+	testutils.MustMatch(t, []*optimizerpb.VRPUnassignedVisit{
+		{VisitId: proto.Int64(1), Pinned: proto.Bool(true)},
+	}, problem.Description.UnassignedVisits, "unassigned visits are all in the problem")
+
+	err = validation.NewValidator(monitoring.NewMockScope(), validation.Config{
+		FailOnRecoverableError: true,
+		ProblemValidators:      RepairableProblemValidators,
+	}).Validate(problem)
+	testutils.MustMatch(t, true, err == nil, "repaired problem is valid")
+}
+
+func problemVisitIDs(problem *optimizerpb.VRPProblem) []int64 {
+	var res []int64
+	for _, visit := range problem.GetDescription().GetVisits() {
+		res = append(res, visit.GetId())
+	}
+	return res
+}
@@ -0,0 +1,260 @@
+package logisticsdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/collections"
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/optimizer/optimizersettings"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxRestBreakPolicyRules bounds the rules of a rest break policy, to derive unique rest break IDs.
+const maxRestBreakPolicyRules = 100
+
+// requiredRestBreak is a rest break that a rest break policy rule requires of a shift.
+type requiredRestBreak struct {
+	ruleIndex     int
+	ruleName      string
+	earliestStart time.Time
+	latestStart   time.Time
+	duration      time.Duration
+}
+
+// requiredRestBreaks returns the rest breaks that the policy requires of a shift, in rule order.
+func requiredRestBreaks(policy *optimizersettings.RestBreakPolicy, shiftStart, shiftEnd time.Time) []requiredRestBreak {
+	if policy == nil {
+		return nil
+	}
+
+	shiftDuration := shiftEnd.Sub(shiftStart)
+	var res []requiredRestBreak
+	for i, rule := range policy.Rules {
+		if i >= maxRestBreakPolicyRules {
+			break
+		}
+		if shiftDuration <= rule.MinShiftDuration() {
+			continue
+		}
+
+		latestStart := shiftEnd.Add(-rule.Duration())
+		if rule.LatestStartOffsetSec > 0 {
+			latestStart = minTime(latestStart, shiftStart.Add(rule.LatestStartOffset()))
+		}
+		earliestStart := shiftStart.Add(rule.EarliestStartOffset())
+		if latestStart.Before(earliestStart) {
+			continue
+		}
+
+		res = append(res, requiredRestBreak{
+			ruleIndex:     i,
+			ruleName:      rule.Name,
+			earliestStart: earliestStart,
+			latestStart:   latestStart,
+			duration:      rule.Duration(),
+		})
+	}
+	return res
+}
+
+// takenRestBreak is a rest break requested by or scheduled for a shift team.
+type takenRestBreak struct {
+	start    time.Time
+	duration time.Duration
+}
+
+// idleGap is a time span of a route between stops, part of which is spent driving to the next stop.
+type idleGap struct {
+	start time.Time
+	end   time.Time
+	drive time.Duration
+}
+
+// satisfiedBy returns whether any of the taken rest breaks satisfies the required rest break.
+func (rb requiredRestBreak) satisfiedBy(taken []takenRestBreak) bool {
+	for _, t := range taken {
+		if t.duration >= rb.duration && !t.start.Before(rb.earliestStart) && !t.start.After(rb.latestStart) {
+			return true
+		}
+	}
+	return false
+}
+
+// fitsIn returns whether the required rest break can be taken in any of the idle gaps.
+func (rb requiredRestBreak) fitsIn(gaps []idleGap) bool {
+	for _, g := range gaps {
+		start := maxTime(g.start, rb.earliestStart)
+		if !start.After(rb.latestStart) && !start.Add(rb.duration+g.drive).After(g.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// policyRestBreakID returns the ID of an unrequested rest break generated by a rest break policy rule.
+// IDs are negative to not collide with requested rest break IDs.
+func policyRestBreakID(shiftTeamID ShiftTeamID, ruleIndex int) int64 {
+	return -(int64(shiftTeamID)*maxRestBreakPolicyRules + int64(ruleIndex))
+}
+
+type policyRestBreaksParams struct {
+	policy              *optimizersettings.RestBreakPolicy
+	snapshot            *logisticssql.ShiftTeamSnapshot
+	shiftTeamSnapshotID ShiftTeamSnapshotID
+	restBreakRequests   []*logisticssql.ShiftTeamRestBreakRequest
+	snapshotTime        time.Time
+}
+
+// policyRestBreaks returns the unrequested rest breaks that the policy requires of a shift team,
+// skipping those satisfied by requested rest breaks or that can no longer be taken.
+func policyRestBreaks(params policyRestBreaksParams) restBreaks {
+	snapshot := params.snapshot
+	shiftTeamID := ShiftTeamID(snapshot.ShiftTeamID)
+	required := requiredRestBreaks(
+		params.policy,
+		time.Unix(snapshot.StartTimestampSec, 0),
+		time.Unix(snapshot.EndTimestampSec, 0))
+
+	taken := make([]takenRestBreak, len(params.restBreakRequests))
+	for i, rbr := range params.restBreakRequests {
+		taken[i] = takenRestBreak{
+			start:    time.Unix(rbr.StartTimestampSec, 0),
+			duration: time.Duration(rbr.DurationSec) * time.Second,
+		}
+	}
+
+	var res restBreaks
+	for _, rb := range required {
+		if rb.latestStart.Before(params.snapshotTime) || rb.satisfiedBy(taken) {
+			continue
+		}
+
+		res = append(res, restBreak{
+			basis: &optimizerpb.VRPRestBreak{
+				Id:                        proto.Int64(policyRestBreakID(shiftTeamID, rb.ruleIndex)),
+				ShiftTeamId:               proto.Int64(int64(params.shiftTeamSnapshotID)),
+				DurationSec:               proto.Int64(int64(rb.duration.Seconds())),
+				Unrequested:               proto.Bool(true),
+				EarliestStartTimestampSec: proto.Int64(maxTime(rb.earliestStart, params.snapshotTime).Unix()),
+				LatestStartTimestampSec:   proto.Int64(rb.latestStart.Unix()),
+			},
+			shiftTeamID: shiftTeamID,
+		})
+	}
+	return res
+}
+
+// restBreakPolicyViolations returns the rest breaks required by the policy that a shift team route
+// has neither a rest break stop nor an idle gap for.
+//
+// drives are the drive durations to each stop of the route, followed by the drive duration to the
+// end location; they are excluded from the idle gaps. Missing drive durations are taken as zero.
+func restBreakPolicyViolations(
+	policy *optimizersettings.RestBreakPolicy,
+	shiftTeamID int64,
+	shiftStart, shiftEnd time.Time,
+	route *logisticspb.ShiftTeamRoute,
+	drives []time.Duration,
+) []*logisticspb.RestBreakPolicyViolation {
+	required := requiredRestBreaks(policy, shiftStart, shiftEnd)
+	if len(required) == 0 {
+		return nil
+	}
+
+	var taken []takenRestBreak
+	var gaps []idleGap
+	drive := func(i int) time.Duration {
+		if i < len(drives) {
+			return drives[i]
+		}
+		return 0
+	}
+	gapStart := &shiftStart
+	stops := route.GetStops()
+	for i, stop := range stops {
+		var stopStart, stopEnd *time.Time
+		if rb := stop.GetRestBreak(); rb != nil {
+			start := time.Unix(rb.GetStartTimestampSec(), 0)
+			duration := time.Duration(rb.GetDurationSec()) * time.Second
+			end := start.Add(duration)
+			taken = append(taken, takenRestBreak{start: start, duration: duration})
+			stopStart, stopEnd = &start, &end
+		}
+		if v := stop.GetVisit(); v != nil {
+			if v.ArrivalTimestampSec != nil {
+				start := time.Unix(v.GetArrivalTimestampSec(), 0)
+				stopStart = &start
+			}
+			if v.CompleteTimestampSec != nil {
+				end := time.Unix(v.GetCompleteTimestampSec(), 0)
+				stopEnd = &end
+			}
+		}
+		// Gaps around stops without estimated timestamps are unknown.
+		if gapStart != nil && stopStart != nil {
+			gaps = append(gaps, idleGap{start: *gapStart, end: *stopStart, drive: drive(i)})
+		}
+		gapStart = stopEnd
+	}
+	if gapStart != nil {
+		gaps = append(gaps, idleGap{start: *gapStart, end: shiftEnd, drive: drive(len(stops))})
+	}
+
+	var violations []*logisticspb.RestBreakPolicyViolation
+	for _, rb := range required {
+		if rb.satisfiedBy(taken) || rb.fitsIn(gaps) {
+			continue
+		}
+
+		violations = append(violations, &logisticspb.RestBreakPolicyViolation{
+			ShiftTeamId:               shiftTeamID,
+			RuleName:                  rb.ruleName,
+			EarliestStartTimestampSec: rb.earliestStart.Unix(),
+			LatestStartTimestampSec:   rb.latestStart.Unix(),
+			DurationSec:               int64(rb.duration.Seconds()),
+		})
+	}
+	return violations
+}
+
+// routeDriveDurations returns the drive durations between the consecutive locations of each route, from the
+// latest distances of the source created after afterCreatedAt. Drives without a known distance are zero.
+func (ldb *LogisticsDB) routeDriveDurations(
+	ctx context.Context,
+	routeLocationIDs map[int64][]int64,
+	sourceID int64,
+	afterCreatedAt time.Time,
+) (map[int64][]time.Duration, error) {
+	pairs := collections.NewLinkedSet[locIDPair](0)
+	for _, locIDs := range routeLocationIDs {
+		for i := 1; i < len(locIDs); i++ {
+			pairs.Add(locIDPair{from: locIDs[i-1], to: locIDs[i]})
+		}
+	}
+	if pairs.Size() == 0 {
+		return nil, nil
+	}
+
+	distances, err := ldb.batchGetLatestDistancesForLocations(ctx,
+		ldb.latestDistancesParams(pairs, sourceID, afterCreatedAt, nil))
+	if err != nil {
+		return nil, err
+	}
+	durations := make(map[locIDPair]time.Duration, len(distances))
+	for _, d := range distances {
+		durations[locIDPair{from: d.FromLocationID, to: d.ToLocationID}] = time.Duration(d.DurationSeconds) * time.Second
+	}
+
+	res := make(map[int64][]time.Duration, len(routeLocationIDs))
+	for routeID, locIDs := range routeLocationIDs {
+		var drives []time.Duration
+		for i := 1; i < len(locIDs); i++ {
+			drives = append(drives, durations[locIDPair{from: locIDs[i-1], to: locIDs[i]}])
+		}
+		res[routeID] = drives
+	}
+	return res, nil
+}
@@ -0,0 +1,358 @@
+package logisticsdb
+
+import (
+	"testing"
+	"time"
+
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/optimizer/optimizersettings"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"google.golang.org/protobuf/proto"
+)
+
+var testRestBreakPolicy = &optimizersettings.RestBreakPolicy{
+	Jurisdiction: "CA",
+	Rules: []optimizersettings.RestBreakRule{
+		{
+			Name:                 "first meal",
+			MinShiftDurationSec:  int64((5 * time.Hour).Seconds()),
+			DurationSec:          int64((30 * time.Minute).Seconds()),
+			LatestStartOffsetSec: int64((4*time.Hour + 30*time.Minute).Seconds()),
+		},
+		{
+			Name:                   "second meal",
+			MinShiftDurationSec:    int64((10 * time.Hour).Seconds()),
+			DurationSec:            int64((30 * time.Minute).Seconds()),
+			EarliestStartOffsetSec: int64((5 * time.Hour).Seconds()),
+			LatestStartOffsetSec:   int64((9*time.Hour + 30*time.Minute).Seconds()),
+		},
+		{
+			Name:                "rest",
+			MinShiftDurationSec: int64((3*time.Hour + 30*time.Minute).Seconds()),
+			DurationSec:         int64((10 * time.Minute).Seconds()),
+		},
+	},
+}
+
+func TestRequiredRestBreaks(t *testing.T) {
+	shiftStart := time.Date(2023, time.September, 18, 8, 0, 0, 0, time.UTC)
+
+	tcs := []struct {
+		Desc          string
+		Policy        *optimizersettings.RestBreakPolicy
+		ShiftDuration time.Duration
+
+		ExpectedRestBreaks []requiredRestBreak
+	}{
+		{
+			Desc:          "no policy",
+			ShiftDuration: 12 * time.Hour,
+		},
+		{
+			Desc:          "short shift",
+			Policy:        testRestBreakPolicy,
+			ShiftDuration: 3 * time.Hour,
+		},
+		{
+			Desc:          "shift of exactly the minimum duration",
+			Policy:        testRestBreakPolicy,
+			ShiftDuration: 5 * time.Hour,
+
+			ExpectedRestBreaks: []requiredRestBreak{
+				{
+					ruleIndex:     2,
+					ruleName:      "rest",
+					earliestStart: shiftStart,
+					latestStart:   shiftStart.Add(5*time.Hour - 10*time.Minute),
+					duration:      10 * time.Minute,
+				},
+			},
+		},
+		{
+			Desc:          "long shift",
+			Policy:        testRestBreakPolicy,
+			ShiftDuration: 12 * time.Hour,
+
+			ExpectedRestBreaks: []requiredRestBreak{
+				{
+					ruleIndex:     0,
+					ruleName:      "first meal",
+					earliestStart: shiftStart,
+					latestStart:   shiftStart.Add(4*time.Hour + 30*time.Minute),
+					duration:      30 * time.Minute,
+				},
+				{
+					ruleIndex:     1,
+					ruleName:      "second meal",
+					earliestStart: shiftStart.Add(5 * time.Hour),
+					latestStart:   shiftStart.Add(9*time.Hour + 30*time.Minute),
+					duration:      30 * time.Minute,
+				},
+				{
+					ruleIndex:     2,
+					ruleName:      "rest",
+					earliestStart: shiftStart,
+					latestStart:   shiftStart.Add(12*time.Hour - 10*time.Minute),
+					duration:      10 * time.Minute,
+				},
+			},
+		},
+		{
+			Desc: "rest break that cannot start before the shift end",
+			Policy: &optimizersettings.RestBreakPolicy{
+				Rules: []optimizersettings.RestBreakRule{
+					{
+						Name:                   "late",
+						DurationSec:            int64((30 * time.Minute).Seconds()),
+						EarliestStartOffsetSec: int64((6 * time.Hour).Seconds()),
+					},
+				},
+			},
+			ShiftDuration: 6 * time.Hour,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			testutils.MustMatch(t, tc.ExpectedRestBreaks, requiredRestBreaks(tc.Policy, shiftStart, shiftStart.Add(tc.ShiftDuration)))
+		})
+	}
+}
+
+func TestRestBreaksForRestBreakRequestsWithPolicy(t *testing.T) {
+	shiftStart := time.Date(2023, time.September, 18, 8, 0, 0, 0, time.UTC)
+	shiftTeamID := int64(1)
+	shiftTeamSnapshotID := int64(11)
+	snapshots := []*logisticssql.ShiftTeamSnapshot{
+		{
+			ID:                shiftTeamSnapshotID,
+			ShiftTeamID:       shiftTeamID,
+			StartTimestampSec: shiftStart.Unix(),
+			EndTimestampSec:   shiftStart.Add(12 * time.Hour).Unix(),
+		},
+	}
+	firstMealRequest := &logisticssql.ShiftTeamRestBreakRequest{
+		ID:                5,
+		ShiftTeamID:       shiftTeamID,
+		LocationID:        6,
+		StartTimestampSec: shiftStart.Add(4 * time.Hour).Unix(),
+		DurationSec:       int64((30 * time.Minute).Seconds()),
+	}
+	requestedRestBreak := restBreak{
+		basis: &optimizerpb.VRPRestBreak{
+			Id:                proto.Int64(firstMealRequest.ID),
+			ShiftTeamId:       proto.Int64(shiftTeamSnapshotID),
+			LocationId:        proto.Int64(firstMealRequest.LocationID),
+			StartTimestampSec: proto.Int64(firstMealRequest.StartTimestampSec),
+			DurationSec:       proto.Int64(firstMealRequest.DurationSec),
+			Unrequested:       proto.Bool(false),
+		},
+		shiftTeamID: ShiftTeamID(shiftTeamID),
+	}
+	policyRestBreak := func(ruleIndex int, earliestStart, latestStart time.Time, duration time.Duration) restBreak {
+		return restBreak{
+			basis: &optimizerpb.VRPRestBreak{
+				Id:                        proto.Int64(policyRestBreakID(ShiftTeamID(shiftTeamID), ruleIndex)),
+				ShiftTeamId:               proto.Int64(shiftTeamSnapshotID),
+				DurationSec:               proto.Int64(int64(duration.Seconds())),
+				Unrequested:               proto.Bool(true),
+				EarliestStartTimestampSec: proto.Int64(earliestStart.Unix()),
+				LatestStartTimestampSec:   proto.Int64(latestStart.Unix()),
+			},
+			shiftTeamID: ShiftTeamID(shiftTeamID),
+		}
+	}
+
+	tcs := []struct {
+		Desc              string
+		RestBreakRequests []*logisticssql.ShiftTeamRestBreakRequest
+		SnapshotTime      time.Time
+
+		ExpectedRestBreaks restBreaks
+	}{
+		{
+			Desc:         "no requested rest breaks",
+			SnapshotTime: shiftStart.Add(-time.Hour),
+
+			ExpectedRestBreaks: restBreaks{
+				policyRestBreak(0, shiftStart, shiftStart.Add(4*time.Hour+30*time.Minute), 30*time.Minute),
+				policyRestBreak(1, shiftStart.Add(5*time.Hour), shiftStart.Add(9*time.Hour+30*time.Minute), 30*time.Minute),
+				policyRestBreak(2, shiftStart, shiftStart.Add(12*time.Hour-10*time.Minute), 10*time.Minute),
+			},
+		},
+		{
+			Desc:              "requested rest break satisfies rules",
+			RestBreakRequests: []*logisticssql.ShiftTeamRestBreakRequest{firstMealRequest},
+			SnapshotTime:      shiftStart.Add(-time.Hour),
+
+			ExpectedRestBreaks: restBreaks{
+				requestedRestBreak,
+				policyRestBreak(1, shiftStart.Add(5*time.Hour), shiftStart.Add(9*time.Hour+30*time.Minute), 30*time.Minute),
+			},
+		},
+		{
+			Desc:         "rest breaks that can no longer be taken",
+			SnapshotTime: shiftStart.Add(6 * time.Hour),
+
+			ExpectedRestBreaks: restBreaks{
+				policyRestBreak(1, shiftStart.Add(6*time.Hour), shiftStart.Add(9*time.Hour+30*time.Minute), 30*time.Minute),
+				policyRestBreak(2, shiftStart.Add(6*time.Hour), shiftStart.Add(12*time.Hour-10*time.Minute), 10*time.Minute),
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			result := restBreaksForRestBreakRequests(
+				tc.RestBreakRequests,
+				snapshots,
+				UnrequestedRestBreakConfig{RestBreakPolicy: testRestBreakPolicy},
+				tc.SnapshotTime)
+
+			testutils.MustMatch(t, tc.ExpectedRestBreaks, result)
+		})
+	}
+}
+
+func TestRestBreakPolicyViolations(t *testing.T) {
+	shiftStart := time.Date(2023, time.September, 18, 8, 0, 0, 0, time.UTC)
+	shiftEnd := shiftStart.Add(6 * time.Hour)
+	policy := &optimizersettings.RestBreakPolicy{
+		Rules: []optimizersettings.RestBreakRule{testRestBreakPolicy.Rules[0]},
+	}
+	visitStop := func(arrival, complete *time.Time) *logisticspb.ShiftTeamRouteStop {
+		visit := &logisticspb.ShiftTeamVisit{}
+		if arrival != nil {
+			visit.ArrivalTimestampSec = proto.Int64(arrival.Unix())
+		}
+		if complete != nil {
+			visit.CompleteTimestampSec = proto.Int64(complete.Unix())
+		}
+		return &logisticspb.ShiftTeamRouteStop{Stop: &logisticspb.ShiftTeamRouteStop_Visit{Visit: visit}}
+	}
+	restBreakStop := func(start time.Time, duration time.Duration) *logisticspb.ShiftTeamRouteStop {
+		return &logisticspb.ShiftTeamRouteStop{
+			Stop: &logisticspb.ShiftTeamRouteStop_RestBreak{
+				RestBreak: &logisticspb.ShiftTeamRestBreak{
+					StartTimestampSec: proto.Int64(start.Unix()),
+					DurationSec:       proto.Int64(int64(duration.Seconds())),
+				},
+			},
+		}
+	}
+	at := func(d time.Duration) *time.Time {
+		ts := shiftStart.Add(d)
+		return &ts
+	}
+	firstMealViolation := &logisticspb.RestBreakPolicyViolation{
+		ShiftTeamId:               1,
+		RuleName:                  "first meal",
+		EarliestStartTimestampSec: shiftStart.Unix(),
+		LatestStartTimestampSec:   shiftStart.Add(4*time.Hour + 30*time.Minute).Unix(),
+		DurationSec:               int64((30 * time.Minute).Seconds()),
+	}
+
+	tcs := []struct {
+		Desc   string
+		Policy *optimizersettings.RestBreakPolicy
+		Stops  []*logisticspb.ShiftTeamRouteStop
+		Drives []time.Duration
+
+		ExpectedViolations []*logisticspb.RestBreakPolicyViolation
+	}{
+		{
+			Desc: "no policy",
+			Stops: []*logisticspb.ShiftTeamRouteStop{
+				visitStop(at(0), at(6*time.Hour)),
+			},
+		},
+		{
+			Desc:   "empty route",
+			Policy: policy,
+		},
+		{
+			Desc:   "rest break stop",
+			Policy: policy,
+			Stops: []*logisticspb.ShiftTeamRouteStop{
+				visitStop(at(0), at(3*time.Hour)),
+				restBreakStop(*at(3 * time.Hour), 30*time.Minute),
+				visitStop(at(3*time.Hour+30*time.Minute), at(6*time.Hour)),
+			},
+		},
+		{
+			Desc:   "idle gap",
+			Policy: policy,
+			Stops: []*logisticspb.ShiftTeamRouteStop{
+				visitStop(at(0), at(3*time.Hour)),
+				visitStop(at(4*time.Hour), at(6*time.Hour)),
+			},
+		},
+		{
+			Desc:   "idle gap with a short drive",
+			Policy: policy,
+			Stops: []*logisticspb.ShiftTeamRouteStop{
+				visitStop(at(0), at(3*time.Hour)),
+				visitStop(at(4*time.Hour), at(6*time.Hour)),
+			},
+			Drives: []time.Duration{0, 20 * time.Minute, 0},
+		},
+		{
+			Desc:   "idle gaps spent driving",
+			Policy: policy,
+			Stops: []*logisticspb.ShiftTeamRouteStop{
+				visitStop(at(30*time.Minute), at(2*time.Hour)),
+				visitStop(at(2*time.Hour+40*time.Minute), at(4*time.Hour)),
+				visitStop(at(4*time.Hour+50*time.Minute), at(6*time.Hour)),
+			},
+			Drives: []time.Duration{30 * time.Minute, 40 * time.Minute, 50 * time.Minute, 0},
+
+			ExpectedViolations: []*logisticspb.RestBreakPolicyViolation{firstMealViolation},
+		},
+		{
+			Desc:   "short rest break stop and idle gaps",
+			Policy: policy,
+			Stops: []*logisticspb.ShiftTeamRouteStop{
+				visitStop(at(0), at(3*time.Hour)),
+				restBreakStop(*at(3 * time.Hour), 10*time.Minute),
+				visitStop(at(3*time.Hour+20*time.Minute), at(6*time.Hour)),
+			},
+
+			ExpectedViolations: []*logisticspb.RestBreakPolicyViolation{firstMealViolation},
+		},
+		{
+			Desc:   "idle gap after the latest start",
+			Policy: policy,
+			Stops: []*logisticspb.ShiftTeamRouteStop{
+				visitStop(at(0), at(5*time.Hour)),
+			},
+
+			ExpectedViolations: []*logisticspb.RestBreakPolicyViolation{firstMealViolation},
+		},
+		{
+			Desc:   "visits without estimated timestamps",
+			Policy: policy,
+			Stops: []*logisticspb.ShiftTeamRouteStop{
+				visitStop(nil, nil),
+				visitStop(at(5*time.Hour), at(6*time.Hour)),
+			},
+
+			ExpectedViolations: []*logisticspb.RestBreakPolicyViolation{firstMealViolation},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			violations := restBreakPolicyViolations(
+				tc.Policy,
+				1,
+				shiftStart,
+				shiftEnd,
+				&logisticspb.ShiftTeamRoute{Stops: tc.Stops},
+				tc.Drives)
+
+			testutils.MustMatch(t, tc.ExpectedViolations, violations)
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
 	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/sqltypes"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -26,11 +27,11 @@ func (rbs restBreaks) toVRPRestBreaks() []*optimizerpb.VRPRestBreak {
 }
 
 func restBreaksForRestBreakRequests(restBreakRequests []*logisticssql.ShiftTeamRestBreakRequest, snapshots []*logisticssql.ShiftTeamSnapshot, cfg UnrequestedRestBreakConfig, snapshotTime time.Time) restBreaks {
-	if len(restBreakRequests) == 0 && !cfg.IncludeUnrequestedRestBreaks {
+	if len(restBreakRequests) == 0 && !cfg.IncludeUnrequestedRestBreaks && cfg.RestBreakPolicy == nil {
 		return nil
 	}
 
-	shiftTeamsWithRequestedRestBreak := make(map[ShiftTeamID]bool, len(snapshots))
+	restBreakRequestsByShiftTeamID := make(map[ShiftTeamID][]*logisticssql.ShiftTeamRestBreakRequest, len(snapshots))
 	snapshotIDIndexByShiftTeamID := make(map[ShiftTeamID]ShiftTeamSnapshotID, len(snapshots))
 	for _, snapshot := range snapshots {
 		snapshotIDIndexByShiftTeamID[ShiftTeamID(snapshot.ShiftTeamID)] = ShiftTeamSnapshotID(snapshot.ID)
@@ -50,13 +51,27 @@ func restBreaksForRestBreakRequests(restBreakRequests []*logisticssql.ShiftTeamR
 			},
 			shiftTeamID: shiftTeamID,
 		})
-		shiftTeamsWithRequestedRestBreak[shiftTeamID] = true
+		restBreakRequestsByShiftTeamID[shiftTeamID] = append(restBreakRequestsByShiftTeamID[shiftTeamID], rbr)
+	}
+
+	if cfg.RestBreakPolicy != nil {
+		for _, snapshot := range snapshots {
+			shiftTeamID := ShiftTeamID(snapshot.ShiftTeamID)
+			res = append(res, policyRestBreaks(policyRestBreaksParams{
+				policy:              cfg.RestBreakPolicy,
+				snapshot:            snapshot,
+				shiftTeamSnapshotID: snapshotIDIndexByShiftTeamID[shiftTeamID],
+				restBreakRequests:   restBreakRequestsByShiftTeamID[shiftTeamID],
+				snapshotTime:        snapshotTime,
+			})...)
+		}
+		return res
 	}
 
 	if cfg.IncludeUnrequestedRestBreaks {
 		for _, snapshot := range snapshots {
 			shiftTeamID := ShiftTeamID(snapshot.ShiftTeamID)
-			if !shiftTeamsWithRequestedRestBreak[shiftTeamID] && cfg.shouldAddUnrequestedRestBreak(snapshotTime, snapshot) {
+			if len(restBreakRequestsByShiftTeamID[shiftTeamID]) == 0 && cfg.shouldAddUnrequestedRestBreak(snapshotTime, snapshot) {
 				res = append(res, restBreak{
 					basis: &optimizerpb.VRPRestBreak{
 						Id:          proto.Int64(int64(shiftTeamID)),
@@ -71,3 +86,49 @@ func restBreaksForRestBreakRequests(restBreakRequests []*logisticssql.ShiftTeamR
 	}
 	return res
 }
+
+// scheduleRestBreakLocator locates the rest break stops of the routes of a VRP solution.
+type scheduleRestBreakLocator struct {
+	restBreaks       map[int64]*optimizerpb.VRPRestBreak
+	visitLocationIDs map[int64]int64
+}
+
+func newScheduleRestBreakLocator(description *optimizerpb.VRPDescription) *scheduleRestBreakLocator {
+	l := &scheduleRestBreakLocator{
+		restBreaks:       make(map[int64]*optimizerpb.VRPRestBreak, len(description.GetRestBreaks())),
+		visitLocationIDs: make(map[int64]int64, len(description.GetVisits())),
+	}
+	for _, rb := range description.GetRestBreaks() {
+		l.restBreaks[rb.GetId()] = rb
+	}
+	for _, visit := range description.GetVisits() {
+		l.visitLocationIDs[visit.GetId()] = visit.GetLocationId()
+	}
+	return l
+}
+
+// addScheduleRestBreakParams returns the params to add a rest break stop following a stop at previousLocationID,
+// and the location of the rest break.
+//
+// Unrequested rest breaks have no break request, so their start, duration and location are stored with them;
+// as in the optimizer, they are taken at the location of the previous stop.
+func (l *scheduleRestBreakLocator) addScheduleRestBreakParams(scheduleID, routeID int64, stop *optimizerpb.VRPShiftTeamRestBreak, previousLocationID int64) (logisticssql.AddScheduleRestBreakParams, int64) {
+	params := logisticssql.AddScheduleRestBreakParams{
+		ScheduleID:              scheduleID,
+		ScheduleRouteID:         routeID,
+		ShiftTeamBreakRequestID: stop.GetRestBreakId(),
+	}
+	rb, ok := l.restBreaks[stop.GetRestBreakId()]
+	if !ok || !rb.GetUnrequested() {
+		return params, rb.GetLocationId()
+	}
+
+	params.StartTimestampSec = sqltypes.ToValidNullInt64(stop.GetStartTimestampSec())
+	params.DurationSec = sqltypes.ToValidNullInt64(rb.GetDurationSec())
+	params.LocationID = sqltypes.ToValidNullInt64(previousLocationID)
+	return params, previousLocationID
+}
+
+func (l *scheduleRestBreakLocator) visitLocationID(visitID int64) int64 {
+	return l.visitLocationIDs[visitID]
+}
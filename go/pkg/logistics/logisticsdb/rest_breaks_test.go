@@ -6,6 +6,7 @@ import (
 
 	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
 	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/sqltypes"
 	"github.com/*company-data-covered*/services/go/pkg/testutils"
 	"google.golang.org/protobuf/proto"
 )
@@ -113,3 +114,47 @@ func TestRestBreaksForRestBreakRequestsWithUnrequested(t *testing.T) {
 
 	testutils.MustMatch(t, []*optimizerpb.VRPRestBreak{expected1, expected2}, result.toVRPRestBreaks())
 }
+
+func TestScheduleRestBreakLocator(t *testing.T) {
+	scheduleID := int64(1)
+	routeID := int64(2)
+	depotLocationID := int64(3)
+	visitLocationID := int64(4)
+	requestedLocationID := int64(5)
+	locator := newScheduleRestBreakLocator(&optimizerpb.VRPDescription{
+		Visits: []*optimizerpb.VRPVisit{
+			{Id: proto.Int64(6), LocationId: proto.Int64(visitLocationID)},
+		},
+		RestBreaks: []*optimizerpb.VRPRestBreak{
+			{Id: proto.Int64(7), LocationId: proto.Int64(requestedLocationID), StartTimestampSec: proto.Int64(100), DurationSec: proto.Int64(30)},
+			{Id: proto.Int64(-8), DurationSec: proto.Int64(60), Unrequested: proto.Bool(true)},
+		},
+	})
+
+	testutils.MustMatch(t, visitLocationID, locator.visitLocationID(6))
+
+	params, locationID := locator.addScheduleRestBreakParams(scheduleID, routeID, &optimizerpb.VRPShiftTeamRestBreak{
+		RestBreakId:       proto.Int64(7),
+		StartTimestampSec: proto.Int64(100),
+	}, depotLocationID)
+	testutils.MustMatch(t, logisticssql.AddScheduleRestBreakParams{
+		ScheduleID:              scheduleID,
+		ScheduleRouteID:         routeID,
+		ShiftTeamBreakRequestID: 7,
+	}, params, "requested rest breaks are stored by their break request")
+	testutils.MustMatch(t, requestedLocationID, locationID)
+
+	params, locationID = locator.addScheduleRestBreakParams(scheduleID, routeID, &optimizerpb.VRPShiftTeamRestBreak{
+		RestBreakId:       proto.Int64(-8),
+		StartTimestampSec: proto.Int64(200),
+	}, visitLocationID)
+	testutils.MustMatch(t, logisticssql.AddScheduleRestBreakParams{
+		ScheduleID:              scheduleID,
+		ScheduleRouteID:         routeID,
+		ShiftTeamBreakRequestID: -8,
+		StartTimestampSec:       sqltypes.ToValidNullInt64(200),
+		DurationSec:             sqltypes.ToValidNullInt64(60),
+		LocationID:              sqltypes.ToValidNullInt64(visitLocationID),
+	}, params, "unrequested rest breaks are stored with their start, duration and previous stop location")
+	testutils.MustMatch(t, visitLocationID, locationID)
+}
@@ -87,6 +87,13 @@ func maxTime(a, b time.Time) time.Time {
 	return b
 }
 
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
 func (a *Actuals) stopComesBeforeInRouteHistory(b *Actuals) (less bool, equal bool) { //nolint: nonamedreturns
 	latestATime := maxTime(a.CurrentlyEnRoute, maxTime(a.Arrival, a.Completion))
 	latestBTime := maxTime(b.CurrentlyEnRoute, maxTime(b.Arrival, b.Completion))
@@ -210,13 +217,14 @@ func (h *routeHistorian) vrpShiftTeamsForSnapshots(snapshots []*logisticssql.Shi
 	shiftTeams := make([]*optimizerpb.VRPShiftTeam, len(snapshots))
 	for i, snapshot := range snapshots {
 		shiftTeamIDMapping[ShiftTeamSnapshotID(snapshot.ID)] = ShiftTeamID(snapshot.ShiftTeamID)
-		startTimestampSec := min(snapshot.StartTimestampSec+h.shiftStartBufferSec, snapshot.EndTimestampSec)
+		startTimestampSec, endTimestampSec := shiftTeamAvailableTimeWindow(snapshot, h.shiftStartBufferSec)
 		shiftTeams[i] = &optimizerpb.VRPShiftTeam{
-			Id:              &snapshot.ID,
-			DepotLocationId: &snapshot.BaseLocationID,
+			Id:                 &snapshot.ID,
+			DepotLocationId:    proto.Int64(shiftTeamStartLocationID(snapshot)),
+			EndDepotLocationId: proto.Int64(shiftTeamEndLocationID(snapshot)),
 			AvailableTimeWindow: &optimizerpb.VRPTimeWindow{
 				StartTimestampSec: &startTimestampSec,
-				EndTimestampSec:   &snapshot.EndTimestampSec,
+				EndTimestampSec:   &endTimestampSec,
 			},
 			Attributes:          snapshotAttrs[snapshot.ID],
 			RouteHistory:        &optimizerpb.VRPShiftTeamRouteHistory{},
@@ -0,0 +1,273 @@
+package logisticsdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/monitoring"
+)
+
+const (
+	routeLegMatchErrorMetric = "route_leg_match_error"
+
+	// Max number of shift team locations deleted per query, to keep retention deletes short.
+	shiftTeamLocationsRetentionBatchSize = 10000
+)
+
+// RouteLeg compares the actual drive of a shift team between two consecutive visits of its route
+// with the drive planned by the optimizer.
+type RouteLeg struct {
+	FromCareRequestID int64
+	ToCareRequestID   int64
+
+	// Planned is the distance the optimizer planned the leg with, or nil if unknown.
+	Planned *logistics.Distance
+
+	// DepartedAt and ArrivedAt are inferred from the shift team locations. Zero if the leg was not driven yet.
+	DepartedAt time.Time
+	ArrivedAt  time.Time
+	// Actual is the distance driven from departure to arrival, with its duration, or nil if the leg was not driven yet.
+	Actual *logistics.Distance
+	// MatchConfidence of the map matching of the shift team locations, from 0 to 1.
+	// 0 if the actual distance was estimated without map matching.
+	MatchConfidence float64
+}
+
+func (l *RouteLeg) driven() bool {
+	return !l.DepartedAt.IsZero() && !l.ArrivedAt.IsZero()
+}
+
+// routeLegsForRoute returns the legs between consecutive visits of the route.
+// Visits separated by a rest break have no leg, as the shift team may not drive between them directly.
+func routeLegsForRoute(route *logisticspb.ShiftTeamRoute) []*RouteLeg {
+	var legs []*RouteLeg
+	var prevCareRequestID *int64
+	for _, stop := range route.GetStops() {
+		visit := stop.GetVisit()
+		if visit == nil {
+			prevCareRequestID = nil
+			continue
+		}
+
+		careRequestID := visit.GetCareRequestId()
+		if prevCareRequestID != nil {
+			legs = append(legs, &RouteLeg{
+				FromCareRequestID: *prevCareRequestID,
+				ToCareRequestID:   careRequestID,
+			})
+		}
+		prevCareRequestID = &careRequestID
+	}
+	return legs
+}
+
+// setPlannedRouteLegDistances sets the distances of the problem the legs were planned with.
+func setPlannedRouteLegDistances(legs []*RouteLeg, problem *optimizerpb.VRPProblem, mappings EntityMappings) {
+	description := problem.GetDescription()
+	careRequestLocationIDs := map[int64]int64{}
+	for _, visit := range description.GetVisits() {
+		careRequestID, ok := mappings.CareRequests[VisitSnapshotID(visit.GetId())]
+		if !ok {
+			continue
+		}
+		careRequestLocationIDs[careRequestID.Int64()] = visit.GetLocationId()
+	}
+
+	distances := map[locIDPair]*optimizerpb.VRPDistance{}
+	for _, distance := range description.GetDistanceMatrix().GetDistances() {
+		distances[locIDPair{from: distance.GetFromLocationId(), to: distance.GetToLocationId()}] = distance
+	}
+
+	for _, leg := range legs {
+		fromLocationID, ok := careRequestLocationIDs[leg.FromCareRequestID]
+		if !ok {
+			continue
+		}
+		toLocationID, ok := careRequestLocationIDs[leg.ToCareRequestID]
+		if !ok {
+			continue
+		}
+		distance, ok := distances[locIDPair{from: fromLocationID, to: toLocationID}]
+		if !ok {
+			continue
+		}
+		leg.Planned = &logistics.Distance{
+			Duration:     time.Duration(distance.GetDurationSec()) * time.Second,
+			LengthMeters: distance.GetLengthMeters(),
+		}
+	}
+}
+
+// setActualRouteLegTimes sets the departures and arrivals of the legs inferred from the shift team geofence events.
+func setActualRouteLegTimes(legs []*RouteLeg, shiftTeamID int64, events []*logisticssql.ShiftTeamGeofenceEvent) {
+	departures := map[int64]time.Time{}
+	arrivals := map[int64]time.Time{}
+	for _, event := range events {
+		if event.ShiftTeamID != shiftTeamID {
+			continue
+		}
+		switch event.EventType {
+		case GeofenceEventTypeArrival:
+			arrivals[event.CareRequestID] = event.EventTimestamp
+		case GeofenceEventTypeDeparture:
+			departures[event.CareRequestID] = event.EventTimestamp
+		}
+	}
+
+	for _, leg := range legs {
+		departedAt, ok := departures[leg.FromCareRequestID]
+		if !ok {
+			continue
+		}
+		arrivedAt, ok := arrivals[leg.ToCareRequestID]
+		if !ok || !arrivedAt.After(departedAt) {
+			continue
+		}
+		leg.DepartedAt = departedAt
+		leg.ArrivedAt = arrivedAt
+	}
+}
+
+func traceInTimeWindow(trace []logistics.TimedLatLng, tw TimeWindow) []logistics.TimedLatLng {
+	var res []logistics.TimedLatLng
+	for _, loc := range shiftTeamLocationsSince(trace, tw.Start) {
+		if loc.Timestamp.After(tw.End) {
+			break
+		}
+		res = append(res, loc)
+	}
+	return res
+}
+
+// setActualRouteLegDistance sets the distance driven on the leg, map matched from the trace if possible,
+// or else estimated from the straight-line length of the trace.
+func (ldb *LogisticsDB) setActualRouteLegDistance(ctx context.Context, leg *RouteLeg, trace []logistics.TimedLatLng, matcher logistics.MapMatchingService) {
+	leg.Actual = &logistics.Distance{
+		Duration:     leg.ArrivedAt.Sub(leg.DepartedAt),
+		LengthMeters: int64(logistics.TraceLengthMeters(trace)),
+	}
+	if matcher == nil || len(trace) < 2 {
+		return
+	}
+
+	matched, err := matcher.MatchTrace(ctx, nil, trace)
+	if err != nil {
+		// Map matching is best effort, and falls back to the straight-line length.
+		ldb.scope.WritePoint(routeLegMatchErrorMetric, nil, monitoring.Fields{careRequestField: leg.ToCareRequestID, "error": err.Error()})
+		return
+	}
+	leg.Actual.LengthMeters = matched.Distance.LengthMeters
+	leg.MatchConfidence = matched.Confidence
+}
+
+type ShiftTeamRouteLegsParams struct {
+	ShiftTeamID int64
+	ScheduleID  int64
+	// Route of the shift team in the schedule.
+	Route              *logisticspb.ShiftTeamRoute
+	LatestSnapshotTime time.Time
+}
+
+// GetShiftTeamRouteLegs returns the legs between consecutive visits of the shift team route in the schedule,
+// comparing the drives planned by the optimizer with the actual drives, map matched from the shift team locations.
+func (ldb *LogisticsDB) GetShiftTeamRouteLegs(ctx context.Context, params ShiftTeamRouteLegsParams) ([]*RouteLeg, error) {
+	legs := routeLegsForRoute(params.Route)
+	if len(legs) == 0 {
+		return nil, nil
+	}
+
+	problemData, err := ldb.VRPProblemDataForSchedule(ctx, params.ScheduleID)
+	if err != nil {
+		return nil, err
+	}
+	setPlannedRouteLegDistances(legs, problemData.VRPProblem, problemData.EntityMappings)
+
+	careRequestIDs := make([]int64, 0, len(legs)+1)
+	careRequestIDs = append(careRequestIDs, legs[0].FromCareRequestID)
+	for _, leg := range legs {
+		careRequestIDs = append(careRequestIDs, leg.ToCareRequestID)
+	}
+	events, err := ldb.queries.GetLatestShiftTeamGeofenceEvents(ctx, logisticssql.GetLatestShiftTeamGeofenceEventsParams{
+		CareRequestIds:     careRequestIDs,
+		LatestSnapshotTime: params.LatestSnapshotTime,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error in GetLatestShiftTeamGeofenceEvents: %w", err)
+	}
+	setActualRouteLegTimes(legs, params.ShiftTeamID, events)
+
+	var drivenTW *TimeWindow
+	for _, leg := range legs {
+		if !leg.driven() {
+			continue
+		}
+		if drivenTW == nil {
+			drivenTW = &TimeWindow{Start: leg.DepartedAt, End: leg.ArrivedAt}
+			continue
+		}
+		if leg.DepartedAt.Before(drivenTW.Start) {
+			drivenTW.Start = leg.DepartedAt
+		}
+		if leg.ArrivedAt.After(drivenTW.End) {
+			drivenTW.End = leg.ArrivedAt
+		}
+	}
+	if drivenTW == nil {
+		return legs, nil
+	}
+
+	rows, err := ldb.queries.GetShiftTeamLocationsInTimeRange(ctx, logisticssql.GetShiftTeamLocationsInTimeRangeParams{
+		ShiftTeamID: params.ShiftTeamID,
+		StartTime:   drivenTW.Start,
+		EndTime:     drivenTW.End,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error in GetShiftTeamLocationsInTimeRange: %w", err)
+	}
+	trace := make([]logistics.TimedLatLng, len(rows))
+	for i, row := range rows {
+		trace[i] = logistics.TimedLatLng{
+			LatLng:    logistics.LatLng{LatE6: row.LatitudeE6, LngE6: row.LongitudeE6},
+			Timestamp: row.CreatedAt,
+		}
+	}
+
+	var matcher logistics.MapMatchingService
+	if ldb.mapServicePicker != nil {
+		matcher, _ = ldb.mapServicePicker.MapMatchingService()
+	}
+	for _, leg := range legs {
+		if !leg.driven() {
+			continue
+		}
+		legTrace := traceInTimeWindow(trace, TimeWindow{Start: leg.DepartedAt, End: leg.ArrivedAt})
+		ldb.setActualRouteLegDistance(ctx, leg, legTrace, matcher)
+	}
+
+	return legs, nil
+}
+
+// DeleteShiftTeamLocationsCreatedBefore deletes the shift team locations older than the retention window,
+// returning the number of locations deleted. The latest location of each shift team is always kept,
+// so idle shift teams keep a current position.
+func (ldb *LogisticsDB) DeleteShiftTeamLocationsCreatedBefore(ctx context.Context, createdBefore time.Time) (int64, error) {
+	var total int64
+	for {
+		deleted, err := ldb.queries.DeleteShiftTeamLocationsCreatedBefore(ctx, logisticssql.DeleteShiftTeamLocationsCreatedBeforeParams{
+			CreatedBefore: createdBefore,
+			MaxRows:       shiftTeamLocationsRetentionBatchSize,
+		})
+		if err != nil {
+			return total, fmt.Errorf("error in DeleteShiftTeamLocationsCreatedBefore: %w", err)
+		}
+		total += deleted
+		if deleted < shiftTeamLocationsRetentionBatchSize {
+			return total, nil
+		}
+	}
+}
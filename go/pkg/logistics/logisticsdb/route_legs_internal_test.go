@@ -0,0 +1,200 @@
+package logisticsdb
+
+import (
+	"testing"
+	"time"
+
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"google.golang.org/protobuf/proto"
+)
+
+func visitStop(careRequestID int64) *logisticspb.ShiftTeamRouteStop {
+	return &logisticspb.ShiftTeamRouteStop{
+		Stop: &logisticspb.ShiftTeamRouteStop_Visit{
+			Visit: &logisticspb.ShiftTeamVisit{CareRequestId: proto.Int64(careRequestID)},
+		},
+	}
+}
+
+func TestRouteLegsForRoute(t *testing.T) {
+	restBreakStop := &logisticspb.ShiftTeamRouteStop{
+		Stop: &logisticspb.ShiftTeamRouteStop_RestBreak{
+			RestBreak: &logisticspb.ShiftTeamRestBreak{RestBreakId: 1},
+		},
+	}
+
+	tcs := []struct {
+		Desc  string
+		Route *logisticspb.ShiftTeamRoute
+
+		ExpectedLegs []*RouteLeg
+	}{
+		{
+			Desc: "consecutive visits",
+			Route: &logisticspb.ShiftTeamRoute{
+				Stops: []*logisticspb.ShiftTeamRouteStop{visitStop(1), visitStop(2), visitStop(3)},
+			},
+
+			ExpectedLegs: []*RouteLeg{
+				{FromCareRequestID: 1, ToCareRequestID: 2},
+				{FromCareRequestID: 2, ToCareRequestID: 3},
+			},
+		},
+		{
+			Desc: "rest break between visits",
+			Route: &logisticspb.ShiftTeamRoute{
+				Stops: []*logisticspb.ShiftTeamRouteStop{visitStop(1), restBreakStop, visitStop(2), visitStop(3)},
+			},
+
+			ExpectedLegs: []*RouteLeg{
+				{FromCareRequestID: 2, ToCareRequestID: 3},
+			},
+		},
+		{
+			Desc: "single visit",
+			Route: &logisticspb.ShiftTeamRoute{
+				Stops: []*logisticspb.ShiftTeamRouteStop{visitStop(1)},
+			},
+		},
+		{
+			Desc: "no route",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			testutils.MustMatch(t, tc.ExpectedLegs, routeLegsForRoute(tc.Route))
+		})
+	}
+}
+
+func TestSetPlannedRouteLegDistances(t *testing.T) {
+	problem := &optimizerpb.VRPProblem{
+		Description: &optimizerpb.VRPDescription{
+			Visits: []*optimizerpb.VRPVisit{
+				{Id: proto.Int64(10), LocationId: proto.Int64(100)},
+				{Id: proto.Int64(20), LocationId: proto.Int64(200)},
+				{Id: proto.Int64(30), LocationId: proto.Int64(300)},
+			},
+			DistanceMatrix: &optimizerpb.VRPDistanceMatrix{
+				Distances: []*optimizerpb.VRPDistance{
+					{FromLocationId: proto.Int64(100), ToLocationId: proto.Int64(200), LengthMeters: proto.Int64(1000), DurationSec: proto.Int64(60)},
+					{FromLocationId: proto.Int64(200), ToLocationId: proto.Int64(100), LengthMeters: proto.Int64(1200), DurationSec: proto.Int64(70)},
+				},
+			},
+		},
+	}
+	mappings := EntityMappings{
+		CareRequests: map[VisitSnapshotID]CareRequestID{10: 1, 20: 2, 30: 3},
+	}
+	legs := []*RouteLeg{
+		{FromCareRequestID: 1, ToCareRequestID: 2},
+		{FromCareRequestID: 2, ToCareRequestID: 3},
+		{FromCareRequestID: 3, ToCareRequestID: 4},
+	}
+
+	setPlannedRouteLegDistances(legs, problem, mappings)
+
+	testutils.MustMatch(t, []*RouteLeg{
+		{
+			FromCareRequestID: 1,
+			ToCareRequestID:   2,
+			Planned:           &logistics.Distance{Duration: time.Minute, LengthMeters: 1000},
+		},
+		{FromCareRequestID: 2, ToCareRequestID: 3},
+		{FromCareRequestID: 3, ToCareRequestID: 4},
+	}, legs)
+}
+
+func TestSetActualRouteLegTimes(t *testing.T) {
+	now := time.Now()
+	shiftTeamID := int64(1)
+	event := func(shiftTeamID int64, careRequestID int64, eventType string, ts time.Time) *logisticssql.ShiftTeamGeofenceEvent {
+		return &logisticssql.ShiftTeamGeofenceEvent{
+			ShiftTeamID:    shiftTeamID,
+			CareRequestID:  careRequestID,
+			EventType:      eventType,
+			EventTimestamp: ts,
+		}
+	}
+
+	tcs := []struct {
+		Desc   string
+		Events []*logisticssql.ShiftTeamGeofenceEvent
+
+		ExpectedLeg *RouteLeg
+	}{
+		{
+			Desc: "driven leg",
+			Events: []*logisticssql.ShiftTeamGeofenceEvent{
+				event(shiftTeamID, 1, GeofenceEventTypeArrival, now),
+				event(shiftTeamID, 1, GeofenceEventTypeDeparture, now.Add(time.Hour)),
+				event(shiftTeamID, 2, GeofenceEventTypeArrival, now.Add(90*time.Minute)),
+			},
+
+			ExpectedLeg: &RouteLeg{
+				FromCareRequestID: 1,
+				ToCareRequestID:   2,
+				DepartedAt:        now.Add(time.Hour),
+				ArrivedAt:         now.Add(90 * time.Minute),
+			},
+		},
+		{
+			Desc: "not arrived yet",
+			Events: []*logisticssql.ShiftTeamGeofenceEvent{
+				event(shiftTeamID, 1, GeofenceEventTypeDeparture, now),
+			},
+
+			ExpectedLeg: &RouteLeg{FromCareRequestID: 1, ToCareRequestID: 2},
+		},
+		{
+			Desc: "arrival before departure",
+			Events: []*logisticssql.ShiftTeamGeofenceEvent{
+				event(shiftTeamID, 1, GeofenceEventTypeDeparture, now),
+				event(shiftTeamID, 2, GeofenceEventTypeArrival, now.Add(-time.Minute)),
+			},
+
+			ExpectedLeg: &RouteLeg{FromCareRequestID: 1, ToCareRequestID: 2},
+		},
+		{
+			Desc: "events of other shift team",
+			Events: []*logisticssql.ShiftTeamGeofenceEvent{
+				event(shiftTeamID+1, 1, GeofenceEventTypeDeparture, now),
+				event(shiftTeamID+1, 2, GeofenceEventTypeArrival, now.Add(time.Minute)),
+			},
+
+			ExpectedLeg: &RouteLeg{FromCareRequestID: 1, ToCareRequestID: 2},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			leg := &RouteLeg{FromCareRequestID: 1, ToCareRequestID: 2}
+			setActualRouteLegTimes([]*RouteLeg{leg}, shiftTeamID, tc.Events)
+			testutils.MustMatch(t, tc.ExpectedLeg, leg)
+		})
+	}
+}
+
+func TestTraceInTimeWindow(t *testing.T) {
+	now := time.Now()
+	trace := []logistics.TimedLatLng{
+		{Timestamp: now},
+		{Timestamp: now.Add(time.Minute)},
+		{Timestamp: now.Add(2 * time.Minute)},
+		{Timestamp: now.Add(3 * time.Minute)},
+	}
+
+	testutils.MustMatch(t, trace[1:3], traceInTimeWindow(trace, TimeWindow{
+		Start: now.Add(time.Minute),
+		End:   now.Add(2 * time.Minute),
+	}))
+	testutils.MustMatch(t, []logistics.TimedLatLng(nil), traceInTimeWindow(trace, TimeWindow{
+		Start: now.Add(time.Hour),
+		End:   now.Add(2 * time.Hour),
+	}))
+}
@@ -0,0 +1,374 @@
+package logisticsdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics"
+	"github.com/jackc/pgx/v4"
+)
+
+// RoutePlaybackParams identify the service region date to replay, by one of its optimizer runs or schedules.
+type RoutePlaybackParams struct {
+	OptimizerRunID int64
+	ScheduleID     int64
+}
+
+// RoutePlayback is the history of a service region date, for replaying the day.
+type RoutePlayback struct {
+	ServiceRegionID            int64     `json:"service_region_id"`
+	ServiceDate                time.Time `json:"service_date"`
+	OpenHoursStartTimestampSec int64     `json:"open_hours_start_timestamp_sec"`
+	OpenHoursEndTimestampSec   int64     `json:"open_hours_end_timestamp_sec"`
+
+	// Schedules of the service region date, in the order they were written.
+	Schedules []*PlaybackSchedule `json:"schedules"`
+
+	ShiftTeamLocations map[ShiftTeamID][]*PlaybackLocation `json:"shift_team_locations"`
+	VisitLocations     map[CareRequestID]logistics.LatLng  `json:"visit_locations"`
+	// VisitPhases are the phase changes of each visit, in order.
+	VisitPhases map[CareRequestID][]*PlaybackVisitPhase `json:"visit_phases"`
+}
+
+// PlaybackSchedule is a schedule of a service region date.
+type PlaybackSchedule struct {
+	ScheduleID     int64     `json:"schedule_id"`
+	OptimizerRunID int64     `json:"optimizer_run_id"`
+	CreatedAt      time.Time `json:"created_at"`
+
+	// Visit stops in route order, by shift team.
+	Routes           map[ShiftTeamID][]*PlaybackStop `json:"routes"`
+	UnassignedVisits []CareRequestID                 `json:"unassigned_visits"`
+
+	// Changes are the visits assigned to different shift teams than in the previous schedule.
+	Changes []*ScheduleChange `json:"changes"`
+}
+
+// PlaybackStop is a visit stop of a schedule route.
+type PlaybackStop struct {
+	CareRequestID       CareRequestID `json:"care_request_id"`
+	ArrivalTimestampSec int64         `json:"arrival_timestamp_sec"`
+}
+
+// PlaybackLocation is a location of a shift team.
+type PlaybackLocation struct {
+	logistics.LatLng
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// PlaybackVisitPhase is the phase of a visit from a given time.
+type PlaybackVisitPhase struct {
+	Phase       string       `json:"phase"`
+	Timestamp   time.Time    `json:"timestamp"`
+	ShiftTeamID *ShiftTeamID `json:"shift_team_id"`
+}
+
+// ScheduleChange is a change of the shift team assigned to a visit between successive schedules.
+// A nil shift team means that the visit is not assigned to any shift team.
+type ScheduleChange struct {
+	CareRequestID   CareRequestID `json:"care_request_id"`
+	FromShiftTeamID *ShiftTeamID  `json:"from_shift_team_id"`
+	ToShiftTeamID   *ShiftTeamID  `json:"to_shift_team_id"`
+}
+
+func (ldb *LogisticsDB) routePlaybackOptimizerRun(ctx context.Context, params RoutePlaybackParams) (*logisticssql.OptimizerRun, error) {
+	optimizerRunID := params.OptimizerRunID
+	if params.ScheduleID != 0 {
+		schedule, err := ldb.queries.GetSchedule(ctx, params.ScheduleID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrScheduleNotFound
+			}
+			return nil, fmt.Errorf("error in GetSchedule: %w", err)
+		}
+		optimizerRunID = schedule.OptimizerRunID
+	}
+
+	optimizerRun, err := ldb.queries.GetOptimizerRun(ctx, optimizerRunID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUnknownOptimizerRunID
+		}
+		return nil, fmt.Errorf("error in GetOptimizerRun: %w", err)
+	}
+	return optimizerRun, nil
+}
+
+// GetRoutePlayback returns the history of the service region date of an optimizer run or schedule:
+// its successive schedules, the locations of its shift teams, and the phase changes of its visits.
+func (ldb *LogisticsDB) GetRoutePlayback(ctx context.Context, params RoutePlaybackParams) (*RoutePlayback, error) {
+	optimizerRun, err := ldb.routePlaybackOptimizerRun(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduleInfos, err := ldb.queries.GetScheduleInfosForServiceRegionDate(ctx, logisticssql.GetScheduleInfosForServiceRegionDateParams{
+		ServiceRegionID:  optimizerRun.ServiceRegionID,
+		ServiceDate:      optimizerRun.ServiceDate,
+		OptimizerRunType: string(ServiceRegionScheduleRunType),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error in GetScheduleInfosForServiceRegionDate: %w", err)
+	}
+
+	playback := &RoutePlayback{
+		ServiceRegionID:            optimizerRun.ServiceRegionID,
+		ServiceDate:                optimizerRun.ServiceDate,
+		OpenHoursStartTimestampSec: optimizerRun.OpenHoursStartTimestampSec,
+		OpenHoursEndTimestampSec:   optimizerRun.OpenHoursEndTimestampSec,
+		Schedules:                  make([]*PlaybackSchedule, len(scheduleInfos)),
+		ShiftTeamLocations:         map[ShiftTeamID][]*PlaybackLocation{},
+		VisitLocations:             map[CareRequestID]logistics.LatLng{},
+		VisitPhases:                map[CareRequestID][]*PlaybackVisitPhase{},
+	}
+	visitLocationIDs := map[CareRequestID]int64{}
+	var previous *PlaybackSchedule
+	var latestSnapshotTime time.Time
+	for i, info := range scheduleInfos {
+		schedule, err := ldb.playbackSchedule(ctx, info, visitLocationIDs)
+		if err != nil {
+			return nil, err
+		}
+		schedule.Changes = scheduleChanges(previous, schedule)
+		playback.Schedules[i] = schedule
+		previous = schedule
+		latestSnapshotTime = info.SnapshotTimestamp
+	}
+
+	if err := ldb.setPlaybackVisitLocations(ctx, playback, visitLocationIDs); err != nil {
+		return nil, err
+	}
+	if err := ldb.setPlaybackVisitPhases(ctx, playback, latestSnapshotTime); err != nil {
+		return nil, err
+	}
+	if err := ldb.setPlaybackShiftTeamLocations(ctx, playback); err != nil {
+		return nil, err
+	}
+
+	return playback, nil
+}
+
+func (ldb *LogisticsDB) playbackSchedule(
+	ctx context.Context,
+	info *logisticssql.GetScheduleInfosForServiceRegionDateRow,
+	visitLocationIDs map[CareRequestID]int64,
+) (*PlaybackSchedule, error) {
+	routes, err := ldb.queries.GetScheduleRoutesForSchedule(ctx, info.ScheduleID)
+	if err != nil {
+		return nil, fmt.Errorf("error in GetScheduleRoutesForSchedule: %w", err)
+	}
+	schedule := &PlaybackSchedule{
+		ScheduleID:       info.ScheduleID,
+		OptimizerRunID:   info.OptimizerRunID,
+		CreatedAt:        info.CreatedAt,
+		Routes:           make(map[ShiftTeamID][]*PlaybackStop, len(routes)),
+		UnassignedVisits: []CareRequestID{},
+	}
+	shiftTeamIDsByRouteID := make(map[int64]ShiftTeamID, len(routes))
+	for _, route := range routes {
+		shiftTeamID := ShiftTeamID(route.ShiftTeamID)
+		shiftTeamIDsByRouteID[route.ID] = shiftTeamID
+		schedule.Routes[shiftTeamID] = []*PlaybackStop{}
+	}
+
+	stops, err := ldb.queries.GetScheduleRouteStopsForSchedule(ctx, logisticssql.GetScheduleRouteStopsForScheduleParams{
+		ScheduleID:         info.ScheduleID,
+		LatestSnapshotTime: info.SnapshotTimestamp,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error in GetScheduleRouteStopsForSchedule: %w", err)
+	}
+	for _, stop := range stops {
+		if !stop.CareRequestID.Valid {
+			continue
+		}
+		shiftTeamID, ok := shiftTeamIDsByRouteID[stop.ScheduleRouteID]
+		if !ok {
+			return nil, fmt.Errorf("unknown schedule_route(%d) for schedule(%d)", stop.ScheduleRouteID, info.ScheduleID)
+		}
+		careRequestID := CareRequestID(stop.CareRequestID.Int64)
+		schedule.Routes[shiftTeamID] = append(schedule.Routes[shiftTeamID], &PlaybackStop{
+			CareRequestID:       careRequestID,
+			ArrivalTimestampSec: stop.ArrivalTimestampSec.Int64,
+		})
+		if stop.VisitLocationID.Valid {
+			visitLocationIDs[careRequestID] = stop.VisitLocationID.Int64
+		}
+	}
+
+	unassignedVisits, err := ldb.queries.GetUnassignedScheduleVisitsForScheduleID(ctx, logisticssql.GetUnassignedScheduleVisitsForScheduleIDParams{
+		ScheduleID:         info.ScheduleID,
+		LatestSnapshotTime: info.SnapshotTimestamp,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error in GetUnassignedScheduleVisitsForScheduleID: %w", err)
+	}
+	for _, visit := range unassignedVisits {
+		schedule.UnassignedVisits = append(schedule.UnassignedVisits, CareRequestID(visit.CareRequestID))
+	}
+
+	return schedule, nil
+}
+
+func (ldb *LogisticsDB) setPlaybackVisitLocations(ctx context.Context, playback *RoutePlayback, visitLocationIDs map[CareRequestID]int64) error {
+	if len(visitLocationIDs) == 0 {
+		return nil
+	}
+
+	locIDs := make([]int64, 0, len(visitLocationIDs))
+	for _, locID := range visitLocationIDs {
+		locIDs = append(locIDs, locID)
+	}
+	locs, err := ldb.GetLocationsByIDs(ctx, locIDs)
+	if err != nil {
+		return err
+	}
+	latLngs := make(map[int64]logistics.LatLng, len(locs))
+	for _, loc := range locs {
+		latLngs[loc.ID] = logistics.LatLng{LatE6: loc.LatitudeE6, LngE6: loc.LongitudeE6}
+	}
+	for careRequestID, locID := range visitLocationIDs {
+		if latLng, ok := latLngs[locID]; ok {
+			playback.VisitLocations[careRequestID] = latLng
+		}
+	}
+	return nil
+}
+
+func (ldb *LogisticsDB) setPlaybackVisitPhases(ctx context.Context, playback *RoutePlayback, latestSnapshotTime time.Time) error {
+	careRequestIDs := playbackCareRequestIDs(playback.Schedules)
+	if len(careRequestIDs) == 0 {
+		return nil
+	}
+
+	rows, err := ldb.queries.GetVisitPhaseHistoryForCareRequests(ctx, logisticssql.GetVisitPhaseHistoryForCareRequestsParams{
+		CareRequestIds:     careRequestIDs,
+		LatestSnapshotTime: latestSnapshotTime,
+	})
+	if err != nil {
+		return fmt.Errorf("error in GetVisitPhaseHistoryForCareRequests: %w", err)
+	}
+	for _, row := range rows {
+		careRequestID := CareRequestID(row.CareRequestID)
+		phases := playback.VisitPhases[careRequestID]
+		if len(phases) > 0 && phases[len(phases)-1].Phase == row.VisitPhaseShortName {
+			continue
+		}
+
+		var shiftTeamID *ShiftTeamID
+		if row.ShiftTeamID.Valid {
+			id := ShiftTeamID(row.ShiftTeamID.Int64)
+			shiftTeamID = &id
+		}
+		playback.VisitPhases[careRequestID] = append(phases, &PlaybackVisitPhase{
+			Phase:       row.VisitPhaseShortName,
+			Timestamp:   row.StatusCreatedAt,
+			ShiftTeamID: shiftTeamID,
+		})
+	}
+	return nil
+}
+
+func (ldb *LogisticsDB) setPlaybackShiftTeamLocations(ctx context.Context, playback *RoutePlayback) error {
+	shiftTeamIDs := map[ShiftTeamID]bool{}
+	for _, schedule := range playback.Schedules {
+		for shiftTeamID := range schedule.Routes {
+			shiftTeamIDs[shiftTeamID] = true
+		}
+	}
+
+	for shiftTeamID := range shiftTeamIDs {
+		rows, err := ldb.queries.GetShiftTeamLocationsInTimeRange(ctx, logisticssql.GetShiftTeamLocationsInTimeRangeParams{
+			ShiftTeamID: int64(shiftTeamID),
+			StartTime:   time.Unix(playback.OpenHoursStartTimestampSec, 0),
+			EndTime:     time.Unix(playback.OpenHoursEndTimestampSec, 0),
+		})
+		if err != nil {
+			return fmt.Errorf("error in GetShiftTeamLocationsInTimeRange: %w", err)
+		}
+		locations := make([]*PlaybackLocation, len(rows))
+		for i, row := range rows {
+			locations[i] = &PlaybackLocation{
+				LatLng:    logistics.LatLng{LatE6: row.LatitudeE6, LngE6: row.LongitudeE6},
+				Timestamp: row.CreatedAt,
+			}
+		}
+		playback.ShiftTeamLocations[shiftTeamID] = locations
+	}
+	return nil
+}
+
+func playbackCareRequestIDs(schedules []*PlaybackSchedule) []int64 {
+	seen := map[CareRequestID]bool{}
+	var res []int64
+	add := func(careRequestID CareRequestID) {
+		if !seen[careRequestID] {
+			seen[careRequestID] = true
+			res = append(res, int64(careRequestID))
+		}
+	}
+	for _, schedule := range schedules {
+		for _, stops := range schedule.Routes {
+			for _, stop := range stops {
+				add(stop.CareRequestID)
+			}
+		}
+		for _, careRequestID := range schedule.UnassignedVisits {
+			add(careRequestID)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
+
+func (s *PlaybackSchedule) assignments() map[CareRequestID]*ShiftTeamID {
+	res := map[CareRequestID]*ShiftTeamID{}
+	for shiftTeamID, stops := range s.Routes {
+		shiftTeamID := shiftTeamID
+		for _, stop := range stops {
+			res[stop.CareRequestID] = &shiftTeamID
+		}
+	}
+	for _, careRequestID := range s.UnassignedVisits {
+		res[careRequestID] = nil
+	}
+	return res
+}
+
+// scheduleChanges returns the visits assigned to different shift teams in the schedule than in the previous schedule,
+// sorted by care request.
+func scheduleChanges(previous, schedule *PlaybackSchedule) []*ScheduleChange {
+	var previousAssignments map[CareRequestID]*ShiftTeamID
+	if previous != nil {
+		previousAssignments = previous.assignments()
+	}
+	assignments := schedule.assignments()
+
+	changes := []*ScheduleChange{}
+	for careRequestID, to := range assignments {
+		from := previousAssignments[careRequestID]
+		if !sameShiftTeam(from, to) {
+			changes = append(changes, &ScheduleChange{CareRequestID: careRequestID, FromShiftTeamID: from, ToShiftTeamID: to})
+		}
+	}
+	for careRequestID, from := range previousAssignments {
+		if _, ok := assignments[careRequestID]; !ok && from != nil {
+			changes = append(changes, &ScheduleChange{CareRequestID: careRequestID, FromShiftTeamID: from})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].CareRequestID < changes[j].CareRequestID
+	})
+	return changes
+}
+
+func sameShiftTeam(a, b *ShiftTeamID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,94 @@
+package logisticsdb
+
+import (
+	"testing"
+
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+)
+
+func shiftTeamIDPtr(id ShiftTeamID) *ShiftTeamID {
+	return &id
+}
+
+func TestScheduleChanges(t *testing.T) {
+	previous := &PlaybackSchedule{
+		Routes: map[ShiftTeamID][]*PlaybackStop{
+			1: {{CareRequestID: 10}, {CareRequestID: 11}},
+			2: {{CareRequestID: 12}},
+		},
+		UnassignedVisits: []CareRequestID{13, 14},
+	}
+
+	tcs := []struct {
+		Desc     string
+		Previous *PlaybackSchedule
+		Schedule *PlaybackSchedule
+
+		ExpectedChanges []*ScheduleChange
+	}{
+		{
+			Desc:     "first schedule",
+			Schedule: previous,
+
+			ExpectedChanges: []*ScheduleChange{
+				{CareRequestID: 10, ToShiftTeamID: shiftTeamIDPtr(1)},
+				{CareRequestID: 11, ToShiftTeamID: shiftTeamIDPtr(1)},
+				{CareRequestID: 12, ToShiftTeamID: shiftTeamIDPtr(2)},
+			},
+		},
+		{
+			Desc:     "same assignments",
+			Previous: previous,
+			Schedule: &PlaybackSchedule{
+				Routes: map[ShiftTeamID][]*PlaybackStop{
+					1: {{CareRequestID: 11}, {CareRequestID: 10}},
+					2: {{CareRequestID: 12}},
+				},
+				UnassignedVisits: []CareRequestID{13, 14},
+			},
+
+			ExpectedChanges: []*ScheduleChange{},
+		},
+		{
+			Desc:     "reassigned, unassigned, assigned and removed visits",
+			Previous: previous,
+			Schedule: &PlaybackSchedule{
+				Routes: map[ShiftTeamID][]*PlaybackStop{
+					1: {{CareRequestID: 10}, {CareRequestID: 13}},
+					2: {{CareRequestID: 11}},
+				},
+				UnassignedVisits: []CareRequestID{12},
+			},
+
+			ExpectedChanges: []*ScheduleChange{
+				{CareRequestID: 11, FromShiftTeamID: shiftTeamIDPtr(1), ToShiftTeamID: shiftTeamIDPtr(2)},
+				{CareRequestID: 12, FromShiftTeamID: shiftTeamIDPtr(2)},
+				{CareRequestID: 13, ToShiftTeamID: shiftTeamIDPtr(1)},
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			testutils.MustMatch(t, tc.ExpectedChanges, scheduleChanges(tc.Previous, tc.Schedule))
+		})
+	}
+}
+
+func TestPlaybackCareRequestIDs(t *testing.T) {
+	schedules := []*PlaybackSchedule{
+		{
+			Routes: map[ShiftTeamID][]*PlaybackStop{
+				1: {{CareRequestID: 12}, {CareRequestID: 10}},
+			},
+			UnassignedVisits: []CareRequestID{11},
+		},
+		{
+			Routes: map[ShiftTeamID][]*PlaybackStop{
+				2: {{CareRequestID: 10}, {CareRequestID: 13}},
+			},
+		},
+	}
+
+	testutils.MustMatch(t, []int64{10, 11, 12, 13}, playbackCareRequestIDs(schedules))
+}
@@ -3,10 +3,14 @@
 package logisticsdb_test
 
 import (
+	"errors"
 	"testing"
 	"time"
 
+	commonpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/common"
 	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+	"github.com/*company-data-covered*/services/go/pkg/monitoring"
 	"github.com/*company-data-covered*/services/go/pkg/testutils"
 )
 
@@ -156,3 +160,62 @@ func TestServiceRegionOpenHours(t *testing.T) {
 	expectedLatestScheduleDays := days[len(days)-numWeekdays:]
 	testutils.MustMatch(t, expectedLatestScheduleDays, newDays, "not matching days")
 }
+
+func TestBlackoutZones(t *testing.T) {
+	ctx, db, _, done := setupDBTest(t)
+	defer done()
+
+	ldb := logisticsdb.NewLogisticsDB(db, nil, noSettingsService, &monitoring.NoopScope{})
+	serviceRegionID := time.Now().UnixNano()
+	start := time.Date(2023, time.September, 15, 8, 0, 0, 0, time.UTC)
+	params := logisticsdb.BlackoutZoneParams{
+		Name: "Flooding",
+		Vertices: []*commonpb.Location{
+			{LatitudeE6: 0, LongitudeE6: 0},
+			{LatitudeE6: 0, LongitudeE6: 10},
+			{LatitudeE6: 10, LongitudeE6: 10},
+		},
+		TimeWindow: logisticsdb.TimeWindow{Start: start, End: start.Add(2 * time.Hour)},
+	}
+
+	zone, err := ldb.AddBlackoutZone(ctx, serviceRegionID, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutils.MustMatch(t, serviceRegionID, zone.ServiceRegionID)
+	testutils.MustMatch(t, 3, len(zone.Polygon))
+
+	zones, err := ldb.GetBlackoutZonesInServiceRegion(ctx, serviceRegionID, logisticsdb.TimeWindow{Start: start.Add(time.Hour), End: start.Add(5 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutils.MustMatch(t, logisticsdb.BlackoutZones{zone}, zones, "overlapping zone should be listed")
+
+	params.TimeWindow = logisticsdb.TimeWindow{Start: start.Add(6 * time.Hour), End: start.Add(8 * time.Hour)}
+	updatedZone, err := ldb.UpdateBlackoutZone(ctx, zone.ID, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutils.MustMatch(t, zone.ID, updatedZone.ID)
+
+	zones, err = ldb.GetBlackoutZonesInServiceRegion(ctx, serviceRegionID, logisticsdb.TimeWindow{Start: start.Add(time.Hour), End: start.Add(5 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutils.MustMatch(t, 0, len(zones), "moved zone should not be listed")
+
+	if err := ldb.DeleteBlackoutZone(ctx, zone.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := ldb.DeleteBlackoutZone(ctx, zone.ID); !errors.Is(err, logisticsdb.ErrBlackoutZoneNotFound) {
+		t.Fatalf("expected deleted zone not to be found: %v", err)
+	}
+	if _, err := ldb.UpdateBlackoutZone(ctx, zone.ID, params); !errors.Is(err, logisticsdb.ErrBlackoutZoneNotFound) {
+		t.Fatalf("expected deleted zone not to be updated: %v", err)
+	}
+
+	params.Vertices = params.Vertices[:2]
+	if _, err := ldb.AddBlackoutZone(ctx, serviceRegionID, params); !errors.Is(err, logisticsdb.ErrInvalidBlackoutZonePolygon) {
+		t.Fatalf("expected invalid polygon: %v", err)
+	}
+}
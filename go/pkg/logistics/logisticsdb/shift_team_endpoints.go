@@ -0,0 +1,155 @@
+package logisticsdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	commonpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/common"
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics"
+	"github.com/jackc/pgx/v4"
+)
+
+// shiftTeamEndpoints are the overrides of where and when a shift team starts and ends its shift.
+type shiftTeamEndpoints struct {
+	StartLocationID               sql.NullInt64
+	EndLocationID                 sql.NullInt64
+	EarliestDepartureTimestampSec sql.NullInt64
+	ReturnByTimestampSec          sql.NullInt64
+}
+
+func upsertNullableLocation(ctx context.Context, queries *logisticssql.Queries, loc *commonpb.Location) (sql.NullInt64, error) {
+	if loc == nil {
+		return sql.NullInt64{}, nil
+	}
+	location, err := UpsertLocation(ctx, queries, logistics.LatLng{
+		LatE6: loc.LatitudeE6,
+		LngE6: loc.LongitudeE6,
+	})
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+	return sql.NullInt64{Int64: location.ID, Valid: true}, nil
+}
+
+// shiftTeamSnapshotEndpoints returns the endpoints to write for a new shift team snapshot.
+//
+// Endpoints of the latest snapshot of the shift team are kept if none are given.
+func shiftTeamSnapshotEndpoints(
+	ctx context.Context,
+	queries *logisticssql.Queries,
+	shiftTeamID int64,
+	endpoints *logisticspb.ShiftTeamEndpoints,
+) (*shiftTeamEndpoints, error) {
+	if endpoints == nil {
+		latest, err := queries.GetLatestShiftTeamSnapshot(ctx, logisticssql.GetLatestShiftTeamSnapshotParams{
+			ShiftTeamID: shiftTeamID,
+			CreatedAt:   time.Now(),
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return &shiftTeamEndpoints{}, nil
+			}
+			return nil, err
+		}
+
+		return &shiftTeamEndpoints{
+			StartLocationID:               latest.StartLocationID,
+			EndLocationID:                 latest.EndLocationID,
+			EarliestDepartureTimestampSec: latest.EarliestDepartureTimestampSec,
+			ReturnByTimestampSec:          latest.ReturnByTimestampSec,
+		}, nil
+	}
+
+	startLocationID, err := upsertNullableLocation(ctx, queries, endpoints.StartLocation)
+	if err != nil {
+		return nil, err
+	}
+	endLocationID, err := upsertNullableLocation(ctx, queries, endpoints.EndLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	var earliestDepartureTimestampSec, returnByTimestampSec sql.NullInt64
+	if endpoints.EarliestDepartureTimestampSec != nil {
+		earliestDepartureTimestampSec = sql.NullInt64{Int64: endpoints.GetEarliestDepartureTimestampSec(), Valid: true}
+	}
+	if endpoints.ReturnByTimestampSec != nil {
+		returnByTimestampSec = sql.NullInt64{Int64: endpoints.GetReturnByTimestampSec(), Valid: true}
+	}
+
+	return &shiftTeamEndpoints{
+		StartLocationID:               startLocationID,
+		EndLocationID:                 endLocationID,
+		EarliestDepartureTimestampSec: earliestDepartureTimestampSec,
+		ReturnByTimestampSec:          returnByTimestampSec,
+	}, nil
+}
+
+// shiftTeamStartLocationID returns the location where the shift team starts its shift.
+func shiftTeamStartLocationID(snapshot *logisticssql.ShiftTeamSnapshot) int64 {
+	if snapshot.StartLocationID.Valid {
+		return snapshot.StartLocationID.Int64
+	}
+	return snapshot.BaseLocationID
+}
+
+// shiftTeamEndLocationID returns the location where the shift team ends its shift.
+func shiftTeamEndLocationID(snapshot *logisticssql.ShiftTeamSnapshot) int64 {
+	if snapshot.EndLocationID.Valid {
+		return snapshot.EndLocationID.Int64
+	}
+	return shiftTeamStartLocationID(snapshot)
+}
+
+// shiftTeamAvailableTimeWindow returns the time window the shift team is available to leave its start location
+// and be back at its end location.
+//
+// The shift start buffer only applies when no earliest departure was given for the shift team.
+func shiftTeamAvailableTimeWindow(snapshot *logisticssql.ShiftTeamSnapshot, shiftStartBufferSec int64) (startTimestampSec int64, endTimestampSec int64) {
+	endTimestampSec = snapshot.EndTimestampSec
+	if snapshot.ReturnByTimestampSec.Valid {
+		endTimestampSec = snapshot.ReturnByTimestampSec.Int64
+	}
+
+	startTimestampSec = snapshot.StartTimestampSec + shiftStartBufferSec
+	if snapshot.EarliestDepartureTimestampSec.Valid {
+		startTimestampSec = snapshot.EarliestDepartureTimestampSec.Int64
+	}
+
+	return min(startTimestampSec, endTimestampSec), endTimestampSec
+}
+
+// shiftTeamRouteLegs sets the first and last legs of the route, between its stops and the base and end locations.
+func shiftTeamRouteLegs(route *logisticspb.ShiftTeamRoute) {
+	stops := route.GetStops()
+	if len(stops) == 0 {
+		return
+	}
+
+	firstLocation, firstArrivalTimestampSec := shiftTeamRouteStopLocationAndArrival(stops[0])
+	lastLocation, _ := shiftTeamRouteStopLocationAndArrival(stops[len(stops)-1])
+	route.FirstLeg = &logisticspb.ShiftTeamRouteLeg{
+		StartLocation:       route.GetBaseLocation(),
+		EndLocation:         firstLocation,
+		ArrivalTimestampSec: firstArrivalTimestampSec,
+	}
+	route.LastLeg = &logisticspb.ShiftTeamRouteLeg{
+		StartLocation:       lastLocation,
+		EndLocation:         route.GetEndLocation(),
+		ArrivalTimestampSec: route.BaseLocationArrivalTimestampSec,
+	}
+}
+
+func shiftTeamRouteStopLocationAndArrival(stop *logisticspb.ShiftTeamRouteStop) (*commonpb.Location, *int64) {
+	if restBreak := stop.GetRestBreak(); restBreak != nil {
+		return restBreak.GetLocation(), restBreak.StartTimestampSec
+	}
+	if visit := stop.GetVisit(); visit != nil {
+		return visit.GetLocation(), visit.ArrivalTimestampSec
+	}
+	return nil, nil
+}
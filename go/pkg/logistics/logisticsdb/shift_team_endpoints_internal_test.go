@@ -0,0 +1,104 @@
+package logisticsdb
+
+import (
+	"database/sql"
+	"testing"
+
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+)
+
+func TestShiftTeamEndpointLocationIDs(t *testing.T) {
+	tcs := []struct {
+		Desc     string
+		Snapshot *logisticssql.ShiftTeamSnapshot
+
+		ExpectedStartLocationID int64
+		ExpectedEndLocationID   int64
+	}{
+		{
+			Desc:     "base location",
+			Snapshot: &logisticssql.ShiftTeamSnapshot{BaseLocationID: 1},
+
+			ExpectedStartLocationID: 1,
+			ExpectedEndLocationID:   1,
+		},
+		{
+			Desc: "home-based shift team",
+			Snapshot: &logisticssql.ShiftTeamSnapshot{
+				BaseLocationID:  1,
+				StartLocationID: sql.NullInt64{Int64: 2, Valid: true},
+			},
+
+			ExpectedStartLocationID: 2,
+			ExpectedEndLocationID:   2,
+		},
+		{
+			Desc: "ends at another depot",
+			Snapshot: &logisticssql.ShiftTeamSnapshot{
+				BaseLocationID: 1,
+				EndLocationID:  sql.NullInt64{Int64: 3, Valid: true},
+			},
+
+			ExpectedStartLocationID: 1,
+			ExpectedEndLocationID:   3,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			testutils.MustMatch(t, tc.ExpectedStartLocationID, shiftTeamStartLocationID(tc.Snapshot))
+			testutils.MustMatch(t, tc.ExpectedEndLocationID, shiftTeamEndLocationID(tc.Snapshot))
+		})
+	}
+}
+
+func TestShiftTeamAvailableTimeWindow(t *testing.T) {
+	shiftStartBufferSec := int64(10)
+	tcs := []struct {
+		Desc     string
+		Snapshot *logisticssql.ShiftTeamSnapshot
+
+		ExpectedStartTimestampSec int64
+		ExpectedEndTimestampSec   int64
+	}{
+		{
+			Desc:     "shift time window",
+			Snapshot: &logisticssql.ShiftTeamSnapshot{StartTimestampSec: 100, EndTimestampSec: 200},
+
+			ExpectedStartTimestampSec: 110,
+			ExpectedEndTimestampSec:   200,
+		},
+		{
+			Desc: "earliest departure and return by",
+			Snapshot: &logisticssql.ShiftTeamSnapshot{
+				StartTimestampSec:             100,
+				EndTimestampSec:               200,
+				EarliestDepartureTimestampSec: sql.NullInt64{Int64: 150, Valid: true},
+				ReturnByTimestampSec:          sql.NullInt64{Int64: 180, Valid: true},
+			},
+
+			ExpectedStartTimestampSec: 150,
+			ExpectedEndTimestampSec:   180,
+		},
+		{
+			Desc: "return by before shift start buffer",
+			Snapshot: &logisticssql.ShiftTeamSnapshot{
+				StartTimestampSec:    100,
+				EndTimestampSec:      200,
+				ReturnByTimestampSec: sql.NullInt64{Int64: 105, Valid: true},
+			},
+
+			ExpectedStartTimestampSec: 105,
+			ExpectedEndTimestampSec:   105,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			startTimestampSec, endTimestampSec := shiftTeamAvailableTimeWindow(tc.Snapshot, shiftStartBufferSec)
+			testutils.MustMatch(t, tc.ExpectedStartTimestampSec, startTimestampSec)
+			testutils.MustMatch(t, tc.ExpectedEndTimestampSec, endTimestampSec)
+		})
+	}
+}
@@ -45,6 +45,7 @@ type ServiceRegionVRPData struct {
 	ShiftTeamAttrs     []*logisticssql.GetAttributesForShiftTeamSnapshotsRow
 	VisitSnapshots     []*logisticssql.GetLatestVisitSnapshotsInRegionRow
 	VisitAttrs         []*logisticssql.GetAttributesForVisitSnapshotsRow
+	VisitAttrPrefs     []*logisticssql.GetAttributePreferencesForVisitSnapshotsRow
 	RestBreakRequests  []*logisticssql.ShiftTeamRestBreakRequest
 
 	Locations        []*logisticssql.Location
@@ -52,6 +53,10 @@ type ServiceRegionVRPData struct {
 
 	PreviousUnassignedVisits []*logisticssql.GetUnassignedScheduleVisitsForScheduleIDRow
 
+	// Blackout zones active during the open hours, and the visits removed for being in one of them.
+	BlackoutZones    BlackoutZones
+	BlackedOutVisits BlackedOutVisits
+
 	CheckFeasibilityData *CheckFeasibilityVRPDataResult
 
 	Settings            *optimizersettings.Settings
@@ -165,6 +170,31 @@ func (ldb *LogisticsDB) GetServiceRegionVRPData(
 		}
 	}
 
+	if serviceRegionSettings.MultiDayVisitSchedulingEnabled {
+		visits, err = ldb.visitsForServiceDate(ctx, visitsForServiceDateParams{
+			visits:       visits,
+			serviceDate:  params.ServiceDate,
+			tz:           openHoursTW.Start.Location(),
+			snapshotTime: snapshotTime,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error in visitsForServiceDate: %w", err)
+		}
+	}
+
+	blackoutZones, err := ldb.GetBlackoutZonesInServiceRegion(ctx, params.ServiceRegionID, *openHoursTW)
+	if err != nil {
+		return nil, err
+	}
+	visits, blackedOutVisits, err := ldb.visitsOutsideBlackoutZones(ctx, visitsOutsideBlackoutZonesParams{
+		visits:      visits,
+		zones:       blackoutZones,
+		openHoursTW: openHoursTW,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error in visitsOutsideBlackoutZones: %w", err)
+	}
+
 	shiftTeamSnapshotIDs := make([]int64, len(shiftTeams))
 	for i, snapshot := range shiftTeams {
 		shiftTeamSnapshotIDs[i] = snapshot.ID
@@ -182,6 +212,10 @@ func (ldb *LogisticsDB) GetServiceRegionVRPData(
 	if err != nil {
 		return nil, fmt.Errorf("error in GetAttributesForVisitSnapshots: %w", err)
 	}
+	visitAttrPrefs, err := queries.GetAttributePreferencesForVisitSnapshots(ctx, visitSnapshotIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error in GetAttributePreferencesForVisitSnapshots: %w", err)
+	}
 
 	restBreakRequests, err := queries.GetShiftTeamRestBreakRequestsForShiftTeams(
 		ctx,
@@ -214,10 +248,13 @@ func (ldb *LogisticsDB) GetServiceRegionVRPData(
 		VisitSnapshots:           visits,
 		ShiftTeamAttrs:           shiftTeamAttrs,
 		VisitAttrs:               visitAttrs,
+		VisitAttrPrefs:           visitAttrPrefs,
 		RestBreakRequests:        restBreakRequests,
 		Locations:                locs,
 		DepotLocationIDs:         depotLocIDs,
 		PreviousUnassignedVisits: vrpUnassignedVisits,
+		BlackoutZones:            blackoutZones,
+		BlackedOutVisits:         blackedOutVisits,
 		CheckFeasibilityData:     checkFeasibilityVRPData,
 		Settings:                 serviceRegionSettings,
 		SnapshotTime:             snapshotTime,
@@ -370,11 +407,12 @@ func (ldb *LogisticsDB) VRPLocations(ctx context.Context, params VRPLocationsPar
 		len(checkFeasibilityLocIDs) +
 		len(visits) +
 		len(restBreakRequests))
-	depotLocIDs := collections.NewLinkedInt64Set(len(shiftTeams))
+	// Depots are the start and end locations of shift teams, which may differ for home-based shift teams.
+	depotLocIDs := collections.NewLinkedInt64Set(2 * len(shiftTeams))
 	for _, shiftTeam := range shiftTeams {
-		allLocIDs.Add(shiftTeam.BaseLocationID)
-		depotLocIDs.Add(shiftTeam.BaseLocationID)
+		depotLocIDs.Add(shiftTeamStartLocationID(shiftTeam), shiftTeamEndLocationID(shiftTeam))
 	}
+	allLocIDs.AddSet(depotLocIDs)
 	for _, visit := range visits {
 		allLocIDs.Add(visit.LocationID)
 	}
@@ -413,6 +451,16 @@ type VRPProblemData struct {
 
 	// EntityMappings are references from VRPProblem identifiers to Station identifiers.
 	EntityMappings EntityMappings
+
+	// MultiDayVisits are visits that may be scheduled on any service date of their arrival time window,
+	// if multi-day visit scheduling is enabled.
+	MultiDayVisits MultiDayVisits
+
+	// ValidationRepairReport reports the repairs applied to the VRPProblem in validation repair mode.
+	ValidationRepairReport *validation.RepairReport
+
+	// BlackedOutVisits are the visits removed from the VRPProblem by blackout zones.
+	BlackedOutVisits BlackedOutVisits
 }
 
 func (ldb *LogisticsDB) CreateVRPProblem(ctx context.Context, params VRPProblemParams) (*VRPProblemData, error) {
@@ -434,6 +482,7 @@ func (ldb *LogisticsDB) CreateVRPProblem(ctx context.Context, params VRPProblemP
 	vrpVisits, err := vrpVisitsForVisitSnapshots(vrpVisitsForVisitSnapshotsParams{
 		visitSnapshots:             visits,
 		attrs:                      vrpData.VisitAttrs,
+		attrPrefs:                  vrpData.VisitAttrPrefs,
 		visitExtraSetupDurationSec: settings.VisitExtraSetupDurationSec,
 		useVisitValue:              settings.UseVisitValue,
 	})
@@ -519,7 +568,8 @@ func (ldb *LogisticsDB) CreateVRPProblem(ctx context.Context, params VRPProblemP
 			ServiceRegionID: vrpData.ServiceRegionID,
 			ServiceDate:     vrpData.ServiceDate,
 		},
-		Settings: *vrpData.Settings,
+		Settings:             *vrpData.Settings,
+		DepartureTimeBuckets: NewDepartureTimeBuckets(*vrpData.OpenHoursTW, vrpData.Settings.DistanceDepartureTimeBucketDuration(), time.Now()),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error in GetDistanceMatrix: %w", err)
@@ -555,7 +605,8 @@ func (ldb *LogisticsDB) CreateVRPProblem(ctx context.Context, params VRPProblemP
 		ldb.scope.With("", monitoring.Tags{serviceRegionTag: I64ToA(vrpData.ServiceRegionID)}, nil),
 		params.ValidationConfig,
 	)
-	if err := validator.Validate(problem); err != nil {
+	repairReport, err := validator.ValidateAndRepair(problem)
+	if err != nil {
 		return nil, err
 	}
 
@@ -564,11 +615,19 @@ func (ldb *LogisticsDB) CreateVRPProblem(ctx context.Context, params VRPProblemP
 		checkFeasibilityDiagnostics = vrpData.CheckFeasibilityData.Diagnostics
 	}
 
+	var multiDayVisits MultiDayVisits
+	if settings.MultiDayVisitSchedulingEnabled && vrpData.CheckFeasibilityData == nil {
+		multiDayVisits = newMultiDayVisits(visits, vrpData.OpenHoursTW.Start.Location())
+	}
+
 	return &VRPProblemData{
 		VRPProblem:                  problem,
 		OptimizerRun:                optimizerRun,
 		CheckFeasibilityDiagnostics: checkFeasibilityDiagnostics,
 		EntityMappings:              newEntityMappings(visits, shiftTeams),
+		MultiDayVisits:              multiDayVisits,
+		ValidationRepairReport:      repairReport,
+		BlackedOutVisits:            vrpData.BlackedOutVisits,
 	}, nil
 }
 
@@ -583,6 +642,7 @@ func shiftTeamIDsFromSnapshots(sts []*logisticssql.ShiftTeamSnapshot) []int64 {
 type vrpVisitsForVisitSnapshotsParams struct {
 	visitSnapshots             []*logisticssql.GetLatestVisitSnapshotsInRegionRow
 	attrs                      []*logisticssql.GetAttributesForVisitSnapshotsRow
+	attrPrefs                  []*logisticssql.GetAttributePreferencesForVisitSnapshotsRow
 	visitExtraSetupDurationSec int64
 	useVisitValue              bool
 }
@@ -607,6 +667,14 @@ func vrpVisitsForVisitSnapshots(params vrpVisitsForVisitSnapshotsParams) ([]*opt
 			snapshotUnwantedAttrs[attr.VisitSnapshotID] = append(snapshotUnwantedAttrs[attr.VisitSnapshotID], &optimizerpb.VRPAttribute{Id: attr.Name})
 		}
 	}
+	snapshotAttrPrefs := map[int64][]*optimizerpb.VRPAttributePreference{}
+	for _, pref := range params.attrPrefs {
+		snapshotAttrPrefs[pref.VisitSnapshotID] = append(snapshotAttrPrefs[pref.VisitSnapshotID], &optimizerpb.VRPAttributePreference{
+			Attribute:       &optimizerpb.VRPAttribute{Id: pref.Name},
+			IsUnwanted:      proto.Bool(pref.IsUnwanted),
+			PenaltyUsdCents: proto.Int64(pref.PenaltyUsdCents),
+		})
+	}
 
 	vrpVisits := make([]*optimizerpb.VRPVisit, len(params.visitSnapshots))
 	for i, snapshot := range params.visitSnapshots {
@@ -653,6 +721,7 @@ func vrpVisitsForVisitSnapshots(params vrpVisitsForVisitSnapshotsParams) ([]*opt
 			ServiceDurationSec:    &snapshot.ServiceDurationSec,
 			RequiredAttributes:    optimizerRequiredAttrs,
 			ForbiddenAttributes:   optimizerForbiddenAttrs,
+			AttributePreferences:  snapshotAttrPrefs[snapshot.ID],
 			Acuity:                visitAcuity,
 			Priority:              vrpPriority(snapshot.IsPrioritized),
 			Value:                 visitValue,
@@ -149,8 +149,10 @@ func TestCreateVRPProblem(t *testing.T) { //nolint:gocyclo,cyclop
 				db,
 				logistics.NewMapServicePicker(
 					mapService,
+					nil,
 					mapService,
 					mockSettingsService,
+					monitoring.NewMockScope(),
 				),
 				mockSettingsService,
 				monitoring.NewMockScope())
@@ -234,7 +236,7 @@ func TestCreateVRPProblem(t *testing.T) { //nolint:gocyclo,cyclop
 									{Name: "shift team attr2"},
 								},
 							},
-						})
+						}, nil)
 					if err != nil {
 						t.Fatal(err)
 					}
@@ -531,8 +533,9 @@ func TestCreateVRPProblem(t *testing.T) { //nolint:gocyclo,cyclop
 					knownTimestampSec = shiftKnownTimestampSecs[shiftTeam.ShiftTeamID]
 				}
 				shiftTeamProto := &optimizerpb.VRPShiftTeam{
-					Id:              &shiftTeam.ID,
-					DepotLocationId: &shiftTeam.BaseLocationID,
+					Id:                 &shiftTeam.ID,
+					DepotLocationId:    &shiftTeam.BaseLocationID,
+					EndDepotLocationId: &shiftTeam.BaseLocationID,
 					AvailableTimeWindow: &optimizerpb.VRPTimeWindow{
 						StartTimestampSec: &startTimestampSec,
 						EndTimestampSec:   &shiftTeam.EndTimestampSec,
@@ -1640,8 +1643,10 @@ func TestLogisticsDB_VRPAvailabilityVisitsForScheduleID(t *testing.T) {
 		db,
 		logistics.NewMapServicePicker(
 			mapService,
+			nil,
 			mapService,
 			mockSettingsService,
+			monitoring.NewMockScope(),
 		),
 		mockSettingsService,
 		monitoring.NewMockScope())
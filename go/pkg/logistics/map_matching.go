@@ -0,0 +1,52 @@
+package logistics
+
+import (
+	"context"
+
+	"github.com/*company-data-covered*/services/go/pkg/monitoring"
+)
+
+// MapMatchingService matches locations reported along a drive to the road network.
+type MapMatchingService interface {
+	// MatchTrace matches a trace of locations sorted by time.
+	MatchTrace(ctx context.Context, tags monitoring.Tags, trace []TimedLatLng) (*MatchedTrace, error)
+}
+
+// MatchedTrace is a trace of locations matched to the road network.
+type MatchedTrace struct {
+	// Distance along the matched roads, with the duration estimated by the map service to drive them.
+	Distance Distance
+	// Confidence of the match, from 0 to 1.
+	Confidence float64
+}
+
+// TraceLengthMeters returns the straight-line length of a trace of locations.
+func TraceLengthMeters(trace []TimedLatLng) float64 {
+	var meters float64
+	for i := 1; i < len(trace); i++ {
+		meters += HaversineDistanceMeters(trace[i-1].LatLng, trace[i].LatLng)
+	}
+	return meters
+}
+
+// DownsampleTrace returns at most maxLocations of a trace of locations sorted by time,
+// evenly spread and keeping the first and last ones, with at most one location per second.
+func DownsampleTrace(trace []TimedLatLng, maxLocations int) []TimedLatLng {
+	var deduped []TimedLatLng
+	for _, loc := range trace {
+		if len(deduped) > 0 && deduped[len(deduped)-1].Timestamp.Unix() == loc.Timestamp.Unix() {
+			continue
+		}
+		deduped = append(deduped, loc)
+	}
+	if len(deduped) <= maxLocations || maxLocations < 2 {
+		return deduped
+	}
+
+	sampled := make([]TimedLatLng, maxLocations)
+	step := float64(len(deduped)-1) / float64(maxLocations-1)
+	for i := range sampled {
+		sampled[i] = deduped[int(float64(i)*step+0.5)]
+	}
+	return sampled
+}
@@ -0,0 +1,69 @@
+package logistics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+)
+
+func TestDownsampleTrace(t *testing.T) {
+	start := time.Unix(1000, 0)
+	timedLatLng := func(sec int) TimedLatLng {
+		return TimedLatLng{LatLng: LatLng{LatE6: int32(sec)}, Timestamp: start.Add(time.Duration(sec) * time.Second)}
+	}
+	var trace []TimedLatLng
+	for sec := 0; sec < 10; sec++ {
+		trace = append(trace, timedLatLng(sec))
+	}
+
+	tcs := []struct {
+		Desc         string
+		Trace        []TimedLatLng
+		MaxLocations int
+
+		Want []TimedLatLng
+	}{
+		{
+			Desc:         "under max",
+			Trace:        trace[:3],
+			MaxLocations: 5,
+
+			Want: trace[:3],
+		},
+		{
+			Desc:         "over max keeps first and last",
+			Trace:        trace,
+			MaxLocations: 4,
+
+			Want: []TimedLatLng{trace[0], trace[3], trace[6], trace[9]},
+		},
+		{
+			Desc: "same second",
+			Trace: []TimedLatLng{
+				trace[0],
+				{LatLng: LatLng{LatE6: 100}, Timestamp: start.Add(500 * time.Millisecond)},
+				trace[1],
+			},
+			MaxLocations: 5,
+
+			Want: trace[:2],
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			testutils.MustMatch(t, tc.Want, DownsampleTrace(tc.Trace, tc.MaxLocations))
+		})
+	}
+}
+
+func TestTraceLengthMeters(t *testing.T) {
+	a := NewLatLng(1, 2)
+	b := NewLatLng(1.01, 2)
+	c := NewLatLng(1.01, 2.01)
+	trace := []TimedLatLng{{LatLng: a}, {LatLng: b}, {LatLng: c}}
+
+	testutils.MustMatch(t, HaversineDistanceMeters(a, b)+HaversineDistanceMeters(b, c), TraceLengthMeters(trace))
+	testutils.MustMatch(t, 0.0, TraceLengthMeters(trace[:1]))
+}
@@ -3,12 +3,14 @@ package logistics
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"cloud.google.com/go/maps/routing/apiv2/routingpb"
 	"github.com/*company-data-covered*/services/go/pkg/logistics/optimizer/optimizersettings"
 	"github.com/*company-data-covered*/services/go/pkg/monitoring"
 	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"googlemaps.github.io/maps"
 )
 
@@ -27,6 +29,7 @@ type Legs []*Distance
 const (
 	distanceMatrixMeasurementName = "get_distance_matrix"
 	getRouteMeasurementName       = "get_route"
+	matchTraceMeasurementName     = "match_trace"
 
 	statusTag        = "status"
 	statusTagSuccess = "OK"
@@ -46,50 +49,92 @@ const (
 // Must be initialized with NewMapServicePicker.
 type MapServicePicker struct {
 	osrm        MapService
+	valhalla    MapService
 	defaultMaps MapService
 
 	otherMapServices map[int64][]MapService
+	circuitBreakers  map[int64]*CircuitBreaker
 
 	settingsService optimizersettings.Service
+	scope           monitoring.Scope
 }
 
 // NewMapServicePicker returns a MapServicePicker. If defaultMaps is nil, then all calls get routed to OSRM.
+// Valhalla is optional, and only used for regions that opt in to it.
 // SettingsService is used to resolve routing by region.
-func NewMapServicePicker(osrm MapService, defaultMaps MapService, settingsService optimizersettings.Service) *MapServicePicker {
-	allServices := map[int64]MapService{}
-	if osrm != nil {
-		allServices[osrm.GetDistanceSourceID()] = osrm
-	}
-	if defaultMaps != nil {
-		allServices[defaultMaps.GetDistanceSourceID()] = defaultMaps
+func NewMapServicePicker(osrm MapService, valhalla MapService, defaultMaps MapService, settingsService optimizersettings.Service, scope monitoring.Scope) *MapServicePicker {
+	var allServices []MapService
+	seenSourceIDs := map[int64]bool{}
+	for _, service := range []MapService{osrm, valhalla, defaultMaps} {
+		if service == nil || seenSourceIDs[service.GetDistanceSourceID()] {
+			continue
+		}
+		seenSourceIDs[service.GetDistanceSourceID()] = true
+		allServices = append(allServices, service)
 	}
 
 	otherServices := map[int64][]MapService{}
-	for sourceID := range allServices {
-		for otherSourceID, service := range allServices {
-			if otherSourceID != sourceID {
-				otherServices[sourceID] = append(otherServices[sourceID], service)
+	circuitBreakers := map[int64]*CircuitBreaker{}
+	for _, service := range allServices {
+		sourceID := service.GetDistanceSourceID()
+		for _, otherService := range allServices {
+			if otherService.GetDistanceSourceID() != sourceID {
+				otherServices[sourceID] = append(otherServices[sourceID], otherService)
 			}
 		}
+		circuitBreakers[sourceID] = NewCircuitBreaker(DefaultCircuitBreakerConfig)
 	}
 
 	return &MapServicePicker{
 		osrm:             osrm,
+		valhalla:         valhalla,
 		defaultMaps:      defaultMaps,
 		settingsService:  settingsService,
 		otherMapServices: otherServices,
+		circuitBreakers:  circuitBreakers,
+		scope:            scope,
 	}
 }
 
+// MapServiceForRegion returns the map service configured for the region.
+// If the region uses map service fallback, it is a FallbackMapService with the other map services as fallbacks.
 func (p *MapServicePicker) MapServiceForRegion(ctx context.Context, serviceRegionID int64) (MapService, error) {
 	settings, err := p.settingsService.ServiceRegionSettings(ctx, serviceRegionID)
 	if err != nil {
 		return nil, fmt.Errorf("MapServicePicker error resolving settings for region(%d): %w", serviceRegionID, err)
 	}
+
+	return p.withFallbacks(p.primaryMapService(settings), settings), nil
+}
+
+func (p *MapServicePicker) primaryMapService(settings *optimizersettings.Settings) MapService {
+	if p.valhalla != nil && settings.UseValhallaMapService {
+		return p.valhalla
+	}
 	if p.defaultMaps != nil && !settings.UseOSRMMapService {
-		return p.defaultMaps, nil
+		return p.defaultMaps
+	}
+	return p.osrm
+}
+
+func (p *MapServicePicker) withFallbacks(primary MapService, settings *optimizersettings.Settings) MapService {
+	if primary == nil || !settings.UseMapServiceFallback {
+		return primary
+	}
+
+	sourceID := primary.GetDistanceSourceID()
+	others := p.otherMapServices[sourceID]
+	if len(others) == 0 {
+		return primary
 	}
-	return p.osrm, nil
+
+	mapServices := append([]MapService{primary}, others...)
+	breakers := make([]*CircuitBreaker, len(mapServices))
+	for i, ms := range mapServices {
+		breakers[i] = p.circuitBreakers[ms.GetDistanceSourceID()]
+	}
+
+	return NewFallbackMapService(mapServices, breakers, p.scope)
 }
 
 func (p *MapServicePicker) OtherMapServicesForRegion(ctx context.Context, serviceRegionID int64) ([]MapService, error) {
@@ -107,7 +152,7 @@ func (p *MapServicePicker) RealTimeTrafficMapService(ctx context.Context, servic
 		return nil, fmt.Errorf("MapServicePicker error resolving settings for region(%d): %w", serviceRegionID, err)
 	}
 	if settings.UseGoogleMapsForRealTimeTraffic {
-		return p.defaultMaps, nil
+		return p.withFallbacks(p.defaultMaps, settings), nil
 	}
 	return p.MapServiceForRegion(ctx, serviceRegionID)
 }
@@ -121,10 +166,25 @@ type MapService interface {
 	IsHealthy(context.Context) bool
 }
 
+// MapMatchingService returns the map service to match traces of locations with, if any supports it.
+func (p *MapServicePicker) MapMatchingService() (MapMatchingService, bool) {
+	matcher, ok := p.osrm.(MapMatchingService)
+	return matcher, ok
+}
+
 type NearestWaypointMapService interface {
 	GetNearestWaypoint(ctx context.Context, latLng LatLng) (*LatLng, error)
 }
 
+// TimeDependentMapService is a MapService that can compute distances for a given departure time,
+// accounting for the expected traffic at that time.
+type TimeDependentMapService interface {
+	MapService
+
+	GetDistanceMatrixAtTime(ctx context.Context, tags monitoring.Tags, departureTime time.Time, origins, destinations []LatLng) (DistanceMatrix, error)
+	GetPathDistanceMatrixAtTime(ctx context.Context, tags monitoring.Tags, departureTime time.Time, path ...LatLng) (DistanceMatrix, error)
+}
+
 type Distance struct {
 	Duration     time.Duration
 	LengthMeters int64
@@ -137,6 +197,9 @@ type DistanceMatrixRequest struct {
 
 	RouteModifiers    *routingpb.RouteModifiers
 	RoutingPreference routingpb.RoutingPreference
+
+	// DepartureTime for traffic aware requests; nil departs now.
+	DepartureTime *time.Time
 }
 
 func (r *DistanceMatrixRequest) ComputeRouteMatrixRequest() *routingpb.ComputeRouteMatrixRequest {
@@ -145,6 +208,7 @@ func (r *DistanceMatrixRequest) ComputeRouteMatrixRequest() *routingpb.ComputeRo
 		Destinations:      r.Destinations.RouteMatrixDestinations(),
 		TravelMode:        routingpb.RouteTravelMode_DRIVE,
 		RoutingPreference: r.RoutingPreference,
+		DepartureTime:     departureTimestamp(r.DepartureTime),
 	}
 }
 
@@ -154,11 +218,25 @@ func (r *DistanceMatrixRequest) Size() int {
 }
 
 func (r *DistanceMatrixRequest) MapsDistanceMatrixRequest() *maps.DistanceMatrixRequest {
+	var departureTime string
+	if r.DepartureTime != nil {
+		departureTime = strconv.FormatInt(r.DepartureTime.Unix(), 10)
+	}
+
 	return &maps.DistanceMatrixRequest{
-		Origins:      r.Origins.GoogleMapsCoordinates(),
-		Destinations: r.Destinations.GoogleMapsCoordinates(),
-		Units:        maps.UnitsMetric,
+		Origins:       r.Origins.GoogleMapsCoordinates(),
+		Destinations:  r.Destinations.GoogleMapsCoordinates(),
+		Units:         maps.UnitsMetric,
+		DepartureTime: departureTime,
+	}
+}
+
+func departureTimestamp(departureTime *time.Time) *timestamppb.Timestamp {
+	if departureTime == nil {
+		return nil
 	}
+
+	return timestamppb.New(*departureTime)
 }
 
 type DistanceMatrix map[LatLng]map[LatLng]Distance
@@ -12,11 +12,13 @@ import (
 	"github.com/*company-data-covered*/services/go/pkg/monitoring"
 	"github.com/*company-data-covered*/services/go/pkg/testutils"
 	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func TestMapServicePickerForRegion(t *testing.T) {
 	validUseOSRMSettingService := &optimizersettings.MockSettingsService{RegionSettings: &optimizersettings.Settings{UseOSRMMapService: true}}
 	validDoNotUseOSRMSettingService := &optimizersettings.MockSettingsService{RegionSettings: &optimizersettings.Settings{UseOSRMMapService: false}}
+	validUseValhallaSettingService := &optimizersettings.MockSettingsService{RegionSettings: &optimizersettings.Settings{UseValhallaMapService: true}}
 	invalidSettingService := &optimizersettings.MockSettingsService{ServiceRegionSettingsErr: errors.New("bad")}
 	defaultMaps := &GoogleMapsService{
 		DistanceSourceID: 123,
@@ -24,6 +26,9 @@ func TestMapServicePickerForRegion(t *testing.T) {
 	osrm := &OSRMService{
 		DistanceSourceID: 456,
 	}
+	valhalla := &ValhallaService{
+		DistanceSourceID: 789,
+	}
 
 	tcs := []struct {
 		Desc   string
@@ -36,26 +41,40 @@ func TestMapServicePickerForRegion(t *testing.T) {
 	}{
 		{
 			Desc:   "default maps when set and not overridden",
-			Picker: NewMapServicePicker(osrm, defaultMaps, validDoNotUseOSRMSettingService),
+			Picker: NewMapServicePicker(osrm, nil, defaultMaps, validDoNotUseOSRMSettingService, monitoring.NewMockScope()),
 
 			Want:       defaultMaps,
 			WantOthers: []MapService{osrm},
 		},
 		{
 			Desc:   "osrm maps when we want to use it",
-			Picker: NewMapServicePicker(osrm, defaultMaps, validUseOSRMSettingService),
+			Picker: NewMapServicePicker(osrm, nil, defaultMaps, validUseOSRMSettingService, monitoring.NewMockScope()),
 
 			Want:       osrm,
 			WantOthers: []MapService{defaultMaps},
 		},
 		{
 			Desc:   "osrm when default maps is not set even if we dont override to osrm",
-			Picker: NewMapServicePicker(osrm, nil, validDoNotUseOSRMSettingService),
+			Picker: NewMapServicePicker(osrm, nil, nil, validDoNotUseOSRMSettingService, monitoring.NewMockScope()),
 			Want:   osrm,
 		},
+		{
+			Desc:   "valhalla maps when we want to use it",
+			Picker: NewMapServicePicker(osrm, valhalla, nil, validUseValhallaSettingService, monitoring.NewMockScope()),
+
+			Want:       valhalla,
+			WantOthers: []MapService{osrm},
+		},
+		{
+			Desc:   "default maps when we want to use valhalla but it is not set",
+			Picker: NewMapServicePicker(osrm, nil, defaultMaps, validUseValhallaSettingService, monitoring.NewMockScope()),
+
+			Want:       defaultMaps,
+			WantOthers: []MapService{osrm},
+		},
 		{
 			Desc:   "settings error case for coverage",
-			Picker: NewMapServicePicker(osrm, nil, invalidSettingService),
+			Picker: NewMapServicePicker(osrm, nil, nil, invalidSettingService, monitoring.NewMockScope()),
 			HasErr: true,
 		},
 	}
@@ -73,6 +92,70 @@ func TestMapServicePickerForRegion(t *testing.T) {
 	}
 }
 
+func TestMapServicePickerFallback(t *testing.T) {
+	defaultMaps := &GoogleMapsService{
+		DistanceSourceID: 123,
+	}
+	osrm := &OSRMService{
+		DistanceSourceID: 456,
+	}
+	valhalla := &ValhallaService{
+		DistanceSourceID: 789,
+	}
+
+	tcs := []struct {
+		Desc     string
+		Settings optimizersettings.Settings
+
+		WantMapServices []MapService
+	}{
+		{
+			Desc: "default maps falls back to osrm then valhalla",
+			Settings: optimizersettings.Settings{
+				UseMapServiceFallback: true,
+			},
+
+			WantMapServices: []MapService{defaultMaps, osrm, valhalla},
+		},
+		{
+			Desc: "valhalla falls back to osrm then default maps",
+			Settings: optimizersettings.Settings{
+				UseValhallaMapService: true,
+				UseMapServiceFallback: true,
+			},
+
+			WantMapServices: []MapService{valhalla, osrm, defaultMaps},
+		},
+		{
+			Desc: "no fallback when not enabled",
+			Settings: optimizersettings.Settings{
+				UseOSRMMapService: true,
+			},
+
+			WantMapServices: []MapService{osrm},
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Desc, func(t *testing.T) {
+			picker := NewMapServicePicker(osrm, valhalla, defaultMaps, &optimizersettings.MockSettingsService{
+				RegionSettings: &tc.Settings,
+			}, monitoring.NewMockScope())
+			ms, err := picker.MapServiceForRegion(context.Background(), 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mapServices := []MapService{ms}
+			if fallback, ok := ms.(*FallbackMapService); ok {
+				mapServices = fallback.MapServices()
+			}
+			testutils.MustMatch(t, tc.WantMapServices, mapServices)
+			testutils.MustMatch(t, tc.WantMapServices[0].GetDistanceSourceID(), ms.GetDistanceSourceID())
+		})
+	}
+}
+
 func TestMapServicePickerRealTimeTrafficMapService(t *testing.T) {
 	defaultMaps := &GoogleMapsService{}
 	osrm := &OSRMService{}
@@ -98,7 +181,7 @@ func TestMapServicePickerRealTimeTrafficMapService(t *testing.T) {
 	ctx := context.Background()
 	for _, tc := range tcs {
 		t.Run(tc.Desc, func(t *testing.T) {
-			picker := NewMapServicePicker(osrm, defaultMaps, &optimizersettings.MockSettingsService{
+			picker := NewMapServicePicker(osrm, nil, defaultMaps, &optimizersettings.MockSettingsService{
 				RegionSettings: &optimizersettings.Settings{
 					UseOSRMMapService:               true,
 					UseGoogleMapsForRealTimeTraffic: tc.UseGoogleMapsForRealTimeTraffic,
@@ -209,6 +292,42 @@ func TestDistanceMatrixRequest_ComputeRouteMatrixRequest(t *testing.T) {
 	}, dmr.ComputeRouteMatrixRequest())
 }
 
+func TestDistanceMatrixRequest_DepartureTime(t *testing.T) {
+	departureTime := time.Date(2023, 9, 5, 8, 0, 0, 0, time.UTC)
+
+	tcs := []struct {
+		Desc          string
+		DepartureTime *time.Time
+
+		WantTimestamp     *timestamppb.Timestamp
+		WantDepartureTime string
+	}{
+		{
+			Desc: "no departure time departs now",
+		},
+		{
+			Desc:          "departure time",
+			DepartureTime: &departureTime,
+
+			WantTimestamp:     timestamppb.New(departureTime),
+			WantDepartureTime: "1693900800",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			dmr := &DistanceMatrixRequest{
+				Origins:       latLngList{NewLatLng(1, 2)},
+				Destinations:  latLngList{NewLatLng(3, 4)},
+				DepartureTime: tc.DepartureTime,
+			}
+
+			testutils.MustMatch(t, tc.WantTimestamp, dmr.ComputeRouteMatrixRequest().GetDepartureTime())
+			testutils.MustMatch(t, tc.WantDepartureTime, dmr.MapsDistanceMatrixRequest().DepartureTime)
+		})
+	}
+}
+
 func TestDistanceMatrixRequest_Size(t *testing.T) {
 	req := &DistanceMatrixRequest{
 		Origins:      latLngList{{}, {}, {}},
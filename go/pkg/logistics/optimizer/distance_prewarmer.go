@@ -0,0 +1,182 @@
+package optimizer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/optimizer/optimizersettings"
+	"github.com/*company-data-covered*/services/go/pkg/monitoring"
+	"go.uber.org/zap"
+)
+
+const (
+	distancePrewarmsMeasurementName = "distance_prewarms"
+
+	locationsField = "locations"
+	elementsField  = "elements"
+	truncatedField = "truncated"
+)
+
+type DistancePrewarmerLogisticsDB interface {
+	GetServiceRegionByID(ctx context.Context, serviceRegionID int64) (*logisticssql.ServiceRegion, error)
+	PrewarmServiceRegionDistances(ctx context.Context, params logisticsdb.PrewarmDistancesParams) (*logisticsdb.PrewarmDistancesResult, error)
+}
+
+// DistancePrewarmer keeps the distances between canonical locations and active shift team locations
+// of each service region fresh in the DB, so that map service latency stays off the user facing paths.
+// Service regions opt in with the DistancePrewarmIntervalSec setting.
+type DistancePrewarmer struct {
+	ldb                  DistancePrewarmerLogisticsDB
+	settingsPollInterval time.Duration
+	logger               *zap.SugaredLogger
+	metrics              monitoring.Scope
+	settingsService      optimizersettings.Service
+
+	mx                     sync.RWMutex
+	serviceRegionsSettings map[int64]optimizersettings.Settings
+	// Service regions with a running prewarm goroutine.
+	runningServiceRegions map[int64]bool
+}
+
+func NewDistancePrewarmer(ldb DistancePrewarmerLogisticsDB, logger *zap.SugaredLogger, metrics monitoring.Scope, settingsPollInterval time.Duration, settingsService optimizersettings.Service) *DistancePrewarmer {
+	return &DistancePrewarmer{
+		ldb:                  ldb,
+		settingsPollInterval: settingsPollInterval,
+		logger:               logger.Named("distance_prewarmer"),
+		metrics:              metrics,
+		settingsService:      settingsService,
+
+		serviceRegionsSettings: map[int64]optimizersettings.Settings{},
+		runningServiceRegions:  map[int64]bool{},
+	}
+}
+
+func (p *DistancePrewarmer) Start(ctx context.Context) {
+	go func() {
+		for {
+			p.populateServiceRegionsSettings(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+
+			case <-time.After(p.settingsPollInterval):
+				continue
+			}
+		}
+	}()
+}
+
+func (p *DistancePrewarmer) populateServiceRegionsSettings(ctx context.Context) {
+	allSettings, err := p.settingsService.AllSettings(ctx)
+	if err != nil {
+		p.logger.Errorw("Could not get all the settings from the settings-service", zap.Error(err))
+		return
+	}
+
+	serviceRegionsSettings := map[int64]optimizersettings.Settings{}
+	for serviceRegionID, settings := range allSettings.OptimizerRegionSettingsMap {
+		if settings.DistancePrewarmIntervalSec > 0 {
+			serviceRegionsSettings[serviceRegionID] = settings
+		}
+	}
+
+	var newServiceRegionIDs []int64
+	p.mx.Lock()
+	p.serviceRegionsSettings = serviceRegionsSettings
+	for serviceRegionID := range serviceRegionsSettings {
+		if !p.runningServiceRegions[serviceRegionID] {
+			p.runningServiceRegions[serviceRegionID] = true
+			newServiceRegionIDs = append(newServiceRegionIDs, serviceRegionID)
+		}
+	}
+	p.mx.Unlock()
+
+	for _, serviceRegionID := range newServiceRegionIDs {
+		logger := p.logger.With("service_region_id", serviceRegionID)
+		logger.Infow("Adding distance prewarmer")
+
+		metrics := p.metrics.With("", monitoring.Tags{
+			serviceRegionTag: logisticsdb.I64ToA(serviceRegionID),
+		}, nil)
+
+		go p.runServiceRegion(ctx, serviceRegionID, logger, metrics)
+	}
+}
+
+// settingsOrStop returns the settings of an enabled service region, or marks its prewarm goroutine as stopped.
+func (p *DistancePrewarmer) settingsOrStop(serviceRegionID int64) (optimizersettings.Settings, bool) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	settings, ok := p.serviceRegionsSettings[serviceRegionID]
+	if !ok {
+		delete(p.runningServiceRegions, serviceRegionID)
+	}
+	return settings, ok
+}
+
+func (p *DistancePrewarmer) runServiceRegion(ctx context.Context, serviceRegionID int64, logger *zap.SugaredLogger, metrics monitoring.Scope) {
+	for {
+		settings, ok := p.settingsOrStop(serviceRegionID)
+		if !ok {
+			logger.Info("Distance prewarmer not enabled, stopping.")
+			return
+		}
+
+		interval := settings.DistancePrewarmInterval()
+		startTimestamp := time.Now()
+
+		// Each run is bounded by the interval, so that a slow map service does not pile up runs.
+		runCtx, cancel := context.WithTimeout(ctx, interval)
+		result, err := p.prewarmServiceRegion(runCtx, serviceRegionID, settings, startTimestamp)
+		cancel()
+		if err != nil {
+			logger.Errorw("Problem prewarming distances", zap.Error(err))
+		}
+
+		fields := monitoring.Fields{
+			durationMsField: time.Since(startTimestamp).Milliseconds(),
+		}
+		if err != nil {
+			fields[errorField] = err.Error()
+		}
+		if result != nil {
+			fields[locationsField] = result.NumLocations
+			fields[elementsField] = result.NumElements
+			fields[truncatedField] = result.Truncated
+		}
+		metrics.WritePoint(distancePrewarmsMeasurementName, nil, fields)
+
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-time.After(interval):
+			continue
+		}
+	}
+}
+
+func (p *DistancePrewarmer) prewarmServiceRegion(ctx context.Context, serviceRegionID int64, settings optimizersettings.Settings, now time.Time) (*logisticsdb.PrewarmDistancesResult, error) {
+	serviceRegion, err := p.ldb.GetServiceRegionByID(ctx, serviceRegionID)
+	if err != nil {
+		return nil, err
+	}
+
+	tzLoc, err := time.LoadLocation(serviceRegion.IanaTimeZoneName)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.ldb.PrewarmServiceRegionDistances(ctx, logisticsdb.PrewarmDistancesParams{
+		ServiceRegionID: serviceRegionID,
+		ServiceDate:     TimestampToDate(now.In(tzLoc)),
+		SnapshotTime:    now,
+		AfterCreatedAt:  settings.DistancePrewarmAfterCreatedAt(now),
+		MaxElements:     settings.DistancePrewarmMaxElements,
+	})
+}
@@ -0,0 +1,165 @@
+package optimizer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/baselogger"
+	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/optimizer/optimizersettings"
+	"github.com/*company-data-covered*/services/go/pkg/monitoring"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+)
+
+type mockDistancePrewarmerLDB struct {
+	serviceRegion    *logisticssql.ServiceRegion
+	serviceRegionErr error
+	prewarmResult    *logisticsdb.PrewarmDistancesResult
+	prewarmErr       error
+
+	prewarmParams *logisticsdb.PrewarmDistancesParams
+	// serviceRegionIDs receives the service region of each prewarm, if set.
+	serviceRegionIDs chan int64
+}
+
+func (ldb *mockDistancePrewarmerLDB) GetServiceRegionByID(ctx context.Context, serviceRegionID int64) (*logisticssql.ServiceRegion, error) {
+	if ldb.serviceRegionIDs != nil {
+		ldb.serviceRegionIDs <- serviceRegionID
+	}
+	return ldb.serviceRegion, ldb.serviceRegionErr
+}
+
+func (ldb *mockDistancePrewarmerLDB) PrewarmServiceRegionDistances(ctx context.Context, params logisticsdb.PrewarmDistancesParams) (*logisticsdb.PrewarmDistancesResult, error) {
+	ldb.prewarmParams = &params
+	return ldb.prewarmResult, ldb.prewarmErr
+}
+
+func TestDistancePrewarmer_PrewarmServiceRegion(t *testing.T) {
+	serviceRegionID := time.Now().UnixNano()
+	// 2023-09-08 02:00 UTC is still 2023-09-07 in Denver.
+	now := time.Date(2023, time.September, 8, 2, 0, 0, 0, time.UTC)
+	settings := optimizersettings.Settings{
+		DistanceValiditySec:        3600,
+		DistancePrewarmIntervalSec: 600,
+		DistancePrewarmMaxElements: 100,
+	}
+	result := &logisticsdb.PrewarmDistancesResult{
+		NumLocations: 3,
+		NumElements:  9,
+	}
+
+	tcs := []struct {
+		Desc string
+		LDB  *mockDistancePrewarmerLDB
+
+		WantParams *logisticsdb.PrewarmDistancesParams
+		Want       *logisticsdb.PrewarmDistancesResult
+		HasErr     bool
+	}{
+		{
+			Desc: "base case",
+			LDB: &mockDistancePrewarmerLDB{
+				serviceRegion: &logisticssql.ServiceRegion{IanaTimeZoneName: "America/Denver"},
+				prewarmResult: result,
+			},
+
+			WantParams: &logisticsdb.PrewarmDistancesParams{
+				ServiceRegionID: serviceRegionID,
+				ServiceDate:     time.Date(2023, time.September, 7, 0, 0, 0, 0, time.UTC),
+				SnapshotTime:    now,
+				AfterCreatedAt:  now.Add(-50 * time.Minute),
+				MaxElements:     100,
+			},
+			Want: result,
+		},
+		{
+			Desc: "service region error",
+			LDB: &mockDistancePrewarmerLDB{
+				serviceRegionErr: errors.New("no service region"),
+			},
+
+			HasErr: true,
+		},
+		{
+			Desc: "bad time zone",
+			LDB: &mockDistancePrewarmerLDB{
+				serviceRegion: &logisticssql.ServiceRegion{IanaTimeZoneName: "Not/AZone"},
+			},
+
+			HasErr: true,
+		},
+		{
+			Desc: "prewarm error",
+			LDB: &mockDistancePrewarmerLDB{
+				serviceRegion: &logisticssql.ServiceRegion{IanaTimeZoneName: "America/Denver"},
+				prewarmErr:    errors.New("map service down"),
+			},
+
+			WantParams: &logisticsdb.PrewarmDistancesParams{
+				ServiceRegionID: serviceRegionID,
+				ServiceDate:     time.Date(2023, time.September, 7, 0, 0, 0, 0, time.UTC),
+				SnapshotTime:    now,
+				AfterCreatedAt:  now.Add(-50 * time.Minute),
+				MaxElements:     100,
+			},
+			HasErr: true,
+		},
+	}
+
+	logger := baselogger.NewSugaredLogger(baselogger.LoggerOptions{})
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			p := NewDistancePrewarmer(tc.LDB, logger, &monitoring.NoopScope{}, time.Hour, &optimizersettings.MockSettingsService{})
+
+			got, err := p.prewarmServiceRegion(context.Background(), serviceRegionID, settings, now)
+			if (err != nil) != tc.HasErr {
+				t.Fatal(err)
+			}
+			testutils.MustMatch(t, tc.Want, got)
+			testutils.MustMatch(t, tc.WantParams, tc.LDB.prewarmParams)
+		})
+	}
+}
+
+func TestDistancePrewarmer_PopulateServiceRegionsSettings(t *testing.T) {
+	serviceRegionID := time.Now().UnixNano()
+	enabledSettings := &optimizersettings.AllSettings{
+		OptimizerRegionSettingsMap: optimizersettings.RegionSettingsMap{
+			serviceRegionID:     {DistancePrewarmIntervalSec: 3600},
+			serviceRegionID + 1: {},
+		},
+	}
+	disabledSettings := &optimizersettings.AllSettings{
+		OptimizerRegionSettingsMap: optimizersettings.RegionSettingsMap{},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ldb := &mockDistancePrewarmerLDB{
+		serviceRegionErr: errors.New("no service region"),
+		serviceRegionIDs: make(chan int64, 10),
+	}
+	settingsService := &optimizersettings.MockSettingsService{AllSettingsConfigs: enabledSettings}
+	p := NewDistancePrewarmer(ldb, baselogger.NewSugaredLogger(baselogger.LoggerOptions{}), &monitoring.NoopScope{}, time.Hour, settingsService)
+
+	p.populateServiceRegionsSettings(ctx)
+	testutils.MustMatch(t, serviceRegionID, <-ldb.serviceRegionIDs)
+
+	p.populateServiceRegionsSettings(ctx)
+	settingsService.AllSettingsConfigs = disabledSettings
+	p.populateServiceRegionsSettings(ctx)
+	settingsService.AllSettingsConfigs = enabledSettings
+	p.populateServiceRegionsSettings(ctx)
+	p.mx.RLock()
+	testutils.MustMatch(t, map[int64]bool{serviceRegionID: true}, p.runningServiceRegions)
+	p.mx.RUnlock()
+
+	select {
+	case id := <-ldb.serviceRegionIDs:
+		t.Fatalf("service region %d prewarmed by a second goroutine", id)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
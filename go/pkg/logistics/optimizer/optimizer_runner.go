@@ -45,6 +45,9 @@ const (
 	dateLayout = "2006-01-02"
 
 	defaultRestBreakDuration = 30 * time.Minute
+
+	// Termination duration for scoring the previous schedule, whose visits are all pinned.
+	rescoreTerminationDurationMs = 1000
 )
 
 var (
@@ -221,6 +224,7 @@ type RunnerLogisticsDB interface {
 	GetAttributesForNames(ctx context.Context, attrNames []string) ([]*logisticssql.Attribute, error)
 	AddServiceRegionAvailabilityVisitsTransactionally(ctx context.Context, params *logisticsdb.AddServiceRegionAvailabilityVisitsTransactionallyParams) ([]*logisticssql.ServiceRegionAvailabilityVisit, []*logisticssql.ServiceRegionAvailabilityVisitAttribute, error)
 	VisitArrivalTimestampsForSchedule(ctx context.Context, scheduleID int64) (map[int64]time.Time, error)
+	GetLatestScheduleRouteAssignments(ctx context.Context, params logisticsdb.ScheduleRouteAssignmentsParams) (*logisticsdb.ScheduleRouteAssignments, error)
 }
 
 type RunResult struct {
@@ -281,6 +285,7 @@ func NewRunner(ldb RunnerLogisticsDB, optimizerConn *grpc.ClientConn, logger *za
 			OptimizerServiceClient: optimizerpb.NewOptimizerServiceClient(optimizerConn),
 			SolveVRPLogisticsDB:    ldb,
 			RouteProvider:          nil,
+			Scope:                  metrics,
 		},
 		ldb:                  ldb,
 		settingsPollInterval: settingsPollInterval,
@@ -560,6 +565,20 @@ func (s stalenessChecker) ShouldForceRecomputeRegion(lastRun *logisticssql.Optim
 	return lastRun.SnapshotTimestamp.Add(forceRecomputeInterval).Before(s.latestSnapshotTimestamp)
 }
 
+// problemValidationConfig returns the validation config of the problems of optimizer runs.
+func problemValidationConfig(settings optimizersettings.Settings) validation.Config {
+	cfg := validation.Config{
+		// for production; we want to gracefully handle recoverable errors and not fail.
+		FailOnRecoverableError: false,
+		ProblemValidators:      logisticsdb.DefaultProblemValidators,
+	}
+	if settings.ValidationRepairModeEnabled {
+		cfg.RepairMode = true
+		cfg.ProblemValidators = logisticsdb.RepairableProblemValidators
+	}
+	return cfg
+}
+
 func (r *Runner) runRegionWithSettingConfig(ctx context.Context, logger *zap.SugaredLogger, settingConfig *SettingsConfig, serviceDate time.Time, latestSnapshotTimestamp time.Time) (*RunResult, error) {
 	settings := settingConfig.Settings
 
@@ -594,16 +613,21 @@ func (r *Runner) runRegionWithSettingConfig(ctx context.Context, logger *zap.Sug
 	if err != nil {
 		return nil, err
 	}
+	for visitID, zone := range vrpData.BlackedOutVisits {
+		logger.Infow("Visit excluded from schedule by blackout zone",
+			"visit_snapshot_id", visitID,
+			"blackout_zone_id", zone.ID,
+			"blackout_zone_name", zone.Name)
+	}
 
 	problemData, err := r.ldb.CreateVRPProblem(ctx, logisticsdb.VRPProblemParams{
-		ServiceRegionVRPData:       vrpData,
-		UseDistancesAfterTime:      earliestDistanceTimestamp,
-		UnrequestedRestBreakConfig: logisticsdb.UnrequestedRestBreakConfig{IncludeUnrequestedRestBreaks: false},
-		ValidationConfig: validation.Config{
-			// for production; we want to gracefully handle recoverable errors and not fail.
-			FailOnRecoverableError: false,
-			ProblemValidators:      logisticsdb.DefaultProblemValidators,
+		ServiceRegionVRPData:  vrpData,
+		UseDistancesAfterTime: earliestDistanceTimestamp,
+		UnrequestedRestBreakConfig: logisticsdb.UnrequestedRestBreakConfig{
+			IncludeUnrequestedRestBreaks: false,
+			RestBreakPolicy:              settings.RestBreakPolicy,
 		},
+		ValidationConfig: problemValidationConfig(settings),
 	})
 	if err != nil {
 		if errors.Is(err, logisticsdb.ErrEmptyVRPDescription) {
@@ -615,6 +639,20 @@ func (r *Runner) runRegionWithSettingConfig(ctx context.Context, logger *zap.Sug
 
 	problemData.OptimizerRun.OptimizerConfigID = settingConfig.Settings.OptimizerConfigID
 
+	var scheduleStability *ScheduleStability
+	previousSchedule, err := r.ldb.GetLatestScheduleRouteAssignments(ctx, logisticsdb.ScheduleRouteAssignmentsParams{
+		ServiceRegionID: settingConfig.ServiceRegionID,
+		ServiceDate:     serviceDate,
+		CreatedBefore:   latestSnapshotTimestamp,
+	})
+	if err != nil {
+		// Stability is best effort, the new schedule is still written without it.
+		logger.Warnw("Problem getting previous schedule for stability", zap.Error(err))
+	}
+	if previousSchedule != nil {
+		scheduleStability = NewScheduleStability(previousSchedule, problemData, settings.StabilityMinScoreImprovement)
+	}
+
 	problem := problemData.VRPProblem
 	req := &optimizerpb.SolveVRPRequest{
 		Problem: problem,
@@ -640,6 +678,15 @@ func (r *Runner) runRegionWithSettingConfig(ctx context.Context, logger *zap.Sug
 		},
 	}
 
+	if scheduleStability != nil && scheduleStability.MinScoreImprovement != nil {
+		previousScore, err := r.rescorePreviousSchedule(ctx, scheduleStability, req, problemData.OptimizerRun)
+		if err != nil {
+			// Without a comparable score for the previous schedule, all solutions are accepted.
+			logger.Infow("Problem rescoring previous schedule for stability", zap.Error(err))
+		}
+		scheduleStability.PreviousScore = previousScore
+	}
+
 	logger.Debugw("vrp request", zap.String("req", req.String()))
 
 	respChan, err := r.VRPSolver.SolveVRP(ctx, &SolveVRPParams{
@@ -648,6 +695,11 @@ func (r *Runner) runRegionWithSettingConfig(ctx context.Context, logger *zap.Sug
 		OptimizerSettings: &settings,
 		OptimizerRunType:  logisticsdb.ServiceRegionScheduleRunType,
 		WriteToDatabase:   true,
+		ScheduleStability: scheduleStability,
+		MultiDayVisits:    problemData.MultiDayVisits,
+
+		ValidationRepairReport: problemData.ValidationRepairReport,
+		BlackedOutVisits:       problemData.BlackedOutVisits,
 	})
 	if err != nil {
 		return nil, err
@@ -663,6 +715,46 @@ func (r *Runner) runRegionWithSettingConfig(ctx context.Context, logger *zap.Sug
 	return &RunResult{Responses: resps, OriginalDescription: problemData.VRPProblem.GetDescription()}, nil
 }
 
+// rescorePreviousSchedule returns the score of the routes of the previous schedule against the problem of the request,
+// as the score of the previous schedule is for the problem it was solved for.
+func (r *Runner) rescorePreviousSchedule(
+	ctx context.Context,
+	stability *ScheduleStability,
+	req *optimizerpb.SolveVRPRequest,
+	optimizerRun *logisticssql.OptimizerRun,
+) (*optimizerpb.VRPScore, error) {
+	problem, err := stability.PreviousRoutesProblem(req.GetProblem())
+	if err != nil {
+		return nil, err
+	}
+
+	rescoreReq := proto.Clone(req).(*optimizerpb.SolveVRPRequest)
+	rescoreReq.Problem = problem
+	// All visits are pinned, so the solver has nothing to optimize.
+	rescoreReq.Config.TerminationDurationMs = proto.Int64(rescoreTerminationDurationMs)
+	rescoreReq.Config.IncludeIntermediateSolutions = proto.Bool(false)
+	rescoreReq.Monitoring.Tags[SolveVRPUseTag] = SolveVRPUseTagScheduleStability
+
+	respChan, err := r.VRPSolver.SolveVRP(ctx, &SolveVRPParams{
+		SolveVRPRequest: rescoreReq,
+		OptimizerRun:    optimizerRun,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var score *optimizerpb.VRPScore
+	for resp := range respChan {
+		if s := resp.Response.GetSolution().GetScore(); s != nil {
+			score = s
+		}
+	}
+	if score == nil {
+		return nil, errors.New("no solution scoring the previous schedule")
+	}
+	return score, nil
+}
+
 func attrsMap(attrs []*logisticssql.Attribute) map[int64]*logisticssql.Attribute {
 	attrMap := map[int64]*logisticssql.Attribute{}
 	for _, attr := range attrs {
@@ -993,10 +1085,9 @@ func (r *Runner) vrpInputForAvailability(
 		UnrequestedRestBreakConfig: logisticsdb.UnrequestedRestBreakConfig{
 			IncludeUnrequestedRestBreaks: true,
 			RestBreakDuration:            defaultRestBreakDuration,
-		}, ValidationConfig: validation.Config{
-			FailOnRecoverableError: false,
-			ProblemValidators:      logisticsdb.DefaultProblemValidators,
+			RestBreakPolicy:              optimizerSettings.RestBreakPolicy,
 		},
+		ValidationConfig: problemValidationConfig(*optimizerSettings),
 	})
 	if err != nil {
 		if errors.Is(err, logisticsdb.ErrEmptyVRPDescription) {
@@ -1141,6 +1232,8 @@ func (r *Runner) runAvailabilityRegionWithSettingConfig(
 
 		AvailabilityVisitIDMap: availabilityVisitIDMap,
 		UnassignedVisits:       serviceRegionVRPData.PreviousUnassignedVisits,
+		ValidationRepairReport: vrpInput.VRPProblemData.ValidationRepairReport,
+		BlackedOutVisits:       vrpInput.VRPProblemData.BlackedOutVisits,
 	})
 	if err != nil {
 		return nil, err
@@ -12,6 +12,7 @@ import (
 	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
 	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
 	"github.com/*company-data-covered*/services/go/pkg/logistics/optimizer/optimizersettings"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/validation"
 	"github.com/*company-data-covered*/services/go/pkg/monitoring"
 	"github.com/*company-data-covered*/services/go/pkg/testutils"
 	"go.uber.org/zap"
@@ -40,6 +41,10 @@ type mockRunnerLDB struct {
 	visitArrivalTimestampsForScheduleErr         error
 	hasAnyNewScheduleSinceLastAvailabilityRun    bool
 	hasAnyNewScheduleSinceLastAvailabilityRunErr error
+	latestScheduleRouteAssignments               *logisticsdb.ScheduleRouteAssignments
+	latestScheduleRouteAssignmentsErr            error
+	validationRepairReport                       *validation.RepairReport
+	blackedOutVisits                             logisticsdb.BlackedOutVisits
 
 	hasAnyNewInfoInRegionDateSinceLastRunFunc func(context.Context, logisticsdb.HasNewInfoParams) (*logisticsdb.NewRegionInfo, error)
 	GetServiceRegionVRPDataRunFunc            func(context.Context, *logisticsdb.ServiceRegionVRPDataParams) (*logisticsdb.ServiceRegionVRPData, error)
@@ -77,6 +82,20 @@ func (ldb *mockRunnerLDB) AddOptimizerRunError(context.Context, logisticssql.Add
 	return errUnimplemented
 }
 
+func (ldb *mockRunnerLDB) AddOptimizerRunValidationRepairs(ctx context.Context, optimizerRunID int64, report *validation.RepairReport) error {
+	ldb.validationRepairReport = report
+	return nil
+}
+
+func (ldb *mockRunnerLDB) AddOptimizerRunBlackedOutVisits(ctx context.Context, optimizerRunID int64, blackedOut logisticsdb.BlackedOutVisits) error {
+	ldb.blackedOutVisits = blackedOut
+	return nil
+}
+
+func (ldb *mockRunnerLDB) AddScheduleStabilityRejection(ctx context.Context, params logisticssql.AddScheduleStabilityRejectionParams) error {
+	return errUnimplemented
+}
+
 func (ldb *mockRunnerLDB) WriteScheduleForVRPSolution(ctx context.Context, params *logisticsdb.WriteScheduleForVRPSolutionParams) (*logisticssql.Schedule, error) {
 	return nil, errUnimplemented
 }
@@ -124,6 +143,10 @@ func (ldb *mockRunnerLDB) HasAnyNewScheduleSinceLastAvailabilityRun(ctx context.
 	return ldb.hasAnyNewScheduleSinceLastAvailabilityRun, ldb.hasAnyNewScheduleSinceLastAvailabilityRunErr
 }
 
+func (ldb *mockRunnerLDB) GetLatestScheduleRouteAssignments(ctx context.Context, params logisticsdb.ScheduleRouteAssignmentsParams) (*logisticsdb.ScheduleRouteAssignments, error) {
+	return ldb.latestScheduleRouteAssignments, ldb.latestScheduleRouteAssignmentsErr
+}
+
 func TestOptimizerRunner_HorizonDays(t *testing.T) {
 	logger := baselogger.NewSugaredLogger(baselogger.LoggerOptions{})
 
@@ -452,6 +475,80 @@ func TestRunRegionWithSettingsWithOptimizerRunType(t *testing.T) {
 	testutils.MustMatch(t, true, result != nil)
 }
 
+func TestRunRegionWithSettingsRescoresPreviousSchedule(t *testing.T) {
+	lastRun := &logisticssql.OptimizerRun{SnapshotTimestamp: time.Now()}
+	rescoredScore := &optimizerpb.VRPScore{HardScore: proto.Int64(0), SoftScore: proto.Int64(500)}
+	var scheduleStability *ScheduleStability
+	var rescoreProblem *optimizerpb.VRPProblem
+	r := &Runner{
+		ldb: &mockRunnerLDB{
+			hasAnyNewInfoInRegionDateSinceLastRunFunc: func(context.Context, logisticsdb.HasNewInfoParams) (*logisticsdb.NewRegionInfo, error) {
+				return &logisticsdb.NewRegionInfo{HasNewInfo: true, LastRun: lastRun, TZ: time.UTC}, nil
+			},
+			GetServiceRegionVRPDataRunFunc: func(context.Context, *logisticsdb.ServiceRegionVRPDataParams) (*logisticsdb.ServiceRegionVRPData, error) {
+				return &logisticsdb.ServiceRegionVRPData{}, nil
+			},
+			CreateVRPProblemRunFunc: func(context.Context, logisticsdb.VRPProblemParams) (*logisticsdb.VRPProblemData, error) {
+				return &logisticsdb.VRPProblemData{
+					OptimizerRun: &logisticssql.OptimizerRun{},
+					VRPProblem: &optimizerpb.VRPProblem{Description: &optimizerpb.VRPDescription{
+						ShiftTeams: []*optimizerpb.VRPShiftTeam{{Id: proto.Int64(101)}},
+						Visits:     []*optimizerpb.VRPVisit{{Id: proto.Int64(110)}},
+					}},
+					EntityMappings: logisticsdb.EntityMappings{
+						CareRequests: map[logisticsdb.VisitSnapshotID]logisticsdb.CareRequestID{110: 10},
+						ShiftTeams:   map[logisticsdb.ShiftTeamSnapshotID]logisticsdb.ShiftTeamID{101: 1},
+					},
+				}, nil
+			},
+			latestScheduleRouteAssignments: &logisticsdb.ScheduleRouteAssignments{
+				ScheduleID: 1,
+				Score:      &optimizerpb.VRPScore{SoftScore: proto.Int64(1000)},
+				Routes:     map[logisticsdb.ShiftTeamID][]logisticsdb.CareRequestID{1: {10}},
+			},
+		},
+		VRPSolver: &MockVRPSolver{
+			SolveVRPRunFunc: func(ctx context.Context, params *SolveVRPParams) (<-chan *WrappedSolveVRPResp, error) {
+				solveVRPResps := make(chan *WrappedSolveVRPResp, 1)
+				defer close(solveVRPResps)
+
+				resp := &optimizerpb.SolveVRPResponse{}
+				if params.SolveVRPRequest.GetMonitoring().GetTags()[SolveVRPUseTag] == SolveVRPUseTagScheduleStability {
+					rescoreProblem = params.SolveVRPRequest.GetProblem()
+					testutils.MustMatch(t, false, params.WriteToDatabase)
+					resp.Solution = &optimizerpb.VRPSolution{Score: rescoredScore}
+				} else {
+					scheduleStability = params.ScheduleStability
+				}
+				solveVRPResps <- &WrappedSolveVRPResp{Response: resp}
+
+				return solveVRPResps, nil
+			},
+		},
+	}
+	_, err := r.runRegionWithSettingConfig(
+		context.Background(),
+		zap.L().Sugar(),
+		&SettingsConfig{
+			Settings: optimizersettings.Settings{
+				OptimizerTerminationDurationMs: 100,
+				StabilityMinScoreImprovement:   proto.Int64(100),
+			},
+			Config: &logisticssql.OptimizerConfig{},
+		},
+		TimestampToDate(time.Now()),
+		time.Now(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testutils.MustMatchProto(t, &optimizerpb.VRPShiftTeamCommitments{Commitments: []*optimizerpb.VRPShiftTeamCommitment{
+		{VisitId: proto.Int64(110)},
+	}}, rescoreProblem.GetDescription().GetShiftTeams()[0].GetUpcomingCommitments())
+	testutils.MustMatchProto(t, rescoredScore, scheduleStability.PreviousScore)
+}
+
 func TestRunAvailabilityRegionWithSettingsBaseCase(t *testing.T) {
 	visitID := int64(10)
 	locationID := int64(99)
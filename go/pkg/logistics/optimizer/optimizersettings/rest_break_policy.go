@@ -0,0 +1,48 @@
+package optimizersettings
+
+import "time"
+
+// RestBreakPolicy describes the rest breaks that the labor rules of a jurisdiction require of shift teams.
+type RestBreakPolicy struct {
+	// Jurisdiction whose labor rules the policy implements, such as "CA".
+	Jurisdiction string `json:"jurisdiction"`
+
+	// Rules for the required rest breaks, each evaluated independently.
+	Rules []RestBreakRule `json:"rules"`
+}
+
+// RestBreakRule requires a rest break of shifts longer than a minimum duration,
+// such as a meal break before the 5th hour of shifts longer than 5 hours.
+type RestBreakRule struct {
+	// Name of the rule, for diagnostics.
+	Name string `json:"name"`
+
+	// Shifts longer than this duration require the rest break.
+	MinShiftDurationSec int64 `json:"min_shift_duration_sec"`
+
+	// Duration of the rest break.
+	DurationSec int64 `json:"duration_sec"`
+
+	// Earliest start of the rest break, relative to the shift start.
+	EarliestStartOffsetSec int64 `json:"earliest_start_offset_sec"`
+
+	// Latest start of the rest break, relative to the shift start.
+	// 0 allows the rest break to start until it would end at the shift end.
+	LatestStartOffsetSec int64 `json:"latest_start_offset_sec"`
+}
+
+func (r RestBreakRule) MinShiftDuration() time.Duration {
+	return time.Duration(r.MinShiftDurationSec) * time.Second
+}
+
+func (r RestBreakRule) Duration() time.Duration {
+	return time.Duration(r.DurationSec) * time.Second
+}
+
+func (r RestBreakRule) EarliestStartOffset() time.Duration {
+	return time.Duration(r.EarliestStartOffsetSec) * time.Second
+}
+
+func (r RestBreakRule) LatestStartOffset() time.Duration {
+	return time.Duration(r.LatestStartOffsetSec) * time.Second
+}
@@ -68,6 +68,14 @@ type Settings struct {
 	// instead of the default configured map service.
 	UseOSRMMapService bool `json:"use_osrm_map_service"`
 
+	// Use Valhalla to fetch distances for all LogisticsDB + other use cases,
+	// taking precedence over UseOSRMMapService. Ignored if Valhalla is not configured.
+	UseValhallaMapService bool `json:"use_valhalla_map_service"`
+
+	// Fall back to the other configured map services when the region's map service fails,
+	// skipping map services with repeated failures until they recover.
+	UseMapServiceFallback bool `json:"use_map_service_fallback"`
+
 	// Use Google maps to calculate eta when a care request is en route,
 	// instead of the service configured by region.
 	UseGoogleMapsForRealTimeTraffic bool `json:"use_google_maps_for_real_time_traffic"`
@@ -129,6 +137,65 @@ type Settings struct {
 	// Used by time window availability to define the time windows duration in hours.
 	// If nil, the duration will default to 4 hours.
 	AvailabilityTimeWindowDurationHrs *int `json:"availability_time_window_duration_hrs"`
+
+	// Duration of departure time buckets for time dependent distances, covering the service region open hours.
+	// Only used by map services that support departure times.
+	// 0 means to only use time independent distances.
+	DistanceDepartureTimeBucketSec int64 `json:"distance_departure_time_bucket_sec"`
+
+	// Interval between background prewarms of the distances between canonical locations and active shift team locations.
+	// 0 disables distance prewarming for the service region.
+	DistancePrewarmIntervalSec int64 `json:"distance_prewarm_interval_sec"`
+
+	// Maximum number of distance matrix elements to prewarm in each run, to bound map service usage.
+	// 0 means no limit.
+	DistancePrewarmMaxElements int64 `json:"distance_prewarm_max_elements"`
+
+	// Radius around visit locations within which shift team locations are considered at the visit,
+	// for inferring arrivals and departures when Station timestamps are missing or late.
+	// 0 disables geofence detection for the service region.
+	GeofenceRadiusMeters int64 `json:"geofence_radius_meters"`
+
+	// Minimum time a shift team must stay within the geofence of a visit location to infer an arrival.
+	GeofenceMinDwellSec int64 `json:"geofence_min_dwell_sec"`
+
+	// Minimum soft score improvement over the previous schedule for accepting a new schedule
+	// that moves visits adjacent to committed visits to other shift teams.
+	// Nil accepts all new schedules.
+	StabilityMinScoreImprovement *int64 `json:"stability_min_score_improvement"`
+
+	// Schedule visits with arrival time windows of a day or longer, such as follow ups due within a few days,
+	// on a single service date of their window instead of on every service date the window overlaps.
+	// The service date is chosen by the first schedule that assigns the visit.
+	MultiDayVisitSchedulingEnabled bool `json:"multi_day_visit_scheduling_enabled"`
+
+	// Repair invalid problems instead of failing optimizer runs, such as by dropping visits with invalid data
+	// as unassignable. Applied repairs are stored with the optimizer runs.
+	ValidationRepairModeEnabled bool `json:"validation_repair_mode_enabled"`
+
+	// Rest break policy implementing the labor rules of the service region's jurisdiction.
+	// Generates the rest breaks required of each shift team, and flags schedules that violate it.
+	// Nil adds a single unrequested rest break for shift teams without a requested rest break, where enabled.
+	RestBreakPolicy *RestBreakPolicy `json:"rest_break_policy"`
+}
+
+func (s Settings) DistanceDepartureTimeBucketDuration() time.Duration {
+	return time.Duration(s.DistanceDepartureTimeBucketSec) * time.Second
+}
+
+func (s Settings) DistancePrewarmInterval() time.Duration {
+	return time.Duration(s.DistancePrewarmIntervalSec) * time.Second
+}
+
+// DistancePrewarmAfterCreatedAt returns the time after which distances don't need to be prewarmed again at now,
+// as they are still valid until the next prewarm.
+func (s Settings) DistancePrewarmAfterCreatedAt(now time.Time) time.Time {
+	validity := time.Duration(s.DistanceValiditySec) * time.Second
+	if validity > s.DistancePrewarmInterval() {
+		validity -= s.DistancePrewarmInterval()
+	}
+
+	return now.Add(-validity)
 }
 
 func (s Settings) NextPollInterval() time.Duration {
@@ -306,3 +306,35 @@ func TestSnapshotsLookbackDuration(t *testing.T) {
 		})
 	}
 }
+
+func TestDistancePrewarmAfterCreatedAt(t *testing.T) {
+	now := time.Date(2023, time.September, 5, 8, 0, 0, 0, time.UTC)
+	tcs := []struct {
+		Description                string
+		DistanceValiditySec        int64
+		DistancePrewarmIntervalSec int64
+		Expected                   time.Time
+	}{
+		{
+			Description:                "Refreshes distances expiring before the next prewarm",
+			DistanceValiditySec:        3600,
+			DistancePrewarmIntervalSec: 600,
+			Expected:                   now.Add(-50 * time.Minute),
+		},
+		{
+			Description:                "Interval longer than validity",
+			DistanceValiditySec:        600,
+			DistancePrewarmIntervalSec: 3600,
+			Expected:                   now.Add(-10 * time.Minute),
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Description, func(t *testing.T) {
+			s := Settings{
+				DistanceValiditySec:        tc.DistanceValiditySec,
+				DistancePrewarmIntervalSec: tc.DistancePrewarmIntervalSec,
+			}
+			testutils.MustMatch(t, tc.Expected, s.DistancePrewarmAfterCreatedAt(now))
+		})
+	}
+}
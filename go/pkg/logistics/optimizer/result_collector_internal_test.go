@@ -8,6 +8,7 @@ import (
 	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
 	logisticssql "github.com/*company-data-covered*/services/go/pkg/generated/sql/logistics"
 	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+	"github.com/*company-data-covered*/services/go/pkg/monitoring"
 	"github.com/*company-data-covered*/services/go/pkg/testutils"
 	"google.golang.org/protobuf/proto"
 )
@@ -15,6 +16,8 @@ import (
 type mockScheduleResultWriter struct {
 	writeScheduleForVRPSolution func(ctx context.Context, params *logisticsdb.WriteScheduleForVRPSolutionParams) (*logisticssql.Schedule, error)
 	addOptimizerRunError        func(ctx context.Context, params logisticssql.AddOptimizerRunErrorParams) error
+
+	addScheduleStabilityRejection func(ctx context.Context, params logisticssql.AddScheduleStabilityRejectionParams) error
 }
 
 func (s mockScheduleResultWriter) WriteScheduleForVRPSolution(ctx context.Context, params *logisticsdb.WriteScheduleForVRPSolutionParams) (*logisticssql.Schedule, error) {
@@ -25,6 +28,10 @@ func (s mockScheduleResultWriter) AddOptimizerRunError(ctx context.Context, para
 	return s.addOptimizerRunError(ctx, params)
 }
 
+func (s mockScheduleResultWriter) AddScheduleStabilityRejection(ctx context.Context, params logisticssql.AddScheduleStabilityRejectionParams) error {
+	return s.addScheduleStabilityRejection(ctx, params)
+}
+
 func TestResultCollector_shouldCallScheduleResultWriter(t *testing.T) {
 	called := make(chan struct{}, 1)
 
@@ -75,3 +82,99 @@ func TestResultCollector_shouldCallScheduleResultWriter(t *testing.T) {
 		t.Fatal("expected ScheduleResultWriter to be called")
 	}
 }
+
+func TestResultCollector_writeResultScheduleStability(t *testing.T) {
+	previousSchedule := &logisticsdb.ScheduleRouteAssignments{
+		ScheduleID: 5,
+		Score:      &optimizerpb.VRPScore{SoftScore: proto.Int64(900)},
+		Routes: map[logisticsdb.ShiftTeamID][]logisticsdb.CareRequestID{
+			1: {10, 11},
+			2: {20},
+		},
+	}
+	problemData := &logisticsdb.VRPProblemData{
+		VRPProblem: &optimizerpb.VRPProblem{Description: &optimizerpb.VRPDescription{
+			ShiftTeams: []*optimizerpb.VRPShiftTeam{
+				{
+					Id: proto.Int64(101),
+					UpcomingCommitments: &optimizerpb.VRPShiftTeamCommitments{Commitments: []*optimizerpb.VRPShiftTeamCommitment{
+						{VisitId: proto.Int64(110)},
+					}},
+				},
+				{Id: proto.Int64(102)},
+			},
+			Visits: []*optimizerpb.VRPVisit{{Id: proto.Int64(110)}, {Id: proto.Int64(111)}, {Id: proto.Int64(120)}},
+		}},
+		EntityMappings: logisticsdb.EntityMappings{
+			CareRequests: map[logisticsdb.VisitSnapshotID]logisticsdb.CareRequestID{110: 10, 111: 11, 120: 20},
+			ShiftTeams:   map[logisticsdb.ShiftTeamSnapshotID]logisticsdb.ShiftTeamID{101: 1, 102: 2},
+		},
+	}
+	reshuffledSolution := stabilityTestSolution(map[int64][]int64{101: {110}, 102: {120, 111}}, nil, 1050)
+
+	tcs := []struct {
+		Desc                string
+		MinScoreImprovement *int64
+
+		ExpectedWrite     bool
+		ExpectedRejection *logisticssql.AddScheduleStabilityRejectionParams
+	}{
+		{
+			Desc:                "not enough improvement",
+			MinScoreImprovement: proto.Int64(100),
+
+			ExpectedWrite: false,
+			ExpectedRejection: &logisticssql.AddScheduleStabilityRejectionParams{
+				OptimizerRunID:                       7,
+				PreviousScheduleID:                   5,
+				SoftScore:                            1050,
+				SoftScoreImprovement:                 50,
+				MovedCareRequestIds:                  []int64{11},
+				MovedCommittedAdjacentCareRequestIds: []int64{11},
+			},
+		},
+		{
+			Desc:                "enough improvement",
+			MinScoreImprovement: proto.Int64(50),
+
+			ExpectedWrite: true,
+		},
+		{
+			Desc: "no min score improvement",
+
+			ExpectedWrite: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			var written bool
+			var rejection *logisticssql.AddScheduleStabilityRejectionParams
+			stability := NewScheduleStability(previousSchedule, problemData, tc.MinScoreImprovement)
+			stability.PreviousScore = &optimizerpb.VRPScore{SoftScore: proto.Int64(1000)}
+			collector := &ResultCollector{
+				ctx: context.Background(),
+				run: &logisticssql.OptimizerRun{ID: 7, ServiceRegionID: 1},
+				ScheduleResultWriter: mockScheduleResultWriter{
+					writeScheduleForVRPSolution: func(ctx context.Context, params *logisticsdb.WriteScheduleForVRPSolutionParams) (*logisticssql.Schedule, error) {
+						written = true
+						return &logisticssql.Schedule{}, nil
+					},
+					addScheduleStabilityRejection: func(ctx context.Context, params logisticssql.AddScheduleStabilityRejectionParams) error {
+						rejection = &params
+						return nil
+					},
+				},
+				ScheduleStability: stability,
+				scope:             monitoring.NewMockScope(),
+			}
+
+			_, err := collector.writeResult(&optimizerpb.SolveVRPResponse{Solution: reshuffledSolution})
+			if err != nil {
+				t.Fatal(err)
+			}
+			testutils.MustMatch(t, tc.ExpectedWrite, written)
+			testutils.MustMatch(t, tc.ExpectedRejection, rejection)
+		})
+	}
+}
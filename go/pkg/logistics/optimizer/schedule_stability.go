@@ -0,0 +1,327 @@
+package optimizer
+
+import (
+	"fmt"
+	"sort"
+
+	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+	"github.com/*company-data-covered*/services/go/pkg/monitoring"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	scheduleDiffMeasurementName = "schedule_diff"
+
+	acceptedField                     = "accepted"
+	movedVisitsField                  = "moved_visits"
+	movedCommittedAdjacentVisitsField = "moved_committed_adjacent_visits"
+	newlyAssignedVisitsField          = "newly_assigned_visits"
+	newlyUnassignedVisitsField        = "newly_unassigned_visits"
+	visitSetChangedField              = "visit_set_changed"
+	softScoreImprovementField         = "soft_score_improvement"
+)
+
+// ScheduleDiff is the difference between the routes of a VRP solution and the previous schedule.
+type ScheduleDiff struct {
+	// Care requests assigned to a different shift team than in the previous schedule.
+	MovedCareRequestIDs []logisticsdb.CareRequestID
+	// Moved care requests that were next to a committed visit in their previous route.
+	MovedCommittedAdjacentCareRequestIDs []logisticsdb.CareRequestID
+
+	NewlyAssignedCareRequestIDs   []logisticsdb.CareRequestID
+	NewlyUnassignedCareRequestIDs []logisticsdb.CareRequestID
+
+	// Whether the visits of the problem differ from the visits of the previous schedule.
+	VisitSetChanged bool
+
+	// Score improvements over the previous schedule rescored against the problem; positive values are improvements.
+	HardScoreImprovement             int64
+	UnassignedVisitsScoreImprovement int64
+	SoftScoreImprovement             int64
+}
+
+// ScheduleStability compares VRP solutions with the previous schedule of the service region date,
+// to avoid reshuffling visits between shift teams for small score improvements.
+type ScheduleStability struct {
+	PreviousSchedule *logisticsdb.ScheduleRouteAssignments
+	// Score of the routes of the previous schedule against the problem, as the score of the previous schedule
+	// is for the problem it was solved for. Nil accepts all solutions.
+	PreviousScore  *optimizerpb.VRPScore
+	EntityMappings logisticsdb.EntityMappings
+	// Care requests committed to or already served by a shift team.
+	CommittedCareRequestIDs map[logisticsdb.CareRequestID]bool
+	// Care requests of the visits of the problem.
+	ProblemCareRequestIDs map[logisticsdb.CareRequestID]bool
+
+	// Minimum soft score improvement for accepting a solution that moves committed adjacent visits.
+	// Nil accepts all solutions.
+	MinScoreImprovement *int64
+}
+
+// NewScheduleStability returns the ScheduleStability for solutions of the problem.
+func NewScheduleStability(
+	previousSchedule *logisticsdb.ScheduleRouteAssignments,
+	problemData *logisticsdb.VRPProblemData,
+	minScoreImprovement *int64,
+) *ScheduleStability {
+	problemCareRequestIDs := map[logisticsdb.CareRequestID]bool{}
+	for _, visit := range problemData.VRPProblem.GetDescription().GetVisits() {
+		if careRequestID, ok := problemData.EntityMappings.CareRequests[logisticsdb.VisitSnapshotID(visit.GetId())]; ok {
+			problemCareRequestIDs[careRequestID] = true
+		}
+	}
+
+	committed := map[logisticsdb.CareRequestID]bool{}
+	for _, shiftTeam := range problemData.VRPProblem.GetDescription().GetShiftTeams() {
+		for _, commitment := range shiftTeam.GetUpcomingCommitments().GetCommitments() {
+			if careRequestID, ok := problemData.EntityMappings.CareRequests[logisticsdb.VisitSnapshotID(commitment.GetVisitId())]; ok {
+				committed[careRequestID] = true
+			}
+		}
+		for _, stop := range shiftTeam.GetRouteHistory().GetStops() {
+			if visit := stop.GetVisit(); visit != nil {
+				if careRequestID, ok := problemData.EntityMappings.CareRequests[logisticsdb.VisitSnapshotID(visit.GetVisitId())]; ok {
+					committed[careRequestID] = true
+				}
+			}
+		}
+	}
+
+	return &ScheduleStability{
+		PreviousSchedule:        previousSchedule,
+		EntityMappings:          problemData.EntityMappings,
+		CommittedCareRequestIDs: committed,
+		ProblemCareRequestIDs:   problemCareRequestIDs,
+		MinScoreImprovement:     minScoreImprovement,
+	}
+}
+
+// PreviousRoutesProblem returns the problem with the routes of the previous schedule pinned as upcoming commitments,
+// and the other visits pinned as unassigned, for the optimizer to score the previous routes against the problem.
+func (s *ScheduleStability) PreviousRoutesProblem(problem *optimizerpb.VRPProblem) (*optimizerpb.VRPProblem, error) {
+	visitIDs := map[logisticsdb.CareRequestID]int64{}
+	for _, visit := range problem.GetDescription().GetVisits() {
+		if careRequestID, ok := s.EntityMappings.CareRequests[logisticsdb.VisitSnapshotID(visit.GetId())]; ok {
+			visitIDs[careRequestID] = visit.GetId()
+		}
+	}
+
+	res := proto.Clone(problem).(*optimizerpb.VRPProblem)
+	servedByShiftTeamIDs := map[int64]logisticsdb.ShiftTeamID{}
+	for _, shiftTeam := range res.GetDescription().GetShiftTeams() {
+		shiftTeamID, ok := s.EntityMappings.ShiftTeams[logisticsdb.ShiftTeamSnapshotID(shiftTeam.GetId())]
+		if !ok {
+			return nil, fmt.Errorf("unknown shift team snapshot(%d)", shiftTeam.GetId())
+		}
+		for _, stop := range shiftTeam.GetRouteHistory().GetStops() {
+			if visit := stop.GetVisit(); visit != nil {
+				servedByShiftTeamIDs[visit.GetVisitId()] = shiftTeamID
+			}
+		}
+	}
+
+	assigned := map[int64]bool{}
+	for _, shiftTeam := range res.GetDescription().GetShiftTeams() {
+		shiftTeamID := s.EntityMappings.ShiftTeams[logisticsdb.ShiftTeamSnapshotID(shiftTeam.GetId())]
+		var commitments []*optimizerpb.VRPShiftTeamCommitment
+		for _, careRequestID := range s.PreviousSchedule.Routes[shiftTeamID] {
+			visitID, ok := visitIDs[careRequestID]
+			if !ok {
+				return nil, fmt.Errorf("care request(%d) of the previous schedule is not in the problem", careRequestID)
+			}
+			assigned[visitID] = true
+
+			// Served visits are already pinned by the route history.
+			servedByShiftTeamID, served := servedByShiftTeamIDs[visitID]
+			if served && servedByShiftTeamID != shiftTeamID {
+				return nil, fmt.Errorf("care request(%d) of the previous schedule was served by another shift team", careRequestID)
+			}
+			if served {
+				continue
+			}
+			commitments = append(commitments, &optimizerpb.VRPShiftTeamCommitment{VisitId: proto.Int64(visitID)})
+		}
+		shiftTeam.UpcomingCommitments = &optimizerpb.VRPShiftTeamCommitments{Commitments: commitments}
+	}
+	for visitID, shiftTeamID := range servedByShiftTeamIDs {
+		if !assigned[visitID] {
+			return nil, fmt.Errorf("visit(%d) served by shift team(%d) is not in the previous schedule", visitID, shiftTeamID)
+		}
+	}
+
+	var unassignedVisits []*optimizerpb.VRPUnassignedVisit
+	for _, visit := range res.GetDescription().GetVisits() {
+		if assigned[visit.GetId()] {
+			continue
+		}
+		unassignedVisits = append(unassignedVisits, &optimizerpb.VRPUnassignedVisit{
+			VisitId: proto.Int64(visit.GetId()),
+			Pinned:  proto.Bool(true),
+		})
+	}
+	res.Description.UnassignedVisits = unassignedVisits
+
+	return res, nil
+}
+
+func (s *ScheduleStability) solutionRoutes(solution *optimizerpb.VRPSolution) map[logisticsdb.CareRequestID]logisticsdb.ShiftTeamID {
+	res := map[logisticsdb.CareRequestID]logisticsdb.ShiftTeamID{}
+	for _, shiftTeam := range solution.GetDescription().GetShiftTeams() {
+		shiftTeamID, ok := s.EntityMappings.ShiftTeams[logisticsdb.ShiftTeamSnapshotID(shiftTeam.GetId())]
+		if !ok {
+			continue
+		}
+		for _, stop := range shiftTeam.GetRoute().GetStops() {
+			visit := stop.GetVisit()
+			if visit == nil {
+				continue
+			}
+			if careRequestID, ok := s.EntityMappings.CareRequests[logisticsdb.VisitSnapshotID(visit.GetVisitId())]; ok {
+				res[careRequestID] = shiftTeamID
+			}
+		}
+	}
+	return res
+}
+
+func (s *ScheduleStability) committedAdjacentCareRequestIDs() map[logisticsdb.CareRequestID]bool {
+	res := map[logisticsdb.CareRequestID]bool{}
+	for _, route := range s.PreviousSchedule.Routes {
+		for i, careRequestID := range route {
+			if s.CommittedCareRequestIDs[careRequestID] {
+				continue
+			}
+			if (i > 0 && s.CommittedCareRequestIDs[route[i-1]]) ||
+				(i < len(route)-1 && s.CommittedCareRequestIDs[route[i+1]]) {
+				res[careRequestID] = true
+			}
+		}
+	}
+	return res
+}
+
+func sortedCareRequestIDs(ids []logisticsdb.CareRequestID) []logisticsdb.CareRequestID {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// Diff returns the difference between the routes of the solution and the previous schedule.
+func (s *ScheduleStability) Diff(solution *optimizerpb.VRPSolution) *ScheduleDiff {
+	previousShiftTeamIDs := map[logisticsdb.CareRequestID]logisticsdb.ShiftTeamID{}
+	for shiftTeamID, route := range s.PreviousSchedule.Routes {
+		for _, careRequestID := range route {
+			previousShiftTeamIDs[careRequestID] = shiftTeamID
+		}
+	}
+	shiftTeamIDs := s.solutionRoutes(solution)
+	committedAdjacent := s.committedAdjacentCareRequestIDs()
+
+	diff := &ScheduleDiff{}
+	for careRequestID, shiftTeamID := range shiftTeamIDs {
+		previousShiftTeamID, ok := previousShiftTeamIDs[careRequestID]
+		if !ok {
+			diff.NewlyAssignedCareRequestIDs = append(diff.NewlyAssignedCareRequestIDs, careRequestID)
+			continue
+		}
+		if previousShiftTeamID == shiftTeamID {
+			continue
+		}
+		diff.MovedCareRequestIDs = append(diff.MovedCareRequestIDs, careRequestID)
+		if committedAdjacent[careRequestID] {
+			diff.MovedCommittedAdjacentCareRequestIDs = append(diff.MovedCommittedAdjacentCareRequestIDs, careRequestID)
+		}
+	}
+	for _, unassignedVisit := range solution.GetDescription().GetUnassignedVisits() {
+		careRequestID, ok := s.EntityMappings.CareRequests[logisticsdb.VisitSnapshotID(unassignedVisit.GetVisitId())]
+		if !ok {
+			continue
+		}
+		if _, ok := previousShiftTeamIDs[careRequestID]; ok {
+			diff.NewlyUnassignedCareRequestIDs = append(diff.NewlyUnassignedCareRequestIDs, careRequestID)
+		}
+	}
+	sortedCareRequestIDs(diff.MovedCareRequestIDs)
+	sortedCareRequestIDs(diff.MovedCommittedAdjacentCareRequestIDs)
+	sortedCareRequestIDs(diff.NewlyAssignedCareRequestIDs)
+	sortedCareRequestIDs(diff.NewlyUnassignedCareRequestIDs)
+	diff.VisitSetChanged = s.visitSetChanged()
+
+	if s.PreviousScore == nil {
+		return diff
+	}
+	score := solution.GetScore()
+	previousScore := s.PreviousScore
+	diff.HardScoreImprovement = score.GetHardScore() - previousScore.GetHardScore()
+	diff.UnassignedVisitsScoreImprovement = score.GetUnassignedVisitsScore() - previousScore.GetUnassignedVisitsScore()
+	diff.SoftScoreImprovement = score.GetSoftScore() - previousScore.GetSoftScore()
+
+	return diff
+}
+
+// visitSetChanged returns whether the care requests of the problem differ from the care requests
+// of the previous schedule.
+func (s *ScheduleStability) visitSetChanged() bool {
+	previous := map[logisticsdb.CareRequestID]bool{}
+	for _, route := range s.PreviousSchedule.Routes {
+		for _, careRequestID := range route {
+			previous[careRequestID] = true
+		}
+	}
+	for _, careRequestID := range s.PreviousSchedule.UnassignedCareRequestIDs {
+		previous[careRequestID] = true
+	}
+
+	if len(previous) != len(s.ProblemCareRequestIDs) {
+		return true
+	}
+	for careRequestID := range previous {
+		if !s.ProblemCareRequestIDs[careRequestID] {
+			return true
+		}
+	}
+	return false
+}
+
+// Accept returns whether a solution with the diff should replace the previous schedule.
+//
+// Solutions that move visits adjacent to committed visits are only accepted if they improve
+// the hard or unassigned visits scores, or improve the soft score by at least MinScoreImprovement,
+// over the previous schedule rescored against the problem. Solutions for a changed set of visits,
+// or that assign new visits, are always accepted, as the previous schedule is outdated.
+func (s *ScheduleStability) Accept(diff *ScheduleDiff) bool {
+	if s.MinScoreImprovement == nil || len(diff.MovedCommittedAdjacentCareRequestIDs) == 0 {
+		return true
+	}
+	if diff.VisitSetChanged || len(diff.NewlyAssignedCareRequestIDs) > 0 {
+		return true
+	}
+	if s.PreviousScore == nil {
+		return true
+	}
+	if diff.HardScoreImprovement > 0 || diff.UnassignedVisitsScoreImprovement > 0 {
+		return true
+	}
+
+	return diff.SoftScoreImprovement >= *s.MinScoreImprovement
+}
+
+func (d *ScheduleDiff) fields(accepted bool) monitoring.Fields {
+	return monitoring.Fields{
+		acceptedField:                     accepted,
+		movedVisitsField:                  len(d.MovedCareRequestIDs),
+		movedCommittedAdjacentVisitsField: len(d.MovedCommittedAdjacentCareRequestIDs),
+		newlyAssignedVisitsField:          len(d.NewlyAssignedCareRequestIDs),
+		newlyUnassignedVisitsField:        len(d.NewlyUnassignedCareRequestIDs),
+		visitSetChangedField:              d.VisitSetChanged,
+		softScoreImprovementField:         d.SoftScoreImprovement,
+	}
+}
+
+func careRequestIDsToInt64s(ids []logisticsdb.CareRequestID) []int64 {
+	res := make([]int64, len(ids))
+	for i, id := range ids {
+		res[i] = int64(id)
+	}
+	return res
+}
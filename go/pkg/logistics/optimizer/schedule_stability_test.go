@@ -0,0 +1,369 @@
+package optimizer
+
+import (
+	"testing"
+
+	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"google.golang.org/protobuf/proto"
+)
+
+func stabilityTestSolution(routes map[int64][]int64, unassignedVisitIDs []int64, softScore int64) *optimizerpb.VRPSolution {
+	var shiftTeams []*optimizerpb.VRPShiftTeam
+	for shiftTeamSnapshotID, visitIDs := range routes {
+		var stops []*optimizerpb.VRPShiftTeamRouteStop
+		for _, visitID := range visitIDs {
+			stops = append(stops, &optimizerpb.VRPShiftTeamRouteStop{
+				Stop: &optimizerpb.VRPShiftTeamRouteStop_Visit{Visit: &optimizerpb.VRPShiftTeamVisit{VisitId: proto.Int64(visitID)}},
+			})
+		}
+		shiftTeams = append(shiftTeams, &optimizerpb.VRPShiftTeam{
+			Id:    proto.Int64(shiftTeamSnapshotID),
+			Route: &optimizerpb.VRPShiftTeamRoute{Stops: stops},
+		})
+	}
+	var unassignedVisits []*optimizerpb.VRPUnassignedVisit
+	for _, visitID := range unassignedVisitIDs {
+		unassignedVisits = append(unassignedVisits, &optimizerpb.VRPUnassignedVisit{VisitId: proto.Int64(visitID)})
+	}
+
+	return &optimizerpb.VRPSolution{
+		Description: &optimizerpb.VRPDescription{ShiftTeams: shiftTeams, UnassignedVisits: unassignedVisits},
+		Score:       &optimizerpb.VRPScore{HardScore: proto.Int64(0), SoftScore: proto.Int64(softScore)},
+	}
+}
+
+func stabilityTestVisits(visitIDs ...int64) []*optimizerpb.VRPVisit {
+	visits := make([]*optimizerpb.VRPVisit, len(visitIDs))
+	for i, visitID := range visitIDs {
+		visits[i] = &optimizerpb.VRPVisit{Id: proto.Int64(visitID)}
+	}
+	return visits
+}
+
+func TestScheduleStability(t *testing.T) {
+	// Snapshot IDs are the station IDs + 100, for readability.
+	problemData := &logisticsdb.VRPProblemData{
+		VRPProblem: &optimizerpb.VRPProblem{Description: &optimizerpb.VRPDescription{
+			ShiftTeams: []*optimizerpb.VRPShiftTeam{
+				{
+					Id: proto.Int64(101),
+					RouteHistory: &optimizerpb.VRPShiftTeamRouteHistory{Stops: []*optimizerpb.VRPShiftTeamRouteStop{
+						{Stop: &optimizerpb.VRPShiftTeamRouteStop_Visit{Visit: &optimizerpb.VRPShiftTeamVisit{VisitId: proto.Int64(110)}}},
+					}},
+				},
+				{
+					Id: proto.Int64(102),
+					UpcomingCommitments: &optimizerpb.VRPShiftTeamCommitments{Commitments: []*optimizerpb.VRPShiftTeamCommitment{
+						{VisitId: proto.Int64(120)},
+					}},
+				},
+			},
+			Visits: stabilityTestVisits(110, 111, 112, 120, 121, 122, 130),
+		}},
+		EntityMappings: logisticsdb.EntityMappings{
+			CareRequests: map[logisticsdb.VisitSnapshotID]logisticsdb.CareRequestID{
+				110: 10, 111: 11, 112: 12, 120: 20, 121: 21, 122: 22, 130: 30,
+			},
+			ShiftTeams: map[logisticsdb.ShiftTeamSnapshotID]logisticsdb.ShiftTeamID{
+				101: 1, 102: 2,
+			},
+		},
+	}
+	previousSchedule := &logisticsdb.ScheduleRouteAssignments{
+		ScheduleID: 1,
+		Score:      &optimizerpb.VRPScore{HardScore: proto.Int64(0), SoftScore: proto.Int64(1000)},
+		Routes: map[logisticsdb.ShiftTeamID][]logisticsdb.CareRequestID{
+			1: {10, 11, 12},
+			2: {20, 21, 22},
+		},
+		UnassignedCareRequestIDs: []logisticsdb.CareRequestID{30},
+	}
+
+	tcs := []struct {
+		Desc                string
+		Solution            *optimizerpb.VRPSolution
+		MinScoreImprovement *int64
+
+		ExpectedDiff     *ScheduleDiff
+		ExpectedAccepted bool
+	}{
+		{
+			Desc:                "same routes",
+			Solution:            stabilityTestSolution(map[int64][]int64{101: {110, 111, 112}, 102: {120, 121, 122}}, nil, 1000),
+			MinScoreImprovement: proto.Int64(100),
+
+			ExpectedDiff:     &ScheduleDiff{},
+			ExpectedAccepted: true,
+		},
+		{
+			Desc:                "moved visit not adjacent to committed visits",
+			Solution:            stabilityTestSolution(map[int64][]int64{101: {110, 111}, 102: {120, 121, 122, 112}}, nil, 1010),
+			MinScoreImprovement: proto.Int64(100),
+
+			ExpectedDiff: &ScheduleDiff{
+				MovedCareRequestIDs:  []logisticsdb.CareRequestID{12},
+				SoftScoreImprovement: 10,
+			},
+			ExpectedAccepted: true,
+		},
+		{
+			Desc:                "moved committed adjacent visit without enough improvement",
+			Solution:            stabilityTestSolution(map[int64][]int64{101: {110, 112, 121}, 102: {120, 111, 122}}, []int64{130}, 1050),
+			MinScoreImprovement: proto.Int64(100),
+
+			ExpectedDiff: &ScheduleDiff{
+				MovedCareRequestIDs:                  []logisticsdb.CareRequestID{11, 21},
+				MovedCommittedAdjacentCareRequestIDs: []logisticsdb.CareRequestID{11, 21},
+				SoftScoreImprovement:                 50,
+			},
+			ExpectedAccepted: false,
+		},
+		{
+			Desc:                "moved committed adjacent visit without enough improvement, assigning a new visit",
+			Solution:            stabilityTestSolution(map[int64][]int64{101: {110, 112, 121}, 102: {120, 111, 122, 130}}, nil, 1050),
+			MinScoreImprovement: proto.Int64(100),
+
+			ExpectedDiff: &ScheduleDiff{
+				MovedCareRequestIDs:                  []logisticsdb.CareRequestID{11, 21},
+				MovedCommittedAdjacentCareRequestIDs: []logisticsdb.CareRequestID{11, 21},
+				NewlyAssignedCareRequestIDs:          []logisticsdb.CareRequestID{30},
+				SoftScoreImprovement:                 50,
+			},
+			ExpectedAccepted: true,
+		},
+		{
+			Desc:                "moved committed adjacent visit with enough improvement",
+			Solution:            stabilityTestSolution(map[int64][]int64{101: {110, 112, 121}, 102: {120, 111, 122}}, nil, 1100),
+			MinScoreImprovement: proto.Int64(100),
+
+			ExpectedDiff: &ScheduleDiff{
+				MovedCareRequestIDs:                  []logisticsdb.CareRequestID{11, 21},
+				MovedCommittedAdjacentCareRequestIDs: []logisticsdb.CareRequestID{11, 21},
+				SoftScoreImprovement:                 100,
+			},
+			ExpectedAccepted: true,
+		},
+		{
+			Desc:     "moved committed adjacent visit without min score improvement",
+			Solution: stabilityTestSolution(map[int64][]int64{101: {110, 112}, 102: {120, 111, 122}}, []int64{121}, 900),
+
+			ExpectedDiff: &ScheduleDiff{
+				MovedCareRequestIDs:                  []logisticsdb.CareRequestID{11},
+				MovedCommittedAdjacentCareRequestIDs: []logisticsdb.CareRequestID{11},
+				NewlyUnassignedCareRequestIDs:        []logisticsdb.CareRequestID{21},
+				SoftScoreImprovement:                 -100,
+			},
+			ExpectedAccepted: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			stability := NewScheduleStability(previousSchedule, problemData, tc.MinScoreImprovement)
+			stability.PreviousScore = previousSchedule.Score
+
+			diff := stability.Diff(tc.Solution)
+			testutils.MustMatch(t, tc.ExpectedDiff, diff)
+			testutils.MustMatch(t, tc.ExpectedAccepted, stability.Accept(diff))
+		})
+	}
+}
+
+func TestScheduleStability_AcceptHardScoreImprovement(t *testing.T) {
+	stability := &ScheduleStability{MinScoreImprovement: proto.Int64(100), PreviousScore: &optimizerpb.VRPScore{}}
+
+	testutils.MustMatch(t, true, stability.Accept(&ScheduleDiff{
+		MovedCommittedAdjacentCareRequestIDs: []logisticsdb.CareRequestID{1},
+		HardScoreImprovement:                 1,
+	}))
+	testutils.MustMatch(t, true, stability.Accept(&ScheduleDiff{
+		MovedCommittedAdjacentCareRequestIDs: []logisticsdb.CareRequestID{1},
+		UnassignedVisitsScoreImprovement:     1,
+	}))
+	testutils.MustMatch(t, false, stability.Accept(&ScheduleDiff{
+		MovedCommittedAdjacentCareRequestIDs: []logisticsdb.CareRequestID{1},
+		SoftScoreImprovement:                 99,
+	}))
+}
+
+func TestScheduleStability_AcceptOutdatedPreviousSchedule(t *testing.T) {
+	problemData := &logisticsdb.VRPProblemData{
+		VRPProblem: &optimizerpb.VRPProblem{Description: &optimizerpb.VRPDescription{
+			ShiftTeams: []*optimizerpb.VRPShiftTeam{
+				{
+					Id: proto.Int64(101),
+					UpcomingCommitments: &optimizerpb.VRPShiftTeamCommitments{Commitments: []*optimizerpb.VRPShiftTeamCommitment{
+						{VisitId: proto.Int64(110)},
+					}},
+				},
+				{Id: proto.Int64(102)},
+			},
+			Visits: stabilityTestVisits(110, 111, 120),
+		}},
+		EntityMappings: logisticsdb.EntityMappings{
+			CareRequests: map[logisticsdb.VisitSnapshotID]logisticsdb.CareRequestID{110: 10, 111: 11, 120: 20},
+			ShiftTeams:   map[logisticsdb.ShiftTeamSnapshotID]logisticsdb.ShiftTeamID{101: 1, 102: 2},
+		},
+	}
+	reshuffledSolution := stabilityTestSolution(map[int64][]int64{101: {110}, 102: {120, 111}}, nil, 1000)
+
+	tcs := []struct {
+		Desc             string
+		PreviousSchedule *logisticsdb.ScheduleRouteAssignments
+		PreviousScore    *optimizerpb.VRPScore
+
+		ExpectedVisitSetChanged bool
+		ExpectedAccepted        bool
+	}{
+		{
+			Desc: "same visits",
+			PreviousSchedule: &logisticsdb.ScheduleRouteAssignments{
+				Routes: map[logisticsdb.ShiftTeamID][]logisticsdb.CareRequestID{1: {10, 11}, 2: {20}},
+			},
+			PreviousScore: &optimizerpb.VRPScore{SoftScore: proto.Int64(1000)},
+
+			ExpectedAccepted: false,
+		},
+		{
+			Desc: "removed visit",
+			PreviousSchedule: &logisticsdb.ScheduleRouteAssignments{
+				Routes:                   map[logisticsdb.ShiftTeamID][]logisticsdb.CareRequestID{1: {10, 11}, 2: {20}},
+				UnassignedCareRequestIDs: []logisticsdb.CareRequestID{30},
+			},
+			PreviousScore: &optimizerpb.VRPScore{SoftScore: proto.Int64(1000)},
+
+			ExpectedVisitSetChanged: true,
+			ExpectedAccepted:        true,
+		},
+		{
+			Desc: "added visit",
+			PreviousSchedule: &logisticsdb.ScheduleRouteAssignments{
+				Routes: map[logisticsdb.ShiftTeamID][]logisticsdb.CareRequestID{1: {10, 11}},
+			},
+			PreviousScore: &optimizerpb.VRPScore{SoftScore: proto.Int64(1000)},
+
+			ExpectedVisitSetChanged: true,
+			ExpectedAccepted:        true,
+		},
+		{
+			Desc: "previous schedule not rescored",
+			PreviousSchedule: &logisticsdb.ScheduleRouteAssignments{
+				Routes: map[logisticsdb.ShiftTeamID][]logisticsdb.CareRequestID{1: {10, 11}, 2: {20}},
+				Score:  &optimizerpb.VRPScore{SoftScore: proto.Int64(1000)},
+			},
+
+			ExpectedAccepted: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			stability := NewScheduleStability(tc.PreviousSchedule, problemData, proto.Int64(100))
+			stability.PreviousScore = tc.PreviousScore
+
+			diff := stability.Diff(reshuffledSolution)
+			testutils.MustMatch(t, tc.ExpectedVisitSetChanged, diff.VisitSetChanged)
+			testutils.MustMatch(t, tc.ExpectedAccepted, stability.Accept(diff))
+		})
+	}
+}
+
+func TestScheduleStability_PreviousRoutesProblem(t *testing.T) {
+	visitStop := func(visitID int64) *optimizerpb.VRPShiftTeamRouteStop {
+		return &optimizerpb.VRPShiftTeamRouteStop{
+			Stop: &optimizerpb.VRPShiftTeamRouteStop_Visit{Visit: &optimizerpb.VRPShiftTeamVisit{VisitId: proto.Int64(visitID)}},
+		}
+	}
+	commitments := func(visitIDs ...int64) *optimizerpb.VRPShiftTeamCommitments {
+		res := &optimizerpb.VRPShiftTeamCommitments{}
+		for _, visitID := range visitIDs {
+			res.Commitments = append(res.Commitments, &optimizerpb.VRPShiftTeamCommitment{VisitId: proto.Int64(visitID)})
+		}
+		return res
+	}
+	problem := &optimizerpb.VRPProblem{Description: &optimizerpb.VRPDescription{
+		ShiftTeams: []*optimizerpb.VRPShiftTeam{
+			{
+				Id:                  proto.Int64(101),
+				RouteHistory:        &optimizerpb.VRPShiftTeamRouteHistory{Stops: []*optimizerpb.VRPShiftTeamRouteStop{visitStop(110)}},
+				UpcomingCommitments: commitments(111),
+			},
+			{
+				Id:                  proto.Int64(102),
+				UpcomingCommitments: commitments(),
+			},
+		},
+		Visits:           stabilityTestVisits(110, 111, 112, 120, 130),
+		UnassignedVisits: []*optimizerpb.VRPUnassignedVisit{{VisitId: proto.Int64(120)}},
+	}}
+	entityMappings := logisticsdb.EntityMappings{
+		CareRequests: map[logisticsdb.VisitSnapshotID]logisticsdb.CareRequestID{110: 10, 111: 11, 112: 12, 120: 20, 130: 30},
+		ShiftTeams:   map[logisticsdb.ShiftTeamSnapshotID]logisticsdb.ShiftTeamID{101: 1, 102: 2},
+	}
+
+	tcs := []struct {
+		Desc   string
+		Routes map[logisticsdb.ShiftTeamID][]logisticsdb.CareRequestID
+
+		ExpectedProblem *optimizerpb.VRPProblem
+		HasErr          bool
+	}{
+		{
+			Desc:   "previous routes pinned",
+			Routes: map[logisticsdb.ShiftTeamID][]logisticsdb.CareRequestID{1: {10, 12, 11}, 2: {20}},
+
+			ExpectedProblem: &optimizerpb.VRPProblem{Description: &optimizerpb.VRPDescription{
+				ShiftTeams: []*optimizerpb.VRPShiftTeam{
+					{
+						Id:                  proto.Int64(101),
+						RouteHistory:        &optimizerpb.VRPShiftTeamRouteHistory{Stops: []*optimizerpb.VRPShiftTeamRouteStop{visitStop(110)}},
+						UpcomingCommitments: commitments(112, 111),
+					},
+					{
+						Id:                  proto.Int64(102),
+						UpcomingCommitments: commitments(120),
+					},
+				},
+				Visits: stabilityTestVisits(110, 111, 112, 120, 130),
+				UnassignedVisits: []*optimizerpb.VRPUnassignedVisit{
+					{VisitId: proto.Int64(130), Pinned: proto.Bool(true)},
+				},
+			}},
+		},
+		{
+			Desc:   "previous care request not in the problem",
+			Routes: map[logisticsdb.ShiftTeamID][]logisticsdb.CareRequestID{1: {10, 11, 40}},
+
+			HasErr: true,
+		},
+		{
+			Desc:   "served visit in another previous route",
+			Routes: map[logisticsdb.ShiftTeamID][]logisticsdb.CareRequestID{1: {11}, 2: {10}},
+
+			HasErr: true,
+		},
+		{
+			Desc:   "served visit not in the previous routes",
+			Routes: map[logisticsdb.ShiftTeamID][]logisticsdb.CareRequestID{1: {11}},
+
+			HasErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			stability := &ScheduleStability{
+				PreviousSchedule: &logisticsdb.ScheduleRouteAssignments{Routes: tc.Routes},
+				EntityMappings:   entityMappings,
+			}
+
+			got, err := stability.PreviousRoutesProblem(problem)
+			if (err != nil) != tc.HasErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			testutils.MustMatchProto(t, tc.ExpectedProblem, got)
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/*company-data-covered*/services/go/pkg/logistics"
 	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
 	"github.com/*company-data-covered*/services/go/pkg/logistics/optimizer/optimizersettings"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/validation"
 	"github.com/*company-data-covered*/services/go/pkg/monitoring"
 )
 
@@ -22,10 +23,11 @@ const (
 	logisticsRunErrorSourceID = 1
 	optimizerRunErrorSourceID = 2
 
-	SolveVRPUseTag             = "use"
-	SolveVRPUseTagFeasibility  = "feasibility"
-	SolveVRPUseTagSchedule     = "schedule"
-	SolveVRPUseTagAvailability = "availability"
+	SolveVRPUseTag                  = "use"
+	SolveVRPUseTagFeasibility       = "feasibility"
+	SolveVRPUseTagSchedule          = "schedule"
+	SolveVRPUseTagAvailability      = "availability"
+	SolveVRPUseTagScheduleStability = "schedule_stability"
 )
 
 type ShiftTeamRoutePolyline struct {
@@ -43,6 +45,9 @@ type SolveVRPLogisticsDB interface {
 	AddOptimizerRun(context.Context, logisticssql.AddOptimizerRunParams, *optimizerpb.VRPConstraintConfig, *optimizersettings.Settings) (*logisticssql.OptimizerRun, error)
 	WriteScheduleForVRPSolution(ctx context.Context, params *logisticsdb.WriteScheduleForVRPSolutionParams) (*logisticssql.Schedule, error)
 	AddOptimizerRunError(ctx context.Context, params logisticssql.AddOptimizerRunErrorParams) error
+	AddOptimizerRunValidationRepairs(ctx context.Context, optimizerRunID int64, report *validation.RepairReport) error
+	AddOptimizerRunBlackedOutVisits(ctx context.Context, optimizerRunID int64, blackedOut logisticsdb.BlackedOutVisits) error
+	AddScheduleStabilityRejection(ctx context.Context, params logisticssql.AddScheduleStabilityRejectionParams) error
 }
 
 func collectVisitIDsForPolyline(shiftTeam *optimizerpb.VRPShiftTeam) []int64 {
@@ -66,6 +71,7 @@ type VRPSolver struct {
 	OptimizerServiceClient optimizerpb.OptimizerServiceClient
 	SolveVRPLogisticsDB    SolveVRPLogisticsDB
 	RouteProvider          RouteProvider
+	Scope                  monitoring.Scope
 }
 
 type RouteProvider interface {
@@ -81,6 +87,18 @@ type SolveVRPParams struct {
 
 	AvailabilityVisitIDMap logisticsdb.AvailabilityVisitIDMap
 	UnassignedVisits       []*logisticssql.GetUnassignedScheduleVisitsForScheduleIDRow
+
+	// Compares written solutions with the previous schedule; nil writes all solutions.
+	ScheduleStability *ScheduleStability
+
+	// Multi-day visits of the problem, to persist the service dates chosen by written solutions.
+	MultiDayVisits logisticsdb.MultiDayVisits
+
+	// Repairs applied to the problem in validation repair mode, stored with the optimizer run.
+	ValidationRepairReport *validation.RepairReport
+
+	// Visits removed from the problem by blackout zones, stored with the optimizer run.
+	BlackedOutVisits logisticsdb.BlackedOutVisits
 }
 
 // SolveVRP solves a vehicle routing problem, returning a channel of intermediary results.
@@ -121,10 +139,15 @@ func (s *VRPSolver) SolveVRP(ctx context.Context, solveVRPParams *SolveVRPParams
 				IDMap:            solveVRPParams.AvailabilityVisitIDMap,
 				UnassignedVisits: solveVRPParams.UnassignedVisits,
 			},
+			solveVRPParams.ScheduleStability,
+			solveVRPParams.ValidationRepairReport,
+			solveVRPParams.BlackedOutVisits,
+			s.Scope,
 		)
 		if err != nil {
 			return nil, err
 		}
+		resultCollector.MultiDayVisits = solveVRPParams.MultiDayVisits
 	}
 
 	respChan := make(chan *WrappedSolveVRPResp, 5)
@@ -247,13 +270,28 @@ func newRun(
 	config *optimizerpb.VRPConstraintConfig,
 	settings *optimizersettings.Settings,
 	availabilityParams AvailabilityParams,
+	scheduleStability *ScheduleStability,
+	validationRepairReport *validation.RepairReport,
+	blackedOutVisits logisticsdb.BlackedOutVisits,
+	scope monitoring.Scope,
 ) (*ResultCollector, error) {
 	run, err := ldb.AddOptimizerRun(ctx, params, config, settings)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := ldb.AddOptimizerRunValidationRepairs(ctx, run.ID, validationRepairReport); err != nil {
+		return nil, err
+	}
+
+	if err := ldb.AddOptimizerRunBlackedOutVisits(ctx, run.ID, blackedOutVisits); err != nil {
+		return nil, err
+	}
+
 	writeChan := make(chan *optimizerpb.SolveVRPResponse, writeChanSize)
+	if scope == nil {
+		scope = &monitoring.NoopScope{}
+	}
 
 	collector := &ResultCollector{
 		ctx:                  context.Background(), // TODO(https://github.com/*company-data-covered*/services/pull/2540#discussion_r1055798348) clean up the lifecycle management of the result collector.
@@ -263,6 +301,8 @@ func newRun(
 		run:                    run,
 		AvailabilityVisitIDMap: availabilityParams.IDMap,
 		UnassignedVisits:       availabilityParams.UnassignedVisits,
+		ScheduleStability:      scheduleStability,
+		scope:                  scope,
 	}
 
 	return collector, nil
@@ -271,18 +311,23 @@ func newRun(
 type ScheduleResultWriter interface {
 	WriteScheduleForVRPSolution(ctx context.Context, params *logisticsdb.WriteScheduleForVRPSolutionParams) (*logisticssql.Schedule, error)
 	AddOptimizerRunError(ctx context.Context, params logisticssql.AddOptimizerRunErrorParams) error
+	AddScheduleStabilityRejection(ctx context.Context, params logisticssql.AddScheduleStabilityRejectionParams) error
 }
 
 type ResultCollector struct {
 	ctx       context.Context
 	writeChan chan *optimizerpb.SolveVRPResponse
 
-	run *logisticssql.OptimizerRun
+	run   *logisticssql.OptimizerRun
+	scope monitoring.Scope
 
 	ScheduleResultWriter ScheduleResultWriter
 
 	AvailabilityVisitIDMap logisticsdb.AvailabilityVisitIDMap
 	UnassignedVisits       []*logisticssql.GetUnassignedScheduleVisitsForScheduleIDRow
+
+	ScheduleStability *ScheduleStability
+	MultiDayVisits    logisticsdb.MultiDayVisits
 }
 
 func (c *ResultCollector) processWriteChan() {
@@ -313,7 +358,30 @@ func (c *ResultCollector) AddResult(resp *optimizerpb.SolveVRPResponse) {
 	c.writeChan <- resp
 }
 
+// writeResult writes the schedule for the response solution, unless the solution
+// is not stable enough compared to the previous schedule, in which case the rejection is recorded instead.
 func (c *ResultCollector) writeResult(resp *optimizerpb.SolveVRPResponse) (*logisticssql.Schedule, error) {
+	if c.ScheduleStability != nil {
+		diff := c.ScheduleStability.Diff(resp.GetSolution())
+		accepted := c.ScheduleStability.Accept(diff)
+		c.scope.WritePoint(scheduleDiffMeasurementName,
+			monitoring.Tags{serviceRegionTag: logisticsdb.I64ToA(c.run.ServiceRegionID)},
+			diff.fields(accepted))
+		if !accepted {
+			score := resp.GetSolution().GetScore()
+			return nil, c.ScheduleResultWriter.AddScheduleStabilityRejection(c.ctx, logisticssql.AddScheduleStabilityRejectionParams{
+				OptimizerRunID:                       c.run.ID,
+				PreviousScheduleID:                   c.ScheduleStability.PreviousSchedule.ScheduleID,
+				HardScore:                            score.GetHardScore(),
+				UnassignedVisitsScore:                score.GetUnassignedVisitsScore(),
+				SoftScore:                            score.GetSoftScore(),
+				SoftScoreImprovement:                 diff.SoftScoreImprovement,
+				MovedCareRequestIds:                  careRequestIDsToInt64s(diff.MovedCareRequestIDs),
+				MovedCommittedAdjacentCareRequestIds: careRequestIDsToInt64s(diff.MovedCommittedAdjacentCareRequestIDs),
+			})
+		}
+	}
+
 	optimizerVersion := resp.GetOptimizerMetadata().GetVersion()
 	params := &logisticsdb.WriteScheduleForVRPSolutionParams{
 		ServiceRegionID:              c.run.ServiceRegionID,
@@ -322,6 +390,8 @@ func (c *ResultCollector) writeResult(resp *optimizerpb.SolveVRPResponse) (*logi
 		Solution:                     resp.Solution,
 		AvailabilityVisitIDMap:       c.AvailabilityVisitIDMap,
 		LastScheduleUnassignedVisits: c.UnassignedVisits,
+		ServiceDate:                  c.run.ServiceDate,
+		MultiDayVisits:               c.MultiDayVisits,
 	}
 	return c.ScheduleResultWriter.WriteScheduleForVRPSolution(c.ctx, params)
 }
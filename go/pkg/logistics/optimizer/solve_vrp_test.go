@@ -11,6 +11,7 @@ import (
 	"github.com/*company-data-covered*/services/go/pkg/logistics"
 	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
 	"github.com/*company-data-covered*/services/go/pkg/logistics/optimizer/optimizersettings"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/validation"
 	"github.com/*company-data-covered*/services/go/pkg/monitoring"
 	"github.com/*company-data-covered*/services/go/pkg/testutils"
 	"google.golang.org/grpc"
@@ -18,11 +19,14 @@ import (
 )
 
 type MockSolveVRPLogisticsDB struct {
-	AddOptimizerRunResult             *logisticssql.OptimizerRun
-	AddOptimizerRunErr                error
-	WriteScheduleForVRPSolutionResult *logisticssql.Schedule
-	WriteScheduleForVRPSolutionErr    error
-	AddOptimizerRunErrorErr           error
+	AddOptimizerRunResult               *logisticssql.OptimizerRun
+	AddOptimizerRunErr                  error
+	WriteScheduleForVRPSolutionResult   *logisticssql.Schedule
+	WriteScheduleForVRPSolutionErr      error
+	AddOptimizerRunErrorErr             error
+	AddOptimizerRunValidationRepairsErr error
+	AddOptimizerRunBlackedOutVisitsErr  error
+	AddScheduleStabilityRejectionErr    error
 }
 
 func (m *MockSolveVRPLogisticsDB) AddOptimizerRun(context.Context, logisticssql.AddOptimizerRunParams, *optimizerpb.VRPConstraintConfig, *optimizersettings.Settings) (*logisticssql.OptimizerRun, error) {
@@ -37,6 +41,18 @@ func (m *MockSolveVRPLogisticsDB) AddOptimizerRunError(ctx context.Context, para
 	return m.AddOptimizerRunErrorErr
 }
 
+func (m *MockSolveVRPLogisticsDB) AddOptimizerRunValidationRepairs(ctx context.Context, optimizerRunID int64, report *validation.RepairReport) error {
+	return m.AddOptimizerRunValidationRepairsErr
+}
+
+func (m *MockSolveVRPLogisticsDB) AddOptimizerRunBlackedOutVisits(ctx context.Context, optimizerRunID int64, blackedOut logisticsdb.BlackedOutVisits) error {
+	return m.AddOptimizerRunBlackedOutVisitsErr
+}
+
+func (m *MockSolveVRPLogisticsDB) AddScheduleStabilityRejection(ctx context.Context, params logisticssql.AddScheduleStabilityRejectionParams) error {
+	return m.AddScheduleStabilityRejectionErr
+}
+
 type mockOptimizerServiceClient struct {
 	grpc.ClientStream
 	solveVRPErr error
@@ -109,17 +125,23 @@ func TestNewRun(t *testing.T) {
 	runnerLDB := &mockRunnerLDB{
 		optimizerRun: wantRun,
 	}
+	wantValidationRepairReport := &validation.RepairReport{
+		Repairs: []*validation.RepairRecord{{ErrorName: "unknown_visit_location", Description: "dropped visit 1"}},
+	}
+	wantBlackedOutVisits := logisticsdb.BlackedOutVisits{2: {ID: 3, Name: "flood"}}
 
 	collector, _ := newRun(context.Background(), runnerLDB, logisticssql.AddOptimizerRunParams{}, &optimizerpb.VRPConstraintConfig{}, &optimizersettings.Settings{}, AvailabilityParams{
 		IDMap:            logisticsdb.AvailabilityVisitIDMap{},
 		UnassignedVisits: []*logisticssql.GetUnassignedScheduleVisitsForScheduleIDRow{},
-	})
+	}, nil, wantValidationRepairReport, wantBlackedOutVisits, nil)
 
 	if collector.writeChan == nil {
 		t.Fatal("writeChan is nil")
 	}
 
 	testutils.MustMatch(t, wantRun, collector.run)
+	testutils.MustMatch(t, wantValidationRepairReport, runnerLDB.validationRepairReport)
+	testutils.MustMatch(t, wantBlackedOutVisits, runnerLDB.blackedOutVisits)
 }
 
 func TestVRPSolver_SolveVRP(t *testing.T) {
@@ -15,10 +15,14 @@ import (
 
 const (
 	osrmOKCode = "Ok"
+
+	// Maximum number of locations of an OSRM match request, per the default OSRM max-matching-size.
+	osrmMaxMatchLocations = 100
 )
 
 var (
 	errBadOSRMResponseCode = errors.New("bad OSRM response code")
+	errTraceTooShort       = errors.New("trace must have at least 2 locations")
 )
 
 type OSRMService struct {
@@ -72,6 +76,24 @@ func (s *OSRMService) NearestWaypointURL(latLng LatLng) string {
 	return uri
 }
 
+func (s *OSRMService) MatchURL(trace []TimedLatLng) string {
+	coords := make([]string, len(trace))
+	timestamps := make([]string, len(trace))
+	for i, loc := range trace {
+		coords[i] = loc.OSRMCoordinate()
+		timestamps[i] = strconv.FormatInt(loc.Timestamp.Unix(), 10)
+	}
+
+	// Gaps split the trace into separate matchings where locations were not reported for a while,
+	// and tidy drops locations too close to each other to be matched reliably.
+	uri := fmt.Sprintf("%s/match/v1/driving/%s?timestamps=%s&overview=false&gaps=split&tidy=true",
+		s.Addr,
+		strings.Join(coords, ";"),
+		strings.Join(timestamps, ";"))
+
+	return uri
+}
+
 type osrmTableResp struct {
 	Code          string
 	DurationsSec  [][]float64 `json:"durations"`
@@ -283,6 +305,78 @@ func (s *OSRMService) GetPathDistanceMatrix(ctx context.Context, mapsTags monito
 	return m.withEnforceDiagonalDistancesAreZero(), nil
 }
 
+type osrmMatching struct {
+	Confidence     float64
+	DistanceMeters float64 `json:"distance"`
+	DurationSec    float64 `json:"duration"`
+}
+
+type osrmMatchResp struct {
+	Code      string
+	Matchings []osrmMatching
+}
+
+func (s *OSRMService) MatchTrace(ctx context.Context, mapsTags monitoring.Tags, trace []TimedLatLng) (*MatchedTrace, error) {
+	startTime := time.Now()
+	trace = DownsampleTrace(trace, osrmMaxMatchLocations)
+	if len(trace) < 2 {
+		return nil, errTraceTooShort
+	}
+
+	var returnErr error
+	defer SendMonitoring(&SendMonitoringParams{
+		Scope: s.ScopedMetrics.With("", mapsTags, monitoring.Fields{
+			numElementsField: len(trace),
+		}),
+		StartTime:       startTime,
+		MeasurementName: matchTraceMeasurementName,
+		ErrorPtr:        &returnErr,
+	})
+
+	resp, err := s.getWithContext(ctx, s.MatchURL(trace))
+	if err != nil {
+		returnErr = err
+		return nil, returnErr
+	}
+	defer resp.Body.Close()
+
+	var osrmMatch osrmMatchResp
+	err = json.NewDecoder(resp.Body).Decode(&osrmMatch)
+	if err != nil {
+		returnErr = err
+		return nil, returnErr
+	}
+
+	if osrmMatch.Code != osrmOKCode {
+		returnErr = errBadOSRMResponseCode
+		return nil, returnErr
+	}
+
+	if len(osrmMatch.Matchings) == 0 {
+		returnErr = errors.New("no matchings")
+		return nil, returnErr
+	}
+
+	var distanceMeters, durationSec, weightedConfidence float64
+	for _, matching := range osrmMatch.Matchings {
+		distanceMeters += matching.DistanceMeters
+		durationSec += matching.DurationSec
+		weightedConfidence += matching.Confidence * matching.DistanceMeters
+	}
+	confidence := 0.0
+	if distanceMeters > 0 {
+		confidence = weightedConfidence / distanceMeters
+	}
+
+	return &MatchedTrace{
+		Distance: Distance{
+			Duration:     time.Duration(durationSec) * time.Second,
+			LengthMeters: int64(distanceMeters),
+		},
+		Confidence: confidence,
+	}, nil
+}
+
 type osrmNearestWaypointResp struct {
 	Code      string
 	Waypoints []struct {
@@ -207,3 +207,83 @@ func TestOSRM_GetDistanceSource(t *testing.T) {
 		})
 	}
 }
+
+func TestOSRM_MatchTrace(t *testing.T) {
+	ctx := context.Background()
+
+	start := time.Unix(1000, 0)
+	trace := []TimedLatLng{
+		{LatLng: NewLatLng(1, 2), Timestamp: start},
+		{LatLng: NewLatLng(1.001, 2), Timestamp: start.Add(10 * time.Second)},
+		{LatLng: NewLatLng(1.002, 2), Timestamp: start.Add(20 * time.Second)},
+	}
+
+	tcs := []struct {
+		Desc     string
+		Trace    []TimedLatLng
+		OSRMResp *osrmMatchResp
+
+		HasErr bool
+		Want   *MatchedTrace
+	}{
+		{
+			Desc:  "base case",
+			Trace: trace,
+			OSRMResp: &osrmMatchResp{
+				Code: osrmOKCode,
+				Matchings: []osrmMatching{
+					{Confidence: 1, DistanceMeters: 300, DurationSec: 20},
+					{Confidence: 0.5, DistanceMeters: 100, DurationSec: 10},
+				},
+			},
+
+			Want: &MatchedTrace{
+				Distance: Distance{
+					Duration:     30 * time.Second,
+					LengthMeters: 400,
+				},
+				Confidence: 0.875,
+			},
+		},
+		{
+			Desc:     "no match",
+			Trace:    trace,
+			OSRMResp: &osrmMatchResp{Code: "NoMatch"},
+
+			HasErr: true,
+		},
+		{
+			Desc:  "single location",
+			Trace: trace[:1],
+
+			HasErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			mockOSRM := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				buf, _ := json.Marshal(tc.OSRMResp)
+				w.Write(buf)
+			}))
+			defer mockOSRM.Close()
+
+			s := *NewOSRMService(mockOSRM.URL, 1, &monitoring.NoopScope{})
+			matched, err := s.MatchTrace(ctx, nil, tc.Trace)
+			testutils.MustMatch(t, tc.HasErr, err != nil)
+			testutils.MustMatch(t, tc.Want, matched)
+		})
+	}
+}
+
+func TestOSRM_MatchURL(t *testing.T) {
+	s := &OSRMService{
+		Addr: "addr",
+	}
+
+	url := s.MatchURL([]TimedLatLng{
+		{LatLng: NewLatLng(1, 2), Timestamp: time.Unix(10, 0)},
+		{LatLng: NewLatLng(3, 4), Timestamp: time.Unix(20, 0)},
+	})
+	testutils.MustMatch(t, "addr/match/v1/driving/2.000000,1.000000;4.000000,3.000000?timestamps=10;20&overview=false&gaps=split&tidy=true", url)
+}
@@ -0,0 +1,326 @@
+package recommendations
+
+import (
+	"math"
+	"time"
+
+	commonpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/common"
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// Average driving speed used to estimate drive durations from straight-line distances,
+	// for locations without a known drive duration.
+	estimatedDriveSpeedMetersPerSec = 11.0
+
+	// Arrivals closer than this to the end of the visit time window are at risk of being late.
+	latenessRiskSlack = 30 * time.Minute
+)
+
+// VisitParams describes the visit to recommend.
+type VisitParams struct {
+	// Location of the visit, or nil if unknown.
+	Location *logistics.LatLng
+
+	ArrivalTimeWindow logisticsdb.TimeWindow
+	ServiceDuration   time.Duration
+}
+
+// ExplainParams are the inputs to explain the recommendation of a visit for a shift team.
+type ExplainParams struct {
+	Visit VisitParams
+
+	// Route of the latest schedule of the shift team, or nil if the shift team has none.
+	Route *logisticspb.ShiftTeamRoute
+	// Drive durations between the visit and the route locations, from the map service distances.
+	DriveDurations logisticsdb.DriveDurations
+
+	HardAttributeMismatches int
+	SoftAttributeMismatches int
+
+	Now time.Time
+}
+
+// routeNode is a location of a route the shift team departs from.
+type routeNode struct {
+	location  *logistics.LatLng
+	departure time.Time
+	// committed is whether the node is a visit committed to the shift team.
+	committed bool
+	// started is whether the shift team already started on the node, so no visit can be inserted before it.
+	started bool
+}
+
+func latLngFromProto(loc *commonpb.Location) *logistics.LatLng {
+	if loc == nil {
+		return nil
+	}
+	return &logistics.LatLng{LatE6: loc.GetLatitudeE6(), LngE6: loc.GetLongitudeE6()}
+}
+
+func routeNodes(route *logisticspb.ShiftTeamRoute, now time.Time) []routeNode {
+	if route.GetBaseLocation() == nil {
+		return nil
+	}
+
+	departure := now
+	if route.BaseLocationDepartureTimestampSec != nil {
+		departure = time.Unix(route.GetBaseLocationDepartureTimestampSec(), 0)
+	}
+	nodes := []routeNode{{location: latLngFromProto(route.GetBaseLocation()), departure: departure}}
+	for _, stop := range route.GetStops() {
+		node := routeNode{}
+		if restBreak := stop.GetRestBreak(); restBreak != nil {
+			node.location = latLngFromProto(restBreak.GetLocation())
+			node.departure = time.Unix(restBreak.GetStartTimestampSec()+restBreak.GetDurationSec(), 0)
+		}
+		if visit := stop.GetVisit(); visit != nil {
+			node.location = latLngFromProto(visit.GetLocation())
+			node.departure = time.Unix(visit.GetCompleteTimestampSec(), 0)
+			switch visit.GetStatus() {
+			case logisticspb.ShiftTeamVisit_STATUS_COMMITTED:
+				node.committed = true
+			case logisticspb.ShiftTeamVisit_STATUS_EN_ROUTE,
+				logisticspb.ShiftTeamVisit_STATUS_ON_SCENE,
+				logisticspb.ShiftTeamVisit_STATUS_COMPLETE:
+				node.started = true
+			}
+		}
+		if node.location == nil {
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+
+	endLocation := route.GetEndLocation()
+	if endLocation == nil {
+		endLocation = route.GetBaseLocation()
+	}
+	return append(nodes, routeNode{location: latLngFromProto(endLocation)})
+}
+
+// RouteLocations returns the locations of the route that a visit can be inserted between.
+func RouteLocations(route *logisticspb.ShiftTeamRoute, now time.Time) []logistics.LatLng {
+	var res []logistics.LatLng
+	for _, node := range routeNodes(route, now) {
+		res = append(res, *node.location)
+	}
+	return res
+}
+
+func estimatedDriveDuration(from, to *logistics.LatLng) time.Duration {
+	meters := logistics.HaversineDistanceMeters(*from, *to)
+	return time.Duration(meters / estimatedDriveSpeedMetersPerSec * float64(time.Second))
+}
+
+// driveDuration returns the known drive duration between the locations,
+// or an estimate from their straight-line distance if unknown.
+func driveDuration(driveDurations logisticsdb.DriveDurations, from, to *logistics.LatLng) time.Duration {
+	if d, ok := driveDurations[logisticsdb.LatLngPair{From: *from, To: *to}]; ok {
+		return d
+	}
+	return estimatedDriveDuration(from, to)
+}
+
+// routeInsertion is the insertion of the visit after a node of a route.
+type routeInsertion struct {
+	addedDriveDuration time.Duration
+	arrival            time.Time
+	lateness           time.Duration
+	// delay of the nodes after the visit.
+	delay time.Duration
+	// index of the node the visit is inserted after.
+	afterIndex int
+}
+
+func (r routeInsertion) betterThan(other routeInsertion) bool {
+	if (r.lateness > 0) != (other.lateness > 0) {
+		return r.lateness <= 0
+	}
+	if r.lateness > 0 && r.lateness != other.lateness {
+		return r.lateness < other.lateness
+	}
+	return r.addedDriveDuration < other.addedDriveDuration
+}
+
+// bestRouteInsertion returns the insertion of the visit that adds the least drive time without being late,
+// or the least late one if all are late.
+func bestRouteInsertion(nodes []routeNode, visit VisitParams, driveDurations logisticsdb.DriveDurations, now time.Time) *routeInsertion {
+	firstIndex := 0
+	for i, node := range nodes {
+		if node.started {
+			firstIndex = i
+		}
+	}
+
+	var best *routeInsertion
+	for i := firstIndex; i < len(nodes)-1; i++ {
+		prev, next := nodes[i], nodes[i+1]
+		departure := prev.departure
+		if departure.Before(now) {
+			departure = now
+		}
+
+		driveToVisit := driveDuration(driveDurations, prev.location, visit.Location)
+		driveFromVisit := driveDuration(driveDurations, visit.Location, next.location)
+		directDrive := driveDuration(driveDurations, prev.location, next.location)
+
+		arrival := departure.Add(driveToVisit)
+		if arrival.Before(visit.ArrivalTimeWindow.Start) {
+			arrival = visit.ArrivalTimeWindow.Start
+		}
+		delay := arrival.Add(visit.ServiceDuration + driveFromVisit).Sub(departure.Add(directDrive))
+		insertion := routeInsertion{
+			addedDriveDuration: driveToVisit + driveFromVisit - directDrive,
+			arrival:            arrival,
+			lateness:           arrival.Sub(visit.ArrivalTimeWindow.End),
+			delay:              delay,
+			afterIndex:         i,
+		}
+		if insertion.addedDriveDuration < 0 {
+			insertion.addedDriveDuration = 0
+		}
+		if insertion.delay < 0 {
+			insertion.delay = 0
+		}
+
+		if best == nil || insertion.betterThan(*best) {
+			best = &insertion
+		}
+	}
+	return best
+}
+
+func latenessRisk(arrival time.Time, arrivalTimeWindow logisticsdb.TimeWindow) logisticspb.AssignmentExplanation_LatenessRisk {
+	slack := arrivalTimeWindow.End.Sub(arrival)
+	switch {
+	case slack < 0:
+		return logisticspb.AssignmentExplanation_LATENESS_RISK_HIGH
+	case slack < latenessRiskSlack:
+		return logisticspb.AssignmentExplanation_LATENESS_RISK_MEDIUM
+	default:
+		return logisticspb.AssignmentExplanation_LATENESS_RISK_LOW
+	}
+}
+
+// Explain returns the breakdown of the recommendation of a visit for a shift team.
+//
+// Drive times and effects on committed visits are only explained when both the location of the visit
+// and the route of the shift team are known. Drive times missing from DriveDurations are estimated.
+func Explain(params ExplainParams) *logisticspb.AssignmentExplanation {
+	explanation := &logisticspb.AssignmentExplanation{
+		HardAttributeMismatches: int32(params.HardAttributeMismatches),
+		SoftAttributeMismatches: int32(params.SoftAttributeMismatches),
+	}
+	if params.Visit.Location == nil || params.Route == nil {
+		return explanation
+	}
+
+	nodes := routeNodes(params.Route, params.Now)
+	insertion := bestRouteInsertion(nodes, params.Visit, params.DriveDurations, params.Now)
+	if insertion == nil {
+		return explanation
+	}
+
+	var delayedCommittedVisits int32
+	if insertion.delay > 0 {
+		for _, node := range nodes[insertion.afterIndex+1:] {
+			if node.committed {
+				delayedCommittedVisits++
+			}
+		}
+	}
+	var committedVisitsDelay time.Duration
+	if delayedCommittedVisits > 0 {
+		committedVisitsDelay = insertion.delay
+	}
+
+	explanation.AddedDriveDurationSec = proto.Int64(int64(insertion.addedDriveDuration.Seconds()))
+	explanation.EstimatedArrivalTimestampSec = proto.Int64(insertion.arrival.Unix())
+	explanation.LatenessRisk = latenessRisk(insertion.arrival, params.Visit.ArrivalTimeWindow)
+	explanation.DelayedCommittedVisits = delayedCommittedVisits
+	explanation.CommittedVisitsDelaySec = proto.Int64(int64(committedVisitsDelay.Seconds()))
+	return explanation
+}
+
+// policyWeights are the costs of each part of an explanation under a ranking policy.
+type policyWeights struct {
+	perDriveMinute           float64
+	perLatenessRisk          float64
+	perHardAttributeMismatch float64
+	perSoftAttributeMismatch float64
+	perDelayedCommittedVisit float64
+	perCommittedDelayMinute  float64
+}
+
+var rankingPolicyWeights = map[logisticspb.AssignmentRankingPolicy]policyWeights{
+	logisticspb.AssignmentRankingPolicy_ASSIGNMENT_RANKING_POLICY_BALANCED: {
+		perDriveMinute:           1,
+		perLatenessRisk:          15,
+		perHardAttributeMismatch: 1000,
+		perSoftAttributeMismatch: 15,
+		perDelayedCommittedVisit: 5,
+		perCommittedDelayMinute:  1,
+	},
+	logisticspb.AssignmentRankingPolicy_ASSIGNMENT_RANKING_POLICY_MINIMIZE_DRIVE_TIME: {
+		perDriveMinute:           1,
+		perLatenessRisk:          5,
+		perHardAttributeMismatch: 1000,
+		perSoftAttributeMismatch: 5,
+		perDelayedCommittedVisit: 1,
+		perCommittedDelayMinute:  0.2,
+	},
+	logisticspb.AssignmentRankingPolicy_ASSIGNMENT_RANKING_POLICY_PROTECT_COMMITTED_VISITS: {
+		perDriveMinute:           0.5,
+		perLatenessRisk:          15,
+		perHardAttributeMismatch: 1000,
+		perSoftAttributeMismatch: 15,
+		perDelayedCommittedVisit: 30,
+		perCommittedDelayMinute:  5,
+	},
+}
+
+// Drive time assumed for explanations without one, so they rank after comparable known ones.
+const unknownDriveMinutes = 60
+
+// RankingCost returns the cost of an explanation under the ranking policy, lower being better,
+// or false if the policy does not rank.
+func RankingCost(explanation *logisticspb.AssignmentExplanation, policy logisticspb.AssignmentRankingPolicy) (float64, bool) {
+	weights, ok := rankingPolicyWeights[policy]
+	if !ok {
+		return 0, false
+	}
+
+	driveMinutes := float64(unknownDriveMinutes)
+	if explanation.AddedDriveDurationSec != nil {
+		driveMinutes = float64(explanation.GetAddedDriveDurationSec()) / 60
+	}
+	var latenessRisk float64
+	if explanation.GetLatenessRisk() != logisticspb.AssignmentExplanation_LATENESS_RISK_UNSPECIFIED {
+		latenessRisk = float64(explanation.GetLatenessRisk() - logisticspb.AssignmentExplanation_LATENESS_RISK_LOW)
+	}
+
+	cost := weights.perDriveMinute*driveMinutes +
+		weights.perLatenessRisk*latenessRisk +
+		weights.perHardAttributeMismatch*float64(explanation.GetHardAttributeMismatches()) +
+		weights.perSoftAttributeMismatch*float64(explanation.GetSoftAttributeMismatches()) +
+		weights.perDelayedCommittedVisit*float64(explanation.GetDelayedCommittedVisits()) +
+		weights.perCommittedDelayMinute*float64(explanation.GetCommittedVisitsDelaySec())/60
+	return math.Round(cost*100) / 100, true
+}
+
+// SetRankingCosts sets the ranking cost of the explanations under the policy,
+// returning whether the policy ranks.
+func SetRankingCosts(policy logisticspb.AssignmentRankingPolicy, explanations ...*logisticspb.AssignmentExplanation) bool {
+	if _, ok := rankingPolicyWeights[policy]; !ok {
+		return false
+	}
+	for _, explanation := range explanations {
+		cost, _ := RankingCost(explanation, policy)
+		explanation.RankingCost = proto.Float64(cost)
+	}
+	return true
+}
@@ -0,0 +1,233 @@
+package recommendations
+
+import (
+	"testing"
+	"time"
+
+	commonpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/common"
+	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics"
+	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestExplain(t *testing.T) {
+	openHoursStart := time.Date(2023, time.September, 16, 8, 0, 0, 0, time.UTC)
+	now := openHoursStart.Add(-time.Hour)
+	baseLocation := logistics.LatLng{LatE6: 0, LngE6: 0}
+	stopLocation := logistics.LatLng{LatE6: 0, LngE6: 20000}
+	endLocation := logistics.LatLng{LatE6: 0, LngE6: 40000}
+	visitLocation := logistics.LatLng{LatE6: 10000, LngE6: 0}
+	stopDeparture := openHoursStart.Add(time.Hour)
+
+	route := func(stopStatus logisticspb.ShiftTeamVisit_Status) *logisticspb.ShiftTeamRoute {
+		return &logisticspb.ShiftTeamRoute{
+			BaseLocation:                      &commonpb.Location{LatitudeE6: baseLocation.LatE6, LongitudeE6: baseLocation.LngE6},
+			BaseLocationDepartureTimestampSec: proto.Int64(openHoursStart.Unix()),
+			EndLocation:                       &commonpb.Location{LatitudeE6: endLocation.LatE6, LongitudeE6: endLocation.LngE6},
+			Stops: []*logisticspb.ShiftTeamRouteStop{
+				{
+					Stop: &logisticspb.ShiftTeamRouteStop_Visit{
+						Visit: &logisticspb.ShiftTeamVisit{
+							CareRequestId:        proto.Int64(1),
+							Location:             &commonpb.Location{LatitudeE6: stopLocation.LatE6, LongitudeE6: stopLocation.LngE6},
+							CompleteTimestampSec: proto.Int64(stopDeparture.Unix()),
+							Status:               stopStatus.Enum(),
+						},
+					},
+				},
+			},
+		}
+	}
+	visit := VisitParams{
+		Location:          &visitLocation,
+		ArrivalTimeWindow: logisticsdb.TimeWindow{Start: openHoursStart, End: openHoursStart.Add(4 * time.Hour)},
+		ServiceDuration:   30 * time.Minute,
+	}
+
+	baseToVisit := estimatedDriveDuration(&baseLocation, &visitLocation)
+	visitToStop := estimatedDriveDuration(&visitLocation, &stopLocation)
+	baseToStop := estimatedDriveDuration(&baseLocation, &stopLocation)
+	stopToVisit := estimatedDriveDuration(&stopLocation, &visitLocation)
+	visitToEnd := estimatedDriveDuration(&visitLocation, &endLocation)
+	stopToEnd := estimatedDriveDuration(&stopLocation, &endLocation)
+	arrivalBeforeStop := openHoursStart.Add(baseToVisit)
+	arrivalAfterStop := stopDeparture.Add(stopToVisit)
+
+	tcs := []struct {
+		Desc   string
+		Params ExplainParams
+
+		ExpectedExplanation *logisticspb.AssignmentExplanation
+	}{
+		{
+			Desc: "inserted before committed visit",
+			Params: ExplainParams{
+				Visit:                   visit,
+				Route:                   route(logisticspb.ShiftTeamVisit_STATUS_COMMITTED),
+				SoftAttributeMismatches: 1,
+				Now:                     now,
+			},
+
+			ExpectedExplanation: &logisticspb.AssignmentExplanation{
+				AddedDriveDurationSec:        proto.Int64(int64((baseToVisit + visitToStop - baseToStop).Seconds())),
+				EstimatedArrivalTimestampSec: proto.Int64(arrivalBeforeStop.Unix()),
+				LatenessRisk:                 logisticspb.AssignmentExplanation_LATENESS_RISK_LOW,
+				SoftAttributeMismatches:      1,
+				DelayedCommittedVisits:       1,
+				CommittedVisitsDelaySec: proto.Int64(int64(
+					arrivalBeforeStop.Add(visit.ServiceDuration + visitToStop).Sub(openHoursStart.Add(baseToStop)).Seconds())),
+			},
+		},
+		{
+			Desc: "inserted after started visit",
+			Params: ExplainParams{
+				Visit: visit,
+				Route: route(logisticspb.ShiftTeamVisit_STATUS_ON_SCENE),
+				Now:   now,
+			},
+
+			ExpectedExplanation: &logisticspb.AssignmentExplanation{
+				AddedDriveDurationSec:        proto.Int64(int64((stopToVisit + visitToEnd - stopToEnd).Seconds())),
+				EstimatedArrivalTimestampSec: proto.Int64(arrivalAfterStop.Unix()),
+				LatenessRisk:                 logisticspb.AssignmentExplanation_LATENESS_RISK_LOW,
+				CommittedVisitsDelaySec:      proto.Int64(0),
+			},
+		},
+		{
+			Desc: "known drive durations",
+			Params: ExplainParams{
+				Visit: visit,
+				Route: route(logisticspb.ShiftTeamVisit_STATUS_ON_SCENE),
+				DriveDurations: logisticsdb.DriveDurations{
+					{From: stopLocation, To: visitLocation}: 10 * time.Minute,
+					{From: visitLocation, To: endLocation}:  20 * time.Minute,
+					{From: stopLocation, To: endLocation}:   25 * time.Minute,
+				},
+				Now: now,
+			},
+
+			ExpectedExplanation: &logisticspb.AssignmentExplanation{
+				AddedDriveDurationSec:        proto.Int64(int64((5 * time.Minute).Seconds())),
+				EstimatedArrivalTimestampSec: proto.Int64(stopDeparture.Add(10 * time.Minute).Unix()),
+				LatenessRisk:                 logisticspb.AssignmentExplanation_LATENESS_RISK_LOW,
+				CommittedVisitsDelaySec:      proto.Int64(0),
+			},
+		},
+		{
+			Desc: "late arrival",
+			Params: ExplainParams{
+				Visit: VisitParams{
+					Location:          &visitLocation,
+					ArrivalTimeWindow: logisticsdb.TimeWindow{Start: now, End: openHoursStart},
+				},
+				Route: route(logisticspb.ShiftTeamVisit_STATUS_ON_SCENE),
+				Now:   now,
+			},
+
+			ExpectedExplanation: &logisticspb.AssignmentExplanation{
+				AddedDriveDurationSec:        proto.Int64(int64((stopToVisit + visitToEnd - stopToEnd).Seconds())),
+				EstimatedArrivalTimestampSec: proto.Int64(arrivalAfterStop.Unix()),
+				LatenessRisk:                 logisticspb.AssignmentExplanation_LATENESS_RISK_HIGH,
+				CommittedVisitsDelaySec:      proto.Int64(0),
+			},
+		},
+		{
+			Desc: "unknown visit location",
+			Params: ExplainParams{
+				Visit:                   VisitParams{ArrivalTimeWindow: visit.ArrivalTimeWindow},
+				Route:                   route(logisticspb.ShiftTeamVisit_STATUS_COMMITTED),
+				HardAttributeMismatches: 2,
+				Now:                     now,
+			},
+
+			ExpectedExplanation: &logisticspb.AssignmentExplanation{
+				HardAttributeMismatches: 2,
+			},
+		},
+		{
+			Desc: "no route",
+			Params: ExplainParams{
+				Visit: visit,
+				Now:   now,
+			},
+
+			ExpectedExplanation: &logisticspb.AssignmentExplanation{},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			testutils.MustMatch(t, tc.ExpectedExplanation, Explain(tc.Params))
+		})
+	}
+}
+
+func TestSetRankingCosts(t *testing.T) {
+	nearby := &logisticspb.AssignmentExplanation{
+		AddedDriveDurationSec:   proto.Int64(600),
+		LatenessRisk:            logisticspb.AssignmentExplanation_LATENESS_RISK_LOW,
+		DelayedCommittedVisits:  2,
+		CommittedVisitsDelaySec: proto.Int64(1800),
+	}
+	far := &logisticspb.AssignmentExplanation{
+		AddedDriveDurationSec:   proto.Int64(1800),
+		LatenessRisk:            logisticspb.AssignmentExplanation_LATENESS_RISK_LOW,
+		CommittedVisitsDelaySec: proto.Int64(0),
+	}
+	unknown := &logisticspb.AssignmentExplanation{}
+	mismatched := &logisticspb.AssignmentExplanation{
+		AddedDriveDurationSec:   proto.Int64(0),
+		HardAttributeMismatches: 1,
+	}
+
+	tcs := []struct {
+		Desc   string
+		Policy logisticspb.AssignmentRankingPolicy
+
+		ExpectedRanks bool
+		ExpectedCosts []float64
+	}{
+		{
+			Desc:   "minimize drive time",
+			Policy: logisticspb.AssignmentRankingPolicy_ASSIGNMENT_RANKING_POLICY_MINIMIZE_DRIVE_TIME,
+
+			ExpectedRanks: true,
+			ExpectedCosts: []float64{18, 30, 60, 1000},
+		},
+		{
+			Desc:   "protect committed visits",
+			Policy: logisticspb.AssignmentRankingPolicy_ASSIGNMENT_RANKING_POLICY_PROTECT_COMMITTED_VISITS,
+
+			ExpectedRanks: true,
+			ExpectedCosts: []float64{215, 15, 30, 1000},
+		},
+		{
+			Desc:   "unspecified",
+			Policy: logisticspb.AssignmentRankingPolicy_ASSIGNMENT_RANKING_POLICY_UNSPECIFIED,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			explanations := []*logisticspb.AssignmentExplanation{
+				proto.Clone(nearby).(*logisticspb.AssignmentExplanation),
+				proto.Clone(far).(*logisticspb.AssignmentExplanation),
+				proto.Clone(unknown).(*logisticspb.AssignmentExplanation),
+				proto.Clone(mismatched).(*logisticspb.AssignmentExplanation),
+			}
+
+			ranks := SetRankingCosts(tc.Policy, explanations...)
+			testutils.MustMatch(t, tc.ExpectedRanks, ranks)
+
+			var costs []float64
+			for _, explanation := range explanations {
+				if explanation.RankingCost != nil {
+					costs = append(costs, explanation.GetRankingCost())
+				}
+			}
+			testutils.MustMatch(t, tc.ExpectedCosts, costs)
+		})
+	}
+}
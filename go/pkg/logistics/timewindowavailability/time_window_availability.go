@@ -9,6 +9,7 @@ import (
 	"github.com/*company-data-covered*/services/go/pkg/generated/proto/common"
 	logisticspb "github.com/*company-data-covered*/services/go/pkg/generated/proto/logistics"
 	optimizerpb "github.com/*company-data-covered*/services/go/pkg/generated/proto/optimizer"
+	"github.com/*company-data-covered*/services/go/pkg/logistics"
 	"github.com/*company-data-covered*/services/go/pkg/logistics/checkfeasibility"
 	"github.com/*company-data-covered*/services/go/pkg/logistics/logisticsdb"
 	"github.com/*company-data-covered*/services/go/pkg/logistics/optimizer"
@@ -207,11 +208,29 @@ func buildTWAvailabilitiesVRP(params *AvailabilitiesParams) (map[*common.Date]*a
 		locID := cfData.LocIDs[0]
 		visitIDs := make([]int64, len(timeWindows))
 		vrpInput := params.VRPInputByDate[date]
-		twVisits := make([]*optimizerpb.VRPVisit, len(timeWindows))
+		var twVisits []*optimizerpb.VRPVisit
+		var twVisitIDs []int64
 		visitsTWMap := make(map[int64]*logisticspb.TimeWindowAvailability)
 		for i, tw := range timeWindows {
 			visitID := int64(-1 * (i + 1))
 			visitIDs[i] = visitID
+			vrpTW, err := logisticsdb.VRPTimeWindowFromTimeWindow(tw)
+			if err != nil {
+				return nil, err
+			}
+
+			// Time windows with the visit location blacked out throughout are unavailable, without asking the optimizer.
+			// Others are checked during their part outside blackout zones.
+			vrpTW, zone := availableVRPTimeWindow(vrpData.BlackoutZones, templateVisit, vrpTW)
+			if zone != nil {
+				visitsTWMap[visitID] = &logisticspb.TimeWindowAvailability{
+					TimeWindow:   tw,
+					Status:       logisticspb.TimeWindowAvailability_STATUS_UNAVAILABLE,
+					BlackoutZone: zone.ToProto(),
+				}
+				continue
+			}
+
 			vrpVisit, err := logisticsdb.VRPVisitFromCheckFeasibilityRequest(&logisticsdb.VRPVisitFromCFVisitParams{
 				Visit:              templateVisit,
 				FeasibilityVisitID: visitID,
@@ -223,11 +242,6 @@ func buildTWAvailabilitiesVRP(params *AvailabilitiesParams) (map[*common.Date]*a
 				return nil, fmt.Errorf("error in vrpVisitFromCheckFeasibilityRequest: %w", err)
 			}
 
-			vrpTW, err := logisticsdb.VRPTimeWindowFromTimeWindow(tw)
-			if err != nil {
-				return nil, err
-			}
-
 			vrpVisit.ArrivalTimeWindow = vrpTW
 			vrpVisit.OverlapSetKey = proto.String(visitSetName)
 
@@ -235,14 +249,15 @@ func buildTWAvailabilitiesVRP(params *AvailabilitiesParams) (map[*common.Date]*a
 				vrpInput.VRPRequest.Problem.Description.Visits,
 				vrpVisit,
 			)
-			twVisits[i] = vrpVisit
+			twVisits = append(twVisits, vrpVisit)
+			twVisitIDs = append(twVisitIDs, visitID)
 			visitsTWMap[visitID] = &logisticspb.TimeWindowAvailability{
 				TimeWindow: tw,
 				Status:     logisticspb.TimeWindowAvailability_STATUS_UNAVAILABLE,
 			}
 		}
 
-		vrpInput.VRPProblemData.FeasibilityVisitIDs = visitIDs
+		vrpInput.VRPProblemData.FeasibilityVisitIDs = twVisitIDs
 
 		twAvailabilitiesByDate[date] = &availabilityVRP{
 			visits:               twVisits,
@@ -260,6 +275,34 @@ func buildTWAvailabilitiesVRP(params *AvailabilitiesParams) (map[*common.Date]*a
 	return twAvailabilitiesByDate, nil
 }
 
+// availableVRPTimeWindow returns the time window clamped to its longest part outside the blackout zones
+// containing the visit location, or the first zone active during the whole time window.
+func availableVRPTimeWindow(
+	zones logisticsdb.BlackoutZones,
+	visit *logisticspb.CheckFeasibilityVisit,
+	vrpTW *optimizerpb.VRPTimeWindow,
+) (*optimizerpb.VRPTimeWindow, *logisticsdb.BlackoutZone) {
+	location := visit.GetLocation()
+	if location == nil {
+		return vrpTW, nil
+	}
+
+	availableTW, zone := zones.AvailableTimeWindow(
+		logistics.LatLng{LatE6: location.GetLatitudeE6(), LngE6: location.GetLongitudeE6()},
+		logisticsdb.TimeWindow{
+			Start: time.Unix(vrpTW.GetStartTimestampSec(), 0),
+			End:   time.Unix(vrpTW.GetEndTimestampSec(), 0),
+		},
+	)
+	if availableTW == nil {
+		return nil, zone
+	}
+	return &optimizerpb.VRPTimeWindow{
+		StartTimestampSec: proto.Int64(availableTW.Start.Unix()),
+		EndTimestampSec:   proto.Int64(availableTW.End.Unix()),
+	}, nil
+}
+
 func possibleTimeWindows(
 	startTime time.Time,
 	endTime time.Time,
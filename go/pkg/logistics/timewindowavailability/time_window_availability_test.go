@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/*company-data-covered*/services/go/pkg/generated/proto/common"
+	"github.com/*company-data-covered*/services/go/pkg/logistics"
 	"github.com/*company-data-covered*/services/go/pkg/logistics/checkfeasibility"
 	"github.com/*company-data-covered*/services/go/pkg/logistics/optimizer"
 
@@ -170,6 +171,80 @@ func TestTimeWindowAvailability_TimeWindowAvailabilities(t *testing.T) {
 	}
 }
 
+func TestTimeWindowAvailability_buildTWAvailabilitiesVRPBlackoutZone(t *testing.T) {
+	openHoursStart := time.Date(2023, time.September, 15, 8, 0, 0, 0, time.UTC)
+	openHoursEnd := openHoursStart.Add(8 * time.Hour)
+	serviceDate := logisticsdb.TimestampToDate(openHoursStart)
+	date := logisticsdb.TimeToProtoDate(&serviceDate)
+
+	zone := &logisticsdb.BlackoutZone{
+		ID:   1,
+		Name: "Flooding",
+		Polygon: logistics.Polygon{
+			{LatE6: 0, LngE6: 0},
+			{LatE6: 0, LngE6: 10},
+			{LatE6: 10, LngE6: 10},
+			{LatE6: 10, LngE6: 0},
+		},
+		TimeWindow: logisticsdb.TimeWindow{Start: openHoursStart, End: openHoursStart.Add(5 * time.Hour)},
+	}
+	vrpInput := &checkfeasibility.SolveVRPInput{
+		VRPProblemData: &logisticsdb.VRPProblemData{},
+		VRPRequest: &optimizerpb.SolveVRPRequest{
+			Problem: &optimizerpb.VRPProblem{Description: &optimizerpb.VRPDescription{}},
+			Config:  &optimizerpb.VRPConfig{},
+		},
+	}
+
+	twAvailabilitiesByDate, err := buildTWAvailabilitiesVRP(&AvailabilitiesParams{
+		ServiceDates: []*common.Date{date},
+		VRPDataByDate: map[*common.Date]*logisticsdb.ServiceRegionVRPData{
+			date: {
+				OpenHoursTW:   &logisticsdb.TimeWindow{Start: openHoursStart, End: openHoursEnd},
+				BlackoutZones: logisticsdb.BlackoutZones{zone},
+				CheckFeasibilityData: &logisticsdb.CheckFeasibilityVRPDataResult{
+					Visits: []*logisticspb.CheckFeasibilityVisit{
+						{
+							MarketId: proto.Int64(1),
+							Location: &common.Location{LatitudeE6: 5, LongitudeE6: 5},
+							ArrivalTimeSpecification: &logisticspb.CheckFeasibilityVisit_ArrivalDate{
+								ArrivalDate: date,
+							},
+						},
+					},
+					LocIDs: []int64{3},
+				},
+			},
+		},
+		VRPInputByDate: map[*common.Date]*checkfeasibility.SolveVRPInput{date: vrpInput},
+		Duration:       4 * time.Hour,
+		StartTimestamp: openHoursStart,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	twData := twAvailabilitiesByDate[date]
+	// The 8-12 and 9-13 windows are within the zone; the full day, 10-14, 11-15 and 12-16 windows are checked
+	// after it ends, at 13.
+	testutils.MustMatch(t, []int64{-1, -2, -3, -4, -5, -6}, twData.availabilityVisitIDs)
+	testutils.MustMatch(t, []int64{-1, -4, -5, -6}, vrpInput.VRPProblemData.FeasibilityVisitIDs)
+	visits := vrpInput.VRPRequest.Problem.Description.Visits
+	testutils.MustMatch(t, 4, len(visits))
+	for _, visit := range visits {
+		testutils.MustMatch(t, zone.TimeWindow.End.Unix(), visit.GetArrivalTimeWindow().GetStartTimestampSec())
+	}
+	for _, visitID := range twData.availabilityVisitIDs {
+		twa := twData.visitsTWMap[visitID]
+		testutils.MustMatch(t, logisticspb.TimeWindowAvailability_STATUS_UNAVAILABLE, twa.Status)
+		if visitID == -2 || visitID == -3 {
+			testutils.MustMatch(t, zone.ToProto(), twa.BlackoutZone)
+		} else {
+			testutils.MustMatch(t, (*logisticspb.BlackoutZone)(nil), twa.BlackoutZone)
+		}
+	}
+}
+
 func TestTimeWindowAvailability_isFeasibleSolution(t *testing.T) {
 	baseVRPSolution := &optimizerpb.VRPSolution{
 		Score: &optimizerpb.VRPScore{
@@ -0,0 +1,434 @@
+package logistics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/monitoring"
+)
+
+const (
+	valhallaCostingAuto    = "auto"
+	valhallaUnitsKM        = "kilometers"
+	valhallaLocationBreak  = "break"
+	valhallaShapePrecision = 1e6
+
+	metersPerKM = 1000
+)
+
+var (
+	errBadValhallaResponse = errors.New("bad Valhalla response")
+	errValhallaNoRoute     = errors.New("no Valhalla route between locations")
+)
+
+// ValhallaService is a MapService backed by a self-hosted Valhalla routing engine.
+// Ref: https://valhalla.github.io/valhalla/api/.
+type ValhallaService struct {
+	Addr             string
+	HTTPClient       *http.Client
+	DistanceSourceID int64
+	ScopedMetrics    monitoring.Scope
+
+	// Maximum number of elements in each matrix request, matching the Valhalla service limits.
+	// 0 means no limit.
+	MaxMatrixElems int
+}
+
+func NewValhallaService(addr string, distanceSourceID int64, scope monitoring.Scope) *ValhallaService {
+	return &ValhallaService{
+		Addr:             addr,
+		HTTPClient:       http.DefaultClient,
+		DistanceSourceID: distanceSourceID,
+		ScopedMetrics:    scope,
+	}
+}
+
+type valhallaLocation struct {
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+	Type string  `json:"type,omitempty"`
+}
+
+func valhallaLocations(latLngs []LatLng, locationType string) []valhallaLocation {
+	locs := make([]valhallaLocation, len(latLngs))
+	for i, ll := range latLngs {
+		locs[i] = valhallaLocation{
+			Lat:  ll.Latitude(),
+			Lon:  ll.Longitude(),
+			Type: locationType,
+		}
+	}
+
+	return locs
+}
+
+type valhallaMatrixReq struct {
+	Sources []valhallaLocation `json:"sources"`
+	Targets []valhallaLocation `json:"targets"`
+	Costing string             `json:"costing"`
+	Units   string             `json:"units"`
+}
+
+type valhallaMatrixElem struct {
+	DistanceKM *float64 `json:"distance"`
+	TimeSec    *float64 `json:"time"`
+	FromIndex  int      `json:"from_index"`
+	ToIndex    int      `json:"to_index"`
+}
+
+type valhallaMatrixResp struct {
+	SourcesToTargets [][]valhallaMatrixElem `json:"sources_to_targets"`
+}
+
+type valhallaRouteReq struct {
+	Locations []valhallaLocation `json:"locations"`
+	Costing   string             `json:"costing"`
+	Units     string             `json:"units"`
+}
+
+type valhallaSummary struct {
+	LengthKM float64 `json:"length"`
+	TimeSec  float64 `json:"time"`
+}
+
+func (s valhallaSummary) Distance() Distance {
+	return Distance{
+		Duration:     time.Duration(s.TimeSec) * time.Second,
+		LengthMeters: int64(s.LengthKM * metersPerKM),
+	}
+}
+
+type valhallaRouteResp struct {
+	Trip struct {
+		Status  int             `json:"status"`
+		Summary valhallaSummary `json:"summary"`
+		Legs    []struct {
+			Summary valhallaSummary `json:"summary"`
+			Shape   string          `json:"shape"`
+		} `json:"legs"`
+	} `json:"trip"`
+}
+
+type valhallaLocateReq struct {
+	Locations []valhallaLocation `json:"locations"`
+	Costing   string             `json:"costing"`
+}
+
+type valhallaLocateResp []struct {
+	Edges []struct {
+		CorrelatedLat float64 `json:"correlated_lat"`
+		CorrelatedLon float64 `json:"correlated_lon"`
+	} `json:"edges"`
+}
+
+type valhallaErrorResp struct {
+	ErrorCode int    `json:"error_code"`
+	Error     string `json:"error"`
+}
+
+func (s *ValhallaService) url(action string) string {
+	return fmt.Sprintf("%s/%s", s.Addr, action)
+}
+
+// post sends req as JSON to the Valhalla action, and decodes the response into resp.
+func (s *ValhallaService) post(ctx context.Context, action string, req any, resp any) error {
+	buf, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url(action), bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := s.HTTPClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		var errResp valhallaErrorResp
+		_ = json.NewDecoder(httpResp.Body).Decode(&errResp)
+		return fmt.Errorf("%w: status(%d), error_code(%d): %s", errBadValhallaResponse, httpResp.StatusCode, errResp.ErrorCode, errResp.Error)
+	}
+
+	return json.NewDecoder(httpResp.Body).Decode(resp)
+}
+
+// matrixOriginChunks splits origins so that each request has at most MaxMatrixElems elements.
+func (s *ValhallaService) matrixOriginChunks(origins, destinations []LatLng) [][]LatLng {
+	chunkSize := len(origins)
+	if s.MaxMatrixElems > 0 {
+		chunkSize = s.MaxMatrixElems / len(destinations)
+		if chunkSize < 1 {
+			chunkSize = 1
+		}
+	}
+
+	var chunks [][]LatLng
+	for i := 0; i < len(origins); i += chunkSize {
+		chunks = append(chunks, origins[i:min(i+chunkSize, len(origins))])
+	}
+
+	return chunks
+}
+
+func (s *ValhallaService) GetDistanceMatrix(ctx context.Context, mapsTags monitoring.Tags, origins, destinations []LatLng) (DistanceMatrix, error) {
+	startTime := time.Now()
+	if len(origins) == 0 || len(destinations) == 0 {
+		return DistanceMatrix{}, nil
+	}
+
+	chunks := s.matrixOriginChunks(origins, destinations)
+
+	var returnErr error
+	tags := mapsTags.Clone()
+	tags[distanceMatrixTypeTag] = distanceMatrixTypeTagRect
+	defer SendMonitoring(&SendMonitoringParams{
+		Scope: s.ScopedMetrics.With("", tags, monitoring.Fields{
+			numRequestsField: len(chunks),
+			numElementsField: len(origins) * len(destinations),
+		}),
+		StartTime:       startTime,
+		MeasurementName: distanceMatrixMeasurementName,
+		ErrorPtr:        &returnErr,
+	})
+
+	matrix := make(DistanceMatrix, len(origins))
+	for _, chunk := range chunks {
+		var resp valhallaMatrixResp
+		err := s.post(ctx, "sources_to_targets", valhallaMatrixReq{
+			Sources: valhallaLocations(chunk, ""),
+			Targets: valhallaLocations(destinations, ""),
+			Costing: valhallaCostingAuto,
+			Units:   valhallaUnitsKM,
+		}, &resp)
+		if err != nil {
+			returnErr = err
+			return nil, returnErr
+		}
+
+		if len(resp.SourcesToTargets) != len(chunk) {
+			returnErr = fmt.Errorf("%w: unexpected number of sources %d for origins %d", errBadValhallaResponse, len(resp.SourcesToTargets), len(chunk))
+			return nil, returnErr
+		}
+
+		for i, row := range resp.SourcesToTargets {
+			if len(row) != len(destinations) {
+				returnErr = fmt.Errorf("%w: unexpected number of targets %d for destinations %d", errBadValhallaResponse, len(row), len(destinations))
+				return nil, returnErr
+			}
+
+			fromLL := chunk[i]
+			toLatLngMap := make(map[LatLng]Distance, len(destinations))
+			for j, elem := range row {
+				toLL := destinations[j]
+				if elem.DistanceKM == nil || elem.TimeSec == nil {
+					returnErr = fmt.Errorf("%w: from %s to %s", errValhallaNoRoute, fromLL, toLL)
+					return nil, returnErr
+				}
+
+				toLatLngMap[toLL] = valhallaSummary{LengthKM: *elem.DistanceKM, TimeSec: *elem.TimeSec}.Distance()
+			}
+			matrix[fromLL] = toLatLngMap
+		}
+	}
+
+	return matrix.withEnforceDiagonalDistancesAreZero(), nil
+}
+
+func (s *ValhallaService) route(ctx context.Context, latLngs []LatLng) (*valhallaRouteResp, error) {
+	if len(latLngs) < 2 {
+		return nil, errors.New("not enough latlngs")
+	}
+
+	var resp valhallaRouteResp
+	err := s.post(ctx, "route", valhallaRouteReq{
+		Locations: valhallaLocations(latLngs, valhallaLocationBreak),
+		Costing:   valhallaCostingAuto,
+		Units:     valhallaUnitsKM,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Trip.Status != 0 {
+		return nil, fmt.Errorf("%w: trip status(%d)", errBadValhallaResponse, resp.Trip.Status)
+	}
+
+	if len(resp.Trip.Legs) != len(latLngs)-1 {
+		return nil, fmt.Errorf("%w: unexpected number of legs %d for latlngs %d", errBadValhallaResponse, len(resp.Trip.Legs), len(latLngs))
+	}
+
+	return &resp, nil
+}
+
+func (s *ValhallaService) GetRoute(ctx context.Context, mapsTags monitoring.Tags, latLngs ...LatLng) (*Route, error) {
+	startTime := time.Now()
+	var returnErr error
+	defer SendMonitoring(&SendMonitoringParams{
+		Scope: s.ScopedMetrics.With("", mapsTags, monitoring.Fields{
+			numElementsField: len(latLngs) - 1,
+		}),
+		StartTime:       startTime,
+		MeasurementName: getRouteMeasurementName,
+		ErrorPtr:        &returnErr,
+	})
+
+	resp, err := s.route(ctx, latLngs)
+	if err != nil {
+		returnErr = err
+		return nil, returnErr
+	}
+
+	var polyline RoutePolyline
+	legs := make(Legs, len(resp.Trip.Legs))
+	for i, leg := range resp.Trip.Legs {
+		shape, err := decodePolyline(leg.Shape, valhallaShapePrecision)
+		if err != nil {
+			returnErr = err
+			return nil, returnErr
+		}
+
+		// Consecutive legs share their end and start points.
+		if len(polyline) > 0 && len(shape) > 0 && polyline[len(polyline)-1] == shape[0] {
+			shape = shape[1:]
+		}
+		polyline = append(polyline, shape...)
+
+		distance := leg.Summary.Distance()
+		legs[i] = &distance
+	}
+
+	return &Route{
+		Polyline: polyline,
+		Distance: resp.Trip.Summary.Distance(),
+		Legs:     legs,
+	}, nil
+}
+
+func (s *ValhallaService) GetPathDistanceMatrix(ctx context.Context, mapsTags monitoring.Tags, latLngs ...LatLng) (DistanceMatrix, error) {
+	startTime := time.Now()
+
+	if len(latLngs) <= 1 {
+		return DistanceMatrix{}, nil
+	}
+
+	var returnErr error
+	tags := mapsTags.Clone()
+	tags[distanceMatrixTypeTag] = distanceMatrixTypeTagPath
+	defer SendMonitoring(&SendMonitoringParams{
+		Scope: s.ScopedMetrics.With("", tags, monitoring.Fields{
+			numRequestsField: 1,
+			numElementsField: len(latLngs) - 1,
+		}),
+		StartTime:       startTime,
+		MeasurementName: distanceMatrixMeasurementName,
+		ErrorPtr:        &returnErr,
+	})
+
+	resp, err := s.route(ctx, latLngs)
+	if err != nil {
+		returnErr = err
+		return nil, returnErr
+	}
+
+	m := make(DistanceMatrix, len(latLngs))
+	for _, ll := range latLngs {
+		m[ll] = make(map[LatLng]Distance)
+	}
+	for i, leg := range resp.Trip.Legs {
+		m[latLngs[i]][latLngs[i+1]] = leg.Summary.Distance()
+	}
+
+	return m.withEnforceDiagonalDistancesAreZero(), nil
+}
+
+func (s *ValhallaService) GetNearestWaypoint(ctx context.Context, latLng LatLng) (*LatLng, error) {
+	var resp valhallaLocateResp
+	err := s.post(ctx, "locate", valhallaLocateReq{
+		Locations: valhallaLocations([]LatLng{latLng}, ""),
+		Costing:   valhallaCostingAuto,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp) == 0 || len(resp[0].Edges) == 0 {
+		return nil, errors.New("no waypoints")
+	}
+
+	edge := resp[0].Edges[0]
+	waypointLatLng := NewLatLng(edge.CorrelatedLat, edge.CorrelatedLon)
+
+	return &waypointLatLng, nil
+}
+
+func (s *ValhallaService) IsHealthy(ctx context.Context) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url("status"), nil)
+	if err != nil {
+		return false
+	}
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+func (s *ValhallaService) GetDistanceSourceID() int64 {
+	return s.DistanceSourceID
+}
+
+// decodePolyline decodes an encoded polyline with the given precision,
+// for example 1e5 for Google Maps polylines, and 1e6 for Valhalla shapes.
+// Ref: https://developers.google.com/maps/documentation/utilities/polylinealgorithm.
+func decodePolyline(encoded string, precision float64) (RoutePolyline, error) {
+	var polyline RoutePolyline
+	var lat, lng int64
+	for i := 0; i < len(encoded); {
+		var deltas [2]int64
+		for d := range deltas {
+			var result int64
+			var shift uint
+			for {
+				if i >= len(encoded) {
+					return nil, errors.New("truncated polyline")
+				}
+				b := int64(encoded[i]) - 63
+				i++
+				result |= (b & 0x1f) << shift
+				shift += 5
+				if b < 0x20 {
+					break
+				}
+			}
+
+			if result&1 != 0 {
+				deltas[d] = ^(result >> 1)
+			} else {
+				deltas[d] = result >> 1
+			}
+		}
+
+		lat += deltas[0]
+		lng += deltas[1]
+		polyline = append(polyline, LatLng{
+			LatE6: int32(math.Round(float64(lat) * E6 / precision)),
+			LngE6: int32(math.Round(float64(lng) * E6 / precision)),
+		})
+	}
+
+	return polyline, nil
+}
@@ -0,0 +1,366 @@
+package logistics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/monitoring"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+)
+
+func valhallaFloat(f float64) *float64 {
+	return &f
+}
+
+func TestValhalla_GetDistanceMatrix(t *testing.T) {
+	ctx := context.Background()
+
+	ll1 := NewLatLng(1, 2)
+	ll2 := NewLatLng(3, 4)
+	ll3 := NewLatLng(5, 6)
+
+	tcs := []struct {
+		Desc           string
+		Origins        []LatLng
+		Destinations   []LatLng
+		MaxMatrixElems int
+		Elem           valhallaMatrixElem
+
+		Want         DistanceMatrix
+		WantRequests int
+		HasErr       bool
+	}{
+		{
+			Desc: "Nothing",
+			Want: DistanceMatrix{},
+		},
+		{
+			Desc:         "1 origin 1 destination",
+			Origins:      []LatLng{ll1},
+			Destinations: []LatLng{ll2},
+			Elem: valhallaMatrixElem{
+				DistanceKM: valhallaFloat(1.5),
+				TimeSec:    valhallaFloat(10),
+			},
+
+			Want: DistanceMatrix{
+				ll1: map[LatLng]Distance{
+					ll2: {
+						Duration:     10 * time.Second,
+						LengthMeters: 1500,
+					},
+				},
+			},
+			WantRequests: 1,
+		},
+		{
+			Desc:           "chunked origins",
+			Origins:        []LatLng{ll1, ll2},
+			Destinations:   []LatLng{ll3},
+			MaxMatrixElems: 1,
+			Elem: valhallaMatrixElem{
+				DistanceKM: valhallaFloat(2),
+				TimeSec:    valhallaFloat(20),
+			},
+
+			Want: DistanceMatrix{
+				ll1: map[LatLng]Distance{
+					ll3: {
+						Duration:     20 * time.Second,
+						LengthMeters: 2000,
+					},
+				},
+				ll2: map[LatLng]Distance{
+					ll3: {
+						Duration:     20 * time.Second,
+						LengthMeters: 2000,
+					},
+				},
+			},
+			WantRequests: 2,
+		},
+		{
+			Desc:         "no route",
+			Origins:      []LatLng{ll1},
+			Destinations: []LatLng{ll2},
+
+			WantRequests: 1,
+			HasErr:       true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			var numRequests int
+			mockValhalla := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				numRequests++
+				var req valhallaMatrixReq
+				_ = json.NewDecoder(r.Body).Decode(&req)
+
+				resp := valhallaMatrixResp{
+					SourcesToTargets: make([][]valhallaMatrixElem, len(req.Sources)),
+				}
+				for i := range req.Sources {
+					for range req.Targets {
+						resp.SourcesToTargets[i] = append(resp.SourcesToTargets[i], tc.Elem)
+					}
+				}
+				buf, _ := json.Marshal(resp)
+				w.Write(buf)
+			}))
+			defer mockValhalla.Close()
+
+			s := NewValhallaService(mockValhalla.URL, 1, &monitoring.NoopScope{})
+			s.MaxMatrixElems = tc.MaxMatrixElems
+			dm, err := s.GetDistanceMatrix(ctx, nil, tc.Origins, tc.Destinations)
+			if (err != nil) != tc.HasErr {
+				t.Fatal(err)
+			}
+			testutils.MustMatch(t, tc.WantRequests, numRequests)
+			if tc.HasErr {
+				return
+			}
+			testutils.MustMatch(t, tc.Want, dm)
+		})
+	}
+}
+
+func TestValhalla_GetPathDistanceMatrix(t *testing.T) {
+	ctx := context.Background()
+
+	ll1 := NewLatLng(1, 2)
+	ll2 := NewLatLng(3, 4)
+
+	tcs := []struct {
+		Desc string
+		Path []LatLng
+		Resp string
+
+		Want   DistanceMatrix
+		HasErr bool
+	}{
+		{
+			Desc: "Nothing",
+			Want: DistanceMatrix{},
+		},
+		{
+			Desc: "1 elem",
+			Path: []LatLng{ll1},
+			Want: DistanceMatrix{},
+		},
+		{
+			Desc: "2 elem",
+			Path: []LatLng{ll1, ll2},
+			Resp: `{"trip":{"status":0,"legs":[{"summary":{"length":1.2,"time":30}}]}}`,
+
+			Want: DistanceMatrix{
+				ll1: map[LatLng]Distance{
+					ll2: {
+						LengthMeters: 1200,
+						Duration:     30 * time.Second,
+					},
+				},
+				ll2: {},
+			},
+		},
+		{
+			Desc: "unexpected number of legs",
+			Path: []LatLng{ll1, ll2},
+			Resp: `{"trip":{"status":0,"legs":[]}}`,
+
+			HasErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			mockValhalla := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tc.Resp))
+			}))
+			defer mockValhalla.Close()
+
+			s := NewValhallaService(mockValhalla.URL, 1, &monitoring.NoopScope{})
+			dm, err := s.GetPathDistanceMatrix(ctx, nil, tc.Path...)
+			if (err != nil) != tc.HasErr {
+				t.Fatal(err)
+			}
+			if tc.HasErr {
+				return
+			}
+			testutils.MustMatch(t, tc.Want, dm)
+		})
+	}
+}
+
+func TestValhalla_GetRoute(t *testing.T) {
+	mockValhalla := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Shapes encoded with precision 1e6.
+		w.Write([]byte(`{"trip":{"status":0,"summary":{"length":3,"time":300},"legs":[` +
+			"{\"summary\":{\"length\":1,\"time\":100},\"shape\":\"_c`|@_gayB_c`|@_c`|@\"}," +
+			"{\"summary\":{\"length\":2,\"time\":200},\"shape\":\"_gayB_kbvD_c`|@_c`|@\"}]}}"))
+	}))
+	defer mockValhalla.Close()
+
+	s := NewValhallaService(mockValhalla.URL, 1, &monitoring.NoopScope{})
+	route, err := s.GetRoute(context.Background(), nil, NewLatLng(1, 2), NewLatLng(2, 3), NewLatLng(3, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testutils.MustMatch(t, &Route{
+		Polyline: RoutePolyline{
+			NewLatLng(1, 2),
+			NewLatLng(2, 3),
+			NewLatLng(3, 4),
+		},
+		Distance: Distance{
+			Duration:     300 * time.Second,
+			LengthMeters: 3000,
+		},
+		Legs: Legs{
+			{
+				Duration:     100 * time.Second,
+				LengthMeters: 1000,
+			},
+			{
+				Duration:     200 * time.Second,
+				LengthMeters: 2000,
+			},
+		},
+	}, route)
+}
+
+func TestValhalla_GetNearestWaypoint(t *testing.T) {
+	tcs := []struct {
+		Desc string
+		Resp string
+
+		Want   *LatLng
+		HasErr bool
+	}{
+		{
+			Desc: "Base case",
+			Resp: `[{"edges":[{"correlated_lat":1.5,"correlated_lon":2.5},{"correlated_lat":3,"correlated_lon":4}]}]`,
+
+			Want: &LatLng{LatE6: 1500000, LngE6: 2500000},
+		},
+		{
+			Desc: "no edges",
+			Resp: `[{"edges":[]}]`,
+
+			HasErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			mockValhalla := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tc.Resp))
+			}))
+			defer mockValhalla.Close()
+
+			s := NewValhallaService(mockValhalla.URL, 1, &monitoring.NoopScope{})
+			ll, err := s.GetNearestWaypoint(context.Background(), NewLatLng(1, 2))
+			if (err != nil) != tc.HasErr {
+				t.Fatal(err)
+			}
+			testutils.MustMatch(t, tc.Want, ll)
+		})
+	}
+}
+
+func TestValhalla_ErrorResponse(t *testing.T) {
+	mockValhalla := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error_code":171,"error":"No suitable edges near location"}`))
+	}))
+	defer mockValhalla.Close()
+
+	s := NewValhallaService(mockValhalla.URL, 1, &monitoring.NoopScope{})
+	_, err := s.GetDistanceMatrix(context.Background(), nil, []LatLng{NewLatLng(1, 2)}, []LatLng{NewLatLng(3, 4)})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestValhalla_IsHealthy(t *testing.T) {
+	tcs := []struct {
+		Desc       string
+		StatusCode int
+
+		Want bool
+	}{
+		{
+			Desc:       "Base case",
+			StatusCode: http.StatusOK,
+
+			Want: true,
+		},
+		{
+			Desc:       "unhealthy",
+			StatusCode: http.StatusServiceUnavailable,
+
+			Want: false,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			mockValhalla := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.StatusCode)
+			}))
+			defer mockValhalla.Close()
+
+			s := NewValhallaService(mockValhalla.URL, 1, &monitoring.NoopScope{})
+			testutils.MustMatch(t, tc.Want, s.IsHealthy(context.Background()))
+		})
+	}
+}
+
+func TestDecodePolyline(t *testing.T) {
+	tcs := []struct {
+		Desc      string
+		Encoded   string
+		Precision float64
+
+		Want   RoutePolyline
+		HasErr bool
+	}{
+		{
+			Desc:      "Google reference polyline",
+			Encoded:   "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+			Precision: 1e5,
+
+			Want: RoutePolyline{
+				{LatE6: 38500000, LngE6: -120200000},
+				{LatE6: 40700000, LngE6: -120950000},
+				{LatE6: 43252000, LngE6: -126453000},
+			},
+		},
+		{
+			Desc:      "empty",
+			Precision: 1e6,
+		},
+		{
+			Desc:      "truncated",
+			Encoded:   "_p~iF",
+			Precision: 1e5,
+
+			HasErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			polyline, err := decodePolyline(tc.Encoded, tc.Precision)
+			if (err != nil) != tc.HasErr {
+				t.Fatal(err)
+			}
+			testutils.MustMatch(t, tc.Want, polyline)
+		})
+	}
+}
@@ -26,6 +26,37 @@ type Error struct {
 	Recoverable bool
 	// Fields are additional fields for metrics reporting -- e.g. the invalid shift_team_id.
 	Fields monitoring.Fields
+	// Repairs fix the error in repair mode, allowing the optimization run to proceed
+	// even if the error is not recoverable.
+	Repairs []*Repair
+}
+
+// Repair is a fix for a validation error, such as dropping an invalid visit as unassignable.
+type Repair struct {
+	// Description is a descriptive message about the fix, for the repair report.
+	Description string
+	// Apply mutates the problem to fix the error.
+	Apply func(problem *optimizerpb.VRPProblem)
+	// DroppedVisitID is the ID of the visit dropped from the problem by the repair, if any.
+	// Dropped visits are not in the problem, so they are surfaced as unassignable from the repair report.
+	DroppedVisitID *int64
+}
+
+// RepairRecord is a repair applied to a problem in repair mode.
+type RepairRecord struct {
+	// ErrorName is the Name of the error that was repaired.
+	ErrorName string
+	// ErrorMsg is the Msg of the error that was repaired.
+	ErrorMsg string
+	// Description is the Description of the applied repair.
+	Description string
+	// DroppedVisitID is the DroppedVisitID of the applied repair.
+	DroppedVisitID *int64
+}
+
+// RepairReport is the report of the repairs applied to a problem in repair mode.
+type RepairReport struct {
+	Repairs []*RepairRecord
 }
 
 // Error implements error.Error.
@@ -39,6 +70,10 @@ type Config struct {
 	// Should likely be true in dev, but false in prod in order to protect our system's healthy operations.
 	FailOnRecoverableError bool
 
+	// RepairMode configures whether errors with repairs are fixed-forward by applying the repairs,
+	// instead of bubbling up to the caller as an invalid problem.
+	RepairMode bool
+
 	// ProblemValidators to run on the problem.
 	ProblemValidators []ProblemValidator
 }
@@ -59,13 +94,36 @@ func NewValidator(sc monitoring.Scope, cfg Config) *Validator {
 
 // Validate validates a problem;  fixing-forward recoverable errors when encountered.
 func (v *Validator) Validate(problem *optimizerpb.VRPProblem) error {
+	_, err := v.ValidateAndRepair(problem)
+	return err
+}
+
+// ValidateAndRepair validates a problem; fixing-forward recoverable errors when encountered,
+// and applying the repairs of other errors in repair mode.
+// Returns the report of the applied repairs.
+func (v *Validator) ValidateAndRepair(problem *optimizerpb.VRPProblem) (*RepairReport, error) {
+	report := &RepairReport{}
 	for _, validator := range v.config.ProblemValidators {
 		if err := validator(problem); err != nil {
 			v.scope.WritePoint("error", monitoring.Tags{"name": err.Name, "recoverable": fmt.Sprintf("%t", err.Recoverable)}, err.Fields)
-			if !err.Recoverable || v.config.FailOnRecoverableError {
-				return err
+			if err.Recoverable && !v.config.FailOnRecoverableError {
+				continue
+			}
+			if !v.config.RepairMode || len(err.Repairs) == 0 {
+				return nil, err
+			}
+
+			for _, repair := range err.Repairs {
+				repair.Apply(problem)
+				report.Repairs = append(report.Repairs, &RepairRecord{
+					ErrorName:      err.Name,
+					ErrorMsg:       err.Msg,
+					Description:    repair.Description,
+					DroppedVisitID: repair.DroppedVisitID,
+				})
 			}
+			v.scope.WritePoint("repair", monitoring.Tags{"name": err.Name}, monitoring.Fields{"count": len(err.Repairs)})
 		}
 	}
-	return nil
+	return report, nil
 }
@@ -53,3 +53,91 @@ func TestValidator(t *testing.T) {
 		})
 	}
 }
+
+func TestValidator_ValidateAndRepair(t *testing.T) {
+	validatorRepairableErr := func(problem *optimizerpb.VRPProblem) *Error {
+		if len(problem.GetDescription().GetVisits()) == 0 {
+			return nil
+		}
+		return &Error{
+			Name:        "repairable_error",
+			Msg:         "repairable error",
+			Recoverable: false,
+			Repairs: []*Repair{
+				{
+					Description: "dropped visits",
+					Apply: func(problem *optimizerpb.VRPProblem) {
+						problem.Description.Visits = nil
+					},
+				},
+			},
+		}
+	}
+
+	tcs := []struct {
+		Desc              string
+		Validators        []ProblemValidator
+		FailOnRecoverable bool
+		RepairMode        bool
+
+		HasError       bool
+		ExpectedReport *RepairReport
+		ExpectedVisits int
+	}{
+		{
+			Desc:       "repairs applied in repair mode",
+			Validators: []ProblemValidator{validatorRepairableErr, validatorRepairableErr, validatorSuccessful},
+			RepairMode: true,
+
+			ExpectedReport: &RepairReport{
+				Repairs: []*RepairRecord{
+					{ErrorName: "repairable_error", ErrorMsg: "repairable error", Description: "dropped visits"},
+				},
+			},
+		},
+		{
+			Desc:       "repairs not applied without repair mode",
+			Validators: []ProblemValidator{validatorRepairableErr},
+
+			HasError:       true,
+			ExpectedVisits: 1,
+		},
+		{
+			Desc:       "non-recoverable error without repairs fails in repair mode",
+			Validators: []ProblemValidator{validatorNonRecoverableErr},
+			RepairMode: true,
+
+			HasError:       true,
+			ExpectedVisits: 1,
+		},
+		{
+			Desc:              "recoverable error with fail and without repairs fails in repair mode",
+			Validators:        []ProblemValidator{validatorRecoverableErr},
+			FailOnRecoverable: true,
+			RepairMode:        true,
+
+			HasError:       true,
+			ExpectedVisits: 1,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			v := &Validator{
+				config: Config{
+					FailOnRecoverableError: tc.FailOnRecoverable,
+					RepairMode:             tc.RepairMode,
+					ProblemValidators:      tc.Validators,
+				},
+				scope: monitoring.NewMockScope(),
+			}
+			problem := &optimizerpb.VRPProblem{Description: &optimizerpb.VRPDescription{
+				Visits: []*optimizerpb.VRPVisit{{}},
+			}}
+
+			report, err := v.ValidateAndRepair(problem)
+			testutils.MustMatch(t, true, tc.HasError == (err != nil), fmt.Sprintf("%v", err))
+			testutils.MustMatch(t, tc.ExpectedReport, report)
+			testutils.MustMatch(t, tc.ExpectedVisits, len(problem.GetDescription().GetVisits()))
+		})
+	}
+}
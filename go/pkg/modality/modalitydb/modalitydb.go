@@ -3,18 +3,28 @@ package modalitydb
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	"github.com/jackc/pgx/v4"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/*company-data-covered*/services/go/pkg/basedb"
+	"github.com/*company-data-covered*/services/go/pkg/eventstreaming"
+	modalitypb "github.com/*company-data-covered*/services/go/pkg/generated/proto/modality"
 	modalitysql "github.com/*company-data-covered*/services/go/pkg/generated/sql/modality"
 	"github.com/*company-data-covered*/services/go/pkg/monitoring"
+	"github.com/*company-data-covered*/services/go/pkg/outbox"
 )
 
+// DefaultConfigsChangedTopic is the topic ModalityConfigsChangedEvent messages are published to by default.
+const DefaultConfigsChangedTopic = "dev.modality.configs-changed"
+
 type ModalityDB struct {
 	db      basedb.DBTX
 	scope   monitoring.Scope
 	queries *modalitysql.Queries
+
+	configsChangedTopic string
 }
 
 func NewModalityDB(db basedb.DBTX, scope monitoring.Scope) *ModalityDB {
@@ -22,9 +32,17 @@ func NewModalityDB(db basedb.DBTX, scope monitoring.Scope) *ModalityDB {
 		db:      db,
 		scope:   scope,
 		queries: modalitysql.New(db),
+
+		configsChangedTopic: DefaultConfigsChangedTopic,
 	}
 }
 
+// WithConfigsChangedTopic sets the topic ModalityConfigsChangedEvent messages are published to.
+func (mdb *ModalityDB) WithConfigsChangedTopic(topic string) *ModalityDB {
+	mdb.configsChangedTopic = topic
+	return mdb
+}
+
 type Modality struct {
 	ID           int64
 	DisplayName  string
@@ -147,7 +165,7 @@ func (mdb *ModalityDB) UpdateModalityConfigs(ctx context.Context, params UpdateM
 		if err != nil {
 			return err
 		}
-		return nil
+		return mdb.writeConfigsChangedEvent(ctx, tx, params.ServiceLineID)
 	})
 	if err != nil {
 		return []*ModalityConfig{}, err
@@ -155,6 +173,26 @@ func (mdb *ModalityDB) UpdateModalityConfigs(ctx context.Context, params UpdateM
 	return mdb.GetModalityConfigsByServiceLineID(ctx, params.ServiceLineID)
 }
 
+// writeConfigsChangedEvent writes a ModalityConfigsChangedEvent to the outbox, keyed by service line
+// so that the events of a service line are consumed in order.
+func (mdb *ModalityDB) writeConfigsChangedEvent(ctx context.Context, tx pgx.Tx, serviceLineID int64) error {
+	event := &modalitypb.ModalityConfigsChangedEvent{ServiceLineId: serviceLineID}
+	value, err := proto.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error marshaling modality configs changed event: %w", err)
+	}
+
+	return outbox.Write(ctx, tx, &eventstreaming.ProducerMessage{
+		Topic: mdb.configsChangedTopic,
+		Key:   []byte(strconv.FormatInt(serviceLineID, 10)),
+		Value: value,
+		Headers: map[string]string{
+			eventstreaming.SchemaHeader:        string(event.ProtoReflect().Descriptor().FullName()),
+			eventstreaming.SchemaVersionHeader: "1",
+		},
+	})
+}
+
 type UpdateMarketModalityConfigsParams struct {
 	ServiceLineID int64
 	Configs       []*MarketModalityConfig
@@ -7,14 +7,18 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/*company-data-covered*/services/go/pkg/basedb"
+	"github.com/*company-data-covered*/services/go/pkg/eventstreaming"
+	modalitypb "github.com/*company-data-covered*/services/go/pkg/generated/proto/modality"
 	modalitysql "github.com/*company-data-covered*/services/go/pkg/generated/sql/modality"
 	"github.com/*company-data-covered*/services/go/pkg/sqltypes"
 	"github.com/*company-data-covered*/services/go/pkg/testutils"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"google.golang.org/protobuf/proto"
 )
 
 const (
@@ -395,6 +399,38 @@ func TestUpdateModalityConfigs(t *testing.T) {
 	}
 }
 
+func TestUpdateModalityConfigs_WritesConfigsChangedEvent(t *testing.T) {
+	ctx, db, _, done := setupDBTest(t)
+	defer done()
+	serviceLineID := time.Now().UnixNano()
+	topic := fmt.Sprintf("test.modality.configs-changed.%d", serviceLineID)
+
+	modalityDB := NewModalityDB(db, nil).WithConfigsChangedTopic(topic)
+	_, err := modalityDB.UpdateModalityConfigs(ctx, UpdateModalityConfigsParams{
+		ServiceLineID: serviceLineID,
+		Configs:       generateModalityConfigs(2, serviceLineID),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var key, value []byte
+	var headers map[string]string
+	err = db.QueryRow(ctx, `SELECT key, value, headers FROM outbox_events WHERE topic = $1`, topic).Scan(&key, &value, &headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var event modalitypb.ModalityConfigsChangedEvent
+	err = proto.Unmarshal(value, &event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutils.MustMatch(t, strconv.FormatInt(serviceLineID, 10), string(key), "event key should be the service line")
+	testutils.MustMatch(t, serviceLineID, event.ServiceLineId, "event should be for the updated service line")
+	testutils.MustMatch(t, "modality.ModalityConfigsChangedEvent", headers[eventstreaming.SchemaHeader], "event should have schema header")
+}
+
 func TestUpdateMarketModalityConfigs(t *testing.T) {
 	ctx, db, queries, done := setupDBTest(t)
 	defer done()
@@ -0,0 +1,57 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/eventstreaming"
+	"github.com/jackc/pgconn"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+// EventIDHeader is the header with the outbox event ID of relayed messages.
+// Events are relayed at least once, so consumers can use it to skip duplicates.
+const EventIDHeader = "x-outbox-event-id"
+
+const (
+	insertEventSQL = `INSERT INTO outbox_events (topic, key, value, headers) VALUES ($1, $2, $3, $4)`
+
+	deletePublishedEventsSQL = `DELETE FROM outbox_events WHERE published_at < $1`
+)
+
+// Execer executes SQL statements, such as a pgx.Tx or a basedb.DBTX.
+type Execer interface {
+	Exec(context.Context, string, ...any) (pgconn.CommandTag, error)
+}
+
+// Write writes the message to the outbox, to be published by a Relay.
+// tx should be the transaction of the business change the message is about,
+// so that the message is only published if the change is committed.
+//
+// The trace of ctx is continued when the message is published.
+func Write(ctx context.Context, tx Execer, message *eventstreaming.ProducerMessage) error {
+	headers := make(map[string]string, len(message.Headers))
+	for k, v := range message.Headers {
+		headers[k] = v
+	}
+	if span, ok := tracer.SpanFromContext(ctx); ok {
+		// Injection only fails for invalid span contexts, which are not worth failing the business change for.
+		_ = tracer.Inject(span.Context(), tracer.TextMapCarrier(headers))
+	}
+
+	_, err := tx.Exec(ctx, insertEventSQL, message.Topic, message.Key, message.Value, headers)
+	if err != nil {
+		return fmt.Errorf("error writing outbox event for %s topic: %w", message.Topic, err)
+	}
+	return nil
+}
+
+// DeletePublishedBefore deletes events published before the given time, returning the number of deleted events.
+func DeletePublishedBefore(ctx context.Context, db Execer, before time.Time) (int64, error) {
+	tag, err := db.Exec(ctx, deletePublishedEventsSQL, before)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting published outbox events: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
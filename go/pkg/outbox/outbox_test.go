@@ -0,0 +1,57 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/eventstreaming"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"github.com/jackc/pgconn"
+)
+
+type mockExecer struct {
+	sql  string
+	args []any
+	tag  pgconn.CommandTag
+	err  error
+}
+
+func (e *mockExecer) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	e.sql = sql
+	e.args = args
+	return e.tag, e.err
+}
+
+func TestWrite(t *testing.T) {
+	headers := map[string]string{"correlation-id": "abc"}
+	message := &eventstreaming.ProducerMessage{
+		Topic:   "topic-1",
+		Key:     []byte("patient-1"),
+		Value:   []byte("value"),
+		Headers: headers,
+	}
+
+	tx := &mockExecer{}
+	err := Write(context.Background(), tx, message)
+
+	testutils.MustMatch(t, nil, err)
+	testutils.MustMatch(t, insertEventSQL, tx.sql)
+	testutils.MustMatch(t, []any{"topic-1", []byte("patient-1"), []byte("value"), headers}, tx.args)
+
+	tx = &mockExecer{err: errors.New("boo")}
+	err = Write(context.Background(), tx, message)
+	testutils.MustMatch(t, "error writing outbox event for topic-1 topic: boo", err.Error())
+}
+
+func TestDeletePublishedBefore(t *testing.T) {
+	before := time.Date(2022, 10, 3, 0, 0, 0, 0, time.UTC)
+	db := &mockExecer{tag: pgconn.CommandTag("DELETE 3")}
+
+	deleted, err := DeletePublishedBefore(context.Background(), db, before)
+
+	testutils.MustMatch(t, nil, err)
+	testutils.MustMatch(t, int64(3), deleted)
+	testutils.MustMatch(t, []any{before}, db.args)
+}
@@ -0,0 +1,314 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/eventstreaming"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"go.uber.org/zap"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+const (
+	defaultBatchSize    = 100
+	defaultPollInterval = time.Second
+	defaultMaxBackoff   = time.Minute
+
+	// relayLockID is the key of the advisory lock held while relaying, so that replicas publish events in order.
+	relayLockID = 7_240_318_554
+
+	relaySpanName = "outbox.relay"
+
+	tryRelayLockSQL = `SELECT pg_try_advisory_xact_lock($1)`
+
+	// pendingEventsSQL only selects events of transactions older than all running transactions,
+	// as ids are assigned before commit, so a running transaction may still commit events with lower ids.
+	pendingEventsSQL = `
+SELECT id, topic, key, value, headers
+FROM outbox_events
+WHERE published_at IS NULL AND xact_id < pg_snapshot_xmin(pg_current_snapshot())
+ORDER BY xact_id, id
+LIMIT $1`
+
+	markEventPublishedSQL = `UPDATE outbox_events SET published_at = CURRENT_TIMESTAMP WHERE id = $1 AND published_at IS NULL`
+
+	markEventFailedSQL = `UPDATE outbox_events SET attempts = attempts + 1, last_error = $2 WHERE id = $1 AND published_at IS NULL`
+)
+
+// DB begins transactions, such as a basedb.DBTX.
+type DB interface {
+	BeginFunc(ctx context.Context, f func(pgx.Tx) error) error
+}
+
+// RelayConfig configures how a Relay polls the outbox.
+type RelayConfig struct {
+	// BatchSize is the max number of events published per transaction. 0 defaults to 100.
+	BatchSize int
+
+	// PollInterval is the wait between polls once the outbox is drained. 0 defaults to 1s.
+	PollInterval time.Duration
+
+	// MaxBackoff caps the wait before retrying after a failure, which doubles from PollInterval. 0 defaults to 1m.
+	MaxBackoff time.Duration
+}
+
+type RelayParams struct {
+	Config RelayConfig
+	DB     DB
+
+	// Producer publishes the events. It should be a sync producer, as async producers do not return errors,
+	// so events would be marked as published even if they failed.
+	Producer eventstreaming.MessageProducer
+	Logger   *zap.SugaredLogger
+}
+
+// Relay publishes pending outbox events to Kafka, in the order of the transactions they were written in.
+// Events are only published once the transactions started before theirs are done, so that none are skipped.
+// A failed event is retried, blocking the events after it, so that their order is kept.
+type Relay struct {
+	db       DB
+	producer eventstreaming.MessageProducer
+	logger   *zap.SugaredLogger
+
+	batchSize    int
+	pollInterval time.Duration
+	maxBackoff   time.Duration
+}
+
+func NewRelay(params RelayParams) *Relay {
+	config := params.Config
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultBatchSize
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultPollInterval
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultMaxBackoff
+	}
+
+	logger := params.Logger
+	if logger == nil {
+		logger = zap.NewNop().Sugar()
+	}
+
+	return &Relay{
+		db:           params.DB,
+		producer:     params.Producer,
+		logger:       logger,
+		batchSize:    config.BatchSize,
+		pollInterval: config.PollInterval,
+		maxBackoff:   config.MaxBackoff,
+	}
+}
+
+// Start relays events in the background until ctx is done.
+func (r *Relay) Start(ctx context.Context) {
+	go r.run(ctx)
+}
+
+func (r *Relay) run(ctx context.Context) {
+	failures := 0
+	for {
+		published, err := r.RelayPending(ctx)
+		wait := r.pollInterval
+		switch {
+		case err != nil:
+			failures++
+			wait = r.backoff(failures)
+			r.logger.Errorw("failed to relay outbox events",
+				"published", published,
+				"failures", failures,
+				"retry_in", wait,
+				zap.Error(err))
+		case published == r.batchSize:
+			// More events may be pending.
+			failures = 0
+			wait = 0
+		default:
+			failures = 0
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the wait before retrying after consecutive failures.
+func (r *Relay) backoff(failures int) time.Duration {
+	backoff := r.pollInterval
+	for i := 1; i < failures; i++ {
+		backoff *= 2
+		if backoff >= r.maxBackoff {
+			return r.maxBackoff
+		}
+	}
+	return backoff
+}
+
+// RelayPending publishes a batch of pending events, returning the number of events published.
+// Nothing is published if another relay holds the outbox lock.
+func (r *Relay) RelayPending(ctx context.Context) (int, error) {
+	var published int
+	var publishErr *publishError
+	err := r.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var err error
+		published, err = r.relayBatch(ctx, &pgBatchStore{db: tx})
+		if errors.As(err, &publishErr) {
+			// Commit the marks of the events published before the failed one.
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error relaying outbox events: %w", err)
+	}
+	if publishErr != nil {
+		return published, publishErr
+	}
+	return published, nil
+}
+
+// publishError is returned for an event that failed to publish, after it was marked as failed.
+type publishError struct {
+	id  int64
+	err error
+}
+
+func (e *publishError) Error() string {
+	return fmt.Sprintf("error publishing outbox event %d: %v", e.id, e.err)
+}
+
+func (e *publishError) Unwrap() error {
+	return e.err
+}
+
+// relayBatch publishes pending events in order until one fails to publish, returning the number of events published.
+func (r *Relay) relayBatch(ctx context.Context, store batchStore) (int, error) {
+	locked, err := store.tryLock(ctx)
+	if err != nil || !locked {
+		return 0, err
+	}
+
+	events, err := store.pendingEvents(ctx, r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, e := range events {
+		if err := r.publish(ctx, e); err != nil {
+			if markErr := store.markFailed(ctx, e.id, err); markErr != nil {
+				return i, markErr
+			}
+			return i, &publishError{id: e.id, err: err}
+		}
+
+		if err := store.markPublished(ctx, e.id); err != nil {
+			return i, err
+		}
+	}
+	return len(events), nil
+}
+
+func (r *Relay) publish(ctx context.Context, e *event) error {
+	opts := []tracer.StartSpanOption{tracer.ResourceName("Relay Topic " + e.topic)}
+	if spanCtx, err := tracer.Extract(tracer.TextMapCarrier(e.headers)); err == nil {
+		opts = append(opts, tracer.ChildOf(spanCtx))
+	}
+	span, ctx := tracer.StartSpanFromContext(ctx, relaySpanName, opts...)
+
+	headers := make(map[string]string, len(e.headers)+1)
+	for k, v := range e.headers {
+		headers[k] = v
+	}
+	headers[EventIDHeader] = strconv.FormatInt(e.id, 10)
+
+	err := r.producer.SendMessage(ctx, &eventstreaming.ProducerMessage{
+		Topic:   e.topic,
+		Key:     e.key,
+		Value:   e.value,
+		Headers: headers,
+	})
+	span.Finish(tracer.WithError(err))
+	return err
+}
+
+// event is a pending outbox event.
+type event struct {
+	id      int64
+	topic   string
+	key     []byte
+	value   []byte
+	headers map[string]string
+}
+
+// batchStore reads and marks the events of a relay batch, within a transaction.
+type batchStore interface {
+	tryLock(ctx context.Context) (bool, error)
+	pendingEvents(ctx context.Context, limit int) ([]*event, error)
+	markPublished(ctx context.Context, id int64) error
+	markFailed(ctx context.Context, id int64, err error) error
+}
+
+type querier interface {
+	Exec(context.Context, string, ...any) (pgconn.CommandTag, error)
+	Query(context.Context, string, ...any) (pgx.Rows, error)
+	QueryRow(context.Context, string, ...any) pgx.Row
+}
+
+type pgBatchStore struct {
+	db querier
+}
+
+func (s *pgBatchStore) tryLock(ctx context.Context) (bool, error) {
+	var locked bool
+	if err := s.db.QueryRow(ctx, tryRelayLockSQL, relayLockID).Scan(&locked); err != nil {
+		return false, fmt.Errorf("error locking outbox: %w", err)
+	}
+	return locked, nil
+}
+
+func (s *pgBatchStore) pendingEvents(ctx context.Context, limit int) ([]*event, error) {
+	rows, err := s.db.Query(ctx, pendingEventsSQL, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying pending outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*event
+	for rows.Next() {
+		e := &event{}
+		if err := rows.Scan(&e.id, &e.topic, &e.key, &e.value, &e.headers); err != nil {
+			return nil, fmt.Errorf("error scanning pending outbox event: %w", err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading pending outbox events: %w", err)
+	}
+	return events, nil
+}
+
+func (s *pgBatchStore) markPublished(ctx context.Context, id int64) error {
+	if _, err := s.db.Exec(ctx, markEventPublishedSQL, id); err != nil {
+		return fmt.Errorf("error marking outbox event %d as published: %w", id, err)
+	}
+	return nil
+}
+
+func (s *pgBatchStore) markFailed(ctx context.Context, id int64, err error) error {
+	if _, execErr := s.db.Exec(ctx, markEventFailedSQL, id, err.Error()); execErr != nil {
+		return fmt.Errorf("error marking outbox event %d as failed: %w", id, execErr)
+	}
+	return nil
+}
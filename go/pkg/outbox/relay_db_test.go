@@ -0,0 +1,108 @@
+//go:build db_test
+
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/eventstreaming"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// testDBName is the default database of Postgres servers, as the outbox table is created in a test schema.
+const testDBName = "postgres"
+
+const createTestOutboxEventsTableSQL = `
+CREATE TABLE outbox_events (
+	id BIGSERIAL PRIMARY KEY,
+	topic TEXT NOT NULL,
+	key BYTEA,
+	value BYTEA NOT NULL,
+	headers JSONB NOT NULL DEFAULT '{}',
+	xact_id XID8 NOT NULL DEFAULT pg_current_xact_id(),
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT,
+	published_at TIMESTAMP WITH TIME ZONE,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+// setupDBTest returns a pool of connections to a test schema with an outbox table.
+func setupDBTest(t testutils.GetDBConnPooler) (context.Context, *pgxpool.Pool, func()) {
+	ctx := context.Background()
+	db := testutils.GetDBConnPool(t, testDBName)
+
+	schema := fmt.Sprintf("outbox_test_%d", time.Now().UnixNano())
+	if _, err := db.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatal(err)
+	}
+
+	config := db.Config()
+	config.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.ConnectConfig(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Exec(ctx, createTestOutboxEventsTableSQL); err != nil {
+		t.Fatal(err)
+	}
+
+	return ctx, pool, func() {
+		pool.Close()
+		db.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE")
+		db.Close()
+	}
+}
+
+func TestRelay_RelayPendingInterleavedTransactions(t *testing.T) {
+	ctx, db, done := setupDBTest(t)
+	defer done()
+
+	producer := &mockProducer{}
+	relay := NewRelay(RelayParams{DB: db, Producer: producer})
+
+	first, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Rollback(ctx)
+	if err := Write(ctx, first, &eventstreaming.ProducerMessage{Topic: "topic", Value: []byte("first")}); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(ctx, second, &eventstreaming.ProducerMessage{Topic: "topic", Value: []byte("second")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	published, err := relay.RelayPending(ctx)
+	testutils.MustMatch(t, nil, err)
+	testutils.MustMatch(t, 0, published, "events committed while an older transaction is running should not be relayed")
+
+	if err := first.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	published, err = relay.RelayPending(ctx)
+	testutils.MustMatch(t, nil, err)
+	testutils.MustMatch(t, 2, published)
+
+	var values []string
+	for _, m := range producer.messages {
+		values = append(values, string(m.Value))
+	}
+	testutils.MustMatch(t, []string{"first", "second"}, values)
+
+	published, err = relay.RelayPending(ctx)
+	testutils.MustMatch(t, nil, err)
+	testutils.MustMatch(t, 0, published)
+}
@@ -0,0 +1,217 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/*company-data-covered*/services/go/pkg/eventstreaming"
+	"github.com/*company-data-covered*/services/go/pkg/testutils"
+	"go.uber.org/zap"
+)
+
+type mockProducer struct {
+	messages []*eventstreaming.ProducerMessage
+	errs     map[string]error
+}
+
+func (p *mockProducer) SendMessage(_ context.Context, message *eventstreaming.ProducerMessage) error {
+	if err := p.errs[string(message.Value)]; err != nil {
+		return err
+	}
+	p.messages = append(p.messages, message)
+	return nil
+}
+
+func (p *mockProducer) Close() error {
+	return nil
+}
+
+type mockBatchStore struct {
+	locked       bool
+	lockErr      error
+	events       []*event
+	eventsErr    error
+	markErr      error
+	publishedIDs []int64
+	failedIDs    []int64
+}
+
+func (s *mockBatchStore) tryLock(context.Context) (bool, error) {
+	return s.locked, s.lockErr
+}
+
+func (s *mockBatchStore) pendingEvents(context.Context, int) ([]*event, error) {
+	return s.events, s.eventsErr
+}
+
+func (s *mockBatchStore) markPublished(_ context.Context, id int64) error {
+	if s.markErr != nil {
+		return s.markErr
+	}
+	s.publishedIDs = append(s.publishedIDs, id)
+	return nil
+}
+
+func (s *mockBatchStore) markFailed(_ context.Context, id int64, _ error) error {
+	if s.markErr != nil {
+		return s.markErr
+	}
+	s.failedIDs = append(s.failedIDs, id)
+	return nil
+}
+
+func TestRelay_RelayBatch(t *testing.T) {
+	newEvents := func() []*event {
+		return []*event{
+			{id: 1, topic: "topic-1", key: []byte("patient-1"), value: []byte("one"), headers: map[string]string{"correlation-id": "abc"}},
+			{id: 2, topic: "topic-2", value: []byte("two"), headers: map[string]string{}},
+			{id: 3, topic: "topic-1", key: []byte("patient-1"), value: []byte("three"), headers: map[string]string{}},
+		}
+	}
+
+	tcs := []struct {
+		Desc        string
+		Store       *mockBatchStore
+		ProducerErr map[string]error
+
+		WantPublished     int
+		WantErr           error
+		WantPublishErr    bool
+		WantMessageValues []string
+		WantPublishedIDs  []int64
+		WantFailedIDs     []int64
+	}{
+		{
+			Desc:  "publishes events in order",
+			Store: &mockBatchStore{locked: true, events: newEvents()},
+
+			WantPublished:     3,
+			WantMessageValues: []string{"one", "two", "three"},
+			WantPublishedIDs:  []int64{1, 2, 3},
+		},
+		{
+			Desc:  "stops at failed event",
+			Store: &mockBatchStore{locked: true, events: newEvents()},
+			ProducerErr: map[string]error{
+				"two": errors.New("kafka is down"),
+			},
+
+			WantPublished:     1,
+			WantPublishErr:    true,
+			WantMessageValues: []string{"one"},
+			WantPublishedIDs:  []int64{1},
+			WantFailedIDs:     []int64{2},
+		},
+		{
+			Desc:  "lock held by another relay",
+			Store: &mockBatchStore{locked: false, events: newEvents()},
+
+			WantPublished: 0,
+		},
+		{
+			Desc:  "lock error",
+			Store: &mockBatchStore{lockErr: errors.New("boo")},
+
+			WantErr: errors.New("boo"),
+		},
+		{
+			Desc:  "pending events error",
+			Store: &mockBatchStore{locked: true, eventsErr: errors.New("boo")},
+
+			WantErr: errors.New("boo"),
+		},
+		{
+			Desc:  "mark error",
+			Store: &mockBatchStore{locked: true, events: newEvents(), markErr: errors.New("boo")},
+
+			WantErr:           errors.New("boo"),
+			WantMessageValues: []string{"one"},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			producer := &mockProducer{errs: tc.ProducerErr}
+			relay := NewRelay(RelayParams{
+				Config:   RelayConfig{BatchSize: 10},
+				Producer: producer,
+				Logger:   zap.NewNop().Sugar(),
+			})
+
+			published, err := relay.relayBatch(context.Background(), tc.Store)
+
+			var publishErr *publishError
+			switch {
+			case tc.WantPublishErr:
+				testutils.MustMatch(t, true, errors.As(err, &publishErr), "expected publish error")
+			case tc.WantErr != nil:
+				testutils.MustMatch(t, tc.WantErr.Error(), err.Error())
+			default:
+				testutils.MustMatch(t, nil, err)
+			}
+			testutils.MustMatch(t, tc.WantPublished, published)
+			testutils.MustMatch(t, tc.WantPublishedIDs, tc.Store.publishedIDs)
+			testutils.MustMatch(t, tc.WantFailedIDs, tc.Store.failedIDs)
+
+			var values []string
+			for _, m := range producer.messages {
+				values = append(values, string(m.Value))
+			}
+			testutils.MustMatch(t, tc.WantMessageValues, values)
+		})
+	}
+}
+
+func TestRelay_Publish(t *testing.T) {
+	producer := &mockProducer{}
+	relay := NewRelay(RelayParams{Producer: producer})
+
+	err := relay.publish(context.Background(), &event{
+		id:      42,
+		topic:   "topic-1",
+		key:     []byte("patient-1"),
+		value:   []byte("value"),
+		headers: map[string]string{"correlation-id": "abc"},
+	})
+
+	testutils.MustMatch(t, nil, err)
+	testutils.MustMatch(t, []*eventstreaming.ProducerMessage{
+		{
+			Topic: "topic-1",
+			Key:   []byte("patient-1"),
+			Value: []byte("value"),
+			Headers: map[string]string{
+				"correlation-id": "abc",
+				EventIDHeader:    "42",
+			},
+		},
+	}, producer.messages)
+}
+
+func TestRelay_Backoff(t *testing.T) {
+	relay := NewRelay(RelayParams{
+		Config: RelayConfig{
+			PollInterval: time.Second,
+			MaxBackoff:   5 * time.Second,
+		},
+	})
+
+	testutils.MustMatch(t, time.Second, relay.backoff(1))
+	testutils.MustMatch(t, 2*time.Second, relay.backoff(2))
+	testutils.MustMatch(t, 4*time.Second, relay.backoff(3))
+	testutils.MustMatch(t, 5*time.Second, relay.backoff(4))
+	testutils.MustMatch(t, 5*time.Second, relay.backoff(100))
+}
+
+func TestNewRelay_Defaults(t *testing.T) {
+	relay := NewRelay(RelayParams{})
+
+	testutils.MustMatch(t, defaultBatchSize, relay.batchSize)
+	testutils.MustMatch(t, defaultPollInterval, relay.pollInterval)
+	testutils.MustMatch(t, defaultMaxBackoff, relay.maxBackoff)
+	if relay.logger == nil {
+		t.Fatal("logger is not defaulted")
+	}
+}
@@ -0,0 +1,32 @@
+package com.*company-data-covered*.logistics.domain;
+
+import com.*company-data-covered*.optimizer.VRPAttributePreference;
+import com.google.common.collect.ImmutableSet;
+
+/**
+ * Weighted preference of a customer for vehicle attributes, penalized instead of enforced when not
+ * met.
+ *
+ * @param attribute Preferred or unwanted attribute.
+ * @param isUnwanted Vehicles with the attribute should be avoided, instead of preferred.
+ * @param penaltyUSDCents Penalty when the preference is not met.
+ */
+public record AttributePreference(Attribute attribute, boolean isUnwanted, long penaltyUSDCents) {
+
+  public static AttributePreference of(VRPAttributePreference pref) {
+    return new AttributePreference(
+        Attribute.of(pref.getAttribute()), pref.getIsUnwanted(), pref.getPenaltyUsdCents());
+  }
+
+  public boolean isMet(ImmutableSet<Attribute> vehicleAttributes) {
+    return vehicleAttributes.contains(attribute) != isUnwanted;
+  }
+
+  public VRPAttributePreference toVRPAttributePreference() {
+    return VRPAttributePreference.newBuilder()
+        .setAttribute(attribute.toVRPAttribute())
+        .setIsUnwanted(isUnwanted)
+        .setPenaltyUsdCents(penaltyUSDCents)
+        .build();
+  }
+}
@@ -10,6 +10,7 @@ import com.*company-data-covered*.optimizer.VRPVisit;
 import com.*company-data-covered*.optimizer.VRPVisitValue;
 import com.fasterxml.jackson.annotation.JsonIgnore;
 import com.fasterxml.jackson.annotation.JsonIgnoreProperties;
+import com.google.common.collect.ImmutableList;
 import com.google.common.collect.ImmutableSet;
 import java.util.Objects;
 import org.optaplanner.core.api.domain.entity.PlanningEntity;
@@ -32,6 +33,7 @@ public class Customer implements Standstill, VRPStop {
   @PlanningId private long id;
   private Location location;
   private AssignabilityChecker assignabilityChecker;
+  private ImmutableList<AttributePreference> attributePreferences = ImmutableList.of();
 
   private long readyTimestampMs;
   private long dueTimestampMs;
@@ -212,6 +214,29 @@ public class Customer implements Standstill, VRPStop {
     this.assignabilityChecker = assignabilityChecker;
   }
 
+  public ImmutableList<AttributePreference> getAttributePreferences() {
+    return attributePreferences;
+  }
+
+  public void setAttributePreferences(ImmutableList<AttributePreference> attributePreferences) {
+    this.attributePreferences = attributePreferences;
+  }
+
+  public boolean hasAttributePreferences() {
+    return !attributePreferences.isEmpty();
+  }
+
+  /**
+   * @return the total penalty of the attribute preferences not met by the vehicle attributes.
+   */
+  public long getUnmetAttributePreferencesPenaltyUSDCents(
+      ImmutableSet<Attribute> vehicleAttributes) {
+    return attributePreferences.stream()
+        .filter(pref -> !pref.isMet(vehicleAttributes))
+        .mapToLong(AttributePreference::penaltyUSDCents)
+        .sum();
+  }
+
   public void setAcuityTimeWindowStartMs(long startTimestampMs) {
     this.acuityTimeWindowStartMs = startTimestampMs;
   }
@@ -331,22 +356,26 @@ public class Customer implements Standstill, VRPStop {
       throw new IllegalStateException(
           "This method must not be called when the previousStandstill is not initialized yet.");
     }
-    return previousStandstill.getDistanceTo(getLocation());
+    Long departureTimestampMs =
+        previousStandstill instanceof Vehicle previousVehicle
+            ? previousVehicle.getDepotDepartureTimestampMs()
+            : ((Customer) previousStandstill).getDepartureTimestampMs();
+    return previousStandstill.getDistanceTo(getLocation(), departureTimestampMs);
   }
 
   @Override
-  public Distance getDistanceTo(Location location) {
-    return getLocation().getDistanceTo(location);
+  public Distance getDistanceTo(Location location, Long departureTimestampMs) {
+    return getLocation().getDistanceTo(location, departureTimestampMs);
   }
 
   /**
-   * Distance to the depot where the vehicle visiting this customer started.
+   * Distance to the location where the vehicle visiting this customer ends its route.
    *
    * @return distance to the depot
    */
   @JsonIgnore
   public Distance getDistanceToDepot() {
-    return getLocation().getDistanceTo(vehicle.getLocation());
+    return getLocation().getDistanceTo(vehicle.getEndLocation(), getDepartureTimestampMs());
   }
 
   public Long getCapacityOffsetAtDepartureMs() {
@@ -81,10 +81,10 @@ public class DepotStop extends Customer {
   }
 
   @Override
-  public Distance getDistanceTo(Location location) {
+  public Distance getDistanceTo(Location location, Long departureTimestampMs) {
     if (isFinalStop) {
       return Distance.ZERO;
     }
-    return super.getDistanceTo(location);
+    return super.getDistanceTo(location, departureTimestampMs);
   }
 }
@@ -1,8 +1,10 @@
 package com.*company-data-covered*.logistics.domain;
 
 import com.*company-data-covered*.logistics.domain.geo.Distance;
+import com.*company-data-covered*.logistics.domain.geo.TimeDependentDistance;
 import com.fasterxml.jackson.annotation.JsonFormat;
 import com.fasterxml.jackson.annotation.JsonIgnoreProperties;
+import java.util.Collections;
 import java.util.Map;
 import java.util.Objects;
 
@@ -16,6 +18,7 @@ public class Location {
   private final double latitude;
   private final double longitude;
   private Map<Location, Distance> distanceMap;
+  private Map<Location, TimeDependentDistance> timeDependentDistanceMap = Collections.emptyMap();
 
   public Location(long id, double latitude, double longitude) {
     this.id = id;
@@ -48,6 +51,22 @@ public class Location {
     return distanceMap;
   }
 
+  /**
+   * Set the time dependent distance map, for the locations with distances per departure time
+   * bucket.
+   *
+   * @param timeDependentDistanceMap a map containing time dependent distances from here to other
+   *     locations
+   */
+  public void setTimeDependentDistanceMap(
+      Map<Location, TimeDependentDistance> timeDependentDistanceMap) {
+    this.timeDependentDistanceMap = timeDependentDistanceMap;
+  }
+
+  public Map<Location, TimeDependentDistance> getTimeDependentDistanceMap() {
+    return timeDependentDistanceMap;
+  }
+
   /**
    * Distance to the given location in meters.
    *
@@ -67,6 +86,27 @@ public class Location {
     return distance;
   }
 
+  /**
+   * Distance to the given location when departing at the given time, from the departure time
+   * bucket containing it, if any.
+   *
+   * @param location other location
+   * @param departureTimestampMs departure time, or null if unknown
+   * @return Distance
+   */
+  public Distance getDistanceTo(Location location, Long departureTimestampMs) {
+    if (departureTimestampMs != null && !this.equals(location)) {
+      TimeDependentDistance timeDependentDistance = timeDependentDistanceMap.get(location);
+      if (timeDependentDistance != null) {
+        Distance distance = timeDependentDistance.getDistanceAt(departureTimestampMs);
+        if (distance != null) {
+          return distance;
+        }
+      }
+    }
+    return getDistanceTo(location);
+  }
+
   // ************************************************************************
   // Complex methods
   // ************************************************************************
@@ -66,13 +66,33 @@ public class RestBreak extends Customer {
       Depot depot,
       long durationMs,
       DefaultProfitComponents defaultProfitComponents) {
+    return UnrequestedRestBreak(
+        id,
+        shiftTeamId,
+        depot.getReadyTimestampMs(),
+        depot.getDueTimestampMs(),
+        durationMs,
+        defaultProfitComponents);
+  }
+
+  /**
+   * Unrequested rest break that must start between earliestStartTimestampMs and
+   * latestStartTimestampMs, such as required by labor rules.
+   */
+  public static RestBreak UnrequestedRestBreak(
+      long id,
+      long shiftTeamId,
+      long earliestStartTimestampMs,
+      long latestStartTimestampMs,
+      long durationMs,
+      DefaultProfitComponents defaultProfitComponents) {
 
     RestBreak rb =
         new RestBreak(
             id,
             Location.UNUSED_LOCATION,
-            depot.getReadyTimestampMs(),
-            depot.getDueTimestampMs(),
+            earliestStartTimestampMs,
+            latestStartTimestampMs,
             durationMs,
             defaultProfitComponents);
 
@@ -127,15 +147,15 @@ public class RestBreak extends Customer {
   }
 
   @Override
-  public Distance getDistanceTo(Location location) {
+  public Distance getDistanceTo(Location location, Long departureTimestampMs) {
     if (isUnrequested) {
       Standstill previousStandstill = super.getPreviousStandstill();
       if (previousStandstill == null) {
         return null;
       }
-      return previousStandstill.getDistanceTo(location);
+      return previousStandstill.getDistanceTo(location, departureTimestampMs);
     }
-    return super.getDistanceTo(location);
+    return super.getDistanceTo(location, departureTimestampMs);
   }
 
   @Override
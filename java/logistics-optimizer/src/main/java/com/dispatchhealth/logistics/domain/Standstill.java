@@ -20,5 +20,12 @@ public interface Standstill {
 
   void setNextCustomer(Customer nextCustomer);
 
-  Distance getDistanceTo(Location location);
+  default Distance getDistanceTo(Location location) {
+    return getDistanceTo(location, null);
+  }
+
+  /**
+   * @param departureTimestampMs departure time, or null to use time independent distances
+   */
+  Distance getDistanceTo(Location location, Long departureTimestampMs);
 }
@@ -24,6 +24,8 @@ public class Vehicle implements Standstill {
 
   @PlanningId private long id;
   private Depot depot;
+  // Location where the vehicle ends its route, if not the depot location.
+  private Location endLocation;
 
   private ImmutableSet<Attribute> attributes;
 
@@ -125,6 +127,21 @@ public class Vehicle implements Standstill {
     return depot.getLocation();
   }
 
+  /**
+   * @return location where the vehicle ends its route, such as the home of a home-based shift team
+   */
+  public Location getEndLocation() {
+    return endLocation != null ? endLocation : depot.getLocation();
+  }
+
+  public boolean hasEndLocation() {
+    return endLocation != null;
+  }
+
+  public void setEndLocation(Location endLocation) {
+    this.endLocation = endLocation;
+  }
+
   public Long getCapacityMs() {
     return capacityMs;
   }
@@ -354,7 +371,7 @@ public class Vehicle implements Standstill {
   }
 
   @Override
-  public Distance getDistanceTo(Location location) {
-    return getLocation().getDistanceTo(location);
+  public Distance getDistanceTo(Location location, Long departureTimestampMs) {
+    return getLocation().getDistanceTo(location, departureTimestampMs);
   }
 }
@@ -0,0 +1,55 @@
+package com.*company-data-covered*.logistics.domain.geo;
+
+import java.util.Collections;
+import java.util.Map;
+import java.util.NavigableMap;
+import java.util.Objects;
+import java.util.TreeMap;
+
+/** Distances between two locations for consecutive departure time buckets of the same duration. */
+public class TimeDependentDistance {
+  private final long bucketDurationMs;
+  private final NavigableMap<Long, Distance> distancesByBucketStartMs;
+
+  public TimeDependentDistance(
+      long bucketDurationMs, Map<Long, Distance> distancesByBucketStartMs) {
+    this.bucketDurationMs = bucketDurationMs;
+    this.distancesByBucketStartMs = new TreeMap<>(distancesByBucketStartMs);
+  }
+
+  public long getBucketDurationMs() {
+    return bucketDurationMs;
+  }
+
+  public NavigableMap<Long, Distance> getDistancesByBucketStartMs() {
+    return Collections.unmodifiableNavigableMap(distancesByBucketStartMs);
+  }
+
+  /**
+   * Distance when departing at the given time.
+   *
+   * @param departureTimestampMs departure time
+   * @return distance of the departure time bucket containing the departure time, or null if none
+   */
+  public Distance getDistanceAt(long departureTimestampMs) {
+    Map.Entry<Long, Distance> bucket = distancesByBucketStartMs.floorEntry(departureTimestampMs);
+    if (bucket == null || departureTimestampMs >= bucket.getKey() + bucketDurationMs) {
+      return null;
+    }
+    return bucket.getValue();
+  }
+
+  @Override
+  public boolean equals(Object o) {
+    if (this == o) return true;
+    if (o == null || getClass() != o.getClass()) return false;
+    TimeDependentDistance that = (TimeDependentDistance) o;
+    return bucketDurationMs == that.bucketDurationMs
+        && distancesByBucketStartMs.equals(that.distancesByBucketStartMs);
+  }
+
+  @Override
+  public int hashCode() {
+    return Objects.hash(bucketDurationMs, distancesByBucketStartMs);
+  }
+}
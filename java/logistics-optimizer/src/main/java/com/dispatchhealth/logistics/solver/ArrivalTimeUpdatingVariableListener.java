@@ -225,7 +225,9 @@ public class ArrivalTimeUpdatingVariableListener
     }
 
     public static long vehicleToCustomerDrivingDurationMs(Vehicle vehicle, Customer customer) {
-      return vehicle.getDistanceTo(customer.getLocation()).getDurationMs();
+      return vehicle
+          .getDistanceTo(customer.getLocation(), vehicle.getDepotDepartureTimestampMs())
+          .getDurationMs();
     }
 
     public static long vehicleArrivalAtCustomerWitReadyPaddingTimestampMs(
@@ -243,7 +245,7 @@ public class ArrivalTimeUpdatingVariableListener
           .map(
               customer ->
                   new Departure(
-                      customer.getDistanceTo(location),
+                      customer.getDistanceTo(location, customer.getDepartureTimestampMs()),
                       departureTimestampMsProvider.apply(customer)))
           .toList();
     }
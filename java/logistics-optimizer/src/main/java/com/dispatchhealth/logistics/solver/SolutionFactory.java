@@ -2,6 +2,7 @@ package com.*company-data-covered*.logistics.solver;
 
 import com.*company-data-covered*.logistics.AssignabilityChecker;
 import com.*company-data-covered*.logistics.domain.Attribute;
+import com.*company-data-covered*.logistics.domain.AttributePreference;
 import com.*company-data-covered*.logistics.domain.CurrentPositionStop;
 import com.*company-data-covered*.logistics.domain.Customer;
 import com.*company-data-covered*.logistics.domain.Depot;
@@ -14,6 +15,7 @@ import com.*company-data-covered*.logistics.domain.Vehicle;
 import com.*company-data-covered*.logistics.domain.VehicleRoutingSolution;
 import com.*company-data-covered*.logistics.domain.VehicleRoutingSolutionConstraintConfiguration;
 import com.*company-data-covered*.logistics.domain.geo.Distance;
+import com.*company-data-covered*.logistics.domain.geo.TimeDependentDistance;
 import com.*company-data-covered*.optimizer.SolveVRPRequest;
 import com.*company-data-covered*.optimizer.VRPAttribute;
 import com.*company-data-covered*.optimizer.VRPConfig;
@@ -34,12 +36,14 @@ import com.*company-data-covered*.optimizer.VRPShiftTeamRouteStop;
 import com.*company-data-covered*.optimizer.VRPShiftTeamVisit;
 import com.*company-data-covered*.optimizer.VRPSolution;
 import com.*company-data-covered*.optimizer.VRPStats;
+import com.*company-data-covered*.optimizer.VRPTimeDependentDistance;
 import com.*company-data-covered*.optimizer.VRPTimeWindow;
 import com.*company-data-covered*.optimizer.VRPUnassignedVisit;
 import com.*company-data-covered*.optimizer.VRPVisit;
 import com.*company-data-covered*.optimizer.VRPVisit.Builder;
 import com.*company-data-covered*.optimizer.VRPVisitAcuity;
 import com.*company-data-covered*.optimizer.VRPVisitLatenessTolerance;
+import com.google.common.collect.ImmutableList;
 import com.google.common.collect.ImmutableSet;
 import com.google.protobuf.util.JsonFormat;
 import java.io.IOException;
@@ -72,6 +76,8 @@ public class SolutionFactory {
     List<Location> locationList = new ArrayList<>(locationsCount);
     HashMap<Long, Location> locationMap = new HashMap<>(locationsCount);
     HashMap<Long, Map<Location, Distance>> locationDistanceMap = new HashMap<>(locationsCount);
+    HashMap<Long, Map<Location, TimeDependentDistance>> locationTimeDependentDistanceMap =
+        new HashMap<>(locationsCount);
     for (VRPLocation vrpLocation : description.getLocationsList()) {
       Location location =
           new Location(
@@ -80,12 +86,17 @@ public class SolutionFactory {
               vrpLocation.getLongitudeE6() / E6);
       HashMap<Location, Distance> distanceMap = new HashMap<>();
       location.setDistanceMap(distanceMap);
+      HashMap<Location, TimeDependentDistance> timeDependentDistanceMap = new HashMap<>();
+      location.setTimeDependentDistanceMap(timeDependentDistanceMap);
 
       locationList.add(location);
       locationMap.put(location.getId(), location);
       locationDistanceMap.put(location.getId(), distanceMap);
+      locationTimeDependentDistanceMap.put(location.getId(), timeDependentDistanceMap);
     }
 
+    long departureTimeBucketDurationMs =
+        description.getDistanceMatrix().getDepartureTimeBucketDurationSec() * SEC_TO_MS;
     for (VRPDistance vrpDistance : description.getDistanceMatrix().getDistancesList()) {
       Location fromLocation = locationMap.get(vrpDistance.getFromLocationId());
       Location toLocation = locationMap.get(vrpDistance.getToLocationId());
@@ -96,6 +107,21 @@ public class SolutionFactory {
       Distance distance =
           Distance.of(vrpDistance.getDurationSec() * SEC_TO_MS, vrpDistance.getLengthMeters());
       locationDistanceMap.get(fromLocation.getId()).put(toLocation, distance);
+
+      if (departureTimeBucketDurationMs > 0 && vrpDistance.getDepartureTimeBucketsCount() > 0) {
+        Map<Long, Distance> distancesByBucketStartMs =
+            new HashMap<>(vrpDistance.getDepartureTimeBucketsCount());
+        for (VRPTimeDependentDistance bucket : vrpDistance.getDepartureTimeBucketsList()) {
+          distancesByBucketStartMs.put(
+              bucket.getDepartureBucketStartTimestampSec() * SEC_TO_MS,
+              Distance.of(bucket.getDurationSec() * SEC_TO_MS, bucket.getLengthMeters()));
+        }
+        locationTimeDependentDistanceMap
+            .get(fromLocation.getId())
+            .put(
+                toLocation,
+                new TimeDependentDistance(departureTimeBucketDurationMs, distancesByBucketStartMs));
+      }
     }
 
     int visitsCount = description.getVisitsCount();
@@ -135,6 +161,10 @@ public class SolutionFactory {
       }
 
       customer.setAssignabilityChecker(new AssignabilityChecker(visit));
+      customer.setAttributePreferences(
+          visit.getAttributePreferencesList().stream()
+              .map(AttributePreference::of)
+              .collect(ImmutableList.toImmutableList()));
 
       customer.setVisitValueCents(visit);
 
@@ -213,11 +243,23 @@ public class SolutionFactory {
         if (!depotMap.containsKey(rb.getShiftTeamId())) {
           throw new IllegalArgumentException("Missing shift team id for rest break");
         }
+        Depot depot = depotMap.get(rb.getShiftTeamId());
+        long earliestStartTimestampMs = depot.getReadyTimestampMs();
+        if (rb.hasEarliestStartTimestampSec()) {
+          earliestStartTimestampMs =
+              Math.max(earliestStartTimestampMs, rb.getEarliestStartTimestampSec() * SEC_TO_MS);
+        }
+        long latestStartTimestampMs = depot.getDueTimestampMs();
+        if (rb.hasLatestStartTimestampSec()) {
+          latestStartTimestampMs =
+              Math.min(latestStartTimestampMs, rb.getLatestStartTimestampSec() * SEC_TO_MS);
+        }
         restBreak =
             RestBreak.UnrequestedRestBreak(
                 rb.getId(),
                 rb.getShiftTeamId(),
-                depotMap.get(rb.getShiftTeamId()),
+                earliestStartTimestampMs,
+                latestStartTimestampMs,
                 rb.getDurationSec() * SEC_TO_MS,
                 defaultProfitComponents);
 
@@ -273,11 +315,18 @@ public class SolutionFactory {
       vehicle.setNumProviderAPP(shiftTeam.getNumAppMembers());
       vehicle.setNumProviderDHMT(shiftTeam.getNumDhmtMembers());
 
+      if (shiftTeam.hasEndDepotLocationId()) {
+        if (!locationMap.containsKey(shiftTeam.getEndDepotLocationId())) {
+          throw new IllegalArgumentException("Missing end location for vehicle id");
+        }
+        vehicle.setEndLocation(locationMap.get(shiftTeam.getEndDepotLocationId()));
+      }
+
       DepotStop depotStop =
           new DepotStop(
               shiftTeam.getId(),
               shiftTeam.getId(),
-              depot.getLocation(),
+              vehicle.getEndLocation(),
               depot.getDueTimestampMs(),
               true,
               defaultProfitComponents);
@@ -530,6 +579,10 @@ public class SolutionFactory {
                       assignabilityChecker.getOrderedRequiredAttributes());
                   vrpCustomer.addAllForbiddenAttributes(
                       assignabilityChecker.getOrderedForbiddenAttributes());
+                  vrpCustomer.addAllAttributePreferences(
+                      customer.getAttributePreferences().stream()
+                          .map(AttributePreference::toVRPAttributePreference)
+                          .toList());
 
                   return vrpCustomer.build();
                 })
@@ -572,6 +625,9 @@ public class SolutionFactory {
                                       vehicle.getDepot().getDueTimestampMs() / SEC_TO_MS))
                           .setNumAppMembers(vehicle.getNumProviderAPP())
                           .setNumDhmtMembers(vehicle.getNumProviderDHMT());
+                  if (vehicle.hasEndLocation()) {
+                    vrpShiftTeam.setEndDepotLocationId(vehicle.getEndLocation().getId());
+                  }
                   Set<Attribute> attributes = vehicle.getAttributes();
                   if (!attributes.isEmpty()) {
                     vrpShiftTeam.addAllAttributes(
@@ -613,10 +669,8 @@ public class SolutionFactory {
                 })
             .toList());
 
-    VRPDistanceMatrix.Builder distanceMatrix = null;
-    if (includeDistanceMatrix) {
-      distanceMatrix = description.getDistanceMatrixBuilder();
-    }
+    VRPDistanceMatrix.Builder distanceMatrix =
+        includeDistanceMatrix ? description.getDistanceMatrixBuilder() : null;
     for (Location location : solution.getLocationList()) {
       description.addLocations(
           VRPLocation.newBuilder()
@@ -630,13 +684,33 @@ public class SolutionFactory {
                 .map(
                     locationDistanceEntry -> {
                       long distanceMeters = locationDistanceEntry.getValue().getMeters();
-                      return VRPDistance.newBuilder()
-                          .setFromLocationId(location.getId())
-                          .setToLocationId(locationDistanceEntry.getKey().getId())
-                          .setDurationSec(
-                              locationDistanceEntry.getValue().getDurationMs() / SEC_TO_MS)
-                          .setLengthMeters(distanceMeters)
-                          .build();
+                      VRPDistance.Builder vrpDistance =
+                          VRPDistance.newBuilder()
+                              .setFromLocationId(location.getId())
+                              .setToLocationId(locationDistanceEntry.getKey().getId())
+                              .setDurationSec(
+                                  locationDistanceEntry.getValue().getDurationMs() / SEC_TO_MS)
+                              .setLengthMeters(distanceMeters);
+                      TimeDependentDistance timeDependentDistance =
+                          location
+                              .getTimeDependentDistanceMap()
+                              .get(locationDistanceEntry.getKey());
+                      if (timeDependentDistance != null) {
+                        timeDependentDistance
+                            .getDistancesByBucketStartMs()
+                            .forEach(
+                                (bucketStartMs, bucketDistance) ->
+                                    vrpDistance.addDepartureTimeBuckets(
+                                        VRPTimeDependentDistance.newBuilder()
+                                            .setDepartureBucketStartTimestampSec(
+                                                bucketStartMs / SEC_TO_MS)
+                                            .setDurationSec(
+                                                bucketDistance.getDurationMs() / SEC_TO_MS)
+                                            .setLengthMeters(bucketDistance.getMeters())));
+                        distanceMatrix.setDepartureTimeBucketDurationSec(
+                            timeDependentDistance.getBucketDurationMs() / SEC_TO_MS);
+                      }
+                      return vrpDistance.build();
                     })
                 .toList());
       }
@@ -54,6 +54,7 @@ public class VehicleRoutingConstraintProvider implements ConstraintProvider {
       vehicleOnSceneTimeCostUSDMills(factory),
       foregoneVisitOpportunityCostMills(factory),
       vehicleProviderOvertimeUSDMills(factory),
+      vehicleUnmetAttributePreferencesUSDMills(factory),
       linearOffsetLatenessToCustomerUSDMills(factory),
       linearOffsetLatenessToDepotUSDMills(factory),
       linearOffsetClinicalUrgencyLatenessToCustomerUSDMills(factory),
@@ -281,6 +282,21 @@ public class VehicleRoutingConstraintProvider implements ConstraintProvider {
         .asConstraint("provider overtime cost");
   }
 
+  protected Constraint vehicleUnmetAttributePreferencesUSDMills(ConstraintFactory factory) {
+    return factory
+        .forEach(Customer.class)
+        .filter(Customer::representsRealCustomerVisitAndNotAnotherStopType)
+        .filter(Customer::hasAttributePreferences)
+        .filter(customer -> !customer.isAssignedToSinkVehicle())
+        .penalizeLong(
+            VehicleRoutingSolutionConstraintConfiguration.ONE_SOFT,
+            customer ->
+                customer.getUnmetAttributePreferencesPenaltyUSDCents(
+                        customer.getVehicle().getAttributes())
+                    * USD_MILLS_PER_CENT)
+        .asConstraint("unmet attribute preferences cost");
+  }
+
   protected Constraint vehicleBaseWageCostUSDMills(ConstraintFactory factory) {
     return factory
         .forEach(Vehicle.class)
@@ -3,6 +3,7 @@ package com.*company-data-covered*.logistics.domain;
 import static org.assertj.core.api.Assertions.assertThat;
 
 import com.*company-data-covered*.logistics.domain.geo.Distance;
+import com.*company-data-covered*.logistics.domain.geo.TimeDependentDistance;
 import com.google.common.collect.ImmutableMap;
 import org.junit.jupiter.api.Test;
 
@@ -24,4 +25,30 @@ class LocationTest {
     assertThat(loc1.getDistanceTo(loc2)).isEqualTo(distance2);
     assertThat(loc1.getDistanceTo(loc3)).isEqualTo(distance3);
   }
+
+  @Test
+  void getDistanceTo_departureTimeBuckets() {
+    Location loc1 = new Location(1, 1.23, 4.56);
+    Location loc2 = new Location(2, 2.34, 5.67);
+
+    Distance distance = Distance.of(222, 2);
+    Distance morningDistance = Distance.of(444, 3);
+    Distance noonDistance = Distance.of(333, 2);
+    long bucketDurationMs = 1000;
+    loc1.setDistanceMap(ImmutableMap.of(loc2, distance));
+    loc1.setTimeDependentDistanceMap(
+        ImmutableMap.of(
+            loc2,
+            new TimeDependentDistance(
+                bucketDurationMs, ImmutableMap.of(0L, morningDistance, 1000L, noonDistance))));
+
+    assertThat(loc1.getDistanceTo(loc2, 0L)).isEqualTo(morningDistance);
+    assertThat(loc1.getDistanceTo(loc2, 999L)).isEqualTo(morningDistance);
+    assertThat(loc1.getDistanceTo(loc2, 1000L)).isEqualTo(noonDistance);
+    // Departures outside of any bucket, or at an unknown time, use the time independent distance.
+    assertThat(loc1.getDistanceTo(loc2, 2000L)).isEqualTo(distance);
+    assertThat(loc1.getDistanceTo(loc2, -1L)).isEqualTo(distance);
+    assertThat(loc1.getDistanceTo(loc2, null)).isEqualTo(distance);
+    assertThat(loc1.getDistanceTo(loc1, 0L)).isEqualTo(Distance.ZERO);
+  }
 }
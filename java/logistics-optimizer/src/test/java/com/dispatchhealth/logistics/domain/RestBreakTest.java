@@ -56,6 +56,20 @@ public class RestBreakTest {
     assertThat(restBreak.getDistanceFromPreviousStandstill()).isEqualTo(Distance.ZERO);
   }
 
+  @Test
+  void unrequestedRestBreak_startsWithinWindow() {
+    Depot depot = new Depot(Location.UNUSED_LOCATION, 0, 100);
+    RestBreak defaultWindow =
+        RestBreak.UnrequestedRestBreak(1, 2, depot, 3, defaultProfitComponents);
+    assertThat(defaultWindow.getReadyTimestampMs()).isEqualTo(0);
+    assertThat(defaultWindow.getDueTimestampMs()).isEqualTo(100);
+
+    RestBreak restBreak = RestBreak.UnrequestedRestBreak(1, 2, 10, 50, 3, defaultProfitComponents);
+    assertThat(restBreak.getReadyTimestampMs()).isEqualTo(10);
+    assertThat(restBreak.getDueTimestampMs()).isEqualTo(50);
+    assertThat(restBreak.getIsUnrequested()).isTrue();
+  }
+
   @Test
   void requestedRestBreak_locationIsConstant() {
     int shiftTeamID = 2;
@@ -10,6 +10,7 @@ import com.*company-data-covered*.logistics.domain.Location;
 import com.*company-data-covered*.logistics.domain.Vehicle;
 import com.*company-data-covered*.logistics.domain.VehicleRoutingSolution;
 import com.*company-data-covered*.logistics.domain.geo.Distance;
+import com.*company-data-covered*.logistics.domain.geo.TimeDependentDistance;
 import com.google.common.collect.ImmutableMap;
 import org.junit.jupiter.api.BeforeEach;
 import org.junit.jupiter.api.Test;
@@ -163,6 +164,63 @@ class ArrivalTimeUpdatingVariableListenerTest {
                 + loc3Loc1Distance.getDurationMs());
   }
 
+  @Test
+  void updateArrivalTime_departureTimeBuckets() {
+    long vehicleReadyTimestampMs = 123L;
+    Depot depot = new Depot(loc1, vehicleReadyTimestampMs, 0);
+    Vehicle vehicle = new Vehicle(VEHICLE_ID, depot, defaultProfitComponents);
+
+    long serviceDurationMs1 = 456L;
+    Customer customer1 =
+        new Customer(CUSTOMER_ID_1, loc2, 0, 0, serviceDurationMs1, defaultProfitComponents);
+    customer1.setPreviousStandstill(vehicle);
+    customer1.setVehicle(vehicle);
+
+    long serviceDurationMs2 = 789L;
+    Customer customer2 =
+        new Customer(CUSTOMER_ID_2, loc3, 0, 0, serviceDurationMs2, defaultProfitComponents);
+    customer2.setPreviousStandstill(customer1);
+    customer2.setVehicle(vehicle);
+    customer1.setNextCustomer(customer2);
+
+    vehicle.setNextCustomer(customer1);
+    attachDepotStopToEnd(vehicle, customer2);
+
+    // Leaving the depot in the first bucket takes longer, while leaving customer1 after the last
+    // bucket uses the time independent distance.
+    long bucketDurationMs = 1000L;
+    Distance loc1Loc2RushHourDistance = Distance.of(5000, 12);
+    Distance loc2Loc3RushHourDistance = Distance.of(6000, 23);
+    loc1.setTimeDependentDistanceMap(
+        ImmutableMap.of(
+            loc2,
+            new TimeDependentDistance(
+                bucketDurationMs, ImmutableMap.of(0L, loc1Loc2RushHourDistance))));
+    loc2.setTimeDependentDistanceMap(
+        ImmutableMap.of(
+            loc3,
+            new TimeDependentDistance(
+                bucketDurationMs, ImmutableMap.of(0L, loc2Loc3RushHourDistance))));
+
+    @SuppressWarnings("unchecked")
+    ScoreDirector<VehicleRoutingSolution> scoreDirector = mock(ScoreDirector.class);
+
+    updater.updateArrivalTime(scoreDirector, customer1);
+
+    assertThat(customer1.getArrivalTimestampMs())
+        .isEqualTo(vehicleReadyTimestampMs + loc1Loc2RushHourDistance.getDurationMs());
+
+    assertThat(customer2.getArrivalTimestampMs())
+        .isEqualTo(
+            vehicleReadyTimestampMs
+                + loc1Loc2RushHourDistance.getDurationMs()
+                + serviceDurationMs1
+                + loc2Loc3Distance.getDurationMs());
+
+    assertThat(vehicle.getTotalDistance())
+        .isEqualTo(loc1Loc2RushHourDistance.add(loc2Loc3Distance).add(loc3Loc1Distance));
+  }
+
   DepotStop attachDepotStopToEnd(Vehicle vehicle, Customer lastCustomer) {
     DepotStop depotStop =
         new DepotStop(
@@ -12,6 +12,7 @@ import com.*company-data-covered*.logistics.domain.RestBreak;
 import com.*company-data-covered*.logistics.domain.SinkVehicle;
 import com.*company-data-covered*.logistics.domain.Vehicle;
 import com.*company-data-covered*.logistics.domain.VehicleRoutingSolution;
+import com.*company-data-covered*.logistics.domain.geo.Distance;
 import com.*company-data-covered*.optimizer.VRPAttribute;
 import com.*company-data-covered*.optimizer.VRPDescription;
 import com.*company-data-covered*.optimizer.VRPDistance;
@@ -28,6 +29,7 @@ import com.*company-data-covered*.optimizer.VRPShiftTeamRouteHistory;
 import com.*company-data-covered*.optimizer.VRPShiftTeamRouteStop;
 import com.*company-data-covered*.optimizer.VRPShiftTeamVisit;
 import com.*company-data-covered*.optimizer.VRPSolution;
+import com.*company-data-covered*.optimizer.VRPTimeDependentDistance;
 import com.*company-data-covered*.optimizer.VRPTimeWindow;
 import com.*company-data-covered*.optimizer.VRPUnassignedVisit;
 import com.*company-data-covered*.optimizer.VRPVisit;
@@ -426,6 +428,62 @@ class SolutionFactoryTest {
     assertThat(score).isEqualTo(expectedVRPScore);
   }
 
+  @Test
+  void fromVRPDescription_endDepotLocation() {
+    VRPDescription.Builder description = fullDescription();
+    description.setShiftTeams(
+        0, description.getShiftTeams(0).toBuilder().setEndDepotLocationId(locationId2));
+
+    VehicleRoutingSolution solution =
+        SolutionFactory.fromVRPDescription(description.build(), defaultProfitComponents);
+
+    Vehicle vehicle = solution.getVehicleList().get(0);
+    assertThat(vehicle.getLocation().getId()).isEqualTo(1);
+    assertThat(vehicle.getEndLocation().getId()).isEqualTo(locationId2);
+    assertThat(solution.getDepotStopList().get(0).getLocation().getId()).isEqualTo(locationId2);
+
+    VRPSolution vrpSolution = SolutionFactory.toVRPSolution(solution, null, false, false);
+    assertThat(vrpSolution.getDescription().getShiftTeams(0).getEndDepotLocationId())
+        .isEqualTo(locationId2);
+  }
+
+  @Test
+  void fromVRPDescription_departureTimeBuckets() {
+    VRPDescription.Builder description = fullDescription();
+    VRPTimeDependentDistance rushHourBucket =
+        VRPTimeDependentDistance.newBuilder()
+            .setDepartureBucketStartTimestampSec(3600)
+            .setLengthMeters(1313)
+            .setDurationSec(24)
+            .build();
+    VRPDistanceMatrix.Builder distanceMatrix = description.getDistanceMatrixBuilder();
+    distanceMatrix.setDepartureTimeBucketDurationSec(3600);
+    distanceMatrix.getDistancesBuilder(0).addDepartureTimeBuckets(rushHourBucket);
+
+    VehicleRoutingSolution solution =
+        SolutionFactory.fromVRPDescription(description.build(), defaultProfitComponents);
+
+    Location location1 = solution.getLocationList().get(0);
+    Location location2 = solution.getLocationList().get(1);
+    assertThat(location1.getDistanceTo(location2, 3_600_000L)).isEqualTo(Distance.of(24000, 1313));
+    assertThat(location1.getDistanceTo(location2, 7_199_999L)).isEqualTo(Distance.of(24000, 1313));
+    assertThat(location1.getDistanceTo(location2, 7_200_000L)).isEqualTo(Distance.of(12000, 1212));
+    assertThat(location2.getDistanceTo(location1, 3_600_000L)).isEqualTo(Distance.of(21000, 2121));
+
+    VRPDistanceMatrix vrpDistanceMatrix =
+        SolutionFactory.toVRPSolution(solution, null, true, false)
+            .getDescription()
+            .getDistanceMatrix();
+    assertThat(vrpDistanceMatrix.getDepartureTimeBucketDurationSec()).isEqualTo(3600);
+    assertThat(
+            vrpDistanceMatrix.getDistancesList().stream()
+                .filter(distance -> distance.getFromLocationId() == 1)
+                .findFirst()
+                .orElseThrow()
+                .getDepartureTimeBucketsList())
+        .containsExactly(rushHourBucket);
+  }
+
   @Test
   void toVRPSolution_visitUsesExtraSetupDuration() {
     Location location = new Location(1, 1.23, 4.56);
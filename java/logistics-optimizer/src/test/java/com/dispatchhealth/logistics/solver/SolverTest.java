@@ -15,6 +15,7 @@ import com.*company-data-covered*.logistics.domain.Vehicle;
 import com.*company-data-covered*.logistics.domain.VehicleRoutingSolution;
 import com.*company-data-covered*.logistics.domain.VehicleRoutingSolutionConstraintConfiguration;
 import com.*company-data-covered*.logistics.domain.geo.Distance;
+import com.*company-data-covered*.logistics.domain.geo.TimeDependentDistance;
 import com.google.common.collect.ImmutableList;
 import com.google.common.collect.ImmutableMap;
 import java.time.Duration;
@@ -215,6 +216,62 @@ public class SolverTest {
     assertThat(solution.getScore().isFeasible()).isFalse();
   }
 
+  @Test
+  void oneCustomer_departureTimeBucketMakesDepotDueTimestampNotFeasible() {
+    Location depotLoc = loc1;
+    Location customerLoc = loc2;
+    Distance depotCustomerDistance = loc1Loc2Distance;
+    Distance customerDepotDistance = loc2Loc1Distance;
+    long vehicleReadyTimestampMs = 0;
+    long vehicleDueTimestampMs = depotCustomerDistance.add(customerDepotDistance).getDurationMs();
+
+    assertThat(
+            solver
+                .solve(oneCustomerProblem(vehicleReadyTimestampMs, vehicleDueTimestampMs))
+                .getScore()
+                .isFeasible())
+        .isTrue();
+
+    // Leaving the depot at rush hour, the vehicle can no longer be back in time.
+    Distance rushHourDepotCustomerDistance =
+        Distance.of(depotCustomerDistance.getDurationMs() * 2, depotCustomerDistance.getMeters());
+    depotLoc.setTimeDependentDistanceMap(
+        ImmutableMap.of(
+            customerLoc,
+            new TimeDependentDistance(
+                HR_TO_MS,
+                ImmutableMap.of(vehicleReadyTimestampMs, rushHourDepotCustomerDistance))));
+
+    assertThat(
+            solver
+                .solve(oneCustomerProblem(vehicleReadyTimestampMs, vehicleDueTimestampMs))
+                .getScore()
+                .isFeasible())
+        .isFalse();
+  }
+
+  private VehicleRoutingSolution oneCustomerProblem(
+      long vehicleReadyTimestampMs, long vehicleDueTimestampMs) {
+    VehicleRoutingSolution problem = emptySolution();
+
+    Location depotLoc = loc1;
+    Location customerLoc = loc2;
+    Depot depot = new Depot(depotLoc, vehicleReadyTimestampMs, vehicleDueTimestampMs);
+    DepotStop depotStop =
+        new DepotStop(
+            VEHICLE_ID, VEHICLE_ID, depotLoc, vehicleDueTimestampMs, true, defaultProfitComponents);
+    Vehicle vehicle = new Vehicle(VEHICLE_ID, depot, defaultProfitComponents);
+    Customer anytimeCustomer =
+        new Customer(CUSTOMER_ID_1, customerLoc, 0, Long.MAX_VALUE, 0, defaultProfitComponents);
+
+    problem.setVehicleList(ImmutableList.of(vehicle));
+    problem.setCustomerVisitList(ImmutableList.of(anytimeCustomer));
+    problem.setRouteStopList(ImmutableList.of(depotStop, anytimeCustomer));
+    problem.setLocationList(ImmutableList.of(depotLoc, customerLoc));
+    problem.setDepotStopList(ImmutableList.of(depotStop));
+    return problem;
+  }
+
   @Test
   void oneCustomer_tooEarlyDepotDueTimestampNotFeasible() {
     VehicleRoutingSolution problem = emptySolution();
@@ -4,6 +4,7 @@ import static org.assertj.core.api.Assertions.assertThat;
 
 import com.*company-data-covered*.logistics.AssignabilityChecker;
 import com.*company-data-covered*.logistics.domain.Attribute;
+import com.*company-data-covered*.logistics.domain.AttributePreference;
 import com.*company-data-covered*.logistics.domain.Customer;
 import com.*company-data-covered*.logistics.domain.Depot;
 import com.*company-data-covered*.logistics.domain.DepotStop;
@@ -910,6 +911,50 @@ class VehicleRoutingConstraintProviderTest {
         .penalizesBy(1);
   }
 
+  @Test
+  void vehicleUnmetAttributePreferencesUSDMills() {
+    Vehicle vehicle = new Vehicle(1, new Depot(loc1, 0, 0), defaultProfitComponents);
+    Customer customer = new Customer(1, loc2, 0, 0, 0, defaultProfitComponents);
+
+    Attribute spanishSpeaking = Attribute.of("language:spanish");
+    Attribute bariatricCar = Attribute.of("car:bariatric");
+    Attribute lastVisitProvider = Attribute.of("provider:1");
+
+    vehicle.setAttributes(ImmutableSet.of(spanishSpeaking));
+    customer.setAttributePreferences(
+        ImmutableList.of(
+            // Met.
+            new AttributePreference(spanishSpeaking, false, 1000),
+            // Met.
+            new AttributePreference(bariatricCar, true, 2000),
+            // Unmet.
+            new AttributePreference(lastVisitProvider, false, 500)));
+    customer.setVehicle(vehicle);
+    vehicle.setNextCustomer(customer);
+    customer.setPreviousStandstill(vehicle);
+
+    constraintVerifier
+        .verifyThat(VehicleRoutingConstraintProvider::vehicleUnmetAttributePreferencesUSDMills)
+        .given(vehicle, customer)
+        .penalizesBy(500 * USD_MILLS_PER_CENT);
+  }
+
+  @Test
+  void vehicleUnmetAttributePreferencesUSDMills_sinkVehicleHasNoPenalty() {
+    SinkVehicle sinkVehicle = new SinkVehicle(new Depot(loc1, 0, 0), defaultProfitComponents);
+    Customer customer = new Customer(1, loc2, 0, 0, 0, defaultProfitComponents);
+    customer.setAttributePreferences(
+        ImmutableList.of(new AttributePreference(Attribute.of("language:spanish"), false, 1000)));
+    customer.setVehicle(sinkVehicle);
+    sinkVehicle.setNextCustomer(customer);
+    customer.setPreviousStandstill(sinkVehicle);
+
+    constraintVerifier
+        .verifyThat(VehicleRoutingConstraintProvider::vehicleUnmetAttributePreferencesUSDMills)
+        .given(sinkVehicle, customer)
+        .penalizesBy(0);
+  }
+
   @Test
   void vehicleUnmatchedAttributesForCustomer_vehicleDoesNotHaveForbiddenAttribute() {
     Vehicle vehicle = new Vehicle(1, new Depot(loc1, 0, 0), defaultProfitComponents);
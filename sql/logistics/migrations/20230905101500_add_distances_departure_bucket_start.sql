@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE
+    distances
+ADD
+    COLUMN departure_bucket_start TIMESTAMP WITH TIME ZONE;
+
+COMMENT ON COLUMN distances.departure_bucket_start IS 'Start of the departure time bucket for time dependent distances, NULL for time independent distances';
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE
+    distances DROP COLUMN departure_bucket_start;
+
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+CREATE INDEX CONCURRENTLY distances_departure_bucket_idx ON distances(
+    from_location_id,
+    to_location_id,
+    source_id,
+    departure_bucket_start,
+    created_at DESC
+)
+WHERE
+    departure_bucket_start IS NOT NULL;
+
+-- +goose Down
+DROP INDEX CONCURRENTLY distances_departure_bucket_idx;
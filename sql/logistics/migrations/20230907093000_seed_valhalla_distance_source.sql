@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+INSERT INTO
+    distance_sources (id, short_name, description)
+VALUES
+    (3, 'valhalla', 'Valhalla (Open Street Maps)');
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM distance_sources
+WHERE
+    id = 3;
+
+-- +goose StatementEnd
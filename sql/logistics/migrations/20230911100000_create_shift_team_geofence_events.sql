@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE shift_team_geofence_events (
+    id BIGSERIAL PRIMARY KEY,
+    shift_team_id BIGINT NOT NULL,
+    care_request_id BIGINT NOT NULL,
+    event_type TEXT NOT NULL,
+    event_timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT shift_team_geofence_events_valid_event_type CHECK (event_type IN ('arrival', 'departure'))
+);
+
+CREATE INDEX shift_team_geofence_events_care_request_idx ON shift_team_geofence_events (care_request_id, created_at DESC);
+
+COMMENT ON TABLE shift_team_geofence_events IS 'Visit arrivals and departures inferred from shift team locations';
+
+COMMENT ON COLUMN shift_team_geofence_events.shift_team_id IS 'Shift team that arrived or departed';
+
+COMMENT ON COLUMN shift_team_geofence_events.care_request_id IS 'Care request of the visit';
+
+COMMENT ON COLUMN shift_team_geofence_events.event_type IS 'Type of event, one of: arrival, departure';
+
+COMMENT ON COLUMN shift_team_geofence_events.event_timestamp IS 'Inferred time of the event, from the shift team locations';
+
+COMMENT ON INDEX shift_team_geofence_events_care_request_idx IS 'Lookup index of geofence events by care request';
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE shift_team_geofence_events;
+
+-- +goose StatementEnd
@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE schedule_stability_rejections (
+    id BIGSERIAL PRIMARY KEY,
+    optimizer_run_id BIGINT NOT NULL,
+    previous_schedule_id BIGINT NOT NULL,
+    hard_score BIGINT NOT NULL,
+    unassigned_visits_score BIGINT NOT NULL,
+    soft_score BIGINT NOT NULL,
+    soft_score_improvement BIGINT NOT NULL,
+    moved_care_request_ids BIGINT [] NOT NULL,
+    moved_committed_adjacent_care_request_ids BIGINT [] NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX schedule_stability_rejections_optimizer_run_idx ON schedule_stability_rejections (optimizer_run_id);
+
+COMMENT ON TABLE schedule_stability_rejections IS 'Solutions of optimizer runs not written as schedules, as they reshuffled committed adjacent visits without enough score improvement';
+
+COMMENT ON COLUMN schedule_stability_rejections.optimizer_run_id IS 'The optimizer run of the rejected solution';
+
+COMMENT ON COLUMN schedule_stability_rejections.previous_schedule_id IS 'The previous schedule kept instead of the rejected solution';
+
+COMMENT ON COLUMN schedule_stability_rejections.hard_score IS 'Hard score of the rejected solution';
+
+COMMENT ON COLUMN schedule_stability_rejections.unassigned_visits_score IS 'Unassigned visits score of the rejected solution';
+
+COMMENT ON COLUMN schedule_stability_rejections.soft_score IS 'Soft score of the rejected solution';
+
+COMMENT ON COLUMN schedule_stability_rejections.soft_score_improvement IS 'Soft score improvement of the rejected solution over the previous schedule rescored against the same problem';
+
+COMMENT ON COLUMN schedule_stability_rejections.moved_care_request_ids IS 'Care requests the rejected solution assigned to a different shift team than the previous schedule';
+
+COMMENT ON COLUMN schedule_stability_rejections.moved_committed_adjacent_care_request_ids IS 'Moved care requests that were next to a committed visit in the previous schedule';
+
+COMMENT ON INDEX schedule_stability_rejections_optimizer_run_idx IS 'Lookup index of schedule stability rejections by optimizer run';
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE schedule_stability_rejections;
+
+-- +goose StatementEnd
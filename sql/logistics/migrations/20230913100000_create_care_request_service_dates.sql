@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE care_request_service_dates (
+    id BIGSERIAL PRIMARY KEY,
+    care_request_id BIGINT NOT NULL,
+    service_region_id BIGINT NOT NULL,
+    service_date DATE NOT NULL,
+    schedule_id BIGINT NOT NULL,
+    is_released BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX care_request_service_dates_care_request_idx ON care_request_service_dates (care_request_id, created_at DESC);
+
+CREATE INDEX care_request_service_dates_service_region_idx ON care_request_service_dates (service_region_id, created_at DESC);
+
+COMMENT ON TABLE care_request_service_dates IS 'Service dates chosen for visits with multi-day arrival time windows';
+
+COMMENT ON COLUMN care_request_service_dates.care_request_id IS 'Care request of the visit';
+
+COMMENT ON COLUMN care_request_service_dates.service_region_id IS 'Service region of the schedule that chose the service date';
+
+COMMENT ON COLUMN care_request_service_dates.service_date IS 'Service date chosen for the visit';
+
+COMMENT ON COLUMN care_request_service_dates.schedule_id IS 'Schedule that chose or released the service date';
+
+COMMENT ON COLUMN care_request_service_dates.is_released IS 'Whether the visit was unassigned from the service date, so it may be scheduled on any date of its window';
+
+COMMENT ON INDEX care_request_service_dates_care_request_idx IS 'Lookup index of service dates by care request';
+
+COMMENT ON INDEX care_request_service_dates_service_region_idx IS 'Lookup index of new service dates by service region';
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE care_request_service_dates;
+
+-- +goose StatementEnd
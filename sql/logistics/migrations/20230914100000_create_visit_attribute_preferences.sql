@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE visit_attribute_preferences (
+    id BIGSERIAL PRIMARY KEY,
+    visit_snapshot_id BIGINT NOT NULL,
+    attribute_id BIGINT NOT NULL,
+    is_unwanted BOOLEAN NOT NULL,
+    penalty_usd_cents BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX visit_attribute_preferences_visit_snapshot_idx ON visit_attribute_preferences (visit_snapshot_id);
+
+COMMENT ON TABLE visit_attribute_preferences IS 'Weighted attribute preferences of visits, which are soft constraints unlike visit_attributes';
+
+COMMENT ON COLUMN visit_attribute_preferences.visit_snapshot_id IS 'Visit snapshot';
+
+COMMENT ON COLUMN visit_attribute_preferences.attribute_id IS 'Attribute';
+
+COMMENT ON COLUMN visit_attribute_preferences.is_unwanted IS 'Whether shift teams with the attribute should be avoided, instead of preferred';
+
+COMMENT ON COLUMN visit_attribute_preferences.penalty_usd_cents IS 'Penalty when the preference is not met';
+
+COMMENT ON INDEX visit_attribute_preferences_visit_snapshot_idx IS 'Lookup index of attribute preferences by visit snapshot';
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE visit_attribute_preferences;
+
+-- +goose StatementEnd
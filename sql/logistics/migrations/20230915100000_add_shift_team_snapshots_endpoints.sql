@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE
+    shift_team_snapshots
+ADD
+    start_location_id BIGINT,
+ADD
+    end_location_id BIGINT,
+ADD
+    earliest_departure_timestamp_sec BIGINT,
+ADD
+    return_by_timestamp_sec BIGINT;
+
+COMMENT ON COLUMN shift_team_snapshots.start_location_id IS 'Location where the shift team starts its shift, if not the base location';
+
+COMMENT ON COLUMN shift_team_snapshots.end_location_id IS 'Location where the shift team ends its shift, if not the start location';
+
+COMMENT ON COLUMN shift_team_snapshots.earliest_departure_timestamp_sec IS 'Earliest departure from the start location, in seconds, if not the shift start';
+
+COMMENT ON COLUMN shift_team_snapshots.return_by_timestamp_sec IS 'Timestamp to be back at the end location by, in seconds, if not the shift end';
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE
+    shift_team_snapshots DROP COLUMN start_location_id,
+    DROP COLUMN end_location_id,
+    DROP COLUMN earliest_departure_timestamp_sec,
+    DROP COLUMN return_by_timestamp_sec;
+
+-- +goose StatementEnd
@@ -0,0 +1,70 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE blackout_zones (
+    id BIGSERIAL PRIMARY KEY,
+    service_region_id BIGINT NOT NULL,
+    name TEXT NOT NULL,
+    latitudes_e6 INTEGER [] NOT NULL,
+    longitudes_e6 INTEGER [] NOT NULL,
+    start_timestamp_sec BIGINT NOT NULL,
+    end_timestamp_sec BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT blackout_zones_valid_time_window CHECK (start_timestamp_sec < end_timestamp_sec),
+    CONSTRAINT blackout_zones_valid_polygon CHECK (
+        cardinality(latitudes_e6) = cardinality(longitudes_e6)
+        AND cardinality(latitudes_e6) >= 3
+    )
+);
+
+CREATE INDEX blackout_zones_service_region_idx ON blackout_zones (service_region_id, end_timestamp_sec);
+
+COMMENT ON TABLE blackout_zones IS 'Areas of service regions closed to visits, such as for weather or safety closures';
+
+COMMENT ON COLUMN blackout_zones.service_region_id IS 'Service region';
+
+COMMENT ON COLUMN blackout_zones.name IS 'Name of the zone, explaining the closure';
+
+COMMENT ON COLUMN blackout_zones.latitudes_e6 IS 'Latitudes of the polygon vertices, in order, in millionths of degrees';
+
+COMMENT ON COLUMN blackout_zones.longitudes_e6 IS 'Longitudes of the polygon vertices, in order, in millionths of degrees';
+
+COMMENT ON COLUMN blackout_zones.start_timestamp_sec IS 'Start of the closure, in seconds';
+
+COMMENT ON COLUMN blackout_zones.end_timestamp_sec IS 'End of the closure, in seconds';
+
+COMMENT ON COLUMN blackout_zones.updated_at IS 'Last update of the zone';
+
+COMMENT ON COLUMN blackout_zones.deleted_at IS 'Deletion of the zone';
+
+COMMENT ON INDEX blackout_zones_service_region_idx IS 'Lookup index of blackout zones by service region and end of the closure';
+
+CREATE TABLE optimizer_run_blacked_out_visits (
+    id BIGSERIAL PRIMARY KEY,
+    optimizer_run_id BIGINT NOT NULL,
+    visit_snapshot_id BIGINT NOT NULL,
+    blackout_zone_id BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX optimizer_run_blacked_out_visits_optimizer_run_idx ON optimizer_run_blacked_out_visits (optimizer_run_id);
+
+COMMENT ON TABLE optimizer_run_blacked_out_visits IS 'Visits excluded from the problems of optimizer runs by blackout zones';
+
+COMMENT ON COLUMN optimizer_run_blacked_out_visits.optimizer_run_id IS 'The optimizer run of the problem';
+
+COMMENT ON COLUMN optimizer_run_blacked_out_visits.visit_snapshot_id IS 'The visit snapshot excluded from the problem, which is unassignable in the schedules of the optimizer run';
+
+COMMENT ON COLUMN optimizer_run_blacked_out_visits.blackout_zone_id IS 'The blackout zone containing the visit during its whole arrival time window';
+
+COMMENT ON INDEX optimizer_run_blacked_out_visits_optimizer_run_idx IS 'Lookup index of blacked out visits by optimizer run';
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE optimizer_run_blacked_out_visits;
+
+DROP TABLE blackout_zones;
+
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE assignment_recommendations (
+    id BIGSERIAL PRIMARY KEY,
+    care_request_id BIGINT NOT NULL,
+    shift_team_id BIGINT NOT NULL,
+    ranking_policy TEXT NOT NULL,
+    rank INTEGER NOT NULL,
+    explanation JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX assignment_recommendations_care_request_idx ON assignment_recommendations (care_request_id, created_at DESC);
+
+COMMENT ON TABLE assignment_recommendations IS 'Assignment recommendations between visits and shift teams shown to dispatchers';
+
+COMMENT ON COLUMN assignment_recommendations.care_request_id IS 'Care request of the recommended visit';
+
+COMMENT ON COLUMN assignment_recommendations.shift_team_id IS 'Recommended shift team';
+
+COMMENT ON COLUMN assignment_recommendations.ranking_policy IS 'Policy the recommendations were ranked by';
+
+COMMENT ON COLUMN assignment_recommendations.rank IS 'Position of the recommendation in the list shown, starting at 0';
+
+COMMENT ON COLUMN assignment_recommendations.explanation IS 'JSON representation of AssignmentExplanation of the recommendation';
+
+COMMENT ON INDEX assignment_recommendations_care_request_idx IS 'Lookup index of assignment recommendations by care request';
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE assignment_recommendations;
+
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+CREATE INDEX CONCURRENTLY check_feasibility_queries_region_created_at_idx ON check_feasibility_queries(service_region_id, created_at DESC);
+
+CREATE INDEX CONCURRENTLY service_region_availability_queries_region_created_at_idx ON service_region_availability_queries(service_region_id, created_at DESC);
+
+-- +goose Down
+DROP INDEX CONCURRENTLY check_feasibility_queries_region_created_at_idx;
+
+DROP INDEX CONCURRENTLY service_region_availability_queries_region_created_at_idx;
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE optimizer_run_validation_repairs (
+    id BIGSERIAL PRIMARY KEY,
+    optimizer_run_id BIGINT NOT NULL,
+    error_name TEXT NOT NULL,
+    error_message TEXT NOT NULL,
+    description TEXT NOT NULL,
+    visit_snapshot_id BIGINT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX optimizer_run_validation_repairs_optimizer_run_idx ON optimizer_run_validation_repairs (optimizer_run_id);
+
+COMMENT ON TABLE optimizer_run_validation_repairs IS 'Repairs applied to the problems of optimizer runs in validation repair mode';
+
+COMMENT ON COLUMN optimizer_run_validation_repairs.optimizer_run_id IS 'The optimizer run of the repaired problem';
+
+COMMENT ON COLUMN optimizer_run_validation_repairs.error_name IS 'Name of the validation error that was repaired';
+
+COMMENT ON COLUMN optimizer_run_validation_repairs.error_message IS 'Message of the validation error that was repaired';
+
+COMMENT ON COLUMN optimizer_run_validation_repairs.description IS 'Description of the repair applied to the problem';
+
+COMMENT ON COLUMN optimizer_run_validation_repairs.visit_snapshot_id IS 'The visit snapshot dropped from the problem by the repair, if any, which is unassignable in the schedules of the optimizer run';
+
+COMMENT ON INDEX optimizer_run_validation_repairs_optimizer_run_idx IS 'Lookup index of validation repairs by optimizer run';
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE optimizer_run_validation_repairs;
+
+-- +goose StatementEnd
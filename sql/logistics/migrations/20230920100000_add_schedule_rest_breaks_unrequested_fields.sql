@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE
+    schedule_rest_breaks
+ADD
+    start_timestamp_sec BIGINT,
+ADD
+    duration_sec BIGINT,
+ADD
+    location_id BIGINT;
+
+COMMENT ON COLUMN schedule_rest_breaks.start_timestamp_sec IS 'Start of an unrequested rest break, in seconds, as it has no break request';
+
+COMMENT ON COLUMN schedule_rest_breaks.duration_sec IS 'Duration of an unrequested rest break, in seconds';
+
+COMMENT ON COLUMN schedule_rest_breaks.location_id IS 'Location of an unrequested rest break, the location of the previous route stop';
+
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE
+    schedule_rest_breaks DROP COLUMN start_timestamp_sec,
+    DROP COLUMN duration_sec,
+    DROP COLUMN location_id;
+
+-- +goose StatementEnd
@@ -146,3 +146,151 @@ SELECT
 FROM
     service_region_availability_visits
     JOIN availability_visit_ids ON availability_visit_ids.id = service_region_availability_visits.id;
+
+-- name: GetServiceRegionAvailabilityQueryDemand :many
+WITH availability_queries AS (
+    SELECT
+        service_region_availability_queries.id,
+        date_trunc('hour', service_region_availability_queries.created_at) :: TIMESTAMPTZ AS hour,
+        service_region_availability_queries.feasibility_status,
+        COALESCE(
+            array_agg(
+                attributes.name
+                ORDER BY
+                    attributes.name
+            ) FILTER (
+                WHERE
+                    attributes.id IS NOT NULL
+            ),
+            '{}'
+        ) :: TEXT [ ] AS attribute_names
+    FROM
+        service_region_availability_queries
+        LEFT JOIN service_region_availability_query_attributes ON service_region_availability_query_attributes.service_region_availability_query_id = service_region_availability_queries.id
+        LEFT JOIN attributes ON attributes.id = service_region_availability_query_attributes.attribute_id
+    WHERE
+        service_region_availability_queries.service_region_id = sqlc.arg(service_region_id)
+        AND service_region_availability_queries.created_at >= sqlc.arg(start_time)
+        AND service_region_availability_queries.created_at < sqlc.arg(end_time)
+    GROUP BY
+        service_region_availability_queries.id
+)
+SELECT
+    hour :: TIMESTAMPTZ AS hour,
+    attribute_names :: TEXT [ ] AS attribute_names,
+    COUNT(*) AS query_count,
+    COUNT(*) FILTER (
+        WHERE
+            feasibility_status IS NULL
+            OR NOT feasibility_status = ANY(sqlc.arg(available_statuses) :: TEXT [ ])
+    ) AS failed_query_count
+FROM
+    availability_queries
+GROUP BY
+    hour,
+    attribute_names
+ORDER BY
+    hour,
+    attribute_names;
+
+-- name: GetServiceRegionCheckFeasibilityQueryDemand :many
+WITH feasibility_queries AS (
+    SELECT
+        check_feasibility_queries.id,
+        date_trunc('hour', check_feasibility_queries.created_at) :: TIMESTAMPTZ AS hour,
+        check_feasibility_queries.response_status,
+        COALESCE(
+            array_agg(
+                attributes.name
+                ORDER BY
+                    attributes.name
+            ) FILTER (
+                WHERE
+                    attributes.id IS NOT NULL
+            ),
+            '{}'
+        ) :: TEXT [ ] AS attribute_names
+    FROM
+        check_feasibility_queries
+        LEFT JOIN check_feasibility_query_attributes ON check_feasibility_query_attributes.check_feasibility_query_id = check_feasibility_queries.id
+        AND check_feasibility_query_attributes.is_required
+        LEFT JOIN attributes ON attributes.id = check_feasibility_query_attributes.attribute_id
+    WHERE
+        check_feasibility_queries.service_region_id = sqlc.arg(service_region_id)
+        AND check_feasibility_queries.created_at >= sqlc.arg(start_time)
+        AND check_feasibility_queries.created_at < sqlc.arg(end_time)
+    GROUP BY
+        check_feasibility_queries.id
+)
+SELECT
+    hour :: TIMESTAMPTZ AS hour,
+    attribute_names :: TEXT [ ] AS attribute_names,
+    COUNT(*) AS query_count,
+    COUNT(*) FILTER (
+        WHERE
+            response_status IS NULL
+            OR NOT response_status = ANY(sqlc.arg(feasible_statuses) :: TEXT [ ])
+    ) AS failed_query_count
+FROM
+    feasibility_queries
+GROUP BY
+    hour,
+    attribute_names
+ORDER BY
+    hour,
+    attribute_names;
+
+-- name: GetServiceRegionCompletedVisitDemand :many
+WITH completed_visits AS (
+    SELECT
+        DISTINCT ON (visit_snapshots.care_request_id) visit_snapshots.care_request_id,
+        visit_snapshots.id AS visit_snapshot_id,
+        visit_phase_snapshots.status_created_at
+    FROM
+        visit_snapshots
+        JOIN visit_phase_snapshots ON visit_phase_snapshots.visit_snapshot_id = visit_snapshots.id
+    WHERE
+        visit_snapshots.service_region_id = sqlc.arg(service_region_id)
+        AND visit_snapshots.created_at >= sqlc.arg(start_time)
+        AND visit_snapshots.created_at < sqlc.arg(end_time)
+        AND visit_phase_snapshots.visit_phase_type_id = sqlc.arg(completed_visit_phase_type_id)
+    ORDER BY
+        visit_snapshots.care_request_id,
+        visit_snapshots.created_at
+),
+completed_visits_attributes AS (
+    SELECT
+        completed_visits.care_request_id,
+        date_trunc('hour', completed_visits.status_created_at) :: TIMESTAMPTZ AS hour,
+        COALESCE(
+            array_agg(
+                attributes.name
+                ORDER BY
+                    attributes.name
+            ) FILTER (
+                WHERE
+                    attributes.id IS NOT NULL
+            ),
+            '{}'
+        ) :: TEXT [ ] AS attribute_names
+    FROM
+        completed_visits
+        LEFT JOIN visit_attributes ON visit_attributes.visit_snapshot_id = completed_visits.visit_snapshot_id
+        AND visit_attributes.is_required
+        LEFT JOIN attributes ON attributes.id = visit_attributes.attribute_id
+    GROUP BY
+        completed_visits.care_request_id,
+        completed_visits.status_created_at
+)
+SELECT
+    hour :: TIMESTAMPTZ AS hour,
+    attribute_names :: TEXT [ ] AS attribute_names,
+    COUNT(*) AS visit_count
+FROM
+    completed_visits_attributes
+GROUP BY
+    hour,
+    attribute_names
+ORDER BY
+    hour,
+    attribute_names;
@@ -71,14 +71,16 @@ INSERT INTO
         to_location_id,
         distance_meters,
         duration_seconds,
-        source_id
+        source_id,
+        departure_bucket_start
     )
 SELECT
     unnest(sqlc.arg(from_location_ids) :: BIGINT [ ]) AS from_location_id,
     unnest(sqlc.arg(to_location_ids) :: BIGINT [ ]) AS to_location_id,
     unnest(sqlc.arg(distances_meters) :: INTEGER [ ]) AS distance_meters,
     unnest(sqlc.arg(durations_seconds) :: INTEGER [ ]) AS duration_seconds,
-    unnest(sqlc.arg(source_ids) :: BIGINT [ ]) AS source_id RETURNING *;
+    unnest(sqlc.arg(source_ids) :: BIGINT [ ]) AS source_id,
+    sqlc.narg(departure_bucket_start) :: TIMESTAMP WITH TIME ZONE AS departure_bucket_start RETURNING *;
 
 -- name: GetDistanceSource :one
 SELECT
@@ -98,6 +100,7 @@ WHERE
     AND to_location_id = $2
     AND source_id = $3
     AND created_at >= $4
+    AND departure_bucket_start IS NULL
 ORDER BY
     created_at DESC
 LIMIT
@@ -121,7 +124,14 @@ FROM
     AND distances.to_location_id = from_to_locs.to_location_id
     AND distances.source_id = from_to_locs.source_id
 WHERE
-    distances.created_at >= $1
+    distances.created_at >= sqlc.arg(created_at)
+    AND (
+        distances.departure_bucket_start = sqlc.narg(departure_bucket_start)
+        OR (
+            distances.departure_bucket_start IS NULL
+            AND sqlc.narg(departure_bucket_start) :: TIMESTAMP WITH TIME ZONE IS NULL
+        )
+    )
 ORDER BY
     distances.from_location_id,
     distances.to_location_id,
@@ -149,6 +159,13 @@ FROM
     AND distances.source_id = from_to_locs.source_id
 WHERE
     distances.created_at >= sqlc.arg(after_created_at)
+    AND (
+        distances.departure_bucket_start = sqlc.narg(departure_bucket_start)
+        OR (
+            distances.departure_bucket_start IS NULL
+            AND sqlc.narg(departure_bucket_start) :: TIMESTAMP WITH TIME ZONE IS NULL
+        )
+    )
 ORDER BY
     distances.from_location_id,
     distances.to_location_id,
@@ -26,7 +26,11 @@ INSERT INTO
         end_timestamp_sec,
         deleted_at,
         num_app_members,
-        num_dhmt_members
+        num_dhmt_members,
+        start_location_id,
+        end_location_id,
+        earliest_departure_timestamp_sec,
+        return_by_timestamp_sec
     )
 SELECT
     sqlc.arg(shift_team_id) AS shift_team_id,
@@ -36,7 +40,11 @@ SELECT
     sqlc.arg(end_timestamp_sec) AS end_timestamp_sec,
     sqlc.arg(deleted_at) AS deleted_at,
     sqlc.arg(num_app_members) AS num_app_members,
-    sqlc.arg(num_dhmt_members) AS num_dhmt_members RETURNING *;
+    sqlc.arg(num_dhmt_members) AS num_dhmt_members,
+    sqlc.narg(start_location_id) :: BIGINT AS start_location_id,
+    sqlc.narg(end_location_id) :: BIGINT AS end_location_id,
+    sqlc.narg(earliest_departure_timestamp_sec) :: BIGINT AS earliest_departure_timestamp_sec,
+    sqlc.narg(return_by_timestamp_sec) :: BIGINT AS return_by_timestamp_sec RETURNING *;
 
 -- name: AddVisitSnapshot :one
 INSERT INTO
@@ -251,6 +259,37 @@ ORDER BY
     visit_attributes.visit_snapshot_id,
     attributes.id;
 
+-- name: AddVisitAttributePreferences :many
+INSERT INTO
+    visit_attribute_preferences (
+        visit_snapshot_id,
+        attribute_id,
+        is_unwanted,
+        penalty_usd_cents
+    )
+SELECT
+    sqlc.arg(visit_snapshot_id) :: BIGINT AS visit_snapshot_id,
+    unnest(sqlc.arg(attribute_ids) :: BIGINT [ ]) AS attribute_id,
+    unnest(sqlc.arg(is_unwanteds) :: BOOL [ ]) AS is_unwanted,
+    unnest(sqlc.arg(penalty_usd_cents) :: BIGINT [ ]) AS penalty_usd_cents RETURNING *;
+
+-- name: GetAttributePreferencesForVisitSnapshots :many
+SELECT
+    visit_attribute_preferences.visit_snapshot_id,
+    visit_attribute_preferences.is_unwanted,
+    visit_attribute_preferences.penalty_usd_cents,
+    attributes.*
+FROM
+    visit_attribute_preferences
+    JOIN attributes ON attributes.id = visit_attribute_preferences.attribute_id
+    JOIN (
+        SELECT
+            unnest(sqlc.arg(visit_snapshot_ids) :: BIGINT [ ]) AS visit_snapshot_id
+    ) visit_snapshot_ids ON visit_attribute_preferences.visit_snapshot_id = visit_snapshot_ids.visit_snapshot_id
+ORDER BY
+    visit_attribute_preferences.visit_snapshot_id,
+    attributes.id;
+
 -- name: GetShiftTeamRestBreakRequestsForShiftTeams :many
 SELECT
     shift_team_rest_break_requests.*
@@ -669,6 +708,144 @@ SELECT
     unnest(sqlc.arg(shift_team_snapshot_ids) :: BIGINT [ ]) AS shift_team_snapshot_id,
     unnest(sqlc.arg(location_ids) :: BIGINT [ ]) AS location_id RETURNING *;
 
+-- name: GetShiftTeamLocationsInTimeRange :many
+SELECT
+    shift_team_locations.created_at,
+    locations.latitude_e6,
+    locations.longitude_e6
+FROM
+    shift_team_locations
+    JOIN shift_team_snapshots ON shift_team_snapshots.id = shift_team_locations.shift_team_snapshot_id
+    JOIN locations ON locations.id = shift_team_locations.location_id
+WHERE
+    shift_team_snapshots.shift_team_id = sqlc.arg(shift_team_id)
+    AND shift_team_locations.created_at >= sqlc.arg(start_time)
+    AND shift_team_locations.created_at <= sqlc.arg(end_time)
+ORDER BY
+    shift_team_locations.created_at;
+
+-- name: DeleteShiftTeamLocationsCreatedBefore :execrows
+DELETE FROM
+    shift_team_locations
+WHERE
+    id IN (
+        SELECT
+            shift_team_locations.id
+        FROM
+            shift_team_locations
+            JOIN shift_team_snapshots ON shift_team_snapshots.id = shift_team_locations.shift_team_snapshot_id
+        WHERE
+            shift_team_locations.created_at < sqlc.arg(created_before)
+            AND EXISTS (
+                SELECT
+                    1
+                FROM
+                    shift_team_locations newer_locations
+                    JOIN shift_team_snapshots newer_snapshots ON newer_snapshots.id = newer_locations.shift_team_snapshot_id
+                WHERE
+                    newer_snapshots.shift_team_id = shift_team_snapshots.shift_team_id
+                    AND (newer_locations.created_at, newer_locations.id) > (shift_team_locations.created_at, shift_team_locations.id)
+            )
+        ORDER BY
+            shift_team_locations.created_at
+        LIMIT
+            sqlc.arg(max_rows)
+    );
+
+-- name: GetGeofenceCandidateVisitsForShiftTeam :many
+SELECT
+    DISTINCT ON (visit_snapshots.care_request_id) visit_snapshots.care_request_id,
+    visit_snapshots.location_id,
+    visit_phase_types.short_name AS visit_phase_short_name,
+    MIN(visit_phase_snapshots.status_created_at) OVER (
+        PARTITION BY visit_snapshots.care_request_id
+    ) :: TIMESTAMP WITH TIME ZONE AS first_status_created_at
+FROM
+    visit_phase_snapshots
+    JOIN visit_snapshots ON visit_snapshots.id = visit_phase_snapshots.visit_snapshot_id
+    JOIN visit_phase_types ON visit_phase_types.id = visit_phase_snapshots.visit_phase_type_id
+WHERE
+    visit_phase_snapshots.shift_team_id = sqlc.arg(shift_team_id) :: BIGINT
+    AND visit_phase_snapshots.status_created_at >= sqlc.arg(status_created_after)
+    AND visit_snapshots.created_at <= sqlc.arg(latest_snapshot_time)
+ORDER BY
+    visit_snapshots.care_request_id,
+    visit_snapshots.created_at DESC,
+    visit_phase_snapshots.created_at DESC;
+
+-- name: GetScheduleRouteCareRequestIDs :many
+SELECT
+    visit_snapshots.care_request_id
+FROM
+    schedule_visits
+    JOIN visit_snapshots ON visit_snapshots.id = schedule_visits.visit_snapshot_id
+WHERE
+    schedule_visits.schedule_route_id = $1;
+
+-- name: AddShiftTeamGeofenceEvent :one
+INSERT INTO
+    shift_team_geofence_events(
+        shift_team_id,
+        care_request_id,
+        event_type,
+        event_timestamp
+    )
+VALUES
+    ($1, $2, $3, $4) RETURNING *;
+
+-- name: GetLatestShiftTeamGeofenceEvents :many
+SELECT
+    DISTINCT ON (care_request_id, event_type) *
+FROM
+    shift_team_geofence_events
+WHERE
+    care_request_id = ANY(sqlc.arg(care_request_ids) :: BIGINT [ ])
+    AND created_at <= sqlc.arg(latest_snapshot_time)
+ORDER BY
+    care_request_id,
+    event_type,
+    created_at DESC;
+
+-- name: AddCareRequestServiceDates :many
+INSERT INTO
+    care_request_service_dates(
+        care_request_id,
+        service_region_id,
+        service_date,
+        schedule_id,
+        is_released
+    )
+SELECT
+    unnest(sqlc.arg(care_request_ids) :: BIGINT [ ]),
+    sqlc.arg(service_region_id) :: BIGINT,
+    sqlc.arg(service_date) :: DATE,
+    sqlc.arg(schedule_id) :: BIGINT,
+    unnest(sqlc.arg(is_released) :: BOOLEAN [ ]) RETURNING *;
+
+-- name: GetLatestCareRequestServiceDates :many
+SELECT
+    DISTINCT ON (care_request_id) *
+FROM
+    care_request_service_dates
+WHERE
+    care_request_id = ANY(sqlc.arg(care_request_ids) :: BIGINT [ ])
+    AND created_at <= sqlc.arg(latest_snapshot_time)
+ORDER BY
+    care_request_id,
+    created_at DESC;
+
+-- name: HasAnyCareRequestServiceDatesInRegionSince :many
+SELECT
+    1
+FROM
+    care_request_service_dates
+WHERE
+    service_region_id = $1
+    AND created_at > sqlc.arg(since_snapshot_time)
+    AND created_at <= sqlc.arg(latest_snapshot_time)
+LIMIT
+    1;
+
 -- name: AddOptimizerRun :one
 INSERT INTO
     optimizer_runs (
@@ -744,6 +921,45 @@ ORDER BY
 LIMIT
     1;
 
+-- name: AddOptimizerRunValidationRepairs :many
+INSERT INTO
+    optimizer_run_validation_repairs (
+        optimizer_run_id,
+        error_name,
+        error_message,
+        description,
+        visit_snapshot_id
+    )
+SELECT
+    sqlc.arg(optimizer_run_id) :: BIGINT,
+    unnest(sqlc.arg(error_names) :: TEXT [ ]),
+    unnest(sqlc.arg(error_messages) :: TEXT [ ]),
+    unnest(sqlc.arg(descriptions) :: TEXT [ ]),
+    -- 0 for repairs that did not drop a visit, as arrays cannot hold NULLs.
+    NULLIF(unnest(sqlc.arg(visit_snapshot_ids) :: BIGINT [ ]), 0) RETURNING *;
+
+-- name: GetOptimizerRunValidationRepairs :many
+SELECT
+    *
+FROM
+    optimizer_run_validation_repairs
+WHERE
+    optimizer_run_id = $1
+ORDER BY
+    id;
+
+-- name: AddOptimizerRunBlackedOutVisits :many
+INSERT INTO
+    optimizer_run_blacked_out_visits (
+        optimizer_run_id,
+        visit_snapshot_id,
+        blackout_zone_id
+    )
+SELECT
+    sqlc.arg(optimizer_run_id) :: BIGINT,
+    unnest(sqlc.arg(visit_snapshot_ids) :: BIGINT [ ]),
+    unnest(sqlc.arg(blackout_zone_ids) :: BIGINT [ ]) RETURNING *;
+
 -- name: GetLatestScheduleForOptimizerRunID :one
 SELECT
     schedules.*,
@@ -1047,3 +1263,55 @@ FROM
     optimizer_run_types
 WHERE
     name = $1;
+
+-- name: AddAssignmentRecommendations :many
+INSERT INTO
+    assignment_recommendations (
+        care_request_id,
+        shift_team_id,
+        ranking_policy,
+        rank,
+        explanation
+    )
+SELECT
+    unnest(sqlc.arg(care_request_ids) :: BIGINT [ ]),
+    unnest(sqlc.arg(shift_team_ids) :: BIGINT [ ]),
+    sqlc.arg(ranking_policy) :: TEXT,
+    unnest(sqlc.arg(ranks) :: INTEGER [ ]),
+    unnest(sqlc.arg(explanations) :: TEXT [ ]) :: JSONB RETURNING *;
+
+-- name: GetAssignmentRecommendationsForCareRequest :many
+SELECT
+    *
+FROM
+    assignment_recommendations
+WHERE
+    care_request_id = $1
+ORDER BY
+    created_at DESC,
+    rank;
+
+-- name: AddScheduleStabilityRejection :one
+INSERT INTO
+    schedule_stability_rejections (
+        optimizer_run_id,
+        previous_schedule_id,
+        hard_score,
+        unassigned_visits_score,
+        soft_score,
+        soft_score_improvement,
+        moved_care_request_ids,
+        moved_committed_adjacent_care_request_ids
+    )
+VALUES
+    ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING *;
+
+-- name: GetScheduleStabilityRejectionsForOptimizerRun :many
+SELECT
+    *
+FROM
+    schedule_stability_rejections
+WHERE
+    optimizer_run_id = $1
+ORDER BY
+    id;
@@ -135,10 +135,13 @@ INSERT INTO
     schedule_rest_breaks (
         schedule_id,
         schedule_route_id,
-        shift_team_break_request_id
+        shift_team_break_request_id,
+        start_timestamp_sec,
+        duration_sec,
+        location_id
     )
 VALUES
-    ($1, $2, $3) RETURNING *;
+    ($1, $2, $3, $4, $5, $6) RETURNING *;
 
 -- name: GetLatestScheduleInfoForServiceRegionDate :one
 SELECT
@@ -159,6 +162,24 @@ ORDER BY
 LIMIT
     1;
 
+-- name: GetScheduleInfosForServiceRegionDate :many
+SELECT
+    schedules.id schedule_id,
+    schedules.optimizer_run_id,
+    schedules.created_at,
+    optimizer_runs.snapshot_timestamp
+FROM
+    schedules
+    JOIN optimizer_runs ON schedules.optimizer_run_id = optimizer_runs.id
+    JOIN optimizer_run_types ON optimizer_run_types.id = optimizer_runs.optimizer_run_type_id
+WHERE
+    optimizer_runs.service_region_id = sqlc.arg(service_region_id)
+    AND optimizer_runs.service_date = sqlc.arg(service_date)
+    AND optimizer_run_types.name = sqlc.arg(optimizer_run_type)
+ORDER BY
+    optimizer_runs.created_at,
+    schedules.created_at;
+
 -- name: GetLatestScheduleRouteVisitForVisitSnapshotID :one
 SELECT
     schedule_routes.shift_team_snapshot_id,
@@ -180,7 +201,15 @@ SELECT
     schedules.id schedule_id,
     schedule_routes.id route_id,
     shift_team_snapshots.id shift_team_snapshot_id,
-    shift_team_snapshots.base_location_id shift_team_base_location_id,
+    COALESCE(
+        shift_team_snapshots.start_location_id,
+        shift_team_snapshots.base_location_id
+    ) :: BIGINT shift_team_base_location_id,
+    COALESCE(
+        shift_team_snapshots.end_location_id,
+        shift_team_snapshots.start_location_id,
+        shift_team_snapshots.base_location_id
+    ) :: BIGINT shift_team_end_location_id,
     shift_team_snapshots.shift_team_id,
     optimizer_runs.service_region_id,
     optimizer_runs.service_date,
@@ -220,10 +249,19 @@ WITH latest_clinical_urgency_level_configs AS (
 )
 SELECT
     schedule_stops.*,
-    shift_team_rest_break_requests.start_timestamp_sec AS break_request_start_timestamp_sec,
-    shift_team_rest_break_requests.id AS break_request_id,
-    shift_team_rest_break_requests.duration_sec AS break_request_duration_sec,
-    shift_team_rest_break_requests.location_id AS break_request_location_id,
+    COALESCE(
+        shift_team_rest_break_requests.start_timestamp_sec,
+        schedule_rest_breaks.start_timestamp_sec
+    ) AS break_request_start_timestamp_sec,
+    schedule_rest_breaks.shift_team_break_request_id AS break_request_id,
+    COALESCE(
+        shift_team_rest_break_requests.duration_sec,
+        schedule_rest_breaks.duration_sec
+    ) AS break_request_duration_sec,
+    COALESCE(
+        shift_team_rest_break_requests.location_id,
+        schedule_rest_breaks.location_id
+    ) AS break_request_location_id,
     schedule_visits.arrival_timestamp_sec,
     schedule_visits.visit_snapshot_id,
     schedule_routes.depot_arrival_timestamp_sec,
@@ -256,12 +294,26 @@ ORDER BY
 SELECT
     schedule_routes.*,
     shift_team_snapshots.shift_team_id,
+    shift_team_snapshots.start_timestamp_sec AS shift_start_timestamp_sec,
+    shift_team_snapshots.end_timestamp_sec AS shift_end_timestamp_sec,
+    locations.id AS base_location_id,
     locations.latitude_e6 AS base_location_latitude_e6,
-    locations.longitude_e6 AS base_location_longitude_e6
+    locations.longitude_e6 AS base_location_longitude_e6,
+    end_locations.id AS end_location_id,
+    end_locations.latitude_e6 AS end_location_latitude_e6,
+    end_locations.longitude_e6 AS end_location_longitude_e6
 FROM
     schedule_routes
     JOIN shift_team_snapshots ON schedule_routes.shift_team_snapshot_id = shift_team_snapshots.id
-    JOIN locations ON locations.id = shift_team_snapshots.base_location_id
+    JOIN locations ON locations.id = COALESCE(
+        shift_team_snapshots.start_location_id,
+        shift_team_snapshots.base_location_id
+    )
+    JOIN locations end_locations ON end_locations.id = COALESCE(
+        shift_team_snapshots.end_location_id,
+        shift_team_snapshots.start_location_id,
+        shift_team_snapshots.base_location_id
+    )
 WHERE
     schedule_id = $1;
 
@@ -295,6 +347,72 @@ WHERE
 ORDER BY
     unassigned_schedule_visits.id;
 
+-- name: GetValidationRepairDroppedVisitsForScheduleID :many
+WITH latest_clinical_urgency_level_configs AS (
+    SELECT
+        DISTINCT ON(clinical_urgency_level_id) clinical_urgency_level_id,
+        clinical_urgency_level_configs.clinical_window_duration_sec
+    FROM
+        clinical_urgency_level_configs
+    WHERE
+        clinical_urgency_level_configs.created_at <= sqlc.arg(latest_snapshot_time)
+    ORDER BY
+        clinical_urgency_level_id,
+        clinical_urgency_level_configs.created_at DESC
+)
+SELECT
+    optimizer_run_validation_repairs.visit_snapshot_id,
+    optimizer_run_validation_repairs.description,
+    visit_snapshots.care_request_id,
+    visit_snapshots.arrival_start_timestamp_sec,
+    visit_snapshots.arrival_end_timestamp_sec,
+    latest_clinical_urgency_level_configs.clinical_window_duration_sec AS clinical_urgency_window_duration_sec,
+    visit_acuity_snapshots.clinical_urgency_level_id AS clinical_urgency_level_id
+FROM
+    schedules
+    JOIN optimizer_run_validation_repairs ON optimizer_run_validation_repairs.optimizer_run_id = schedules.optimizer_run_id
+    JOIN visit_snapshots ON optimizer_run_validation_repairs.visit_snapshot_id = visit_snapshots.id
+    LEFT JOIN visit_acuity_snapshots ON visit_snapshots.id = visit_acuity_snapshots.visit_snapshot_id
+    LEFT JOIN latest_clinical_urgency_level_configs ON visit_acuity_snapshots.clinical_urgency_level_id = latest_clinical_urgency_level_configs.clinical_urgency_level_id
+WHERE
+    schedules.id = sqlc.arg(schedule_id)
+ORDER BY
+    optimizer_run_validation_repairs.id;
+
+-- name: GetBlackedOutVisitsForScheduleID :many
+WITH latest_clinical_urgency_level_configs AS (
+    SELECT
+        DISTINCT ON(clinical_urgency_level_id) clinical_urgency_level_id,
+        clinical_urgency_level_configs.clinical_window_duration_sec
+    FROM
+        clinical_urgency_level_configs
+    WHERE
+        clinical_urgency_level_configs.created_at <= sqlc.arg(latest_snapshot_time)
+    ORDER BY
+        clinical_urgency_level_id,
+        clinical_urgency_level_configs.created_at DESC
+)
+SELECT
+    optimizer_run_blacked_out_visits.visit_snapshot_id,
+    blackout_zones.id AS blackout_zone_id,
+    blackout_zones.name AS blackout_zone_name,
+    visit_snapshots.care_request_id,
+    visit_snapshots.arrival_start_timestamp_sec,
+    visit_snapshots.arrival_end_timestamp_sec,
+    latest_clinical_urgency_level_configs.clinical_window_duration_sec AS clinical_urgency_window_duration_sec,
+    visit_acuity_snapshots.clinical_urgency_level_id AS clinical_urgency_level_id
+FROM
+    schedules
+    JOIN optimizer_run_blacked_out_visits ON optimizer_run_blacked_out_visits.optimizer_run_id = schedules.optimizer_run_id
+    JOIN blackout_zones ON optimizer_run_blacked_out_visits.blackout_zone_id = blackout_zones.id
+    JOIN visit_snapshots ON optimizer_run_blacked_out_visits.visit_snapshot_id = visit_snapshots.id
+    LEFT JOIN visit_acuity_snapshots ON visit_snapshots.id = visit_acuity_snapshots.visit_snapshot_id
+    LEFT JOIN latest_clinical_urgency_level_configs ON visit_acuity_snapshots.clinical_urgency_level_id = latest_clinical_urgency_level_configs.clinical_urgency_level_id
+WHERE
+    schedules.id = sqlc.arg(schedule_id)
+ORDER BY
+    optimizer_run_blacked_out_visits.id;
+
 -- name: GetOptimizerRunForScheduleID :one
 SELECT
     optimizer_runs.service_date,
@@ -321,10 +439,19 @@ WITH latest_clinical_urgency_level_configs AS (
 )
 SELECT
     schedule_stops.*,
-    shift_team_rest_break_requests.start_timestamp_sec AS break_request_start_timestamp_sec,
-    shift_team_rest_break_requests.id AS break_request_id,
-    shift_team_rest_break_requests.duration_sec AS break_request_duration_sec,
-    shift_team_rest_break_requests.location_id AS break_request_location_id,
+    COALESCE(
+        shift_team_rest_break_requests.start_timestamp_sec,
+        schedule_rest_breaks.start_timestamp_sec
+    ) AS break_request_start_timestamp_sec,
+    schedule_rest_breaks.shift_team_break_request_id AS break_request_id,
+    COALESCE(
+        shift_team_rest_break_requests.duration_sec,
+        schedule_rest_breaks.duration_sec
+    ) AS break_request_duration_sec,
+    COALESCE(
+        shift_team_rest_break_requests.location_id,
+        schedule_rest_breaks.location_id
+    ) AS break_request_location_id,
     schedule_visits.arrival_timestamp_sec,
     schedule_visits.visit_snapshot_id,
     schedule_routes.depot_arrival_timestamp_sec,
@@ -415,6 +542,27 @@ ORDER BY
     visit_snapshots.care_request_id,
     visit_snapshots.created_at DESC;
 
+-- name: GetVisitPhaseHistoryForCareRequests :many
+SELECT
+    visit_snapshots.care_request_id,
+    visit_phase_types.short_name AS visit_phase_short_name,
+    visit_phase_snapshots.status_created_at,
+    visit_phase_snapshots.shift_team_id
+FROM
+    visit_snapshots
+    JOIN(
+        SELECT
+            unnest(sqlc.arg(care_request_ids) :: BIGINT [ ]) AS id
+    ) care_request_ids ON care_request_ids.id = visit_snapshots.care_request_id
+    JOIN visit_phase_snapshots ON visit_snapshots.id = visit_phase_snapshots.visit_snapshot_id
+    JOIN visit_phase_types ON visit_phase_snapshots.visit_phase_type_id = visit_phase_types.id
+WHERE
+    visit_snapshots.created_at <= sqlc.arg(latest_snapshot_time)
+ORDER BY
+    visit_snapshots.care_request_id,
+    visit_phase_snapshots.status_created_at,
+    visit_phase_snapshots.created_at;
+
 -- name: GetVisitPhaseTypeForShortName :one
 SELECT
     *
@@ -235,3 +235,53 @@ WHERE
         LIMIT
             1
     );
+
+-- name: AddBlackoutZone :one
+INSERT INTO
+    blackout_zones (
+        service_region_id,
+        name,
+        latitudes_e6,
+        longitudes_e6,
+        start_timestamp_sec,
+        end_timestamp_sec
+    )
+VALUES
+    ($1, $2, $3, $4, $5, $6) RETURNING *;
+
+-- name: UpdateBlackoutZone :one
+UPDATE
+    blackout_zones
+SET
+    name = $2,
+    latitudes_e6 = $3,
+    longitudes_e6 = $4,
+    start_timestamp_sec = $5,
+    end_timestamp_sec = $6,
+    updated_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1
+    AND deleted_at IS NULL RETURNING *;
+
+-- name: DeleteBlackoutZone :one
+UPDATE
+    blackout_zones
+SET
+    deleted_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1
+    AND deleted_at IS NULL RETURNING *;
+
+-- name: GetBlackoutZonesInServiceRegion :many
+SELECT
+    *
+FROM
+    blackout_zones
+WHERE
+    service_region_id = sqlc.arg(service_region_id)
+    AND deleted_at IS NULL
+    AND start_timestamp_sec < sqlc.arg(end_timestamp_sec)
+    AND end_timestamp_sec > sqlc.arg(start_timestamp_sec)
+ORDER BY
+    start_timestamp_sec,
+    id;
@@ -16,6 +16,82 @@ SET row_security = off;
 
 SET default_table_access_method = heap;
 
+--
+-- Name: assignment_recommendations; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.assignment_recommendations (
+    id bigint NOT NULL,
+    care_request_id bigint NOT NULL,
+    shift_team_id bigint NOT NULL,
+    ranking_policy text NOT NULL,
+    rank integer NOT NULL,
+    explanation jsonb NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+
+--
+-- Name: TABLE assignment_recommendations; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON TABLE public.assignment_recommendations IS 'Assignment recommendations between visits and shift teams shown to dispatchers';
+
+
+--
+-- Name: COLUMN assignment_recommendations.care_request_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.assignment_recommendations.care_request_id IS 'Care request of the recommended visit';
+
+
+--
+-- Name: COLUMN assignment_recommendations.shift_team_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.assignment_recommendations.shift_team_id IS 'Recommended shift team';
+
+
+--
+-- Name: COLUMN assignment_recommendations.ranking_policy; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.assignment_recommendations.ranking_policy IS 'Policy the recommendations were ranked by';
+
+
+--
+-- Name: COLUMN assignment_recommendations.rank; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.assignment_recommendations.rank IS 'Position of the recommendation in the list shown, starting at 0';
+
+
+--
+-- Name: COLUMN assignment_recommendations.explanation; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.assignment_recommendations.explanation IS 'JSON representation of AssignmentExplanation of the recommendation';
+
+
+--
+-- Name: assignment_recommendations_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.assignment_recommendations_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: assignment_recommendations_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.assignment_recommendations_id_seq OWNED BY public.assignment_recommendations.id;
+
+
 --
 -- Name: attributes; Type: TABLE; Schema: public; Owner: -
 --
@@ -60,6 +136,184 @@ CREATE SEQUENCE public.attributes_id_seq
 ALTER SEQUENCE public.attributes_id_seq OWNED BY public.attributes.id;
 
 
+--
+-- Name: blackout_zones; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.blackout_zones (
+    id bigint NOT NULL,
+    service_region_id bigint NOT NULL,
+    name text NOT NULL,
+    latitudes_e6 integer[] NOT NULL,
+    longitudes_e6 integer[] NOT NULL,
+    start_timestamp_sec bigint NOT NULL,
+    end_timestamp_sec bigint NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    deleted_at timestamp with time zone,
+    CONSTRAINT blackout_zones_valid_polygon CHECK (((cardinality(latitudes_e6) = cardinality(longitudes_e6)) AND (cardinality(latitudes_e6) >= 3))),
+    CONSTRAINT blackout_zones_valid_time_window CHECK ((start_timestamp_sec < end_timestamp_sec))
+);
+
+
+--
+-- Name: TABLE blackout_zones; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON TABLE public.blackout_zones IS 'Areas of service regions closed to visits, such as for weather or safety closures';
+
+
+--
+-- Name: COLUMN blackout_zones.service_region_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.blackout_zones.service_region_id IS 'Service region';
+
+
+--
+-- Name: COLUMN blackout_zones.name; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.blackout_zones.name IS 'Name of the zone, explaining the closure';
+
+
+--
+-- Name: COLUMN blackout_zones.latitudes_e6; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.blackout_zones.latitudes_e6 IS 'Latitudes of the polygon vertices, in order, in millionths of degrees';
+
+
+--
+-- Name: COLUMN blackout_zones.longitudes_e6; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.blackout_zones.longitudes_e6 IS 'Longitudes of the polygon vertices, in order, in millionths of degrees';
+
+
+--
+-- Name: COLUMN blackout_zones.start_timestamp_sec; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.blackout_zones.start_timestamp_sec IS 'Start of the closure, in seconds';
+
+
+--
+-- Name: COLUMN blackout_zones.end_timestamp_sec; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.blackout_zones.end_timestamp_sec IS 'End of the closure, in seconds';
+
+
+--
+-- Name: COLUMN blackout_zones.updated_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.blackout_zones.updated_at IS 'Last update of the zone';
+
+
+--
+-- Name: COLUMN blackout_zones.deleted_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.blackout_zones.deleted_at IS 'Deletion of the zone';
+
+
+--
+-- Name: blackout_zones_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.blackout_zones_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: blackout_zones_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.blackout_zones_id_seq OWNED BY public.blackout_zones.id;
+
+
+--
+-- Name: care_request_service_dates; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.care_request_service_dates (
+    id bigint NOT NULL,
+    care_request_id bigint NOT NULL,
+    service_region_id bigint NOT NULL,
+    service_date date NOT NULL,
+    schedule_id bigint NOT NULL,
+    is_released boolean DEFAULT false NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+
+--
+-- Name: TABLE care_request_service_dates; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON TABLE public.care_request_service_dates IS 'Service dates chosen for visits with multi-day arrival time windows';
+
+
+--
+-- Name: COLUMN care_request_service_dates.care_request_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.care_request_service_dates.care_request_id IS 'Care request of the visit';
+
+
+--
+-- Name: COLUMN care_request_service_dates.service_region_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.care_request_service_dates.service_region_id IS 'Service region of the schedule that chose the service date';
+
+
+--
+-- Name: COLUMN care_request_service_dates.service_date; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.care_request_service_dates.service_date IS 'Service date chosen for the visit';
+
+
+--
+-- Name: COLUMN care_request_service_dates.schedule_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.care_request_service_dates.schedule_id IS 'Schedule that chose or released the service date';
+
+
+--
+-- Name: COLUMN care_request_service_dates.is_released; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.care_request_service_dates.is_released IS 'Whether the visit was unassigned from the service date, so it may be scheduled on any date of its window';
+
+
+--
+-- Name: care_request_service_dates_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.care_request_service_dates_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: care_request_service_dates_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.care_request_service_dates_id_seq OWNED BY public.care_request_service_dates.id;
+
+
 --
 -- Name: check_feasibility_queries; Type: TABLE; Schema: public; Owner: -
 --
@@ -443,7 +697,8 @@ CREATE TABLE public.distances (
     to_location_id bigint NOT NULL,
     distance_meters integer NOT NULL,
     duration_seconds integer NOT NULL,
-    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    departure_bucket_start timestamp with time zone
 );
 
 
@@ -489,6 +744,13 @@ COMMENT ON COLUMN public.distances.distance_meters IS 'Meters between from_locat
 COMMENT ON COLUMN public.distances.duration_seconds IS 'Seconds between from_location_id and to_location_id';
 
 
+--
+-- Name: COLUMN distances.departure_bucket_start; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.distances.departure_bucket_start IS 'Start of the departure time bucket for time dependent distances, NULL for time independent distances';
+
+
 --
 -- Name: distances_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
@@ -732,6 +994,66 @@ CREATE SEQUENCE public.optimizer_constraint_configs_id_seq
 ALTER SEQUENCE public.optimizer_constraint_configs_id_seq OWNED BY public.optimizer_constraint_configs.id;
 
 
+--
+-- Name: optimizer_run_blacked_out_visits; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.optimizer_run_blacked_out_visits (
+    id bigint NOT NULL,
+    optimizer_run_id bigint NOT NULL,
+    visit_snapshot_id bigint NOT NULL,
+    blackout_zone_id bigint NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+
+--
+-- Name: TABLE optimizer_run_blacked_out_visits; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON TABLE public.optimizer_run_blacked_out_visits IS 'Visits excluded from the problems of optimizer runs by blackout zones';
+
+
+--
+-- Name: COLUMN optimizer_run_blacked_out_visits.optimizer_run_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.optimizer_run_blacked_out_visits.optimizer_run_id IS 'The optimizer run of the problem';
+
+
+--
+-- Name: COLUMN optimizer_run_blacked_out_visits.visit_snapshot_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.optimizer_run_blacked_out_visits.visit_snapshot_id IS 'The visit snapshot excluded from the problem, which is unassignable in the schedules of the optimizer run';
+
+
+--
+-- Name: COLUMN optimizer_run_blacked_out_visits.blackout_zone_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.optimizer_run_blacked_out_visits.blackout_zone_id IS 'The blackout zone containing the visit during its whole arrival time window';
+
+
+--
+-- Name: optimizer_run_blacked_out_visits_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.optimizer_run_blacked_out_visits_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: optimizer_run_blacked_out_visits_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.optimizer_run_blacked_out_visits_id_seq OWNED BY public.optimizer_run_blacked_out_visits.id;
+
+
 --
 -- Name: optimizer_run_error_sources; Type: TABLE; Schema: public; Owner: -
 --
@@ -887,6 +1209,82 @@ CREATE SEQUENCE public.optimizer_run_types_id_seq
 ALTER SEQUENCE public.optimizer_run_types_id_seq OWNED BY public.optimizer_run_types.id;
 
 
+--
+-- Name: optimizer_run_validation_repairs; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.optimizer_run_validation_repairs (
+    id bigint NOT NULL,
+    optimizer_run_id bigint NOT NULL,
+    error_name text NOT NULL,
+    error_message text NOT NULL,
+    description text NOT NULL,
+    visit_snapshot_id bigint,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+
+--
+-- Name: TABLE optimizer_run_validation_repairs; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON TABLE public.optimizer_run_validation_repairs IS 'Repairs applied to the problems of optimizer runs in validation repair mode';
+
+
+--
+-- Name: COLUMN optimizer_run_validation_repairs.optimizer_run_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.optimizer_run_validation_repairs.optimizer_run_id IS 'The optimizer run of the repaired problem';
+
+
+--
+-- Name: COLUMN optimizer_run_validation_repairs.error_name; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.optimizer_run_validation_repairs.error_name IS 'Name of the validation error that was repaired';
+
+
+--
+-- Name: COLUMN optimizer_run_validation_repairs.error_message; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.optimizer_run_validation_repairs.error_message IS 'Message of the validation error that was repaired';
+
+
+--
+-- Name: COLUMN optimizer_run_validation_repairs.description; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.optimizer_run_validation_repairs.description IS 'Description of the repair applied to the problem';
+
+
+--
+-- Name: COLUMN optimizer_run_validation_repairs.visit_snapshot_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.optimizer_run_validation_repairs.visit_snapshot_id IS 'The visit snapshot dropped from the problem by the repair, if any, which is unassignable in the schedules of the optimizer run';
+
+
+--
+-- Name: optimizer_run_validation_repairs_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.optimizer_run_validation_repairs_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: optimizer_run_validation_repairs_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.optimizer_run_validation_repairs_id_seq OWNED BY public.optimizer_run_validation_repairs.id;
+
+
 --
 -- Name: optimizer_runs; Type: TABLE; Schema: public; Owner: -
 --
@@ -1148,7 +1546,10 @@ CREATE TABLE public.schedule_rest_breaks (
     schedule_id bigint NOT NULL,
     schedule_route_id bigint NOT NULL,
     shift_team_break_request_id bigint NOT NULL,
-    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    start_timestamp_sec bigint,
+    duration_sec bigint,
+    location_id bigint
 );
 
 
@@ -1180,6 +1581,27 @@ COMMENT ON COLUMN public.schedule_rest_breaks.schedule_route_id IS 'Schedule rou
 COMMENT ON COLUMN public.schedule_rest_breaks.shift_team_break_request_id IS 'Break request associated with the rest break stop';
 
 
+--
+-- Name: COLUMN schedule_rest_breaks.start_timestamp_sec; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.schedule_rest_breaks.start_timestamp_sec IS 'Start of an unrequested rest break, in seconds, as it has no break request';
+
+
+--
+-- Name: COLUMN schedule_rest_breaks.duration_sec; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.schedule_rest_breaks.duration_sec IS 'Duration of an unrequested rest break, in seconds';
+
+
+--
+-- Name: COLUMN schedule_rest_breaks.location_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.schedule_rest_breaks.location_id IS 'Location of an unrequested rest break, the location of the previous route stop';
+
+
 --
 -- Name: schedule_rest_breaks_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
@@ -1259,6 +1681,106 @@ CREATE SEQUENCE public.schedule_routes_id_seq
 ALTER SEQUENCE public.schedule_routes_id_seq OWNED BY public.schedule_routes.id;
 
 
+--
+-- Name: schedule_stability_rejections; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.schedule_stability_rejections (
+    id bigint NOT NULL,
+    optimizer_run_id bigint NOT NULL,
+    previous_schedule_id bigint NOT NULL,
+    hard_score bigint NOT NULL,
+    unassigned_visits_score bigint NOT NULL,
+    soft_score bigint NOT NULL,
+    soft_score_improvement bigint NOT NULL,
+    moved_care_request_ids bigint[] NOT NULL,
+    moved_committed_adjacent_care_request_ids bigint[] NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+
+--
+-- Name: TABLE schedule_stability_rejections; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON TABLE public.schedule_stability_rejections IS 'Solutions of optimizer runs not written as schedules, as they reshuffled committed adjacent visits without enough score improvement';
+
+
+--
+-- Name: COLUMN schedule_stability_rejections.optimizer_run_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.schedule_stability_rejections.optimizer_run_id IS 'The optimizer run of the rejected solution';
+
+
+--
+-- Name: COLUMN schedule_stability_rejections.previous_schedule_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.schedule_stability_rejections.previous_schedule_id IS 'The previous schedule kept instead of the rejected solution';
+
+
+--
+-- Name: COLUMN schedule_stability_rejections.hard_score; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.schedule_stability_rejections.hard_score IS 'Hard score of the rejected solution';
+
+
+--
+-- Name: COLUMN schedule_stability_rejections.unassigned_visits_score; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.schedule_stability_rejections.unassigned_visits_score IS 'Unassigned visits score of the rejected solution';
+
+
+--
+-- Name: COLUMN schedule_stability_rejections.soft_score; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.schedule_stability_rejections.soft_score IS 'Soft score of the rejected solution';
+
+
+--
+-- Name: COLUMN schedule_stability_rejections.soft_score_improvement; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.schedule_stability_rejections.soft_score_improvement IS 'Soft score improvement of the rejected solution over the previous schedule rescored against the same problem';
+
+
+--
+-- Name: COLUMN schedule_stability_rejections.moved_care_request_ids; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.schedule_stability_rejections.moved_care_request_ids IS 'Care requests the rejected solution assigned to a different shift team than the previous schedule';
+
+
+--
+-- Name: COLUMN schedule_stability_rejections.moved_committed_adjacent_care_request_ids; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.schedule_stability_rejections.moved_committed_adjacent_care_request_ids IS 'Moved care requests that were next to a committed visit in the previous schedule';
+
+
+--
+-- Name: schedule_stability_rejections_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.schedule_stability_rejections_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: schedule_stability_rejections_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.schedule_stability_rejections_id_seq OWNED BY public.schedule_stability_rejections.id;
+
+
 --
 -- Name: schedule_stats; Type: TABLE; Schema: public; Owner: -
 --
@@ -2344,6 +2866,75 @@ CREATE SEQUENCE public.shift_team_attributes_id_seq
 ALTER SEQUENCE public.shift_team_attributes_id_seq OWNED BY public.shift_team_attributes.id;
 
 
+--
+-- Name: shift_team_geofence_events; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.shift_team_geofence_events (
+    id bigint NOT NULL,
+    shift_team_id bigint NOT NULL,
+    care_request_id bigint NOT NULL,
+    event_type text NOT NULL,
+    event_timestamp timestamp with time zone NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT shift_team_geofence_events_valid_event_type CHECK ((event_type = ANY (ARRAY['arrival'::text, 'departure'::text])))
+);
+
+
+--
+-- Name: TABLE shift_team_geofence_events; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON TABLE public.shift_team_geofence_events IS 'Visit arrivals and departures inferred from shift team locations';
+
+
+--
+-- Name: COLUMN shift_team_geofence_events.shift_team_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.shift_team_geofence_events.shift_team_id IS 'Shift team that arrived or departed';
+
+
+--
+-- Name: COLUMN shift_team_geofence_events.care_request_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.shift_team_geofence_events.care_request_id IS 'Care request of the visit';
+
+
+--
+-- Name: COLUMN shift_team_geofence_events.event_type; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.shift_team_geofence_events.event_type IS 'Type of event, one of: arrival, departure';
+
+
+--
+-- Name: COLUMN shift_team_geofence_events.event_timestamp; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.shift_team_geofence_events.event_timestamp IS 'Inferred time of the event, from the shift team locations';
+
+
+--
+-- Name: shift_team_geofence_events_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.shift_team_geofence_events_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: shift_team_geofence_events_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.shift_team_geofence_events_id_seq OWNED BY public.shift_team_geofence_events.id;
+
+
 --
 -- Name: shift_team_locations; Type: TABLE; Schema: public; Owner: -
 --
@@ -2479,6 +3070,10 @@ CREATE TABLE public.shift_team_snapshots (
     created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
     num_app_members integer DEFAULT 0 NOT NULL,
     num_dhmt_members integer DEFAULT 0 NOT NULL,
+    start_location_id bigint,
+    end_location_id bigint,
+    earliest_departure_timestamp_sec bigint,
+    return_by_timestamp_sec bigint,
     CONSTRAINT shift_team_snapshots_valid_time_window CHECK ((start_timestamp_sec < end_timestamp_sec))
 );
 
@@ -2539,6 +3134,34 @@ COMMENT ON COLUMN public.shift_team_snapshots.num_app_members IS 'number of memb
 COMMENT ON COLUMN public.shift_team_snapshots.num_dhmt_members IS 'number of members that are DHMT on the shift team.';
 
 
+--
+-- Name: COLUMN shift_team_snapshots.start_location_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.shift_team_snapshots.start_location_id IS 'Location where the shift team starts its shift, if not the base location';
+
+
+--
+-- Name: COLUMN shift_team_snapshots.end_location_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.shift_team_snapshots.end_location_id IS 'Location where the shift team ends its shift, if not the start location';
+
+
+--
+-- Name: COLUMN shift_team_snapshots.earliest_departure_timestamp_sec; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.shift_team_snapshots.earliest_departure_timestamp_sec IS 'Earliest departure from the start location, in seconds, if not the shift start';
+
+
+--
+-- Name: COLUMN shift_team_snapshots.return_by_timestamp_sec; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.shift_team_snapshots.return_by_timestamp_sec IS 'Timestamp to be back at the end location by, in seconds, if not the shift end';
+
+
 --
 -- Name: shift_team_snapshots_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
@@ -2815,6 +3438,74 @@ CREATE SEQUENCE public.visit_acuity_snapshots_id_seq
 ALTER SEQUENCE public.visit_acuity_snapshots_id_seq OWNED BY public.visit_acuity_snapshots.id;
 
 
+--
+-- Name: visit_attribute_preferences; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.visit_attribute_preferences (
+    id bigint NOT NULL,
+    visit_snapshot_id bigint NOT NULL,
+    attribute_id bigint NOT NULL,
+    is_unwanted boolean NOT NULL,
+    penalty_usd_cents bigint NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+
+--
+-- Name: TABLE visit_attribute_preferences; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON TABLE public.visit_attribute_preferences IS 'Weighted attribute preferences of visits, which are soft constraints unlike visit_attributes';
+
+
+--
+-- Name: COLUMN visit_attribute_preferences.visit_snapshot_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.visit_attribute_preferences.visit_snapshot_id IS 'Visit snapshot';
+
+
+--
+-- Name: COLUMN visit_attribute_preferences.attribute_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.visit_attribute_preferences.attribute_id IS 'Attribute';
+
+
+--
+-- Name: COLUMN visit_attribute_preferences.is_unwanted; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.visit_attribute_preferences.is_unwanted IS 'Whether shift teams with the attribute should be avoided, instead of preferred';
+
+
+--
+-- Name: COLUMN visit_attribute_preferences.penalty_usd_cents; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.visit_attribute_preferences.penalty_usd_cents IS 'Penalty when the preference is not met';
+
+
+--
+-- Name: visit_attribute_preferences_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.visit_attribute_preferences_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: visit_attribute_preferences_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.visit_attribute_preferences_id_seq OWNED BY public.visit_attribute_preferences.id;
+
+
 --
 -- Name: visit_attributes; Type: TABLE; Schema: public; Owner: -
 --
@@ -3296,6 +3987,13 @@ CREATE SEQUENCE public.visit_value_snapshots_id_seq
 ALTER SEQUENCE public.visit_value_snapshots_id_seq OWNED BY public.visit_value_snapshots.id;
 
 
+--
+-- Name: assignment_recommendations id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.assignment_recommendations ALTER COLUMN id SET DEFAULT nextval('public.assignment_recommendations_id_seq'::regclass);
+
+
 --
 -- Name: attributes id; Type: DEFAULT; Schema: public; Owner: -
 --
@@ -3303,6 +4001,20 @@ ALTER SEQUENCE public.visit_value_snapshots_id_seq OWNED BY public.visit_value_s
 ALTER TABLE ONLY public.attributes ALTER COLUMN id SET DEFAULT nextval('public.attributes_id_seq'::regclass);
 
 
+--
+-- Name: blackout_zones id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.blackout_zones ALTER COLUMN id SET DEFAULT nextval('public.blackout_zones_id_seq'::regclass);
+
+
+--
+-- Name: care_request_service_dates id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.care_request_service_dates ALTER COLUMN id SET DEFAULT nextval('public.care_request_service_dates_id_seq'::regclass);
+
+
 --
 -- Name: check_feasibility_queries id; Type: DEFAULT; Schema: public; Owner: -
 --
@@ -3373,6 +4085,13 @@ ALTER TABLE ONLY public.optimizer_configs ALTER COLUMN id SET DEFAULT nextval('p
 ALTER TABLE ONLY public.optimizer_constraint_configs ALTER COLUMN id SET DEFAULT nextval('public.optimizer_constraint_configs_id_seq'::regclass);
 
 
+--
+-- Name: optimizer_run_blacked_out_visits id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.optimizer_run_blacked_out_visits ALTER COLUMN id SET DEFAULT nextval('public.optimizer_run_blacked_out_visits_id_seq'::regclass);
+
+
 --
 -- Name: optimizer_run_error_sources id; Type: DEFAULT; Schema: public; Owner: -
 --
@@ -3394,6 +4113,13 @@ ALTER TABLE ONLY public.optimizer_run_errors ALTER COLUMN id SET DEFAULT nextval
 ALTER TABLE ONLY public.optimizer_run_types ALTER COLUMN id SET DEFAULT nextval('public.optimizer_run_types_id_seq'::regclass);
 
 
+--
+-- Name: optimizer_run_validation_repairs id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.optimizer_run_validation_repairs ALTER COLUMN id SET DEFAULT nextval('public.optimizer_run_validation_repairs_id_seq'::regclass);
+
+
 --
 -- Name: optimizer_runs id; Type: DEFAULT; Schema: public; Owner: -
 --
@@ -3429,6 +4155,13 @@ ALTER TABLE ONLY public.schedule_rest_breaks ALTER COLUMN id SET DEFAULT nextval
 ALTER TABLE ONLY public.schedule_routes ALTER COLUMN id SET DEFAULT nextval('public.schedule_routes_id_seq'::regclass);
 
 
+--
+-- Name: schedule_stability_rejections id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.schedule_stability_rejections ALTER COLUMN id SET DEFAULT nextval('public.schedule_stability_rejections_id_seq'::regclass);
+
+
 --
 -- Name: schedule_stats id; Type: DEFAULT; Schema: public; Owner: -
 --
@@ -3555,6 +4288,13 @@ ALTER TABLE ONLY public.service_regions ALTER COLUMN id SET DEFAULT nextval('pub
 ALTER TABLE ONLY public.shift_team_attributes ALTER COLUMN id SET DEFAULT nextval('public.shift_team_attributes_id_seq'::regclass);
 
 
+--
+-- Name: shift_team_geofence_events id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.shift_team_geofence_events ALTER COLUMN id SET DEFAULT nextval('public.shift_team_geofence_events_id_seq'::regclass);
+
+
 --
 -- Name: shift_team_locations id; Type: DEFAULT; Schema: public; Owner: -
 --
@@ -3604,6 +4344,13 @@ ALTER TABLE ONLY public.virtual_app_visit_phase_types ALTER COLUMN id SET DEFAUL
 ALTER TABLE ONLY public.visit_acuity_snapshots ALTER COLUMN id SET DEFAULT nextval('public.visit_acuity_snapshots_id_seq'::regclass);
 
 
+--
+-- Name: visit_attribute_preferences id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.visit_attribute_preferences ALTER COLUMN id SET DEFAULT nextval('public.visit_attribute_preferences_id_seq'::regclass);
+
+
 --
 -- Name: visit_attributes id; Type: DEFAULT; Schema: public; Owner: -
 --
@@ -3653,6 +4400,14 @@ ALTER TABLE ONLY public.visit_snapshots ALTER COLUMN id SET DEFAULT nextval('pub
 ALTER TABLE ONLY public.visit_value_snapshots ALTER COLUMN id SET DEFAULT nextval('public.visit_value_snapshots_id_seq'::regclass);
 
 
+--
+-- Name: assignment_recommendations assignment_recommendations_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.assignment_recommendations
+    ADD CONSTRAINT assignment_recommendations_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: attributes attributes_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -3676,6 +4431,22 @@ ALTER TABLE ONLY public.attributes
 COMMENT ON CONSTRAINT attributes_unique_name ON public.attributes IS 'Unique index on attribute names';
 
 
+--
+-- Name: blackout_zones blackout_zones_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.blackout_zones
+    ADD CONSTRAINT blackout_zones_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: care_request_service_dates care_request_service_dates_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.care_request_service_dates
+    ADD CONSTRAINT care_request_service_dates_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: check_feasibility_queries check_feasibility_queries_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -3779,6 +4550,14 @@ ALTER TABLE ONLY public.optimizer_constraint_configs
     ADD CONSTRAINT optimizer_constraint_configs_unique_configs UNIQUE (config);
 
 
+--
+-- Name: optimizer_run_blacked_out_visits optimizer_run_blacked_out_visits_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.optimizer_run_blacked_out_visits
+    ADD CONSTRAINT optimizer_run_blacked_out_visits_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: optimizer_run_error_sources optimizer_run_error_sources_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -3803,6 +4582,14 @@ ALTER TABLE ONLY public.optimizer_run_types
     ADD CONSTRAINT optimizer_run_types_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: optimizer_run_validation_repairs optimizer_run_validation_repairs_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.optimizer_run_validation_repairs
+    ADD CONSTRAINT optimizer_run_validation_repairs_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: optimizer_runs optimizer_runs_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -3851,6 +4638,14 @@ ALTER TABLE ONLY public.schedule_routes
     ADD CONSTRAINT schedule_routes_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: schedule_stability_rejections schedule_stability_rejections_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.schedule_stability_rejections
+    ADD CONSTRAINT schedule_stability_rejections_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: schedule_stats schedule_stats_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -4010,6 +4805,14 @@ ALTER TABLE ONLY public.shift_team_attributes
 COMMENT ON CONSTRAINT shift_team_attributes_unique_shift_attribute ON public.shift_team_attributes IS 'Unique index on shift team attributes';
 
 
+--
+-- Name: shift_team_geofence_events shift_team_geofence_events_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.shift_team_geofence_events
+    ADD CONSTRAINT shift_team_geofence_events_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: shift_team_locations shift_team_locations_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -4066,6 +4869,14 @@ ALTER TABLE ONLY public.visit_acuity_snapshots
     ADD CONSTRAINT visit_acuity_snapshots_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: visit_attribute_preferences visit_attribute_preferences_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.visit_attribute_preferences
+    ADD CONSTRAINT visit_attribute_preferences_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: visit_attributes visit_attributes_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -4137,6 +4948,20 @@ ALTER TABLE ONLY public.visit_value_snapshots
     ADD CONSTRAINT visit_value_snapshots_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: assignment_recommendations_care_request_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX assignment_recommendations_care_request_idx ON public.assignment_recommendations USING btree (care_request_id, created_at DESC);
+
+
+--
+-- Name: INDEX assignment_recommendations_care_request_idx; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON INDEX public.assignment_recommendations_care_request_idx IS 'Lookup index of assignment recommendations by care request';
+
+
 --
 -- Name: INDEX attributes_unique_name; Type: COMMENT; Schema: public; Owner: -
 --
@@ -4144,6 +4969,48 @@ ALTER TABLE ONLY public.visit_value_snapshots
 COMMENT ON INDEX public.attributes_unique_name IS 'Unique index on attribute names';
 
 
+--
+-- Name: blackout_zones_service_region_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX blackout_zones_service_region_idx ON public.blackout_zones USING btree (service_region_id, end_timestamp_sec);
+
+
+--
+-- Name: INDEX blackout_zones_service_region_idx; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON INDEX public.blackout_zones_service_region_idx IS 'Lookup index of blackout zones by service region and end of the closure';
+
+
+--
+-- Name: care_request_service_dates_care_request_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX care_request_service_dates_care_request_idx ON public.care_request_service_dates USING btree (care_request_id, created_at DESC);
+
+
+--
+-- Name: INDEX care_request_service_dates_care_request_idx; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON INDEX public.care_request_service_dates_care_request_idx IS 'Lookup index of service dates by care request';
+
+
+--
+-- Name: care_request_service_dates_service_region_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX care_request_service_dates_service_region_idx ON public.care_request_service_dates USING btree (service_region_id, created_at DESC);
+
+
+--
+-- Name: INDEX care_request_service_dates_service_region_idx; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON INDEX public.care_request_service_dates_service_region_idx IS 'Lookup index of new service dates by service region';
+
+
 --
 -- Name: check_feasibility_queries_care_request_idx; Type: INDEX; Schema: public; Owner: -
 --
@@ -4151,6 +5018,13 @@ COMMENT ON INDEX public.attributes_unique_name IS 'Unique index on attribute nam
 CREATE INDEX check_feasibility_queries_care_request_idx ON public.check_feasibility_queries USING btree (care_request_id, created_at DESC);
 
 
+--
+-- Name: check_feasibility_queries_region_created_at_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX check_feasibility_queries_region_created_at_idx ON public.check_feasibility_queries USING btree (service_region_id, created_at DESC);
+
+
 --
 -- Name: check_feasibility_query_attributes_idx; Type: INDEX; Schema: public; Owner: -
 --
@@ -4214,6 +5088,13 @@ COMMENT ON INDEX public.distance_source_short_name_idx IS 'Index of distance sou
 CREATE INDEX distances_created_at_idx ON public.distances USING btree (created_at DESC);
 
 
+--
+-- Name: distances_departure_bucket_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX distances_departure_bucket_idx ON public.distances USING btree (from_location_id, to_location_id, source_id, departure_bucket_start, created_at DESC) WHERE (departure_bucket_start IS NOT NULL);
+
+
 --
 -- Name: INDEX location_unique_lat_lng; Type: COMMENT; Schema: public; Owner: -
 --
@@ -4256,6 +5137,20 @@ CREATE INDEX markets_station_market_idx ON public.markets USING btree (station_m
 COMMENT ON INDEX public.markets_station_market_idx IS 'Lookup index of station_market_id on markets';
 
 
+--
+-- Name: optimizer_run_blacked_out_visits_optimizer_run_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX optimizer_run_blacked_out_visits_optimizer_run_idx ON public.optimizer_run_blacked_out_visits USING btree (optimizer_run_id);
+
+
+--
+-- Name: INDEX optimizer_run_blacked_out_visits_optimizer_run_idx; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON INDEX public.optimizer_run_blacked_out_visits_optimizer_run_idx IS 'Lookup index of blacked out visits by optimizer run';
+
+
 --
 -- Name: optimizer_run_error_optimizer_run_idx; Type: INDEX; Schema: public; Owner: -
 --
@@ -4277,6 +5172,20 @@ CREATE UNIQUE INDEX optimizer_run_error_sources_short_name_idx ON public.optimiz
 CREATE INDEX optimizer_run_types_idx ON public.optimizer_runs USING btree (optimizer_run_type_id);
 
 
+--
+-- Name: optimizer_run_validation_repairs_optimizer_run_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX optimizer_run_validation_repairs_optimizer_run_idx ON public.optimizer_run_validation_repairs USING btree (optimizer_run_id);
+
+
+--
+-- Name: INDEX optimizer_run_validation_repairs_optimizer_run_idx; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON INDEX public.optimizer_run_validation_repairs_optimizer_run_idx IS 'Lookup index of validation repairs by optimizer run';
+
+
 --
 -- Name: optimizer_runs_created_at_idx; Type: INDEX; Schema: public; Owner: -
 --
@@ -4382,6 +5291,20 @@ CREATE INDEX schedule_service_region_idx ON public.schedules USING btree (servic
 COMMENT ON INDEX public.schedule_service_region_idx IS 'Lookups of schedules by service region';
 
 
+--
+-- Name: schedule_stability_rejections_optimizer_run_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX schedule_stability_rejections_optimizer_run_idx ON public.schedule_stability_rejections USING btree (optimizer_run_id);
+
+
+--
+-- Name: INDEX schedule_stability_rejections_optimizer_run_idx; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON INDEX public.schedule_stability_rejections_optimizer_run_idx IS 'Lookup index of schedule stability rejections by optimizer run';
+
+
 --
 -- Name: schedule_stats_schedule_idx; Type: INDEX; Schema: public; Owner: -
 --
@@ -4459,6 +5382,13 @@ CREATE INDEX schedule_visits_created_at_idx ON public.schedule_visits USING btre
 CREATE INDEX schedules_created_at_idx ON public.schedules USING btree (created_at DESC);
 
 
+--
+-- Name: service_region_availability_queries_region_created_at_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX service_region_availability_queries_region_created_at_idx ON public.service_region_availability_queries USING btree (service_region_id, created_at DESC);
+
+
 --
 -- Name: service_region_availability_queries_service_region_service_date; Type: INDEX; Schema: public; Owner: -
 --
@@ -4599,6 +5529,20 @@ CREATE INDEX service_region_open_hours_schedules_idx ON public.service_region_op
 CREATE INDEX shift_team_attributes_created_at_idx ON public.shift_team_attributes USING btree (created_at DESC);
 
 
+--
+-- Name: shift_team_geofence_events_care_request_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX shift_team_geofence_events_care_request_idx ON public.shift_team_geofence_events USING btree (care_request_id, created_at DESC);
+
+
+--
+-- Name: INDEX shift_team_geofence_events_care_request_idx; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON INDEX public.shift_team_geofence_events_care_request_idx IS 'Lookup index of geofence events by care request';
+
+
 --
 -- Name: shift_team_locations_created_at_idx; Type: INDEX; Schema: public; Owner: -
 --
@@ -4732,6 +5676,20 @@ CREATE UNIQUE INDEX visit_acuity_snapshots_visit_snapshot_idx ON public.visit_ac
 COMMENT ON INDEX public.visit_acuity_snapshots_visit_snapshot_idx IS 'Lookup index on visits acuity, by visit snapshot ID';
 
 
+--
+-- Name: visit_attribute_preferences_visit_snapshot_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX visit_attribute_preferences_visit_snapshot_idx ON public.visit_attribute_preferences USING btree (visit_snapshot_id);
+
+
+--
+-- Name: INDEX visit_attribute_preferences_visit_snapshot_idx; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON INDEX public.visit_attribute_preferences_visit_snapshot_idx IS 'Lookup index of attribute preferences by visit snapshot';
+
+
 --
 -- Name: visit_attributes_created_at_idx; Type: INDEX; Schema: public; Owner: -
 --
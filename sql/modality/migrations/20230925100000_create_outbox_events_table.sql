@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    topic TEXT NOT NULL,
+    key BYTEA,
+    value BYTEA NOT NULL,
+    headers JSONB NOT NULL DEFAULT '{}',
+    xact_id XID8 NOT NULL DEFAULT pg_current_xact_id(),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    published_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE outbox_events IS 'Events to publish to Kafka, written in the same transaction as the changes they are about';
+COMMENT ON COLUMN outbox_events.xact_id IS 'ID of the transaction that wrote the event, to relay events once older transactions are done';
+COMMENT ON COLUMN outbox_events.attempts IS 'Number of failed attempts to publish the event';
+COMMENT ON COLUMN outbox_events.published_at IS 'When the event was published, NULL if pending';
+
+CREATE INDEX outbox_events_pending_idx ON outbox_events (xact_id, id)
+WHERE
+    published_at IS NULL;
+
+CREATE INDEX outbox_events_published_at_idx ON outbox_events (published_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE outbox_events;
+-- +goose StatementEnd
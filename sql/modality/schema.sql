@@ -345,6 +345,71 @@ CREATE SEQUENCE public.network_modality_configurations_id_seq
 ALTER SEQUENCE public.network_modality_configurations_id_seq OWNED BY public.network_modality_configurations.id;
 
 
+--
+-- Name: outbox_events; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.outbox_events (
+    id bigint NOT NULL,
+    topic text NOT NULL,
+    key bytea,
+    value bytea NOT NULL,
+    headers jsonb DEFAULT '{}'::jsonb NOT NULL,
+    xact_id xid8 DEFAULT pg_current_xact_id() NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    last_error text,
+    published_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+
+--
+-- Name: TABLE outbox_events; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON TABLE public.outbox_events IS 'Events to publish to Kafka, written in the same transaction as the changes they are about';
+
+
+--
+-- Name: COLUMN outbox_events.xact_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.outbox_events.xact_id IS 'ID of the transaction that wrote the event, to relay events once older transactions are done';
+
+
+--
+-- Name: COLUMN outbox_events.attempts; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.outbox_events.attempts IS 'Number of failed attempts to publish the event';
+
+
+--
+-- Name: COLUMN outbox_events.published_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.outbox_events.published_at IS 'When the event was published, NULL if pending';
+
+
+--
+-- Name: outbox_events_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.outbox_events_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: outbox_events_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.outbox_events_id_seq OWNED BY public.outbox_events.id;
+
+
 --
 -- Name: schema_migrations; Type: TABLE; Schema: public; Owner: -
 --
@@ -412,6 +477,13 @@ ALTER TABLE ONLY public.modality_configurations ALTER COLUMN id SET DEFAULT next
 ALTER TABLE ONLY public.network_modality_configurations ALTER COLUMN id SET DEFAULT nextval('public.network_modality_configurations_id_seq'::regclass);
 
 
+--
+-- Name: outbox_events id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.outbox_events ALTER COLUMN id SET DEFAULT nextval('public.outbox_events_id_seq'::regclass);
+
+
 --
 -- Name: schema_migrations id; Type: DEFAULT; Schema: public; Owner: -
 --
@@ -459,6 +531,14 @@ ALTER TABLE ONLY public.network_modality_configurations
     ADD CONSTRAINT network_modality_configurations_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: outbox_events outbox_events_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.outbox_events
+    ADD CONSTRAINT outbox_events_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: schema_migrations schema_migrations_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -523,6 +603,20 @@ CREATE INDEX network_modality_configurations_service_line_id_idx ON public.netwo
 COMMENT ON INDEX public.network_modality_configurations_service_line_id_idx IS 'Lookup index for network_modality_configurations by service_line_id';
 
 
+--
+-- Name: outbox_events_pending_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX outbox_events_pending_idx ON public.outbox_events USING btree (xact_id, id) WHERE (published_at IS NULL);
+
+
+--
+-- Name: outbox_events_published_at_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX outbox_events_published_at_idx ON public.outbox_events USING btree (published_at);
+
+
 --
 -- Name: service_line_id_idx; Type: INDEX; Schema: public; Owner: -
 --